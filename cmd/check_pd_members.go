@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newCheckPDMembersCmd() *cobra.Command {
+	var removeStale bool
+	cmd := &cobra.Command{
+		Use:   "check-pd-members <cluster-name>",
+		Short: "Check whether the member list of PD matches the topology",
+		Long: `Check whether the member list of PD matches the topology. The members absent
+from the topology are reported as stale, and the running PD instances of the topology
+which are not members are reported as missing. The stale members are removed with
+--remove-stale, except the healthy ones and the leader.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot check the PD members of non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			report, err := operator.ReconcilePDMembers(metadata.Topology, removeStale, 5*time.Second, nil)
+			if err != nil {
+				return err
+			}
+
+			rows := [][]string{{"Member", "Client URLs", "Problem", "Status"}}
+			for _, m := range report.Stale {
+				status := "Kept"
+				switch {
+				case m.Removed:
+					status = "Removed"
+				case m.Err != nil:
+					status = "Kept: " + m.Err.Error()
+				}
+				rows = append(rows, []string{m.Name, strings.Join(m.ClientURLs, ","), "Not in the topology", status})
+			}
+			for _, id := range report.Missing {
+				rows = append(rows, []string{id, "", "Running but not a member", "Kept"})
+			}
+			if len(rows) > 1 {
+				cliutil.PrintTable(rows, true)
+			}
+
+			if !report.Consistent() {
+				return errors.Errorf("the PD member list of cluster %s is inconsistent with the topology", clusterName)
+			}
+			log.Infof("The PD member list of cluster `%s` matches the topology", clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&removeStale, "remove-stale", false, "Remove the stale members which are not healthy")
+
+	return cmd
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newCheckSSHCmd() *cobra.Command {
+	var nodes []string
+
+	cmd := &cobra.Command{
+		Use:   "check-ssh <cluster-name>",
+		Short: "Check whether all the hosts can be logged in via SSH",
+		Long: `Check whether all the hosts of the cluster can be logged in via SSH with the
+deploy user and the key of the cluster, by running a trivial command on each host.
+The hosts failed are listed with the reasons.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot check non-exists cluster %s", clusterName)
+			}
+
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			nodeFilter := set.NewStringSet(nodes...)
+			uniqueHosts := set.NewStringSet()
+			var hosts []string
+			metadata.Topology.IterInstance(func(inst meta.Instance) {
+				if len(nodeFilter) > 0 && !nodeFilter.Exist(inst.GetHost()) {
+					return
+				}
+				if !uniqueHosts.Exist(inst.GetHost()) {
+					uniqueHosts.Insert(inst.GetHost())
+					hosts = append(hosts, inst.GetHost())
+				}
+			})
+			if len(hosts) == 0 {
+				return errors.Errorf("no host of cluster %s to check", clusterName)
+			}
+
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				CheckSSH(hosts).
+				Build()
+
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			log.Infof("All the %d hosts of cluster `%s` can be logged in via SSH", len(hosts), clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&nodes, "node", "N", nil, "Only check specified hosts")
+
+	return cmd
+}
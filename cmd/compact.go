@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newCompactCmd() *cobra.Command {
+	var (
+		nodes           []string
+		compactOpt      operator.CompactOptions
+		compactTimeout  int64
+		transferTimeout int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compact <cluster-name>",
+		Short: "Compact the data of TiKV to reclaim the space",
+		Long: `Compact the kv DB of the TiKV instances by tikv-ctl to reclaim the space, e.g. during
+a maintenance window. The instances are compacted one by one, and the leaders of each
+store are evicted before the compaction so that the serving is not impacted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot compact non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			instances := operator.FilterInstance((&meta.TiKVComponent{Specification: metadata.Topology}).Instances(), set.NewStringSet(nodes...))
+			if len(nodes) > len(instances) {
+				return errors.Errorf("only the TiKV instances can be compacted, some of %v are not TiKV instances of cluster %s", nodes, clusterName)
+			}
+			if len(instances) == 0 {
+				return errors.Errorf("no TiKV instance of cluster %s to compact", clusterName)
+			}
+			compactOpt.Timeout = time.Duration(compactTimeout) * time.Second
+			retryOpt := &utils.RetryOption{
+				Delay:   2 * time.Second,
+				Timeout: time.Duration(transferTimeout) * time.Second,
+			}
+
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				CompactTiKV(metadata.Topology, instances, compactOpt, retryOpt).
+				Build()
+
+			if err := runValidationHook("compact", clusterName, metadata.Version, nodes, metadata.Topology, t); err != nil {
+				return err
+			}
+
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			log.Infof("Compacted %d TiKV instances of cluster `%s` successfully", len(instances), clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&nodes, "node", "N", nil, "Only compact specified TiKV nodes")
+	cmd.Flags().StringSliceVar(&compactOpt.CFs, "cf", []string{"default", "write"}, "The column families to compact")
+	cmd.Flags().IntVar(&compactOpt.Threads, "threads", 8, "The number of threads of each compaction")
+	cmd.Flags().BoolVar(&compactOpt.Bottommost, "bottommost", false, "Compact the bottommost level too, which reclaims the most space but takes longer")
+	cmd.Flags().Int64Var(&compactTimeout, "compact-timeout", 7200, "Timeout in seconds of the compaction of each column family")
+	cmd.Flags().Int64Var(&transferTimeout, "transfer-timeout", 300, "Timeout in seconds when evicting the leaders of each store")
+
+	return cmd
+}
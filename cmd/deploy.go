@@ -30,6 +30,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/repository"
@@ -47,6 +48,10 @@ var (
 	errDeployPortConflict  = errNSDeploy.NewType("port_conflict", errutil.ErrTraitPreCheck)
 )
 
+// reachabilityMaxPeers is the max number of peers each host connects to when checking the
+// reachability between hosts, to avoid the connections growing quadratically in huge clusters
+const reachabilityMaxPeers = 16
+
 type componentInfo struct {
 	component string
 	version   repository.Version
@@ -55,6 +60,30 @@ type componentInfo struct {
 type deployOptions struct {
 	user         string // username to login to the SSH server
 	identityFile string // path to the private key file
+	planFile     string // path to export the plan of remote commands and transfers to
+	fixFirewall  bool   // add the firewall rules to permit the ports used by the cluster
+	cleanup      bool   // kill the stray processes and remove the partial files left by a previous deploy
+	reuseData    bool   // deploy onto the data directories which are not empty
+	timezone     string // the expected timezone of the hosts, the most common one of them if empty
+	fixTimezone  bool   // set the timezone of the hosts not in the expected one
+	fixHostname  bool   // set unique hostnames to the hosts whose hostnames are duplicated
+	allowSELinux bool   // the components are permitted by the SELinux policies in enforcing mode
+	fixSELinux   bool   // set SELinux to permissive on the hosts where it's enforcing
+	fixSwap      bool   // disable the swap of the hosts persistently
+	fixBlockDev  bool   // set the I/O scheduler and the read-ahead of the data disks of TiKV
+	incremental  bool   // transfer only the files of the packages which differ on the hosts
+
+	denySharedLogDev bool // fail rather than warn if the data and log directories of a TiKV instance are on the same disk
+	fixNproc         bool // raise the max user processes of the deploy user to the minimum
+
+	symlinkTargets []string // the directories the symlinked deploy, data and log directories may point into
+
+	hardwareTolerance float64 // the max ratio the hardware of a node deviates from the others of the component
+
+	pinnedSources map[string]string // the expected URLs of the artifacts by component:version
+
+	skipLogRotate bool                  // don't install the logrotate configs of the instances
+	logRotate     task.LogRotateOptions // the retention of the rotated logs
 }
 
 func newDeploy() *cobra.Command {
@@ -80,6 +109,27 @@ func newDeploy() *cobra.Command {
 
 	cmd.Flags().StringVar(&opt.user, "user", "root", "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVar(&opt.planFile, "plan", "", "Export the remote commands and file transfers to the file (JSON if it ends with .json, otherwise YAML) instead of deploying")
+	cmd.Flags().BoolVar(&opt.cleanup, "cleanup-leftovers", false, "Kill the processes in the deploy directories listening on the ports of the cluster and remove the partial files left by a previous failed deploy, the other processes on the ports are only reported")
+	cmd.Flags().BoolVar(&opt.reuseData, "allow-non-empty-data-dir", false, "Deploy onto the data directories which are not empty, e.g. to reuse the data intentionally")
+	cmd.Flags().StringSliceVar(&opt.symlinkTargets, "allowed-symlink-target", nil, "The directories the symlinked deploy, data and log directories are allowed to point into, e.g: /data1,/data2")
+	cmd.Flags().StringVar(&opt.timezone, "timezone", "", "The expected timezone of the hosts, e.g: Asia/Shanghai, the most common one of the hosts is expected if not specified")
+	cmd.Flags().BoolVar(&opt.fixTimezone, "fix-timezone", false, "Set the timezone of the hosts not in the expected one by timedatectl")
+	cmd.Flags().BoolVar(&opt.fixHostname, "fix-hostname", false, "Set unique hostnames by hostnamectl to the hosts whose hostnames are the same as another host")
+	cmd.Flags().StringToStringVar(&opt.pinnedSources, "pin-source", nil, "Fail if the artifacts are not resolved to the pinned URLs, e.g: tikv:v4.0.0=https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz")
+	cmd.Flags().BoolVar(&opt.allowSELinux, "allow-selinux-enforcing", false, "Deploy to the hosts where SELinux is enforcing, the policies must permit the components")
+	cmd.Flags().BoolVar(&opt.fixSELinux, "fix-selinux", false, "Set SELinux to permissive on the hosts where it's enforcing")
+	cmd.Flags().BoolVar(&opt.skipLogRotate, "skip-log-rotate", false, "Don't install the logrotate configs to rotate the logs of the instances")
+	cmd.Flags().StringVar(&opt.logRotate.MaxSize, "log-rotate-size", "100M", "The size a log is rotated at")
+	cmd.Flags().IntVar(&opt.logRotate.MaxAge, "log-rotate-age", 7, "The days the rotated logs are kept")
+	cmd.Flags().IntVar(&opt.logRotate.Keep, "log-rotate-keep", 10, "The max number of the rotated logs kept of each log")
+	cmd.Flags().Float64Var(&opt.hardwareTolerance, "hardware-tolerance", 0.2, "Warn about the nodes whose CPU count, memory or disk size deviates from the median of the same component by more than the ratio")
+	cmd.Flags().BoolVar(&opt.fixSwap, "fix-swap", false, "Disable the swap of the hosts at runtime and after reboot")
+	cmd.Flags().BoolVar(&opt.fixBlockDev, "fix-block-device", false, "Set the I/O scheduler and the read-ahead of the data disks of TiKV, and persist them by udev rules")
+	cmd.Flags().BoolVar(&opt.fixNproc, "fix-nproc", false, fmt.Sprintf("Raise the max user processes of the deploy user to %d by the PAM limits on the hosts where it's lower", task.MinNproc))
+	cmd.Flags().BoolVar(&opt.denySharedLogDev, "deny-shared-log-device", false, "Fail rather than warn if the data_dir and log_dir of a TiKV instance are on the same disk")
+	cmd.Flags().BoolVar(&opt.fixFirewall, "fix-firewall", false, "Add firewall rules to permit the ports used by the cluster if they are blocked by firewalld or iptables")
+	cmd.Flags().BoolVar(&opt.incremental, "incremental", false, "Transfer only the files of the packages which differ from the ones on the hosts by checksum, e.g. to redeploy onto the existing deploy directories")
 
 	return cmd
 }
@@ -416,6 +466,7 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 					sshConnProps.IdentityFilePassphrase,
 					sshTimeout,
 				).
+				CheckDeployUser(inst.GetHost(), globalOptions.User).
 				EnvInit(inst.GetHost(), globalOptions.User).
 				UserSSH(inst.GetHost(), inst.GetSSHPort(), globalOptions.User, sshTimeout).
 				Mkdir(globalOptions.User, inst.GetHost(), dirs...).
@@ -428,26 +479,48 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 	// Download missing component
 	downloadCompTasks = buildDownloadCompTasks(clusterVersion, &topo)
 
+	// Check the binaries can be executed on one host of each component
+	checkBinaryTasks := buildCheckBinaryTasks(clusterVersion, &topo, globalOptions.User)
+	checkFirewallTasks := buildCheckFirewallTasks(&topo, opt.fixFirewall)
+	checkOSTasks := buildCheckOSTasks(&topo)
+	scanLeftoverTasks := buildScanLeftoverTasks(&topo, globalOptions.User, opt.cleanup)
+	checkDataDirTasks := buildCheckDataDirTasks(&topo, globalOptions.User, opt.reuseData)
+	checkNUMATasks := buildCheckNUMATasks(&topo)
+	checkPortRangeTasks := buildCheckPortRangeTasks(&topo)
+	checkBlockDeviceTasks := buildCheckBlockDeviceTasks(&topo, globalOptions.User, opt.fixBlockDev)
+	checkLogDeviceTasks := buildCheckLogDeviceTasks(&topo, globalOptions.User, opt.denySharedLogDev)
+	checkUtilityTasks := buildCheckUtilityTasks(&topo, opt.skipLogRotate)
+	checkSymlinkTasks := buildCheckSymlinkTasks(&topo, globalOptions.User, opt.symlinkTargets)
+	reachHosts, reachPorts := hostUsedPorts(&topo)
+
 	// Deploy components to remote
+	var rotateEntries []task.LogRotateEntry
 	topo.IterInstance(func(inst meta.Instance) {
 		version := bindversion.ComponentVersion(inst.ComponentName(), clusterVersion)
 		deployDir := clusterutil.Abs(globalOptions.User, inst.DeployDir())
 		// data dir would be empty for components which don't need it
 		dataDir := inst.DataDir()
 		if dataDir != "" {
-			clusterutil.Abs(globalOptions.User, dataDir)
+			dataDir = clusterutil.Abs(globalOptions.User, dataDir)
 		}
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(globalOptions.User, inst.LogDir())
+		rotateEntries = append(rotateEntries, task.LogRotateEntry{
+			Host:      inst.GetHost(),
+			Component: inst.ComponentName(),
+			Name:      strings.TrimSuffix(inst.ServiceName(), ".service"),
+			LogDir:    logDir,
+		})
 		// Deploy component
-		t := task.NewBuilder().
+		b := task.NewBuilder().
+			Incremental(opt.incremental).
 			Mkdir(globalOptions.User, inst.GetHost(),
 				deployDir, dataDir, logDir,
 				filepath.Join(deployDir, "bin"),
 				filepath.Join(deployDir, "conf"),
 				filepath.Join(deployDir, "scripts")).
 			CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir).
-			InitConfig(
+			CheckedInitConfig(
 				clusterName,
 				clusterVersion,
 				inst,
@@ -459,8 +532,12 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 					Cache:  meta.ClusterPath(clusterName, "config"),
 				},
 			).
-			BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost()))
-		deployCompTasks = append(deployCompTasks, t)
+			DirPermission(globalOptions.User, inst.GetHost(), "755", deployDir, dataDir, logDir)
+		switch inst.ComponentName() {
+		case meta.ComponentTiKV, meta.ComponentTiFlash:
+			b.VerifyNUMABinding(inst, deployDir)
+		}
+		deployCompTasks = append(deployCompTasks, b.BuildAsStep(fmt.Sprintf("  - Copy %s -> %s", inst.ComponentName(), inst.GetHost())))
 	})
 
 	// Deploy monitor relevant components to remote
@@ -470,19 +547,78 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		globalOptions,
 		topo.MonitoredOptions,
 		clusterVersion,
+		opt.incremental,
 	)
 	downloadCompTasks = append(downloadCompTasks, dlTasks...)
 	deployCompTasks = append(deployCompTasks, dpTasks...)
 
-	t := task.NewBuilder().
+	b := task.NewBuilder().
+		Step("+ Validate configs",
+			task.NewBuilder().
+				ValidateConfig(&topo, clusterVersion).
+				ValidateLabels(&topo, "").
+				ValidateTiFlashConfig(&topo, globalOptions.User).
+				Build()).
 		Step("+ Generate SSH keys",
 			task.NewBuilder().SSHKeyGen(meta.ClusterPath(clusterName, "ssh", "id_rsa")).Build()).
 		ParallelStep("+ Download TiDB components", downloadCompTasks...).
 		ParallelStep("+ Initialize target host environments", envInitTasks...).
-		ParallelStep("+ Copy files", deployCompTasks...).
-		Build()
+		ParallelStep("+ Check required utilities", checkUtilityTasks...).
+		ParallelStep("+ Scan leftovers of previous deploy", scanLeftoverTasks...).
+		ParallelStep("+ Check data directories", checkDataDirTasks...).
+		ParallelStep("+ Check symlinked directories", checkSymlinkTasks...).
+		ParallelStep("+ Check OS compatibility", checkOSTasks...).
+		ParallelStep("+ Check NUMA nodes", checkNUMATasks...).
+		ParallelStep("+ Check data disks", checkBlockDeviceTasks...).
+		ParallelStep("+ Check log disks", checkLogDeviceTasks...).
+		ParallelStep("+ Check binaries", checkBinaryTasks...).
+		ParallelStep("+ Check firewall", checkFirewallTasks...).
+		ParallelStep("+ Check ephemeral port range", checkPortRangeTasks...).
+		Step("+ Check DNS resolution",
+			task.NewBuilder().CheckDNS(reachHosts).Build()).
+		Step("+ Check reachability between hosts",
+			task.NewBuilder().CheckReachability(reachHosts, reachPorts, reachabilityMaxPeers).Build()).
+		Step("+ Check timezone",
+			task.NewBuilder().CheckTimezone(reachHosts, opt.timezone, opt.fixTimezone).Build()).
+		Step("+ Check hostnames",
+			task.NewBuilder().CheckHostname(reachHosts, opt.fixHostname).Build()).
+		Step("+ Check security modules",
+			task.NewBuilder().CheckSecurityModule(reachHosts, opt.allowSELinux, opt.fixSELinux).Build()).
+		Step("+ Check swap",
+			task.NewBuilder().CheckSwap(reachHosts, opt.fixSwap).Build()).
+		Step("+ Check max user processes",
+			task.NewBuilder().CheckNproc(reachHosts, globalOptions.User, opt.fixNproc).Build()).
+		Step("+ Check hardware",
+			task.NewBuilder().CheckHardware(hardwareGroups(&topo, globalOptions.User), opt.hardwareTolerance).Build()).
+		Step("+ Check OS distributions",
+			task.NewBuilder().CheckOSConsistency(componentHosts(&topo)).Build()).
+		Extensions(task.PhasePreDeploy, selectedInstances(&topo, operator.Options{})).
+		Breakpoint("deploying the files",
+			fmt.Sprintf("Cluster: %s", clusterName),
+			fmt.Sprintf("Version: %s", clusterVersion),
+			fmt.Sprintf("Deploy user: %s", globalOptions.User),
+			fmt.Sprintf("Instances: %s", instancesSummary(&topo))).
+		ParallelStep("+ Copy files", deployCompTasks...)
+	if !opt.skipLogRotate {
+		b.Step("+ Set up log rotation", task.NewBuilder().LogRotate(rotateEntries, opt.logRotate).Build())
+	}
+	t := b.Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := runValidationHook("deploy", clusterName, clusterVersion, nil, &topo, t); err != nil {
+		return err
+	}
+
+	ctx := newTaskContext()
+	// The prechecks read the same information of the hosts
+	ctx.EnableCommandCache()
+	ctx.PinArtifactSources(opt.pinnedSources)
+	if opt.planFile != "" {
+		// Nothing is left for the cluster as it's not deployed
+		defer os.RemoveAll(meta.ClusterPath(clusterName))
+		ctx.SetPlan(task.NewPlan())
+	}
+
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -490,10 +626,19 @@ func deploy(clusterName, clusterVersion, topoFile string, opt deployOptions) err
 		return errors.Trace(err)
 	}
 
+	if plan := ctx.Plan(); plan != nil {
+		if err := plan.WriteFile(opt.planFile); err != nil {
+			return err
+		}
+		log.Infof("Exported the plan of deploying cluster `%s` to %s", clusterName, opt.planFile)
+		return nil
+	}
+
 	err = meta.SaveClusterMeta(clusterName, &meta.ClusterMeta{
-		User:     globalOptions.User,
-		Version:  clusterVersion,
-		Topology: &topo,
+		User:            globalOptions.User,
+		Version:         clusterVersion,
+		Topology:        &topo,
+		ArtifactSources: ctx.ArtifactSources(),
 	})
 	if err != nil {
 		return errors.Trace(err)
@@ -520,12 +665,367 @@ func buildDownloadCompTasks(version string, topo *meta.Specification) []*task.St
 	return tasks
 }
 
+// buildCheckBinaryTasks checks the binary of each component on the first host of it, in the
+// deploy directory of the first instance
+func buildCheckBinaryTasks(version string, topo *meta.Specification, user string) []*task.StepDisplay {
+	var tasks []*task.StepDisplay
+	topo.IterComponent(func(comp meta.Component) {
+		if len(comp.Instances()) < 1 {
+			return
+		}
+		version := bindversion.ComponentVersion(comp.Name(), version)
+		inst := comp.Instances()[0]
+		host := inst.GetHost()
+		t := task.NewBuilder().
+			CheckBinary(comp.Name(), version, host, user, clusterutil.Abs(user, inst.DeployDir())).
+			BuildAsStep(fmt.Sprintf("  - Check %s:%s -> %s", comp.Name(), version, host))
+		tasks = append(tasks, t)
+	})
+	return tasks
+}
+
+// buildCheckOSTasks checks the OS of each host against all the components deployed on it,
+// and the host is managed by systemd
+func buildCheckOSTasks(topo *meta.Specification) []*task.StepDisplay {
+	var hosts []string
+	hostComponents := map[string][]string{}
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostComponents[host]; !found {
+			hosts = append(hosts, host)
+		}
+		for _, comp := range hostComponents[host] {
+			if comp == inst.ComponentName() {
+				return
+			}
+		}
+		hostComponents[host] = append(hostComponents[host], inst.ComponentName())
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckOS(host, hostComponents[host]).
+			CheckInitSystem(host).
+			CheckClocksource(host).
+			CheckTimeSync(host).
+			BuildAsStep(fmt.Sprintf("  - Check OS -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// hostUsedPorts returns the hosts in the order of the instances and the ports of all
+// the instances and the monitoring agents on each host
+func hostUsedPorts(topo *meta.Specification) ([]string, map[string][]int) {
+	var hosts []string
+	hostPorts := map[string][]int{}
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostPorts[host]; !found {
+			hosts = append(hosts, host)
+			hostPorts[host] = []int{topo.MonitoredOptions.NodeExporterPort, topo.MonitoredOptions.BlackboxExporterPort}
+		}
+		hostPorts[host] = append(hostPorts[host], inst.UsedPorts()...)
+	})
+	return hosts, hostPorts
+}
+
+// buildScanLeftoverTasks scans the stray processes on the ports of the cluster and the partial
+// files in the deploy directories of each host
+func buildScanLeftoverTasks(topo *meta.Specification, user string, clean bool) []*task.StepDisplay {
+	var hosts []string
+	hostUnits := map[string]map[int]string{}
+	hostDirs := map[string][]string{}
+	monitored := topo.MonitoredOptions
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostUnits[host]; !found {
+			hosts = append(hosts, host)
+			hostUnits[host] = map[int]string{
+				monitored.NodeExporterPort:     fmt.Sprintf("%s-%d.service", meta.ComponentNodeExporter, monitored.NodeExporterPort),
+				monitored.BlackboxExporterPort: fmt.Sprintf("%s-%d.service", meta.ComponentBlackboxExporter, monitored.BlackboxExporterPort),
+			}
+			hostDirs[host] = []string{clusterutil.Abs(user, monitored.DeployDir)}
+		}
+		for _, port := range inst.UsedPorts() {
+			hostUnits[host][port] = inst.ServiceName()
+		}
+		hostDirs[host] = append(hostDirs[host], clusterutil.Abs(user, inst.DeployDir()))
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			ScanLeftover(host, hostUnits[host], hostDirs[host], clean).
+			BuildAsStep(fmt.Sprintf("  - Scan leftovers -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckNUMATasks checks the NUMA nodes bound by the TiKV and TiFlash instances exist on each host
+func buildCheckNUMATasks(topo *meta.Specification) []*task.StepDisplay {
+	var hosts []string
+	hostNodes := map[string]map[string]string{}
+	topo.IterInstance(func(inst meta.Instance) {
+		node := task.NUMANode(inst)
+		if node == "" {
+			return
+		}
+		host := inst.GetHost()
+		if _, found := hostNodes[host]; !found {
+			hosts = append(hosts, host)
+			hostNodes[host] = map[string]string{}
+		}
+		hostNodes[host][inst.ID()] = node
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckNUMA(host, hostNodes[host]).
+			BuildAsStep(fmt.Sprintf("  - Check NUMA nodes -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckUtilityTasks checks the utilities used by the later steps of the deploy are installed
+// on each host, numactl is only required by the hosts of the instances bound to NUMA nodes
+func buildCheckUtilityTasks(topo *meta.Specification, skipLogRotate bool) []*task.StepDisplay {
+	required := []string{"tar", "ss", "timedatectl"}
+	if !skipLogRotate {
+		required = append(required, "logrotate")
+	}
+
+	var hosts []string
+	hostNUMA := map[string]bool{}
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostNUMA[host]; !found {
+			hosts = append(hosts, host)
+		}
+		hostNUMA[host] = hostNUMA[host] || task.NUMANode(inst) != ""
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		utilities := append([]string{}, required...)
+		if hostNUMA[host] {
+			utilities = append(utilities, "numactl")
+		}
+		t := task.NewBuilder().
+			CheckUtility(host, utilities).
+			BuildAsStep(fmt.Sprintf("  - Check required utilities -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckDataDirTasks checks the data directories of all the instances on each host are
+// empty or absent, a TiFlash instance may have several of them
+func buildCheckDataDirTasks(topo *meta.Specification, user string, allowNonEmpty bool) []*task.StepDisplay {
+	var hosts []string
+	hostDirs := map[string][]string{}
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostDirs[host]; !found {
+			hosts = append(hosts, host)
+			hostDirs[host] = []string{}
+		}
+		for _, dir := range strings.Split(inst.DataDir(), ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				hostDirs[host] = append(hostDirs[host], clusterutil.Abs(user, dir))
+			}
+		}
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		if len(hostDirs[host]) == 0 {
+			continue
+		}
+		t := task.NewBuilder().
+			CheckDataDir(host, hostDirs[host], allowNonEmpty).
+			BuildAsStep(fmt.Sprintf("  - Check data directories -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckBlockDeviceTasks checks the I/O scheduler and the read-ahead of the disks backing
+// the data directories of the TiKV instances on each host
+func buildCheckBlockDeviceTasks(topo *meta.Specification, user string, fix bool) []*task.StepDisplay {
+	var hosts []string
+	hostDirs := map[string][]string{}
+	for _, inst := range (&meta.TiKVComponent{Specification: topo}).Instances() {
+		host := inst.GetHost()
+		if _, found := hostDirs[host]; !found {
+			hosts = append(hosts, host)
+		}
+		hostDirs[host] = append(hostDirs[host], clusterutil.Abs(user, inst.DataDir()))
+	}
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckBlockDevice(host, hostDirs[host], fix).
+			BuildAsStep(fmt.Sprintf("  - Check data disks -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckLogDeviceTasks checks the data and log directories of each TiKV instance are on
+// separate disks
+func buildCheckLogDeviceTasks(topo *meta.Specification, user string, strict bool) []*task.StepDisplay {
+	var hosts []string
+	hostBuilders := map[string]*task.Builder{}
+	for _, inst := range (&meta.TiKVComponent{Specification: topo}).Instances() {
+		host := inst.GetHost()
+		if _, found := hostBuilders[host]; !found {
+			hosts = append(hosts, host)
+			hostBuilders[host] = task.NewBuilder()
+		}
+		hostBuilders[host].CheckLogDevice(host, inst.ID(), clusterutil.Abs(user, inst.DataDir()), clusterutil.Abs(user, inst.LogDir()), strict)
+	}
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		tasks = append(tasks, hostBuilders[host].BuildAsStep(fmt.Sprintf("  - Check log disks -> %s", host)))
+	}
+	return tasks
+}
+
+// buildCheckSymlinkTasks checks the deploy, data and log directories of all the instances on
+// each host are not redirected by symlinks to unexpected targets
+func buildCheckSymlinkTasks(topo *meta.Specification, user string, allowed []string) []*task.StepDisplay {
+	var hosts []string
+	hostDirs := map[string][]string{}
+	seen := map[string]bool{}
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostDirs[host]; !found {
+			hosts = append(hosts, host)
+			hostDirs[host] = []string{}
+		}
+		dirs := append([]string{inst.DeployDir(), inst.LogDir()}, strings.Split(inst.DataDir(), ",")...)
+		for _, dir := range dirs {
+			if dir = strings.TrimSpace(dir); dir == "" {
+				continue
+			}
+			dir = clusterutil.Abs(user, dir)
+			if key := host + ":" + dir; !seen[key] {
+				seen[key] = true
+				hostDirs[host] = append(hostDirs[host], dir)
+			}
+		}
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckSymlink(host, hostDirs[host], allowed).
+			BuildAsStep(fmt.Sprintf("  - Check symlinked directories -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// hardwareGroups returns the hosts of the components balanced by PD or the load balancer with
+// the data directory of the first instance on each host, the hardware of them should be close
+func hardwareGroups(topo *meta.Specification, user string) map[string][]task.HardwareNode {
+	groups := map[string][]task.HardwareNode{}
+	topo.IterInstance(func(inst meta.Instance) {
+		comp := inst.ComponentName()
+		switch comp {
+		case meta.ComponentTiKV, meta.ComponentTiFlash, meta.ComponentTiDB, meta.ComponentPD:
+		default:
+			return
+		}
+		for _, node := range groups[comp] {
+			if node.Host == inst.GetHost() {
+				return
+			}
+		}
+		node := task.HardwareNode{Host: inst.GetHost()}
+		if dataDir := strings.Split(inst.DataDir(), ",")[0]; dataDir != "" {
+			node.DataDir = clusterutil.Abs(user, strings.TrimSpace(dataDir))
+		}
+		groups[comp] = append(groups[comp], node)
+	})
+	return groups
+}
+
+// componentHosts returns the distinct hosts of each component
+func componentHosts(topo *meta.Specification) map[string][]string {
+	groups := map[string][]string{}
+	seen := map[string]set.StringSet{}
+	topo.IterInstance(func(inst meta.Instance) {
+		comp := inst.ComponentName()
+		if seen[comp] == nil {
+			seen[comp] = set.NewStringSet()
+		}
+		if seen[comp].Exist(inst.GetHost()) {
+			return
+		}
+		seen[comp].Insert(inst.GetHost())
+		groups[comp] = append(groups[comp], inst.GetHost())
+	})
+	return groups
+}
+
+// buildCheckPortRangeTasks checks the ports of all the instances and the monitoring agents
+// on each host are out of the ephemeral port range
+func buildCheckPortRangeTasks(topo *meta.Specification) []*task.StepDisplay {
+	var hosts []string
+	hostPorts := map[string]map[int]string{}
+	monitored := topo.MonitoredOptions
+	topo.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		if _, found := hostPorts[host]; !found {
+			hosts = append(hosts, host)
+			hostPorts[host] = map[int]string{
+				monitored.NodeExporterPort:     meta.ComponentNodeExporter,
+				monitored.BlackboxExporterPort: meta.ComponentBlackboxExporter,
+			}
+		}
+		for _, port := range inst.UsedPorts() {
+			hostPorts[host][port] = inst.ID()
+		}
+	})
+
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckPortRange(host, hostPorts[host]).
+			BuildAsStep(fmt.Sprintf("  - Check ephemeral port range -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// buildCheckFirewallTasks checks the ports of all the instances and the monitoring agents on each host
+func buildCheckFirewallTasks(topo *meta.Specification, fix bool) []*task.StepDisplay {
+	hosts, hostPorts := hostUsedPorts(topo)
+	var tasks []*task.StepDisplay
+	for _, host := range hosts {
+		t := task.NewBuilder().
+			CheckFirewall(host, hostPorts[host], fix).
+			BuildAsStep(fmt.Sprintf("  - Check firewall -> %s", host))
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
 func buildMonitoredDeployTask(
 	clusterName string,
 	uniqueHosts map[string]int, // host -> ssh-port
 	globalOptions meta.GlobalOptions,
 	monitoredOptions meta.MonitoredOptions,
-	version string) (downloadCompTasks []*task.StepDisplay, deployCompTasks []*task.StepDisplay) {
+	version string,
+	incremental bool) (downloadCompTasks []*task.StepDisplay, deployCompTasks []*task.StepDisplay) {
 	for _, comp := range []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter} {
 		version := bindversion.ComponentVersion(comp, version)
 		t := task.NewBuilder().
@@ -538,13 +1038,14 @@ func buildMonitoredDeployTask(
 			// data dir would be empty for components which don't need it
 			dataDir := monitoredOptions.DataDir
 			if dataDir != "" {
-				clusterutil.Abs(globalOptions.User, dataDir)
+				dataDir = clusterutil.Abs(globalOptions.User, dataDir)
 			}
 			// log dir will always be with values, but might not used by the component
 			logDir := clusterutil.Abs(globalOptions.User, monitoredOptions.LogDir)
 
 			// Deploy component
 			t := task.NewBuilder().
+				Incremental(incremental).
 				UserSSH(host, sshPort, globalOptions.User, sshTimeout).
 				Mkdir(globalOptions.User, host,
 					deployDir, dataDir, logDir,
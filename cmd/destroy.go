@@ -30,6 +30,7 @@ import (
 )
 
 func newDestroyCmd() *cobra.Command {
+	var options operator.Options
 	cmd := &cobra.Command{
 		Use:   "destroy <cluster-name>",
 		Short: "Destroy a specified cluster",
@@ -64,11 +65,15 @@ func newDestroyCmd() *cobra.Command {
 					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, operator.Options{}).
+				ClusterOperate(metadata.Topology, operator.StopOperation, options).
 				ClusterOperate(metadata.Topology, operator.DestroyOperation, operator.Options{}).
 				Build()
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			if err := runValidationHook("destroy", clusterName, metadata.Version, nil, metadata.Topology, t); err != nil {
+				return err
+			}
+
+			if err := t.Execute(newTaskContext()); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
@@ -84,5 +89,7 @@ func newDestroyCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Int64Var(&options.GracePeriod, "grace-period", 30, "Seconds waited for an instance to exit after SIGTERM before killing it by SIGKILL")
+
 	return cmd
 }
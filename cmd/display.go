@@ -23,7 +23,6 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
-	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/set"
 	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
@@ -95,7 +94,7 @@ func destroyTombsomeIfNeed(clusterName string, metadata *meta.ClusterMeta) error
 		return nil
 	}
 
-	ctx := task.NewContext()
+	ctx := newTaskContext()
 	err := ctx.SetSSHKeySet(meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
@@ -141,7 +140,7 @@ func displayClusterTopology(opt *displayOption) error {
 		{"ID", "Role", "Host", "Ports", "Status", "Data Dir", "Deploy Dir"},
 	}
 
-	ctx := task.NewContext()
+	ctx := newTaskContext()
 	err = ctx.SetSSHKeySet(meta.ClusterPath(opt.clusterName, "ssh", "id_rsa"),
 		meta.ClusterPath(opt.clusterName, "ssh", "id_rsa.pub"))
 	if err != nil {
@@ -215,6 +214,7 @@ func displayClusterTopology(opt *displayOption) error {
 	})
 
 	cliutil.PrintTable(clusterTable, true)
+	warnSchedulingPaused(opt.clusterName)
 
 	return nil
 }
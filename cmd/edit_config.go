@@ -120,6 +120,18 @@ func editTopo(clusterName string, metadata *meta.ClusterMeta) error {
 
 	log.Infof("Apply the change...")
 
+	// keep the topology running in the cluster, so that the reload only touches the
+	// instances changed since then
+	applied, err := meta.AppliedTopology(clusterName)
+	if err != nil {
+		return err
+	}
+	if applied == nil {
+		if err := meta.SaveAppliedTopology(clusterName, metadata.Topology); err != nil {
+			return errors.Annotate(err, "failed to save the applied topology")
+		}
+	}
+
 	metadata.Topology = newTopo
 	err = meta.SaveClusterMeta(clusterName, metadata)
 	if err != nil {
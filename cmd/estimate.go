@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/bindversion"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	tiupmeta "github.com/pingcap-incubator/tiup/pkg/meta"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newEstimateCmd() *cobra.Command {
+	var (
+		mirrorThroughput float64 // MiB/s
+		hostThroughput   float64 // MiB/s
+		hostOverhead     time.Duration
+		parallelism      int
+	)
+	cmd := &cobra.Command{
+		Use:   "estimate <version> <topology.yaml>",
+		Short: "Estimate the time of deploying a cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			var topo meta.TopologySpecification
+			if err := utils.ParseTopologyYaml(args[1], &topo); err != nil {
+				return err
+			}
+
+			est := operator.EstimateDeploy(&topo, packageInfos(args[0], &topo), operator.EstimateOptions{
+				MirrorThroughput: int64(mirrorThroughput * 1024 * 1024),
+				HostThroughput:   int64(hostThroughput * 1024 * 1024),
+				HostOverhead:     hostOverhead,
+				Parallelism:      parallelism,
+			})
+
+			rows := [][]string{{"Phase", "Duration", "Detail"}}
+			for _, phase := range est.Phases {
+				rows = append(rows, []string{phase.Name, phase.Duration.Round(time.Second).String(), phase.Detail})
+			}
+			rows = append(rows, []string{"Total", est.Total.Round(time.Second).String(), ""})
+			cliutil.PrintTable(rows, true)
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64Var(&mirrorThroughput, "mirror-throughput", 10, "Assumed throughput in MiB/s when downloading the components from the mirror")
+	cmd.Flags().Float64Var(&hostThroughput, "host-throughput", 50, "Assumed throughput in MiB/s when copying the files to each host")
+	cmd.Flags().DurationVar(&hostOverhead, "host-overhead", 3*time.Second, "Assumed fixed cost of each step on a host over SSH")
+	cmd.Flags().IntVar(&parallelism, "parallel", 0, "Max number of hosts deployed at the same time, 0 means all the hosts")
+
+	return cmd
+}
+
+// packageInfos returns the sizes of the packages of the components used by the topology, the
+// size is got from the local cache, or the mirror if the package is not cached and the
+// mirror is not forbidden by --offline
+func packageInfos(version string, topo *meta.Specification) map[string]operator.PackageInfo {
+	components := []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter}
+	topo.IterComponent(func(comp meta.Component) {
+		if len(comp.Instances()) > 0 {
+			components = append(components, comp.Name())
+		}
+	})
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	mirror := tiupmeta.Mirror()
+	packages := make(map[string]operator.PackageInfo)
+	for _, comp := range components {
+		fileName := fmt.Sprintf("%s-%s-linux-amd64.tar.gz", comp, bindversion.ComponentVersion(comp, version))
+		if fi, err := os.Stat(meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName)); err == nil {
+			packages[comp] = operator.PackageInfo{Size: fi.Size(), Cached: true}
+			continue
+		}
+		if offline {
+			log.Warnf("The size of %s is unknown and not estimated as it's not cached", fileName)
+			continue
+		}
+		size, err := mirrorFileSize(client, mirror, fileName)
+		if err != nil {
+			log.Warnf("The size of %s is unknown and not estimated: %s", fileName, err)
+			continue
+		}
+		packages[comp] = operator.PackageInfo{Size: size}
+	}
+	return packages
+}
+
+// mirrorFileSize returns the size of the file in the mirror, which is a local directory or
+// a HTTP server
+func mirrorFileSize(client *http.Client, mirror, fileName string) (int64, error) {
+	if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
+		fi, err := os.Stat(filepath.Join(mirror, fileName))
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	}
+
+	res, err := client.Head(strings.TrimSuffix(mirror, "/") + "/" + fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.ContentLength < 0 {
+		return 0, errors.Errorf("unexpected response %s of %s", res.Status, res.Request.URL)
+	}
+	return res.ContentLength, nil
+}
@@ -16,6 +16,7 @@ package cmd
 import (
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
@@ -29,6 +30,7 @@ import (
 type execOptions struct {
 	command string
 	sudo    bool
+	dir     string
 	roles   []string
 	nodes   []string
 }
@@ -73,11 +75,15 @@ func newExecCmd() *cobra.Command {
 				}
 			})
 
+			deployDir := clusterutil.Abs(metadata.User, metadata.Topology.GlobalOptions.DeployDir)
 			for host := range uniqueHosts {
-				shellTasks = append(shellTasks,
-					task.NewBuilder().
-						Shell(host, opt.command, opt.sudo).
-						Build())
+				b := task.NewBuilder()
+				if opt.dir != "" {
+					b.ShellInDir(host, deployDir, opt.dir, opt.command, opt.sudo)
+				} else {
+					b.Shell(host, opt.command, opt.sudo)
+				}
+				shellTasks = append(shellTasks, b.Build())
 			}
 
 			t := task.NewBuilder().
@@ -88,7 +94,7 @@ func newExecCmd() *cobra.Command {
 				Parallel(shellTasks...).
 				Build()
 
-			execCtx := task.NewContext()
+			execCtx := newTaskContext()
 			if err := t.Execute(execCtx); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
@@ -120,6 +126,7 @@ func newExecCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&opt.command, "command", "ls", "the command run on cluster host")
 	cmd.Flags().BoolVar(&opt.sudo, "sudo", false, "use root permissions (default false)")
+	cmd.Flags().StringVar(&opt.dir, "dir", "", "the working directory of the command, must be inside the deploy directory")
 	cmd.Flags().StringSliceVarP(&opt.roles, "role", "R", nil, "Only exec on host with specified roles")
 	cmd.Flags().StringSliceVarP(&opt.nodes, "node", "N", nil, "Only exec on host with specified nodes")
 
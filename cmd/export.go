@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	var (
+		output         string
+		placementRules string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export <cluster-name>",
+		Short: "Export the current topology of a TiDB cluster to a YAML file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot export non-exists cluster %s", clusterName)
+			}
+
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				output = clusterName + "-topology.yaml"
+			}
+
+			tb := task.NewBuilder().ExportTopology(clusterName, metadata.Topology, output)
+			if placementRules != "" {
+				tb.ValidateLabels(metadata.Topology, placementRules)
+			}
+			t := tb.Build()
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			log.Infof("Exported topology of cluster `%s` to %s", clusterName, output)
+			if placementRules != "" {
+				log.Infof("Exported placement rules of cluster `%s` to %s", clusterName, placementRules)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The file to write the topology to (default \"<cluster-name>-topology.yaml\")")
+	cmd.Flags().StringVar(&placementRules, "placement-rules", "", "Also write the PD placement rules generated from the TiKV labels to the file as JSON")
+
+	return cmd
+}
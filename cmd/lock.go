@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/spf13/cobra"
+)
+
+// mutatingCommands are the commands changing the cluster of their first argument, the cluster
+// is locked during them so that the concurrent operations on it exclude each other
+var mutatingCommands = set.NewStringSet(
+	"deploy", "start", "stop", "restart", "reload", "upgrade", "scale-in", "scale-out",
+	"destroy", "edit-config", "patch", "replace-node", "set-store", "compact",
+	"transfer-monitor", "migrate-monitor", "push-config",
+	"pause-scheduling", "resume-scheduling", "exec",
+)
+
+// mutatingFlags are the flags making the otherwise read-only commands change the cluster,
+// the cluster is locked if they are set
+var mutatingFlags = map[string]string{
+	"verify-layout":    "repair",
+	"check-pd-members": "remove-stale",
+}
+
+// isMutating returns whether the command changes the cluster
+func isMutating(cmd *cobra.Command) bool {
+	if mutatingCommands.Exist(cmd.Name()) {
+		return true
+	}
+	flag, ok := mutatingFlags[cmd.Name()]
+	if !ok {
+		return false
+	}
+	set, err := cmd.Flags().GetBool(flag)
+	return err == nil && set
+}
+
+var (
+	forceLock     bool     // take the lock of the cluster over from the operation in progress
+	lockEndpoints []string // endpoints of the etcd storing the locks, the local files are used if empty
+
+	lockStoreOnce sync.Once
+	lockStore     meta.LockStore
+	lockStoreErr  error
+	etcdLockStore *meta.EtcdLockStore
+
+	operationLock *meta.ClusterLock // the lock held by the current command
+)
+
+// lockCluster locks the cluster for the operation in the lock store
+func lockCluster(clusterName, operation string) (*meta.ClusterLock, error) {
+	lockStoreOnce.Do(func() {
+		if len(lockEndpoints) == 0 {
+			lockStore = meta.DefaultLockStore()
+			return
+		}
+		etcdLockStore, lockStoreErr = meta.NewEtcdLockStore(lockEndpoints, nil)
+		lockStore = etcdLockStore
+	})
+	if lockStoreErr != nil {
+		return nil, lockStoreErr
+	}
+	if forceLock {
+		log.Warnf("Taking the lock of cluster `%s` over from the operation in progress if any", clusterName)
+	}
+	return meta.LockCluster(lockStore, clusterName, operation, forceLock)
+}
+
+// unlockCluster releases the lock, a failure is only logged as the lock can be taken over by
+// --force-lock
+func unlockCluster(lock *meta.ClusterLock) {
+	if lock == nil {
+		return
+	}
+	if err := lock.Unlock(); err != nil {
+		log.Warnf("Failed to release the lock of the cluster: %s", err)
+	}
+}
+
+// closeLockStore closes the connection to the etcd storing the locks
+func closeLockStore() {
+	if etcdLockStore != nil {
+		_ = etcdLockStore.Close()
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newLogsCmd() *cobra.Command {
+	var (
+		options  operator.Options
+		tailOpt  operator.TailOptions
+		nodeFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs <cluster-name>",
+		Short: "Follow the logs of the instances of a cluster",
+		Long: `Follow the logs of the selected instances of a cluster, the lines of all the logs are
+merged and prefixed with the component and the instance. Press Ctrl+C to stop.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot follow the logs of non-exists cluster %s", clusterName)
+			}
+
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+			if options.Nodes, err = nodesFromFile(metadata.Topology, nodeFile, options.Nodes); err != nil {
+				return err
+			}
+			instances := selectedInstances(metadata.Topology, options)
+			if len(instances) == 0 {
+				return errors.New("no instance is selected")
+			}
+
+			ctx := newTaskContext()
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				Build()
+			if err := t.Execute(ctx); err != nil {
+				if errorx.Cast(err) != nil {
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			tailCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sc := make(chan os.Signal, 1)
+			signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sc)
+			go func() {
+				select {
+				case <-sc:
+					cancel()
+				case <-tailCtx.Done():
+				}
+			}()
+
+			return operator.TailLogs(tailCtx, ctx, instances, metadata.User, tailOpt, os.Stdout)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only follow the logs of specified roles")
+	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only follow the logs of specified nodes")
+	cmd.Flags().StringVar(&nodeFile, "node-file", "", "File listing the hosts or nodes to follow the logs of, one per line, intersected with --node if both are given")
+	cmd.Flags().IntVarP(&tailOpt.Lines, "lines", "n", 10, "The number of the last lines of each log shown before following")
+	cmd.Flags().StringVar(&tailOpt.File, "file", "", "The name of the log file in the log directory, e.g. tikv_stderr.log, <component>.log by default")
+	cmd.Flags().IntVar(&tailOpt.MaxTails, "max-tails", operator.DefaultMaxTails, "The max number of the logs followed at the same time")
+	return cmd
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateMonitorCmd() *cobra.Command {
+	var nodes []string
+
+	cmd := &cobra.Command{
+		Use:   "migrate-monitor <cluster-name> <data-dir>",
+		Short: "Move the data of Prometheus to a new data directory",
+		Long: `Move the data of Prometheus to a new data directory. The space of the new
+directory is checked first, then Prometheus is stopped, the data is copied and
+verified, and Prometheus is started with the new directory. The original data
+is kept and should be removed manually.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot migrate non-exists cluster %s", clusterName)
+			}
+			if !filepath.IsAbs(args[1]) {
+				return errors.Errorf("the data directory %s must be an absolute path", args[1])
+			}
+
+			logger.EnableAuditLog()
+			return migrateMonitor(clusterName, filepath.Clean(args[1]), nodes)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&nodes, "node", "N", nil, "Only migrate specified Prometheus nodes")
+
+	return cmd
+}
+
+func migrateMonitor(clusterName, dataDir string, nodes []string) error {
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	nodeFilter := set.NewStringSet(nodes...)
+	var migrateTasks []task.Task
+	for i, inst := range (&meta.MonitorComponent{Specification: metadata.Topology}).Instances() {
+		if len(nodes) > 0 && !nodeFilter.Exist(inst.ID()) {
+			continue
+		}
+		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
+		// the data dir is relative to the deploy dir in the run script
+		srcDir := inst.DataDir()
+		if !filepath.IsAbs(srcDir) {
+			srcDir = filepath.Join(deployDir, srcDir)
+		}
+		if srcDir == dataDir {
+			return errors.Errorf("the data of %s is already in %s", inst.ID(), dataDir)
+		}
+
+		t := task.NewBuilder().
+			MigrateMonitorData(clusterName, metadata.Version, inst, metadata.User, srcDir, meta.DirPaths{
+				Deploy: deployDir,
+				Data:   dataDir,
+				Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+				Cache:  meta.ClusterPath(clusterName, "config"),
+			}).
+			Build()
+		migrateTasks = append(migrateTasks, t)
+
+		// the instances have copied the spec, so the new data dir is only saved to the meta
+		metadata.Topology.Monitors[i].DataDir = dataDir
+	}
+	if len(migrateTasks) == 0 {
+		return errors.Errorf("no Prometheus instance found on specified nodes(%v)", nodes)
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+		Parallel(migrateTasks...).
+		Build()
+
+	if err := runValidationHook("migrate-monitor", clusterName, metadata.Version, nodes, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+		return err
+	}
+	log.Infof("Migrated the monitoring data of cluster `%s` to %s successfully", clusterName, dataDir)
+	return nil
+}
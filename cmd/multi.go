@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+// multiOperated are the past tenses of the operations supported by multi
+var multiOperated = map[string]string{
+	"start":   "Started",
+	"stop":    "Stopped",
+	"restart": "Restarted",
+}
+
+func newMultiCmd() *cobra.Command {
+	var (
+		options  operator.Options
+		parallel int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "multi <start|stop|restart> <cluster-name>...",
+		Short: "Start, stop or restart multiple clusters concurrently",
+		Long: `Start, stop or restart multiple clusters concurrently, each cluster is operated
+in isolation from the others, and a failed cluster doesn't stop the others. The
+result of each cluster is reported at the end.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return cmd.Help()
+			}
+
+			operation := args[0]
+			var run task.ClusterRunner
+			switch operation {
+			case "start":
+				run = func(clusterName string, ctx *task.Context) error {
+					return startCluster(ctx, clusterName, "", options)
+				}
+			case "stop":
+				run = func(clusterName string, ctx *task.Context) error {
+					return stopCluster(ctx, clusterName, "", options)
+				}
+			case "restart":
+				run = func(clusterName string, ctx *task.Context) error {
+					return restartCluster(ctx, clusterName, "", options, false, nil)
+				}
+			default:
+				return errors.Errorf("unsupported operation %s, it must be one of start, stop and restart", operation)
+			}
+
+			var clusters []string
+			seen := set.NewStringSet()
+			for _, clusterName := range args[1:] {
+				if seen.Exist(clusterName) {
+					continue
+				}
+				seen.Insert(clusterName)
+				if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+					return errors.Errorf("cannot %s non-exists cluster %s", operation, clusterName)
+				}
+				clusters = append(clusters, clusterName)
+			}
+
+			// each cluster is locked only during its own operation
+			locked := func(clusterName string, ctx *task.Context) error {
+				lock, err := lockCluster(clusterName, "multi "+operation)
+				if err != nil {
+					return err
+				}
+				defer unlockCluster(lock)
+				return run(clusterName, ctx)
+			}
+			results := task.RunClusters(clusters, parallel, func(string) *task.Context {
+				return newTaskContext()
+			}, locked)
+
+			rows := [][]string{{"Cluster", "Result", "Duration", "Error"}}
+			for _, r := range results {
+				result, msg := "succeeded", ""
+				if r.Err != nil {
+					result, msg = "failed", strings.SplitN(r.Err.Error(), "\n", 2)[0]
+				}
+				rows = append(rows, []string{r.Cluster, result, r.Duration.Round(time.Second).String(), msg})
+			}
+			cliutil.PrintTable(rows, true)
+
+			failed := task.FailedClusters(results)
+			if len(failed) > 0 {
+				var names []string
+				for _, r := range failed {
+					names = append(names, r.Cluster)
+				}
+				return errors.Errorf("failed to %s %d of %d clusters: %s", operation, len(failed), len(results), strings.Join(names, ", "))
+			}
+
+			log.Infof("%s %d clusters successfully", multiOperated[operation], len(results))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only operate specified roles of each cluster")
+	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only operate specified nodes, the nodes not in a cluster are ignored by it")
+	cmd.Flags().IntVar(&parallel, "parallel", 4, "Max number of clusters operated at the same time, 0 means unlimited")
+	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when draining the changefeeds of the TiCDC captures, or waiting for the drainers to be synced")
+	cmd.Flags().BoolVar(&options.Force, "force", false, "Stop the instances without draining the TiCDC captures or waiting for the drainers to be synced")
+
+	return cmd
+}
@@ -35,8 +35,9 @@ import (
 
 func newPatchCmd() *cobra.Command {
 	var (
-		overwrite bool
-		options   operator.Options
+		overwrite   bool
+		incremental bool
+		options     operator.Options
 	)
 	cmd := &cobra.Command{
 		Use:   "patch <cluster-name> <package-path>",
@@ -48,11 +49,12 @@ func newPatchCmd() *cobra.Command {
 			if len(options.Nodes) == 0 && len(options.Roles) == 0 {
 				return errors.New("the flag -R or -N must be specified at least one")
 			}
-			return patch(args[0], args[1], options, overwrite)
+			return patch(args[0], args[1], options, overwrite, incremental)
 		},
 	}
 
 	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Use this package in the future scale-out operations")
+	cmd.Flags().BoolVar(&incremental, "incremental", false, "Transfer only the files of the package which differ from the ones on the hosts by checksum")
 	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Specify the nodes")
 	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Specify the role")
 	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
@@ -60,7 +62,7 @@ func newPatchCmd() *cobra.Command {
 	return cmd
 }
 
-func patch(clusterName, packagePath string, options operator.Options, overwrite bool) error {
+func patch(clusterName, packagePath string, options operator.Options, overwrite, incremental bool) error {
 	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		return errors.Errorf("cannot patch non-exists cluster %s", clusterName)
 	}
@@ -85,7 +87,7 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 	var replacePackageTasks []task.Task
 	for _, inst := range insts {
 		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
-		tb := task.NewBuilder()
+		tb := task.NewBuilder().Incremental(incremental)
 		tb.BackupComponent(inst.ComponentName(), metadata.Version, inst.GetHost(), deployDir).
 			InstallPackage(packagePath, inst.GetHost(), deployDir)
 		replacePackageTasks = append(replacePackageTasks, tb.Build())
@@ -100,7 +102,11 @@ func patch(clusterName, packagePath string, options operator.Options, overwrite
 		ClusterOperate(metadata.Topology, operator.UpgradeOperation, options).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := runValidationHook("patch", clusterName, metadata.Version, options.Nodes, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -148,6 +154,9 @@ func checkPackage(clusterName, comp, packagePath string) error {
 	if err != nil {
 		return err
 	}
+	if offline {
+		return task.ErrOfflineCacheMiss.New("The manifest of %s can't be fetched from the mirror to verify the package in the offline mode", comp)
+	}
 	manifest, err := tiupmeta.Repository().ComponentVersions(comp)
 	if err != nil {
 		return err
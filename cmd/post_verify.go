@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/bindversion"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+)
+
+// verifyAfter enables the verifications of the end state of the cluster after the operations
+// which opt into them
+var verifyAfter bool
+
+// runVerification verifies the cluster is in the intended state after the operation if
+// --verify is specified: all the instances are running the version of the cluster and are
+// healthy, and their deploy directories have the binaries and config files. The deviations
+// are returned as an error.
+func runVerification(operation, clusterName string) error {
+	if !verifyAfter {
+		return nil
+	}
+
+	// the metadata may be changed by the operation
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	var layoutTasks []task.Task
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		dataDir := inst.DataDir()
+		if dataDir != "" {
+			dataDir = clusterutil.Abs(metadata.User, dataDir)
+		}
+		t := task.NewBuilder().
+			VerifyLayout(
+				clusterName,
+				metadata.Version,
+				bindversion.ComponentVersion(inst.ComponentName(), metadata.Version),
+				inst,
+				metadata.User,
+				meta.DirPaths{
+					Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+					Data:   dataDir,
+					Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+					Cache:  meta.ClusterPath(clusterName, "config"),
+				},
+				false,
+			).
+			Build()
+		layoutTasks = append(layoutTasks, t)
+	})
+
+	log.Infof("Verifying cluster `%s` after the %s", clusterName, operation)
+	return task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+		VerifyOperation(operation, clusterName,
+			task.Verification{Name: "version", Task: task.NewBuilder().CheckVersion(metadata.Topology, metadata.Version).Build()},
+			task.Verification{Name: "health", Task: task.NewBuilder().CheckHealth(metadata.Topology).Build()},
+			task.Verification{Name: "layout", Task: task.NewBuilder().Parallel(layoutTasks...).Build()},
+		).
+		Build().
+		Execute(newTaskContext())
+}
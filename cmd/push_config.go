@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newPushConfigCmd() *cobra.Command {
+	var (
+		nodes []string
+		roles []string
+		units bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "push-config <cluster-name>",
+		Short: "Push the regenerated config of the instances without restarting them",
+		Long: `Render the config files and run scripts of the instances from the topology and push
+them to the hosts, then reload systemd. Nothing is restarted, and the version is not changed,
+so the new config takes effect on the next restart. The systemd units are pushed as well with
+--units. The files changed are reported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot push config to non-exists cluster %s", clusterName)
+			}
+
+			logger.EnableAuditLog()
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			nodeFilter := set.NewStringSet(nodes...)
+			roleFilter := set.NewStringSet(roles...)
+			var instances []meta.Instance
+			metadata.Topology.IterInstance(func(inst meta.Instance) {
+				if len(nodeFilter) > 0 && !nodeFilter.Exist(inst.ID()) {
+					return
+				}
+				if len(roleFilter) > 0 && !roleFilter.Exist(inst.ComponentName()) {
+					return
+				}
+				instances = append(instances, inst)
+			})
+			if len(instances) == 0 {
+				return errors.Errorf("no instance of cluster %s to push config to", clusterName)
+			}
+
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				PushConfig(clusterName, metadata.Version, metadata.User, instances, units).
+				Build()
+
+			if err := runValidationHook("push-config", clusterName, metadata.Version, nodes, metadata.Topology, t); err != nil {
+				return err
+			}
+
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&nodes, "node", "N", nil, "Only push config to specified nodes")
+	cmd.Flags().StringSliceVarP(&roles, "role", "R", nil, "Only push config to specified roles")
+	cmd.Flags().BoolVar(&units, "units", false, "Push the systemd units of the instances as well")
+
+	return cmd
+}
@@ -14,6 +14,8 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/joomcode/errorx"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/bindversion"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
@@ -22,13 +24,18 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
 	"github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/spf13/cobra"
 )
 
 func newReloadCmd() *cobra.Command {
-	var options operator.Options
+	var (
+		options  operator.Options
+		full     bool
+		nodeFile string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "reload <cluster-name>",
@@ -48,13 +55,42 @@ func newReloadCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if options.Nodes, err = nodesFromFile(metadata.Topology, nodeFile, options.Nodes); err != nil {
+				return err
+			}
 
-			t, err := buildReloadTask(clusterName, metadata, options)
+			// only the instances changed by edit-config are reloaded unless the nodes or
+			// roles are specified explicitly
+			var (
+				changed set.StringSet
+				applied *meta.TopologySpecification
+			)
+			partial := len(options.Roles) > 0 || len(options.Nodes) > 0
+			if !full && !partial {
+				applied, err = meta.AppliedTopology(clusterName)
+				if err != nil {
+					return err
+				}
+				if applied != nil {
+					options.Nodes = changedNodes(applied, metadata.Topology)
+					if len(options.Nodes) == 0 {
+						log.Infof("Nothing is changed since the last reload, use --full to reload all the instances")
+						return meta.RemoveAppliedTopology(clusterName)
+					}
+					changed = set.NewStringSet(options.Nodes...)
+				}
+			}
+
+			t, err := buildReloadTask(clusterName, metadata, options, applied, changed)
 			if err != nil {
 				return err
 			}
 
-			if err := t.Execute(task.NewContext()); err != nil {
+			if err := runValidationHook("reload", clusterName, metadata.Version, options.Nodes, metadata.Topology, t); err != nil {
+				return err
+			}
+
+			if err := t.Execute(newTaskContext()); err != nil {
 				if errorx.Cast(err) != nil {
 					// FIXME: Map possible task errors and give suggestions.
 					return err
@@ -62,14 +98,23 @@ func newReloadCmd() *cobra.Command {
 				return errors.Trace(err)
 			}
 
+			// all the changes are applied
+			if !partial {
+				if err := meta.RemoveAppliedTopology(clusterName); err != nil {
+					return err
+				}
+			}
+
 			log.Infof("Reloaded cluster `%s` successfully", clusterName)
 
-			return nil
+			return runVerification("reload", clusterName)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only start specified nodes")
+	cmd.Flags().StringVar(&nodeFile, "node-file", "", "File listing the hosts or nodes to reload, one per line, intersected with --node if both are given")
+	cmd.Flags().BoolVar(&full, "full", false, "Reload all the instances even if their configs are not changed")
 	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
 
 	return cmd
@@ -79,6 +124,8 @@ func buildReloadTask(
 	clusterName string,
 	metadata *meta.ClusterMeta,
 	options operator.Options,
+	applied *meta.TopologySpecification,
+	changed set.StringSet,
 ) (task.Task, error) {
 
 	var refreshConfigTasks []task.Task
@@ -86,11 +133,14 @@ func buildReloadTask(
 	topo := metadata.Topology
 
 	topo.IterInstance(func(inst meta.Instance) {
+		if changed != nil && !changed.Exist(inst.ID()) {
+			return
+		}
 		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
 		// data dir would be empty for components which don't need it
 		dataDir := inst.DataDir()
 		if dataDir != "" {
-			clusterutil.Abs(metadata.User, dataDir)
+			dataDir = clusterutil.Abs(metadata.User, dataDir)
 		}
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(metadata.User, inst.LogDir())
@@ -106,7 +156,7 @@ func buildReloadTask(
 		}
 
 		// Refresh all configuration
-		t := tb.InitConfig(clusterName,
+		t := tb.CheckedInitConfig(clusterName,
 			metadata.Version,
 			inst, metadata.User,
 			meta.DirPaths{
@@ -118,7 +168,12 @@ func buildReloadTask(
 		refreshConfigTasks = append(refreshConfigTasks, t)
 	})
 
-	t := task.NewBuilder().
+	b := task.NewBuilder()
+	// report the changes which take effect only after restarting
+	if applied != nil {
+		b.CheckConfigReload(applied, metadata.Topology)
+	}
+	t := b.
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
@@ -129,3 +184,19 @@ func buildReloadTask(
 
 	return t, nil
 }
+
+// changedNodes returns the ids of the instances changed since the applied topology, and
+// reports them by component
+func changedNodes(applied, current *meta.TopologySpecification) []string {
+	var nodes []string
+	byComponent := meta.ChangedNodes(applied, current)
+	for _, comp := range current.ComponentsByStartOrder() {
+		ids := byComponent[comp.Name()]
+		if len(ids) == 0 {
+			continue
+		}
+		log.Infof("The config of component %s is changed on: %s", comp.Name(), strings.Join(ids, ", "))
+		nodes = append(nodes, ids...)
+	}
+	return nodes
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+type replaceNodeOptions struct {
+	scaleOutOptions
+	sshPort int   // SSH port of the new host
+	timeout int64 // timeout in seconds when transferring PD and TiKV store leaders
+
+	skipPDBackup bool // don't back up the metadata of PD before replacing
+}
+
+func newReplaceNodeCmd() *cobra.Command {
+	opt := replaceNodeOptions{}
+	cmd := &cobra.Command{
+		Use:          "replace-node <cluster-name> <node-id> <new-host>",
+		Short:        "Replace a node of a TiDB cluster by a new host",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return cmd.Help()
+			}
+
+			logger.EnableAuditLog()
+			return replaceNode(args[0], args[1], args[2], opt)
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.user, "user", "root", "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().IntVar(&opt.sshPort, "ssh-port", 0, "The SSH port of the new host, the one of the replaced node is used if not specified")
+	cmd.Flags().Int64Var(&opt.timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	cmd.Flags().BoolVar(&opt.skipPDBackup, "skip-pd-backup", false, "Don't back up the metadata of PD to the cluster directory before replacing the node")
+
+	return cmd
+}
+
+func replaceNode(clusterName, nodeID, newHost string, opt replaceNodeOptions) error {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot replace node of non-exists cluster %s", clusterName)
+	}
+
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	newPart, err := metadata.Topology.ReplaceInstance(nodeID, newHost, opt.sshPort)
+	if err != nil {
+		return err
+	}
+	var oldHost string
+	metadata.Topology.IterInstance(func(instance meta.Instance) {
+		if instance.ID() == nodeID {
+			oldHost = instance.GetHost()
+		}
+	})
+
+	// Abort the replacement if the merged topology is invalid
+	mergedTopo := metadata.Topology.Merge(newPart)
+	if err := mergedTopo.Validate(); err != nil {
+		return err
+	}
+	if err := checkClusterPortConflict(clusterName, mergedTopo); err != nil {
+		return err
+	}
+	if err := checkClusterDirConflict(clusterName, mergedTopo); err != nil {
+		return err
+	}
+
+	patchedComponents := set.NewStringSet()
+	newPart.IterInstance(func(instance meta.Instance) {
+		if exists := tiuputils.IsExist(meta.ClusterPath(clusterName, meta.PatchDirName, instance.ComponentName()+".tar.gz")); exists {
+			patchedComponents.Insert(instance.ComponentName())
+		}
+	})
+	if !skipConfirm {
+		if err := confirmTopology(clusterName, metadata.Version, newPart, patchedComponents); err != nil {
+			return err
+		}
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The node %s will be removed from `%s` after the new one joins the cluster.\nDo you want to continue? [y/N]:",
+			nodeID,
+			color.HiYellowString(clusterName)); err != nil {
+			return err
+		}
+	}
+
+	// Inherit existing global configuration
+	newPart.GlobalOptions = metadata.Topology.GlobalOptions
+	newPart.MonitoredOptions = metadata.Topology.MonitoredOptions
+	newPart.ServerConfigs = metadata.Topology.ServerConfigs
+
+	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile)
+	if err != nil {
+		return err
+	}
+
+	// The replaced node may be down, so it's not started or refreshed when the new one joins
+	deploy, join := buildScaleOutSteps(clusterName, metadata, mergedTopo, opt.scaleOutOptions, sshConnProps, newPart, patchedComponents, set.NewStringSet(nodeID))
+	remove := func(reachable bool) task.Task {
+		// metadata.Topology is the merged one after the new node joins
+		options := operator.Options{Nodes: []string{nodeID}, Timeout: opt.timeout, Unreachable: !reachable}
+		deletedNodes := options.Nodes
+		if reachable {
+			deletedNodes = operator.AsyncNodes(metadata.Topology, options.Nodes, false)
+		}
+		return task.NewBuilder().
+			ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+			ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+			UpdateMeta(clusterName, metadata, deletedNodes).
+			CleanupUnits(destroyedUnits(metadata.Topology, deletedNodes), meta.ClusterPath(clusterName, pendingCleanupFileName)).
+			Build()
+	}
+	b := task.NewBuilder()
+	if !opt.skipPDBackup {
+		b.BackupPDMeta(metadata.Topology, "replace-node", pdBackupPath(clusterName, "replace-node"))
+	}
+	t := b.ReplaceNode(oldHost, deploy, join, remove).Build()
+
+	if err := runValidationHook("replace-node", clusterName, metadata.Version, []string{nodeID}, mergedTopo, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	log.Infof("Replaced node %s of cluster `%s` by %s successfully", nodeID, clusterName, newHost)
+
+	return nil
+}
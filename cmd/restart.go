@@ -26,7 +26,12 @@ import (
 )
 
 func newRestartCmd() *cobra.Command {
-	var options operator.Options
+	var (
+		options              operator.Options
+		rolling              bool
+		componentConcurrency map[string]int // per component, unlike the global --concurrency
+		nodeFile             string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "restart <cluster-name>",
@@ -41,35 +46,77 @@ func newRestartCmd() *cobra.Command {
 				return errors.Errorf("cannot restart non-exists cluster %s", clusterName)
 			}
 
-			logger.EnableAuditLog()
-			metadata, err := meta.ClusterMetadata(clusterName)
-			if err != nil {
-				return err
-			}
+			return restartCluster(newTaskContext(), clusterName, nodeFile, options, rolling, componentConcurrency)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only restart specified roles")
+	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only restart specified nodes")
+	cmd.Flags().StringVar(&nodeFile, "node-file", "", "File listing the hosts or nodes to restart, one per line, intersected with --node if both are given")
+	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders in rolling restart, draining the changefeeds of the TiCDC captures, or waiting for the drainers to be synced")
+	cmd.Flags().BoolVar(&options.Force, "force", false, "Restart the instances without transferring the leaders in rolling restart, draining the TiCDC captures or waiting for the drainers to be synced")
+	cmd.Flags().BoolVar(&rolling, "rolling", false, "Restart the instances component by component instead of stopping the whole cluster")
+	cmd.Flags().StringToIntVar(&componentConcurrency, "component-concurrency", nil, "Max number of instances of a component restarted at the same time in rolling restart, e.g. tidb=4,tikv=1, the ones of PD and TiKV are clamped below the majority")
+	cmd.Flags().Int64Var(&options.GracePeriod, "grace-period", 0, "Seconds waited for an instance to exit after SIGTERM before killing it by SIGKILL in rolling restart, 0 means waiting for systemd")
+	cmd.Flags().StringVar(&options.ZoneLabel, "zone-label", "", "Restart the instances zone by zone, the zone of a host is the value of the label of its TiKV instances, e.g. zone")
+	return cmd
+}
 
-			t := task.NewBuilder().
-				SSHKeySet(
-					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
-					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
-				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
-				ClusterOperate(metadata.Topology, operator.RestartOperation, options).
-				Build()
+// restartCluster restarts the cluster, component by component if rolling is enabled
+func restartCluster(
+	ctx *task.Context,
+	clusterName, nodeFile string,
+	options operator.Options,
+	rolling bool,
+	componentConcurrency map[string]int,
+) error {
+	logger.EnableAuditLog()
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+	if options.Nodes, err = nodesFromFile(metadata.Topology, nodeFile, options.Nodes); err != nil {
+		return err
+	}
 
-			if err := t.Execute(task.NewContext()); err != nil {
-				if errorx.Cast(err) != nil {
-					// FIXME: Map possible task errors and give suggestions.
-					return err
-				}
-				return errors.Trace(err)
+	instances := selectedInstances(metadata.Topology, options)
+	b := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+		Extensions(task.PhasePreStop, instances)
+	if options.ZoneLabel != "" && !rolling {
+		return errors.New("--zone-label is only supported by the rolling restart")
+	}
+	if rolling {
+		var zones []operator.Zone
+		if options.ZoneLabel != "" {
+			if zones, err = operator.Zones(metadata.Topology, options.ZoneLabel); err != nil {
+				return err
 			}
+		}
+		b.RollingRestart(metadata.Topology, options, operator.ConcurrencyPolicy(componentConcurrency), zones)
+	} else {
+		b.ClusterOperate(metadata.Topology, operator.RestartOperation, options)
+	}
+	t := b.CheckListenAddress(instances).
+		Extensions(task.PhasePostStart, instances).
+		Build()
 
-			log.Infof("Restarted cluster `%s` successfully", clusterName)
+	if err := runValidationHook("restart", clusterName, metadata.Version, options.Nodes, metadata.Topology, t); err != nil {
+		return err
+	}
 
-			return nil
-		},
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
 	}
 
-	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only restart specified roles")
-	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only restart specified nodes")
-	return cmd
+	log.Infof("Restarted cluster `%s` successfully", clusterName)
+
+	return runVerification("restart", clusterName)
 }
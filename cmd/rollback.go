@@ -0,0 +1,212 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+// scaleOutRecord is persisted when a scale-out fails, it holds the arguments to rebuild the
+// same task tree and the record of the executed tasks, so that a subtree can be rolled back.
+// It's persisted in JSON as the descriptions of the tasks are not always valid plain YAML scalars.
+type scaleOutRecord struct {
+	// the metadata before the scale-out and the new part, in YAML as the meta file
+	Metadata     string               `json:"metadata"`
+	NewPart      string               `json:"new_part"`
+	Patched      []string             `json:"patched,omitempty"`
+	User         string               `json:"user"`
+	IdentityFile string               `json:"identity_file,omitempty"`
+	Incremental  bool                 `json:"incremental,omitempty"`
+	Executed     task.ExecutionRecord `json:"executed"`
+}
+
+func newScaleOutRecord(metadata *meta.ClusterMeta, newPart *meta.TopologySpecification, patched set.StringSet, opt scaleOutOptions) (*scaleOutRecord, error) {
+	metaYAML, err := yaml.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	newPartYAML, err := yaml.Marshal(newPart)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := &scaleOutRecord{
+		Metadata:     string(metaYAML),
+		NewPart:      string(newPartYAML),
+		User:         opt.user,
+		IdentityFile: opt.identityFile,
+		Incremental:  opt.incremental,
+	}
+	for comp := range patched {
+		r.Patched = append(r.Patched, comp)
+	}
+	sort.Strings(r.Patched)
+	return r, nil
+}
+
+func scaleOutRecordPath(clusterName string) string {
+	return meta.ClusterPath(clusterName, "rollback", "scale-out.json")
+}
+
+// topology returns the metadata before the scale-out and the new part
+func (r *scaleOutRecord) topology() (*meta.ClusterMeta, *meta.TopologySpecification, error) {
+	var metadata meta.ClusterMeta
+	if err := yaml.Unmarshal([]byte(r.Metadata), &metadata); err != nil {
+		return nil, nil, errors.Annotate(err, "failed to parse the metadata of the scale-out")
+	}
+	var newPart meta.TopologySpecification
+	if err := yaml.Unmarshal([]byte(r.NewPart), &newPart); err != nil {
+		return nil, nil, errors.Annotate(err, "failed to parse the topology of the scale-out")
+	}
+	return &metadata, &newPart, nil
+}
+
+// buildTask rebuilds the task tree of the scale-out, and returns it with the merged topology
+func (r *scaleOutRecord) buildTask(clusterName string) (task.Task, *meta.ClusterMeta, *meta.Specification, error) {
+	metadata, newPart, err := r.topology()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mergedTopo := metadata.Topology.Merge(newPart)
+	opt := scaleOutOptions{user: r.User, identityFile: r.IdentityFile, incremental: r.Incremental}
+	// the root SSH tasks are never executed again, so the password is not needed
+	sshConnProps := &cliutil.SSHConnectionProps{IdentityFile: r.IdentityFile}
+	t, err := buildScaleOutTask(clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, set.NewStringSet(r.Patched...))
+	return t, metadata, mergedTopo, err
+}
+
+func saveScaleOutRecord(clusterName string, r *scaleOutRecord) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	path := scaleOutRecordPath(clusterName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Annotatef(ioutil.WriteFile(path, data, 0600), "failed to save the record of the scale-out to %s", path)
+}
+
+func loadScaleOutRecord(clusterName string) (*scaleOutRecord, error) {
+	path := scaleOutRecordPath(clusterName)
+	if tiuputils.IsNotExist(path) {
+		return nil, errors.Errorf("no failed scale-out of cluster %s to roll back", clusterName)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var r scaleOutRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the record of the scale-out %s", path)
+	}
+	return &r, nil
+}
+
+func newRollbackCmd() *cobra.Command {
+	var list bool
+	cmd := &cobra.Command{
+		Use:   "rollback <cluster-name> [task-id]",
+		Short: "Roll back the tasks of the last failed scale-out",
+		Long: `Roll back a subtree of the tasks of the last failed scale-out of the cluster, the task
+tree is rebuilt from the record saved when the scale-out failed, and the executed tasks
+with the ID and inside it are rolled back in reverse order. The ones which don't support
+rollback are skipped. Only the scale-out is supported now, use --list to show the IDs of
+the executed tasks.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 && !(list && len(args) == 1) {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			record, err := loadScaleOutRecord(clusterName)
+			if err != nil {
+				return err
+			}
+			if list {
+				ids := make([]string, 0, len(record.Executed))
+				for id := range record.Executed {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+				rows := [][]string{{"ID", "Task"}}
+				for _, id := range ids {
+					rows = append(rows, []string{id, record.Executed[id]})
+				}
+				cliutil.PrintTable(rows, true)
+				return nil
+			}
+
+			logger.EnableAuditLog()
+			return rollbackScaleOut(clusterName, record, args[1])
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "List the IDs of the executed tasks of the failed scale-out")
+
+	return cmd
+}
+
+func rollbackScaleOut(clusterName string, record *scaleOutRecord, id string) error {
+	t, metadata, mergedTopo, err := record.buildTask(clusterName)
+	if err != nil {
+		return err
+	}
+	ctx := newTaskContext()
+	if err := record.Executed.Apply(ctx, t); err != nil {
+		return err
+	}
+	if desc, ok := record.Executed[id]; ok && !skipConfirm {
+		if err := cliutil.PromptForConfirmOrAbortError(fmt.Sprintf("Do you want to roll back task '%s' %s? [y/N]: ", id, desc)); err != nil {
+			return err
+		}
+	}
+
+	// the executors of the tasks are not persisted, the ones of the deploy user are set for all
+	// the hosts which are in the cluster or initialized by the scale-out
+	if err := ctx.SetSSHKeySet(
+		meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+		meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")); err != nil {
+		return err
+	}
+	if err := ctx.SetClusterSSH(mergedTopo, metadata.User, sshTimeout); err != nil {
+		return err
+	}
+
+	if err := task.RollbackSubtree(ctx, t, id); err != nil {
+		if errorx.Cast(err) != nil {
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	log.Infof("Rolled back task '%s' of the failed scale-out of cluster `%s`", id, clusterName)
+	return nil
+}
@@ -14,22 +14,32 @@
 package cmd
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/joomcode/errorx"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/colorutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/flags"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/version"
 	"github.com/pingcap-incubator/tiup/pkg/localdata"
 	tiupmeta "github.com/pingcap-incubator/tiup/pkg/meta"
 	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -40,6 +50,36 @@ var (
 	errNS       = errorx.NewNamespace("cmd")
 	sshTimeout  int64 // timeout in seconds when connecting an SSH server
 	skipConfirm bool
+	opTimeout   int64     // timeout in seconds of the whole operation
+	opDeadline  time.Time // deadline of the whole operation, calculated by opTimeout
+)
+
+var (
+	eventSocketPath string            // path of the Unix domain socket to serve task events
+	eventSocket     *task.EventSocket // serves the task events if eventSocketPath is specified
+	proxy           string            // proxy to fetch the manifests and artifacts, overrides HTTP(S)_PROXY
+	validationHook  string            // command to validate the disruptive operations before they are performed
+	verboseSpec     string            // hosts and components whose remote commands are logged verbosely
+	verboseScope    *log.Scope        // parsed from verboseSpec
+	changeID        string            // id of the change stamped on the logs, audit records and events
+	deterministic   bool              // execute the parallel tasks one by one in order
+	transferRate    float64           // cap of the aggregate rate of the file transfers in MB/s
+	outputLimit     int64             // cap of the stdout and stderr each captured from a command in KB
+	outputSink      string            // directory the full outputs of the truncated commands are saved to
+	concurrency     int               // max number of the tasks or instances operated at the same time in each step
+	breakpoint      string            // how the operation goes on at the breakpoints, passed through if empty
+	offline         bool              // use the local cache strictly and never fetch anything from the mirror
+)
+
+var (
+	skipSignature  bool              // don't verify the signatures of the downloaded artifacts
+	signingKeyPath string            // path of the trusted key to verify the artifacts
+	signingKey     ed25519.PublicKey // parsed from signingKeyPath
+)
+
+var (
+	policyPath string       // path of the policy file the topology is validated against
+	policy     *task.Policy // loaded from policyPath
 )
 
 func init() {
@@ -58,9 +98,72 @@ func init() {
 		SilenceErrors: true,
 		Version:       version.NewTiOpsVersion().FullInfo(),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if changeID != "" {
+				logger.SetChangeID(changeID)
+			}
+			if transferRate < 0 {
+				return errors.Errorf("invalid --transfer-bandwidth %v, it must not be negative", transferRate)
+			}
+			executor.SetTransferBandwidth(transferRate)
+			if outputLimit < 0 {
+				return errors.Errorf("invalid --output-limit %d, it must not be negative", outputLimit)
+			}
+			executor.SetOutputLimit(outputLimit*1024, outputSink)
+			if concurrency < 0 {
+				return errors.Errorf("invalid --concurrency %d, it must not be negative", concurrency)
+			}
+			switch breakpoint {
+			case "", "prompt", "proceed", "abort":
+			default:
+				return errors.Errorf("invalid --breakpoint %s, it must be one of prompt, proceed and abort", breakpoint)
+			}
+			if opTimeout > 0 {
+				opDeadline = time.Now().Add(time.Second * time.Duration(opTimeout))
+			}
+			if policyPath != "" {
+				p, err := task.LoadPolicy(policyPath)
+				if err != nil {
+					return err
+				}
+				policy = p
+			}
+			if signingKeyPath != "" {
+				data, err := ioutil.ReadFile(signingKeyPath)
+				if err != nil {
+					return errors.Annotatef(err, "failed to read the signing key %s", signingKeyPath)
+				}
+				if signingKey, err = task.ParseSigningKey(data); err != nil {
+					return err
+				}
+			}
 			if err := meta.Initialize(); err != nil {
 				return err
 			}
+			if isMutating(cmd) && len(args) > 0 {
+				lock, err := lockCluster(args[0], cmd.Name())
+				if err != nil {
+					return err
+				}
+				operationLock = lock
+				notifyOperationBegin(cmd.Name(), args[0])
+			}
+			if verboseSpec != "" {
+				scope, err := log.ParseScope(verboseSpec)
+				if err != nil {
+					return err
+				}
+				verboseScope = scope
+			}
+			if eventSocketPath != "" {
+				s, err := task.NewEventSocket(eventSocketPath)
+				if err != nil {
+					return err
+				}
+				eventSocket = s
+			}
+			if err := utils.ProxyConfigFromEnv().WithOverride(proxy).Apply(); err != nil {
+				return err
+			}
 			return tiupmeta.InitRepository(repository.Options{
 				GOOS:   "linux",
 				GOARCH: "amd64",
@@ -78,6 +181,26 @@ func init() {
 
 	rootCmd.PersistentFlags().Int64Var(&sshTimeout, "ssh-timeout", 5, "Timeout in seconds to connect host via SSH, ignored for operations that don't need an SSH connection.")
 	rootCmd.PersistentFlags().BoolVarP(&skipConfirm, "yes", "y", false, "Skip all confirmations and assumes 'yes'")
+	rootCmd.PersistentFlags().Int64Var(&opTimeout, "operation-timeout", 0, "Timeout in seconds of the whole operation, the operation is aborted if it's not finished in time. 0 means no timeout.")
+	rootCmd.PersistentFlags().StringVar(&eventSocketPath, "event-socket", "", "Serve the task events as newline-delimited JSON on the Unix domain socket for external UIs")
+	rootCmd.PersistentFlags().StringVar(&validationHook, "validation-hook", os.Getenv("TIUP_CLUSTER_VALIDATION_HOOK"), "Command to validate the disruptive operations, it receives the operation and topology as JSON on stdin and the operation is aborted unless it exits zero (env TIUP_CLUSTER_VALIDATION_HOOK)")
+	rootCmd.PersistentFlags().StringVar(&verboseSpec, "verbose-scope", "", "Log the remote commands and outputs of the hosts or components verbosely, e.g: host=172.16.5.140,component=tikv")
+	rootCmd.PersistentFlags().StringVar(&changeID, "change-id", os.Getenv("TIUP_CLUSTER_CHANGE_ID"), "ID of the change, e.g. the ticket, stamped on the logs, audit records and task events of the operation for correlation (env TIUP_CLUSTER_CHANGE_ID)")
+	rootCmd.PersistentFlags().BoolVar(&deterministic, "deterministic", false, "Execute the parallel tasks one by one in a fixed order, so that the logs are reproducible for debugging")
+	rootCmd.PersistentFlags().Float64Var(&transferRate, "transfer-bandwidth", 0, "Cap the aggregate bandwidth of the concurrent file transfers in MB/s, 0 means unlimited")
+	rootCmd.PersistentFlags().Int64Var(&outputLimit, "output-limit", 0, "Cap the stdout and stderr each captured from a remote command in KB, the rest is dropped with a marker to avoid running out of memory, 0 means unlimited")
+	rootCmd.PersistentFlags().StringVar(&outputSink, "output-sink", "", "Directory to save the full outputs of the commands truncated by --output-limit to")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 0, "Max number of the tasks run in parallel, and of the instances of a component started or stopped at the same time, in each step of the operation, 0 means the default of each step")
+	rootCmd.PersistentFlags().StringVar(&breakpoint, "breakpoint", "", "Pause the operation after the prechecks and before changing the hosts with a summary: 'prompt' to confirm interactively, or 'proceed' and 'abort' to decide non-interactively")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Never connect to the mirror, the components and manifests must be in the local cache, for the air-gapped environments")
+	rootCmd.PersistentFlags().BoolVar(&skipSignature, "skip-signature-check", false, "Don't verify the signatures of the downloaded components, for the mirrors which don't sign them")
+	rootCmd.PersistentFlags().StringVar(&signingKeyPath, "signing-key", "", "Path of the trusted ed25519 public key in base64 to verify the signatures of the components, the one published by the mirror is used by default")
+	rootCmd.PersistentFlags().StringVar(&policyPath, "policy", os.Getenv("TIUP_CLUSTER_POLICY"), "Policy file of the rules the topology must comply with before the operations, e.g. the minimum number of PD instances (env TIUP_CLUSTER_POLICY)")
+	rootCmd.PersistentFlags().StringVar(&webhookURL, "webhook", os.Getenv("TIUP_CLUSTER_WEBHOOK"), "URL to post the start, finish and failure of the operations changing the clusters to as JSON, e.g. for the ChatOps notifications (env TIUP_CLUSTER_WEBHOOK)")
+	rootCmd.PersistentFlags().BoolVar(&verifyAfter, "verify", false, "Verify the versions, health and deploy directories of the instances after the start, restart, reload, upgrade and scale-out, the deviations are reported as a failure")
+	rootCmd.PersistentFlags().BoolVar(&forceLock, "force-lock", false, "Take the lock of the cluster over from the operation in progress, only if it's known to be abandoned")
+	rootCmd.PersistentFlags().StringSliceVar(&lockEndpoints, "lock-etcd", nil, "Endpoints of the etcd to store the locks of the clusters shared by the control machines, the locks are local files by default")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "Proxy to fetch the components from the mirror, overrides HTTP_PROXY and HTTPS_PROXY, the hosts in NO_PROXY are still connected directly")
 
 	rootCmd.AddCommand(
 		newDeploy(),
@@ -86,6 +209,9 @@ func init() {
 		newRestartCmd(),
 		newScaleInCmd(),
 		newScaleOutCmd(),
+		newRollbackCmd(),
+		newReplaceNodeCmd(),
+		newEstimateCmd(),
 		newDestroyCmd(),
 		newUpgradeCmd(),
 		newExecCmd(),
@@ -94,12 +220,137 @@ func init() {
 		newAuditCmd(),
 		newImportCmd(),
 		newEditConfigCmd(),
+		newExportCmd(),
+		newScrapeTargetsCmd(),
+		newShowConfigCmd(),
 		newReloadCmd(),
+		newPushConfigCmd(),
+		newCheckVersionCmd(),
+		newCheckPDMembersCmd(),
+		newCheckSSHCmd(),
+		newSetStoreCmd(),
+		newPauseSchedulingCmd(),
+		newResumeSchedulingCmd(),
+		newMultiCmd(),
+		newLogsCmd(),
 		newPatchCmd(),
+		newMigrateMonitorCmd(),
+		newTransferMonitorCmd(),
+		newCompactCmd(),
+		newVerifyLayoutCmd(),
+		newVerifyMonitorCmd(),
 		newTestCmd(), // hidden command for test internally
 	)
 }
 
+// newTaskContext returns a task context with the deadline of the whole operation,
+// the task events are served on the event socket if it's enabled, and the remote
+// commands in the verbose scope are logged to stderr
+func newTaskContext() *task.Context {
+	ctx := task.NewContext()
+	ctx.SetDeadline(opDeadline)
+	ctx.SetVerboseScope(verboseScope, os.Stderr)
+	ctx.SetDeterministic(deterministic)
+	ctx.SetConcurrency(concurrency)
+	ctx.SetBreakpointHandler(breakpointHandler())
+	ctx.SetOffline(offline)
+	ctx.SetSignatureCheck(!skipSignature)
+	if signingKey != nil {
+		ctx.SetSigningKey(signingKey)
+	}
+	if changeID != "" {
+		ctx.SetChangeID(changeID)
+	}
+	if eventSocket != nil {
+		eventSocket.Attach(ctx)
+	}
+	return ctx
+}
+
+// breakpointHandler returns the handler of the breakpoints by --breakpoint, the --yes flag
+// makes the prompting one proceed
+func breakpointHandler() task.BreakpointHandler {
+	switch breakpoint {
+	case "prompt":
+		if skipConfirm {
+			return func(string) bool { return true }
+		}
+		return func(phase string) bool {
+			return cliutil.PromptForConfirm("Do you want to proceed to %s? [y/N]: ", phase)
+		}
+	case "proceed":
+		return func(string) bool { return true }
+	case "abort":
+		return func(string) bool { return false }
+	}
+	return nil
+}
+
+// instancesSummary summarizes the instances of the topology by component, e.g: pd x3, tikv x3
+func instancesSummary(topo *meta.Specification) string {
+	var parts []string
+	hosts := set.NewStringSet()
+	for _, comp := range topo.ComponentsByStartOrder() {
+		insts := comp.Instances()
+		if len(insts) == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s x%d", comp.Name(), len(insts)))
+		for _, inst := range insts {
+			hosts.Insert(inst.GetHost())
+		}
+	}
+	return fmt.Sprintf("%s on %d hosts", strings.Join(parts, ", "), len(hosts))
+}
+
+// runValidationHook validates the topology after the operation against the policy, and runs
+// the validation hook against the operation if they are specified, an error is returned if
+// the topology violates the policy or the hook rejects the operation
+func runValidationHook(operation, clusterName, version string, nodes []string, topo *meta.Specification, t task.Task) error {
+	// the cluster is gone after destroying, and the removed nodes are out of scale-in
+	if policy != nil && operation != "destroy" {
+		var removed []string
+		if operation == "scale-in" {
+			removed = nodes
+		}
+		if err := task.NewBuilder().ValidatePolicy(topo, policy, removed).Build().Execute(task.NewContext()); err != nil {
+			return err
+		}
+	}
+	if validationHook == "" {
+		return nil
+	}
+	payload, err := task.NewHookPayload(operation, clusterName, version, nodes, topo, t)
+	if err != nil {
+		return err
+	}
+	payload.ChangeID = changeID
+	return task.NewBuilder().ValidationHook(validationHook, payload).Build().Execute(task.NewContext())
+}
+
+// pdBackupPath returns the file the metadata of PD is backed up to before the operation
+func pdBackupPath(clusterName, operation string) string {
+	return meta.ClusterPath(clusterName, "backup", fmt.Sprintf("pd-%s-%s.json", operation, time.Now().Format("20060102150405")))
+}
+
+// nodesFromFile returns the nodes constrained by the node file if it's given, otherwise the
+// nodes are returned as they are
+func nodesFromFile(topo *meta.Specification, file string, nodes []string) ([]string, error) {
+	if file == "" {
+		return nodes, nil
+	}
+	return operator.NodesFromFile(topo, file, nodes)
+}
+
+// selectedInstances returns the instances matched by the role and node filters in the start order
+func selectedInstances(topo *meta.Specification, options operator.Options) []meta.Instance {
+	var insts []meta.Instance
+	for _, comp := range operator.FilterComponent(topo.ComponentsByStartOrder(), set.NewStringSet(options.Roles...)) {
+		insts = append(insts, operator.FilterInstance(comp.Instances(), set.NewStringSet(options.Nodes...))...)
+	}
+	return insts
+}
+
 func printErrorMessageForNormalError(err error) {
 	_, _ = colorutil.ColorErrorMsg.Fprintf(os.Stderr, "\nError: %s\n", err.Error())
 }
@@ -155,6 +406,18 @@ func extractSuggestionFromErrorX(err *errorx.Error) string {
 	return ""
 }
 
+// closeEventSocket closes the event socket if it's served, it's closed on every exit path so
+// that the socket file is never left behind
+func closeEventSocket() {
+	if eventSocket == nil {
+		return
+	}
+	if err := eventSocket.Close(); err != nil {
+		zap.L().Warn("Failed to close the event socket", zap.String("path", eventSocketPath), zap.Error(err))
+	}
+	eventSocket = nil
+}
+
 // Execute executes the root command
 func Execute() {
 	zap.L().Info("Execute command", zap.String("command", cliutil.OsArgs()))
@@ -172,6 +435,10 @@ func Execute() {
 	if err != nil {
 		code = 1
 	}
+	notifyOperationFinish(err)
+	unlockCluster(operationLock)
+	closeLockStore()
+	closeEventSocket()
 
 	zap.L().Info("Execute command finished", zap.Int("code", code), zap.Error(err))
 
@@ -181,6 +448,9 @@ func Execute() {
 		} else {
 			printErrorMessageForNormalError(err)
 		}
+		if category := errutil.CategoryOf(err); category != errutil.CategoryUnknown {
+			_, _ = fmt.Fprintf(os.Stderr, "Error category: %s\n", category)
+		}
 
 		if !errorx.HasTrait(err, errutil.ErrTraitPreCheck) {
 			logger.OutputDebugLog()
@@ -34,7 +34,8 @@ import (
 
 func newScaleInCmd() *cobra.Command {
 	var (
-		options operator.Options
+		options      operator.Options
+		skipPDBackup bool
 	)
 	cmd := &cobra.Command{
 		Use:   "scale-in <cluster-name>",
@@ -56,20 +57,21 @@ func newScaleInCmd() *cobra.Command {
 			}
 
 			logger.EnableAuditLog()
-			return scaleIn(clusterName, options)
+			return scaleIn(clusterName, options, skipPDBackup)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Specify the nodes")
-	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders, or draining the TiCDC captures")
 	cmd.Flags().BoolVar(&options.Force, "force", false, "Force just try stop and destroy instance before removing the instance from topo")
+	cmd.Flags().BoolVar(&skipPDBackup, "skip-pd-backup", false, "Don't back up the metadata of PD to the cluster directory before scaling in")
 
 	_ = cmd.MarkFlagRequired("node")
 
 	return cmd
 }
 
-func scaleIn(clusterName string, options operator.Options) error {
+func scaleIn(clusterName string, options operator.Options, skipPDBackup bool) error {
 	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		return errors.Errorf("cannot scale-in non-exists cluster %s", clusterName)
 	}
@@ -91,7 +93,7 @@ func scaleIn(clusterName string, options operator.Options) error {
 			// data dir would be empty for components which don't need it
 			dataDir := instance.DataDir()
 			if dataDir != "" {
-				clusterutil.Abs(metadata.User, dataDir)
+				dataDir = clusterutil.Abs(metadata.User, dataDir)
 			}
 			// log dir will always be with values, but might not used by the component
 			logDir := clusterutil.Abs(metadata.User, instance.LogDir())
@@ -126,18 +128,25 @@ func scaleIn(clusterName string, options operator.Options) error {
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, sshTimeout)
+	if !skipPDBackup {
+		b.BackupPDMeta(metadata.Topology, "scale-in", pdBackupPath(clusterName, "scale-in"))
+	}
 
+	destroyedNodes := options.Nodes
 	if !options.Force {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
-			UpdateMeta(clusterName, metadata, operator.AsyncNodes(metadata.Topology, options.Nodes, false))
-	} else {
-		b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
-			UpdateMeta(clusterName, metadata, options.Nodes)
+		destroyedNodes = operator.AsyncNodes(metadata.Topology, options.Nodes, false)
 	}
+	b.ClusterOperate(metadata.Topology, operator.ScaleInOperation, options).
+		UpdateMeta(clusterName, metadata, destroyedNodes).
+		CleanupUnits(destroyedUnits(metadata.Topology, destroyedNodes), meta.ClusterPath(clusterName, pendingCleanupFileName))
 
 	t := b.Parallel(regenConfigTasks...).Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := runValidationHook("scale-in", clusterName, metadata.Version, options.Nodes, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -149,3 +158,19 @@ func scaleIn(clusterName string, options operator.Options) error {
 
 	return nil
 }
+
+// pendingCleanupFileName is the file under the cluster directory which records the systemd
+// units left on the unreachable hosts
+const pendingCleanupFileName = "pending_cleanup.yaml"
+
+// destroyedUnits returns the systemd units of the destroyed nodes by host
+func destroyedUnits(topo *meta.Specification, nodes []string) map[string][]string {
+	destroyed := set.NewStringSet(nodes...)
+	units := make(map[string][]string)
+	topo.IterInstance(func(instance meta.Instance) {
+		if destroyed.Exist(instance.ID()) {
+			units[instance.GetHost()] = append(units[instance.GetHost()], instance.ServiceName())
+		}
+	})
+	return units
+}
@@ -14,6 +14,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/joomcode/errorx"
@@ -35,6 +37,7 @@ import (
 type scaleOutOptions struct {
 	user         string // username to login to the SSH server
 	identityFile string // path to the private key file
+	incremental  bool   // transfer only the files of the packages which differ on the hosts
 }
 
 func newScaleOutCmd() *cobra.Command {
@@ -55,6 +58,7 @@ func newScaleOutCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&opt.user, "user", "root", "The user name to login via SSH. The user must has root (or sudo) privilege.")
 	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().BoolVar(&opt.incremental, "incremental", false, "Transfer only the files of the packages which differ from the ones on the hosts by checksum, e.g. to scale out onto the existing deploy directories")
 
 	return cmd
 }
@@ -116,17 +120,38 @@ func scaleOut(clusterName, topoFile string, opt scaleOutOptions) error {
 		return err
 	}
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := runValidationHook("scale-out", clusterName, metadata.Version, nil, mergedTopo, t); err != nil {
+		return err
+	}
+
+	// the metadata is replaced by the merged one in the scale-out, the record of a failed
+	// scale-out keeps the original one to rebuild the same task tree
+	record, err := newScaleOutRecord(metadata, &newPart, patchedComponents, opt)
+	if err != nil {
+		return err
+	}
+
+	ctx := newTaskContext()
+	if err := t.Execute(ctx); err != nil {
+		record.Executed = task.NewExecutionRecord(ctx, t)
+		if err := saveScaleOutRecord(clusterName, record); err != nil {
+			log.Warnf("Failed to save the record of the scale-out: %v", err)
+		} else {
+			log.Infof("The executed tasks can be rolled back by `%s rollback %s <task-id>`, see `--list` for the IDs", cliutil.OsArgs0(), clusterName)
+		}
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
 		}
 		return errors.Trace(err)
 	}
+	if err := os.RemoveAll(scaleOutRecordPath(clusterName)); err != nil {
+		return errors.Trace(err)
+	}
 
 	log.Infof("Scaled cluster `%s` out successfully", clusterName)
 
-	return nil
+	return runVerification("scale-out", clusterName)
 }
 
 // Deprecated
@@ -146,6 +171,21 @@ func buildScaleOutTask(
 	sshConnProps *cliutil.SSHConnectionProps,
 	newPart *meta.TopologySpecification,
 	patchedComponents set.StringSet) (task.Task, error) {
+	deploy, join := buildScaleOutSteps(clusterName, metadata, mergedTopo, opt, sshConnProps, newPart, patchedComponents, set.NewStringSet())
+	return task.NewBuilder().Serial(deploy, join).Build(), nil
+}
+
+// buildScaleOutSteps returns the tasks to deploy the new part to the hosts, and the tasks to
+// start the new part to join the cluster, the excluded nodes are not started or refreshed
+func buildScaleOutSteps(
+	clusterName string,
+	metadata *meta.ClusterMeta,
+	mergedTopo *meta.Specification,
+	opt scaleOutOptions,
+	sshConnProps *cliutil.SSHConnectionProps,
+	newPart *meta.TopologySpecification,
+	patchedComponents set.StringSet,
+	excluded set.StringSet) (deploy task.Task, join task.Task) {
 	var (
 		envInitTasks       []task.Task // tasks which are used to initialize environment
 		downloadCompTasks  []task.Task // tasks which are used to download components
@@ -181,6 +221,7 @@ func buildScaleOutTask(
 					sshConnProps.IdentityFilePassphrase,
 					sshTimeout,
 				).
+				CheckDeployUser(instance.GetHost(), metadata.User).
 				EnvInit(instance.GetHost(), metadata.User).
 				UserSSH(instance.GetHost(), instance.GetSSHPort(), metadata.User, sshTimeout).
 				Mkdir(globalOptions.User, instance.GetHost(), dirs...).
@@ -200,13 +241,14 @@ func buildScaleOutTask(
 		// data dir would be empty for components which don't need it
 		dataDir := inst.DataDir()
 		if dataDir != "" {
-			clusterutil.Abs(metadata.User, dataDir)
+			dataDir = clusterutil.Abs(metadata.User, dataDir)
 		}
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(metadata.User, inst.LogDir())
 
 		// Deploy component
 		tb := task.NewBuilder().
+			Incremental(opt.incremental).
 			UserSSH(inst.GetHost(), inst.GetSSHPort(), metadata.User, sshTimeout).
 			Mkdir(metadata.User, inst.GetHost(),
 				deployDir, dataDir, logDir,
@@ -232,12 +274,22 @@ func buildScaleOutTask(
 		deployCompTasks = append(deployCompTasks, t)
 	})
 
+	var startOpt operator.Options
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		if len(excluded) > 0 && !excluded.Exist(inst.ID()) {
+			startOpt.Nodes = append(startOpt.Nodes, inst.ID())
+		}
+	})
+
 	mergedTopo.IterInstance(func(inst meta.Instance) {
+		if excluded.Exist(inst.ID()) {
+			return
+		}
 		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
 		// data dir would be empty for components which don't need it
 		dataDir := inst.DataDir()
 		if dataDir != "" {
-			clusterutil.Abs(metadata.User, dataDir)
+			dataDir = clusterutil.Abs(metadata.User, dataDir)
 		}
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(metadata.User, inst.LogDir())
@@ -274,20 +326,28 @@ func buildScaleOutTask(
 		metadata.Topology.GlobalOptions,
 		metadata.Topology.MonitoredOptions,
 		metadata.Version,
+		opt.incremental,
 	)
 	downloadCompTasks = append(downloadCompTasks, convertStepDisplaysToTasks(dlTasks)...)
 	deployCompTasks = append(deployCompTasks, convertStepDisplaysToTasks(dpTasks)...)
 
-	return task.NewBuilder().
+	deploy = task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		Parallel(downloadCompTasks...).
+		Breakpoint("deploying the new instances",
+			fmt.Sprintf("Cluster: %s", clusterName),
+			fmt.Sprintf("Version: %s", metadata.Version),
+			fmt.Sprintf("New instances: %s", instancesSummary(newPart))).
 		Parallel(envInitTasks...).
 		Parallel(deployCompTasks...).
+		Build()
+
+	join = task.NewBuilder().
 		// TODO: find another way to make sure current cluster started
 		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
-		ClusterOperate(metadata.Topology, operator.StartOperation, operator.Options{}).
+		ClusterOperate(metadata.Topology, operator.StartOperation, startOpt).
 		ClusterSSH(newPart, metadata.User, sshTimeout).
 		Func("save meta", func() error {
 			metadata.Topology = mergedTopo
@@ -295,7 +355,8 @@ func buildScaleOutTask(
 		}).
 		ClusterOperate(newPart, operator.StartOperation, operator.Options{}).
 		Parallel(refreshConfigTasks...).
-		ClusterOperate(metadata.Topology, operator.RestartOperation, operator.Options{Roles: []string{meta.ComponentPrometheus}}).
-		Build(), nil
+		ClusterOperate(metadata.Topology, operator.RestartOperation, operator.Options{Roles: []string{meta.ComponentPrometheus}, Nodes: startOpt.Nodes}).
+		Build()
 
+	return deploy, join
 }
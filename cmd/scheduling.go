@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+// schedulingStatePath returns the file the schedule limits of PD are saved to when the
+// scheduling of the cluster is paused
+func schedulingStatePath(clusterName string) string {
+	return meta.ClusterPath(clusterName, "scheduling-paused.json")
+}
+
+func newPauseSchedulingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pause-scheduling <cluster-name>",
+		Short: "Pause the scheduling of PD during a maintenance",
+		Long: `Stop PD from moving the regions around by setting the schedule limits to 0, e.g.
+to avoid the churn during a maintenance. The prior limits are saved, and restored exactly
+by resume-scheduling.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScheduling(cmd, args, func(b *task.Builder, topo *meta.Specification, path string) {
+				b.PauseScheduling(topo, path)
+			})
+		},
+	}
+	return cmd
+}
+
+func newResumeSchedulingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume-scheduling <cluster-name>",
+		Short: "Resume the scheduling of PD paused by pause-scheduling",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScheduling(cmd, args, func(b *task.Builder, topo *meta.Specification, path string) {
+				b.ResumeScheduling(topo, path)
+			})
+		},
+	}
+	return cmd
+}
+
+func runScheduling(cmd *cobra.Command, args []string, build func(b *task.Builder, topo *meta.Specification, path string)) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	clusterName := args[0]
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot %s of non-exists cluster %s", cmd.Name(), clusterName)
+	}
+
+	logger.EnableAuditLog()
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	b := task.NewBuilder()
+	build(b, metadata.Topology, schedulingStatePath(clusterName))
+	t := b.Build()
+
+	if err := runValidationHook(cmd.Name(), clusterName, metadata.Version, nil, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// warnSchedulingPaused warns if the scheduling of the cluster is left paused, so that it's
+// not disabled permanently by accident
+func warnSchedulingPaused(clusterName string) {
+	state, err := task.ReadSchedulingState(schedulingStatePath(clusterName))
+	if err != nil || state == nil {
+		return
+	}
+	log.Warnf("The scheduling of PD is paused since %s (%s ago), please resume it by `%s resume-scheduling %s` after the maintenance",
+		state.Time.Format(time.RFC3339), time.Since(state.Time).Round(time.Minute), cliutil.OsArgs0(), clusterName)
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newScrapeTargetsCmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "scrape-targets <cluster-name>",
+		Short: "Export the metrics endpoints of a TiDB cluster for an external Prometheus",
+		Long: `Export the metrics endpoints of all the instances of a TiDB cluster in the JSON
+format of the file based service discovery of Prometheus, so that the cluster can be
+scraped by an external Prometheus with file_sd_configs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot export non-exists cluster %s", clusterName)
+			}
+
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			targets := operator.ScrapeTargets(clusterName, metadata.Topology)
+			data, err := operator.FileSDConfig(targets)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				fmt.Print(string(data))
+				return nil
+			}
+			if err := ioutil.WriteFile(output, data, 0644); err != nil {
+				return errors.Trace(err)
+			}
+			log.Infof("Exported %d scrape targets of cluster `%s` to %s", len(targets), clusterName, output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "The file to write the targets to, they are printed to stdout if it's not specified")
+
+	return cmd
+}
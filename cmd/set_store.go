@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newSetStoreCmd() *cobra.Command {
+	var (
+		labels       map[string]string
+		leaderWeight float64
+		regionWeight float64
+	)
+	cmd := &cobra.Command{
+		Use:   "set-store <cluster-name> <tikv-node>",
+		Short: "Set the labels and weights of a TiKV store online",
+		Long: `Set the labels and weights of a TiKV store through PD without restarting it,
+e.g. to rebalance a cluster of heterogeneous hosts. The label keys must be in
+replication.location-labels of PD. The labels of the instance in the topology are
+updated to the ones applied by PD, so that they are kept when it's restarted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			clusterName, node := args[0], args[1]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot set the store of non-exists cluster %s", clusterName)
+			}
+
+			attrs := operator.StoreAttributes{Labels: labels}
+			if cmd.Flags().Changed("leader-weight") {
+				attrs.LeaderWeight = &leaderWeight
+			}
+			if cmd.Flags().Changed("region-weight") {
+				attrs.RegionWeight = &regionWeight
+			}
+			if len(attrs.Labels) == 0 && attrs.LeaderWeight == nil && attrs.RegionWeight == nil {
+				return errors.New("nothing to set, please specify --label, --leader-weight or --region-weight")
+			}
+			for _, w := range []*float64{attrs.LeaderWeight, attrs.RegionWeight} {
+				if w != nil && *w < 0 {
+					return errors.Errorf("invalid weight %v, it must not be negative", *w)
+				}
+			}
+			for k, v := range attrs.Labels {
+				if strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+					return errors.Errorf("invalid label %s=%s", k, v)
+				}
+			}
+
+			logger.EnableAuditLog()
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+			if err := runValidationHook("set-store", clusterName, metadata.Version, []string{node}, metadata.Topology, nil); err != nil {
+				return err
+			}
+
+			if err := operator.SetStoreAttributes(metadata.Topology, node, attrs, 5*time.Second, nil); err != nil {
+				return err
+			}
+			if err := meta.SaveClusterMeta(clusterName, metadata); err != nil {
+				return errors.Trace(err)
+			}
+
+			log.Infof("Set the store %s of cluster `%s` successfully", node, clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringToStringVar(&labels, "label", nil, "The labels to set, e.g: zone=z1,host=h1, the other labels of the store are kept")
+	cmd.Flags().Float64Var(&leaderWeight, "leader-weight", 1, "The leader weight of the store")
+	cmd.Flags().Float64Var(&regionWeight, "region-weight", 1, "The region weight of the store")
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newShowConfigCmd() *cobra.Command {
+	var (
+		filterRole []string
+		filterNode []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "show-config <cluster-name>",
+		Short: "Show the effective config of the instances of a TiDB cluster",
+		Long: `Show the effective config of the instances of a TiDB cluster, which is merged from
+the generated items, server_configs, the imported configs, the instance configs and the config
+fragments in the same way as deploying, and where each item comes from. Nothing is deployed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot show the config of non-exists cluster %s", clusterName)
+			}
+
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			filterRoles := set.NewStringSet(filterRole...)
+			filterNodes := set.NewStringSet(filterNode...)
+			for _, comp := range metadata.Topology.ComponentsByStartOrder() {
+				for _, inst := range comp.Instances() {
+					if len(filterRoles) > 0 && !filterRoles.Exist(inst.Role()) {
+						continue
+					}
+					if len(filterNodes) > 0 && !filterNodes.Exist(inst.ID()) {
+						continue
+					}
+					if err := showConfig(clusterName, metadata, inst); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&filterRole, "role", "R", nil, "Only show the configs of specified roles")
+	cmd.Flags().StringSliceVarP(&filterNode, "node", "N", nil, "Only show the configs of specified nodes")
+
+	return cmd
+}
+
+// showConfig prints the effective config items of the instance, it prints nothing if the
+// instance has no config file
+func showConfig(clusterName string, metadata *meta.ClusterMeta, inst meta.Instance) error {
+	dataDir := inst.DataDir()
+	if dataDir != "" {
+		dataDir = clusterutil.Abs(metadata.User, dataDir)
+	}
+	paths := meta.DirPaths{
+		Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+		Data:   dataDir,
+		Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+		Cache:  meta.ClusterPath(clusterName, "config"),
+	}
+
+	items, err := meta.EffectiveConfig(clusterName, metadata.Version, inst, paths)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Effective config of %s %s:\n", inst.ComponentName(), inst.ID())
+	rows := [][]string{{"File", "Key", "Value", "Source"}}
+	for _, item := range items {
+		value := fmt.Sprintf("%v", item.Value)
+		if s, ok := item.Value.(string); ok {
+			value = fmt.Sprintf("%q", s)
+		}
+		rows = append(rows, []string{item.File, item.Key, value, item.Source})
+	}
+	cliutil.PrintTable(rows, true)
+	fmt.Println()
+	return nil
+}
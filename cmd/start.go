@@ -26,7 +26,10 @@ import (
 )
 
 func newStartCmd() *cobra.Command {
-	var options operator.Options
+	var (
+		options  operator.Options
+		nodeFile string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "start <cluster-name>",
@@ -41,32 +44,40 @@ func newStartCmd() *cobra.Command {
 				return errors.Errorf("cannot start non-exists cluster %s", clusterName)
 			}
 
-			return startCluster(clusterName, options)
+			return startCluster(newTaskContext(), clusterName, nodeFile, options)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only start specified roles")
 	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only start specified nodes")
+	cmd.Flags().StringVar(&nodeFile, "node-file", "", "File listing the hosts or nodes to start, one per line, intersected with --node if both are given")
 	return cmd
 }
 
-func startCluster(clusterName string, options operator.Options) error {
+// startCluster starts the instances of the cluster matched by the options
+func startCluster(ctx *task.Context, clusterName, nodeFile string, options operator.Options) error {
 	logger.EnableAuditLog()
 	log.Infof("Starting cluster %s...", clusterName)
 	metadata, err := meta.ClusterMetadata(clusterName)
 	if err != nil {
 		return err
 	}
+	if options.Nodes, err = nodesFromFile(metadata.Topology, nodeFile, options.Nodes); err != nil {
+		return err
+	}
 
+	instances := selectedInstances(metadata.Topology, options)
 	t := task.NewBuilder().
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
 		ClusterOperate(metadata.Topology, operator.StartOperation, options).
+		CheckListenAddress(instances).
+		Extensions(task.PhasePostStart, instances).
 		Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -76,5 +87,5 @@ func startCluster(clusterName string, options operator.Options) error {
 
 	log.Infof("Started cluster `%s` successfully", clusterName)
 
-	return nil
+	return runVerification("start", clusterName)
 }
@@ -26,7 +26,10 @@ import (
 )
 
 func newStopCmd() *cobra.Command {
-	var options operator.Options
+	var (
+		options  operator.Options
+		nodeFile string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "stop <cluster-name>",
@@ -41,35 +44,51 @@ func newStopCmd() *cobra.Command {
 				return errors.Errorf("cannot stop non-exists cluster %s", clusterName)
 			}
 
-			logger.EnableAuditLog()
-			metadata, err := meta.ClusterMetadata(clusterName)
-			if err != nil {
-				return err
-			}
-
-			t := task.NewBuilder().
-				SSHKeySet(
-					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
-					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
-				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
-				ClusterOperate(metadata.Topology, operator.StopOperation, options).
-				Build()
-
-			if err := t.Execute(task.NewContext()); err != nil {
-				if errorx.Cast(err) != nil {
-					// FIXME: Map possible task errors and give suggestions.
-					return err
-				}
-				return errors.Trace(err)
-			}
-
-			log.Infof("Stopped cluster `%s` successfully", clusterName)
-
-			return nil
+			return stopCluster(newTaskContext(), clusterName, nodeFile, options)
 		},
 	}
 
 	cmd.Flags().StringSliceVarP(&options.Roles, "role", "R", nil, "Only stop specified roles")
 	cmd.Flags().StringSliceVarP(&options.Nodes, "node", "N", nil, "Only stop specified nodes")
+	cmd.Flags().StringVar(&nodeFile, "node-file", "", "File listing the hosts or nodes to stop, one per line, intersected with --node if both are given")
+	cmd.Flags().Int64Var(&options.Timeout, "transfer-timeout", 300, "Timeout in seconds when draining the changefeeds of the TiCDC captures, or waiting for the drainers to be synced")
+	cmd.Flags().BoolVar(&options.Force, "force", false, "Stop the instances without draining the TiCDC captures or waiting for the drainers to be synced")
 	return cmd
 }
+
+// stopCluster stops the instances of the cluster matched by the options
+func stopCluster(ctx *task.Context, clusterName, nodeFile string, options operator.Options) error {
+	logger.EnableAuditLog()
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+	if options.Nodes, err = nodesFromFile(metadata.Topology, nodeFile, options.Nodes); err != nil {
+		return err
+	}
+
+	t := task.NewBuilder().
+		SSHKeySet(
+			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+		Extensions(task.PhasePreStop, selectedInstances(metadata.Topology, options)).
+		ClusterOperate(metadata.Topology, operator.StopOperation, options).
+		Build()
+
+	if err := runValidationHook("stop", clusterName, metadata.Version, options.Nodes, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(ctx); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	log.Infof("Stopped cluster `%s` successfully", clusterName)
+
+	return nil
+}
@@ -17,6 +17,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	"github.com/pingcap-incubator/tiup/pkg/utils"
@@ -63,7 +65,7 @@ func newTestCmd() *cobra.Command {
 }
 
 func createDB(spec meta.TiDBSpec) (db *sql.DB, err error) {
-	dsn := fmt.Sprintf("root:@tcp(%s:%d)/?charset=utf8mb4,utf8&multiStatements=true", spec.Host, spec.Port)
+	dsn := fmt.Sprintf("root:@tcp(%s)/?charset=utf8mb4,utf8&multiStatements=true", net.JoinHostPort(spec.Host, strconv.Itoa(spec.Port)))
 	db, err = sql.Open("mysql", dsn)
 
 	return
@@ -0,0 +1,284 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+type transferMonitorOptions struct {
+	scaleOutOptions
+	nodes    []string // Prometheus and Grafana nodes to transfer
+	sshPort  int      // SSH port of the new host
+	skipData bool     // don't copy the data of Prometheus
+	timeout  int64    // timeout in seconds waiting for the new instances to be healthy
+}
+
+func newTransferMonitorCmd() *cobra.Command {
+	opt := transferMonitorOptions{}
+	cmd := &cobra.Command{
+		Use:   "transfer-monitor <cluster-name> <new-host>",
+		Short: "Move the Prometheus and Grafana instances to a new host",
+		Long: `Move the Prometheus and Grafana instances to a new host. The new instances are
+deployed with the data of Prometheus copied from the old ones, and they are started and
+verified healthy before Grafana is re-pointed to the new Prometheus. The old instances
+are removed at last, so the monitoring keeps working during the transfer.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return cmd.Help()
+			}
+
+			logger.EnableAuditLog()
+			return transferMonitor(args[0], args[1], opt)
+		},
+	}
+
+	cmd.Flags().StringVar(&opt.user, "user", "root", "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().StringVarP(&opt.identityFile, "identity_file", "i", "", "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringSliceVarP(&opt.nodes, "node", "N", nil, "Only transfer specified Prometheus or Grafana nodes, all of them are transferred if not specified")
+	cmd.Flags().IntVar(&opt.sshPort, "ssh-port", 0, "The SSH port of the new host, the one of the old node is used if not specified")
+	cmd.Flags().BoolVar(&opt.skipData, "skip-data", false, "Don't copy the data of Prometheus to the new host")
+	cmd.Flags().Int64Var(&opt.timeout, "wait-timeout", 120, "Timeout in seconds waiting for the new instances to be healthy")
+
+	return cmd
+}
+
+func transferMonitor(clusterName, newHost string, opt transferMonitorOptions) error {
+	if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+		return errors.Errorf("cannot transfer the monitor of non-exists cluster %s", clusterName)
+	}
+
+	metadata, err := meta.ClusterMetadata(clusterName)
+	if err != nil {
+		return err
+	}
+
+	// the old instances to transfer, all the Prometheus and Grafana ones by default
+	components := map[string]string{} // node id -> component
+	var oldInsts []meta.Instance
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		components[inst.ID()] = inst.ComponentName()
+	})
+	nodeFilter := set.NewStringSet(opt.nodes...)
+	for _, id := range opt.nodes {
+		switch components[id] {
+		case meta.ComponentPrometheus, meta.ComponentGrafana:
+		case "":
+			return errors.Errorf("cannot find node id '%s' in topology", id)
+		default:
+			return errors.Errorf("node '%s' is neither Prometheus nor Grafana", id)
+		}
+	}
+	metadata.Topology.IterInstance(func(inst meta.Instance) {
+		switch inst.ComponentName() {
+		case meta.ComponentPrometheus, meta.ComponentGrafana:
+			if len(opt.nodes) == 0 || nodeFilter.Exist(inst.ID()) {
+				oldInsts = append(oldInsts, inst)
+			}
+		}
+	})
+	if len(oldInsts) == 0 {
+		return errors.Errorf("no Prometheus or Grafana instance of cluster %s to transfer", clusterName)
+	}
+
+	newPart := &meta.TopologySpecification{}
+	var oldNodes []string
+	for _, inst := range oldInsts {
+		part, err := metadata.Topology.ReplaceInstance(inst.ID(), newHost, opt.sshPort)
+		if err != nil {
+			return err
+		}
+		newPart = newPart.Merge(part)
+		oldNodes = append(oldNodes, inst.ID())
+	}
+
+	// Abort the transfer if the merged topology is invalid
+	mergedTopo := metadata.Topology.Merge(newPart)
+	if err := mergedTopo.Validate(); err != nil {
+		return err
+	}
+	if err := checkClusterPortConflict(clusterName, mergedTopo); err != nil {
+		return err
+	}
+	if err := checkClusterDirConflict(clusterName, mergedTopo); err != nil {
+		return err
+	}
+
+	if !skipConfirm {
+		if err := confirmTopology(clusterName, metadata.Version, newPart, set.NewStringSet()); err != nil {
+			return err
+		}
+		if err := cliutil.PromptForConfirmOrAbortError(
+			"The nodes %v will be removed from `%s` after the new ones are healthy.\nDo you want to continue? [y/N]:",
+			oldNodes,
+			color.HiYellowString(clusterName)); err != nil {
+			return err
+		}
+	}
+
+	// Inherit existing global configuration
+	newPart.GlobalOptions = metadata.Topology.GlobalOptions
+	newPart.MonitoredOptions = metadata.Topology.MonitoredOptions
+	newPart.ServerConfigs = metadata.Topology.ServerConfigs
+
+	sshConnProps, err := cliutil.ReadIdentityFileOrPassword(opt.identityFile)
+	if err != nil {
+		return err
+	}
+
+	// The old instances are kept running without refreshing until the new ones are healthy
+	excluded := set.NewStringSet(oldNodes...)
+	deploy, join := buildScaleOutSteps(clusterName, metadata, mergedTopo, opt.scaleOutOptions, sshConnProps, newPart, set.NewStringSet(), excluded)
+
+	migrate := buildCopyMonitorDataTask(clusterName, metadata, newPart, opt.skipData)
+	var newInsts []meta.Instance
+	newPart.IterInstance(func(inst meta.Instance) {
+		newInsts = append(newInsts, inst)
+	})
+	verify := task.NewBuilder().CheckMonitorHealth(newInsts, time.Duration(opt.timeout)*time.Second).Build()
+	repoint := buildRepointGrafanaTask(clusterName, metadata, withoutNodes(mergedTopo, excluded))
+	decommission := task.NewBuilder().
+		ClusterOperate(mergedTopo, operator.ScaleInOperation, operator.Options{Nodes: oldNodes}).
+		UpdateMeta(clusterName, metadata, oldNodes).
+		CleanupUnits(destroyedUnits(mergedTopo, oldNodes), meta.ClusterPath(clusterName, pendingCleanupFileName)).
+		Build()
+
+	t := task.NewBuilder().
+		TransferMonitor(deploy, migrate, join, verify, repoint, decommission).
+		Build()
+
+	if err := runValidationHook("transfer-monitor", clusterName, metadata.Version, oldNodes, mergedTopo, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(newTaskContext()); err != nil {
+		if errorx.Cast(err) != nil {
+			// FIXME: Map possible task errors and give suggestions.
+			return err
+		}
+		return errors.Trace(err)
+	}
+
+	log.Infof("Transferred the monitor of cluster `%s` to %s successfully", clusterName, newHost)
+	return nil
+}
+
+// buildCopyMonitorDataTask copies the data of the old Prometheus instances to their successors
+// in the new part, which have the same ports
+func buildCopyMonitorDataTask(clusterName string, metadata *meta.ClusterMeta, newPart *meta.Specification, skip bool) task.Task {
+	if skip || len(newPart.Monitors) == 0 {
+		return nil
+	}
+	dataDir := func(inst meta.Instance) string {
+		deployDir := clusterutil.Abs(metadata.User, inst.DeployDir())
+		// the data dir is relative to the deploy dir in the run script
+		if dir := inst.DataDir(); !filepath.IsAbs(dir) {
+			return filepath.Join(deployDir, dir)
+		}
+		return inst.DataDir()
+	}
+
+	olds := (&meta.MonitorComponent{Specification: metadata.Topology}).Instances()
+	b := task.NewBuilder().ClusterSSH(metadata.Topology, metadata.User, sshTimeout)
+	var copyTasks []task.Task
+	for _, inst := range (&meta.MonitorComponent{Specification: newPart}).Instances() {
+		for _, old := range olds {
+			if old.GetPort() != inst.GetPort() {
+				continue
+			}
+			copyTasks = append(copyTasks, task.NewBuilder().
+				CopyMonitorData(old.GetHost(), dataDir(old), inst.GetHost(), dataDir(inst), meta.ClusterPath(clusterName, "config")).
+				Build())
+			break
+		}
+	}
+	return b.Parallel(copyTasks...).Build()
+}
+
+// buildRepointGrafanaTask regenerates the configs of Grafana and PD in the topology without the
+// old instances, so they refer to the new Prometheus. Grafana is restarted to load the new
+// datasource, while PD loads the new metric storage on its next restart.
+func buildRepointGrafanaTask(clusterName string, metadata *meta.ClusterMeta, topo *meta.Specification) task.Task {
+	var (
+		initTasks []task.Task
+		grafanas  []string
+	)
+	topo.IterInstance(func(inst meta.Instance) {
+		switch inst.ComponentName() {
+		case meta.ComponentGrafana:
+			grafanas = append(grafanas, inst.ID())
+		case meta.ComponentPD:
+		default:
+			return
+		}
+		dataDir := inst.DataDir()
+		if dataDir != "" {
+			dataDir = clusterutil.Abs(metadata.User, dataDir)
+		}
+		initTasks = append(initTasks, task.NewBuilder().InitConfig(
+			clusterName,
+			metadata.Version,
+			inst,
+			metadata.User,
+			meta.DirPaths{
+				Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+				Data:   dataDir,
+				Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+				Cache:  meta.ClusterPath(clusterName, "config"),
+			},
+		).Build())
+	})
+	if len(topo.Monitors) == 0 || len(initTasks) == 0 {
+		return nil
+	}
+	b := task.NewBuilder().Parallel(initTasks...)
+	if len(grafanas) > 0 {
+		b.ClusterOperate(topo, operator.RestartOperation, operator.Options{Roles: []string{meta.ComponentGrafana}, Nodes: grafanas})
+	}
+	return b.Build()
+}
+
+// withoutNodes returns a copy of the topology without the Prometheus and Grafana nodes
+func withoutNodes(topo *meta.Specification, nodes set.StringSet) *meta.Specification {
+	result := *topo
+	result.Monitors = nil
+	for i, inst := range (&meta.MonitorComponent{Specification: topo}).Instances() {
+		if !nodes.Exist(inst.ID()) {
+			result.Monitors = append(result.Monitors, topo.Monitors[i])
+		}
+	}
+	result.Grafana = nil
+	for i, inst := range (&meta.GrafanaComponent{Specification: topo}).Instances() {
+		if !nodes.Exist(inst.ID()) {
+			result.Grafana = append(result.Grafana, topo.Grafana[i])
+		}
+	}
+	return &result
+}
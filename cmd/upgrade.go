@@ -25,15 +25,14 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
-	"github.com/pingcap-incubator/tiup/pkg/repository"
 	"github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 	"github.com/spf13/cobra"
-	"golang.org/x/mod/semver"
 )
 
 type upgradeOptions struct {
-	options operator.Options
+	options      operator.Options
+	skipPDBackup bool // don't back up the metadata of PD before upgrading
 }
 
 func newUpgradeCmd() *cobra.Command {
@@ -51,25 +50,13 @@ func newUpgradeCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().BoolVar(&opt.options.Force, "force", false, "Force upgrade won't transfer leader")
-	cmd.Flags().Int64Var(&opt.options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders")
+	cmd.Flags().StringVar(&opt.options.ZoneLabel, "zone-label", "", "Upgrade the instances zone by zone, the zone of a host is the value of the label of its TiKV instances, e.g. zone")
+	cmd.Flags().Int64Var(&opt.options.Timeout, "transfer-timeout", 300, "Timeout in seconds when transferring PD and TiKV store leaders, draining the TiCDC captures, or waiting for the drainers to be synced")
+	cmd.Flags().BoolVar(&opt.skipPDBackup, "skip-pd-backup", false, "Don't back up the metadata of PD to the cluster directory before upgrading")
 
 	return cmd
 }
 
-func versionCompare(curVersion, newVersion string) error {
-	switch semver.Compare(curVersion, newVersion) {
-	case -1:
-		return nil
-	case 1:
-		if repository.Version(newVersion).IsNightly() {
-			return nil
-		}
-		return errors.New(fmt.Sprintf("unsupport upgrade from %s to %s", curVersion, newVersion))
-	default:
-		return errors.Errorf("please specify a higher version than %s", curVersion)
-	}
-}
-
 func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 	if utils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
 		return errors.Errorf("cannot upgrade non-exists cluster %s", clusterName)
@@ -80,6 +67,18 @@ func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 		return err
 	}
 
+	// resume the interrupted upgrade to the same version, the binaries of the instances backed
+	// up have been replaced then and must not be backed up again as the ones of the current version
+	ctx := newTaskContext()
+	state, err := task.ResumeUpgrade(ctx, meta.ClusterPath(clusterName, task.UpgradeSnapshotFileName), clusterVersion)
+	if err != nil {
+		return err
+	}
+	if state.Started() {
+		log.Infof("Resuming the interrupted upgrade to %s, %d instances have been upgraded", clusterVersion, state.Done())
+	}
+	opt.options.UpgradeState = state
+
 	var (
 		downloadCompTasks []task.Task // tasks which are used to download components
 		copyCompTasks     []task.Task // tasks which are used to copy components to remote host
@@ -87,10 +86,6 @@ func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 		uniqueComps = map[componentInfo]struct{}{}
 	)
 
-	if err := versionCompare(metadata.Version, clusterVersion); err != nil {
-		return err
-	}
-
 	for _, comp := range metadata.Topology.ComponentsByStartOrder() {
 		for _, inst := range comp.Instances() {
 			version := bindversion.ComponentVersion(inst.ComponentName(), clusterVersion)
@@ -115,20 +110,28 @@ func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 			// data dir would be empty for components which don't need it
 			dataDir := inst.DataDir()
 			if dataDir != "" {
-				clusterutil.Abs(metadata.User, dataDir)
+				dataDir = clusterutil.Abs(metadata.User, dataDir)
 			}
 			// log dir will always be with values, but might not used by the component
 			logDir := clusterutil.Abs(metadata.User, inst.LogDir())
 
 			// Deploy component
 			tb := task.NewBuilder()
+			backup := func() {
+				id := inst.ID()
+				if state.State(id) != operator.NodePending {
+					return
+				}
+				tb.BackupComponent(inst.ComponentName(), metadata.Version, inst.GetHost(), deployDir).
+					Func("record backup", func() error { return state.BackedUp(id) })
+			}
 			if inst.IsImported() {
 				switch inst.ComponentName() {
 				case meta.ComponentPrometheus, meta.ComponentGrafana, meta.ComponentAlertManager:
 					tb.CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir)
 				default:
-					tb.BackupComponent(inst.ComponentName(), metadata.Version, inst.GetHost(), deployDir).
-						CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir)
+					backup()
+					tb.CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir)
 				}
 				tb.InitConfig(
 					clusterName,
@@ -143,24 +146,37 @@ func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 					},
 				)
 			} else {
-				tb.BackupComponent(inst.ComponentName(), metadata.Version, inst.GetHost(), deployDir).
-					CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir)
+				backup()
+				tb.CopyComponent(inst.ComponentName(), version, inst.GetHost(), deployDir)
 			}
 			copyCompTasks = append(copyCompTasks, tb.Build())
 		}
 	}
 
-	t := task.NewBuilder().
+	b := task.NewBuilder().
+		CheckUpgradePath(metadata.Version, clusterVersion).
+		ValidateConfig(metadata.Topology, clusterVersion).
 		SSHKeySet(
 			meta.ClusterPath(clusterName, "ssh", "id_rsa"),
 			meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
 		ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+		CheckFileLimits(selectedInstances(metadata.Topology, opt.options)).
 		Parallel(downloadCompTasks...).
-		Parallel(copyCompTasks...).
-		ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt.options).
-		Build()
+		Breakpoint("upgrading the instances",
+			fmt.Sprintf("Cluster: %s", clusterName),
+			fmt.Sprintf("Version: %s -> %s", metadata.Version, clusterVersion),
+			fmt.Sprintf("Instances: %s", instancesSummary(metadata.Topology))).
+		Parallel(copyCompTasks...)
+	if !opt.skipPDBackup {
+		b.BackupPDMeta(metadata.Topology, "upgrade", pdBackupPath(clusterName, "upgrade"))
+	}
+	t := b.ClusterOperate(metadata.Topology, operator.UpgradeOperation, opt.options).Build()
 
-	if err := t.Execute(task.NewContext()); err != nil {
+	if err := runValidationHook("upgrade", clusterName, clusterVersion, opt.options.Nodes, metadata.Topology, t); err != nil {
+		return err
+	}
+
+	if err := t.Execute(ctx); err != nil {
 		if errorx.Cast(err) != nil {
 			// FIXME: Map possible task errors and give suggestions.
 			return err
@@ -175,8 +191,15 @@ func upgrade(clusterName, clusterVersion string, opt upgradeOptions) error {
 	if err := os.RemoveAll(meta.ClusterPath(clusterName, "patch")); err != nil {
 		return errors.Trace(err)
 	}
+	var upgraded []string
+	for _, inst := range selectedInstances(metadata.Topology, opt.options) {
+		upgraded = append(upgraded, inst.ID())
+	}
+	if err := state.Finish(upgraded); err != nil {
+		return err
+	}
 
 	log.Infof("Upgraded cluster `%s` successfully", clusterName)
 
-	return nil
+	return runVerification("upgrade", clusterName)
 }
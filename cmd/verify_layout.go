@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/bindversion"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyLayoutCmd() *cobra.Command {
+	var (
+		nodes  []string
+		roles  []string
+		repair bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify-layout <cluster-name>",
+		Short: "Verify the deploy directories of the instances",
+		Long: `Verify the deploy directory of each instance has the bin/, conf/ and scripts/
+subdirectories, the main binary, the config files and the run script, which may be
+moved around manually. The missing pieces are staged again with --repair.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot verify non-exists cluster %s", clusterName)
+			}
+
+			if repair {
+				logger.EnableAuditLog()
+			}
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			nodeFilter := set.NewStringSet(nodes...)
+			roleFilter := set.NewStringSet(roles...)
+			var verifyTasks []*task.StepDisplay
+			metadata.Topology.IterInstance(func(inst meta.Instance) {
+				if len(nodeFilter) > 0 && !nodeFilter.Exist(inst.ID()) {
+					return
+				}
+				if len(roleFilter) > 0 && !roleFilter.Exist(inst.ComponentName()) {
+					return
+				}
+				dataDir := inst.DataDir()
+				if dataDir != "" {
+					dataDir = clusterutil.Abs(metadata.User, dataDir)
+				}
+				t := task.NewBuilder().
+					VerifyLayout(
+						clusterName,
+						metadata.Version,
+						bindversion.ComponentVersion(inst.ComponentName(), metadata.Version),
+						inst,
+						metadata.User,
+						meta.DirPaths{
+							Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+							Data:   dataDir,
+							Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+							Cache:  meta.ClusterPath(clusterName, "config"),
+						},
+						repair,
+					).
+					BuildAsStep("  - Verify " + inst.ID())
+				verifyTasks = append(verifyTasks, t)
+			})
+			if len(verifyTasks) == 0 {
+				return errors.Errorf("no instance of cluster %s to verify", clusterName)
+			}
+
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				ParallelStep("+ Verify deploy directories", verifyTasks...).
+				Build()
+
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			log.Infof("The deploy directories of %d instances of cluster `%s` are verified", len(verifyTasks), clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&nodes, "node", "N", nil, "Only verify specified nodes")
+	cmd.Flags().StringSliceVarP(&roles, "role", "R", nil, "Only verify specified roles")
+	cmd.Flags().BoolVar(&repair, "repair", false, "Stage the missing directories, binaries, config files and scripts again")
+
+	return cmd
+}
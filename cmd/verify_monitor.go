@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
+	"github.com/pingcap/errors"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyMonitorCmd() *cobra.Command {
+	var regenerate bool
+
+	cmd := &cobra.Command{
+		Use:   "verify-monitor <cluster-name>",
+		Short: "Verify the scrape targets of Prometheus match the topology",
+		Long: `Verify the scrape targets in the prometheus.yml deployed to each Prometheus instance
+match the instances of the current topology, they may be stale after the topology
+is changed. The config is generated and Prometheus is reloaded with --regenerate.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Help()
+			}
+
+			clusterName := args[0]
+			if tiuputils.IsNotExist(meta.ClusterPath(clusterName, meta.MetaFileName)) {
+				return errors.Errorf("cannot verify non-exists cluster %s", clusterName)
+			}
+
+			if regenerate {
+				logger.EnableAuditLog()
+			}
+			metadata, err := meta.ClusterMetadata(clusterName)
+			if err != nil {
+				return err
+			}
+
+			var verifyTasks []*task.StepDisplay
+			for _, inst := range (&meta.MonitorComponent{Specification: metadata.Topology}).Instances() {
+				t := task.NewBuilder().
+					CheckMonitorTargets(
+						clusterName,
+						metadata.Version,
+						inst.(*meta.MonitorInstance),
+						metadata.User,
+						meta.DirPaths{
+							Deploy: clusterutil.Abs(metadata.User, inst.DeployDir()),
+							Data:   clusterutil.Abs(metadata.User, inst.DataDir()),
+							Log:    clusterutil.Abs(metadata.User, inst.LogDir()),
+							Cache:  meta.ClusterPath(clusterName, "config"),
+						},
+						regenerate,
+					).
+					BuildAsStep("  - Verify " + inst.ID())
+				verifyTasks = append(verifyTasks, t)
+			}
+			if len(verifyTasks) == 0 {
+				return errors.Errorf("no Prometheus instance of cluster %s to verify", clusterName)
+			}
+
+			t := task.NewBuilder().
+				SSHKeySet(
+					meta.ClusterPath(clusterName, "ssh", "id_rsa"),
+					meta.ClusterPath(clusterName, "ssh", "id_rsa.pub")).
+				ClusterSSH(metadata.Topology, metadata.User, sshTimeout).
+				ParallelStep("+ Verify scrape targets of Prometheus", verifyTasks...).
+				Build()
+
+			if err := t.Execute(newTaskContext()); err != nil {
+				if errorx.Cast(err) != nil {
+					// FIXME: Map possible task errors and give suggestions.
+					return err
+				}
+				return errors.Trace(err)
+			}
+
+			log.Infof("The scrape targets of Prometheus of cluster `%s` match the topology", clusterName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&regenerate, "regenerate", false, "Generate the config of Prometheus from the topology and reload it if the targets are stale")
+
+	return cmd
+}
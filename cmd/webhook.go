@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/task"
+)
+
+const (
+	// webhookTimeout is the timeout of each post to the webhook
+	webhookTimeout = 5 * time.Second
+	// webhookFlushTimeout is the max time waited for the events to be posted before exiting
+	webhookFlushTimeout = 15 * time.Second
+)
+
+var (
+	webhookURL string // URL to post the lifecycle events of the mutating operations to
+
+	webhook          *task.Webhook
+	operationEvents  = task.NewEventBus()
+	webhookOperation string
+	webhookCluster   string
+)
+
+// notifyOperationBegin posts the start of the operation on the cluster to the webhook if
+// it's specified
+func notifyOperationBegin(operation, clusterName string) {
+	if webhookURL == "" {
+		return
+	}
+	webhook = task.NewWebhook(webhookURL, webhookTimeout)
+	operationEvents.SetChangeID(changeID)
+	webhook.Attach(&operationEvents)
+	webhookOperation, webhookCluster = operation, clusterName
+	operationEvents.PublishOperationBegin(operation, clusterName)
+}
+
+// notifyOperationFinish posts the result of the operation to the webhook, and waits for the
+// events to be posted
+func notifyOperationFinish(err error) {
+	if webhook == nil {
+		return
+	}
+	operationEvents.PublishOperationFinish(webhookOperation, webhookCluster, err)
+	webhook.Close(webhookFlushTimeout)
+}
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap/errors"
 	"go.etcd.io/etcd/clientv3"
 )
@@ -188,3 +189,27 @@ func (c *BinlogClient) OfflinePump(addr string, nodeID string) error {
 func (c *BinlogClient) OfflineDrainer(addr string, nodeID string) error {
 	return c.offline(addr, nodeID)
 }
+
+// DrainerStatus represents the response of the status api of drainer
+type DrainerStatus struct {
+	PumpPos map[string]int64 `json:"PumpPos"` // the latest commit ts received from each pump
+	Synced  bool             `json:"Synced"`  // whether all the received binlogs are replicated downstream
+	LastTS  int64            `json:"LastTS"`  // the commit ts of the saved checkpoint
+}
+
+// GetDrainerStatus queries the status api of the drainer of the address
+func GetDrainerStatus(addr string, timeout time.Duration, tlsConfig *tls.Config) (*DrainerStatus, error) {
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	body, err := utils.NewHTTPClient(timeout, tlsConfig).Get(fmt.Sprintf("%s://%s/status", scheme, addr))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var status DrainerStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, errors.Annotatef(err, "data: %s", string(body))
+	}
+	return &status, nil
+}
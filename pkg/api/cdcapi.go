@@ -0,0 +1,122 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// CDCClient is an HTTP client of the TiCDC open API, any capture of the cluster serves
+// the requests and forwards them to the owner if needed
+type CDCClient struct {
+	addrs      []string
+	tlsEnabled bool
+	httpClient *utils.HTTPClient
+}
+
+// NewCDCClient returns a new CDCClient
+func NewCDCClient(addrs []string, timeout time.Duration, tlsConfig *tls.Config) *CDCClient {
+	return &CDCClient{
+		addrs:      addrs,
+		tlsEnabled: tlsConfig != nil,
+		httpClient: utils.NewHTTPClient(timeout, tlsConfig),
+	}
+}
+
+var (
+	cdcCapturesURI    = "api/v1/captures"
+	cdcDrainURI       = "api/v1/captures/drain"
+	cdcOwnerResignURI = "api/v1/owner/resign"
+)
+
+// Capture is a TiCDC server registered in the cluster
+type Capture struct {
+	ID            string `json:"id"`
+	IsOwner       bool   `json:"is_owner"`
+	AdvertiseAddr string `json:"address"`
+}
+
+type drainCaptureRequest struct {
+	CaptureID string `json:"capture_id"`
+}
+
+type drainCaptureResponse struct {
+	CurrentTableCount int `json:"current_table_count"`
+}
+
+func (c *CDCClient) getEndpoints(cmd string) (endpoints []string) {
+	scheme := "http"
+	if c.tlsEnabled {
+		scheme = "https"
+	}
+	for _, addr := range c.addrs {
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s/%s", scheme, addr, cmd))
+	}
+	return
+}
+
+// GetCaptures queries the captures of the TiCDC cluster
+func (c *CDCClient) GetCaptures() ([]*Capture, error) {
+	var captures []*Capture
+	err := tryURLs(c.getEndpoints(cdcCapturesURI), func(endpoint string) error {
+		body, err := c.httpClient.Get(endpoint)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &captures)
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return captures, nil
+}
+
+// ResignOwner makes the current owner resign, a new owner is elected from the captures
+func (c *CDCClient) ResignOwner() error {
+	err := tryURLs(c.getEndpoints(cdcOwnerResignURI), func(endpoint string) error {
+		_, err := c.httpClient.Post(endpoint, nil)
+		return err
+	})
+	return errors.AddStack(err)
+}
+
+// DrainCapture moves the tables replicated by the capture to the other captures, it
+// returns the number of the tables still on the capture. It must be called repeatedly
+// until no table is left because the tables are moved asynchronously.
+func (c *CDCClient) DrainCapture(id string) (int, error) {
+	req, err := json.Marshal(drainCaptureRequest{CaptureID: id})
+	if err != nil {
+		return 0, errors.AddStack(err)
+	}
+
+	var resp drainCaptureResponse
+	err = tryURLs(c.getEndpoints(cdcDrainURI), func(endpoint string) error {
+		body, err := c.httpClient.Put(endpoint, bytes.NewReader(req))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &resp)
+	})
+	if err != nil {
+		return 0, errors.AddStack(err)
+	}
+	return resp.CurrentTableCount, nil
+}
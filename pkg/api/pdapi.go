@@ -225,6 +225,38 @@ func (pc *PDClient) GetMembers() (*pdpb.GetMembersResponse, error) {
 	return &members, nil
 }
 
+// pdMetadataURIs are the APIs the metadata of the PD cluster is exported from
+var pdMetadataURIs = map[string]string{
+	"cluster":    pdClusterIDURI,
+	"config":     pdConfigURI,
+	"members":    pdMembersURI,
+	"stores":     pdStoresURI,
+	"schedulers": pdSchedulersURI,
+}
+
+// GetMetadata exports the metadata of the PD cluster, e.g. the cluster ID, the config, the
+// members, the stores and the schedulers, as the raw responses of the APIs by name
+func (pc *PDClient) GetMetadata() (map[string]json.RawMessage, error) {
+	metadata := make(map[string]json.RawMessage)
+	for name, uri := range pdMetadataURIs {
+		err := tryURLs(pc.getEndpoints(uri), func(endpoint string) error {
+			body, err := pc.httpClient.Get(endpoint)
+			if err != nil {
+				return err
+			}
+			if !json.Valid(body) {
+				return errors.Errorf("invalid response of %s: %s", endpoint, body)
+			}
+			metadata[name] = body
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to get the %s of PD", name)
+		}
+	}
+	return metadata, nil
+}
+
 // EvictPDLeader evicts the PD leader
 func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
 	// get current members
@@ -281,6 +313,46 @@ func (pc *PDClient) EvictPDLeader(retryOpt *utils.RetryOption) error {
 	return nil
 }
 
+// TransferPDLeader transfers the PD leadership to the member with the given name
+// and waits until the member becomes the leader
+func (pc *PDClient) TransferPDLeader(name string, retryOpt *utils.RetryOption) error {
+	cmd := fmt.Sprintf("%s/%s", pdLeaderTransferURI, name)
+	endpoints := pc.getEndpoints(cmd)
+
+	err := tryURLs(endpoints, func(endpoint string) error {
+		_, err := pc.httpClient.Post(endpoint, nil)
+		return err
+	})
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	// wait for the transfer to complete
+	if retryOpt == nil {
+		retryOpt = &utils.RetryOption{
+			Delay:   time.Second * 2,
+			Timeout: time.Second * 60,
+		}
+	}
+	if err := utils.Retry(func() error {
+		currLeader, err := pc.GetLeader()
+		if err != nil {
+			return err
+		}
+
+		if currLeader.Name == name {
+			return nil
+		}
+
+		// return error by default, to make the retry work
+		log.Debugf("Still waitting for the PD leader to transfer to %s", name)
+		return errors.New("still waitting for the PD leader to transfer")
+	}, *retryOpt); err != nil {
+		return fmt.Errorf("error transferring PD leader to %s, %v", name, err)
+	}
+	return nil
+}
+
 const (
 	// pdEvictLeaderName is evict leader scheduler name.
 	pdEvictLeaderName = "evict-leader-scheduler"
@@ -579,3 +651,126 @@ func (pc *PDClient) DelStore(host string, retryOpt *utils.RetryOption) error {
 	}
 	return nil
 }
+
+// GetStore returns the latest store of a (TiKV) host, ErrStoreNotExists if there's none
+// The host parameter should be in format of IP:Port, that matches store's address
+func (pc *PDClient) GetStore(host string) (*pdserverapi.StoreInfo, error) {
+	stores, err := pc.GetStores()
+	if err != nil {
+		return nil, err
+	}
+
+	var latestStore *pdserverapi.StoreInfo
+	for _, storeInfo := range stores.Stores {
+		if storeInfo.Store.Address != host {
+			continue
+		}
+		if latestStore == nil || storeInfo.Store.Id > latestStore.Store.Id {
+			latestStore = storeInfo
+		}
+	}
+	if latestStore == nil {
+		return nil, errors.Annotatef(ErrStoreNotExists, "address: %s", host)
+	}
+	return latestStore, nil
+}
+
+// SetStoreLabels sets the labels of the store, the labels not specified are kept
+func (pc *PDClient) SetStoreLabels(storeID uint64, labels map[string]string) error {
+	body, err := json.Marshal(labels)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/%d/label", pdStoreURI, storeID))
+	err = tryURLs(endpoints, func(endpoint string) error {
+		_, err := pc.httpClient.Post(endpoint, bytes.NewBuffer(body))
+		return err
+	})
+	if err != nil {
+		return errors.Annotatef(err, "failed to set the labels of store %d", storeID)
+	}
+	return nil
+}
+
+// pdStoreWeightRequest is the request body when setting the weights of a store
+type pdStoreWeightRequest struct {
+	Leader float64 `json:"leader"`
+	Region float64 `json:"region"`
+}
+
+// SetStoreWeight sets the leader and region weights of the store
+func (pc *PDClient) SetStoreWeight(storeID uint64, leader, region float64) error {
+	body, err := json.Marshal(pdStoreWeightRequest{Leader: leader, Region: region})
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	endpoints := pc.getEndpoints(fmt.Sprintf("%s/%d/weight", pdStoreURI, storeID))
+	err = tryURLs(endpoints, func(endpoint string) error {
+		_, err := pc.httpClient.Post(endpoint, bytes.NewBuffer(body))
+		return err
+	})
+	if err != nil {
+		return errors.Annotatef(err, "failed to set the weights of store %d", storeID)
+	}
+	return nil
+}
+
+// ScheduleLimitKeys are the keys of the schedule config limiting the operators created by
+// PD concurrently, no region is moved by the schedulers and checkers if all of them are 0
+var ScheduleLimitKeys = []string{
+	"leader-schedule-limit",
+	"region-schedule-limit",
+	"replica-schedule-limit",
+	"merge-schedule-limit",
+	"hot-region-schedule-limit",
+}
+
+// GetScheduleLimits queries the limits of the schedule config by ScheduleLimitKeys
+func (pc *PDClient) GetScheduleLimits() (map[string]uint64, error) {
+	var config map[string]json.RawMessage
+	endpoints := pc.getEndpoints(pdConfigURI + "/schedule")
+	err := tryURLs(endpoints, func(endpoint string) error {
+		body, err := pc.httpClient.Get(endpoint)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &config)
+	})
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to get the schedule config of PD")
+	}
+
+	limits := make(map[string]uint64)
+	for _, key := range ScheduleLimitKeys {
+		raw, ok := config[key]
+		if !ok {
+			continue
+		}
+		var limit uint64
+		if err := json.Unmarshal(raw, &limit); err != nil {
+			return nil, errors.Annotatef(err, "invalid %s of PD: %s", key, raw)
+		}
+		limits[key] = limit
+	}
+	return limits, nil
+}
+
+// SetScheduleLimits sets the limits of the schedule config, the ones not specified are kept
+func (pc *PDClient) SetScheduleLimits(limits map[string]uint64) error {
+	body, err := json.Marshal(limits)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	endpoints := pc.getEndpoints(pdConfigURI)
+	err = tryURLs(endpoints, func(endpoint string) error {
+		_, err := pc.httpClient.Post(endpoint, bytes.NewBuffer(body))
+		return err
+	})
+	if err != nil {
+		return errors.Annotate(err, "failed to set the schedule config of PD")
+	}
+	return nil
+}
@@ -29,6 +29,8 @@ func ComponentVersion(comp, version string) repository.Version {
 		return "v0.17.0"
 	case meta.ComponentPushwaygate:
 		return "v0.7.0"
+	case meta.ComponentTiProxy:
+		return "v1.0.0"
 	default:
 		return repository.Version(version)
 	}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errutil
+
+import (
+	"github.com/joomcode/errorx"
+)
+
+// Category is the category of a failure, for the operators and automation to tell the
+// failures apart without parsing the messages
+type Category string
+
+// The categories of the failures
+const (
+	// CategoryConnectivity means failing to connect or talk to a host, e.g. SSH is refused
+	// or a command timed out
+	CategoryConnectivity Category = "connectivity"
+	// CategoryPermission means the user is not permitted to do something, e.g. failing to
+	// authenticate or to write a file without sudo
+	CategoryPermission Category = "permission"
+	// CategoryPreCheck means the cluster or the hosts don't meet the requirements of the
+	// operation, which is refused before anything is changed
+	CategoryPreCheck Category = "pre_check"
+	// CategoryComponent means a component fails to be operated, e.g. it doesn't come up
+	CategoryComponent Category = "component"
+	// CategoryUnknown means the failure is not classified
+	CategoryUnknown Category = "unknown"
+)
+
+var (
+	// ErrPropCategory is a property of an Error that classifies it, it's attached by Classify.
+	ErrPropCategory = errorx.RegisterProperty("category")
+
+	// ErrTraitConnectivity means that the Error is a connectivity failure.
+	ErrTraitConnectivity = errorx.RegisterTrait("connectivity")
+	// ErrTraitPermission means that the Error is a permission failure.
+	ErrTraitPermission = errorx.RegisterTrait("permission")
+	// ErrTraitComponent means that the Error is a component failure.
+	ErrTraitComponent = errorx.RegisterTrait("component")
+)
+
+// categoryTraits are the traits of the Error types which classify them
+var categoryTraits = []struct {
+	trait    errorx.Trait
+	category Category
+}{
+	{ErrTraitConnectivity, CategoryConnectivity},
+	{ErrTraitPermission, CategoryPermission},
+	{ErrTraitPreCheck, CategoryPreCheck},
+	{ErrTraitComponent, CategoryComponent},
+}
+
+// Classify classifies the error into the category if it's not classified yet, so the
+// category of the innermost classified error wins, e.g. a component fails to start as
+// its host is unreachable is a connectivity failure. The message and the type of the
+// error are kept.
+func Classify(err error, category Category) error {
+	if err == nil || CategoryOf(err) != CategoryUnknown {
+		return err
+	}
+	return errorx.Decorate(err, "").WithProperty(ErrPropCategory, category)
+}
+
+// CategoryOf returns the category of the error, which is the one of the outermost error
+// in the chain of causes classified by the property or the traits
+func CategoryOf(err error) Category {
+	for err != nil {
+		if x := errorx.Cast(err); x != nil {
+			if v, ok := x.Property(ErrPropCategory); ok {
+				if category, ok := v.(Category); ok {
+					return category
+				}
+			}
+			for _, t := range categoryTraits {
+				if x.HasTrait(t.trait) {
+					return t.category
+				}
+			}
+		}
+		switch e := err.(type) {
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return CategoryUnknown
+		}
+	}
+	return CategoryUnknown
+}
+
+// IsConnectivityError returns whether the error is a connectivity failure
+func IsConnectivityError(err error) bool {
+	return CategoryOf(err) == CategoryConnectivity
+}
+
+// IsPermissionError returns whether the error is a permission failure
+func IsPermissionError(err error) bool {
+	return CategoryOf(err) == CategoryPermission
+}
+
+// IsPreCheckError returns whether the error is a pre-check failure
+func IsPreCheckError(err error) bool {
+	return CategoryOf(err) == CategoryPreCheck
+}
+
+// IsComponentError returns whether the error is a component failure
+func IsComponentError(err error) bool {
+	return CategoryOf(err) == CategoryComponent
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+	pingcaperrors "github.com/pingcap/errors"
+)
+
+type errutilSuite struct{}
+
+var _ = Suite(&errutilSuite{})
+
+func TestErrutil(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *errutilSuite) TestCategory(c *C) {
+	errNS := errorx.NewNamespace("test")
+	errPreCheck := errNS.NewType("pre_check", ErrTraitPreCheck)
+	errTimedout := errNS.NewType("timedout", ErrTraitConnectivity)
+	errPlain := errNS.NewType("plain")
+
+	c.Assert(CategoryOf(nil), Equals, CategoryUnknown)
+	c.Assert(CategoryOf(errors.New("failed")), Equals, CategoryUnknown)
+	c.Assert(CategoryOf(errPlain.New("failed")), Equals, CategoryUnknown)
+
+	// by the traits through the annotations
+	err := pingcaperrors.Annotate(errPreCheck.New("not ready"), "failed to deploy")
+	c.Assert(IsPreCheckError(err), IsTrue)
+	c.Assert(IsConnectivityError(pingcaperrors.AddStack(errTimedout.New("timed out"))), IsTrue)
+
+	// classified without changing the message and the type
+	plain := errPlain.New("no such file")
+	err = Classify(plain, CategoryPermission)
+	c.Assert(err.Error(), Equals, plain.Error())
+	c.Assert(errorx.IsOfType(err, errPlain), IsTrue)
+	c.Assert(IsPermissionError(pingcaperrors.Annotate(err, "failed to mkdir")), IsTrue)
+	c.Assert(Classify(nil, CategoryPermission), IsNil)
+
+	// the innermost classification wins
+	err = Classify(pingcaperrors.Annotate(errTimedout.New("timed out"), "failed to start"), CategoryComponent)
+	c.Assert(IsConnectivityError(err), IsTrue)
+	c.Assert(IsComponentError(err), IsFalse)
+	err = Classify(Classify(errors.New("not up"), CategoryComponent), CategoryPermission)
+	c.Assert(IsComponentError(err), IsTrue)
+	c.Assert(err.Error(), Equals, "not up")
+}
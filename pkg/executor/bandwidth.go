@@ -0,0 +1,151 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// transferChunk is the most bytes a transfer takes from the limiter at a time, so that the
+// concurrent transfers share the bandwidth evenly
+const transferChunk = 32 * 1024
+
+// BandwidthLimiter is a token bucket limiting the aggregate rate of the transfers sharing
+// it, the tokens are the bytes allowed to be transferred
+type BandwidthLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBandwidthLimiter returns a BandwidthLimiter of the rate in bytes per second, up to 100ms
+// of the rate can be transferred in a burst
+func NewBandwidthLimiter(rate int64) *BandwidthLimiter {
+	burst := float64(rate) / 10
+	if burst < transferChunk {
+		burst = transferChunk
+	}
+	return &BandwidthLimiter{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// WaitN blocks until n bytes are allowed to be transferred. The bytes are reserved before
+// waiting so that the concurrent callers are served in order.
+func (l *BandwidthLimiter) WaitN(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+var (
+	transferLimiterMu sync.RWMutex
+	transferLimiter   *BandwidthLimiter
+)
+
+// SetTransferBandwidth caps the aggregate rate of all the file transfers of the executors in
+// MB/s, 0 means unlimited
+func SetTransferBandwidth(mbps float64) {
+	transferLimiterMu.Lock()
+	defer transferLimiterMu.Unlock()
+	if mbps <= 0 {
+		transferLimiter = nil
+		return
+	}
+	transferLimiter = NewBandwidthLimiter(int64(mbps * 1024 * 1024))
+}
+
+func currentTransferLimiter() *BandwidthLimiter {
+	transferLimiterMu.RLock()
+	defer transferLimiterMu.RUnlock()
+	return transferLimiter
+}
+
+// limitedReader reads at the rate allowed by the limiter
+type limitedReader struct {
+	r io.Reader
+	l *BandwidthLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > transferChunk {
+		p = p[:transferChunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.l.WaitN(n)
+	}
+	return n, err
+}
+
+// limitedWriter writes at the rate allowed by the limiter
+type limitedWriter struct {
+	w io.Writer
+	l *BandwidthLimiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > transferChunk {
+			chunk = chunk[:transferChunk]
+		}
+		w.l.WaitN(len(chunk))
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// limitReader returns the reader throttled by the transfer bandwidth if it's capped
+func limitReader(r io.Reader) io.Reader {
+	if l := currentTransferLimiter(); l != nil {
+		return &limitedReader{r: r, l: l}
+	}
+	return r
+}
+
+// limitWriter returns the writer throttled by the transfer bandwidth if it's capped
+func limitWriter(w io.Writer) io.Writer {
+	if l := currentTransferLimiter(); l != nil {
+		return &limitedWriter{w: w, l: l}
+	}
+	return w
+}
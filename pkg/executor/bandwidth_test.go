@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *executorSuite) TestBandwidthLimiter(c *C) {
+	const (
+		rate      = 4 * 1024 * 1024 // 4MB/s
+		transfers = 4
+		size      = 512 * 1024
+	)
+	l := NewBandwidthLimiter(rate)
+
+	// the concurrent transfers share the bandwidth
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < transfers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			src := bytes.NewReader(make([]byte, size))
+			if i%2 == 0 {
+				n, err := io.Copy(ioutil.Discard, &limitedReader{r: src, l: l})
+				c.Check(err, IsNil)
+				c.Check(n, Equals, int64(size))
+			} else {
+				n, err := io.Copy(&limitedWriter{w: ioutil.Discard, l: l}, src)
+				c.Check(err, IsNil)
+				c.Check(n, Equals, int64(size))
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// only the burst can be transferred beyond the rate
+	total := float64(transfers * size)
+	minimum := time.Duration((total - l.burst) / rate * float64(time.Second))
+	c.Assert(elapsed >= minimum, IsTrue, Commentf("%d bytes are transferred in %s, faster than %d bytes/s", int(total), elapsed, rate))
+	c.Assert(elapsed < 3*minimum, IsTrue, Commentf("%d bytes are transferred in %s", int(total), elapsed))
+}
+
+func (s *executorSuite) TestTransferBandwidth(c *C) {
+	defer SetTransferBandwidth(0)
+
+	src := bytes.NewReader(nil)
+	c.Assert(limitReader(src), Equals, io.Reader(src))
+	c.Assert(limitWriter(ioutil.Discard), Equals, ioutil.Discard)
+
+	SetTransferBandwidth(1.5)
+	r, ok := limitReader(src).(*limitedReader)
+	c.Assert(ok, IsTrue)
+	c.Assert(r.l.rate, Equals, float64(1.5*1024*1024))
+	w, ok := limitWriter(ioutil.Discard).(*limitedWriter)
+	c.Assert(ok, IsTrue)
+	// the transfers share the same limiter
+	c.Assert(w.l, Equals, r.l)
+
+	SetTransferBandwidth(0)
+	c.Assert(currentTransferLimiter(), IsNil)
+}
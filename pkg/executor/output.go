@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	outputLimitMu sync.RWMutex
+	outputLimit   int64  // max bytes of stdout and stderr each kept in memory per command
+	outputSinkDir string // directory the full outputs of the truncated commands are saved to
+	outputSeq     int64  // sequence of the sink files in a run
+)
+
+// SetOutputLimit caps the bytes of stdout and stderr each captured from a command, 0 means
+// unlimited. The overflowing outputs are dropped with a marker, and saved to the files in
+// sinkDir in full if it's not empty.
+func SetOutputLimit(limit int64, sinkDir string) {
+	outputLimitMu.Lock()
+	defer outputLimitMu.Unlock()
+	if limit < 0 {
+		limit = 0
+	}
+	outputLimit = limit
+	outputSinkDir = sinkDir
+}
+
+// newCommandOutputs returns the capped buffers of the stdout and stderr of a command on the host
+func newCommandOutputs(host string) (stdout, stderr *cappedOutput) {
+	outputLimitMu.RLock()
+	limit, dir := outputLimit, outputSinkDir
+	outputLimitMu.RUnlock()
+
+	var outPath, errPath string
+	if limit > 0 && dir != "" {
+		name := fmt.Sprintf("%s-%s-%d", host, time.Now().Format("20060102150405"), atomic.AddInt64(&outputSeq, 1))
+		outPath = filepath.Join(dir, name+".stdout")
+		errPath = filepath.Join(dir, name+".stderr")
+	}
+	return newCappedOutput(limit, outPath), newCappedOutput(limit, errPath)
+}
+
+// cappedOutput is an io.Writer keeping up to limit bytes of the output, the rest is counted
+// and dropped. The full output is written to the sink file once the limit is exceeded, the
+// file is not created for the outputs within the limit.
+type cappedOutput struct {
+	limit     int64
+	buf       bytes.Buffer
+	truncated int64
+	sinkPath  string
+	sink      *os.File
+	sinkErr   error
+}
+
+func newCappedOutput(limit int64, sinkPath string) *cappedOutput {
+	return &cappedOutput{limit: limit, sinkPath: sinkPath}
+}
+
+// Write implements the io.Writer interface, it never fails so that the command is not
+// interrupted by the overflow, the failures of the sink are logged instead
+func (o *cappedOutput) Write(p []byte) (int, error) {
+	if o.limit <= 0 {
+		return o.buf.Write(p)
+	}
+	if o.sink != nil {
+		o.writeSink(p)
+	}
+
+	keep := o.limit - int64(o.buf.Len())
+	if keep > int64(len(p)) {
+		keep = int64(len(p))
+	}
+	if keep > 0 {
+		o.buf.Write(p[:keep])
+	} else {
+		keep = 0
+	}
+	if rest := p[keep:]; len(rest) > 0 {
+		o.truncated += int64(len(rest))
+		if o.sink == nil && o.sinkPath != "" && o.sinkErr == nil {
+			o.openSink()
+			o.writeSink(o.buf.Bytes())
+			o.writeSink(rest)
+		}
+	}
+	return len(p), nil
+}
+
+func (o *cappedOutput) openSink() {
+	if err := os.MkdirAll(filepath.Dir(o.sinkPath), 0755); err != nil {
+		o.sinkErr = err
+	} else {
+		o.sink, o.sinkErr = os.Create(o.sinkPath)
+	}
+	if o.sinkErr != nil {
+		zap.L().Warn("failed to create the output sink", zap.String("path", o.sinkPath), zap.Error(o.sinkErr))
+	}
+}
+
+func (o *cappedOutput) writeSink(p []byte) {
+	if o.sink == nil || o.sinkErr != nil {
+		return
+	}
+	if _, err := o.sink.Write(p); err != nil {
+		o.sinkErr = err
+		zap.L().Warn("failed to write the output sink", zap.String("path", o.sinkPath), zap.Error(err))
+	}
+}
+
+// String returns the kept output, with a marker of the dropped bytes and the sink file
+// if it's truncated
+func (o *cappedOutput) String() string {
+	if o.truncated == 0 {
+		return o.buf.String()
+	}
+	marker := fmt.Sprintf("[truncated %d bytes]", o.truncated)
+	if o.sink != nil && o.sinkErr == nil {
+		marker = fmt.Sprintf("[truncated %d bytes, the full output is saved to %s]", o.truncated, o.sinkPath)
+	}
+	out := o.buf.String()
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out += "\n"
+	}
+	return out + marker + "\n"
+}
+
+// Truncated returns the number of the dropped bytes
+func (o *cappedOutput) Truncated() int64 {
+	return o.truncated
+}
+
+// Close closes the sink file if it's created
+func (o *cappedOutput) Close() error {
+	if o.sink == nil {
+		return nil
+	}
+	return o.sink.Close()
+}
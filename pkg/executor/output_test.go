@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *executorSuite) TestCappedOutput(c *C) {
+	// unlimited
+	o := newCappedOutput(0, "")
+	_, _ = o.Write([]byte(strings.Repeat("a", 4096)))
+	c.Assert(o.String(), Equals, strings.Repeat("a", 4096))
+	c.Assert(o.Truncated(), Equals, int64(0))
+
+	// within the limit
+	o = newCappedOutput(16, "")
+	_, _ = o.Write([]byte("hello\n"))
+	c.Assert(o.String(), Equals, "hello\n")
+
+	// the output over the limit is truncated with a marker
+	o = newCappedOutput(16, "")
+	for i := 0; i < 10; i++ {
+		n, err := o.Write([]byte("0123456789\n"))
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, 11)
+	}
+	c.Assert(o.Truncated(), Equals, int64(110-16))
+	c.Assert(o.String(), Equals, "0123456789\n01234\n[truncated 94 bytes]\n")
+	c.Assert(o.Close(), IsNil)
+}
+
+func (s *executorSuite) TestCappedOutputSink(c *C) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "sink", "172.16.5.140-1.stdout")
+	full := strings.Repeat("0123456789\n", 100)
+
+	o := newCappedOutput(32, path)
+	for _, line := range strings.SplitAfter(full, "\n") {
+		_, _ = o.Write([]byte(line))
+	}
+	c.Assert(o.Close(), IsNil)
+	c.Assert(o.Truncated(), Equals, int64(len(full)-32))
+	c.Assert(o.String(), Equals, full[:32]+"\n[truncated 1068 bytes, the full output is saved to "+path+"]\n")
+
+	// the sink has the full output
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, full)
+
+	// the sink is not created for the outputs within the limit
+	path = filepath.Join(dir, "172.16.5.141-2.stdout")
+	o = newCappedOutput(32, path)
+	_, _ = o.Write([]byte("ok\n"))
+	c.Assert(o.Close(), IsNil)
+	c.Assert(o.String(), Equals, "ok\n")
+	_, err = ioutil.ReadFile(path)
+	c.Assert(err, NotNil)
+}
+
+func (s *executorSuite) TestSetOutputLimit(c *C) {
+	defer SetOutputLimit(0, "")
+
+	stdout, stderr := newCommandOutputs("172.16.5.140")
+	c.Assert(stdout.limit, Equals, int64(0))
+	c.Assert(stdout.sinkPath, Equals, "")
+
+	dir := c.MkDir()
+	SetOutputLimit(1024, dir)
+	stdout, stderr = newCommandOutputs("172.16.5.140")
+	c.Assert(stdout.limit, Equals, int64(1024))
+	c.Assert(stderr.limit, Equals, int64(1024))
+	c.Assert(filepath.Dir(stdout.sinkPath), Equals, dir)
+	c.Assert(stdout.sinkPath, Matches, ".*/172.16.5.140-[0-9]+-[0-9]+.stdout")
+	c.Assert(stderr.sinkPath, Equals, strings.TrimSuffix(stdout.sinkPath, ".stdout")+".stderr")
+
+	// the sink files of the commands don't collide
+	next, _ := newCommandOutputs("172.16.5.140")
+	c.Assert(next.sinkPath, Not(Equals), stdout.sinkPath)
+}
@@ -14,7 +14,10 @@
 package executor
 
 import (
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -46,9 +49,19 @@ var (
 	// ErrSSHExecuteFailed is ErrSSHExecuteFailed
 	ErrSSHExecuteFailed = errNSSSH.NewType("execute_failed")
 	// ErrSSHExecuteTimedout is ErrSSHExecuteTimedout
-	ErrSSHExecuteTimedout = errNSSSH.NewType("execute_timedout")
+	ErrSSHExecuteTimedout = errNSSSH.NewType("execute_timedout", errutil.ErrTraitConnectivity)
 )
 
+// permissionDeniedMessages are the messages in stderr which mean the command is refused
+// for the lack of the permission
+var permissionDeniedMessages = []string{
+	"Permission denied",
+	"Operation not permitted",
+	"sudo: a password is required",
+	"sudo: a terminal is required",
+	"is not in the sudoers file",
+}
+
 var executeDefaultTimeout = time.Second * 60
 
 func init() {
@@ -105,7 +118,7 @@ func (e *SSHExecutor) Initialize(config SSHConfig) {
 
 	// build easyssh config
 	e.Config = &easyssh.MakeConfig{
-		Server:  config.Host,
+		Server:  utils.UnwrapHost(config.Host),
 		Port:    strconv.Itoa(config.Port),
 		User:    config.User,
 		Timeout: config.Timeout, // timeout when connecting to remote
@@ -120,11 +133,17 @@ func (e *SSHExecutor) Initialize(config SSHConfig) {
 	}
 }
 
+// SudoCommand wraps the command to be run as root. The command is double quoted by the outer
+// shell, so `$`, backquotes and double quotes in it must be escaped or avoided.
+func SudoCommand(cmd string) string {
+	return fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
+}
+
 // Execute run the command via SSH, it's not invoking any specific shell by default.
 func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
 	// try to acquire root permission
 	if sudo {
-		cmd = fmt.Sprintf("sudo -H -u root bash -c \"%s\"", cmd)
+		cmd = SudoCommand(cmd)
 	}
 
 	// set a basic PATH in case it's empty on login
@@ -135,7 +154,7 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 	if len(timeout) == 0 {
 		timeout = append(timeout, executeDefaultTimeout)
 	}
-	stdout, stderr, done, err := e.Config.Run(cmd, timeout...)
+	stdout, stderr, done, err := e.run(cmd, timeout...)
 
 	zap.L().Info("ssh command",
 		zap.String("host", e.Config.Server),
@@ -146,7 +165,7 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 
 	if err != nil {
 		baseErr := ErrSSHExecuteFailed.
-			Wrap(err, "Failed to execute command over SSH for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
+			Wrap(err, "Failed to execute command over SSH for '%s@%s'", e.Config.User, net.JoinHostPort(e.Config.Server, e.Config.Port)).
 			WithProperty(ErrPropSSHCommand, cmd).
 			WithProperty(ErrPropSSHStdout, stdout).
 			WithProperty(ErrPropSSHStderr, stderr)
@@ -157,12 +176,12 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 					e.Config.Server,
 					color.YellowString(output)))
 		}
-		return []byte(stdout), []byte(stderr), baseErr
+		return []byte(stdout), []byte(stderr), classifySSHError(baseErr, stderr)
 	}
 
 	if !done { // timeout case,
 		return []byte(stdout), []byte(stderr), ErrSSHExecuteTimedout.
-			Wrap(err, "Execute command over SSH timedout for '%s@%s:%s'", e.Config.User, e.Config.Server, e.Config.Port).
+			Wrap(err, "Execute command over SSH timedout for '%s@%s'", e.Config.User, net.JoinHostPort(e.Config.Server, e.Config.Port)).
 			WithProperty(ErrPropSSHCommand, cmd).
 			WithProperty(ErrPropSSHStdout, stdout).
 			WithProperty(ErrPropSSHStderr, stderr)
@@ -171,19 +190,85 @@ func (e *SSHExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) (
 	return []byte(stdout), []byte(stderr), nil
 }
 
+// run is like easyssh's Run, except that the outputs are capped by the output limit rather
+// than accumulated in full
+func (e *SSHExecutor) run(cmd string, timeout ...time.Duration) (string, string, bool, error) {
+	outChan, errChan, doneChan, resultChan, err := e.Config.Stream(cmd, timeout...)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	stdout, stderr := newCommandOutputs(e.Config.Server)
+	defer stdout.Close()
+	defer stderr.Close()
+
+	var done bool
+loop:
+	for {
+		select {
+		case done = <-doneChan:
+			break loop
+		case line := <-outChan:
+			if line != "" {
+				_, _ = stdout.Write([]byte(line + "\n"))
+			}
+		case line := <-errChan:
+			if line != "" {
+				_, _ = stderr.Write([]byte(line + "\n"))
+			}
+		case err = <-resultChan:
+		}
+	}
+	if stdout.Truncated() > 0 || stderr.Truncated() > 0 {
+		zap.L().Warn("the output of the ssh command is truncated",
+			zap.String("host", e.Config.Server),
+			zap.String("cmd", cmd),
+			zap.Int64("stdout_truncated", stdout.Truncated()),
+			zap.Int64("stderr_truncated", stderr.Truncated()))
+	}
+	return stdout.String(), stderr.String(), done, err
+}
+
+// classifySSHError classifies the error of a command or a transfer by the cause and the
+// stderr, the error is returned as it is if the category is unknown, e.g. the command
+// just exits with a non-zero code
+func classifySSHError(err error, stderr string) error {
+	msg := err.Error()
+	if strings.Contains(msg, "ssh: unable to authenticate") {
+		return errutil.Classify(err, errutil.CategoryPermission)
+	}
+	for _, denied := range permissionDeniedMessages {
+		if strings.Contains(stderr, denied) {
+			return errutil.Classify(err, errutil.CategoryPermission)
+		}
+	}
+	cause := err
+	if x := errorx.Cast(err); x != nil && x.Cause() != nil {
+		cause = x.Cause()
+	}
+	var netErr net.Error
+	if stderrors.As(cause, &netErr) || strings.Contains(msg, "ssh: handshake failed") {
+		return errutil.Classify(err, errutil.CategoryConnectivity)
+	}
+	return err
+}
+
 // Transfer copies files via SCP
 // This function depends on `scp` (a tool from OpenSSH or other SSH implementation)
 // This function is based on easyssh.MakeConfig.Scp() but with support of copying
 // file from remote to local.
 func (e *SSHExecutor) Transfer(src string, dst string, download bool) error {
 	if !download {
-		return e.Config.Scp(src, dst)
+		if err := e.scp(src, dst); err != nil {
+			return classifySSHError(uploadFailed(e, e.Config.Server, src, dst, err), "")
+		}
+		return nil
 	}
 
 	// download file from remote
 	session, client, err := e.Config.Connect()
 	if err != nil {
-		return err
+		return classifySSHError(err, "")
 	}
 	defer client.Close()
 	defer session.Close()
@@ -192,12 +277,44 @@ func (e *SSHExecutor) Transfer(src string, dst string, download bool) error {
 	if err = utils.CreateDir(targetPath); err != nil {
 		return err
 	}
-	targetFile, err := os.Create(dst)
+	return downloadTo(dst, func(w io.Writer) error {
+		session.Stdout = limitWriter(w)
+		return session.Run(fmt.Sprintf("cat %s", src))
+	})
+}
+
+// scp uploads the file by the scp protocol like easyssh.MakeConfig.Scp(), and the content is
+// throttled by the transfer bandwidth
+func (e *SSHExecutor) scp(src string, dst string) error {
+	session, client, err := e.Config.Connect()
 	if err != nil {
 		return err
 	}
+	defer client.Close()
+	defer session.Close()
 
-	session.Stdout = targetFile
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer w.Close()
+		fmt.Fprintln(w, "C0644", stat.Size(), filepath.Base(dst))
+		if stat.Size() > 0 {
+			_, _ = io.Copy(w, limitReader(f))
+		}
+		fmt.Fprint(w, "\x00")
+	}()
 
-	return session.Run(fmt.Sprintf("cat %s", src))
+	return session.Run(fmt.Sprintf("scp -tr %s", dst))
 }
@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	. "github.com/pingcap/check"
+)
+
+type executorSuite struct {
+}
+
+var _ = Suite(&executorSuite{})
+
+func TestExecutor(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *executorSuite) TestIPv6Host(c *C) {
+	for _, host := range []string{"fd00::1", "[fd00::1]"} {
+		e := NewSSHExecutor(SSHConfig{Host: host, Port: 22, User: "tidb"})
+		c.Assert(e.Config.Server, Equals, "fd00::1")
+		c.Assert(e.Config.Port, Equals, "22")
+	}
+}
+
+func (s *executorSuite) TestIPv6Dial(c *C) {
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		c.Skip("IPv6 loopback is not available")
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// nothing is listening on the port, the dial must reach the IPv6 address
+	// instead of failing to parse it
+	e := NewSSHExecutor(SSHConfig{
+		Host:     "[::1]",
+		Port:     port,
+		User:     "tidb",
+		Password: "tidb",
+		Timeout:  time.Second,
+	})
+	_, _, err = e.Execute("ls", false)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, `(?s).*'tidb@\[::1\]:`+strconv.Itoa(port)+`'.*`)
+	c.Assert(err.Error(), Not(Matches), "(?s).*too many colons.*")
+}
+
+func (s *executorSuite) TestClassifySSHError(c *C) {
+	wrap := func(err error) error {
+		return ErrSSHExecuteFailed.Wrap(err, "Failed to execute command over SSH for 'tidb@172.16.5.140:22'")
+	}
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}
+	c.Assert(errutil.IsConnectivityError(classifySSHError(wrap(refused), "")), IsTrue)
+	c.Assert(errutil.IsConnectivityError(classifySSHError(errors.New("ssh: handshake failed: EOF"), "")), IsTrue)
+	c.Assert(errutil.IsConnectivityError(ErrSSHExecuteTimedout.New("timed out")), IsTrue)
+
+	err := classifySSHError(wrap(errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]")), "")
+	c.Assert(errutil.IsPermissionError(err), IsTrue)
+	err = classifySSHError(wrap(errors.New("Process exited with status 1")), "mkdir: cannot create directory '/data': Permission denied\n")
+	c.Assert(errutil.IsPermissionError(err), IsTrue)
+	c.Assert(errorx.IsOfType(err, ErrSSHExecuteFailed), IsTrue)
+	err = classifySSHError(wrap(errors.New("Process exited with status 1")), "sudo: a password is required\n")
+	c.Assert(errutil.IsPermissionError(err), IsTrue)
+
+	// the command just fails
+	err = classifySSHError(wrap(errors.New("Process exited with status 3")), "tikv is not running\n")
+	c.Assert(errutil.CategoryOf(err), Equals, errutil.CategoryUnknown)
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// StreamExecutor is implemented by the executors which can stream the output of the long
+// running commands, e.g. `tail -f`
+type StreamExecutor interface {
+	// Stream runs the command and calls fn with each line of its output until the command
+	// exits or ctx is done. The remote command is terminated on cancellation, and nil is
+	// returned in that case.
+	Stream(ctx context.Context, cmd string, sudo bool, fn func(line string)) error
+}
+
+// Stream runs the command by the executor if it supports streaming
+func Stream(ctx context.Context, e TiOpsExecutor, cmd string, sudo bool, fn func(line string)) error {
+	s, ok := e.(StreamExecutor)
+	if !ok {
+		return errors.Errorf("the executor %T doesn't support streaming", e)
+	}
+	return s.Stream(ctx, cmd, sudo, fn)
+}
+
+var _ StreamExecutor = &SSHExecutor{}
+
+// Stream implements the StreamExecutor interface. A pseudo terminal is allocated for the
+// command, so that it's hung up with the terminal once the session is closed.
+func (e *SSHExecutor) Stream(ctx context.Context, cmd string, sudo bool, fn func(line string)) error {
+	if sudo {
+		cmd = SudoCommand(cmd)
+	}
+	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
+	addr := net.JoinHostPort(e.Config.Server, e.Config.Port)
+
+	session, client, err := e.Config.Connect()
+	if err != nil {
+		return ErrSSHExecuteFailed.Wrap(err, "Failed to connect '%s@%s'", e.Config.User, addr)
+	}
+	defer client.Close()
+	defer session.Close()
+
+	if err := session.RequestPty("dumb", 0, 0, ssh.TerminalModes{ssh.ECHO: 0}); err != nil {
+		return ErrSSHExecuteFailed.Wrap(err, "Failed to allocate a terminal on '%s@%s'", e.Config.User, addr)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := session.Start(cmd); err != nil {
+		return ErrSSHExecuteFailed.Wrap(err, "Failed to execute command over SSH for '%s@%s'", e.Config.User, addr).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblocks reading the output
+			session.Close()
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	if err := scanLines(stdout, fn); err != nil && ctx.Err() == nil {
+		return errors.Annotatef(err, "failed to read the output of %s on %s", cmd, addr)
+	}
+	err = session.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return ErrSSHExecuteFailed.Wrap(err, "Failed to execute command over SSH for '%s@%s'", e.Config.User, addr).
+			WithProperty(ErrPropSSHCommand, cmd)
+	}
+	return nil
+}
+
+// scanLines calls fn with each line read from r, the carriage returns added by the
+// terminal are trimmed
+func scanLines(r io.Reader, fn func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fn(strings.TrimRight(scanner.Text(), "\r"))
+	}
+	return scanner.Err()
+}
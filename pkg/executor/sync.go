@@ -0,0 +1,210 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// SyncStats is the result of syncing a directory to the remote
+type SyncStats struct {
+	Files       int   // number of the local files
+	Transferred int   // number of the files transferred
+	Bytes       int64 // size of the files transferred
+	SavedBytes  int64 // size of the files not transferred as they are unchanged on the remote
+}
+
+// String implements the fmt.Stringer interface
+func (s *SyncStats) String() string {
+	return fmt.Sprintf("%d of %d files transferred, %d bytes sent, %d bytes saved", s.Transferred, s.Files, s.Bytes, s.SavedBytes)
+}
+
+// SyncDir copies the files in the local directory src to the remote directory dst like
+// rsync: the SHA-256 checksums of the remote files are compared with the local ones and only
+// the changed or absent files are transferred, so the remote files end up the same as a full
+// copy, including the permissions, the symlinks and the empty directories. The files are
+// transferred to temporary files and moved into place, so a running program never reads a
+// partial file. The remote files absent from src are kept.
+func SyncDir(e TiOpsExecutor, src, dst string) (*SyncStats, error) {
+	remote, err := remoteChecksums(e, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{}
+	var (
+		changed []string
+		dirs    []string
+	)
+	links := make(map[string]string)
+	sizes := make(map[string]int64)
+	modes := make(map[os.FileMode][]string)
+	err = filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, shellQuote(path.Join(dst, rel)))
+			return nil
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			links[rel] = target
+			return nil
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		stats.Files++
+		sum, err := FileChecksum(p)
+		if err != nil {
+			return err
+		}
+		if remote[rel] == sum {
+			stats.SavedBytes += info.Size()
+			return nil
+		}
+		changed = append(changed, rel)
+		sizes[rel] = info.Size()
+		modes[info.Mode().Perm()] = append(modes[info.Mode().Perm()], rel)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to compute the checksums of %s", src)
+	}
+
+	// the directories and the symlinks are created every time, as only the checksums of
+	// the remote files are known
+	cmds := []string{"mkdir -p " + strings.Join(dirs, " ")}
+	var names []string
+	for rel := range links {
+		names = append(names, rel)
+	}
+	sort.Strings(names)
+	for _, rel := range names {
+		cmds = append(cmds, fmt.Sprintf("ln -sfn %s %s", shellQuote(links[rel]), shellQuote(path.Join(dst, rel))))
+	}
+	if _, stderr, err := e.Execute(strings.Join(cmds, " && "), false); err != nil {
+		return nil, errors.Annotatef(err, "failed to create the directories in %s, stderr: %s", dst, stderr)
+	}
+	if len(changed) == 0 {
+		return stats, nil
+	}
+
+	for _, rel := range changed {
+		if err := e.Transfer(filepath.Join(src, filepath.FromSlash(rel)), syncTempFile(dst, rel), false); err != nil {
+			return nil, err
+		}
+		stats.Transferred++
+		stats.Bytes += sizes[rel]
+	}
+
+	// the files are transferred as 0644, the executables have to be restored
+	var perms []os.FileMode
+	for perm := range modes {
+		if perm != 0644 {
+			perms = append(perms, perm)
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	for _, perm := range perms {
+		var files []string
+		for _, rel := range modes[perm] {
+			files = append(files, shellQuote(syncTempFile(dst, rel)))
+		}
+		if _, stderr, err := e.Execute(fmt.Sprintf("chmod %o %s", perm, strings.Join(files, " ")), false); err != nil {
+			return nil, errors.Annotatef(err, "failed to set the permissions of the files in %s, stderr: %s", dst, stderr)
+		}
+	}
+
+	var moves []string
+	for _, rel := range changed {
+		moves = append(moves, fmt.Sprintf("mv -f %s %s", shellQuote(syncTempFile(dst, rel)), shellQuote(path.Join(dst, rel))))
+	}
+	if _, stderr, err := e.Execute(strings.Join(moves, " && "), false); err != nil {
+		return nil, errors.Annotatef(err, "failed to move the files into place in %s, stderr: %s", dst, stderr)
+	}
+	return stats, nil
+}
+
+// syncTempFile returns the temporary file which the file is transferred to before being
+// moved into place
+func syncTempFile(dst, rel string) string {
+	return path.Join(dst, rel) + ".tmp"
+}
+
+// shellQuote quotes the string as a single argument of the shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// remoteChecksums returns the SHA-256 checksums of the files in the remote directory by the
+// paths relative to it, it's empty if the directory doesn't exist
+func remoteChecksums(e TiOpsExecutor, dir string) (map[string]string, error) {
+	cmd := fmt.Sprintf("if [ -d %[1]s ]; then cd %[1]s && find . -type f -exec sha256sum {} +; fi", shellQuote(dir))
+	stdout, stderr, err := e.Execute(cmd, false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to compute the checksums of %s, stderr: %s", dir, stderr)
+	}
+	return parseChecksums(string(stdout)), nil
+}
+
+// parseChecksums parses the output of sha256sum, which has lines like `<checksum>  ./<path>`.
+// The escaped lines of the paths with special characters are skipped, so that the files are
+// always transferred.
+func parseChecksums(output string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "\\") {
+			continue
+		}
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "./")] = fields[0]
+	}
+	return sums
+}
+
+// FileChecksum returns the hex encoded SHA-256 checksum of the local file
+func FileChecksum(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// localExecutor runs the commands and the transfers on the local host as the remote, and
+// records the transfers
+type localExecutor struct {
+	transfers []string
+}
+
+func (e *localExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	stdout, err := exec.Command("sh", "-c", cmd).Output()
+	return stdout, nil, err
+}
+
+func (e *localExecutor) Transfer(src string, dst string, download bool) error {
+	e.transfers = append(e.transfers, dst)
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+func writeFiles(c *C, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		c.Assert(os.MkdirAll(filepath.Dir(p), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(p, []byte(content), 0644), IsNil)
+	}
+}
+
+func readFiles(c *C, dir string) map[string]string {
+	files := make(map[string]string)
+	c.Assert(filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		rel, _ := filepath.Rel(dir, p)
+		files[rel] = string(data)
+		return err
+	}), IsNil)
+	return files
+}
+
+func (s *executorSuite) TestSyncDir(c *C) {
+	src := c.MkDir()
+	writeFiles(c, src, map[string]string{
+		"bin/tikv-server":     "binary v4.0.1",
+		"conf/tikv.toml":      "[server]\n",
+		"scripts/run_tikv.sh": "#!/bin/bash\n",
+	})
+
+	// the full copy
+	full := filepath.Join(c.MkDir(), "deploy")
+	e := &localExecutor{}
+	stats, err := SyncDir(e, src, full)
+	c.Assert(err, IsNil)
+	c.Assert(stats.Transferred, Equals, 3)
+	c.Assert(stats.SavedBytes, Equals, int64(0))
+	c.Assert(readFiles(c, full), DeepEquals, readFiles(c, src))
+
+	// only the changed and absent files are transferred to the existing deploy directory
+	dst := filepath.Join(c.MkDir(), "deploy")
+	writeFiles(c, dst, map[string]string{
+		"bin/tikv-server": "binary v4.0.0",
+		"conf/tikv.toml":  "[server]\n",
+		"log/tikv.log":    "kept",
+	})
+	e = &localExecutor{}
+	stats, err = SyncDir(e, src, dst)
+	c.Assert(err, IsNil)
+	// the files are transferred to the temporary files and moved into place
+	c.Assert(e.transfers, DeepEquals, []string{filepath.Join(dst, "bin/tikv-server.tmp"), filepath.Join(dst, "scripts/run_tikv.sh.tmp")})
+	c.Assert(*stats, DeepEquals, SyncStats{Files: 3, Transferred: 2, Bytes: 25, SavedBytes: 9})
+	c.Assert(stats.String(), Equals, "2 of 3 files transferred, 25 bytes sent, 9 bytes saved")
+
+	synced := readFiles(c, dst)
+	c.Assert(synced["log/tikv.log"], Equals, "kept")
+	delete(synced, "log/tikv.log")
+	c.Assert(synced, DeepEquals, readFiles(c, full))
+
+	// nothing is transferred again
+	e = &localExecutor{}
+	stats, err = SyncDir(e, src, dst)
+	c.Assert(err, IsNil)
+	c.Assert(e.transfers, HasLen, 0)
+	c.Assert(stats.SavedBytes, Equals, int64(34))
+}
+
+func (s *executorSuite) TestParseChecksums(c *C) {
+	sums := parseChecksums("7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730  ./conf/tikv.toml\n" +
+		"\\5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  ./conf/a\\nb\n")
+	c.Assert(sums, DeepEquals, map[string]string{
+		"conf/tikv.toml": "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730",
+	})
+}
+
+func (s *executorSuite) TestSyncDirPermissionsAndQuoting(c *C) {
+	src := c.MkDir()
+	writeFiles(c, src, map[string]string{
+		"pd-server":   "binary v4.0.1",
+		"pd.toml":     "[schedule]\n",
+		"it's up.txt": "quoted",
+	})
+	c.Assert(os.Chmod(filepath.Join(src, "pd-server"), 0755), IsNil)
+
+	// the directory has a space and a quote
+	dst := filepath.Join(c.MkDir(), "deploy dir's", "bin")
+	stats, err := SyncDir(&localExecutor{}, src, dst)
+	c.Assert(err, IsNil)
+	c.Assert(stats.Transferred, Equals, 3)
+	c.Assert(readFiles(c, dst), DeepEquals, readFiles(c, src))
+	info, err := os.Stat(filepath.Join(dst, "pd-server"))
+	c.Assert(err, IsNil)
+	c.Assert(info.Mode().Perm(), Equals, os.FileMode(0755))
+	info, err = os.Stat(filepath.Join(dst, "pd.toml"))
+	c.Assert(err, IsNil)
+	c.Assert(info.Mode().Perm(), Equals, os.FileMode(0644))
+
+	// the checksums of the quoted directory are read
+	stats, err = SyncDir(&localExecutor{}, src, dst)
+	c.Assert(err, IsNil)
+	c.Assert(stats.Transferred, Equals, 0)
+}
+
+func (s *executorSuite) TestSyncDirSymlinksAndEmptyDirs(c *C) {
+	src := c.MkDir()
+	writeFiles(c, src, map[string]string{
+		"bin/tikv-server-v4.0.1": "binary v4.0.1",
+	})
+	c.Assert(os.Symlink("tikv-server-v4.0.1", filepath.Join(src, "bin", "tikv-server")), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(src, "data", "raft"), 0755), IsNil)
+
+	dst := filepath.Join(c.MkDir(), "deploy")
+	for i := 0; i < 2; i++ {
+		e := &localExecutor{}
+		stats, err := SyncDir(e, src, dst)
+		c.Assert(err, IsNil)
+		c.Assert(stats.Files, Equals, 1)
+
+		target, err := os.Readlink(filepath.Join(dst, "bin", "tikv-server"))
+		c.Assert(err, IsNil)
+		c.Assert(target, Equals, "tikv-server-v4.0.1")
+		info, err := os.Stat(filepath.Join(dst, "data", "raft"))
+		c.Assert(err, IsNil)
+		c.Assert(info.IsDir(), IsTrue)
+		c.Assert(readFiles(c, dst), DeepEquals, readFiles(c, src))
+	}
+
+	// the remote file is replaced by the symlink
+	c.Assert(os.Remove(filepath.Join(dst, "bin", "tikv-server")), IsNil)
+	writeFiles(c, dst, map[string]string{"bin/tikv-server": "binary v4.0.0"})
+	_, err := SyncDir(&localExecutor{}, src, dst)
+	c.Assert(err, IsNil)
+	c.Assert(readFiles(c, dst), DeepEquals, readFiles(c, src))
+}
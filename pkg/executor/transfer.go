@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"go.uber.org/zap"
+)
+
+var (
+	errNSTransfer = errNS.NewSubNamespace("transfer")
+	// ErrTransferNoSpace means a file transfer failed because the disk of the target is full
+	ErrTransferNoSpace = errNSTransfer.NewType("no_space")
+)
+
+// isNoSpace returns whether the error is caused by no space left on the device
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || strings.Contains(err.Error(), "No space left on device")
+}
+
+// remoteNoSpace returns whether the available space of the directory on the remote host is
+// less than the size, the scp doesn't report why it fails so the disk is checked afterwards
+func remoteNoSpace(e TiOpsExecutor, dir string, size int64) bool {
+	stdout, _, err := e.Execute(fmt.Sprintf("df -Pk %s", dir), false)
+	if err != nil {
+		return false
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted on
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	if len(lines) < 2 {
+		return false
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return false
+	}
+	available, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return false
+	}
+	return available*1024 < size
+}
+
+// noSpaceError removes the partial file by cleanup, and returns the error of no space left
+// on the host where the path is
+func noSpaceError(err error, host, path string, cleanup func() error) error {
+	if cerr := cleanup(); cerr != nil {
+		zap.L().Warn("failed to remove the partial file", zap.String("host", host), zap.String("path", path), zap.Error(cerr))
+	}
+	return ErrTransferNoSpace.
+		Wrap(err, "No space left on %s when transferring %s", host, path).
+		WithProperty(cliutil.SuggestionFromFormat("Please free up the disk of %s where %s is, and try again.", host, filepath.Dir(path)))
+}
+
+// uploadFailed checks whether the upload failed because the remote disk is full, and
+// removes the partial file on the remote host if so
+func uploadFailed(e TiOpsExecutor, host, src, dst string, err error) error {
+	fi, serr := os.Stat(src)
+	if serr != nil {
+		return err
+	}
+	if !isNoSpace(err) && !remoteNoSpace(e, filepath.Dir(dst), fi.Size()) {
+		return err
+	}
+	return noSpaceError(err, host, dst, func() error {
+		_, _, err := e.Execute(fmt.Sprintf("rm -f %s", dst), false)
+		return err
+	})
+}
+
+// downloadTo writes the output of run to the local file dst, the partial file is removed
+// if the local disk is full
+func downloadTo(dst string, run func(w io.Writer) error) error {
+	targetFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	err = run(targetFile)
+	if cerr := targetFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil && isNoSpace(err) {
+		return noSpaceError(err, "localhost", dst, func() error {
+			return os.Remove(dst)
+		})
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// dfExecutor reports the available space by df and records the commands
+type dfExecutor struct {
+	available string
+	cmds      []string
+}
+
+func (e *dfExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.cmds = append(e.cmds, cmd)
+	if strings.HasPrefix(cmd, "df ") {
+		return []byte("Filesystem     1024-blocks     Used Available Capacity Mounted on\n/dev/vdb        103080888 103080888 " + e.available + " 100% /data\n"), nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (e *dfExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (s *executorSuite) TestDownloadNoSpace(c *C) {
+	dir, err := ioutil.TempDir("", "transfer")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "tikv.log")
+
+	err = downloadTo(dst, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return &os.PathError{Op: "write", Path: dst, Err: syscall.ENOSPC}
+	})
+	c.Assert(errorx.IsOfType(err, ErrTransferNoSpace), IsTrue)
+	c.Assert(err.Error(), Matches, ".*No space left on localhost when transferring .*/tikv.log.*")
+	_, err = os.Stat(dst)
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// the other errors are returned as is
+	err = downloadTo(dst, func(w io.Writer) error {
+		return errors.New("connection reset")
+	})
+	c.Assert(err, ErrorMatches, "connection reset")
+	c.Assert(downloadTo(dst, func(w io.Writer) error {
+		_, err := w.Write([]byte("ok"))
+		return err
+	}), IsNil)
+}
+
+func (s *executorSuite) TestUploadNoSpace(c *C) {
+	dir, err := ioutil.TempDir("", "transfer")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "tikv-server")
+	c.Assert(ioutil.WriteFile(src, make([]byte, 4096), 0644), IsNil)
+	scpErr := errors.New("Process exited with status 1")
+
+	// the disk is full, the partial file is removed
+	e := &dfExecutor{available: "0"}
+	err = uploadFailed(e, "172.16.5.140", src, "/data/deploy/bin/tikv-server", scpErr)
+	c.Assert(errorx.IsOfType(err, ErrTransferNoSpace), IsTrue)
+	c.Assert(err.Error(), Matches, ".*No space left on 172.16.5.140 when transferring /data/deploy/bin/tikv-server.*")
+	c.Assert(e.cmds, DeepEquals, []string{"df -Pk /data/deploy/bin", "rm -f /data/deploy/bin/tikv-server"})
+
+	// the disk has enough space, the error is not about it
+	e = &dfExecutor{available: "1024"}
+	err = uploadFailed(e, "172.16.5.140", src, "/data/deploy/bin/tikv-server", scpErr)
+	c.Assert(err, Equals, scpErr)
+	c.Assert(e.cmds, DeepEquals, []string{"df -Pk /data/deploy/bin"})
+
+	// the error says so
+	e = &dfExecutor{available: "1024"}
+	err = uploadFailed(e, "172.16.5.140", src, "/data/deploy/bin/tikv-server", errors.New("scp: /data/deploy/bin/tikv-server: No space left on device"))
+	c.Assert(errorx.IsOfType(err, ErrTransferNoSpace), IsTrue)
+	c.Assert(e.cmds, DeepEquals, []string{"rm -f /data/deploy/bin/tikv-server"})
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWorkDirOutOfDeployDir means the working directory is not in the deploy directory
+	ErrWorkDirOutOfDeployDir = errNS.NewType("workdir_out_of_deploy_dir")
+)
+
+// WorkDirExecutor wraps an executor to run all commands in the working directory,
+// the transfers are not affected
+type WorkDirExecutor struct {
+	TiOpsExecutor
+	dir string
+}
+
+// NewWorkDirExecutor returns an executor which runs commands in dir, the dir must be
+// an absolute path inside the deploy directory
+func NewWorkDirExecutor(e TiOpsExecutor, deployDir, dir string) (*WorkDirExecutor, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(deployDir, dir)
+	}
+	dir = filepath.Clean(dir)
+	rel, err := filepath.Rel(filepath.Clean(deployDir), dir)
+	if !filepath.IsAbs(deployDir) || err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, ErrWorkDirOutOfDeployDir.New("Working directory '%s' is not in the deploy directory '%s'", dir, deployDir)
+	}
+	return &WorkDirExecutor{TiOpsExecutor: e, dir: dir}, nil
+}
+
+// Dir returns the working directory of the commands
+func (e *WorkDirExecutor) Dir() string {
+	return e.dir
+}
+
+// Execute implements the TiOpsExecutor interface, the command is run after changing to
+// the working directory and is not run if the directory can not be entered
+func (e *WorkDirExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return e.TiOpsExecutor.Execute(fmt.Sprintf("cd %s && %s", shellQuote(e.dir), cmd), sudo, timeout...)
+}
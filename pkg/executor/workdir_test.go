@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// recordExecutor records the commands and transfers without running them
+type recordExecutor struct {
+	cmds      []string
+	sudo      []bool
+	transfers []string
+}
+
+func (e *recordExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.cmds = append(e.cmds, cmd)
+	e.sudo = append(e.sudo, sudo)
+	return nil, nil, nil
+}
+
+func (e *recordExecutor) Transfer(src string, dst string, download bool) error {
+	e.transfers = append(e.transfers, src+" -> "+dst)
+	return nil
+}
+
+func (s *executorSuite) TestWorkDir(c *C) {
+	e := &recordExecutor{}
+
+	w, err := NewWorkDirExecutor(e, "/home/tidb/deploy", "/home/tidb/deploy/tikv-20160")
+	c.Assert(err, IsNil)
+	_, _, err = w.Execute("bin/tikv-ctl --version", true)
+	c.Assert(err, IsNil)
+
+	// relative directories are inside the deploy directory
+	w, err = NewWorkDirExecutor(e, "/home/tidb/deploy", "pd-2379/")
+	c.Assert(err, IsNil)
+	c.Assert(w.Dir(), Equals, "/home/tidb/deploy/pd-2379")
+	_, _, err = w.Execute("ls", false)
+	c.Assert(err, IsNil)
+
+	// the deploy directory itself is allowed
+	w, err = NewWorkDirExecutor(e, "/home/tidb/deploy/", "/home/tidb/deploy")
+	c.Assert(err, IsNil)
+	_, _, err = w.Execute("ls", false)
+	c.Assert(err, IsNil)
+
+	// the directory is quoted
+	w, err = NewWorkDirExecutor(e, "/home/tidb/deploy", "tidb's data")
+	c.Assert(err, IsNil)
+	_, _, err = w.Execute("ls", false)
+	c.Assert(err, IsNil)
+
+	c.Assert(e.cmds, DeepEquals, []string{
+		"cd '/home/tidb/deploy/tikv-20160' && bin/tikv-ctl --version",
+		"cd '/home/tidb/deploy/pd-2379' && ls",
+		"cd '/home/tidb/deploy' && ls",
+		`cd '/home/tidb/deploy/tidb'\''s data' && ls`,
+	})
+	c.Assert(e.sudo, DeepEquals, []bool{true, false, false, false})
+
+	// transfers are not affected
+	c.Assert(w.Transfer("/tmp/tidb.toml", "/home/tidb/deploy/conf/tidb.toml", false), IsNil)
+	c.Assert(e.transfers, DeepEquals, []string{"/tmp/tidb.toml -> /home/tidb/deploy/conf/tidb.toml"})
+}
+
+func (s *executorSuite) TestWorkDirOutOfDeployDir(c *C) {
+	e := &recordExecutor{}
+	for _, dir := range []string{"/home/tidb", "/home/tidb/deploy-1", "../", "tikv-20160/../../data", "/tmp"} {
+		_, err := NewWorkDirExecutor(e, "/home/tidb/deploy", dir)
+		c.Assert(err, NotNil, Commentf("dir: %s", dir))
+		c.Assert(err.Error(), Matches, ".*is not in the deploy directory.*")
+	}
+
+	_, err := NewWorkDirExecutor(e, "deploy", "deploy/tikv-20160")
+	c.Assert(err, NotNil)
+	c.Assert(e.cmds, HasLen, 0)
+}
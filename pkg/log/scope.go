@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Scope selects the hosts and components whose remote commands are logged verbosely
+type Scope struct {
+	hosts      map[string]bool
+	components []*regexp.Regexp
+}
+
+// ParseScope parses a comma separated list like `host=172.16.5.140,component=tikv`
+func ParseScope(spec string) (*Scope, error) {
+	s := &Scope{hosts: make(map[string]bool)}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid log scope '%s', it should be host=<host> or component=<component>", item)
+		}
+		switch kv[0] {
+		case "host":
+			s.hosts[kv[1]] = true
+		case "component":
+			// The instances of a component are identified by the service names and the
+			// default directory names like `tikv-20160` in the commands
+			s.components = append(s.components, regexp.MustCompile(`(^|[^\w-])`+regexp.QuoteMeta(kv[1])+`-\d+\b`))
+		default:
+			return nil, fmt.Errorf("invalid log scope '%s', unknown kind '%s'", item, kv[0])
+		}
+	}
+	return s, nil
+}
+
+// Empty returns true if nothing is selected
+func (s *Scope) Empty() bool {
+	return s == nil || (len(s.hosts) == 0 && len(s.components) == 0)
+}
+
+// Match returns true if the host is selected, or the command operates on an
+// instance of the selected components
+func (s *Scope) Match(host, cmd string) bool {
+	if s.Empty() {
+		return false
+	}
+	if s.hosts[host] {
+		return true
+	}
+	for _, re := range s.components {
+		if re.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
+}
@@ -18,12 +18,29 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// baseLogger is the global logger without the change id
+var baseLogger *zap.Logger
+
 // InitGlobalLogger initializes zap global logger.
 func InitGlobalLogger() {
 	core := zapcore.NewTee(
 		newAuditLogCore(),
 		newDebugLogCore(),
 	)
-	logger := zap.New(core)
-	zap.ReplaceGlobals(logger)
+	baseLogger = zap.New(core)
+	zap.ReplaceGlobals(baseLogger)
+}
+
+// SetChangeID stamps the change id on all the entries logged to the audit and debug
+// logs afterwards, so that they can be correlated with the ticket of the change. It
+// can be called repeatedly, the empty id removes the stamp.
+func SetChangeID(id string) {
+	if baseLogger == nil {
+		baseLogger = zap.L()
+	}
+	if id == "" {
+		zap.ReplaceGlobals(baseLogger)
+		return
+	}
+	zap.ReplaceGlobals(baseLogger.With(zap.String("change_id", id)))
 }
@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"go.uber.org/zap"
+)
+
+func TestLogger(t *testing.T) {
+	TestingT(t)
+}
+
+type loggerSuite struct{}
+
+var _ = Suite(&loggerSuite{})
+
+func (s *loggerSuite) TestChangeID(c *C) {
+	InitGlobalLogger()
+	defer SetChangeID("")
+
+	zap.L().Info("before the change id")
+	SetChangeID("CHG-1024")
+	zap.L().Info("deploying")
+	// set again is not stamped twice
+	SetChangeID("CHG-1024")
+	zap.L().Warn("retrying")
+	SetChangeID("")
+	zap.L().Info("after the change id")
+
+	for _, buf := range []string{auditBuffer.String(), debugBuffer.String()} {
+		lines := strings.Split(strings.TrimSpace(buf), "\n")
+		lines = lines[len(lines)-4:]
+		c.Assert(strings.Contains(lines[0], "change_id"), IsFalse)
+		c.Assert(lines[1], Matches, `.*deploying\s+\{"change_id": "CHG-1024"\}`)
+		c.Assert(lines[2], Matches, `.*retrying\s+\{"change_id": "CHG-1024"\}`)
+		c.Assert(strings.Contains(lines[3], "change_id"), IsFalse)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestBinlogInitConfig(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	cache, err := ioutil.TempDir("", "binlog")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := TopologySpecification{}
+	err = yaml.Unmarshal([]byte(`
+server_configs:
+  pump:
+    gc: 7
+  drainer:
+    syncer.db-type: mysql
+    syncer.worker-count: 16
+pd_servers:
+  - host: 172.16.5.53
+pump_servers:
+  - host: 172.16.5.140
+drainer_servers:
+  - host: 172.16.5.141
+    commit_ts: 417115796117045249
+    config:
+      syncer.worker-count: 32
+      syncer.to.host: 172.16.5.200
+`), &topo)
+	c.Assert(err, IsNil)
+
+	// pump
+	paths := DirPaths{
+		Deploy: "/home/tidb/deploy/pump-8250",
+		Data:   "/home/tidb/deploy/pump-8250/data",
+		Log:    "/home/tidb/deploy/pump-8250/log",
+		Cache:  cache,
+	}
+	e := &recordExecutor{transfers: map[string]string{}}
+	c.Assert((&PumpComponent{&topo}).Instances()[0].InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+	script, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/pump-8250/scripts/run_pump.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, `(?s).*exec bin/pump \\
+    --node-id="172.16.5.140:8250" \\
+    --addr="0.0.0.0:8250" \\
+    --advertise-addr="172.16.5.140:8250" \\
+    --pd-urls="http://172.16.5.53:2379" \\
+    --data-dir="/home/tidb/deploy/pump-8250/data" \\
+    --log-file="/home/tidb/deploy/pump-8250/log/pump.log" \\
+    --config=conf/pump.toml 2>> "/home/tidb/deploy/pump-8250/log/pump_stderr.log"
+`)
+	data, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/pump-8250/conf/pump.toml"])
+	c.Assert(err, IsNil)
+	var pumpConf struct {
+		GC int `toml:"gc"`
+	}
+	_, err = toml.Decode(string(data), &pumpConf)
+	c.Assert(err, IsNil)
+	c.Assert(pumpConf.GC, Equals, 7)
+
+	// drainer
+	paths = DirPaths{
+		Deploy: "/home/tidb/deploy/drainer-8249",
+		Data:   "/home/tidb/deploy/drainer-8249/data",
+		Log:    "/home/tidb/deploy/drainer-8249/log",
+		Cache:  cache,
+	}
+	e = &recordExecutor{transfers: map[string]string{}}
+	c.Assert((&DrainerComponent{&topo}).Instances()[0].InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+	script, err = ioutil.ReadFile(e.transfers["/home/tidb/deploy/drainer-8249/scripts/run_drainer.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, `(?s).*exec bin/drainer \\
+    --node-id="172.16.5.141:8249" \\
+    --addr="172.16.5.141:8249" \\
+    --pd-urls="http://172.16.5.53:2379" \\
+    --data-dir="/home/tidb/deploy/drainer-8249/data" \\
+    --log-file="/home/tidb/deploy/drainer-8249/log/drainer.log" \\
+    --config=conf/drainer.toml \\
+    --initial-commit-ts="417115796117045249" 2>> "/home/tidb/deploy/drainer-8249/log/drainer_stderr.log"
+`)
+	data, err = ioutil.ReadFile(e.transfers["/home/tidb/deploy/drainer-8249/conf/drainer.toml"])
+	c.Assert(err, IsNil)
+	var drainerConf struct {
+		Syncer struct {
+			DBType      string `toml:"db-type"`
+			WorkerCount int    `toml:"worker-count"`
+			To          struct {
+				Host string `toml:"host"`
+			} `toml:"to"`
+		} `toml:"syncer"`
+	}
+	_, err = toml.Decode(string(data), &drainerConf)
+	c.Assert(err, IsNil)
+	c.Assert(drainerConf.Syncer.DBType, Equals, "mysql")
+	c.Assert(drainerConf.Syncer.WorkerCount, Equals, 32)
+	c.Assert(drainerConf.Syncer.To.Host, Equals, "172.16.5.200")
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestCDCSpec(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.53
+cdc_servers:
+  - host: 172.16.5.139
+  - host: 172.16.5.139
+    port: 8301
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.CDCServers, HasLen, 2)
+	c.Assert(topo.CDCServers[0].Port, Equals, 8300)
+	c.Assert(topo.CDCServers[0].DeployDir, Equals, "deploy/cdc-8300")
+	c.Assert(topo.CDCServers[1].DeployDir, Equals, "deploy/cdc-8301")
+
+	ins := (&CDCComponent{&topo}).Instances()
+	c.Assert(ins, HasLen, 2)
+	c.Assert(ins[1].ID(), Equals, "172.16.5.139:8301")
+	c.Assert(ins[1].UsedPorts(), DeepEquals, []int{8301})
+	c.Assert(ins[1].ServiceName(), Equals, "cdc-8301.service")
+
+	// TiCDC is started after the upstream cluster and stopped before it
+	start := topo.ComponentsByStartOrder()
+	c.Assert(start[7].Name(), Equals, ComponentCDC)
+	c.Assert(start[6].Name(), Equals, ComponentDrainer)
+	stop := topo.ComponentsByStopOrder()
+	c.Assert(stop[3].Name(), Equals, ComponentCDC)
+
+	merged := topo.Merge(&TopologySpecification{CDCServers: []CDCSpec{{Host: "172.16.5.141"}}})
+	c.Assert(merged.CDCServers, HasLen, 3)
+
+	err = yaml.Unmarshal([]byte(`
+cdc_servers:
+  - host: 172.16.5.139
+    port: 2379
+pd_servers:
+  - host: 172.16.5.139
+`), &TopologySpecification{})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*port '2379' conflicts.*")
+}
+
+func (s *metaSuite) TestCDCInitConfig(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	cache, err := ioutil.TempDir("", "cdc")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := TopologySpecification{}
+	err = yaml.Unmarshal([]byte(`
+server_configs:
+  cdc:
+    per-table-memory-quota: 10485760
+    sorter.max-memory-percentage: 70
+pd_servers:
+  - host: 172.16.5.53
+  - host: 172.16.5.54
+cdc_servers:
+  - host: 172.16.5.139
+    gc_ttl: 86400
+    tz: Asia/Shanghai
+    config:
+      per-table-memory-quota: 20971520
+  - host: 172.16.5.140
+`), &topo)
+	c.Assert(err, IsNil)
+
+	paths := DirPaths{
+		Deploy: "/home/tidb/deploy/cdc-8300",
+		Log:    "/home/tidb/deploy/cdc-8300/log",
+		Cache:  cache,
+	}
+	insts := (&CDCComponent{&topo}).Instances()
+	e := &recordExecutor{transfers: map[string]string{}}
+	c.Assert(insts[0].InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+
+	script, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/cdc-8300/scripts/run_cdc.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Equals, `#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+DEPLOY_DIR=/home/tidb/deploy/cdc-8300
+
+cd "${DEPLOY_DIR}" || exit 1
+exec bin/cdc server \
+    --addr "0.0.0.0:8300" \
+    --advertise-addr "172.16.5.139:8300" \
+    --pd "http://172.16.5.53:2379,http://172.16.5.54:2379" \
+    --gc-ttl 86400 \
+    --tz "Asia/Shanghai" \
+    --config conf/cdc.toml \
+    --log-file "/home/tidb/deploy/cdc-8300/log/cdc.log" 2>> "/home/tidb/deploy/cdc-8300/log/cdc_stderr.log"
+`)
+
+	data, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/cdc-8300/conf/cdc.toml"])
+	c.Assert(err, IsNil)
+	var conf struct {
+		PerTableMemoryQuota int `toml:"per-table-memory-quota"`
+		Sorter              struct {
+			MaxMemoryPercentage int `toml:"max-memory-percentage"`
+		} `toml:"sorter"`
+	}
+	_, err = toml.Decode(string(data), &conf)
+	c.Assert(err, IsNil)
+	c.Assert(conf.PerTableMemoryQuota, Equals, 20971520)
+	c.Assert(conf.Sorter.MaxMemoryPercentage, Equals, 70)
+
+	// the optional flags are omitted
+	e = &recordExecutor{transfers: map[string]string{}}
+	c.Assert(insts[1].InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+	script, err = ioutil.ReadFile(e.transfers["/home/tidb/deploy/cdc-8300/scripts/run_cdc.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Not(Matches), "(?s).*--gc-ttl.*")
+	c.Assert(string(script), Not(Matches), "(?s).*--tz.*")
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// AppliedTopologyFileName is the file name of the topology which is applied to the cluster
+// last time, it's saved by edit-config before the first unapplied change
+const AppliedTopologyFileName = "applied_topology.yaml"
+
+// SaveAppliedTopology saves the topology as the one applied to the cluster
+func SaveAppliedTopology(clusterName string, topo *TopologySpecification) error {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := EnsureClusterDir(clusterName); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ClusterPath(clusterName, AppliedTopologyFileName), data, 0644)
+}
+
+// AppliedTopology returns the topology applied to the cluster last time, it's nil if all
+// the changes are applied already
+func AppliedTopology(clusterName string) (*TopologySpecification, error) {
+	data, err := ioutil.ReadFile(ClusterPath(clusterName, AppliedTopologyFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.AddStack(err)
+	}
+	topo := new(TopologySpecification)
+	if err := yaml.Unmarshal(data, topo); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse %s", AppliedTopologyFileName)
+	}
+	return topo, nil
+}
+
+// RemoveAppliedTopology removes the applied topology after all the changes are applied
+func RemoveAppliedTopology(clusterName string) error {
+	err := os.Remove(ClusterPath(clusterName, AppliedTopologyFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
+
+// ChangedNodes returns the ids of the instances whose effective configs differ between the
+// applied and the current topology, grouped by component. An instance is changed if its own
+// specification or the server configs of its component are changed, or it's added after the
+// applied one. All the instances are changed if the global or monitored options are changed,
+// and the monitoring instances are changed if any instance is added or removed.
+func ChangedNodes(applied, current *TopologySpecification) map[string][]string {
+	changed := make(map[string][]string)
+	all := !reflect.DeepEqual(applied.GlobalOptions, current.GlobalOptions) ||
+		!reflect.DeepEqual(applied.MonitoredOptions, current.MonitoredOptions)
+
+	appliedSpecs := make(map[string]InstanceSpec)
+	appliedValue := reflect.ValueOf(applied).Elem()
+	currentValue := reflect.ValueOf(current).Elem()
+	for i := 0; i < appliedValue.NumField(); i++ {
+		if isSkipField(appliedValue.Field(i)) {
+			continue
+		}
+		compSpecs := appliedValue.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			spec := compSpecs.Index(index).Interface().(InstanceSpec)
+			appliedSpecs[specKey(i, spec)] = spec
+		}
+	}
+
+	type candidate struct {
+		spec    InstanceSpec
+		changed bool
+	}
+	var candidates []candidate
+	membership := false
+	for i := 0; i < currentValue.NumField(); i++ {
+		if isSkipField(currentValue.Field(i)) {
+			continue
+		}
+		compSpecs := currentValue.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			spec := compSpecs.Index(index).Interface().(InstanceSpec)
+			role := spec.Role()
+			old, found := appliedSpecs[specKey(i, spec)]
+			membership = membership || !found
+			candidates = append(candidates, candidate{spec, all || !found || !reflect.DeepEqual(old, spec) ||
+				!reflect.DeepEqual(applied.ServerConfigs.of(role), current.ServerConfigs.of(role))})
+		}
+	}
+	membership = membership || len(candidates) != len(appliedSpecs)
+
+	for _, cand := range candidates {
+		role := cand.spec.Role()
+		// the monitoring components are configured with the addresses of all the instances
+		monitor := role == ComponentPrometheus || role == ComponentGrafana || role == ComponentAlertManager
+		if cand.changed || (membership && monitor) {
+			changed[role] = append(changed[role], specID(cand.spec))
+		}
+	}
+	return changed
+}
+
+// specKey identifies an instance by the field of the topology and the node id
+func specKey(field int, spec InstanceSpec) string {
+	return fmt.Sprintf("%d/%s", field, specID(spec))
+}
+
+func specID(spec InstanceSpec) string {
+	return utils.JoinHostPort(reflect.ValueOf(spec).FieldByName("Host").String(), spec.GetMainPort())
+}
+
+// of returns the server configs applied to the instances of the role
+func (s ServerConfigs) of(role string) []map[string]interface{} {
+	configs := map[string][]map[string]interface{}{
+		ComponentTiDB:    {s.TiDB},
+		ComponentTiKV:    {s.TiKV},
+		ComponentPD:      {s.PD},
+		ComponentTiFlash: {s.TiFlash, s.TiFlashLearner},
+		ComponentPump:    {s.Pump},
+		ComponentDrainer: {s.Drainer},
+		ComponentTiProxy: {s.TiProxy},
+		ComponentCDC:     {s.CDC},
+	}
+	return configs[role]
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+const changesBaseTopology = `
+server_configs:
+  tikv:
+    storage.block-cache.capacity: 8GB
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`
+
+func changesTopology(c *C, doc string) *TopologySpecification {
+	topo := new(TopologySpecification)
+	c.Assert(yaml.Unmarshal([]byte(doc), topo), IsNil)
+	return topo
+}
+
+func (s *metaSuite) TestChangedNodes(c *C) {
+	applied := changesTopology(c, changesBaseTopology)
+	c.Assert(ChangedNodes(applied, changesTopology(c, changesBaseTopology)), HasLen, 0)
+
+	// only the tikv instances are changed by the server configs of tikv
+	current := changesTopology(c, `
+server_configs:
+  tikv:
+    storage.block-cache.capacity: 16GB
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`)
+	c.Assert(ChangedNodes(applied, current), DeepEquals, map[string][]string{
+		ComponentTiKV: {"172.16.5.140:20160", "172.16.5.141:20160"},
+	})
+
+	// the changed instance and the new one
+	current = changesTopology(c, `
+server_configs:
+  tikv:
+    storage.block-cache.capacity: 8GB
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+    config:
+      log.level: warn
+  - host: 172.16.5.141
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`)
+	c.Assert(ChangedNodes(applied, current), DeepEquals, map[string][]string{
+		ComponentTiDB: {"172.16.5.140:4000", "172.16.5.141:4000"},
+	})
+
+	// the monitoring instances are changed if any instance is removed
+	applied = changesTopology(c, changesBaseTopology+"monitoring_servers:\n  - host: 172.16.5.142\n")
+	current = changesTopology(c, `
+server_configs:
+  tikv:
+    storage.block-cache.capacity: 8GB
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+monitoring_servers:
+  - host: 172.16.5.142
+`)
+	c.Assert(ChangedNodes(applied, current), DeepEquals, map[string][]string{
+		ComponentPrometheus: {"172.16.5.142:9090"},
+	})
+
+	// all are changed by the global options
+	applied = changesTopology(c, changesBaseTopology)
+	current = changesTopology(c, "global:\n  deploy_dir: /data/deploy\n"+changesBaseTopology)
+	changed := ChangedNodes(applied, current)
+	c.Assert(changed[ComponentPD], HasLen, 1)
+	c.Assert(changed[ComponentTiDB], HasLen, 1)
+	c.Assert(changed[ComponentTiKV], HasLen, 2)
+}
+
+func (s *metaSuite) TestAppliedTopology(c *C) {
+	dir, err := ioutil.TempDir("", "tiops-applied")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	oldProfile := profileDir
+	profileDir = dir
+	defer func() { profileDir = oldProfile }()
+
+	topo, err := AppliedTopology("test")
+	c.Assert(err, IsNil)
+	c.Assert(topo, IsNil)
+
+	c.Assert(SaveAppliedTopology("test", changesTopology(c, changesBaseTopology)), IsNil)
+	topo, err = AppliedTopology("test")
+	c.Assert(err, IsNil)
+	c.Assert(topo.TiKVServers, HasLen, 2)
+	c.Assert(ChangedNodes(topo, changesTopology(c, changesBaseTopology)), HasLen, 0)
+
+	c.Assert(RemoveAppliedTopology("test"), IsNil)
+	c.Assert(RemoveAppliedTopology("test"), IsNil)
+	topo, err = AppliedTopology("test")
+	c.Assert(err, IsNil)
+	c.Assert(topo, IsNil)
+}
@@ -47,6 +47,9 @@ type ClusterMeta struct {
 	//EnableFirewall bool   `yaml:"firewall"`
 
 	Topology *TopologySpecification `yaml:"topology"`
+
+	// ArtifactSources are the URLs the artifacts were downloaded from, by component:version
+	ArtifactSources map[string]string `yaml:"artifact_sources,omitempty"`
 }
 
 // EnsureClusterDir ensures that the cluster directory exists.
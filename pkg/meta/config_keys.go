@@ -0,0 +1,251 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"golang.org/x/mod/semver"
+)
+
+// configKeyChange describes a configuration item which has been deprecated or
+// removed since a version of the component
+type configKeyChange struct {
+	key         string
+	since       string
+	removed     bool
+	replacement string
+}
+
+// The top level configuration items known by the components, the configuration
+// of the components not listed here are not checked for unknown items
+var knownConfigSections = map[string]set.StringSet{
+	ComponentTiDB: set.NewStringSet(
+		"host", "advertise-address", "port", "cors", "store", "path", "socket", "lease",
+		"run-ddl", "split-table", "token-limit", "oom-action", "mem-quota-query",
+		"tmp-storage-path", "tmp-storage-quota", "oom-use-tmp-storage", "enable-streaming",
+		"enable-batch-dml", "lower-case-table-names", "server-version", "compatible-kill-query",
+		"check-mb4-value-in-utf8", "max-index-length", "alter-primary-key",
+		"treat-old-version-utf8-as-utf8mb4", "enable-table-lock", "delay-clean-table-lock",
+		"split-region-max-num", "stmt-summary", "repair-mode", "repair-table-list",
+		"max-server-connections", "new_collations_enabled_on_first_bootstrap",
+		"enable-dynamic-config", "enable-telemetry", "labels", "log", "security", "status",
+		"performance", "prepared-plan-cache", "opentracing", "proxy-protocol", "tikv-client",
+		"binlog", "plugin", "pessimistic-txn", "txn-local-latches", "experimental",
+		"isolation-read",
+	),
+	ComponentTiKV: set.NewStringSet(
+		"log-level", "log-file", "log-format", "log-rotation-timespan", "log-rotation-size",
+		"slow-log-file", "slow-log-threshold", "panic-when-unexpected-key-or-data",
+		"refresh-config-interval", "readpool", "server", "storage", "pd", "metric",
+		"raftstore", "coprocessor", "rocksdb", "raftdb", "security", "import", "backup",
+		"pessimistic-txn", "gc", "split",
+	),
+	ComponentPD: set.NewStringSet(
+		"name", "data-dir", "client-urls", "peer-urls", "advertise-client-urls",
+		"advertise-peer-urls", "initial-cluster", "initial-cluster-state",
+		"initial-cluster-token", "join", "lease", "log", "log-file", "log-level",
+		"tso-save-interval", "enable-prevote", "quota-backend-bytes", "auto-compaction-mode",
+		"auto-compaction-retention", "force-new-cluster", "tikv-interval", "security",
+		"label-property", "namespace-classifier", "metric", "schedule", "replication",
+		"pd-server", "cluster-version", "dashboard", "replication-mode",
+		"enable-dynamic-config",
+	),
+}
+
+// The configuration items known to be changed across versions
+var configKeyChanges = map[string][]configKeyChange{
+	ComponentTiDB: {
+		{key: "log.file.log-rotate", since: "v4.0.0", removed: true},
+		{key: "enable-streaming", since: "v4.0.0"},
+		{key: "txn-local-latches", since: "v4.0.0"},
+	},
+	ComponentTiKV: {
+		{key: "server.end-point-concurrency", since: "v3.0.0", removed: true, replacement: "readpool.coprocessor.high-concurrency"},
+		{key: "rocksdb.defaultcf.block-cache-size", since: "v4.0.0", replacement: "storage.block-cache.capacity"},
+		{key: "rocksdb.writecf.block-cache-size", since: "v4.0.0", replacement: "storage.block-cache.capacity"},
+		{key: "rocksdb.lockcf.block-cache-size", since: "v4.0.0", replacement: "storage.block-cache.capacity"},
+		{key: "raftdb.defaultcf.block-cache-size", since: "v4.0.0", replacement: "storage.block-cache.capacity"},
+	},
+	ComponentPD: {
+		{key: "namespace-classifier", since: "v4.0.0", removed: true},
+		{key: "schedule.disable-raft-learner", since: "v4.0.0", removed: true},
+		{key: "schedule.disable-namespace-relocation", since: "v4.0.0", removed: true},
+	},
+}
+
+// ConfigKeyIssue is a configuration item which may not work with the version of component
+type ConfigKeyIssue struct {
+	Component string
+	Instance  string // ID of the instance, empty for server_configs
+	Key       string
+	Unknown   bool
+	Removed   bool
+	Since     string
+	Replace   string
+}
+
+// String implements the fmt.Stringer interface
+func (i ConfigKeyIssue) String() string {
+	where := fmt.Sprintf("server_configs.%s", i.Component)
+	if i.Instance != "" {
+		where = fmt.Sprintf("%s %s", i.Component, i.Instance)
+	}
+
+	var msg string
+	switch {
+	case i.Unknown:
+		msg = fmt.Sprintf("%s: unknown config `%s`", where, i.Key)
+	case i.Removed:
+		msg = fmt.Sprintf("%s: config `%s` has been removed since %s", where, i.Key, i.Since)
+	default:
+		msg = fmt.Sprintf("%s: config `%s` has been deprecated since %s", where, i.Key, i.Since)
+	}
+	if i.Replace != "" {
+		msg += fmt.Sprintf(", use `%s` instead", i.Replace)
+	}
+	return msg
+}
+
+// CheckConfigKeys checks the configuration items of the component against the known
+// items of the version, the unknown, deprecated and removed items are returned
+func CheckConfigKeys(comp, version string, config map[string]interface{}) ([]ConfigKeyIssue, error) {
+	if len(config) == 0 {
+		return nil, nil
+	}
+	keys, err := flattenConfigKeys(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []ConfigKeyIssue
+	for _, key := range keys {
+		if known, ok := knownConfigSections[comp]; ok && !known.Exist(strings.Split(key, ".")[0]) {
+			issues = append(issues, ConfigKeyIssue{Component: comp, Key: key, Unknown: true})
+			continue
+		}
+		for _, change := range configKeyChanges[comp] {
+			if key != change.key && !strings.HasPrefix(key, change.key+".") {
+				continue
+			}
+			if !versionSince(version, change.since) {
+				continue
+			}
+			issues = append(issues, ConfigKeyIssue{
+				Component: comp,
+				Key:       key,
+				Removed:   change.removed,
+				Since:     change.since,
+				Replace:   change.replacement,
+			})
+		}
+	}
+	return issues, nil
+}
+
+// CheckConfigKeys checks the server_configs and the config of all instances against
+// the known configuration items of the version
+func (topo *Specification) CheckConfigKeys(version string) ([]ConfigKeyIssue, error) {
+	var issues []ConfigKeyIssue
+
+	globals := map[string]map[string]interface{}{
+		ComponentTiDB:    topo.ServerConfigs.TiDB,
+		ComponentTiKV:    topo.ServerConfigs.TiKV,
+		ComponentPD:      topo.ServerConfigs.PD,
+		ComponentTiFlash: topo.ServerConfigs.TiFlash,
+		ComponentPump:    topo.ServerConfigs.Pump,
+		ComponentDrainer: topo.ServerConfigs.Drainer,
+		ComponentTiProxy: topo.ServerConfigs.TiProxy,
+		ComponentCDC:     topo.ServerConfigs.CDC,
+	}
+	for _, comp := range topo.ComponentsByStartOrder() {
+		found, err := CheckConfigKeys(comp.Name(), version, globals[comp.Name()])
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+
+		for _, inst := range comp.Instances() {
+			config, ok := instanceConfig(inst)
+			if !ok {
+				continue
+			}
+			found, err := CheckConfigKeys(comp.Name(), version, config)
+			if err != nil {
+				return nil, err
+			}
+			for i := range found {
+				found[i].Instance = inst.ID()
+			}
+			issues = append(issues, found...)
+		}
+	}
+	return issues, nil
+}
+
+// instanceConfig returns the config field of the instance specification
+func instanceConfig(inst Instance) (map[string]interface{}, bool) {
+	spec := reflect.ValueOf(inst).Elem().FieldByName("InstanceSpec")
+	if !spec.IsValid() {
+		return nil, false
+	}
+	config := spec.Elem().FieldByName("Config")
+	if !config.IsValid() {
+		return nil, false
+	}
+	return config.Interface().(map[string]interface{}), true
+}
+
+// flattenConfigKeys returns all leaf keys of the config in the form of `a.b.c`
+func flattenConfigKeys(config map[string]interface{}) ([]string, error) {
+	nested, err := flattenMap(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	var collect func(prefix string, m map[string]interface{})
+	collect = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				collect(key, sub)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	collect("", nested)
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// versionSince returns if the version is the same or newer than since,
+// the nightly version is treated as the newest one
+func versionSince(version, since string) bool {
+	if version == "nightly" {
+		return true
+	}
+	if !semver.IsValid(version) {
+		return false
+	}
+	return semver.Compare(version, since) >= 0
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestCheckConfigKeys(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+server_configs:
+  tidb:
+    log.file.log-rotate: true
+    log.slow-threshold: 300
+  tikv:
+    rocksdb:
+      defaultcf:
+        block-cache-size: "1GB"
+  pd:
+    replication.max-replicas: 3
+tidb_servers:
+  - host: 172.16.5.138
+    config:
+      no-such-section.enabled: true
+tikv_servers:
+  - host: 172.16.5.139
+pd_servers:
+  - host: 172.16.5.140
+    config:
+      schedule.disable-raft-learner: true
+`), &topo)
+	c.Assert(err, IsNil)
+
+	// nothing changed in the old version except the unknown config
+	issues, err := topo.CheckConfigKeys("v3.0.12")
+	c.Assert(err, IsNil)
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0].Unknown, IsTrue)
+	c.Assert(issues[0].Key, Equals, "no-such-section.enabled")
+	c.Assert(issues[0].Instance, Equals, "172.16.5.138:4000")
+
+	issues, err = topo.CheckConfigKeys("v4.0.0")
+	c.Assert(err, IsNil)
+	c.Assert(issues, HasLen, 4)
+	var msgs []string
+	for _, issue := range issues {
+		msgs = append(msgs, issue.String())
+	}
+	c.Assert(msgs, DeepEquals, []string{
+		"pd 172.16.5.140:2379: config `schedule.disable-raft-learner` has been removed since v4.0.0",
+		"server_configs.tikv: config `rocksdb.defaultcf.block-cache-size` has been deprecated since v4.0.0, use `storage.block-cache.capacity` instead",
+		"server_configs.tidb: config `log.file.log-rotate` has been removed since v4.0.0",
+		"tidb 172.16.5.138:4000: unknown config `no-such-section.enabled`",
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The configuration items which can be changed online by SQL or pd-ctl without restarting the
+// instances, all the other ones take effect only after restarting. A key ends with `.` means
+// all the items under it.
+var onlineConfigKeys = map[string][]string{
+	ComponentTiDB: {
+		"log.level",
+		"log.slow-threshold",
+		"log.expensive-threshold",
+		"log.query-log-max-len",
+		"performance.feedback-probability",
+		"performance.query-feedback-limit",
+		"performance.pseudo-estimate-ratio",
+		"performance.force-priority",
+		"oom-action",
+		"mem-quota-query",
+	},
+	ComponentTiKV: {
+		"raftstore.",
+		"coprocessor.",
+		"gc.",
+		"split.",
+		"pessimistic-txn.",
+		"storage.block-cache.capacity",
+		"rocksdb.max-background-jobs",
+		"rocksdb.rate-bytes-per-sec",
+		"rocksdb.defaultcf.block-cache-size",
+		"rocksdb.writecf.block-cache-size",
+		"rocksdb.lockcf.block-cache-size",
+		"raftdb.defaultcf.block-cache-size",
+	},
+	ComponentPD: {
+		"log.level",
+		"schedule.",
+		"replication.",
+		"pd-server.",
+		"label-property.",
+	},
+}
+
+// ConfigChange is a configuration item of an instance changed since the applied topology
+type ConfigChange struct {
+	Component string
+	Instance  string
+	// Key is empty if the specification of the instance other than the config is changed,
+	// or the instance is added
+	Key    string
+	Online bool
+}
+
+// OnlineConfigKey returns if the configuration item of the component can be changed online
+// without restarting
+func OnlineConfigKey(comp, key string) bool {
+	for _, online := range onlineConfigKeys[comp] {
+		if key == online || (strings.HasSuffix(online, ".") && strings.HasPrefix(key, online)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigChanges returns the changed configuration items of the instances changed between the
+// applied and the current topology in the order of the current one, each of them is classified
+// as online or restart required. An instance changed without any changed item is reported by a
+// change of an empty key, which requires restarting.
+func ConfigChanges(applied, current *TopologySpecification) ([]ConfigChange, error) {
+	changed := make(map[string]bool)
+	for _, ids := range ChangedNodes(applied, current) {
+		for _, id := range ids {
+			changed[id] = true
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	appliedInstances := make(map[string]Instance)
+	applied.IterInstance(func(inst Instance) {
+		appliedInstances[inst.ComponentName()+"/"+inst.ID()] = inst
+	})
+
+	var changes []ConfigChange
+	var err error
+	current.IterInstance(func(inst Instance) {
+		if err != nil || !changed[inst.ID()] {
+			return
+		}
+		comp := inst.ComponentName()
+		old, found := appliedInstances[comp+"/"+inst.ID()]
+		if !found {
+			changes = append(changes, ConfigChange{Component: comp, Instance: inst.ID()})
+			return
+		}
+
+		var keys []string
+		keys, err = changedConfigKeys(
+			effectiveConfig(applied.ServerConfigs.of(comp), old),
+			effectiveConfig(current.ServerConfigs.of(comp), inst))
+		if err != nil {
+			return
+		}
+		if len(keys) == 0 {
+			changes = append(changes, ConfigChange{Component: comp, Instance: inst.ID()})
+			return
+		}
+		for _, key := range keys {
+			changes = append(changes, ConfigChange{
+				Component: comp,
+				Instance:  inst.ID(),
+				Key:       key,
+				Online:    OnlineConfigKey(comp, key),
+			})
+		}
+	})
+	return changes, err
+}
+
+// effectiveConfig returns the server configs overwritten by the config of the instance, the
+// configs are ordered by precedence
+func effectiveConfig(globals []map[string]interface{}, inst Instance) []map[string]interface{} {
+	configs := append([]map[string]interface{}{}, globals...)
+	if config, ok := instanceConfig(inst); ok {
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// changedConfigKeys returns the sorted leaf keys whose values are different between the
+// configs, including the ones absent from either of them
+func changedConfigKeys(old, current []map[string]interface{}) ([]string, error) {
+	oldValues, err := configValues(old)
+	if err != nil {
+		return nil, err
+	}
+	currentValues, err := configValues(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key, value := range currentValues {
+		if v, ok := oldValues[key]; !ok || !reflect.DeepEqual(v, value) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range oldValues {
+		if _, ok := currentValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// configValues returns the values of the leaf keys in the form of `a.b.c` of the configs
+// merged in order
+func configValues(configs []map[string]interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, config := range configs {
+		var err error
+		if merged, err = merge(merged, config); err != nil {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]interface{})
+	var collect func(prefix string, m map[string]interface{})
+	collect = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				collect(key, sub)
+				continue
+			}
+			values[key] = v
+		}
+	}
+	collect("", merged)
+	return values, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestConfigChanges(c *C) {
+	applied := changesTopology(c, `
+server_configs:
+  tidb:
+    log.level: info
+  tikv:
+    storage.block-cache.capacity: 8GB
+    raftstore.apply-pool-size: 2
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`)
+	changes, err := ConfigChanges(applied, changesTopology(c, changesBaseTopology))
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 3)
+
+	current := changesTopology(c, `
+server_configs:
+  tidb:
+    log.level: warn
+    log.file.max-size: 300
+  tikv:
+    storage.block-cache.capacity: 16GB
+    raftstore.apply-pool-size: 2
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+    config:
+      raftstore.apply-pool-size: 4
+      server.grpc-concurrency: 8
+  - host: 172.16.5.142
+`)
+	changes, err = ConfigChanges(applied, current)
+	c.Assert(err, IsNil)
+	c.Assert(changes, DeepEquals, []ConfigChange{
+		{Component: ComponentTiKV, Instance: "172.16.5.140:20160", Key: "storage.block-cache.capacity", Online: true},
+		{Component: ComponentTiKV, Instance: "172.16.5.141:20160", Key: "raftstore.apply-pool-size", Online: true},
+		{Component: ComponentTiKV, Instance: "172.16.5.141:20160", Key: "server.grpc-concurrency"},
+		{Component: ComponentTiKV, Instance: "172.16.5.141:20160", Key: "storage.block-cache.capacity", Online: true},
+		{Component: ComponentTiKV, Instance: "172.16.5.142:20160"},
+		{Component: ComponentTiDB, Instance: "172.16.5.140:4000", Key: "log.file.max-size"},
+		{Component: ComponentTiDB, Instance: "172.16.5.140:4000", Key: "log.level", Online: true},
+	})
+
+	changes, err = ConfigChanges(current, current)
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 0)
+
+	c.Assert(OnlineConfigKey(ComponentPD, "schedule.leader-schedule-limit"), IsTrue)
+	c.Assert(OnlineConfigKey(ComponentPD, "schedule"), IsFalse)
+	c.Assert(OnlineConfigKey(ComponentTiDB, "log.level"), IsTrue)
+	c.Assert(OnlineConfigKey(ComponentTiDB, "log.level.x"), IsFalse)
+	c.Assert(OnlineConfigKey(ComponentPump, "gc"), IsFalse)
+}
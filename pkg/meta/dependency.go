@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// ComponentDependencies are the components which must be up before each component is
+// started for the cluster to form, the start order of the components is derived from them.
+// A new component declares the components it depends on here.
+var ComponentDependencies = map[string][]string{
+	ComponentTiKV:         {ComponentPD},
+	ComponentPump:         {ComponentPD},
+	ComponentTiDB:         {ComponentPD, ComponentTiKV, ComponentPump},
+	ComponentTiProxy:      {ComponentTiDB},
+	ComponentTiFlash:      {ComponentPD, ComponentTiKV},
+	ComponentDrainer:      {ComponentPD, ComponentPump},
+	ComponentCDC:          {ComponentPD, ComponentTiKV},
+	ComponentGrafana:      {ComponentPrometheus},
+	ComponentAlertManager: {},
+}
+
+// DependencyOrder sorts the components so that each one comes after the ones it depends on,
+// the components not constrained by each other are kept in the given order. The dependencies
+// absent from the components are ignored, and an error is returned if they are circular.
+func DependencyOrder(names []string, deps map[string][]string) ([]string, error) {
+	pending := make(map[string]bool)
+	for _, name := range names {
+		pending[name] = true
+	}
+
+	var sorted []string
+	for len(sorted) < len(names) {
+		next := ""
+		for _, name := range names {
+			if !pending[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps[name] {
+				if pending[dep] && dep != name {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = name
+				break
+			}
+		}
+		if next == "" {
+			var cycle []string
+			for _, name := range names {
+				if pending[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, errors.Errorf("circular dependencies among components %s", strings.Join(cycle, ", "))
+		}
+		pending[next] = false
+		sorted = append(sorted, next)
+	}
+	return sorted, nil
+}
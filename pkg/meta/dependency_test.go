@@ -0,0 +1,56 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestDependencyOrder(c *C) {
+	// the unconstrained ones are kept in order
+	sorted, err := DependencyOrder([]string{"a", "b", "c"}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sorted, DeepEquals, []string{"a", "b", "c"})
+
+	// the dependencies come first, and the absent ones are ignored
+	sorted, err = DependencyOrder([]string{"tidb", "tikv", "pd", "grafana"}, map[string][]string{
+		"tidb":    {"pd", "tikv", "pump"},
+		"tikv":    {"pd"},
+		"grafana": {"prometheus"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(sorted, DeepEquals, []string{"pd", "tikv", "tidb", "grafana"})
+
+	_, err = DependencyOrder([]string{"pd", "a", "b"}, map[string][]string{"a": {"b"}, "b": {"a"}})
+	c.Assert(err, ErrorMatches, "circular dependencies among components a, b")
+}
+
+func (s *metaSuite) TestComponentsByStartOrder(c *C) {
+	topo := &Specification{}
+	started := map[string]bool{}
+	for _, comp := range topo.ComponentsByStartOrder() {
+		for _, dep := range ComponentDependencies[comp.Name()] {
+			c.Assert(started[dep], IsTrue, Commentf("%s is started before %s", comp.Name(), dep))
+		}
+		started[comp.Name()] = true
+	}
+	c.Assert(started, HasLen, 11)
+
+	var names []string
+	for _, comp := range topo.ComponentsByStopOrder() {
+		names = append(names, comp.Name())
+	}
+	c.Assert(names[0], Equals, ComponentAlertManager)
+	c.Assert(names[len(names)-1], Equals, ComponentPD)
+}
@@ -17,10 +17,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
-	"strconv"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/template/scripts"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 )
 
 // DrainerComponent represents Drainer component.
@@ -52,7 +52,7 @@ func (c *DrainerComponent) Instances() []Instance {
 				s.DataDir,
 			},
 			statusFn: func(_ ...string) string {
-				url := fmt.Sprintf("http://%s:%d/status", s.Host, s.Port)
+				url := fmt.Sprintf("http://%s/status", utils.JoinHostPort(s.Host, s.Port))
 				return statusByURL(url)
 			},
 		}})
@@ -84,7 +84,7 @@ func (i *DrainerInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 
 	spec := i.InstanceSpec.(DrainerSpec)
 	cfg := scripts.NewDrainerScript(
-		i.GetHost()+":"+strconv.Itoa(i.GetPort()),
+		utils.JoinHostPort(i.GetHost(), i.GetPort()),
 		i.GetHost(),
 		paths.Deploy,
 		paths.Data,
@@ -0,0 +1,190 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+	"golang.org/x/mod/semver"
+)
+
+// The sources of the effective configuration items, from the lowest precedence to the highest
+const (
+	ConfigSourceGenerated     = "generated"
+	ConfigSourceServerConfigs = "server_configs"
+	ConfigSourceImported      = "imported"
+	ConfigSourceInstance      = "config"
+	ConfigSourceLearner       = "learner_config"
+	ConfigSourceFragment      = "config_fragment"
+)
+
+// EffectiveConfigItem is an item of the config file of an instance and where its value is from
+type EffectiveConfigItem struct {
+	File   string
+	Key    string
+	Value  interface{}
+	Source string
+}
+
+// configLayer is a source of the config file of an instance
+type configLayer struct {
+	source string
+	config map[string]interface{}
+}
+
+// EffectiveConfig returns the items of the config files rendered for the instance by
+// InitConfig with the paths, ordered by file and key, and each of them is annotated with its
+// source. It's empty if the instance has no config file.
+func EffectiveConfig(clusterName, clusterVersion string, inst Instance, paths DirPaths) ([]EffectiveConfigItem, error) {
+	files, err := configLayers(clusterName, clusterVersion, inst, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []EffectiveConfigItem
+	for _, name := range sortedKeys(files) {
+		layers := files[name]
+		var merged []map[string]interface{}
+		sources := make(map[string]string)
+		for _, layer := range layers {
+			values, err := configValues([]map[string]interface{}{layer.config})
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid %s of %s", layer.source, inst.ID())
+			}
+			for key := range values {
+				sources[key] = layer.source
+			}
+			merged = append(merged, layer.config)
+		}
+
+		values, err := configValues(merged)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(values) {
+			items = append(items, EffectiveConfigItem{File: name, Key: key, Value: values[key], Source: sources[key]})
+		}
+	}
+	return items, nil
+}
+
+// configLayers returns the sources of each config file of the instance by the file name in the
+// order of precedence, in the same way as InitConfig of the component
+func configLayers(clusterName, clusterVersion string, inst Instance, paths DirPaths) (map[string][]configLayer, error) {
+	comp := inst.ComponentName()
+	var spec InstanceSpec
+	if v := reflect.ValueOf(inst).Elem().FieldByName("InstanceSpec"); v.IsValid() {
+		spec, _ = v.Interface().(InstanceSpec)
+	}
+	config, _ := instanceConfig(inst)
+	t, ok := inst.(interface{ topology() *Specification })
+	if !ok {
+		return nil, nil
+	}
+	topo := t.topology()
+	globals := topo.ServerConfigs.of(comp)
+	if len(globals) == 0 {
+		return nil, nil
+	}
+
+	var generated map[string]interface{}
+
+	files := make(map[string][]configLayer)
+	switch comp {
+	case ComponentTiFlash:
+		flash := inst.(*TiFlashInstance)
+		cfg := flash.script(paths)
+		learner, err := flash.InitTiFlashLearnerConfig(cfg, nil)
+		if err != nil {
+			return nil, err
+		}
+		learnerLayers := []configLayer{
+			{ConfigSourceGenerated, learner},
+			{ConfigSourceServerConfigs, topo.ServerConfigs.TiFlashLearner},
+		}
+		if inst.IsImported() {
+			imported, err := importedConfig(clusterName, fmt.Sprintf("%s-learner-%s-%d.toml", comp, inst.GetHost(), inst.GetPort()))
+			if err != nil {
+				return nil, err
+			}
+			learnerLayers = append(learnerLayers, configLayer{ConfigSourceImported, imported})
+		}
+		files[comp+"-learner.toml"] = append(learnerLayers, configLayer{ConfigSourceLearner, spec.(TiFlashSpec).LearnerConfig})
+
+		if generated, err = flash.InitTiFlashConfig(cfg, nil); err != nil {
+			return nil, err
+		}
+	case ComponentTiProxy:
+		generated = tiproxyConfig(spec.(TiProxySpec), topo.GetPDList(), paths.Log)
+	}
+
+	layers := []configLayer{
+		{ConfigSourceGenerated, generated},
+		{ConfigSourceServerConfigs, globals[0]},
+	}
+	if inst.IsImported() {
+		imported, err := importedConfig(clusterName, fmt.Sprintf("%s-%s-%d.toml", comp, inst.GetHost(), inst.GetPort()))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, configLayer{ConfigSourceImported, imported})
+	}
+	layers = append(layers, configLayer{ConfigSourceInstance, config})
+
+	// the address of the metrics storage is always set to the config of PD
+	if comp == ComponentPD && semver.Compare(clusterVersion, "v3.1.0") >= 0 && len(topo.Monitors) > 0 {
+		prom := topo.Monitors[0]
+		layers = append(layers, configLayer{ConfigSourceGenerated, map[string]interface{}{
+			"pd-server.metric-storage": "http://" + utils.JoinHostPort(prom.Host, prom.Port),
+		}})
+	}
+
+	if fragment := configFragment(spec); fragment != "" {
+		var fragmentData map[string]interface{}
+		if _, err := toml.Decode(fragment, &fragmentData); err != nil {
+			return nil, errors.Annotatef(err, "invalid config_fragment of %s", inst.ID())
+		}
+		layers = append(layers, configLayer{ConfigSourceFragment, fragmentData})
+	}
+	files[comp+".toml"] = layers
+	return files, nil
+}
+
+// importedConfig reads the config of the instance imported from TiDB Ansible
+func importedConfig(clusterName, name string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(ClusterPath(clusterName, "config", name))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var config map[string]interface{}
+	if err := toml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the imported config %s", name)
+	}
+	return config, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
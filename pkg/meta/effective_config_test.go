@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	. "github.com/pingcap/check"
+)
+
+// effectiveItems returns the effective config items of the instance by file and key
+func effectiveItems(c *C, topo *Specification, id string) map[string]EffectiveConfigItem {
+	items := make(map[string]EffectiveConfigItem)
+	topo.IterInstance(func(inst Instance) {
+		if inst.ID() != id {
+			return
+		}
+		result, err := EffectiveConfig("test", "v4.0.0", inst, DirPaths{
+			Deploy: "/home/tidb/deploy",
+			Data:   "/home/tidb/data",
+			Log:    "/home/tidb/deploy/log",
+		})
+		c.Assert(err, IsNil)
+		for _, item := range result {
+			items[item.File+":"+item.Key] = item
+		}
+	})
+	return items
+}
+
+func (s *metaSuite) TestEffectiveConfig(c *C) {
+	topo := changesTopology(c, `
+server_configs:
+  tikv:
+    readpool.storage.use-unified-pool: true
+    storage.block-cache.capacity: 8GB
+    raftstore.apply-pool-size: 2
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      storage.block-cache.capacity: 16GB
+      server.grpc-concurrency: 8
+    config_fragment: |
+      [raftstore]
+      apply-pool-size = 4
+monitoring_servers:
+  - host: 172.16.5.140
+`)
+
+	items := effectiveItems(c, topo, "172.16.5.140:20160")
+	c.Assert(items, HasLen, 4)
+	c.Assert(items["tikv.toml:readpool.storage.use-unified-pool"], DeepEquals, EffectiveConfigItem{
+		File: "tikv.toml", Key: "readpool.storage.use-unified-pool", Value: true, Source: ConfigSourceServerConfigs,
+	})
+	c.Assert(items["tikv.toml:storage.block-cache.capacity"].Value, Equals, "16GB")
+	c.Assert(items["tikv.toml:storage.block-cache.capacity"].Source, Equals, ConfigSourceInstance)
+	c.Assert(items["tikv.toml:server.grpc-concurrency"].Source, Equals, ConfigSourceInstance)
+	c.Assert(items["tikv.toml:raftstore.apply-pool-size"].Value, Equals, int64(4))
+	c.Assert(items["tikv.toml:raftstore.apply-pool-size"].Source, Equals, ConfigSourceFragment)
+
+	// the address of the metrics storage is generated for PD
+	items = effectiveItems(c, topo, "172.16.5.140:2379")
+	c.Assert(items, HasLen, 1)
+	c.Assert(items["pd.toml:pd-server.metric-storage"].Value, Equals, "http://172.16.5.140:9090")
+	c.Assert(items["pd.toml:pd-server.metric-storage"].Source, Equals, ConfigSourceGenerated)
+
+	// the monitoring components have no config file
+	c.Assert(effectiveItems(c, topo, "172.16.5.140:9090"), HasLen, 0)
+}
+
+func (s *metaSuite) TestEffectiveConfigTiFlash(c *C) {
+	topo := changesTopology(c, `
+server_configs:
+  tiflash:
+    logger.level: info
+  tiflash-learner:
+    server.labels.zone: z1
+pd_servers:
+  - host: 172.16.5.140
+tiflash_servers:
+  - host: 172.16.5.141
+    config:
+      logger.level: debug
+    learner_config:
+      log-level: warn
+`)
+
+	items := effectiveItems(c, topo, "172.16.5.141:9000")
+	c.Assert(items["tiflash.toml:logger.level"].Value, Equals, "debug")
+	c.Assert(items["tiflash.toml:logger.level"].Source, Equals, ConfigSourceInstance)
+	c.Assert(items["tiflash.toml:flash.service_addr"].Value, Equals, "172.16.5.141:3930")
+	c.Assert(items["tiflash.toml:flash.service_addr"].Source, Equals, ConfigSourceGenerated)
+	c.Assert(items["tiflash-learner.toml:server.labels.zone"].Source, Equals, ConfigSourceServerConfigs)
+	c.Assert(items["tiflash-learner.toml:log-level"].Value, Equals, "warn")
+	c.Assert(items["tiflash-learner.toml:log-level"].Source, Equals, ConfigSourceLearner)
+	c.Assert(items["tiflash-learner.toml:server.engine-addr"].Source, Equals, ConfigSourceGenerated)
+}
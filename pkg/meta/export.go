@@ -0,0 +1,139 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap/errors"
+)
+
+// ExportTopology serializes the topology to a clean YAML document which can be used
+// to deploy the cluster again. The fields derived from the global options while
+// parsing (e.g: ssh_port, name, deploy_dir, data_dir and log_dir) are omitted if they
+// have not been changed, the parsed result of the exported document equals to topo.
+func ExportTopology(clusterName string, topo *TopologySpecification) ([]byte, error) {
+	exported := *topo
+	if err := clearDerivedFields(&exported); err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(&exported)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+
+	// The comments of the original topology file are not kept in the meta,
+	// so only a header is added to describe where this file comes from
+	buf := bytes.NewBufferString(fmt.Sprintf(`# Topology of cluster %s exported by tiup-cluster.
+# It can be used to deploy the same cluster again.
+`, clusterName))
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// clearDerivedFields resets the fields which are filled by setCustomDefaults to
+// empty values, the component slices are copied so the origin topology is not changed
+func clearDerivedFields(topo *TopologySpecification) error {
+	globalOptions := topo.GlobalOptions
+
+	monitored := &topo.MonitoredOptions
+	monitorDir := fmt.Sprintf("%s-%d", RoleMonitor, monitored.NodeExporterPort)
+	if monitored.DeployDir == filepath.Join(globalOptions.DeployDir, monitorDir) {
+		monitored.DeployDir = ""
+	}
+	if monitored.DataDir == filepath.Join(globalOptions.DataDir, monitorDir) {
+		monitored.DataDir = ""
+	}
+
+	v := reflect.ValueOf(topo).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if isSkipField(field) || field.Kind() != reflect.Slice {
+			continue
+		}
+
+		copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(copied, field)
+		field.Set(copied)
+
+		for j := 0; j < copied.Len(); j++ {
+			if err := clearDerivedSpecFields(&globalOptions, copied.Index(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func clearDerivedSpecFields(globalOptions *GlobalOptions, spec reflect.Value) error {
+	instSpec, ok := spec.Interface().(InstanceSpec)
+	if !ok {
+		return errors.Errorf("unknown component specification %s", spec.Type().Name())
+	}
+	role := instSpec.Role()
+	port := getPort(spec)
+
+	for j := 0; j < spec.NumField(); j++ {
+		field := spec.Field(j)
+		switch spec.Type().Field(j).Name {
+		case "SSHPort":
+			if field.Int() == int64(globalOptions.SSHPort) {
+				field.SetInt(0)
+			}
+		case "Name":
+			host := spec.FieldByName("Host").String()
+			clientPort := spec.FieldByName("ClientPort").Int()
+			if field.String() == fmt.Sprintf("pd-%s-%d", host, clientPort) {
+				field.SetString("")
+			}
+		case "DataDir":
+			if field.String() == filepath.Join(globalOptions.DataDir, fmt.Sprintf("%s-%s", role, port)) {
+				field.SetString("")
+			}
+		case "DeployDir":
+			if field.String() == filepath.Join(globalOptions.DeployDir, fmt.Sprintf("%s-%s", role, port)) {
+				field.SetString("")
+			}
+		case "LogDir":
+			if field.String() == globalOptions.LogDir {
+				field.SetString("")
+			}
+		}
+	}
+	return nil
+}
+
+// TopologyJSON serializes the topology to JSON with the same field names as the YAML
+// document, the fields derived from the global options are kept.
+func TopologyJSON(topo *TopologySpecification) ([]byte, error) {
+	data, err := yaml.Marshal(topo)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	data, err = json.Marshal(strKeyMap(doc))
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return data, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestExportTopology(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: "tidb"
+  ssh_port: 220
+  deploy_dir: "/tidb-deploy"
+  data_dir: "/tidb-data"
+server_configs:
+  tidb:
+    log.slow-threshold: 300
+tidb_servers:
+  - host: 172.16.5.138
+    deploy_dir: "/my-deploy/tidb"
+  - host: 172.16.5.139
+    ssh_port: 22
+    port: 4001
+tikv_servers:
+  - host: 172.16.5.140
+    data_dir: "/ssd/tikv"
+    config:
+      server.labels: { zone: "z1" }
+pd_servers:
+  - host: 172.16.5.53
+    name: "pd-1"
+tiproxy_servers:
+  - host: 172.16.5.141
+monitoring_servers:
+  - host: 172.16.5.53
+`), &topo)
+	c.Assert(err, IsNil)
+
+	data, err := ExportTopology("test", &topo)
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(string(data), "# Topology of cluster test"), IsTrue)
+
+	// the derived fields should be omitted and the customized fields kept
+	output := string(data)
+	c.Assert(strings.Contains(output, "/tidb-deploy/tidb-4001"), IsFalse)
+	c.Assert(strings.Contains(output, "/tidb-data/tikv-20160"), IsFalse)
+	c.Assert(strings.Contains(output, "/tidb-deploy/monitor-9100"), IsFalse)
+	c.Assert(strings.Contains(output, "/my-deploy/tidb"), IsTrue)
+	c.Assert(strings.Contains(output, "/ssd/tikv"), IsTrue)
+	c.Assert(strings.Contains(output, "ssh_port: 22\n"), IsTrue)
+
+	// the origin topology should not be changed
+	c.Assert(topo.TiDBServers[1].DeployDir, Equals, "/tidb-deploy/tidb-4001")
+	c.Assert(topo.TiDBServers[0].SSHPort, Equals, 220)
+
+	reparsed := TopologySpecification{}
+	err = yaml.Unmarshal(data, &reparsed)
+	c.Assert(err, IsNil)
+	c.Assert(reparsed, DeepEquals, topo)
+}
+
+func (s *metaSuite) TestTopologyJSON(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  user: "tidb"
+  deploy_dir: "/tidb-deploy"
+server_configs:
+  tikv:
+    readpool.storage.use-unified-pool: true
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { zone: "z1" }
+`), &topo)
+	c.Assert(err, IsNil)
+
+	data, err := TopologyJSON(&topo)
+	c.Assert(err, IsNil)
+	var doc struct {
+		Global struct {
+			User string `json:"user"`
+		} `json:"global"`
+		ServerConfigs struct {
+			TiKV map[string]interface{} `json:"tikv"`
+		} `json:"server_configs"`
+		TiKVServers []struct {
+			Host      string                 `json:"host"`
+			DeployDir string                 `json:"deploy_dir"`
+			Config    map[string]interface{} `json:"config"`
+		} `json:"tikv_servers"`
+	}
+	c.Assert(json.Unmarshal(data, &doc), IsNil)
+	c.Assert(doc.Global.User, Equals, "tidb")
+	c.Assert(doc.ServerConfigs.TiKV["readpool.storage.use-unified-pool"], Equals, true)
+	c.Assert(doc.TiKVServers, HasLen, 1)
+	c.Assert(doc.TiKVServers[0].Host, Equals, "172.16.5.140")
+	// the derived fields are kept
+	c.Assert(doc.TiKVServers[0].DeployDir, Equals, "/tidb-deploy/tikv-20160")
+	c.Assert(doc.TiKVServers[0].Config["server.labels"], DeepEquals, map[string]interface{}{"zone": "z1"})
+}
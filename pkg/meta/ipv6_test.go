@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+var ipv6Topology = `
+pd_servers:
+  - host: fd00::1
+  - host: fd00::2
+tikv_servers:
+  - host: fd00::3
+`
+
+func (s *metaSuite) TestIPv6Endpoints(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(ipv6Topology), &topo)
+	c.Assert(err, IsNil)
+
+	c.Assert(topo.GetPDList(), DeepEquals, []string{"[fd00::1]:2379", "[fd00::2]:2379"})
+
+	inst := (&TiKVComponent{&topo}).Instances()[0]
+	c.Assert(inst.GetHost(), Equals, "fd00::3")
+	c.Assert(inst.ID(), Equals, "[fd00::3]:20160")
+
+	host, port, err := utils.ParseHostPort(inst.ID())
+	c.Assert(err, IsNil)
+	c.Assert(host, Equals, "fd00::3")
+	c.Assert(port, Equals, 20160)
+}
+
+func (s *metaSuite) TestIPv6InitConfig(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	cache, err := ioutil.TempDir("", "ipv6")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := TopologySpecification{}
+	err = yaml.Unmarshal([]byte(ipv6Topology), &topo)
+	c.Assert(err, IsNil)
+
+	e := &recordExecutor{transfers: map[string]string{}}
+	paths := DirPaths{
+		Deploy: "/home/tidb/deploy/tikv-20160",
+		Data:   "/home/tidb/deploy/tikv-20160/data",
+		Log:    "/home/tidb/deploy/tikv-20160/log",
+		Cache:  cache,
+	}
+	inst := (&TiKVComponent{&topo}).Instances()[0]
+	c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+
+	script, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/tikv-20160/scripts/run_tikv.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, `(?s).*--advertise-addr "\[fd00::3\]:20160".*`)
+	c.Assert(string(script), Matches, `(?s).*--status-addr "\[fd00::3\]:20180".*`)
+	c.Assert(string(script), Matches, `(?s).*--pd "\[fd00::1\]:2379,\[fd00::2\]:2379".*`)
+
+	paths = DirPaths{
+		Deploy: "/home/tidb/deploy/pd-2379",
+		Data:   "/home/tidb/deploy/pd-2379/data",
+		Log:    "/home/tidb/deploy/pd-2379/log",
+		Cache:  cache,
+	}
+	inst = (&PDComponent{&topo}).Instances()[0]
+	c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+
+	script, err = ioutil.ReadFile(e.transfers["/home/tidb/deploy/pd-2379/scripts/run_pd.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, `(?s).*--client-urls="http://\[fd00::1\]:2379".*`)
+	c.Assert(string(script), Matches, `(?s).*--peer-urls="http://\[fd00::1\]:2380".*`)
+}
@@ -0,0 +1,345 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// defaultMaxReplicas is the default value of `replication.max-replicas` of PD
+const defaultMaxReplicas = 3
+
+// Kinds of the label issues
+const (
+	LabelIssueMissing      = "missing"
+	LabelIssueDuplicate    = "duplicate"
+	LabelIssueInconsistent = "inconsistent"
+	// LabelIssueUnlabeled means multiple instances are on the same host without location labels,
+	// PD may place the replicas of a region on the same host
+	LabelIssueUnlabeled = "unlabeled"
+)
+
+// LabelIssue is a problem of the labels of a TiKV instance
+type LabelIssue struct {
+	Instance string
+	Kind     string
+	Message  string
+}
+
+// String implements the fmt.Stringer interface
+func (i LabelIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Instance, i.Message)
+}
+
+// LabelConstraint is the label constraint of a placement rule
+type LabelConstraint struct {
+	Key    string   `json:"key"`
+	Op     string   `json:"op"`
+	Values []string `json:"values"`
+}
+
+// PlacementRule is the placement rule of PD, see https://pingcap.com/docs/stable/how-to/configure/placement-rules/
+type PlacementRule struct {
+	GroupID          string            `json:"group_id"`
+	ID               string            `json:"id"`
+	Index            int               `json:"index,omitempty"`
+	StartKeyHex      string            `json:"start_key"`
+	EndKeyHex        string            `json:"end_key"`
+	Role             string            `json:"role"`
+	Count            int               `json:"count"`
+	LabelConstraints []LabelConstraint `json:"label_constraints,omitempty"`
+	LocationLabels   []string          `json:"location_labels,omitempty"`
+}
+
+// configValue returns the value of the key in the form of `a.b.c` in the nested config
+func configValue(config map[string]interface{}, key string) (interface{}, bool) {
+	parts := strings.Split(key, ".")
+	var val interface{} = config
+	for _, part := range parts {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if val, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return val, true
+}
+
+// ReplicationConfig returns the `replication.location-labels` and `replication.max-replicas`
+// of PD, the config of the first PD instance overwrites the server_configs
+func (topo *Specification) ReplicationConfig() ([]string, int, error) {
+	configs := []map[string]interface{}{topo.ServerConfigs.PD}
+	if len(topo.PDServers) > 0 {
+		configs = append(configs, topo.PDServers[0].Config)
+	}
+	// the value in the last config which has the key is used
+	value := func(key string) (interface{}, bool) {
+		var (
+			val   interface{}
+			found bool
+		)
+		for _, config := range configs {
+			nested, _ := flattenMap(config)
+			if v, ok := configValue(nested, key); ok {
+				val, found = v, true
+			}
+		}
+		return val, found
+	}
+
+	var labels []string
+	if val, ok := value("replication.location-labels"); ok {
+		list, ok := val.([]interface{})
+		if !ok && val != nil {
+			return nil, 0, errors.Errorf("replication.location-labels should be an array, but got %v", val)
+		}
+		for _, label := range list {
+			labels = append(labels, fmt.Sprint(label))
+		}
+	}
+
+	replicas := defaultMaxReplicas
+	if val, ok := value("replication.max-replicas"); ok {
+		n, err := strconv.Atoi(fmt.Sprint(val))
+		if err != nil || n <= 0 {
+			return nil, 0, errors.Errorf("replication.max-replicas should be a positive integer, but got %v", val)
+		}
+		replicas = n
+	}
+	return labels, replicas, nil
+}
+
+// TiKVLabels returns the `server.labels` of each TiKV instance, indexed by the instance ID,
+// the labels of the instance overwrite the ones in server_configs
+func (topo *Specification) TiKVLabels() (map[string]map[string]string, error) {
+	// the labels are read from each config separately as merging them may change the global one
+	labelsOf := func(config map[string]interface{}, labels map[string]string) error {
+		nested, err := flattenMap(config)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		val, ok := configValue(nested, "server.labels")
+		if !ok {
+			return nil
+		}
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("server.labels should be a map, but got %v", val)
+		}
+		for k, v := range m {
+			labels[k] = fmt.Sprint(v)
+		}
+		return nil
+	}
+
+	result := map[string]map[string]string{}
+	for _, inst := range (&TiKVComponent{Specification: topo}).Instances() {
+		labels := map[string]string{}
+		if err := labelsOf(topo.ServerConfigs.TiKV, labels); err != nil {
+			return nil, err
+		}
+		instConfig, _ := instanceConfig(inst)
+		if err := labelsOf(instConfig, labels); err != nil {
+			return nil, errors.Annotatef(err, "invalid config of %s", inst.ID())
+		}
+		result[inst.ID()] = labels
+	}
+	return result, nil
+}
+
+// SetTiKVLabels sets the `server.labels` of the TiKV instance to the labels, which replace
+// the labels in its config in any form, e.g. nested in `server` or `server.labels.zone`
+func (topo *Specification) SetTiKVLabels(id string, labels map[string]string) error {
+	for i := range topo.TiKVServers {
+		spec := &topo.TiKVServers[i]
+		if utils.JoinHostPort(spec.Host, spec.Port) != id {
+			continue
+		}
+		if spec.Config == nil {
+			spec.Config = make(map[string]interface{})
+		}
+		for key := range spec.Config {
+			if strings.HasPrefix(key, "server.labels.") {
+				delete(spec.Config, key)
+			}
+		}
+		if server, ok := spec.Config["server"].(map[string]interface{}); ok {
+			delete(server, "labels")
+			if len(server) == 0 {
+				delete(spec.Config, "server")
+			}
+		}
+		value := make(map[string]interface{}, len(labels))
+		for k, v := range labels {
+			value[k] = v
+		}
+		spec.Config["server.labels"] = value
+		return nil
+	}
+	return errors.Errorf("TiKV instance %s is not found", id)
+}
+
+// location returns the values of the location labels joined by `/`
+func location(labels map[string]string, locationLabels []string) string {
+	var values []string
+	for _, key := range locationLabels {
+		values = append(values, key+"="+labels[key])
+	}
+	return strings.Join(values, "/")
+}
+
+// CheckLabels checks the labels of the TiKV instances against the location labels of PD:
+// every TiKV instance must have all the location labels, the instances on different hosts
+// must not be in the same location, and the instances on the same host must be in the same location
+func (topo *Specification) CheckLabels() ([]LabelIssue, error) {
+	locationLabels, _, err := topo.ReplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+	tikvLabels, err := topo.TiKVLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LabelIssue
+	instances := (&TiKVComponent{Specification: topo}).Instances()
+	if len(locationLabels) == 0 {
+		hosts := map[string]string{}
+		for _, inst := range instances {
+			if other, found := hosts[inst.GetHost()]; found {
+				issues = append(issues, LabelIssue{
+					Instance: inst.ID(),
+					Kind:     LabelIssueUnlabeled,
+					Message:  fmt.Sprintf("replication.location-labels of PD is suggested as it is on the same host with %s", other),
+				})
+				continue
+			}
+			hosts[inst.GetHost()] = inst.ID()
+		}
+		return issues, nil
+	}
+
+	locations := map[string]Instance{} // location -> first instance in it
+	hostLocations := map[string]Instance{}
+	for _, inst := range instances {
+		labels := tikvLabels[inst.ID()]
+		var missing []string
+		for _, key := range locationLabels {
+			if _, ok := labels[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			issues = append(issues, LabelIssue{
+				Instance: inst.ID(),
+				Kind:     LabelIssueMissing,
+				Message:  fmt.Sprintf("missing labels %s in server.labels", strings.Join(missing, ", ")),
+			})
+			continue
+		}
+
+		loc := location(labels, locationLabels)
+		if other, found := hostLocations[inst.GetHost()]; found {
+			if otherLoc := location(tikvLabels[other.ID()], locationLabels); otherLoc != loc {
+				issues = append(issues, LabelIssue{
+					Instance: inst.ID(),
+					Kind:     LabelIssueInconsistent,
+					Message:  fmt.Sprintf("location %s is different from %s of %s on the same host", loc, otherLoc, other.ID()),
+				})
+			}
+			continue
+		}
+		hostLocations[inst.GetHost()] = inst
+
+		if other, found := locations[loc]; found {
+			issues = append(issues, LabelIssue{
+				Instance: inst.ID(),
+				Kind:     LabelIssueDuplicate,
+				Message:  fmt.Sprintf("location %s is the same as %s on a different host", loc, other.ID()),
+			})
+			continue
+		}
+		locations[loc] = inst
+	}
+	return issues, nil
+}
+
+// PlacementRules generates the placement rules of PD from the topology. The replicas are
+// distributed evenly across the values of the first location label, and the rest of the
+// location labels are used to isolate the replicas in each of them. Only the default rule
+// is generated if there is no location label.
+func (topo *Specification) PlacementRules() ([]PlacementRule, error) {
+	locationLabels, replicas, err := topo.ReplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+	defaultRule := PlacementRule{
+		GroupID:        "pd",
+		ID:             "default",
+		Role:           "voter",
+		Count:          replicas,
+		LocationLabels: locationLabels,
+	}
+	if len(locationLabels) == 0 {
+		return []PlacementRule{defaultRule}, nil
+	}
+
+	tikvLabels, err := topo.TiKVLabels()
+	if err != nil {
+		return nil, err
+	}
+	top := locationLabels[0]
+	var values []string
+	seen := map[string]bool{}
+	for _, labels := range tikvLabels {
+		if value, ok := labels[top]; ok && !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	if len(values) < 2 {
+		return []PlacementRule{defaultRule}, nil
+	}
+	sort.Strings(values)
+
+	var rules []PlacementRule
+	for i, value := range values {
+		count := replicas / len(values)
+		if i < replicas%len(values) {
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		rules = append(rules, PlacementRule{
+			GroupID: "pd",
+			ID:      fmt.Sprintf("%s-%s", top, value),
+			Role:    "voter",
+			Count:   count,
+			LabelConstraints: []LabelConstraint{
+				{Key: top, Op: "in", Values: []string{value}},
+			},
+			LocationLabels: locationLabels[1:],
+		})
+	}
+	return rules, nil
+}
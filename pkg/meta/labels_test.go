@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestCheckLabels(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+server_configs:
+  tikv:
+    server.labels: { zone: "z1" }
+  pd:
+    replication.location-labels: ["zone", "host"]
+    replication.max-replicas: 5
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { host: "h1" }
+  - host: 172.16.5.140
+    port: 20161
+    status_port: 20181
+    config:
+      server.labels: { host: "h1" }
+  - host: 172.16.5.141
+    config:
+      server.labels: { host: "h2" }
+  - host: 172.16.5.142
+    config:
+      server.labels: { zone: "z2", host: "h3" }
+`), &topo), IsNil)
+
+	labels, replicas, err := topo.ReplicationConfig()
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, []string{"zone", "host"})
+	c.Assert(replicas, Equals, 5)
+
+	tikvLabels, err := topo.TiKVLabels()
+	c.Assert(err, IsNil)
+	c.Assert(tikvLabels["172.16.5.142:20160"], DeepEquals, map[string]string{"zone": "z2", "host": "h3"})
+
+	issues, err := topo.CheckLabels()
+	c.Assert(err, IsNil)
+	c.Assert(issues, HasLen, 0)
+
+	rules, err := topo.PlacementRules()
+	c.Assert(err, IsNil)
+	c.Assert(rules, DeepEquals, []PlacementRule{
+		{
+			GroupID:          "pd",
+			ID:               "zone-z1",
+			Role:             "voter",
+			Count:            3,
+			LabelConstraints: []LabelConstraint{{Key: "zone", Op: "in", Values: []string{"z1"}}},
+			LocationLabels:   []string{"host"},
+		},
+		{
+			GroupID:          "pd",
+			ID:               "zone-z2",
+			Role:             "voter",
+			Count:            2,
+			LabelConstraints: []LabelConstraint{{Key: "zone", Op: "in", Values: []string{"z2"}}},
+			LocationLabels:   []string{"host"},
+		},
+	})
+}
+
+func (s *metaSuite) TestCheckLabelsMislabeled(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+server_configs:
+  pd:
+    replication.location-labels: ["zone", "host"]
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { zone: "z1", host: "h1" }
+  - host: 172.16.5.140
+    port: 20161
+    status_port: 20181
+    config:
+      server.labels: { zone: "z1", host: "h2" }
+  - host: 172.16.5.141
+    config:
+      server.labels: { zone: "z1", host: "h1" }
+  - host: 172.16.5.142
+    config:
+      server.labels: { host: "h3" }
+`), &topo), IsNil)
+
+	issues, err := topo.CheckLabels()
+	c.Assert(err, IsNil)
+	c.Assert(issues, DeepEquals, []LabelIssue{
+		{
+			Instance: "172.16.5.140:20161",
+			Kind:     LabelIssueInconsistent,
+			Message:  "location zone=z1/host=h2 is different from zone=z1/host=h1 of 172.16.5.140:20160 on the same host",
+		},
+		{
+			Instance: "172.16.5.141:20160",
+			Kind:     LabelIssueDuplicate,
+			Message:  "location zone=z1/host=h1 is the same as 172.16.5.140:20160 on a different host",
+		},
+		{
+			Instance: "172.16.5.142:20160",
+			Kind:     LabelIssueMissing,
+			Message:  "missing labels zone in server.labels",
+		},
+	})
+}
+
+func (s *metaSuite) TestCheckLabelsWithoutLocationLabels(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.140
+    port: 20161
+    status_port: 20181
+  - host: 172.16.5.141
+`), &topo), IsNil)
+
+	issues, err := topo.CheckLabels()
+	c.Assert(err, IsNil)
+	c.Assert(issues, HasLen, 1)
+	c.Assert(issues[0].Instance, Equals, "172.16.5.140:20161")
+	c.Assert(issues[0].Kind, Equals, LabelIssueUnlabeled)
+
+	// only the default rule is generated
+	rules, err := topo.PlacementRules()
+	c.Assert(err, IsNil)
+	c.Assert(rules, DeepEquals, []PlacementRule{{GroupID: "pd", ID: "default", Role: "voter", Count: 3}})
+}
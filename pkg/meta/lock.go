@@ -0,0 +1,266 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSLock = errNS.NewSubNamespace("lock")
+	// ErrClusterLocked means another mutating operation is in progress on the cluster
+	ErrClusterLocked = errNSLock.NewType("locked")
+)
+
+// LockHolder describes the operation holding the lock of a cluster
+type LockHolder struct {
+	Operation string    `json:"operation"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Since     time.Time `json:"since"`
+}
+
+// String implements the fmt.Stringer interface
+func (h LockHolder) String() string {
+	return fmt.Sprintf("%s by %s@%s (pid %d) since %s", h.Operation, h.User, h.Host, h.PID, h.Since.Format(time.RFC3339))
+}
+
+// Equal returns if the holders are the same one
+func (h LockHolder) Equal(other LockHolder) bool {
+	return h.Operation == other.Operation && h.User == other.User && h.Host == other.Host &&
+		h.PID == other.PID && h.Since.Equal(other.Since)
+}
+
+// NewLockHolder returns the holder of the current process for the operation
+func NewLockHolder(operation string) LockHolder {
+	holder := LockHolder{
+		Operation: operation,
+		User:      "unknown",
+		Host:      "unknown",
+		PID:       os.Getpid(),
+		Since:     time.Now().Round(time.Second),
+	}
+	if u, err := user.Current(); err == nil {
+		holder.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		holder.Host = host
+	}
+	return holder
+}
+
+// LockStore stores the locks of the clusters. The locks are files in the profile directory by
+// default, a shared coordination store makes them effective across the control machines.
+type LockStore interface {
+	// TryLock locks the cluster for the holder, ok is false with the current holder if it's
+	// locked by another one
+	TryLock(cluster string, holder LockHolder) (current LockHolder, ok bool, err error)
+	// Unlock unlocks the cluster if it's locked by the holder
+	Unlock(cluster string, holder LockHolder) error
+	// ForceUnlock unlocks the cluster whoever locks it
+	ForceUnlock(cluster string) error
+}
+
+// ClusterLock is the lock of a cluster held by the current operation
+type ClusterLock struct {
+	store   LockStore
+	cluster string
+	holder  LockHolder
+}
+
+// LockCluster locks the cluster for the operation, ErrClusterLocked is returned with the
+// current holder if another operation is in progress, and the lock is taken over from it if
+// force is enabled
+func LockCluster(store LockStore, cluster, operation string, force bool) (*ClusterLock, error) {
+	holder := NewLockHolder(operation)
+	current, ok, err := store.TryLock(cluster, holder)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && force {
+		if err := store.ForceUnlock(cluster); err != nil {
+			return nil, err
+		}
+		if current, ok, err = store.TryLock(cluster, holder); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, ErrClusterLocked.
+			New("Cluster `%s` is locked, operation already in progress: %s", cluster, current).
+			WithProperty(cliutil.SuggestionFromString("Please wait for the operation to finish, or run with --force-lock to take the lock over if it's abandoned."))
+	}
+	return &ClusterLock{store: store, cluster: cluster, holder: holder}, nil
+}
+
+// Holder returns the holder of the lock
+func (l *ClusterLock) Holder() LockHolder {
+	return l.holder
+}
+
+// Unlock releases the lock
+func (l *ClusterLock) Unlock() error {
+	return l.store.Unlock(l.cluster, l.holder)
+}
+
+// FileLockStore stores the lock of a cluster as a file created exclusively in the directory,
+// the lock of a process exited on the same host is considered abandoned and taken over. The
+// takeover and the release are serialized by flock on a guard file which is never removed, so
+// that a lock just created by another process is not removed as the abandoned one.
+type FileLockStore struct {
+	dir string
+}
+
+// NewFileLockStore returns a FileLockStore of the directory
+func NewFileLockStore(dir string) *FileLockStore {
+	return &FileLockStore{dir: dir}
+}
+
+// DefaultLockStore returns the FileLockStore in the profile directory
+func DefaultLockStore() *FileLockStore {
+	return NewFileLockStore(ProfilePath(TiOpsLockDir))
+}
+
+func (s *FileLockStore) path(cluster string) string {
+	return filepath.Join(s.dir, cluster+".lock")
+}
+
+// TryLock implements the LockStore interface
+func (s *FileLockStore) TryLock(cluster string, holder LockHolder) (LockHolder, bool, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return LockHolder{}, false, errors.AddStack(err)
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return LockHolder{}, false, errors.AddStack(err)
+	}
+
+	for retry := 0; ; retry++ {
+		f, err := os.OpenFile(s.path(cluster), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(s.path(cluster))
+				return LockHolder{}, false, errors.Annotatef(err, "failed to lock cluster %s", cluster)
+			}
+			return holder, true, nil
+		}
+		if !os.IsExist(err) {
+			return LockHolder{}, false, errors.Annotatef(err, "failed to lock cluster %s", cluster)
+		}
+
+		current, err := s.holder(cluster)
+		if err != nil {
+			return LockHolder{}, false, err
+		}
+		// take over the lock abandoned by an exited process once
+		if retry > 0 || !abandoned(current, holder.Host) {
+			return current, false, nil
+		}
+		if err := s.removeIf(cluster, current); err != nil {
+			return LockHolder{}, false, err
+		}
+	}
+}
+
+// guard locks the guard file of the cluster exclusively, the returned function releases it
+func (s *FileLockStore) guard(cluster string) (func(), error) {
+	f, err := os.OpenFile(s.path(cluster)+".guard", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, errors.AddStack(err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
+
+// removeIf removes the lock if it's still held by the holder, it's kept if it's replaced since
+// the holder was read
+func (s *FileLockStore) removeIf(cluster string, holder LockHolder) error {
+	release, err := s.guard(cluster)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	data, err := ioutil.ReadFile(s.path(cluster))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	var current LockHolder
+	if json.Unmarshal(data, &current) != nil || !current.Equal(holder) {
+		return nil
+	}
+	if err := os.Remove(s.path(cluster)); err != nil && !os.IsNotExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
+
+// holder reads the holder of the lock, the lock being written by another process right now
+// has an unknown holder
+func (s *FileLockStore) holder(cluster string) (LockHolder, error) {
+	var current LockHolder
+	data, err := ioutil.ReadFile(s.path(cluster))
+	if err != nil && !os.IsNotExist(err) {
+		return current, errors.AddStack(err)
+	}
+	if err != nil || json.Unmarshal(data, &current) != nil {
+		current = LockHolder{Operation: "unknown", User: "unknown", Host: "unknown"}
+	}
+	return current, nil
+}
+
+// Unlock implements the LockStore interface
+func (s *FileLockStore) Unlock(cluster string, holder LockHolder) error {
+	return s.removeIf(cluster, holder)
+}
+
+// ForceUnlock implements the LockStore interface
+func (s *FileLockStore) ForceUnlock(cluster string) error {
+	if err := os.Remove(s.path(cluster)); err != nil && !os.IsNotExist(err) {
+		return errors.AddStack(err)
+	}
+	return nil
+}
+
+// abandoned returns if the holder is a process on the host which has exited
+func abandoned(holder LockHolder, host string) bool {
+	if holder.Host != host || holder.PID <= 0 {
+		return false
+	}
+	return syscall.Kill(holder.PID, 0) == syscall.ESRCH
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/errors"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// etcdLockPrefix is the prefix of the keys of the locks in etcd
+const etcdLockPrefix = "/tiup-cluster/locks/"
+
+// EtcdLockStore stores the lock of a cluster as a key in etcd created in a transaction, so
+// that the operations from different control machines sharing the etcd exclude each other
+type EtcdLockStore struct {
+	client  *clientv3.Client
+	timeout time.Duration
+}
+
+// NewEtcdLockStore connects the etcd of the endpoints to store the locks
+func NewEtcdLockStore(endpoints []string, tlsConfig *tls.Config) (*EtcdLockStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: time.Second * 5,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return &EtcdLockStore{client: client, timeout: time.Second * 5}, nil
+}
+
+// Close closes the connection to etcd
+func (s *EtcdLockStore) Close() error {
+	return s.client.Close()
+}
+
+// TryLock implements the LockStore interface
+func (s *EtcdLockStore) TryLock(cluster string, holder LockHolder) (LockHolder, bool, error) {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return LockHolder{}, false, errors.AddStack(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	key := etcdLockPrefix + cluster
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(data))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return LockHolder{}, false, errors.Annotatef(err, "failed to lock cluster %s", cluster)
+	}
+	if resp.Succeeded {
+		return holder, true, nil
+	}
+
+	current := LockHolder{Operation: "unknown", User: "unknown", Host: "unknown"}
+	if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+		_ = json.Unmarshal(kvs[0].Value, &current)
+	}
+	return current, false, nil
+}
+
+// Unlock implements the LockStore interface
+func (s *EtcdLockStore) Unlock(cluster string, holder LockHolder) error {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	key := etcdLockPrefix + cluster
+	_, err = s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", string(data))).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	return errors.Annotatef(err, "failed to unlock cluster %s", cluster)
+}
+
+// ForceUnlock implements the LockStore interface
+func (s *EtcdLockStore) ForceUnlock(cluster string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	_, err := s.client.Delete(ctx, etcdLockPrefix+cluster)
+	return errors.Annotatef(err, "failed to unlock cluster %s", cluster)
+}
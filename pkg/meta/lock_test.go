@@ -0,0 +1,170 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestLockClusterConcurrently(c *C) {
+	store := NewFileLockStore(filepath.Join(c.MkDir(), "locks"))
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		locks []*ClusterLock
+		errs  []error
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := LockCluster(store, "test-cluster", "upgrade", false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			locks = append(locks, lock)
+		}()
+	}
+	wg.Wait()
+
+	c.Assert(locks, HasLen, 1)
+	c.Assert(errs, HasLen, 15)
+	for _, err := range errs {
+		c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+		c.Assert(err.Error(), Matches, ".*Cluster `test-cluster` is locked, operation already in progress: upgrade by .* since .*")
+	}
+
+	// the other clusters are not affected
+	other, err := LockCluster(store, "other-cluster", "start", false)
+	c.Assert(err, IsNil)
+	c.Assert(other.Unlock(), IsNil)
+
+	c.Assert(locks[0].Unlock(), IsNil)
+	lock, err := LockCluster(store, "test-cluster", "stop", false)
+	c.Assert(err, IsNil)
+	c.Assert(lock.Holder().Operation, Equals, "stop")
+	c.Assert(lock.Unlock(), IsNil)
+}
+
+func (s *metaSuite) TestLockClusterTakeOver(c *C) {
+	dir := c.MkDir()
+	store := NewFileLockStore(dir)
+
+	lock, err := LockCluster(store, "test-cluster", "scale-in", false)
+	c.Assert(err, IsNil)
+	_, err = LockCluster(store, "test-cluster", "destroy", false)
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+
+	// forced to take it over, and the old holder doesn't release the new one
+	forced, err := LockCluster(store, "test-cluster", "destroy", true)
+	c.Assert(err, IsNil)
+	c.Assert(lock.Unlock(), IsNil)
+	_, err = LockCluster(store, "test-cluster", "start", false)
+	c.Assert(errorx.IsOfType(err, ErrClusterLocked), IsTrue)
+	c.Assert(err.Error(), Matches, ".*in progress: destroy by .*")
+	c.Assert(forced.Unlock(), IsNil)
+
+	// the lock of an exited process on this host is abandoned
+	cmd := exec.Command("true")
+	c.Assert(cmd.Run(), IsNil)
+	holder := NewLockHolder("deploy")
+	holder.PID = cmd.Process.Pid
+	data, err := json.Marshal(holder)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "test-cluster.lock"), data, 0644), IsNil)
+	lock, err = LockCluster(store, "test-cluster", "start", false)
+	c.Assert(err, IsNil)
+	c.Assert(lock.Holder().PID, Equals, os.Getpid())
+	c.Assert(lock.Unlock(), IsNil)
+
+	// but not the one of another host
+	holder.Host = "another-host"
+	data, err = json.Marshal(holder)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "test-cluster.lock"), data, 0644), IsNil)
+	_, err = LockCluster(store, "test-cluster", "start", false)
+	c.Assert(err, ErrorMatches, ".*in progress: deploy by .*@another-host .*")
+
+	c.Assert(LockHolder{Operation: "upgrade", User: "tidb", Host: "h1", PID: 42, Since: time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC)}.String(),
+		Equals, "upgrade by tidb@h1 (pid 42) since 2020-05-01T08:00:00Z")
+}
+
+func (s *metaSuite) TestLockClusterTakeOverConcurrently(c *C) {
+	dir := c.MkDir()
+	cmd := exec.Command("true")
+	c.Assert(cmd.Run(), IsNil)
+	abandoned := NewLockHolder("deploy")
+	abandoned.PID = cmd.Process.Pid
+	data, err := json.Marshal(abandoned)
+	c.Assert(err, IsNil)
+
+	for round := 0; round < 20; round++ {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, "test-cluster.lock"), data, 0644), IsNil)
+
+		// all of them see the abandoned lock, only one takes it over
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			holders []LockHolder
+			start   = make(chan struct{})
+		)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				holder := NewLockHolder(fmt.Sprintf("op-%d", i))
+				_, ok, err := NewFileLockStore(dir).TryLock("test-cluster", holder)
+				c.Check(err, IsNil)
+				if ok {
+					mu.Lock()
+					holders = append(holders, holder)
+					mu.Unlock()
+				}
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		c.Assert(holders, HasLen, 1)
+		current, err := NewFileLockStore(dir).holder("test-cluster")
+		c.Assert(err, IsNil)
+		c.Assert(current.Equal(holders[0]), IsTrue)
+		c.Assert(NewFileLockStore(dir).Unlock("test-cluster", holders[0]), IsNil)
+	}
+
+	// the lock replaced since the abandoned holder was read is kept
+	store := NewFileLockStore(dir)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "test-cluster.lock"), data, 0644), IsNil)
+	lock, err := LockCluster(store, "test-cluster", "start", false)
+	c.Assert(err, IsNil)
+	c.Assert(store.removeIf("test-cluster", abandoned), IsNil)
+	current, err := store.holder("test-cluster")
+	c.Assert(err, IsNil)
+	c.Assert(current.Equal(lock.Holder()), IsTrue)
+	c.Assert(lock.Unlock(), IsNil)
+}
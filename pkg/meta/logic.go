@@ -30,6 +30,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/template/config"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/template/scripts"
 	system "github.com/pingcap-incubator/tiup-cluster/pkg/template/systemd"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/set"
 	"github.com/pingcap/errors"
 	"golang.org/x/mod/semver"
@@ -41,8 +42,10 @@ const (
 	ComponentTiKV             = "tikv"
 	ComponentPD               = "pd"
 	ComponentTiFlash          = "tiflash"
+	ComponentTiProxy          = "tiproxy"
 	ComponentGrafana          = "grafana"
 	ComponentDrainer          = "drainer"
+	ComponentCDC              = "cdc"
 	ComponentPump             = "pump"
 	ComponentAlertManager     = "alertmanager"
 	ComponentPrometheus       = "prometheus"
@@ -113,14 +116,24 @@ type instance struct {
 	statusFn  func(pdHosts ...string) string
 }
 
+// topology returns the specification which the instance belongs to
+func (i *instance) topology() *Specification {
+	return i.topo
+}
+
 // Ready implements Instance interface
 func (i *instance) Ready(e executor.TiOpsExecutor) error {
 	return PortStarted(e, i.port)
 }
 
-// WaitForDown implements Instance interface
+// WaitForDown implements Instance interface, it waits for the shutdown timeout of the component
 func (i *instance) WaitForDown(e executor.TiOpsExecutor) error {
-	return PortStopped(e, i.port)
+	c := module.WaitForConfig{
+		Port:    i.port,
+		State:   "stopped",
+		Timeout: i.topo.ShutdownTimeout(i.ComponentName()),
+	}
+	return module.NewWaitFor(c).Execute(e)
 }
 
 func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths DirPaths) error {
@@ -134,7 +147,8 @@ func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths
 		WithMemoryLimit(resource.MemoryLimit).
 		WithCPUQuota(resource.CPUQuota).
 		WithIOReadBandwidthMax(resource.IOReadBandwidthMax).
-		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax)
+		WithIOWriteBandwidthMax(resource.IOWriteBandwidthMax).
+		WithTimeoutStopSec(int(i.topo.ShutdownTimeout(comp).Seconds()))
 
 	// For not auto start if using binlogctl to offline.
 	// bad design
@@ -142,7 +156,11 @@ func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths
 		systemCfg.Restart = "on-failure"
 	}
 
-	if err := systemCfg.ConfigToFile(sysCfg); err != nil {
+	if tplFile := i.topo.SystemdTemplate(comp); tplFile != "" {
+		if err := systemCfg.ConfigToFileWithTemplate(tplFile, sysCfg); err != nil {
+			return errors.Annotatef(err, "failed to render the systemd_template of %s", comp)
+		}
+	} else if err := systemCfg.ConfigToFile(sysCfg); err != nil {
 		return err
 	}
 	tgt := filepath.Join("/tmp", comp+"_"+uuid.New().String()+".service")
@@ -160,6 +178,11 @@ func (i *instance) InitConfig(e executor.TiOpsExecutor, _, _, user string, paths
 // mergeServerConfig merges the server configuration and overwrite the global configuration
 func (i *instance) mergeServerConfig(e executor.TiOpsExecutor, globalConf, instanceConf map[string]interface{}, paths DirPaths) error {
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("%s-%s-%d.toml", i.ComponentName(), i.GetHost(), i.GetPort()))
+	// the raw config fragment of the instance takes precedence over the modeled configs
+	instanceConf, err := mergeFragment(instanceConf, configFragment(i.InstanceSpec))
+	if err != nil {
+		return errors.Annotatef(err, "invalid config_fragment of %s", i.ID())
+	}
 	conf, err := merge2Toml(i.ComponentName(), globalConf, instanceConf)
 	if err != nil {
 		return err
@@ -196,7 +219,7 @@ func (i *instance) ScaleConfig(e executor.TiOpsExecutor, _ *Specification, clust
 
 // ID returns the identifier of this instance, the ID is constructed by host:port
 func (i *instance) ID() string {
-	return fmt.Sprintf("%s:%d", i.host, i.port)
+	return utils.JoinHostPort(i.host, i.port)
 }
 
 // ComponentName implements Instance interface
@@ -590,7 +613,7 @@ func (i *PDInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clusterVe
 			spec.Config = map[string]interface{}{}
 		}
 		prom := i.topo.Monitors[0]
-		spec.Config["pd-server.metric-storage"] = fmt.Sprintf("http://%s:%d", prom.Host, prom.Port)
+		spec.Config["pd-server.metric-storage"] = "http://" + utils.JoinHostPort(prom.Host, prom.Port)
 	}
 
 	specConfig := spec.Config
@@ -805,18 +828,6 @@ func (i *TiFlashInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 
 	spec := i.InstanceSpec.(TiFlashSpec)
 
-	tidbStatusAddrs := []string{}
-	for _, tidb := range i.topo.TiDBServers {
-		tidbStatusAddrs = append(tidbStatusAddrs, fmt.Sprintf("%s:%d", tidb.Host, uint64(tidb.StatusPort)))
-	}
-	tidbStatusStr := strings.Join(tidbStatusAddrs, ",")
-
-	var pdAddrs []string
-	for _, pd := range i.topo.PDServers {
-		pdAddrs = append(pdAddrs, fmt.Sprintf("%s:%d", pd.Host, uint64(pd.ClientPort)))
-	}
-	pdStr := strings.Join(pdAddrs, ",")
-
 	// replication.enable-placement-rules should be set to true to enable TiFlash
 	// TODO: Move this logic to an independent checkConfig procedure
 	const key = "replication.enable-placement-rules"
@@ -829,16 +840,7 @@ func (i *TiFlashInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 		}
 	}
 
-	cfg := scripts.NewTiFlashScript(
-		i.GetHost(),
-		paths.Deploy,
-		paths.Data,
-		paths.Log,
-		tidbStatusStr,
-		pdStr,
-	).WithTCPPort(spec.TCPPort).WithHTTPPort(spec.HTTPPort).WithFlashServicePort(spec.FlashServicePort).
-		WithFlashProxyPort(spec.FlashProxyPort).WithFlashProxyStatusPort(spec.FlashProxyStatusPort).
-		WithStatusPort(spec.StatusPort).WithTmpDir(spec.TmpDir).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
+	cfg := i.script(paths).AppendEndpoints(i.instance.topo.Endpoints(deployUser)...)
 
 	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tiflash_%s_%d.sh", i.GetHost(), i.GetPort()))
 	if err := cfg.ConfigToFile(fp); err != nil {
@@ -919,6 +921,60 @@ func (i *TiFlashInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 	return i.mergeServerConfig(e, conf, specConfig, paths)
 }
 
+// script returns the run script of the instance with the paths
+func (i *TiFlashInstance) script(paths DirPaths) *scripts.TiFlashScript {
+	spec := i.InstanceSpec.(TiFlashSpec)
+
+	tidbStatusAddrs := []string{}
+	for _, tidb := range i.topo.TiDBServers {
+		tidbStatusAddrs = append(tidbStatusAddrs, utils.JoinHostPort(tidb.Host, tidb.StatusPort))
+	}
+	tidbStatusStr := strings.Join(tidbStatusAddrs, ",")
+
+	var pdAddrs []string
+	for _, pd := range i.topo.PDServers {
+		pdAddrs = append(pdAddrs, utils.JoinHostPort(pd.Host, pd.ClientPort))
+	}
+	pdStr := strings.Join(pdAddrs, ",")
+
+	return scripts.NewTiFlashScript(
+		i.GetHost(),
+		paths.Deploy,
+		paths.Data,
+		paths.Log,
+		tidbStatusStr,
+		pdStr,
+	).WithTCPPort(spec.TCPPort).WithHTTPPort(spec.HTTPPort).WithFlashServicePort(spec.FlashServicePort).
+		WithFlashProxyPort(spec.FlashProxyPort).WithFlashProxyStatusPort(spec.FlashProxyStatusPort).
+		WithStatusPort(spec.StatusPort).WithTmpDir(spec.TmpDir).WithNumaNode(spec.NumaNode)
+}
+
+// RenderConfigs returns the configs of TiFlash and its proxy rendered by InitConfig with the
+// paths, the configs of the imported instance are not included
+func (i *TiFlashInstance) RenderConfigs(paths DirPaths) (flash, learner map[string]interface{}, err error) {
+	spec := i.InstanceSpec.(TiFlashSpec)
+	cfg := i.script(paths)
+
+	if learner, err = i.InitTiFlashLearnerConfig(cfg, i.topo.ServerConfigs.TiFlashLearner); err != nil {
+		return nil, nil, err
+	}
+	if learner, err = merge(learner, spec.LearnerConfig); err != nil {
+		return nil, nil, err
+	}
+
+	if flash, err = i.InitTiFlashConfig(cfg, i.topo.ServerConfigs.TiFlash); err != nil {
+		return nil, nil, err
+	}
+	instanceConf, err := mergeFragment(spec.Config, configFragment(spec))
+	if err != nil {
+		return nil, nil, errors.Annotatef(err, "invalid config_fragment of %s", i.ID())
+	}
+	if flash, err = merge(flash, instanceConf); err != nil {
+		return nil, nil, err
+	}
+	return flash, learner, nil
+}
+
 // ScaleConfig deploy temporary config on scaling
 func (i *TiFlashInstance) ScaleConfig(e executor.TiOpsExecutor, b *Specification, clusterName, clusterVersion, deployUser string, paths DirPaths) error {
 	s := i.instance.topo
@@ -1000,6 +1056,21 @@ func (i *MonitorInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 
 	// transfer config
 	fp = filepath.Join(paths.Cache, fmt.Sprintf("tikv_%s.yml", i.GetHost()))
+	cfig := i.PrometheusConfig(clusterName)
+	if err := cfig.ConfigToFile(fp); err != nil {
+		return err
+	}
+	dst = filepath.Join(paths.Deploy, "conf", "prometheus.yml")
+	if err := e.Transfer(fp, dst, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PrometheusConfig returns the config of Prometheus whose scrape targets are the instances of
+// the current topology
+func (i *MonitorInstance) PrometheusConfig(clusterName string) *config.PrometheusConfig {
 	cfig := config.NewPrometheusConfig(clusterName)
 	cfig.AddBlackbox(i.GetHost(), uint64(i.topo.MonitoredOptions.BlackboxExporterPort))
 	uniqueHosts := set.NewStringSet()
@@ -1015,6 +1086,10 @@ func (i *MonitorInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 		uniqueHosts.Insert(db.Host)
 		cfig.AddTiDB(db.Host, uint64(db.StatusPort))
 	}
+	for _, proxy := range i.topo.TiProxyServers {
+		uniqueHosts.Insert(proxy.Host)
+		cfig.AddTiProxy(proxy.Host, uint64(proxy.StatusPort))
+	}
 	for _, flash := range i.topo.TiFlashServers {
 		uniqueHosts.Insert(flash.Host)
 		cfig.AddTiFlashLearner(flash.Host, uint64(flash.FlashProxyStatusPort))
@@ -1028,6 +1103,10 @@ func (i *MonitorInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 		uniqueHosts.Insert(drainer.Host)
 		cfig.AddDrainer(drainer.Host, uint64(drainer.Port))
 	}
+	for _, cdc := range i.topo.CDCServers {
+		uniqueHosts.Insert(cdc.Host)
+		cfig.AddCDC(cdc.Host, uint64(cdc.Port))
+	}
 	for _, grafana := range i.topo.Grafana {
 		uniqueHosts.Insert(grafana.Host)
 		cfig.AddGrafana(grafana.Host, uint64(grafana.Port))
@@ -1038,15 +1117,7 @@ func (i *MonitorInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clus
 		cfig.AddMonitoredServer(host)
 	}
 
-	if err := cfig.ConfigToFile(fp); err != nil {
-		return err
-	}
-	dst = filepath.Join(paths.Deploy, "conf", "prometheus.yml")
-	if err := e.Transfer(fp, dst, false); err != nil {
-		return err
-	}
-
-	return nil
+	return cfig
 }
 
 // GrafanaComponent represents Grafana component.
@@ -1245,18 +1316,37 @@ func (topo *Specification) ComponentsByStopOrder() (comps []Component) {
 	return
 }
 
-// ComponentsByStartOrder return component in the order need to start.
+// ComponentsByStartOrder return component in the order need to start, which is sorted
+// by ComponentDependencies.
 func (topo *Specification) ComponentsByStartOrder() (comps []Component) {
-	// "pd", "tikv", "pump", "tidb", "drainer", "prometheus", "grafana", "alertmanager"
-	comps = append(comps, &PDComponent{topo})
-	comps = append(comps, &TiKVComponent{topo})
-	comps = append(comps, &PumpComponent{topo})
-	comps = append(comps, &TiDBComponent{topo})
-	comps = append(comps, &TiFlashComponent{topo})
-	comps = append(comps, &DrainerComponent{topo})
-	comps = append(comps, &MonitorComponent{topo})
-	comps = append(comps, &GrafanaComponent{topo})
-	comps = append(comps, &AlertManagerComponent{topo})
+	// "pd", "tikv", "pump", "tidb", "tiproxy", "tiflash", "drainer", "cdc", "prometheus", "grafana", "alertmanager"
+	all := []Component{
+		&PDComponent{topo},
+		&TiKVComponent{topo},
+		&PumpComponent{topo},
+		&TiDBComponent{topo},
+		&TiProxyComponent{topo},
+		&TiFlashComponent{topo},
+		&DrainerComponent{topo},
+		&CDCComponent{topo},
+		&MonitorComponent{topo},
+		&GrafanaComponent{topo},
+		&AlertManagerComponent{topo},
+	}
+
+	byName := make(map[string]Component)
+	var names []string
+	for _, comp := range all {
+		byName[comp.Name()] = comp
+		names = append(names, comp.Name())
+	}
+	sorted, err := DependencyOrder(names, ComponentDependencies)
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range sorted {
+		comps = append(comps, byName[name])
+	}
 	return
 }
 
@@ -1299,7 +1389,7 @@ func (topo *Specification) Endpoints(user string) []*scripts.PDScript {
 		// data dir would be empty for components which don't need it
 		dataDir := spec.DataDir
 		if dataDir != "" {
-			clusterutil.Abs(user, dataDir)
+			dataDir = clusterutil.Abs(user, dataDir)
 		}
 		// log dir will always be with values, but might not used by the component
 		logDir := clusterutil.Abs(user, spec.LogDir)
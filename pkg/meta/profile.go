@@ -28,6 +28,7 @@ const (
 	TiOpsPackageCacheDir = "packages"
 	TiOpsClusterDir      = "clusters"
 	TiOpsAuditDir        = "audit"
+	TiOpsLockDir         = "locks"
 )
 
 var profileDir string
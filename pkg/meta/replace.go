@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// ReplaceInstance returns the topology which only contains the successor of the instance
+// with the node id. The successor is on the new host, and reuses the ports, directories,
+// labels and configuration of the replaced instance. The SSH port is inherited if sshPort
+// is zero. The default name of a PD instance is derived from the new host, because the
+// names of the PD members must be unique while the old one is still in the cluster.
+func (topo *TopologySpecification) ReplaceInstance(id, host string, sshPort int) (*TopologySpecification, error) {
+	newPart := &TopologySpecification{}
+	topoSpec := reflect.ValueOf(topo).Elem()
+	newSpec := reflect.ValueOf(newPart).Elem()
+
+	for i := 0; i < topoSpec.NumField(); i++ {
+		if isSkipField(topoSpec.Field(i)) {
+			continue
+		}
+
+		compSpecs := topoSpec.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			compSpec := compSpecs.Index(index)
+			spec := compSpec.Interface().(InstanceSpec)
+			oldHost := compSpec.FieldByName("Host").String()
+			if utils.JoinHostPort(oldHost, spec.GetMainPort()) != id {
+				continue
+			}
+			if oldHost == host {
+				return nil, errors.Errorf("node '%s' is already on host %s", id, host)
+			}
+			if f := compSpec.FieldByName("Offline"); f.IsValid() && f.Bool() {
+				return nil, errors.Errorf("node '%s' is offline and can't be replaced", id)
+			}
+
+			successor := reflect.New(compSpec.Type()).Elem()
+			successor.Set(compSpec)
+			successor.FieldByName("Host").SetString(host)
+			if sshPort > 0 {
+				successor.FieldByName("SSHPort").SetInt(int64(sshPort))
+			}
+			// the new host is provisioned freshly even if the old one is imported
+			if imported := successor.FieldByName("Imported"); imported.IsValid() {
+				imported.SetBool(false)
+			}
+			if name := successor.FieldByName("Name"); name.IsValid() {
+				clientPort := successor.FieldByName("ClientPort").Int()
+				if name.String() != "" && name.String() != fmt.Sprintf("pd-%s-%d", oldHost, clientPort) {
+					return nil, errors.Errorf("the name '%s' of node '%s' can't be reused by another PD member, please replace it by scale-out and scale-in", name.String(), id)
+				}
+				name.SetString(fmt.Sprintf("pd-%s-%d", host, clientPort))
+			}
+
+			newSpec.Field(i).Set(reflect.Append(newSpec.Field(i), successor))
+			return newPart, nil
+		}
+	}
+
+	return nil, errors.Errorf("cannot find node id '%s' in topology", id)
+}
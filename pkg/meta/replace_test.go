@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+func (s *metaSuite) TestReplaceInstance(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+global:
+  ssh_port: 220
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+    name: pd-custom
+tikv_servers:
+  - host: 172.16.5.140
+    port: 20160
+    data_dir: /data/tikv
+    config:
+      server.labels: { zone: z1, host: h1 }
+  - host: 172.16.5.141
+    offline: true
+`), &topo)
+	c.Assert(err, IsNil)
+
+	newPart, err := topo.ReplaceInstance("172.16.5.140:20160", "172.16.5.150", 0)
+	c.Assert(err, IsNil)
+	c.Assert(newPart.PDServers, HasLen, 0)
+	c.Assert(newPart.TiKVServers, HasLen, 1)
+	tikv := newPart.TiKVServers[0]
+	c.Assert(tikv.Host, Equals, "172.16.5.150")
+	c.Assert(tikv.SSHPort, Equals, 220)
+	c.Assert(tikv.Port, Equals, 20160)
+	c.Assert(tikv.DataDir, Equals, "/data/tikv")
+	c.Assert(tikv.Config["server.labels"], DeepEquals, topo.TiKVServers[0].Config["server.labels"])
+	// the replaced one is untouched
+	c.Assert(topo.TiKVServers[0].Host, Equals, "172.16.5.140")
+
+	newPart, err = topo.ReplaceInstance("172.16.5.140:2379", "172.16.5.150", 22)
+	c.Assert(err, IsNil)
+	c.Assert(newPart.PDServers[0].Name, Equals, "pd-172.16.5.150-2379")
+	c.Assert(newPart.PDServers[0].SSHPort, Equals, 22)
+
+	_, err = topo.ReplaceInstance("172.16.5.141:2379", "172.16.5.150", 0)
+	c.Assert(err, ErrorMatches, ".*the name 'pd-custom' of node '172.16.5.141:2379' can't be reused.*")
+	_, err = topo.ReplaceInstance("172.16.5.141:20160", "172.16.5.150", 0)
+	c.Assert(err, ErrorMatches, ".*node '172.16.5.141:20160' is offline.*")
+	_, err = topo.ReplaceInstance("172.16.5.140:20160", "172.16.5.140", 0)
+	c.Assert(err, ErrorMatches, ".*already on host 172.16.5.140.*")
+	_, err = topo.ReplaceInstance("172.16.5.142:20160", "172.16.5.150", 0)
+	c.Assert(err, ErrorMatches, ".*cannot find node id '172.16.5.142:20160'.*")
+}
@@ -140,3 +140,51 @@ func mergeImported(importConfig []byte, specConfig map[string]interface{}) (map[
 	}
 	return lhs, nil
 }
+
+// configFragment returns the raw TOML config fragment of the instance specification, it's
+// empty if the component doesn't support the fragment
+func configFragment(spec InstanceSpec) string {
+	v := reflect.Indirect(reflect.ValueOf(spec))
+	if f := v.FieldByName("ConfigFragment"); f.IsValid() {
+		return f.String()
+	}
+	return ""
+}
+
+// mergeFragment merges the raw TOML config fragment to the config, the items in the
+// fragment overwrite the ones in the config
+func mergeFragment(config map[string]interface{}, fragment string) (map[string]interface{}, error) {
+	if strings.TrimSpace(fragment) == "" {
+		return config, nil
+	}
+	var fragmentData map[string]interface{}
+	if _, err := toml.Decode(fragment, &fragmentData); err != nil {
+		return config, err
+	}
+	return merge(config, fragmentData)
+}
+
+// configFragmentsValidate checks whether the config fragments of all instances can be parsed
+func (topo *TopologySpecification) configFragmentsValidate() error {
+	topoSpec := reflect.ValueOf(topo).Elem()
+	topoType := reflect.TypeOf(topo).Elem()
+
+	for i := 0; i < topoSpec.NumField(); i++ {
+		if isSkipField(topoSpec.Field(i)) {
+			continue
+		}
+
+		compSpecs := topoSpec.Field(i)
+		for index := 0; index < compSpecs.Len(); index++ {
+			spec := compSpecs.Index(index).Interface().(InstanceSpec)
+			var fragmentData map[string]interface{}
+			if _, err := toml.Decode(configFragment(spec), &fragmentData); err != nil {
+				host, _ := spec.SSH()
+				return errors.Errorf("invalid config_fragment of `%s` instance '%s:%d': %s",
+					topoType.Field(i).Tag.Get("yaml"), host, spec.GetMainPort(), err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package meta
 import (
 	"bytes"
 
+	"github.com/BurntSushi/toml"
 	goyaml "github.com/goccy/go-yaml"
 	"github.com/pingcap/check"
 )
@@ -35,3 +36,65 @@ server_configs:
 	decimal = bytes.Contains(get, []byte("0.0"))
 	c.Assert(decimal, check.IsTrue)
 }
+
+func (s *configSuite) TestMergeFragment(c *check.C) {
+	topo := new(TopologySpecification)
+	err := goyaml.Unmarshal([]byte(`
+server_configs:
+  tikv:
+    readpool.storage.use-unified-pool: false
+    storage.reserve-space: 2GB
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      storage.reserve-space: 1GB
+      raftstore.capacity: 100GB
+    config_fragment: |
+      [raftstore]
+      capacity = "200GB"
+      hibernate-regions = true
+`), topo)
+	c.Assert(err, check.IsNil)
+	c.Assert(topo.Validate(), check.IsNil)
+
+	spec := topo.TiKVServers[0]
+	instanceConf, err := mergeFragment(spec.Config, configFragment(spec))
+	c.Assert(err, check.IsNil)
+	got, err := merge2Toml("tikv", topo.ServerConfigs.TiKV, instanceConf)
+	c.Assert(err, check.IsNil)
+
+	// server_configs < config < config_fragment
+	var conf map[string]interface{}
+	c.Assert(toml.Unmarshal(got, &conf), check.IsNil)
+	c.Assert(conf["readpool"], check.DeepEquals, map[string]interface{}{
+		"storage": map[string]interface{}{"use-unified-pool": false},
+	})
+	c.Assert(conf["storage"], check.DeepEquals, map[string]interface{}{"reserve-space": "1GB"})
+	c.Assert(conf["raftstore"], check.DeepEquals, map[string]interface{}{
+		"capacity":          "200GB",
+		"hibernate-regions": true,
+	})
+
+	// the config is kept without fragment
+	same, err := mergeFragment(spec.Config, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(same, check.DeepEquals, spec.Config)
+}
+
+func (s *configSuite) TestInvalidFragment(c *check.C) {
+	topo := new(TopologySpecification)
+	err := goyaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.140
+pd_servers:
+  - host: 172.16.5.141
+    config_fragment: |
+      [schedule]
+      leader-schedule-limit = 4
+      max-merge-region-size = 
+`), topo)
+	c.Assert(err, check.ErrorMatches, "invalid config_fragment of `pd_servers` instance '172.16.5.141:2379': Near line 3.*")
+
+	_, err = mergeFragment(nil, "[server\nfoo = 1")
+	c.Assert(err, check.NotNil)
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// defaultShutdownTimeouts are the seconds to wait for the instances of the components to exit
+// before they're killed, the ones flushing more data on shutdown are given longer
+var defaultShutdownTimeouts = map[string]int{
+	ComponentTiFlash:      600,
+	ComponentTiKV:         300,
+	ComponentPD:           120,
+	ComponentPump:         120,
+	ComponentDrainer:      120,
+	ComponentCDC:          120,
+	ComponentTiDB:         60,
+	ComponentTiProxy:      60,
+	ComponentGrafana:      30,
+	ComponentAlertManager: 30,
+	ComponentPrometheus:   60,
+}
+
+// defaultShutdownTimeout is used by the components without a default timeout
+const defaultShutdownTimeout = 60
+
+// ShutdownTimeout returns how long to wait for an instance of the component to exit, the
+// shutdown_timeout of the global options takes precedence over the default of the component
+func (topo *Specification) ShutdownTimeout(component string) time.Duration {
+	if topo != nil {
+		if sec, ok := topo.GlobalOptions.ShutdownTimeout[component]; ok {
+			return time.Duration(sec) * time.Second
+		}
+	}
+	if sec, ok := defaultShutdownTimeouts[component]; ok {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultShutdownTimeout * time.Second
+}
+
+// shutdownTimeoutsValidate checks the shutdown timeouts are set for the known components
+// and they are positive
+func (topo *Specification) shutdownTimeoutsValidate() error {
+	known := map[string]bool{}
+	for _, comp := range topo.ComponentsByStartOrder() {
+		known[comp.Name()] = true
+	}
+	var comps []string
+	for comp := range topo.GlobalOptions.ShutdownTimeout {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+	for _, comp := range comps {
+		if !known[comp] {
+			return errors.Errorf("invalid shutdown_timeout of unknown component '%s'", comp)
+		}
+		if sec := topo.GlobalOptions.ShutdownTimeout[comp]; sec <= 0 {
+			return errors.Errorf("invalid shutdown_timeout of component '%s': %d, it must be positive", comp, sec)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// listeningExecutor reports the ports are always listened
+type listeningExecutor struct {
+	ports []int
+}
+
+func (e *listeningExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	var lines []string
+	for _, port := range e.ports {
+		lines = append(lines, fmt.Sprintf("LISTEN 0 128 *:%d *:*", port))
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil, nil
+}
+
+func (e *listeningExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (s *metaSuite) TestShutdownTimeout(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+global:
+  shutdown_timeout:
+    tidb: 1
+    tiflash: 900
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	c.Assert(topo.ShutdownTimeout(ComponentTiDB), Equals, time.Second)
+	c.Assert(topo.ShutdownTimeout(ComponentTiFlash), Equals, 900*time.Second)
+	c.Assert(topo.ShutdownTimeout(ComponentTiKV), Equals, 300*time.Second)
+	c.Assert(topo.ShutdownTimeout(ComponentPD), Equals, 120*time.Second)
+	c.Assert(topo.ShutdownTimeout(ComponentNodeExporter), Equals, 60*time.Second)
+	c.Assert((*Specification)(nil).ShutdownTimeout(ComponentTiFlash), Equals, 600*time.Second)
+
+	err := yaml.Unmarshal([]byte(`
+global:
+  shutdown_timeout:
+    tidb: 0
+`), &TopologySpecification{})
+	c.Assert(err, ErrorMatches, ".*invalid shutdown_timeout of component 'tidb': 0, it must be positive.*")
+	err = yaml.Unmarshal([]byte(`
+global:
+  shutdown_timeout:
+    tidb-server: 30
+`), &TopologySpecification{})
+	c.Assert(err, ErrorMatches, ".*invalid shutdown_timeout of unknown component 'tidb-server'.*")
+}
+
+func (s *metaSuite) TestShutdownTimeoutWait(c *C) {
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+global:
+  shutdown_timeout:
+    tidb: 1
+    tikv: 2
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	e := &listeningExecutor{ports: []int{20160, 4000}}
+
+	// the wait for each component is governed by the timeout of it
+	for _, comp := range []Component{&TiDBComponent{&topo}, &TiKVComponent{&topo}} {
+		inst := comp.Instances()[0]
+		start := time.Now()
+		err := inst.WaitForDown(e)
+		timeout := topo.ShutdownTimeout(comp.Name())
+		c.Assert(err, ErrorMatches, fmt.Sprintf("timed out waiting for port %d to be stopped after %s", inst.GetPort(), timeout))
+		c.Assert(time.Since(start) >= timeout, IsTrue)
+	}
+
+	// the stopped instance is not waited
+	c.Assert((&TiDBComponent{&topo}).Instances()[0].WaitForDown(&listeningExecutor{}), IsNil)
+}
+
+func (s *metaSuite) TestShutdownTimeoutUnit(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	cache, err := ioutil.TempDir("", "shutdown")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+global:
+  shutdown_timeout:
+    tidb: 30
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	units := map[string]string{}
+	for _, comp := range []Component{&TiDBComponent{&topo}, &PDComponent{&topo}} {
+		inst := comp.Instances()[0]
+		e := &recordExecutor{transfers: map[string]string{}}
+		paths := DirPaths{Deploy: "/home/tidb/deploy/" + comp.Name(), Cache: cache}
+		c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+		for dst, src := range e.transfers {
+			if strings.HasSuffix(dst, ".service") {
+				data, err := ioutil.ReadFile(src)
+				c.Assert(err, IsNil)
+				units[comp.Name()] = string(data)
+			}
+		}
+	}
+	// systemd kills the instances after the timeout
+	c.Assert(units[ComponentTiDB], Matches, "(?s).*\nTimeoutStopSec=30s\n.*")
+	c.Assert(units[ComponentPD], Matches, "(?s).*\nTimeoutStopSec=120s\n.*")
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"sort"
+
+	system "github.com/pingcap-incubator/tiup-cluster/pkg/template/systemd"
+	"github.com/pingcap/errors"
+)
+
+// SystemdTemplate returns the unit template file overriding the built-in one of the
+// component, it's empty if the built-in one is used
+func (topo *Specification) SystemdTemplate(component string) string {
+	if topo == nil {
+		return ""
+	}
+	return topo.GlobalOptions.SystemdTemplate[component]
+}
+
+// systemdTemplatesValidate checks the unit templates are set for the known components and
+// they render with the variables of the built-in template
+func (topo *Specification) systemdTemplatesValidate() error {
+	known := map[string]bool{}
+	for _, comp := range topo.ComponentsByStartOrder() {
+		known[comp.Name()] = true
+	}
+	var comps []string
+	for comp := range topo.GlobalOptions.SystemdTemplate {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+	for _, comp := range comps {
+		if !known[comp] {
+			return errors.Errorf("invalid systemd_template of unknown component '%s'", comp)
+		}
+		cfg := system.NewConfig(comp, topo.GlobalOptions.User, topo.GlobalOptions.DeployDir).
+			WithMemoryLimit(topo.GlobalOptions.ResourceControl.MemoryLimit).
+			WithCPUQuota(topo.GlobalOptions.ResourceControl.CPUQuota).
+			WithIOReadBandwidthMax(topo.GlobalOptions.ResourceControl.IOReadBandwidthMax).
+			WithIOWriteBandwidthMax(topo.GlobalOptions.ResourceControl.IOWriteBandwidthMax).
+			WithTimeoutStopSec(int(topo.ShutdownTimeout(comp).Seconds()))
+		if _, err := cfg.ConfigWithTemplateFile(topo.GlobalOptions.SystemdTemplate[comp]); err != nil {
+			return errors.Errorf("invalid systemd_template of component '%s': %s", comp, err)
+		}
+	}
+	return nil
+}
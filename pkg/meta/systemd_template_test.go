@@ -0,0 +1,138 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+const customUnitTemplate = `[Unit]
+Description={{.ServiceName}} service
+Wants=network-online.target
+
+[Service]
+Slice=tidb.slice
+OOMScoreAdjust=-1000
+User={{.User}}
+ExecStart={{.DeployDir}}/scripts/run_{{.ServiceName}}.sh
+{{- if .TimeoutStopSec}}
+TimeoutStopSec={{.TimeoutStopSec}}s
+{{- end}}
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// writeUnitTemplate writes the template to a file of the directory and returns the path
+func writeUnitTemplate(c *C, dir, name, tpl string) string {
+	fp := filepath.Join(dir, name)
+	c.Assert(ioutil.WriteFile(fp, []byte(tpl), 0644), IsNil)
+	return fp
+}
+
+func (s *metaSuite) TestSystemdTemplate(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	dir, err := ioutil.TempDir("", "systemd-template")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	fp := writeUnitTemplate(c, dir, "tidb.service.tpl", customUnitTemplate)
+
+	topo := TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(fmt.Sprintf(`
+global:
+  systemd_template:
+    tidb: %s
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+`, fp)), &topo), IsNil)
+	c.Assert(topo.SystemdTemplate(ComponentTiDB), Equals, fp)
+	c.Assert(topo.SystemdTemplate(ComponentPD), Equals, "")
+
+	units := map[string]string{}
+	for _, comp := range []Component{&TiDBComponent{&topo}, &PDComponent{&topo}} {
+		inst := comp.Instances()[0]
+		e := &recordExecutor{transfers: map[string]string{}}
+		paths := DirPaths{Deploy: "/home/tidb/deploy/" + comp.Name(), Cache: dir}
+		c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+		for dst, src := range e.transfers {
+			if strings.HasSuffix(dst, ".service") {
+				data, err := ioutil.ReadFile(src)
+				c.Assert(err, IsNil)
+				units[comp.Name()] = string(data)
+			}
+		}
+	}
+
+	// the custom template is rendered with the variables of the instance
+	c.Assert(units[ComponentTiDB], Equals, `[Unit]
+Description=tidb service
+Wants=network-online.target
+
+[Service]
+Slice=tidb.slice
+OOMScoreAdjust=-1000
+User=tidb
+ExecStart=/home/tidb/deploy/tidb/scripts/run_tidb.sh
+TimeoutStopSec=60s
+
+[Install]
+WantedBy=multi-user.target
+`)
+	// the others use the built-in one
+	c.Assert(units[ComponentPD], Matches, "(?s).*\nRestart=always\n.*")
+	c.Assert(units[ComponentPD], Not(Matches), "(?s).*OOMScoreAdjust.*")
+}
+
+func (s *metaSuite) TestSystemdTemplateInvalid(c *C) {
+	dir, err := ioutil.TempDir("", "systemd-template")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	unmarshal := func(comp, fp string) error {
+		return yaml.Unmarshal([]byte(fmt.Sprintf(`
+global:
+  systemd_template:
+    %s: %s
+tikv_servers:
+  - host: 172.16.5.140
+`, comp, fp)), &TopologySpecification{})
+	}
+
+	fp := writeUnitTemplate(c, dir, "syntax.tpl", "User={{.User\n")
+	c.Assert(unmarshal("tikv", fp), ErrorMatches, ".*invalid systemd_template of component 'tikv': template: .*unclosed action.*")
+
+	fp = writeUnitTemplate(c, dir, "variable.tpl", "Slice={{.Slice}}\n")
+	c.Assert(unmarshal("tikv", fp), ErrorMatches, ".*invalid systemd_template of component 'tikv': .*can't evaluate field Slice.*")
+
+	fp = filepath.Join(dir, "missing.tpl")
+	c.Assert(unmarshal("tikv", fp), ErrorMatches, ".*invalid systemd_template of component 'tikv': open .*missing.tpl: no such file or directory.*")
+
+	fp = writeUnitTemplate(c, dir, "valid.tpl", customUnitTemplate)
+	c.Assert(unmarshal("tikv", fp), IsNil)
+	c.Assert(unmarshal("tikv-server", fp), ErrorMatches, ".*invalid systemd_template of unknown component 'tikv-server'.*")
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// CheckTiFlashConfigs cross-checks the rendered configs of TiFlash and its proxy, which are
+// coupled by the addresses, ports, paths and labels, and returns the mismatches. The proxy
+// config is expected to be deployed to the conf directory of the deploy dir.
+func CheckTiFlashConfigs(flash, learner map[string]interface{}, deployDir string) []string {
+	var problems []string
+
+	serviceAddr := configString(flash, "flash.service_addr")
+	if engineAddr := configString(learner, "server.engine-addr"); serviceAddr != engineAddr {
+		problems = append(problems, fmt.Sprintf("flash.service_addr `%s` is not the server.engine-addr `%s` of the proxy", serviceAddr, engineAddr))
+	}
+	if proxyConfig, expected := configString(flash, "flash.proxy.config"), filepath.Join(deployDir, "conf", "tiflash-learner.toml"); proxyConfig != expected {
+		problems = append(problems, fmt.Sprintf("flash.proxy.config `%s` is not the config of the proxy `%s`", proxyConfig, expected))
+	}
+
+	// all the ports must be distinct
+	ports := []struct{ key, port string }{
+		{"tcp_port", configString(flash, "tcp_port")},
+		{"http_port", configString(flash, "http_port")},
+		{"status.metrics_port", configString(flash, "status.metrics_port")},
+		{"flash.service_addr", addrPort(serviceAddr)},
+		{"server.addr of the proxy", addrPort(configString(learner, "server.addr"))},
+		{"server.status-addr of the proxy", addrPort(configString(learner, "server.status-addr"))},
+	}
+	for i, a := range ports {
+		for _, b := range ports[i+1:] {
+			if a.port != "" && a.port == b.port {
+				problems = append(problems, fmt.Sprintf("%s and %s use the same port %s", a.key, b.key, a.port))
+			}
+		}
+	}
+	if port, advertised := addrPort(configString(learner, "server.addr")), addrPort(configString(learner, "server.advertise-addr")); advertised != "" && port != advertised {
+		problems = append(problems, fmt.Sprintf("server.advertise-addr port %s of the proxy is not its server.addr port %s", advertised, port))
+	}
+
+	// the proxy stores the raft data in the data dir of TiFlash
+	dataDir := configString(learner, "storage.data-dir")
+	under := false
+	for _, dir := range strings.Split(configString(flash, "path"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" && isSubDir(dir, dataDir) {
+			under = true
+		}
+	}
+	if !under {
+		problems = append(problems, fmt.Sprintf("storage.data-dir `%s` of the proxy is not under the path `%s`", dataDir, configString(flash, "path")))
+	}
+
+	if engine := configString(learner, "server.labels.engine"); engine != "" && engine != "tiflash" {
+		problems = append(problems, fmt.Sprintf("server.labels.engine of the proxy is `%s` rather than `tiflash`", engine))
+	}
+	return problems
+}
+
+// configString returns the value of the key in the form of `a.b.c` in the nested config as
+// a string, it's empty if the key is absent
+func configString(config map[string]interface{}, key string) string {
+	var value interface{} = config
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		if value, ok = m[part]; !ok {
+			return ""
+		}
+	}
+	if _, ok := value.(map[string]interface{}); ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// addrPort returns the port of the address, it's empty if there is no port
+func addrPort(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// isSubDir returns if the path is the dir or under it
+func isSubDir(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../") && !filepath.IsAbs(rel)
+}
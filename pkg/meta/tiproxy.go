@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/template/scripts"
+)
+
+// TiProxyComponent represents TiProxy component.
+type TiProxyComponent struct{ *Specification }
+
+// Name implements Component interface.
+func (c *TiProxyComponent) Name() string {
+	return ComponentTiProxy
+}
+
+// Instances implements Component interface.
+func (c *TiProxyComponent) Instances() []Instance {
+	ins := make([]Instance, 0, len(c.TiProxyServers))
+	for _, s := range c.TiProxyServers {
+		s := s
+		ins = append(ins, &TiProxyInstance{instance{
+			InstanceSpec: s,
+			name:         c.Name(),
+			host:         s.Host,
+			port:         s.Port,
+			sshp:         s.SSHPort,
+			topo:         c.Specification,
+
+			usedPorts: []int{
+				s.Port,
+				s.StatusPort,
+			},
+			usedDirs: []string{
+				s.DeployDir,
+			},
+			statusFn: s.Status,
+		}})
+	}
+	return ins
+}
+
+// TiProxyInstance represent the TiProxy instance
+type TiProxyInstance struct {
+	instance
+}
+
+// InitConfig implement Instance interface
+func (i *TiProxyInstance) InitConfig(e executor.TiOpsExecutor, clusterName, clusterVersion, deployUser string, paths DirPaths) error {
+	if err := i.instance.InitConfig(e, clusterName, clusterVersion, deployUser, paths); err != nil {
+		return err
+	}
+
+	spec := i.InstanceSpec.(TiProxySpec)
+	cfg := scripts.NewTiProxyScript(
+		i.GetHost(),
+		paths.Deploy,
+		paths.Log,
+	).WithNumaNode(spec.NumaNode)
+	fp := filepath.Join(paths.Cache, fmt.Sprintf("run_tiproxy_%s_%d.sh", i.GetHost(), i.GetPort()))
+	if err := cfg.ConfigToFile(fp); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(paths.Deploy, "scripts", "run_tiproxy.sh")
+	if err := e.Transfer(fp, dst, false); err != nil {
+		return err
+	}
+	if _, _, err := e.Execute("chmod +x "+dst, false); err != nil {
+		return err
+	}
+
+	globalConfig, err := merge(tiproxyConfig(spec, i.topo.GetPDList(), paths.Log), i.topo.ServerConfigs.TiProxy)
+	if err != nil {
+		return err
+	}
+	return i.mergeServerConfig(e, globalConfig, spec.Config, paths)
+}
+
+// ScaleConfig deploy temporary config on scaling
+func (i *TiProxyInstance) ScaleConfig(e executor.TiOpsExecutor, b *Specification, clusterName, clusterVersion, deployUser string, paths DirPaths) error {
+	s := i.instance.topo
+	defer func() { i.instance.topo = s }()
+	i.instance.topo = b
+	return i.InitConfig(e, clusterName, clusterVersion, deployUser, paths)
+}
+
+// tiproxyConfig generates the configuration items which are decided by the topology,
+// TiProxy discovers the backend TiDB servers from PD so only the PD addresses are needed.
+// They can be overwritten by server_configs and the config of the instance.
+func tiproxyConfig(spec TiProxySpec, pdList []string, logDir string) map[string]interface{} {
+	return map[string]interface{}{
+		"proxy.addr":            fmt.Sprintf("0.0.0.0:%d", spec.Port),
+		"proxy.advertise-addr":  spec.Host,
+		"proxy.pd-addrs":        strings.Join(pdList, ","),
+		"api.addr":              fmt.Sprintf("0.0.0.0:%d", spec.StatusPort),
+		"log.log-file.filename": filepath.Join(logDir, "tiproxy.log"),
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// recordExecutor records the transferred files and does nothing on execution
+type recordExecutor struct {
+	transfers map[string]string
+}
+
+func (e *recordExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	return nil, nil, nil
+}
+
+func (e *recordExecutor) Transfer(src string, dst string, download bool) error {
+	e.transfers[dst] = src
+	return nil
+}
+
+func (s *metaSuite) TestTiProxySpec(c *C) {
+	topo := TopologySpecification{}
+	err := yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.53
+tidb_servers:
+  - host: 172.16.5.138
+tiproxy_servers:
+  - host: 172.16.5.139
+  - host: 172.16.5.140
+    port: 6001
+    status_port: 3081
+`), &topo)
+	c.Assert(err, IsNil)
+	c.Assert(topo.TiProxyServers, HasLen, 2)
+	c.Assert(topo.TiProxyServers[0].Port, Equals, 6000)
+	c.Assert(topo.TiProxyServers[0].StatusPort, Equals, 3080)
+	c.Assert(topo.TiProxyServers[0].DeployDir, Equals, "deploy/tiproxy-6000")
+	c.Assert(topo.TiProxyServers[1].DeployDir, Equals, "deploy/tiproxy-6001")
+
+	ins := (&TiProxyComponent{&topo}).Instances()
+	c.Assert(ins, HasLen, 2)
+	c.Assert(ins[1].ID(), Equals, "172.16.5.140:6001")
+	c.Assert(ins[1].UsedPorts(), DeepEquals, []int{6001, 3081})
+	c.Assert(ins[1].ServiceName(), Equals, "tiproxy-6001.service")
+
+	// TiProxy must be started after TiDB and stopped before TiDB
+	names := func(comps []Component) []string {
+		var ret []string
+		for _, comp := range comps {
+			ret = append(ret, comp.Name())
+		}
+		return ret
+	}
+	start := names(topo.ComponentsByStartOrder())
+	c.Assert(start[3:5], DeepEquals, []string{ComponentTiDB, ComponentTiProxy})
+	stop := names(topo.ComponentsByStopOrder())
+	c.Assert(stop[len(stop)-5:len(stop)-3], DeepEquals, []string{ComponentTiProxy, ComponentTiDB})
+
+	merged := topo.Merge(&TopologySpecification{TiProxyServers: []TiProxySpec{{Host: "172.16.5.141"}}})
+	c.Assert(merged.TiProxyServers, HasLen, 3)
+
+	err = yaml.Unmarshal([]byte(`
+tiproxy_servers:
+  - host: 172.16.5.139
+    port: 4000
+tidb_servers:
+  - host: 172.16.5.139
+`), &TopologySpecification{})
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*port '4000' conflicts.*")
+}
+
+func (s *metaSuite) TestTiProxyInitConfig(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	cache, err := ioutil.TempDir("", "tiproxy")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	topo := TopologySpecification{}
+	err = yaml.Unmarshal([]byte(`
+server_configs:
+  tiproxy:
+    log.level: warn
+    proxy.max-connections: 100
+pd_servers:
+  - host: 172.16.5.53
+  - host: 172.16.5.54
+tiproxy_servers:
+  - host: 172.16.5.139
+    config:
+      proxy.max-connections: 200
+`), &topo)
+	c.Assert(err, IsNil)
+
+	e := &recordExecutor{transfers: map[string]string{}}
+	inst := (&TiProxyComponent{&topo}).Instances()[0]
+	paths := DirPaths{
+		Deploy: "/home/tidb/deploy/tiproxy-6000",
+		Log:    "/home/tidb/deploy/tiproxy-6000/log",
+		Cache:  cache,
+	}
+	c.Assert(inst.InitConfig(e, "test", "v4.0.0", "tidb", paths), IsNil)
+
+	script, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/tiproxy-6000/scripts/run_tiproxy.sh"])
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, "(?s).*exec bin/tiproxy.*--config=conf/tiproxy.toml.*")
+
+	data, err := ioutil.ReadFile(e.transfers["/home/tidb/deploy/tiproxy-6000/conf/tiproxy.toml"])
+	c.Assert(err, IsNil)
+	var conf struct {
+		Proxy struct {
+			Addr           string `toml:"addr"`
+			AdvertiseAddr  string `toml:"advertise-addr"`
+			PDAddrs        string `toml:"pd-addrs"`
+			MaxConnections int    `toml:"max-connections"`
+		} `toml:"proxy"`
+		API struct {
+			Addr string `toml:"addr"`
+		} `toml:"api"`
+		Log struct {
+			Level   string `toml:"level"`
+			LogFile struct {
+				Filename string `toml:"filename"`
+			} `toml:"log-file"`
+		} `toml:"log"`
+	}
+	_, err = toml.Decode(string(data), &conf)
+	c.Assert(err, IsNil)
+	c.Assert(conf.Proxy.Addr, Equals, "0.0.0.0:6000")
+	c.Assert(conf.Proxy.AdvertiseAddr, Equals, "172.16.5.139")
+	c.Assert(conf.Proxy.PDAddrs, Equals, "172.16.5.53:2379,172.16.5.54:2379")
+	c.Assert(conf.Proxy.MaxConnections, Equals, 200)
+	c.Assert(conf.API.Addr, Equals, "0.0.0.0:3080")
+	c.Assert(conf.Log.Level, Equals, "warn")
+	c.Assert(conf.Log.LogFile.Filename, Equals, "/home/tidb/deploy/tiproxy-6000/log/tiproxy.log")
+}
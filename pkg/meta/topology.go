@@ -50,21 +50,23 @@ type (
 	// ResourceControl is used to control the system resource
 	// See: https://www.freedesktop.org/software/systemd/man/systemd.resource-control.html
 	ResourceControl struct {
-		MemoryLimit         string `yaml:"memory_limit"`
-		CPUQuota            string `yaml:"cpu_quota"`
-		IOReadBandwidthMax  string `yaml:"io_read_bandwidth_max"`
-		IOWriteBandwidthMax string `yaml:"io_write_bandwidth_max"`
+		MemoryLimit         string `yaml:"memory_limit,omitempty"`
+		CPUQuota            string `yaml:"cpu_quota,omitempty"`
+		IOReadBandwidthMax  string `yaml:"io_read_bandwidth_max,omitempty"`
+		IOWriteBandwidthMax string `yaml:"io_write_bandwidth_max,omitempty"`
 	}
 
 	// GlobalOptions represents the global options for all groups in topology
 	// specification in topology.yaml
 	GlobalOptions struct {
-		User            string          `yaml:"user,omitempty" default:"tidb"`
-		SSHPort         int             `yaml:"ssh_port,omitempty" default:"22"`
-		DeployDir       string          `yaml:"deploy_dir,omitempty" default:"deploy"`
-		DataDir         string          `yaml:"data_dir,omitempty" default:"data"`
-		LogDir          string          `yaml:"log_dir,omitempty"`
-		ResourceControl ResourceControl `yaml:"resource_control"`
+		User            string            `yaml:"user,omitempty" default:"tidb"`
+		SSHPort         int               `yaml:"ssh_port,omitempty" default:"22"`
+		DeployDir       string            `yaml:"deploy_dir,omitempty" default:"deploy"`
+		DataDir         string            `yaml:"data_dir,omitempty" default:"data"`
+		LogDir          string            `yaml:"log_dir,omitempty"`
+		ResourceControl ResourceControl   `yaml:"resource_control,omitempty"`
+		ShutdownTimeout map[string]int    `yaml:"shutdown_timeout,omitempty"` // seconds to wait for the instances of each component to exit
+		SystemdTemplate map[string]string `yaml:"systemd_template,omitempty"` // the unit template files overriding the built-in one of each component
 	}
 
 	// MonitoredOptions represents the monitored node configuration
@@ -74,18 +76,20 @@ type (
 		DeployDir            string          `yaml:"deploy_dir,omitempty"`
 		DataDir              string          `yaml:"data_dir,omitempty"`
 		LogDir               string          `yaml:"log_dir,omitempty"`
-		ResourceControl      ResourceControl `yaml:"resource_control"`
+		ResourceControl      ResourceControl `yaml:"resource_control,omitempty"`
 	}
 
 	// ServerConfigs represents the server runtime configuration
 	ServerConfigs struct {
-		TiDB           map[string]interface{} `yaml:"tidb"`
-		TiKV           map[string]interface{} `yaml:"tikv"`
-		PD             map[string]interface{} `yaml:"pd"`
-		TiFlash        map[string]interface{} `yaml:"tiflash"`
-		TiFlashLearner map[string]interface{} `yaml:"tiflash-learner"`
-		Pump           map[string]interface{} `yaml:"pump"`
-		Drainer        map[string]interface{} `yaml:"drainer"`
+		TiDB           map[string]interface{} `yaml:"tidb,omitempty"`
+		TiKV           map[string]interface{} `yaml:"tikv,omitempty"`
+		PD             map[string]interface{} `yaml:"pd,omitempty"`
+		TiFlash        map[string]interface{} `yaml:"tiflash,omitempty"`
+		TiFlashLearner map[string]interface{} `yaml:"tiflash-learner,omitempty"`
+		Pump           map[string]interface{} `yaml:"pump,omitempty"`
+		Drainer        map[string]interface{} `yaml:"drainer,omitempty"`
+		TiProxy        map[string]interface{} `yaml:"tiproxy,omitempty"`
+		CDC            map[string]interface{} `yaml:"cdc,omitempty"`
 	}
 
 	// TopologySpecification represents the specification of topology.yaml
@@ -95,11 +99,13 @@ type (
 		ServerConfigs    ServerConfigs      `yaml:"server_configs,omitempty"`
 		TiDBServers      []TiDBSpec         `yaml:"tidb_servers"`
 		TiKVServers      []TiKVSpec         `yaml:"tikv_servers"`
-		TiFlashServers   []TiFlashSpec      `yaml:"tiflash_servers"`
+		TiFlashServers   []TiFlashSpec      `yaml:"tiflash_servers,omitempty"`
 		PDServers        []PDSpec           `yaml:"pd_servers"`
+		TiProxyServers   []TiProxySpec      `yaml:"tiproxy_servers,omitempty"`
 		PumpServers      []PumpSpec         `yaml:"pump_servers,omitempty"`
 		Drainers         []DrainerSpec      `yaml:"drainer_servers,omitempty"`
-		Monitors         []PrometheusSpec   `yaml:"monitoring_servers"`
+		CDCServers       []CDCSpec          `yaml:"cdc_servers,omitempty"`
+		Monitors         []PrometheusSpec   `yaml:"monitoring_servers,omitempty"`
 		Grafana          []GrafanaSpec      `yaml:"grafana_servers,omitempty"`
 		Alertmanager     []AlertManagerSpec `yaml:"alertmanager_servers,omitempty"`
 	}
@@ -116,7 +122,8 @@ type TiDBSpec struct {
 	LogDir          string                 `yaml:"log_dir,omitempty"`
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
-	ResourceControl ResourceControl        `yaml:"resource_control"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
 }
 
 // statusByURL queries current status of the instance by http status api.
@@ -137,7 +144,7 @@ func statusByURL(url string) string {
 
 // Status queries current status of the instance
 func (s TiDBSpec) Status(pdList ...string) string {
-	url := fmt.Sprintf("http://%s:%d/status", s.Host, s.StatusPort)
+	url := fmt.Sprintf("http://%s/status", utils.JoinHostPort(s.Host, s.StatusPort))
 	return statusByURL(url)
 }
 
@@ -174,7 +181,11 @@ type TiKVSpec struct {
 	Offline         bool                   `yaml:"offline,omitempty"`
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
-	ResourceControl ResourceControl        `yaml:"resource_control"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+	// The weights of the store in PD set online, the default ones of PD if zero
+	LeaderWeight float64 `yaml:"leader_weight,omitempty"`
+	RegionWeight float64 `yaml:"region_weight,omitempty"`
 }
 
 // Status queries current status of the instance
@@ -188,7 +199,7 @@ func (s TiKVSpec) Status(pdList ...string) string {
 		return "Down"
 	}
 
-	name := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	name := utils.JoinHostPort(s.Host, s.Port)
 
 	// only get status of the latest store, it is the store with lagest ID number
 	// older stores might be legacy ones that already offlined
@@ -236,7 +247,7 @@ type PDSpec struct {
 	SSHPort  int    `yaml:"ssh_port,omitempty"`
 	Imported bool   `yaml:"imported,omitempty"`
 	// Use Name to get the name with a default value if it's empty.
-	Name            string                 `yaml:"name"`
+	Name            string                 `yaml:"name,omitempty"`
 	ClientPort      int                    `yaml:"client_port" default:"2379"`
 	PeerPort        int                    `yaml:"peer_port" default:"2380"`
 	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
@@ -244,12 +255,13 @@ type PDSpec struct {
 	LogDir          string                 `yaml:"log_dir,omitempty"`
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
-	ResourceControl ResourceControl        `yaml:"resource_control"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
 }
 
 // Status queries current status of the instance
 func (s PDSpec) Status(pdList ...string) string {
-	pdapi := api.NewPDClient([]string{fmt.Sprintf("%s:%d", s.Host, s.ClientPort)},
+	pdapi := api.NewPDClient([]string{utils.JoinHostPort(s.Host, s.ClientPort)},
 		statusQueryTimeout, nil)
 	healths, err := pdapi.GetHealth()
 	if err != nil {
@@ -315,13 +327,14 @@ type TiFlashSpec struct {
 	TmpDir               string                 `yaml:"tmp_path,omitempty"`
 	NumaNode             string                 `yaml:"numa_node,omitempty"`
 	Config               map[string]interface{} `yaml:"config,omitempty"`
+	ConfigFragment       string                 `yaml:"config_fragment,omitempty"`
 	LearnerConfig        map[string]interface{} `yaml:"learner_config,omitempty"`
-	ResourceControl      ResourceControl        `yaml:"resource_control"`
+	ResourceControl      ResourceControl        `yaml:"resource_control,omitempty"`
 }
 
 // Status queries current status of the instance
 func (s TiFlashSpec) Status(flashList ...string) string {
-	url := fmt.Sprintf("http://%s:%d/?query=select%%20version()", s.Host, s.HTTPPort)
+	url := fmt.Sprintf("http://%s/?query=select%%20version()", utils.JoinHostPort(s.Host, s.HTTPPort))
 	return statusByURL(url)
 }
 
@@ -357,7 +370,8 @@ type PumpSpec struct {
 	Offline         bool                   `yaml:"offline,omitempty"`
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
-	ResourceControl ResourceControl        `yaml:"resource_control"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
 }
 
 // Role returns the component role of the instance
@@ -380,6 +394,47 @@ func (s PumpSpec) IsImported() bool {
 	return s.Imported
 }
 
+// TiProxySpec represents the TiProxy topology specification in topology.yaml
+type TiProxySpec struct {
+	Host            string                 `yaml:"host"`
+	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	Port            int                    `yaml:"port" default:"6000"`
+	StatusPort      int                    `yaml:"status_port" default:"3080"`
+	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
+	LogDir          string                 `yaml:"log_dir,omitempty"`
+	NumaNode        string                 `yaml:"numa_node,omitempty"`
+	Config          map[string]interface{} `yaml:"config,omitempty"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+}
+
+// Status queries current status of the instance
+func (s TiProxySpec) Status(pdList ...string) string {
+	url := fmt.Sprintf("http://%s/api/debug/health", utils.JoinHostPort(s.Host, s.StatusPort))
+	return statusByURL(url)
+}
+
+// Role returns the component role of the instance
+func (s TiProxySpec) Role() string {
+	return ComponentTiProxy
+}
+
+// SSH returns the host and SSH port of the instance
+func (s TiProxySpec) SSH() (string, int) {
+	return s.Host, s.SSHPort
+}
+
+// GetMainPort returns the main port of the instance
+func (s TiProxySpec) GetMainPort() int {
+	return s.Port
+}
+
+// IsImported returns if the node is imported from TiDB-Ansible
+func (s TiProxySpec) IsImported() bool {
+	// TiDB-Ansible never deploys TiProxy
+	return false
+}
+
 // DrainerSpec represents the Drainer topology specification in topology.yaml
 type DrainerSpec struct {
 	Host            string                 `yaml:"host"`
@@ -393,7 +448,8 @@ type DrainerSpec struct {
 	Offline         bool                   `yaml:"offline,omitempty"`
 	NumaNode        string                 `yaml:"numa_node,omitempty"`
 	Config          map[string]interface{} `yaml:"config,omitempty"`
-	ResourceControl ResourceControl        `yaml:"resource_control"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
 }
 
 // Role returns the component role of the instance
@@ -416,6 +472,48 @@ func (s DrainerSpec) IsImported() bool {
 	return s.Imported
 }
 
+// CDCSpec represents the TiCDC topology specification in topology.yaml
+type CDCSpec struct {
+	Host            string                 `yaml:"host"`
+	SSHPort         int                    `yaml:"ssh_port,omitempty"`
+	Port            int                    `yaml:"port" default:"8300"`
+	DeployDir       string                 `yaml:"deploy_dir,omitempty"`
+	LogDir          string                 `yaml:"log_dir,omitempty"`
+	GCTTL           int64                  `yaml:"gc_ttl,omitempty"`
+	TZ              string                 `yaml:"tz,omitempty"`
+	NumaNode        string                 `yaml:"numa_node,omitempty"`
+	Config          map[string]interface{} `yaml:"config,omitempty"`
+	ConfigFragment  string                 `yaml:"config_fragment,omitempty"`
+	ResourceControl ResourceControl        `yaml:"resource_control,omitempty"`
+}
+
+// Status queries current status of the instance
+func (s CDCSpec) Status(pdList ...string) string {
+	url := fmt.Sprintf("http://%s/status", utils.JoinHostPort(s.Host, s.Port))
+	return statusByURL(url)
+}
+
+// Role returns the component role of the instance
+func (s CDCSpec) Role() string {
+	return ComponentCDC
+}
+
+// SSH returns the host and SSH port of the instance
+func (s CDCSpec) SSH() (string, int) {
+	return s.Host, s.SSHPort
+}
+
+// GetMainPort returns the main port of the instance
+func (s CDCSpec) GetMainPort() int {
+	return s.Port
+}
+
+// IsImported returns if the node is imported from TiDB-Ansible
+func (s CDCSpec) IsImported() bool {
+	// TiDB-Ansible never deploys TiCDC
+	return false
+}
+
 // PrometheusSpec represents the Prometheus Server topology specification in topology.yaml
 type PrometheusSpec struct {
 	Host            string          `yaml:"host"`
@@ -426,7 +524,7 @@ type PrometheusSpec struct {
 	DataDir         string          `yaml:"data_dir,omitempty"`
 	LogDir          string          `yaml:"log_dir,omitempty"`
 	Retention       string          `yaml:"storage_retention,omitempty"`
-	ResourceControl ResourceControl `yaml:"resource_control"`
+	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
 }
 
 // Role returns the component role of the instance
@@ -456,7 +554,7 @@ type GrafanaSpec struct {
 	Imported        bool            `yaml:"imported,omitempty"`
 	Port            int             `yaml:"port" default:"3000"`
 	DeployDir       string          `yaml:"deploy_dir,omitempty"`
-	ResourceControl ResourceControl `yaml:"resource_control"`
+	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
 }
 
 // Role returns the component role of the instance
@@ -489,7 +587,7 @@ type AlertManagerSpec struct {
 	DeployDir       string          `yaml:"deploy_dir,omitempty"`
 	DataDir         string          `yaml:"data_dir,omitempty"`
 	LogDir          string          `yaml:"log_dir,omitempty"`
-	ResourceControl ResourceControl `yaml:"resource_control"`
+	ResourceControl ResourceControl `yaml:"resource_control,omitempty"`
 }
 
 // Role returns the component role of the instance
@@ -733,6 +831,18 @@ func (topo *TopologySpecification) Validate() error {
 		return err
 	}
 
+	if err := topo.configFragmentsValidate(); err != nil {
+		return err
+	}
+
+	if err := topo.shutdownTimeoutsValidate(); err != nil {
+		return err
+	}
+
+	if err := topo.systemdTemplatesValidate(); err != nil {
+		return err
+	}
+
 	return topo.dirConflictsDetect()
 }
 
@@ -741,7 +851,7 @@ func (topo *TopologySpecification) GetPDList() []string {
 	var pdList []string
 
 	for _, pd := range topo.PDServers {
-		pdList = append(pdList, fmt.Sprintf("%s:%d", pd.Host, pd.ClientPort))
+		pdList = append(pdList, utils.JoinHostPort(pd.Host, pd.ClientPort))
 	}
 
 	return pdList
@@ -757,8 +867,10 @@ func (topo *TopologySpecification) Merge(that *TopologySpecification) *TopologyS
 		TiKVServers:      append(topo.TiKVServers, that.TiKVServers...),
 		PDServers:        append(topo.PDServers, that.PDServers...),
 		TiFlashServers:   append(topo.TiFlashServers, that.TiFlashServers...),
+		TiProxyServers:   append(topo.TiProxyServers, that.TiProxyServers...),
 		PumpServers:      append(topo.PumpServers, that.PumpServers...),
 		Drainers:         append(topo.Drainers, that.Drainers...),
+		CDCServers:       append(topo.CDCServers, that.CDCServers...),
 		Monitors:         append(topo.Monitors, that.Monitors...),
 		Grafana:          append(topo.Grafana, that.Grafana...),
 		Alertmanager:     append(topo.Alertmanager, that.Alertmanager...),
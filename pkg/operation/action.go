@@ -17,7 +17,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -26,6 +25,7 @@ import (
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/module"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/set"
 	"github.com/pingcap/errors"
 	"golang.org/x/sync/errgroup"
@@ -43,12 +43,21 @@ func Start(
 	components := spec.ComponentsByStartOrder()
 	components = FilterComponent(components, roleFilter)
 
+	started := make(map[string][]meta.Instance)
 	for _, com := range components {
 		insts := FilterInstance(com.Instances(), nodeFilter)
-		err := StartComponent(getter, insts)
+		if len(insts) > 0 {
+			if err := waitDependencies(spec, com.Name(), started); err != nil {
+				return err
+			}
+		}
+		err := startComponent(getter, insts, options.Concurrency)
 		if err != nil {
 			return errors.Annotatef(err, "failed to start %s", com.Name())
 		}
+		if len(insts) > 0 {
+			started[com.Name()] = insts
+		}
 		for _, inst := range insts {
 			if !uniqueHosts.Exist(inst.GetHost()) {
 				uniqueHosts.Insert(inst.GetHost())
@@ -62,6 +71,34 @@ func Start(
 	return nil
 }
 
+// componentReady waits for the started instances of a component to be fully up, before
+// the components depending on it are started. The instances are ready when their ports
+// are listened, and the components absent from it need nothing more.
+var componentReady = map[string]func(spec *meta.Specification, insts []meta.Instance) error{
+	meta.ComponentPD: func(spec *meta.Specification, insts []meta.Instance) error {
+		pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, nil)
+		return pdClient.WaitLeader(&utils.RetryOption{Delay: time.Second, Timeout: 60 * time.Second})
+	},
+}
+
+// waitDependencies waits for the dependencies of the component started in this run to be
+// fully up, each of them is removed from started once it's waited for
+func waitDependencies(spec *meta.Specification, name string, started map[string][]meta.Instance) error {
+	for _, dep := range meta.ComponentDependencies[name] {
+		insts, ok := started[dep]
+		ready := componentReady[dep]
+		if !ok || ready == nil {
+			continue
+		}
+		log.Infof("Waiting for %s to be up before starting %s", dep, name)
+		if err := ready(spec, insts); err != nil {
+			return errors.Annotatef(err, "%s is not up, which %s depends on", dep, name)
+		}
+		delete(started, dep)
+	}
+	return nil
+}
+
 // Stop the cluster.
 func Stop(
 	getter ExecutorGetter,
@@ -78,9 +115,33 @@ func Stop(
 		instCount[inst.GetHost()] = instCount[inst.GetHost()] + 1
 	})
 
+	policy, escalate := options.StopPolicy()
 	for _, com := range components {
 		insts := FilterInstance(com.Instances(), nodeFilter)
-		err := StopComponent(getter, insts)
+		// move the changefeeds to the captures kept running
+		if com.Name() == meta.ComponentCDC && !options.Force {
+			if err := DrainCDC(spec, insts, DrainRetryOption(options)); err != nil {
+				return err
+			}
+		}
+		// keep the PD leader on the members kept running
+		if com.Name() == meta.ComponentPD && !options.Force {
+			if err := transferPDLeaderBeforeStop(spec, insts, DrainRetryOption(options)); err != nil {
+				return err
+			}
+		}
+		// save the checkpoints of the drainers before they are stopped
+		if com.Name() == meta.ComponentDrainer && !options.Force {
+			if err := WaitDrainersSynced(insts, DrainRetryOption(options)); err != nil {
+				return err
+			}
+		}
+		var err error
+		if escalate {
+			err = stopComponentWithPolicy(getter, insts, policy, options.Concurrency)
+		} else {
+			err = stopComponent(getter, insts, options.Concurrency)
+		}
 		if err != nil {
 			return errors.Annotatef(err, "failed to stop %s", com.Name())
 		}
@@ -146,7 +207,7 @@ func DestroyTombstone(
 			continue
 		}
 
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 
 		tombstone, err := pdClient.IsTombStone(id)
 		if err != nil {
@@ -185,7 +246,7 @@ func DestroyTombstone(
 			continue
 		}
 
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 
 		tombstone, err := binlogClient.IsPumpTombstone(id)
 		if err != nil {
@@ -222,7 +283,7 @@ func DestroyTombstone(
 			continue
 		}
 
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 
 		tombstone, err := binlogClient.IsDrainerTombstone(id)
 		if err != nil {
@@ -334,40 +395,49 @@ func RestartComponent(getter ExecutorGetter, instances []meta.Instance) error {
 	log.Infof("Restarting component %s", name)
 
 	for _, ins := range instances {
-		e := getter.Get(ins.GetHost())
-		log.Infof("\tRestarting instance %s", ins.GetHost())
-
-		// Restart by systemd.
-		c := module.SystemdModuleConfig{
-			Unit:         ins.ServiceName(),
-			ReloadDaemon: true,
-			Action:       "restart",
+		if err := RestartInstance(getter, ins); err != nil {
+			return err
 		}
-		systemd := module.NewSystemdModule(c)
-		stdout, stderr, err := systemd.Execute(e)
+	}
 
-		if len(stdout) > 0 {
-			fmt.Println(string(stdout))
-		}
-		if len(stderr) > 0 {
-			log.Errorf(string(stderr))
-		}
+	return nil
+}
 
-		if err != nil {
-			return errors.Annotatef(err, "failed to restart: %s", ins.GetHost())
-		}
+// RestartInstance restarts the instance and waits for it to be ready.
+func RestartInstance(getter ExecutorGetter, ins meta.Instance) error {
+	e := getter.Get(ins.GetHost())
+	log.Infof("\tRestarting instance %s", ins.GetHost())
 
-		// Check ready.
-		err = ins.Ready(e)
-		if err != nil {
-			str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
-			log.Errorf(str)
-			return errors.Annotatef(err, str)
-		}
+	// Restart by systemd.
+	c := module.SystemdModuleConfig{
+		Unit:         ins.ServiceName(),
+		ReloadDaemon: true,
+		Action:       "restart",
+	}
+	systemd := module.NewSystemdModule(c)
+	stdout, stderr, err := systemd.Execute(e)
+
+	if len(stdout) > 0 {
+		fmt.Println(string(stdout))
+	}
+	if len(stderr) > 0 {
+		log.Errorf(string(stderr))
+	}
 
-		log.Infof("\tRestart %s success", ins.GetHost())
+	if err != nil {
+		return errors.Annotatef(err, "failed to restart: %s", ins.GetHost())
 	}
 
+	// Check ready.
+	err = ins.Ready(e)
+	if err != nil {
+		str := fmt.Sprintf("\t%s failed to restart: %s", ins.GetHost(), err)
+		log.Errorf(str)
+		return errors.Annotatef(err, str)
+	}
+
+	log.Infof("\tRestart %s success", ins.GetHost())
+
 	return nil
 }
 
@@ -423,6 +493,11 @@ func startInstance(getter ExecutorGetter, ins meta.Instance) error {
 
 // StartComponent start the instances.
 func StartComponent(getter ExecutorGetter, instances []meta.Instance) error {
+	return startComponent(getter, instances, 0)
+}
+
+// startComponent starts the instances, at most limit of them at the same time if it's positive
+func startComponent(getter ExecutorGetter, instances []meta.Instance, limit int) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -430,21 +505,13 @@ func StartComponent(getter ExecutorGetter, instances []meta.Instance) error {
 	name := instances[0].ComponentName()
 	log.Infof("Starting component %s", name)
 
-	errg, _ := errgroup.WithContext(context.Background())
-
-	for _, ins := range instances {
-		ins := ins
-
-		errg.Go(func() error {
-			err := startInstance(getter, ins)
-			if err != nil {
-				return errors.AddStack(err)
-			}
-			return nil
-		})
-	}
-
-	return errg.Wait()
+	return forEachInstance(instances, limit, func(ins meta.Instance) error {
+		err := startInstance(getter, ins)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		return nil
+	})
 }
 
 // StopMonitored stop BlackboxExporter and NodeExporter
@@ -560,6 +627,11 @@ func stopInstance(getter ExecutorGetter, ins meta.Instance) error {
 
 // StopComponent stop the instances.
 func StopComponent(getter ExecutorGetter, instances []meta.Instance) error {
+	return stopComponent(getter, instances, 0)
+}
+
+// stopComponent stops the instances, at most limit of them at the same time if it's positive
+func stopComponent(getter ExecutorGetter, instances []meta.Instance, limit int) error {
 	if len(instances) <= 0 {
 		return nil
 	}
@@ -567,21 +639,13 @@ func StopComponent(getter ExecutorGetter, instances []meta.Instance) error {
 	name := instances[0].ComponentName()
 	log.Infof("Stopping component %s", name)
 
-	errg, _ := errgroup.WithContext(context.Background())
-
-	for _, ins := range instances {
-		ins := ins
-		errg.Go(func() error {
-
-			err := stopInstance(getter, ins)
-			if err != nil {
-				return errors.AddStack(err)
-			}
-			return nil
-		})
-	}
-
-	return errg.Wait()
+	return forEachInstance(instances, limit, func(ins meta.Instance) error {
+		err := stopInstance(getter, ins)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		return nil
+	})
 }
 
 // GetServiceStatus return the Acitive line of status.
@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+// defaultDrainTimeout is used to drain the TiCDC captures if the timeout is not set
+const defaultDrainTimeout = 300
+
+// DrainRetryOption returns the retry option to wait for the TiCDC captures to be drained
+func DrainRetryOption(options Options) *utils.RetryOption {
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	return &utils.RetryOption{
+		Timeout: time.Second * time.Duration(timeout),
+		Delay:   time.Second * 2,
+	}
+}
+
+// DrainCDC moves the tables replicated by the TiCDC instances to the other captures before
+// the instances are stopped, the owner role is resigned first if one of them is the owner.
+// Nothing is drained if no other capture is alive, the changefeeds are resumed from their
+// checkpoints after the captures start again. Draining is best effort, the failures are
+// warned and never block stopping the instances.
+func DrainCDC(spec *meta.Specification, instances []meta.Instance, retryOpt *utils.RetryOption) error {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	stopping := set.NewStringSet()
+	for _, inst := range instances {
+		stopping.Insert(inst.ID())
+	}
+	// the requests are served by the captures to keep first
+	var addrs, stoppingAddrs []string
+	for _, inst := range (&meta.CDCComponent{Specification: spec}).Instances() {
+		if stopping.Exist(inst.ID()) {
+			stoppingAddrs = append(stoppingAddrs, inst.ID())
+		} else {
+			addrs = append(addrs, inst.ID())
+		}
+	}
+	client := api.NewCDCClient(append(addrs, stoppingAddrs...), 5*time.Second, nil)
+
+	captures, err := client.GetCaptures()
+	if err != nil {
+		log.Warnf("Ignore draining the TiCDC captures, failed to get them: %v", err)
+		return nil
+	}
+	byAddr := make(map[string]*api.Capture)
+	alive := 0
+	for _, capture := range captures {
+		byAddr[capture.AdvertiseAddr] = capture
+		if !stopping.Exist(capture.AdvertiseAddr) {
+			alive++
+		}
+	}
+	if alive == 0 {
+		log.Infof("No other TiCDC capture is alive, the changefeeds are resumed from their checkpoints after the captures start again")
+		return nil
+	}
+
+	for _, inst := range instances {
+		capture, ok := byAddr[inst.ID()]
+		if !ok {
+			// not running
+			continue
+		}
+		if err := drainCapture(client, capture, retryOpt); err != nil {
+			log.Warnf("Ignore draining the TiCDC capture %s, %v", inst.ID(), err)
+		}
+	}
+	return nil
+}
+
+// drainCapture resigns the owner role of the capture if it's the owner, and waits for its
+// tables to be moved to the other captures
+func drainCapture(client *api.CDCClient, capture *api.Capture, retryOpt *utils.RetryOption) error {
+	if capture.IsOwner {
+		log.Infof("Resigning the TiCDC owner %s...", capture.AdvertiseAddr)
+		if err := client.ResignOwner(); err != nil {
+			return errors.Annotate(err, "failed to resign the owner")
+		}
+		if err := utils.Retry(func() error {
+			captures, err := client.GetCaptures()
+			if err != nil {
+				return err
+			}
+			for _, c := range captures {
+				if c.IsOwner && c.ID != capture.ID {
+					return nil
+				}
+			}
+			log.Debugf("Still waiting for the new TiCDC owner to be elected")
+			return errors.New("still waiting for the new TiCDC owner to be elected")
+		}, *retryOpt); err != nil {
+			return errors.Errorf("error waiting for the new owner, %v", err)
+		}
+	}
+
+	log.Infof("Draining the changefeeds from TiCDC capture %s...", capture.AdvertiseAddr)
+	// the first request fails fast if the version doesn't support draining
+	tables, err := client.DrainCapture(capture.ID)
+	if err != nil {
+		return errors.Annotate(err, "failed to drain the capture")
+	}
+	if tables == 0 {
+		return nil
+	}
+	if err := utils.Retry(func() error {
+		tables, err := client.DrainCapture(capture.ID)
+		if err != nil {
+			return err
+		}
+		if tables == 0 {
+			return nil
+		}
+		log.Debugf("Still waiting for %d tables to be moved from %s", tables, capture.AdvertiseAddr)
+		return errors.New("still waiting for the tables to be moved")
+	}, *retryOpt); err != nil {
+		return errors.Errorf("error draining the capture, %v", err)
+	}
+	return nil
+}
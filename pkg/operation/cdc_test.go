@@ -0,0 +1,200 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+type cdcSuite struct{}
+
+var _ = Suite(&cdcSuite{})
+
+// mockCDC serves the open API of a TiCDC cluster, a drain request moves at most two
+// tables of the capture to the other captures. The requests and the systemctl commands
+// executed by the recorder are logged in order.
+type mockCDC struct {
+	mu          sync.Mutex
+	captures    []*api.Capture
+	tables      map[string]int
+	unsupported bool
+	events      []string
+}
+
+func (m *mockCDC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case r.URL.Path == "/api/v1/captures" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(m.captures)
+	case r.URL.Path == "/api/v1/owner/resign" && r.Method == http.MethodPost:
+		m.events = append(m.events, "resign")
+		var next *api.Capture
+		for _, c := range m.captures {
+			if !c.IsOwner && next == nil {
+				next = c
+			}
+			c.IsOwner = false
+		}
+		next.IsOwner = true
+	case r.URL.Path == "/api/v1/captures/drain" && r.Method == http.MethodPut:
+		if m.unsupported {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req struct {
+			CaptureID string `json:"capture_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		m.events = append(m.events, "drain "+req.CaptureID)
+		moved := m.tables[req.CaptureID]
+		if moved > 2 {
+			moved = 2
+		}
+		m.tables[req.CaptureID] -= moved
+		for _, c := range m.captures {
+			if c.ID != req.CaptureID {
+				m.tables[c.ID] += moved
+				break
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"current_table_count": m.tables[req.CaptureID]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (m *mockCDC) record(event string) {
+	m.mu.Lock()
+	m.events = append(m.events, event)
+	m.mu.Unlock()
+}
+
+func (m *mockCDC) log() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.events...)
+}
+
+// stopRecorder logs the systemctl commands to the mocked TiCDC cluster
+type stopRecorder struct {
+	cdc *mockCDC
+}
+
+func (e *stopRecorder) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if strings.HasPrefix(cmd, "systemctl") {
+		e.cdc.record(cmd)
+	}
+	return nil, nil, nil
+}
+
+func (e *stopRecorder) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+// cdcCluster starts two captures serving the mocked cluster, the first one is the owner
+func cdcCluster(c *C, tables ...int) (*mockCDC, *meta.Specification, func()) {
+	m := &mockCDC{tables: map[string]int{}}
+	var ports []string
+	var servers []*httptest.Server
+	for i, id := range []string{"capture-1", "capture-2"} {
+		server := httptest.NewServer(m)
+		servers = append(servers, server)
+		ports = append(ports, serverPort(c, server.Listener.Addr().String()))
+		m.captures = append(m.captures, &api.Capture{ID: id, IsOwner: i == 0, AdvertiseAddr: server.Listener.Addr().String()})
+		m.tables[id] = tables[i]
+	}
+
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+cdc_servers:
+  - host: 127.0.0.1
+    port: `+ports[0]+`
+  - host: 127.0.0.1
+    port: `+ports[1]+`
+`), topo), IsNil)
+	return m, topo, func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+}
+
+func (s *cdcSuite) TestDrainCDC(c *C) {
+	m, topo, stop := cdcCluster(c, 5, 1)
+	defer stop()
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second}
+
+	// the owner is resigned and the tables are moved in several rounds
+	insts := (&meta.CDCComponent{Specification: topo}).Instances()
+	c.Assert(DrainCDC(topo, insts[:1], retryOpt), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{"resign", "drain capture-1", "drain capture-1", "drain capture-1"})
+	c.Assert(m.captures[0].IsOwner, IsFalse)
+	c.Assert(m.captures[1].IsOwner, IsTrue)
+	c.Assert(m.tables, DeepEquals, map[string]int{"capture-1": 0, "capture-2": 6})
+
+	// nothing is drained if all the captures are stopped
+	m.events = nil
+	c.Assert(DrainCDC(topo, insts, retryOpt), IsNil)
+	c.Assert(m.log(), HasLen, 0)
+
+	// it's not running
+	m.captures = m.captures[1:]
+	c.Assert(DrainCDC(topo, insts[:1], retryOpt), IsNil)
+	c.Assert(m.log(), HasLen, 0)
+}
+
+func (s *cdcSuite) TestDrainCDCUnsupported(c *C) {
+	m, topo, stop := cdcCluster(c, 0, 3)
+	defer stop()
+	m.unsupported = true
+
+	// it's warned without retrying
+	insts := (&meta.CDCComponent{Specification: topo}).Instances()
+	c.Assert(DrainCDC(topo, insts[1:], &utils.RetryOption{Delay: time.Second, Timeout: time.Minute}), IsNil)
+	c.Assert(m.tables["capture-2"], Equals, 3)
+}
+
+func (s *cdcSuite) TestStopDrainsCDC(c *C) {
+	m, topo, stop := cdcCluster(c, 2, 1)
+	defer stop()
+	getter := hostGetter{"127.0.0.1": &stopRecorder{cdc: m}}
+	insts := (&meta.CDCComponent{Specification: topo}).Instances()
+
+	// the capture is drained before it's stopped
+	c.Assert(Stop(getter, topo, Options{Nodes: []string{insts[1].ID()}}), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{
+		"drain capture-2",
+		"systemctl daemon-reload && systemctl stop " + insts[1].ServiceName(),
+	})
+	c.Assert(m.tables, DeepEquals, map[string]int{"capture-1": 3, "capture-2": 0})
+
+	// not drained in force mode
+	m.events = nil
+	c.Assert(Stop(getter, topo, Options{Nodes: []string{insts[0].ID()}, Force: true}), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{
+		"systemctl daemon-reload && systemctl stop " + insts[0].ServiceName(),
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// CompactOptions are the options to compact the kv DB of the TiKV instances
+type CompactOptions struct {
+	CFs        []string      // the column families to compact
+	Threads    int           // the threads of each compaction
+	Bottommost bool          // compact the bottommost level too, which reclaims the most space
+	Timeout    time.Duration // the timeout of the compaction of each column family
+}
+
+// compactCommand returns the tikv-ctl command to compact the column family of the instance
+func compactCommand(inst meta.Instance, cf string, opt CompactOptions) string {
+	cmd := fmt.Sprintf("%s/bin/tikv-ctl --host %s compact -d kv -c %s",
+		strings.TrimSuffix(inst.DeployDir(), "/"), addr(inst), cf)
+	if opt.Threads > 0 {
+		cmd += fmt.Sprintf(" --threads %d", opt.Threads)
+	}
+	if opt.Bottommost {
+		cmd += " --bottommost force"
+	}
+	return cmd
+}
+
+// CompactTiKV compacts the kv DB of the TiKV instances one by one by tikv-ctl on the host
+// of each instance. The leaders of the store are evicted before the compaction so that the
+// serving is not impacted by it, and the eviction is removed after the compaction even if
+// it fails. The rest of the instances are not compacted once one fails.
+func CompactTiKV(
+	getter ExecutorGetter,
+	spec *meta.Specification,
+	instances []meta.Instance,
+	opt CompactOptions,
+	retryOpt *utils.RetryOption,
+) error {
+	pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, nil)
+	for i, inst := range instances {
+		log.Infof("Compacting %s (%d/%d)", inst.ID(), i+1, len(instances))
+		if err := pdClient.EvictStoreLeader(addr(inst), retryOpt); err != nil {
+			if !utils.IsTimeoutOrMaxRetry(err) {
+				return errors.Annotatef(err, "failed to evict store leader %s", inst.ID())
+			}
+			log.Warnf("Ignore evicting store leader from %s, %v", inst.ID(), err)
+		}
+
+		err := compactInstance(getter, inst, opt)
+		if rerr := pdClient.RemoveStoreEvict(addr(inst)); rerr != nil {
+			if err == nil {
+				err = errors.Annotatef(rerr, "failed to remove evict store scheduler for %s", inst.ID())
+			} else {
+				log.Errorf("Failed to remove evict store scheduler for %s: %v", inst.ID(), rerr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compactInstance compacts the column families of the instance one by one
+func compactInstance(getter ExecutorGetter, inst meta.Instance, opt CompactOptions) error {
+	e := getter.Get(inst.GetHost())
+	for _, cf := range opt.CFs {
+		start := time.Now()
+		log.Infof("\tCompacting column family %s of %s", cf, inst.ID())
+		var timeout []time.Duration
+		if opt.Timeout > 0 {
+			timeout = append(timeout, opt.Timeout)
+		}
+		if _, stderr, err := e.Execute(compactCommand(inst, cf, opt), false, timeout...); err != nil {
+			return errors.Annotatef(err, "failed to compact column family %s of %s: %s", cf, inst.ID(), strings.TrimSpace(string(stderr)))
+		}
+		log.Infof("\tCompacted column family %s of %s in %s", cf, inst.ID(), time.Since(start).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+type compactSuite struct{}
+
+var _ = Suite(&compactSuite{})
+
+// mockStores serves the stores of PD, the leaders of a store are evicted once the evicting
+// scheduler of it is added. All the requests and commands are recorded to the events.
+type mockStores struct {
+	mu      sync.Mutex
+	stores  map[string]uint64 // address -> id
+	leaders map[uint64]int
+	events  []string
+}
+
+func (m *mockStores) record(event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *mockStores) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case r.URL.Path == "/pd/api/v1/stores":
+		var stores []map[string]interface{}
+		for address, id := range m.stores {
+			stores = append(stores, map[string]interface{}{
+				"store":  map[string]interface{}{"id": id, "address": address},
+				"status": map[string]interface{}{"leader_count": m.leaders[id]},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"count": len(stores), "stores": stores})
+	case r.URL.Path == "/pd/api/v1/schedulers" && r.Method == http.MethodPost:
+		var req struct {
+			StoreID uint64 `json:"store_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		m.leaders[req.StoreID] = 0
+		m.events = append(m.events, fmt.Sprintf("evict %d", req.StoreID))
+	case strings.HasPrefix(r.URL.Path, "/pd/api/v1/schedulers/evict-leader-scheduler-") && r.Method == http.MethodDelete:
+		m.events = append(m.events, "remove "+strings.TrimPrefix(r.URL.Path, "/pd/api/v1/schedulers/evict-leader-scheduler-"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// compactExecutor records the compaction commands to the stores, the compaction of the
+// failed column family fails
+type compactExecutor struct {
+	stores *mockStores
+	failed string
+}
+
+func (e *compactExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.stores.record(cmd)
+	if e.failed != "" && strings.Contains(cmd, " -c "+e.failed) {
+		return nil, []byte("compact failed"), errors.New("exit status 1")
+	}
+	return nil, nil, nil
+}
+
+func (e *compactExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func compactCluster(c *C) (*mockStores, *meta.Specification, func()) {
+	m := &mockStores{
+		stores:  map[string]uint64{"127.0.0.1:20160": 1, "127.0.0.1:20161": 4},
+		leaders: map[uint64]int{1: 10, 4: 20},
+	}
+	server := httptest.NewServer(m)
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: `+serverPort(c, server.Listener.Addr().String())+`
+tikv_servers:
+  - host: 127.0.0.1
+    deploy_dir: /home/tidb/deploy/tikv-20160
+  - host: 127.0.0.1
+    port: 20161
+    status_port: 20181
+    deploy_dir: /home/tidb/deploy/tikv-20161/
+`), topo), IsNil)
+	return m, topo, server.Close
+}
+
+func (s *compactSuite) TestCompactTiKV(c *C) {
+	m, topo, closer := compactCluster(c)
+	defer closer()
+
+	getter := hostGetter{"127.0.0.1": &compactExecutor{stores: m}}
+	opt := CompactOptions{CFs: []string{"default", "write"}, Threads: 4, Bottommost: true, Timeout: time.Minute}
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second}
+	insts := (&meta.TiKVComponent{Specification: topo}).Instances()
+	c.Assert(CompactTiKV(getter, topo, insts, opt, retryOpt), IsNil)
+
+	// one store at a time, with its leaders evicted
+	c.Assert(m.events, DeepEquals, []string{
+		"evict 1",
+		"/home/tidb/deploy/tikv-20160/bin/tikv-ctl --host 127.0.0.1:20160 compact -d kv -c default --threads 4 --bottommost force",
+		"/home/tidb/deploy/tikv-20160/bin/tikv-ctl --host 127.0.0.1:20160 compact -d kv -c write --threads 4 --bottommost force",
+		"remove 1",
+		"evict 4",
+		"/home/tidb/deploy/tikv-20161/bin/tikv-ctl --host 127.0.0.1:20161 compact -d kv -c default --threads 4 --bottommost force",
+		"/home/tidb/deploy/tikv-20161/bin/tikv-ctl --host 127.0.0.1:20161 compact -d kv -c write --threads 4 --bottommost force",
+		"remove 4",
+	})
+}
+
+func (s *compactSuite) TestCompactTiKVFailed(c *C) {
+	m, topo, closer := compactCluster(c)
+	defer closer()
+
+	// the eviction is removed and the rest are not compacted after the failure
+	getter := hostGetter{"127.0.0.1": &compactExecutor{stores: m, failed: "write"}}
+	opt := CompactOptions{CFs: []string{"default", "write", "lock"}}
+	insts := (&meta.TiKVComponent{Specification: topo}).Instances()
+	err := CompactTiKV(getter, topo, insts, opt, &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second})
+	c.Assert(err, ErrorMatches, "failed to compact column family write of 127.0.0.1:20160: compact failed: exit status 1")
+	c.Assert(m.events, DeepEquals, []string{
+		"evict 1",
+		"/home/tidb/deploy/tikv-20160/bin/tikv-ctl --host 127.0.0.1:20160 compact -d kv -c default",
+		"/home/tidb/deploy/tikv-20160/bin/tikv-ctl --host 127.0.0.1:20160 compact -d kv -c write",
+		"remove 1",
+	})
+}
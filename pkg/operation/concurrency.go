@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"golang.org/x/sync/errgroup"
+)
+
+// ConcurrencyPolicy maps a component name to the max number of its instances
+// which can be restarted at the same time during rolling operations
+type ConcurrencyPolicy map[string]int
+
+// defaultConcurrency is used for the components not set in the policy, the stateless
+// components can be restarted in small batches while the others are restarted one by one
+var defaultConcurrency = map[string]int{
+	meta.ComponentTiDB:    2,
+	meta.ComponentTiProxy: 2,
+}
+
+// Concurrency returns the max number of instances of the component which can be
+// restarted at the same time
+func (p ConcurrencyPolicy) Concurrency(component string) int {
+	if n, ok := p[component]; ok && n > 0 {
+		return n
+	}
+	if n, ok := defaultConcurrency[component]; ok {
+		return n
+	}
+	return 1
+}
+
+// defaultMaxReplicas is the number of the replicas of a region if replication.max-replicas is not set
+const defaultMaxReplicas = 3
+
+// Clamp returns the policy with the concurrency of PD and TiKV below the majority of the PD
+// instances and of the replicas of a region respectively, so that restarting a batch never
+// makes the PD cluster or a region lose its quorum
+func (p ConcurrencyPolicy) Clamp(spec *meta.Specification) ConcurrencyPolicy {
+	maxReplicas := defaultMaxReplicas
+	switch n := spec.ServerConfigs.PD["replication.max-replicas"].(type) {
+	case int:
+		maxReplicas = n
+	case uint64:
+		maxReplicas = int(n)
+	case float64:
+		maxReplicas = int(n)
+	}
+	limits := map[string]int{
+		meta.ComponentPD:   minorityOf(len(spec.PDServers)),
+		meta.ComponentTiKV: minorityOf(maxReplicas),
+	}
+
+	clamped := make(ConcurrencyPolicy, len(p))
+	for comp, n := range p {
+		clamped[comp] = n
+	}
+	for comp, limit := range limits {
+		if n := clamped.Concurrency(comp); n > limit {
+			log.Warnf("The concurrency of %s is clamped from %d to %d to keep the majority available", comp, n, limit)
+			clamped[comp] = limit
+		}
+	}
+	return clamped
+}
+
+// minorityOf returns the max number of the members which can be down without losing the majority,
+// at least 1 so that a single member can still be restarted
+func minorityOf(n int) int {
+	if n := (n - 1) / 2; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// Batches splits the instances of a component into batches which are restarted one
+// after another, the instances in the same batch are restarted at the same time
+func (p ConcurrencyPolicy) Batches(instances []meta.Instance) [][]meta.Instance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	size := p.Concurrency(instances[0].ComponentName())
+	var batches [][]meta.Instance
+	for len(instances) > size {
+		batches = append(batches, instances[:size])
+		instances = instances[size:]
+	}
+	return append(batches, instances)
+}
+
+// forEachInstance calls fn for each of the instances concurrently, at most limit of them at
+// the same time if limit is positive, and returns the first error
+func forEachInstance(instances []meta.Instance, limit int, fn func(ins meta.Instance) error) error {
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+	errg := errgroup.Group{}
+	for _, ins := range instances {
+		ins := ins
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		errg.Go(func() error {
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			return fn(ins)
+		})
+	}
+	return errg.Wait()
+}
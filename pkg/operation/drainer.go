@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// WaitDrainersSynced waits for the Drainer instances to replicate the binlogs received from
+// the pumps downstream before they are stopped, so that the checkpoints are saved and nothing
+// is replicated again after they start. The instances not responding are not running and
+// skipped. Unlike draining TiCDC, an error is returned if a drainer is still behind after the
+// timeout, so that it's not stopped unless forced.
+func WaitDrainersSynced(instances []meta.Instance, retryOpt *utils.RetryOption) error {
+	for _, inst := range instances {
+		status, err := api.GetDrainerStatus(inst.ID(), 5*time.Second, nil)
+		if err != nil {
+			log.Debugf("Ignore waiting for drainer %s, failed to get the status: %v", inst.ID(), err)
+			continue
+		}
+		if status.Synced {
+			continue
+		}
+
+		log.Infof("Waiting for drainer %s to replicate the pending binlogs, checkpoint %d, pumps at %d...",
+			inst.ID(), status.LastTS, maxPumpPos(status))
+		if err := utils.Retry(func() error {
+			status, err = api.GetDrainerStatus(inst.ID(), 5*time.Second, nil)
+			if err != nil {
+				return err
+			}
+			if status.Synced {
+				return nil
+			}
+			log.Debugf("Still waiting for drainer %s, checkpoint %d, pumps at %d", inst.ID(), status.LastTS, maxPumpPos(status))
+			return errors.New("still waiting for the drainer to be synced")
+		}, *retryOpt); err != nil {
+			if status == nil {
+				return errors.Annotatef(err, "failed to wait for drainer %s to be synced", inst.ID())
+			}
+			return errors.Errorf("drainer %s is still behind, checkpoint %d, pumps at %d, it's not stopped to keep the binlogs replicated, use --force to stop it anyway: %v",
+				inst.ID(), status.LastTS, maxPumpPos(status), err)
+		}
+	}
+	return nil
+}
+
+// maxPumpPos returns the latest commit ts the drainer received from the pumps
+func maxPumpPos(status *api.DrainerStatus) int64 {
+	var pos int64
+	for _, ts := range status.PumpPos {
+		if ts > pos {
+			pos = ts
+		}
+	}
+	return pos
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+type drainerSuite struct{}
+
+var _ = Suite(&drainerSuite{})
+
+// mockDrainer serves the status api of a drainer, which is synced after the status is polled
+// for pending times. The polls and the systemctl commands are logged in order.
+type mockDrainer struct {
+	mu      sync.Mutex
+	pending int
+	events  []string
+}
+
+func (m *mockDrainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.URL.Path != "/status" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	m.events = append(m.events, "status")
+	status := api.DrainerStatus{
+		PumpPos: map[string]int64{"pump-1": 417115799421665281, "pump-2": 417115799421665283},
+		Synced:  m.pending == 0,
+		LastTS:  417115799421665283,
+	}
+	if m.pending > 0 {
+		status.LastTS = 417115796117045249
+		m.pending--
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+func (m *mockDrainer) record(event string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *mockDrainer) log() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := m.events
+	m.events = nil
+	return events
+}
+
+// drainerRecorder logs the systemctl commands to the mocked drainer
+type drainerRecorder struct {
+	drainer *mockDrainer
+}
+
+func (e *drainerRecorder) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if strings.HasPrefix(cmd, "systemctl") {
+		e.drainer.record(cmd)
+	}
+	return nil, nil, nil
+}
+
+func (e *drainerRecorder) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+// drainerCluster starts a drainer serving the status api, and another one not running
+func drainerCluster(c *C, pending int) (*mockDrainer, *meta.Specification, func()) {
+	m := &mockDrainer{pending: pending}
+	server := httptest.NewServer(m)
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+drainer_servers:
+  - host: 127.0.0.1
+    port: `+serverPort(c, server.Listener.Addr().String())+`
+  - host: 127.0.0.1
+    port: 1
+`), topo), IsNil)
+	return m, topo, server.Close
+}
+
+func (s *drainerSuite) TestWaitDrainersSynced(c *C) {
+	m, topo, stop := drainerCluster(c, 0)
+	defer stop()
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: 200 * time.Millisecond}
+	insts := (&meta.DrainerComponent{Specification: topo}).Instances()
+
+	// it's synced, and the one not running is skipped
+	c.Assert(WaitDrainersSynced(insts, retryOpt), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{"status"})
+
+	// the pending binlogs are replicated in several polls
+	m.pending = 3
+	c.Assert(WaitDrainersSynced(insts, retryOpt), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{"status", "status", "status", "status"})
+
+	// it's still behind after the timeout
+	m.pending = 1000
+	err := WaitDrainersSynced(insts[:1], retryOpt)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, "drainer "+insts[0].ID()+" is still behind, checkpoint 417115796117045249, pumps at 417115799421665283, .*use --force to stop it anyway.*")
+}
+
+func (s *drainerSuite) TestStopWaitsDrainer(c *C) {
+	m, topo, stop := drainerCluster(c, 2)
+	defer stop()
+	getter := hostGetter{"127.0.0.1": &drainerRecorder{drainer: m}}
+	insts := (&meta.DrainerComponent{Specification: topo}).Instances()
+
+	// the drainer is stopped after it's synced
+	c.Assert(Stop(getter, topo, Options{Nodes: []string{insts[0].ID()}, Timeout: 5}), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{
+		"status", "status", "status",
+		"systemctl daemon-reload && systemctl stop " + insts[0].ServiceName(),
+	})
+
+	// it's not stopped while it's behind
+	m.pending = 1000
+	options := Options{Nodes: []string{insts[0].ID()}, Timeout: 1}
+	c.Assert(Stop(getter, topo, options), NotNil)
+	for _, event := range m.log() {
+		c.Assert(event, Equals, "status")
+	}
+
+	// unless forced
+	options.Force = true
+	c.Assert(Stop(getter, topo, options), IsNil)
+	c.Assert(m.log(), DeepEquals, []string{
+		"systemctl daemon-reload && systemctl stop " + insts[0].ServiceName(),
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+// EstimateOptions are the assumptions of estimating the time of deploying a cluster
+type EstimateOptions struct {
+	MirrorThroughput int64         // bytes per second when downloading from the mirror
+	HostThroughput   int64         // bytes per second when copying files to a host
+	HostOverhead     time.Duration // fixed cost of a step on a host over SSH
+	Parallelism      int           // max number of hosts handled at the same time, 0 means all
+}
+
+// PackageInfo is the information of the package of a component used by the estimation
+type PackageInfo struct {
+	Size   int64 // size of the package in bytes
+	Cached bool  // whether the package is in the local cache, so it's not downloaded
+}
+
+// DeployPhase is the estimated time of a phase of deploying
+type DeployPhase struct {
+	Name     string
+	Duration time.Duration
+	Detail   string
+}
+
+// DeployEstimate is the estimated time of deploying a cluster
+type DeployEstimate struct {
+	Phases []DeployPhase
+	Total  time.Duration
+}
+
+// EstimateDeploy estimates the time of deploying the topology with the packages of the
+// components. The packages are downloaded one after another from the mirror, while the
+// hosts are handled in parallel, so the time of a per-host phase is the time of the
+// busiest slot when the hosts are scheduled into the slots of the parallelism.
+func EstimateDeploy(topo *meta.Specification, packages map[string]PackageInfo, opt EstimateOptions) *DeployEstimate {
+	var hosts []string
+	hostBytes := make(map[string]int64)
+	hostInstances := make(map[string]int)
+	components := []string{meta.ComponentNodeExporter, meta.ComponentBlackboxExporter}
+	topo.IterComponent(func(comp meta.Component) {
+		if len(comp.Instances()) > 0 {
+			components = append(components, comp.Name())
+		}
+		for _, inst := range comp.Instances() {
+			host := inst.GetHost()
+			if _, found := hostInstances[host]; !found {
+				hosts = append(hosts, host)
+				// the monitoring agents are deployed to every host
+				hostBytes[host] += packages[meta.ComponentNodeExporter].Size + packages[meta.ComponentBlackboxExporter].Size
+				hostInstances[host] += 2
+			}
+			hostBytes[host] += packages[comp.Name()].Size
+			hostInstances[host]++
+		}
+	})
+
+	var downloadBytes int64
+	downloads := 0
+	for _, comp := range components {
+		if pkg := packages[comp]; !pkg.Cached {
+			downloadBytes += pkg.Size
+			downloads++
+		}
+	}
+
+	parallelism := opt.Parallelism
+	if parallelism <= 0 || parallelism > len(hosts) {
+		parallelism = len(hosts)
+	}
+
+	costs := make([]time.Duration, 0, len(hosts))
+	var copyBytes int64
+	for _, host := range hosts {
+		costs = append(costs, transferTime(hostBytes[host], opt.HostThroughput)+opt.HostOverhead*time.Duration(hostInstances[host]))
+		copyBytes += hostBytes[host]
+	}
+	perHost := make([]time.Duration, len(hosts))
+	for i := range perHost {
+		perHost[i] = opt.HostOverhead
+	}
+
+	estimate := &DeployEstimate{}
+	estimate.add(DeployPhase{
+		Name:     "Download components",
+		Duration: transferTime(downloadBytes, opt.MirrorThroughput),
+		Detail:   fmt.Sprintf("%d packages, %s", downloads, humanBytes(downloadBytes)),
+	})
+	estimate.add(DeployPhase{
+		Name:     "Initialize hosts",
+		Duration: makespan(perHost, parallelism),
+		Detail:   fmt.Sprintf("%d hosts, parallelism %d", len(hosts), parallelism),
+	})
+	estimate.add(DeployPhase{
+		Name:     "Check hosts",
+		Duration: makespan(perHost, parallelism),
+		Detail:   fmt.Sprintf("%d hosts, parallelism %d", len(hosts), parallelism),
+	})
+	estimate.add(DeployPhase{
+		Name:     "Copy files",
+		Duration: makespan(costs, parallelism),
+		Detail:   fmt.Sprintf("%s to %d hosts, parallelism %d", humanBytes(copyBytes), len(hosts), parallelism),
+	})
+	return estimate
+}
+
+func (e *DeployEstimate) add(phase DeployPhase) {
+	e.Phases = append(e.Phases, phase)
+	e.Total += phase.Duration
+}
+
+// transferTime returns the time of transferring the bytes at the throughput
+func transferTime(bytes, throughput int64) time.Duration {
+	if bytes <= 0 || throughput <= 0 {
+		return 0
+	}
+	return time.Duration(float64(bytes) / float64(throughput) * float64(time.Second))
+}
+
+// makespan returns the time of finishing all jobs by the slots, the longest jobs are
+// scheduled first and each job is assigned to the least loaded slot
+func makespan(jobs []time.Duration, slots int) time.Duration {
+	if len(jobs) == 0 || slots <= 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, jobs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+
+	loads := make([]time.Duration, slots)
+	for _, job := range sorted {
+		least := 0
+		for i := range loads {
+			if loads[i] < loads[least] {
+				least = i
+			}
+		}
+		loads[least] += job
+	}
+
+	var result time.Duration
+	for _, load := range loads {
+		if load > result {
+			result = load
+		}
+	}
+	return result
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type estimateSuite struct{}
+
+var _ = Suite(&estimateSuite{})
+
+func TestOperator(t *testing.T) {
+	TestingT(t)
+}
+
+const mib = 1024 * 1024
+
+var estimatePackages = map[string]PackageInfo{
+	meta.ComponentPD:               {Size: 30 * mib},
+	meta.ComponentTiKV:             {Size: 100 * mib},
+	meta.ComponentTiDB:             {Size: 40 * mib, Cached: true},
+	meta.ComponentNodeExporter:     {Size: 5 * mib},
+	meta.ComponentBlackboxExporter: {Size: 5 * mib},
+}
+
+// tikvTopology returns a topology with a PD and the number of TiKV hosts
+func tikvTopology(c *C, hosts int) *meta.Specification {
+	var lines []string
+	for i := 0; i < hosts; i++ {
+		lines = append(lines, fmt.Sprintf("  - host: 172.16.5.%d", 140+i))
+	}
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.100
+tidb_servers:
+  - host: 172.16.5.100
+tikv_servers:
+`+strings.Join(lines, "\n")), topo), IsNil)
+	return topo
+}
+
+func phase(e *DeployEstimate, name string) DeployPhase {
+	for _, p := range e.Phases {
+		if p.Name == name {
+			return p
+		}
+	}
+	return DeployPhase{}
+}
+
+func (s *estimateSuite) TestEstimateDeploy(c *C) {
+	opt := EstimateOptions{
+		MirrorThroughput: 10 * mib,
+		HostThroughput:   10 * mib,
+		HostOverhead:     time.Second,
+	}
+	e := EstimateDeploy(tikvTopology(c, 3), estimatePackages, opt)
+
+	// the cached TiDB package is not downloaded
+	download := phase(e, "Download components")
+	c.Assert(download.Duration, Equals, 14*time.Second)
+	c.Assert(download.Detail, Equals, "4 packages, 140.0 MiB")
+
+	// a TiKV host with the agents takes 110MiB and 3 steps, which is busier than the one
+	// with PD, TiDB and the agents
+	copy := phase(e, "Copy files")
+	c.Assert(copy.Duration, Equals, 14*time.Second)
+	c.Assert(copy.Detail, Equals, "410.0 MiB to 4 hosts, parallelism 4")
+	c.Assert(phase(e, "Initialize hosts").Duration, Equals, time.Second)
+
+	var total time.Duration
+	for _, p := range e.Phases {
+		total += p.Duration
+	}
+	c.Assert(e.Total, Equals, total)
+}
+
+func (s *estimateSuite) TestEstimateScale(c *C) {
+	opt := EstimateOptions{
+		MirrorThroughput: 10 * mib,
+		HostThroughput:   10 * mib,
+		HostOverhead:     time.Second,
+		Parallelism:      2,
+	}
+
+	// each TiKV host takes 11s+3s to copy, and the hosts are copied 2 by 2
+	small := EstimateDeploy(tikvTopology(c, 4), estimatePackages, opt)
+	large := EstimateDeploy(tikvTopology(c, 8), estimatePackages, opt)
+	c.Assert(phase(small, "Copy files").Duration, Equals, 40*time.Second)
+	c.Assert(phase(large, "Copy files").Duration, Equals, 68*time.Second)
+	c.Assert(phase(large, "Initialize hosts").Duration, Equals, 5*time.Second)
+	c.Assert(large.Total > small.Total, IsTrue)
+	// the downloading doesn't depend on the hosts
+	c.Assert(phase(large, "Download components").Duration, Equals, phase(small, "Download components").Duration)
+
+	// more parallelism, less time
+	opt.Parallelism = 4
+	faster := EstimateDeploy(tikvTopology(c, 8), estimatePackages, opt)
+	c.Assert(phase(faster, "Copy files").Duration, Equals, 40*time.Second)
+	c.Assert(faster.Total < large.Total, IsTrue)
+
+	// the parallelism is bounded by the hosts
+	opt.Parallelism = 100
+	all := EstimateDeploy(tikvTopology(c, 8), estimatePackages, opt)
+	opt.Parallelism = 0
+	c.Assert(EstimateDeploy(tikvTopology(c, 8), estimatePackages, opt), DeepEquals, all)
+	c.Assert(phase(all, "Copy files").Duration, Equals, 14*time.Second)
+}
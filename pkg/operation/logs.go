@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+// DefaultMaxTails is the default max number of the logs tailed at the same time
+const DefaultMaxTails = 32
+
+// TailOptions are the options of tailing the logs of the instances
+type TailOptions struct {
+	Lines    int    // the number of the last lines shown before following
+	File     string // the name of the log file in the log directory, <component>.log if empty
+	MaxTails int    // the max number of the logs tailed at the same time
+}
+
+// TailLogs follows the logs of the instances until ctx is done, the lines are written to out
+// prefixed with the component and the instance. The remote tails are terminated once ctx is
+// done. Tailing the other logs goes on if one of them fails, and the first failure is
+// returned after all of them end.
+func TailLogs(
+	ctx context.Context,
+	getter ExecutorGetter,
+	instances []meta.Instance,
+	deployUser string,
+	opt TailOptions,
+	out io.Writer,
+) error {
+	maxTails := opt.MaxTails
+	if maxTails <= 0 {
+		maxTails = DefaultMaxTails
+	}
+	// the tails never end by themselves, the ones waiting for a slot would never start
+	if len(instances) > maxTails {
+		return errors.Errorf("%d instances are selected, at most %d logs can be tailed at the same time", len(instances), maxTails)
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	writeLine := func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.WriteString(out, line+"\n")
+	}
+	for _, inst := range instances {
+		inst := inst
+		file := opt.File
+		if file == "" {
+			file = inst.ComponentName() + ".log"
+		}
+		path := filepath.Join(clusterutil.Abs(deployUser, inst.LogDir()), file)
+		prefix := fmt.Sprintf("[%s %s] ", inst.ComponentName(), inst.ID())
+		cmd := fmt.Sprintf("tail -n %d -F %s", opt.Lines, path)
+		e := getter.Get(inst.GetHost())
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := executor.Stream(ctx, e, cmd, false, func(line string) {
+				writeLine(prefix + line)
+			})
+			if err == nil {
+				return
+			}
+			writeLine(fmt.Sprintf("%serror: %s", prefix, err))
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = errors.Annotatef(err, "failed to tail %s on %s", path, inst.GetHost())
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
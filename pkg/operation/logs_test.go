@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type logsSuite struct{}
+
+var _ = Suite(&logsSuite{})
+
+// tailExecutor emits a line of the log every interval until the tail is cancelled
+type tailExecutor struct {
+	versionExecutor
+	host     string
+	interval time.Duration
+
+	mu     sync.Mutex
+	cmds   []string
+	closed bool
+}
+
+func (e *tailExecutor) Stream(ctx context.Context, cmd string, sudo bool, fn func(line string)) error {
+	e.mu.Lock()
+	e.cmds = append(e.cmds, cmd)
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.closed = true
+		e.mu.Unlock()
+	}()
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.interval):
+			fn(fmt.Sprintf("line %d of %s", i, e.host))
+		}
+	}
+}
+
+type hostGetter map[string]executor.TiOpsExecutor
+
+func (g hostGetter) Get(host string) executor.TiOpsExecutor {
+	return g[host]
+}
+
+// syncBuffer is written by the tails concurrently and read by the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func tailInstances(c *C) []meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+    log_dir: /data/tikv-20160/log
+  - host: 172.16.5.141
+`), topo), IsNil)
+	var insts []meta.Instance
+	topo.IterInstance(func(inst meta.Instance) {
+		insts = append(insts, inst)
+	})
+	return insts
+}
+
+func (s *logsSuite) TestTailLogs(c *C) {
+	executors := map[string]*tailExecutor{
+		"172.16.5.140": {host: "172.16.5.140", interval: 5 * time.Millisecond},
+		"172.16.5.141": {host: "172.16.5.141", interval: 7 * time.Millisecond},
+	}
+	getter := hostGetter{}
+	for host, e := range executors {
+		getter[host] = e
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- TailLogs(ctx, getter, tailInstances(c), "tidb", TailOptions{Lines: 20}, out)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		c.Assert(err, IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the tails are not closed after cancellation")
+	}
+
+	c.Assert(executors["172.16.5.140"].cmds, DeepEquals, []string{"tail -n 20 -F /data/tikv-20160/log/tikv.log"})
+	c.Assert(executors["172.16.5.141"].cmds, DeepEquals, []string{"tail -n 20 -F /home/tidb/deploy/tikv-20160/log/tikv.log"})
+	for _, e := range executors {
+		c.Assert(e.closed, IsTrue)
+	}
+
+	// the lines of both logs are merged, each line is written whole with its prefix
+	lineRegexp := regexp.MustCompile(`^\[tikv (172\.16\.5\.14[01]):20160\] line \d+ of (172\.16\.5\.14[01])$`)
+	hosts := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		m := lineRegexp.FindStringSubmatch(line)
+		c.Assert(m, NotNil, Commentf("unexpected line %q", line))
+		c.Assert(m[1], Equals, m[2])
+		hosts[m[1]] = true
+	}
+	c.Assert(hosts, DeepEquals, map[string]bool{"172.16.5.140": true, "172.16.5.141": true})
+}
+
+func (s *logsSuite) TestTailLogsFailed(c *C) {
+	// streaming is not supported by the executor of 172.16.5.141
+	e := &tailExecutor{host: "172.16.5.140", interval: time.Millisecond}
+	getter := hostGetter{"172.16.5.140": e, "172.16.5.141": versionExecutor{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out := &syncBuffer{}
+	err := TailLogs(ctx, getter, tailInstances(c), "tidb", TailOptions{File: "tikv_stderr.log"}, out)
+	c.Assert(err, ErrorMatches, "failed to tail /home/tidb/deploy/tikv-20160/log/tikv_stderr.log on 172.16.5.141: the executor .* doesn't support streaming")
+	// the other one goes on until cancelled
+	c.Assert(e.closed, IsTrue)
+	c.Assert(out.String(), Matches, `(?s).*\[tikv 172\.16\.5\.141:20160\] error: .*`)
+	c.Assert(out.String(), Matches, `(?s).*\[tikv 172\.16\.5\.140:20160\] line 0 of 172\.16\.5\.140.*`)
+
+	// too many
+	err = TailLogs(context.Background(), getter, tailInstances(c), "tidb", TailOptions{MaxTails: 1}, out)
+	c.Assert(err, ErrorMatches, "2 instances are selected, at most 1 logs can be tailed at the same time")
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+// ParseNodeFile parses the entries of a node file, an entry is either a host or the ID of
+// an instance like 172.16.5.140:20160. The entries are separated by lines, commas or spaces,
+// and the rest of a line after # is a comment.
+func ParseNodeFile(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		entries = append(entries, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.AddStack(err)
+	}
+	return entries, nil
+}
+
+// ResolveNodes resolves the entries to the IDs of the instances in the topology in the start
+// order, a host entry selects all the instances on the host. The entries matching no instance
+// are returned as unknown.
+func ResolveNodes(spec *meta.Specification, entries []string) (nodes []string, unknown []string) {
+	matched := set.NewStringSet()
+	selected := set.NewStringSet()
+	spec.IterInstance(func(inst meta.Instance) {
+		host := inst.GetHost()
+		for _, entry := range entries {
+			if entry != inst.ID() && strings.Trim(entry, "[]") != host {
+				continue
+			}
+			matched.Insert(entry)
+			if !selected.Exist(inst.ID()) {
+				selected.Insert(inst.ID())
+				nodes = append(nodes, inst.ID())
+			}
+		}
+	})
+	reported := set.NewStringSet()
+	for _, entry := range entries {
+		if !matched.Exist(entry) && !reported.Exist(entry) {
+			reported.Insert(entry)
+			unknown = append(unknown, entry)
+		}
+	}
+	return nodes, unknown
+}
+
+// NodesFromFile returns the nodes listed in the node file, intersected with the nodes given
+// explicitly if any. The entries of the file not in the topology are reported as an error
+// rather than ignored, as the file may be generated for another cluster.
+func NodesFromFile(spec *meta.Specification, path string, nodes []string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to open the node file")
+	}
+	defer f.Close()
+	entries, err := ParseNodeFile(f)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read the node file %s", path)
+	}
+	if len(entries) == 0 {
+		return nil, errors.Errorf("the node file %s lists no node", path)
+	}
+
+	resolved, unknown := ResolveNodes(spec, entries)
+	if len(unknown) > 0 {
+		return nil, errors.Errorf("%d entries of the node file %s are not hosts or nodes of the cluster: %s",
+			len(unknown), path, strings.Join(unknown, ", "))
+	}
+	if len(nodes) == 0 {
+		return resolved, nil
+	}
+
+	given := set.NewStringSet(nodes...)
+	var result []string
+	for _, node := range resolved {
+		if given.Exist(node) {
+			result = append(result, node)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.Errorf("none of the nodes of the node file %s is specified by --node", path)
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type nodeFileSuite struct{}
+
+var _ = Suite(&nodeFileSuite{})
+
+func nodeFileTopology(c *C) *meta.Specification {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.141
+    port: 20161
+    status_port: 20181
+tidb_servers:
+  - host: 172.16.5.142
+`), topo), IsNil)
+	return topo
+}
+
+func (s *nodeFileSuite) TestParseNodeFile(c *C) {
+	entries, err := ParseNodeFile(strings.NewReader(`# the hosts to restart
+172.16.5.140
+172.16.5.141:20160, 172.16.5.141:20161	172.16.5.142 # the tidb
+
+  ,
+`))
+	c.Assert(err, IsNil)
+	c.Assert(entries, DeepEquals, []string{"172.16.5.140", "172.16.5.141:20160", "172.16.5.141:20161", "172.16.5.142"})
+
+	entries, err = ParseNodeFile(strings.NewReader("# nothing\n\n"))
+	c.Assert(err, IsNil)
+	c.Assert(entries, HasLen, 0)
+}
+
+func (s *nodeFileSuite) TestResolveNodes(c *C) {
+	topo := nodeFileTopology(c)
+
+	// a host selects all the instances on it
+	nodes, unknown := ResolveNodes(topo, []string{"172.16.5.142", "172.16.5.141:20161", "172.16.5.140"})
+	c.Assert(nodes, DeepEquals, []string{"172.16.5.140:2379", "172.16.5.140:20160", "172.16.5.141:20161", "172.16.5.142:4000"})
+	c.Assert(unknown, HasLen, 0)
+
+	// the duplicated nodes are selected once and the unknown entries reported once
+	nodes, unknown = ResolveNodes(topo, []string{"172.16.5.141", "172.16.5.141:20160", "172.16.5.143", "172.16.5.140:4000", "172.16.5.143"})
+	c.Assert(nodes, DeepEquals, []string{"172.16.5.141:20160", "172.16.5.141:20161"})
+	c.Assert(unknown, DeepEquals, []string{"172.16.5.143", "172.16.5.140:4000"})
+}
+
+func (s *nodeFileSuite) TestNodesFromFile(c *C) {
+	topo := nodeFileTopology(c)
+	dir, err := ioutil.TempDir("", "node-file")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	write := func(content string) string {
+		path := filepath.Join(dir, "nodes")
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+		return path
+	}
+
+	path := write("172.16.5.141\n172.16.5.142:4000\n")
+	nodes, err := NodesFromFile(topo, path, nil)
+	c.Assert(err, IsNil)
+	c.Assert(nodes, DeepEquals, []string{"172.16.5.141:20160", "172.16.5.141:20161", "172.16.5.142:4000"})
+
+	// intersected with the given nodes
+	nodes, err = NodesFromFile(topo, path, []string{"172.16.5.140:2379", "172.16.5.141:20161"})
+	c.Assert(err, IsNil)
+	c.Assert(nodes, DeepEquals, []string{"172.16.5.141:20161"})
+
+	_, err = NodesFromFile(topo, path, []string{"172.16.5.140:2379"})
+	c.Assert(err, ErrorMatches, "none of the nodes of the node file .* is specified by --node")
+
+	// the unknown entries are reported
+	path = write("172.16.5.141\n172.16.5.150\n172.16.5.142:4001\n")
+	_, err = NodesFromFile(topo, path, nil)
+	c.Assert(err, ErrorMatches, "2 entries of the node file .* are not hosts or nodes of the cluster: 172.16.5.150, 172.16.5.142:4001")
+
+	path = write("# empty\n")
+	_, err = NodesFromFile(topo, path, nil)
+	c.Assert(err, ErrorMatches, "the node file .* lists no node")
+
+	_, err = NodesFromFile(topo, filepath.Join(dir, "absent"), nil)
+	c.Assert(err, ErrorMatches, "failed to open the node file.*")
+}
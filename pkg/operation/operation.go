@@ -15,6 +15,7 @@ package operator
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
@@ -27,6 +28,35 @@ type Options struct {
 	Nodes   []string
 	Force   bool  // Option for upgrade subcommand
 	Timeout int64 // timeout in seconds for operations that support it, not to confuse with SSH timeout
+
+	// Unreachable means the hosts of the nodes to scale in are down, so the nodes are just
+	// removed from the cluster without stopping and destroying them
+	Unreachable bool
+
+	// ZoneLabel is the label of the TiKV instances by which the instances are upgraded or
+	// restarted zone by zone, the instances of a zone are done before the next zone
+	ZoneLabel string
+
+	// GracePeriod is the seconds waited for the instances to exit after SIGTERM before
+	// killing them by SIGKILL, they are stopped by systemd without escalation if it's zero
+	GracePeriod int64
+
+	// UpgradeState records the state of each instance in the upgrade, the instances done are
+	// skipped so that an interrupted upgrade is resumed where it stopped
+	UpgradeState UpgradeState
+
+	// Concurrency is the max number of the instances of a component started or stopped at
+	// the same time, 0 means all of them
+	Concurrency int
+}
+
+// StopPolicy returns the policy to stop the instances, ok is false if the instances are
+// stopped by systemd without escalation
+func (o Options) StopPolicy() (policy StopPolicy, ok bool) {
+	if o.GracePeriod <= 0 {
+		return StopPolicy{}, false
+	}
+	return StopPolicy{GracePeriod: time.Duration(o.GracePeriod) * time.Second}, true
 }
 
 // Operation represents the type of cluster operation
@@ -0,0 +1,221 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// StaleMember is a member of the PD cluster which is not in the topology
+type StaleMember struct {
+	Name       string
+	ClientURLs []string
+	Healthy    bool
+	Leader     bool
+	Removed    bool
+	Err        error // the member is not removed because of it
+}
+
+// PDMemberReport is the result of reconciling the PD member list with the topology
+type PDMemberReport struct {
+	Stale []StaleMember
+	// Missing are the IDs of the running PD instances of the topology which are not
+	// members of the cluster
+	Missing []string
+}
+
+// Consistent returns whether the member list matches the topology after reconciling
+func (r *PDMemberReport) Consistent() bool {
+	if len(r.Missing) > 0 {
+		return false
+	}
+	for _, m := range r.Stale {
+		if !m.Removed {
+			return false
+		}
+	}
+	return true
+}
+
+// ReconcilePDMembers compares the member list of the PD cluster with the PD instances of
+// the topology, a member is matched by its name or client URLs. The stale members are
+// removed if remove is set, but a healthy member or the leader is never removed, and
+// nothing is removed if none of the topology is a member as the topology is probably not
+// the one of the cluster.
+func ReconcilePDMembers(
+	spec *meta.Specification,
+	remove bool,
+	timeout time.Duration,
+	retryOpt *utils.RetryOption,
+) (*PDMemberReport, error) {
+	pdClient := api.NewPDClient(spec.GetPDList(), timeout, nil)
+	members, err := pdClient.GetMembers()
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to get the members of PD")
+	}
+	healthInfo, err := pdClient.GetHealth()
+	if err != nil {
+		return nil, errors.Annotate(err, "failed to get the health of PD members")
+	}
+	healthy := make(map[string]bool)
+	for _, h := range healthInfo.Healths {
+		healthy[h.Name] = h.Health
+	}
+
+	instances := make(map[string]*meta.PDInstance)
+	for _, inst := range (&meta.PDComponent{Specification: spec}).Instances() {
+		instances[inst.ID()] = inst.(*meta.PDInstance)
+	}
+
+	report := &PDMemberReport{}
+	matched := make(map[string]bool)
+	for _, m := range members.Members {
+		if id := matchPDInstance(m, instances); id != "" {
+			matched[id] = true
+			continue
+		}
+		report.Stale = append(report.Stale, StaleMember{
+			Name:       m.Name,
+			ClientURLs: m.ClientUrls,
+			Healthy:    healthy[m.Name],
+			Leader:     members.Leader != nil && members.Leader.Name == m.Name,
+		})
+	}
+
+	client := utils.NewHTTPClient(timeout, nil)
+	for _, inst := range (&meta.PDComponent{Specification: spec}).Instances() {
+		if matched[inst.ID()] {
+			continue
+		}
+		// the instance is down if it can't answer, it's expected to be absent
+		if _, err := statusVersion(client, fmt.Sprintf("http://%s/%s", inst.ID(), pdVersionURI)); err == nil {
+			report.Missing = append(report.Missing, inst.ID())
+		}
+	}
+
+	if !remove {
+		return report, nil
+	}
+	for i := range report.Stale {
+		m := &report.Stale[i]
+		switch {
+		case len(matched) == 0:
+			m.Err = errors.New("none of the PD instances of the topology is a member")
+		case m.Leader:
+			m.Err = errors.New("it's the leader")
+		case m.Healthy:
+			m.Err = errors.New("it's healthy")
+		default:
+			if err := pdClient.DelPD(m.Name, retryOpt); err != nil {
+				m.Err = err
+				continue
+			}
+			m.Removed = true
+		}
+	}
+	return report, nil
+}
+
+// matchPDInstance returns the ID of the instance which is the member, empty if not found
+func matchPDInstance(m *pdpb.Member, instances map[string]*meta.PDInstance) string {
+	for id, inst := range instances {
+		if inst.Name != "" && inst.Name == m.Name {
+			return id
+		}
+	}
+	for _, u := range m.ClientUrls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		if _, found := instances[parsed.Host]; found {
+			return parsed.Host
+		}
+	}
+	return ""
+}
+
+// TransferPDLeader transfers the leadership of the PD cluster to another member before the
+// instance is stopped if it's the leader, to avoid the election and TSO hiccup caused by losing
+// the leader. The target is a healthy member not in stopping, the names of all the PD instances
+// stopped in the same operation, so that the leadership doesn't move to one stopped next or
+// already down. The target is returned, empty if no transfer happened.
+func TransferPDLeader(pdList []string, inst *meta.PDInstance, stopping []string, retryOpt *utils.RetryOption) (string, error) {
+	pdClient := api.NewPDClient(pdList, 5*time.Second, nil)
+
+	members, err := pdClient.GetMembers()
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to get members of PD cluster")
+	}
+	if members.Leader == nil || members.Leader.Name != inst.Name {
+		return "", nil
+	}
+
+	healths, err := pdClient.GetHealth()
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to get the health of PD members")
+	}
+	healthy := make(map[string]bool)
+	for _, h := range healths.Healths {
+		healthy[h.Name] = h.Health
+	}
+	excluded := set.NewStringSet(stopping...)
+	excluded.Insert(inst.Name)
+
+	var target string
+	for _, member := range members.Members {
+		if !excluded.Exist(member.Name) && healthy[member.Name] {
+			target = member.Name
+			break
+		}
+	}
+	if target == "" {
+		log.Warnf("No healthy PD member is kept running, stop the leader %s without transferring", inst.Name)
+		return "", nil
+	}
+
+	log.Infof("\tTransferring PD leader from %s to %s", inst.Name, target)
+	if err := pdClient.TransferPDLeader(target, retryOpt); err != nil {
+		return "", errors.Annotatef(err, "failed to transfer PD leader from %s", inst.Name)
+	}
+	return target, nil
+}
+
+// transferPDLeaderBeforeStop transfers the leadership away from the PD instances to be stopped,
+// nothing is transferred if all the PD instances are stopped as no member is kept serving
+func transferPDLeaderBeforeStop(spec *meta.Specification, insts []meta.Instance, retryOpt *utils.RetryOption) error {
+	if len(insts) == 0 || len(insts) >= len(spec.PDServers) {
+		return nil
+	}
+	var stopping []string
+	for _, inst := range insts {
+		stopping = append(stopping, inst.(*meta.PDInstance).Name)
+	}
+	for _, inst := range insts {
+		if _, err := TransferPDLeader(spec.GetPDList(), inst.(*meta.PDInstance), stopping, retryOpt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+type pdMemberSuite struct{}
+
+var _ = Suite(&pdMemberSuite{})
+
+// mockPD serves the member list, the members can be deleted by name
+type mockPD struct {
+	mu      sync.Mutex
+	leader  string
+	members []*pdpb.Member
+	healthy map[string]bool
+	deleted []string
+	// the members which the leadership is transferred to
+	transfers []string
+}
+
+func (p *mockPD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case r.URL.Path == "/pd/api/v1/version":
+		fmt.Fprint(w, `{"version":"v4.0.0"}`)
+	case r.URL.Path == "/pd/api/v1/members" && r.Method == http.MethodGet:
+		resp := &pdpb.GetMembersResponse{Members: p.members}
+		for _, m := range p.members {
+			if m.Name == p.leader {
+				resp.Leader = m
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case strings.HasPrefix(r.URL.Path, "/pd/api/v1/members/name/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(r.URL.Path, "/pd/api/v1/members/name/")
+		var members []*pdpb.Member
+		for _, m := range p.members {
+			if m.Name != name {
+				members = append(members, m)
+			}
+		}
+		p.members = members
+		p.deleted = append(p.deleted, name)
+	case r.URL.Path == "/pd/api/v1/leader":
+		_ = json.NewEncoder(w).Encode(pdpb.Member{Name: p.leader})
+	case strings.HasPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/") && r.Method == http.MethodPost:
+		p.leader = strings.TrimPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/")
+		p.transfers = append(p.transfers, p.leader)
+	case r.URL.Path == "/pd/health":
+		var healths []map[string]interface{}
+		for _, m := range p.members {
+			healths = append(healths, map[string]interface{}{"name": m.Name, "health": p.healthy[m.Name]})
+		}
+		_ = json.NewEncoder(w).Encode(healths)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func serverPort(c *C, addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	c.Assert(err, IsNil)
+	return port
+}
+
+func (s *pdMemberSuite) TestReconcilePDMembers(c *C) {
+	pd := &mockPD{}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	// running but not a member, e.g. it's bootstrapped as another cluster
+	alone := httptest.NewServer(&mockPD{})
+	defer alone.Close()
+	// down
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	downPort := serverPort(c, l.Addr().String())
+	c.Assert(l.Close(), IsNil)
+
+	pd.leader = "pd-1"
+	pd.members = []*pdpb.Member{
+		{Name: "pd-1", ClientUrls: []string{server.URL}},
+		{Name: "pd-stale-2", ClientUrls: []string{"http://172.16.5.141:2379"}},
+		{Name: "pd-stale-3", ClientUrls: []string{"http://172.16.5.142:2379"}},
+	}
+	pd.healthy = map[string]bool{"pd-1": true, "pd-stale-3": true}
+
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: `+serverPort(c, server.Listener.Addr().String())+`
+  - host: 127.0.0.1
+    client_port: `+serverPort(c, alone.Listener.Addr().String())+`
+    peer_port: 2381
+  - host: 127.0.0.1
+    client_port: `+downPort+`
+    peer_port: 2382
+`), topo), IsNil)
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second}
+
+	// report only
+	report, err := ReconcilePDMembers(topo, false, time.Second, retryOpt)
+	c.Assert(err, IsNil)
+	c.Assert(report.Stale, HasLen, 2)
+	c.Assert(report.Stale[0].Name, Equals, "pd-stale-2")
+	c.Assert(report.Stale[0].Healthy, IsFalse)
+	c.Assert(report.Stale[1].Name, Equals, "pd-stale-3")
+	c.Assert(report.Stale[1].Healthy, IsTrue)
+	c.Assert(report.Missing, DeepEquals, []string{alone.Listener.Addr().String()})
+	c.Assert(report.Consistent(), IsFalse)
+	c.Assert(pd.deleted, HasLen, 0)
+
+	// only the unhealthy stale member is removed
+	report, err = ReconcilePDMembers(topo, true, time.Second, retryOpt)
+	c.Assert(err, IsNil)
+	c.Assert(report.Stale[0].Removed, IsTrue)
+	c.Assert(report.Stale[1].Removed, IsFalse)
+	c.Assert(report.Stale[1].Err, ErrorMatches, "it's healthy")
+	c.Assert(pd.deleted, DeepEquals, []string{"pd-stale-2"})
+	c.Assert(pd.members, HasLen, 2)
+}
+
+func (s *pdMemberSuite) TestReconcilePDMembersUnrelated(c *C) {
+	pd := &mockPD{
+		leader: "pd-1",
+		members: []*pdpb.Member{
+			{Name: "pd-1", ClientUrls: []string{"http://172.16.5.140:2379"}},
+			{Name: "pd-2", ClientUrls: []string{"http://172.16.5.141:2379"}},
+		},
+		healthy: map[string]bool{"pd-1": true},
+	}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+
+	// the topology is not the one of the cluster, nothing is removed
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: `+serverPort(c, server.Listener.Addr().String())+`
+`), topo), IsNil)
+	report, err := ReconcilePDMembers(topo, true, time.Second, nil)
+	c.Assert(err, IsNil)
+	c.Assert(report.Stale, HasLen, 2)
+	c.Assert(report.Stale[0].Leader, IsTrue)
+	for _, m := range report.Stale {
+		c.Assert(m.Removed, IsFalse)
+		c.Assert(m.Err, ErrorMatches, "none of the PD instances of the topology is a member")
+	}
+	c.Assert(pd.deleted, HasLen, 0)
+	c.Assert(report.Missing, DeepEquals, []string{server.Listener.Addr().String()})
+}
+
+func (s *pdMemberSuite) TestTransferPDLeaderBeforeStop(c *C) {
+	pd := &mockPD{leader: "pd-1"}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	for _, name := range []string{"pd-1", "pd-2", "pd-3"} {
+		pd.members = append(pd.members, &pdpb.Member{Name: name})
+	}
+	pd.healthy = map[string]bool{"pd-1": true, "pd-2": true, "pd-3": true}
+
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    name: pd-1
+    client_port: `+serverPort(c, server.Listener.Addr().String())+`
+  - host: 172.16.5.141
+    name: pd-2
+  - host: 172.16.5.142
+    name: pd-3
+`), topo), IsNil)
+	insts := (&meta.PDComponent{Specification: topo}).Instances()
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second}
+
+	// the leadership doesn't move to pd-2 which is stopped too
+	c.Assert(transferPDLeaderBeforeStop(topo, insts[:2], retryOpt), IsNil)
+	c.Assert(pd.transfers, DeepEquals, []string{"pd-3"})
+
+	// nothing is transferred if all the members are stopped
+	pd.leader = "pd-1"
+	pd.transfers = nil
+	c.Assert(transferPDLeaderBeforeStop(topo, insts, retryOpt), IsNil)
+	c.Assert(pd.transfers, HasLen, 0)
+}
@@ -14,7 +14,6 @@
 package operator
 
 import (
-	"strconv"
 	"time"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
@@ -149,6 +148,11 @@ func ScaleIn(
 		Delay:   time.Second * 5,
 	}
 
+	// keep the PD leader on the members kept running before deleting the members
+	if err := transferPDLeaderBeforeStop(spec, deletedDiff[meta.ComponentPD], timeoutOpt); err != nil {
+		return err
+	}
+
 	// Delete member from cluster
 	for _, component := range spec.ComponentsByStartOrder() {
 		for _, instance := range component.Instances() {
@@ -166,17 +170,26 @@ func ScaleIn(
 					return err
 				}
 			case meta.ComponentDrainer:
-				addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
+				addr := utils.JoinHostPort(instance.GetHost(), instance.GetPort())
 				err := binlogClient.OfflineDrainer(addr, addr)
 				if err != nil {
 					return errors.AddStack(err)
 				}
 			case meta.ComponentPump:
-				addr := instance.GetHost() + ":" + strconv.Itoa(instance.GetPort())
+				addr := utils.JoinHostPort(instance.GetHost(), instance.GetPort())
 				err := binlogClient.OfflineDrainer(addr, addr)
 				if err != nil {
 					return errors.AddStack(err)
 				}
+			case meta.ComponentCDC:
+				if err := DrainCDC(spec, []meta.Instance{instance}, timeoutOpt); err != nil {
+					return err
+				}
+			}
+
+			if options.Unreachable {
+				log.Warnf("The host of %s is unreachable, the files of it are left on %s", instance.ID(), instance.GetHost())
+				continue
 			}
 
 			if !asyncOfflineComps.Exist(instance.ComponentName()) {
@@ -193,9 +206,14 @@ func ScaleIn(
 		}
 	}
 
+	// the unreachable nodes can't be destroyed after they become tombstone
+	if options.Unreachable {
+		return nil
+	}
+
 	for i := 0; i < len(spec.TiKVServers); i++ {
 		s := spec.TiKVServers[i]
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 		if !deletedNodes.Exist(id) {
 			continue
 		}
@@ -205,7 +223,7 @@ func ScaleIn(
 
 	for i := 0; i < len(spec.PumpServers); i++ {
 		s := spec.PumpServers[i]
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 		if !deletedNodes.Exist(id) {
 			continue
 		}
@@ -215,7 +233,7 @@ func ScaleIn(
 
 	for i := 0; i < len(spec.Drainers); i++ {
 		s := spec.Drainers[i]
-		id := s.Host + ":" + strconv.Itoa(s.Port)
+		id := utils.JoinHostPort(s.Host, s.Port)
 		if !deletedNodes.Exist(id) {
 			continue
 		}
@@ -0,0 +1,122 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+const defaultMetricsPath = "/metrics"
+
+// ScrapeTarget is an endpoint of the metrics of an instance, the labels have the cluster and
+// the job, which is the same as the one of the bundled Prometheus so that the dashboards work
+type ScrapeTarget struct {
+	Host        string            `json:"host"`
+	Port        int               `json:"port"`
+	MetricsPath string            `json:"metrics_path"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// Addr returns the address of the target
+func (t ScrapeTarget) Addr() string {
+	return utils.JoinHostPort(t.Host, t.Port)
+}
+
+// ScrapeTargets returns the metrics endpoints of all the instances of the cluster scraped by
+// the bundled Prometheus, including the node exporter of every host. The monitoring
+// components themselves are not included.
+func ScrapeTargets(clusterName string, spec *meta.Specification) []ScrapeTarget {
+	var targets []ScrapeTarget
+	hosts := set.NewStringSet()
+	var uniqueHosts []string
+	add := func(job, host string, port int, metricsPath string) {
+		targets = append(targets, ScrapeTarget{
+			Host:        host,
+			Port:        port,
+			MetricsPath: metricsPath,
+			Labels:      map[string]string{"cluster": clusterName, "job": job},
+		})
+		if !hosts.Exist(host) {
+			hosts.Insert(host)
+			uniqueHosts = append(uniqueHosts, host)
+		}
+	}
+
+	for _, pd := range spec.PDServers {
+		add("pd", pd.Host, pd.ClientPort, defaultMetricsPath)
+	}
+	for _, kv := range spec.TiKVServers {
+		add("tikv", kv.Host, kv.StatusPort, defaultMetricsPath)
+	}
+	for _, db := range spec.TiDBServers {
+		add("tidb", db.Host, db.StatusPort, defaultMetricsPath)
+	}
+	for _, proxy := range spec.TiProxyServers {
+		add("tiproxy", proxy.Host, proxy.StatusPort, "/api/metrics")
+	}
+	for _, flash := range spec.TiFlashServers {
+		add("tiflash", flash.Host, flash.StatusPort, defaultMetricsPath)
+		add("tiflash", flash.Host, flash.FlashProxyStatusPort, defaultMetricsPath)
+	}
+	for _, pump := range spec.PumpServers {
+		add("pump", pump.Host, pump.Port, defaultMetricsPath)
+	}
+	for _, drainer := range spec.Drainers {
+		add("drainer", drainer.Host, drainer.Port, defaultMetricsPath)
+	}
+	for _, cdc := range spec.CDCServers {
+		add("ticdc", cdc.Host, cdc.Port, defaultMetricsPath)
+	}
+	for _, host := range uniqueHosts {
+		targets = append(targets, ScrapeTarget{
+			Host:        host,
+			Port:        spec.MonitoredOptions.NodeExporterPort,
+			MetricsPath: defaultMetricsPath,
+			Labels:      map[string]string{"cluster": clusterName, "job": "overwritten-nodes"},
+		})
+	}
+	return targets
+}
+
+// fileSDGroup is a target group of the file based service discovery of Prometheus
+type fileSDGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// FileSDConfig returns the targets in the JSON format of the file based service discovery of
+// Prometheus, a group for each target with its metrics path in the `__metrics_path__` label
+func FileSDConfig(targets []ScrapeTarget) ([]byte, error) {
+	groups := make([]fileSDGroup, 0, len(targets))
+	for _, t := range targets {
+		labels := map[string]string{"__metrics_path__": t.MetricsPath}
+		for k, v := range t.Labels {
+			labels[k] = v
+		}
+		groups = append(groups, fileSDGroup{
+			Targets: []string{t.Addr()},
+			Labels:  labels,
+		})
+	}
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(data, '\n'), nil
+}
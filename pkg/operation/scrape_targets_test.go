@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type scrapeTargetsSuite struct{}
+
+var _ = Suite(&scrapeTargetsSuite{})
+
+func (s *scrapeTargetsSuite) TestScrapeTargets(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+monitored:
+  node_exporter_port: 9200
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+    status_port: 20181
+tidb_servers:
+  - host: 172.16.5.142
+tiproxy_servers:
+  - host: 172.16.5.142
+tiflash_servers:
+  - host: 172.16.5.143
+cdc_servers:
+  - host: 172.16.5.141
+grafana_servers:
+  - host: 172.16.5.144
+monitoring_servers:
+  - host: 172.16.5.144
+`), topo), IsNil)
+
+	var addrs, jobs, paths []string
+	targets := ScrapeTargets("test-cluster", topo)
+	for _, t := range targets {
+		addrs = append(addrs, t.Addr())
+		jobs = append(jobs, t.Labels["job"])
+		paths = append(paths, t.MetricsPath)
+		c.Assert(t.Labels["cluster"], Equals, "test-cluster")
+	}
+	// the monitoring components are not scraped
+	c.Assert(addrs, DeepEquals, []string{
+		"172.16.5.140:2379", "172.16.5.140:20180", "172.16.5.141:20181", "172.16.5.142:10080",
+		"172.16.5.142:3080", "172.16.5.143:8234", "172.16.5.143:20292", "172.16.5.141:8300",
+		"172.16.5.140:9200", "172.16.5.141:9200", "172.16.5.142:9200", "172.16.5.143:9200",
+	})
+	c.Assert(jobs, DeepEquals, []string{
+		"pd", "tikv", "tikv", "tidb", "tiproxy", "tiflash", "tiflash", "ticdc",
+		"overwritten-nodes", "overwritten-nodes", "overwritten-nodes", "overwritten-nodes",
+	})
+	c.Assert(paths[4], Equals, "/api/metrics")
+	c.Assert(paths[5], Equals, "/metrics")
+
+	data, err := FileSDConfig(targets)
+	c.Assert(err, IsNil)
+	var groups []fileSDGroup
+	c.Assert(json.Unmarshal(data, &groups), IsNil)
+	c.Assert(groups, HasLen, len(targets))
+	c.Assert(groups[4], DeepEquals, fileSDGroup{
+		Targets: []string{"172.16.5.142:3080"},
+		Labels:  map[string]string{"__metrics_path__": "/api/metrics", "cluster": "test-cluster", "job": "tiproxy"},
+	})
+	// the labels of the targets are not changed
+	c.Assert(targets[4].Labels, HasLen, 2)
+
+	data, err = FileSDConfig(nil)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "[]\n")
+}
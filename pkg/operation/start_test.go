@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type startSuite struct{}
+
+var _ = Suite(&startSuite{})
+
+func (s *startSuite) TestStartByDependencies(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+tidb_servers:
+  - host: 172.16.5.140
+`), topo), IsNil)
+
+	hosts := &upgradeHosts{running: map[string]bool{}, limit: -1}
+	origin := componentReady
+	defer func() { componentReady = origin }()
+	var waited []string
+	componentReady = map[string]func(*meta.Specification, []meta.Instance) error{
+		meta.ComponentPD: func(spec *meta.Specification, insts []meta.Instance) error {
+			hosts.mu.Lock()
+			defer hosts.mu.Unlock()
+			c.Assert(insts, HasLen, 2)
+			// all the PD instances are started and none of TiKV
+			waited = append(waited, strings.Join(hosts.starts, ","))
+			hosts.starts = append(hosts.starts, "pd is up")
+			return nil
+		},
+	}
+
+	c.Assert(Start(hosts, topo, Options{}), IsNil)
+	var starts []string
+	for _, addr := range hosts.starts {
+		// the monitoring agents are started along with the first instance of each host
+		if !strings.HasSuffix(addr, ":9100") && !strings.HasSuffix(addr, ":9115") {
+			starts = append(starts, addr)
+		}
+	}
+	c.Assert(waited, HasLen, 1)
+	c.Assert(waited[0], Not(Matches), ".*:20160.*")
+	// the instances of a component are started concurrently
+	sort.Strings(starts[:2])
+	sort.Strings(starts[3:5])
+	c.Assert(starts, DeepEquals, []string{
+		"172.16.5.140:2379", "172.16.5.141:2379",
+		"pd is up",
+		"172.16.5.140:20160", "172.16.5.141:20160",
+		"172.16.5.140:4000",
+	})
+
+	// the components already running are not waited for
+	hosts = &upgradeHosts{running: map[string]bool{}, limit: -1}
+	waited = nil
+	c.Assert(Start(hosts, topo, Options{Roles: []string{meta.ComponentTiKV}}), IsNil)
+	c.Assert(waited, HasLen, 0)
+}
+
+func (s *startSuite) TestForEachInstanceLimit(c *C) {
+	topo := &meta.Specification{}
+	for i := 0; i < 6; i++ {
+		topo.TiDBServers = append(topo.TiDBServers, meta.TiDBSpec{Host: "172.16.5.140", Port: 4000 + i})
+	}
+	var insts []meta.Instance
+	for _, com := range topo.ComponentsByStartOrder() {
+		if com.Name() == meta.ComponentTiDB {
+			insts = com.Instances()
+		}
+	}
+	c.Assert(insts, HasLen, 6)
+
+	inflight := func(limit int) int32 {
+		var running, max int32
+		err := forEachInstance(insts, limit, func(ins meta.Instance) error {
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&max)
+				if cur <= old || atomic.CompareAndSwapInt32(&max, old, cur) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		c.Assert(err, IsNil)
+		return atomic.LoadInt32(&max)
+	}
+	c.Assert(inflight(2), Equals, int32(2))
+	c.Assert(inflight(0), Equals, int32(6))
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+const (
+	// defaultPollInterval is the interval of checking whether the stopped unit is inactive
+	defaultPollInterval = 500 * time.Millisecond
+	// killWait is the time waited for the unit to be inactive after SIGKILL
+	killWait = 10 * time.Second
+)
+
+// StopPolicy stops an instance by SIGTERM, and escalates to SIGKILL if the instance is still
+// running after the grace period, so that stopping never waits for a stuck process forever
+type StopPolicy struct {
+	GracePeriod  time.Duration
+	PollInterval time.Duration
+}
+
+// Stop stops the instance by the policy, escalated is true if SIGKILL is sent
+func (p StopPolicy) Stop(e executor.TiOpsExecutor, ins meta.Instance) (escalated bool, err error) {
+	unit := ins.ServiceName()
+	// systemd sends SIGTERM to the processes and doesn't restart the stopping unit
+	_, stderr, err := e.Execute(fmt.Sprintf("systemctl daemon-reload && systemctl stop --no-block %s", unit), true)
+	if err != nil {
+		// the unit doesn't exist, that's exactly what we want
+		if bytes.Contains(stderr, []byte(" not loaded.")) {
+			log.Warnf(string(stderr))
+			return false, nil
+		}
+		return false, errors.Annotatef(err, "failed to stop %s on %s, stderr: %s", unit, ins.GetHost(), stderr)
+	}
+
+	inactive, err := p.waitInactive(e, unit, p.GracePeriod)
+	if err != nil || inactive {
+		return false, err
+	}
+
+	log.Warnf("\t%s on %s doesn't exit in %s after SIGTERM, sending SIGKILL", unit, ins.GetHost(), p.GracePeriod)
+	if _, stderr, err := e.Execute(fmt.Sprintf("systemctl kill --signal=SIGKILL %s", unit), true); err != nil {
+		return true, errors.Annotatef(err, "failed to kill %s on %s, stderr: %s", unit, ins.GetHost(), stderr)
+	}
+	inactive, err = p.waitInactive(e, unit, killWait)
+	if err != nil {
+		return true, err
+	}
+	if !inactive {
+		return true, errors.Errorf("%s on %s is still running after SIGKILL", unit, ins.GetHost())
+	}
+	return true, nil
+}
+
+// waitInactive waits for the unit to be inactive until the timeout
+func (p StopPolicy) waitInactive(e executor.TiOpsExecutor, unit string, timeout time.Duration) (bool, error) {
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		// is-active exits with non-zero if the unit is not active, the state is got from stdout
+		stdout, stderr, err := e.Execute(fmt.Sprintf("systemctl is-active %s", unit), true)
+		switch state := strings.TrimSpace(string(stdout)); state {
+		case "inactive", "failed", "unknown":
+			return true, nil
+		case "":
+			if err != nil {
+				return false, errors.Annotatef(err, "failed to get the state of %s, stderr: %s", unit, stderr)
+			}
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+// StopComponentWithPolicy stops the instances by the policy, the instances needed to be
+// killed are reported
+func StopComponentWithPolicy(getter ExecutorGetter, instances []meta.Instance, policy StopPolicy) error {
+	return stopComponentWithPolicy(getter, instances, policy, 0)
+}
+
+// stopComponentWithPolicy stops the instances by the policy, at most limit of them at the
+// same time if it's positive
+func stopComponentWithPolicy(getter ExecutorGetter, instances []meta.Instance, policy StopPolicy, limit int) error {
+	if len(instances) <= 0 {
+		return nil
+	}
+
+	name := instances[0].ComponentName()
+	log.Infof("Stopping component %s", name)
+
+	return forEachInstance(instances, limit, func(ins meta.Instance) error {
+		log.Infof("\tStopping instance %s", ins.GetHost())
+		escalated, err := policy.Stop(getter.Get(ins.GetHost()), ins)
+		if err != nil {
+			return errors.Annotatef(err, "failed to stop: %s %s:%d", name, ins.GetHost(), ins.GetPort())
+		}
+		if escalated {
+			log.Warnf("\tStop %s %s:%d by SIGKILL", name, ins.GetHost(), ins.GetPort())
+		} else {
+			log.Infof("\tStop %s %s:%d success", name, ins.GetHost(), ins.GetPort())
+		}
+		return nil
+	})
+}
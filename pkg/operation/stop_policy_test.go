@@ -0,0 +1,107 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type stopSuite struct{}
+
+var _ = Suite(&stopSuite{})
+
+// unitExecutor mocks systemd, the unit leaves the active state once one of the exit
+// signals is sent to it
+type unitExecutor struct {
+	mu       sync.Mutex
+	exitOn   string
+	inactive bool
+	cmds     []string
+}
+
+func (e *unitExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cmds = append(e.cmds, cmd)
+	if strings.HasPrefix(cmd, e.exitOn) {
+		e.inactive = true
+	}
+	if strings.HasPrefix(cmd, "systemctl is-active") {
+		if e.inactive {
+			return []byte("inactive\n"), nil, nil
+		}
+		return []byte("deactivating\n"), nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (e *unitExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func (e *unitExecutor) killed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, cmd := range e.cmds {
+		if strings.HasPrefix(cmd, "systemctl kill --signal=SIGKILL") {
+			return true
+		}
+	}
+	return false
+}
+
+func tikvInstance(c *C) meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+`), topo), IsNil)
+	for _, comp := range topo.ComponentsByStartOrder() {
+		if comp.Name() == meta.ComponentTiKV {
+			return comp.Instances()[0]
+		}
+	}
+	c.Fatal("no tikv instance")
+	return nil
+}
+
+func (s *stopSuite) TestStopPolicy(c *C) {
+	ins := tikvInstance(c)
+	policy := StopPolicy{GracePeriod: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+
+	// exits on SIGTERM
+	e := &unitExecutor{exitOn: "systemctl daemon-reload && systemctl stop"}
+	escalated, err := policy.Stop(e, ins)
+	c.Assert(err, IsNil)
+	c.Assert(escalated, IsFalse)
+	c.Assert(e.killed(), IsFalse)
+	c.Assert(e.cmds[0], Equals, "systemctl daemon-reload && systemctl stop --no-block tikv-20160.service")
+
+	// ignores SIGTERM and is killed after the grace period
+	e = &unitExecutor{exitOn: "systemctl kill --signal=SIGKILL"}
+	start := time.Now()
+	escalated, err = policy.Stop(e, ins)
+	c.Assert(err, IsNil)
+	c.Assert(escalated, IsTrue)
+	c.Assert(e.killed(), IsTrue)
+	c.Assert(time.Since(start) >= policy.GracePeriod, IsTrue)
+
+	c.Assert(StopComponentWithPolicy(versionGetter{&unitExecutor{exitOn: "systemctl kill"}}, []meta.Instance{ins}, policy), IsNil)
+}
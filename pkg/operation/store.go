@@ -0,0 +1,169 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+	pdserverapi "github.com/pingcap/pd/v4/server/api"
+)
+
+// StoreAttributes are the attributes of a TiKV store which can be changed online, the
+// weights are kept if they are nil
+type StoreAttributes struct {
+	Labels       map[string]string
+	LeaderWeight *float64
+	RegionWeight *float64
+}
+
+// SetStoreAttributes sets the labels and weights of the store of the TiKV instance through
+// PD without restarting it, and waits for PD to apply them. The keys of the labels must be
+// the location labels of PD. The labels of the instance in the topology are updated as TiKV
+// reports the ones in its config when it's restarted, and the weights are recorded too.
+func SetStoreAttributes(
+	spec *meta.Specification,
+	node string,
+	attrs StoreAttributes,
+	timeout time.Duration,
+	retryOpt *utils.RetryOption,
+) error {
+	var inst meta.Instance
+	for _, i := range (&meta.TiKVComponent{Specification: spec}).Instances() {
+		if i.ID() == node {
+			inst = i
+		}
+	}
+	if inst == nil {
+		return errors.Errorf("%s is not a TiKV instance of the cluster", node)
+	}
+
+	locationLabels, _, err := spec.ReplicationConfig()
+	if err != nil {
+		return err
+	}
+	valid := make(map[string]bool)
+	for _, key := range locationLabels {
+		valid[key] = true
+	}
+	var invalid []string
+	for key := range attrs.Labels {
+		if !valid[key] {
+			invalid = append(invalid, key)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return errors.Errorf("the label keys %s are not in replication.location-labels [%s] of PD", strings.Join(invalid, ", "), strings.Join(locationLabels, ", "))
+	}
+
+	pdClient := api.NewPDClient(spec.GetPDList(), timeout, nil)
+	store, err := pdClient.GetStore(node)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the store of %s", node)
+	}
+	id := store.Store.Id
+
+	if len(attrs.Labels) > 0 {
+		log.Infof("Setting the labels of store %d (%s) to %s", id, node, formatLabels(attrs.Labels))
+		if err := pdClient.SetStoreLabels(id, attrs.Labels); err != nil {
+			return err
+		}
+	}
+	if attrs.LeaderWeight != nil || attrs.RegionWeight != nil {
+		// both the weights are set by PD at the same time
+		if attrs.LeaderWeight == nil {
+			leader := store.Status.LeaderWeight
+			attrs.LeaderWeight = &leader
+		}
+		if attrs.RegionWeight == nil {
+			region := store.Status.RegionWeight
+			attrs.RegionWeight = &region
+		}
+		leader, region := *attrs.LeaderWeight, *attrs.RegionWeight
+		log.Infof("Setting the weights of store %d (%s) to leader %v, region %v", id, node, leader, region)
+		if err := pdClient.SetStoreWeight(id, leader, region); err != nil {
+			return err
+		}
+	}
+
+	if retryOpt == nil {
+		retryOpt = &utils.RetryOption{
+			Delay:   time.Second,
+			Timeout: time.Second * 30,
+		}
+	}
+	if err := utils.Retry(func() error {
+		store, err = pdClient.GetStore(node)
+		if err != nil {
+			return err
+		}
+		return storeApplied(store, attrs)
+	}, *retryOpt); err != nil {
+		return errors.Annotatef(err, "the attributes of store %d are not applied by PD", id)
+	}
+
+	// the labels of the store are the merged ones as the others are kept by PD
+	labels := make(map[string]string)
+	for _, l := range store.Store.Labels {
+		labels[l.Key] = l.Value
+	}
+	if err := spec.SetTiKVLabels(node, labels); err != nil {
+		return err
+	}
+	for i := range spec.TiKVServers {
+		if s := &spec.TiKVServers[i]; utils.JoinHostPort(s.Host, s.Port) == node && attrs.LeaderWeight != nil {
+			s.LeaderWeight, s.RegionWeight = *attrs.LeaderWeight, *attrs.RegionWeight
+		}
+	}
+	return nil
+}
+
+// storeApplied returns an error if the store doesn't have the attributes
+func storeApplied(store *pdserverapi.StoreInfo, attrs StoreAttributes) error {
+	labels := make(map[string]string)
+	for _, l := range store.Store.Labels {
+		labels[l.Key] = l.Value
+	}
+	for k, v := range attrs.Labels {
+		if labels[k] != v {
+			return errors.Errorf("label %s is %q rather than %q", k, labels[k], v)
+		}
+	}
+	if attrs.LeaderWeight != nil {
+		leader, region := *attrs.LeaderWeight, *attrs.RegionWeight
+		if store.Status.LeaderWeight != leader || store.Status.RegionWeight != region {
+			return errors.Errorf("the weights are leader %v, region %v rather than leader %v, region %v",
+				store.Status.LeaderWeight, store.Status.RegionWeight, leader, region)
+		}
+	}
+	return nil
+}
+
+// formatLabels formats the labels in the order of the keys, e.g: host=h1,zone=z1
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
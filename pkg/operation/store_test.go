@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+type storeSuite struct{}
+
+var _ = Suite(&storeSuite{})
+
+// mockStorePD serves a single store, whose labels and weights can be set
+type mockStorePD struct {
+	mu           sync.Mutex
+	address      string
+	labels       map[string]string
+	leaderWeight float64
+	regionWeight float64
+	calls        []string
+}
+
+func (p *mockStorePD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == "/pd/api/v1/stores" && r.Method == http.MethodGet:
+		var labels []map[string]string
+		for k, v := range p.labels {
+			labels = append(labels, map[string]string{"key": k, "value": v})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"count": 1,
+			"stores": []interface{}{map[string]interface{}{
+				"store":  map[string]interface{}{"id": 1, "address": p.address, "labels": labels},
+				"status": map[string]interface{}{"leader_weight": p.leaderWeight, "region_weight": p.regionWeight},
+			}},
+		})
+	case r.URL.Path == "/pd/api/v1/store/1/label" && r.Method == http.MethodPost:
+		p.calls = append(p.calls, "label "+string(body))
+		labels := make(map[string]string)
+		_ = json.Unmarshal(body, &labels)
+		for k, v := range labels {
+			p.labels[k] = v
+		}
+	case r.URL.Path == "/pd/api/v1/store/1/weight" && r.Method == http.MethodPost:
+		p.calls = append(p.calls, "weight "+string(body))
+		var weights struct {
+			Leader float64 `json:"leader"`
+			Region float64 `json:"region"`
+		}
+		_ = json.Unmarshal(body, &weights)
+		p.leaderWeight, p.regionWeight = weights.Leader, weights.Region
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func storeTopology(c *C, pdPort string) *meta.Specification {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(fmt.Sprintf(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: %s
+    config:
+      replication.location-labels: [zone, host]
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels:
+        zone: z1
+`, pdPort)), topo), IsNil)
+	return topo
+}
+
+func (s *storeSuite) TestSetStoreAttributes(c *C) {
+	pd := &mockStorePD{
+		address:      "172.16.5.140:20160",
+		labels:       map[string]string{"zone": "z1"},
+		leaderWeight: 1,
+		regionWeight: 1,
+	}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	topo := storeTopology(c, serverPort(c, server.Listener.Addr().String()))
+	retryOpt := &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second}
+
+	// the other label is kept, and the region weight is kept as it's not specified
+	leader := 2.5
+	err := SetStoreAttributes(topo, "172.16.5.140:20160", StoreAttributes{
+		Labels:       map[string]string{"host": "h1"},
+		LeaderWeight: &leader,
+	}, time.Second, retryOpt)
+	c.Assert(err, IsNil)
+	c.Assert(pd.calls, DeepEquals, []string{
+		`label {"host":"h1"}`,
+		`weight {"leader":2.5,"region":1}`,
+	})
+
+	labels, err := topo.TiKVLabels()
+	c.Assert(err, IsNil)
+	c.Assert(labels["172.16.5.140:20160"], DeepEquals, map[string]string{"zone": "z1", "host": "h1"})
+	c.Assert(topo.TiKVServers[0].LeaderWeight, Equals, 2.5)
+	c.Assert(topo.TiKVServers[0].RegionWeight, Equals, 1.0)
+}
+
+func (s *storeSuite) TestSetStoreAttributesInvalid(c *C) {
+	pd := &mockStorePD{address: "172.16.5.140:20160", labels: map[string]string{}}
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	topo := storeTopology(c, serverPort(c, server.Listener.Addr().String()))
+
+	err := SetStoreAttributes(topo, "172.16.5.140:20160", StoreAttributes{
+		Labels: map[string]string{"rack": "r1", "dc": "d1", "zone": "z2"},
+	}, time.Second, nil)
+	c.Assert(err, ErrorMatches, `the label keys dc, rack are not in replication.location-labels \[zone, host\] of PD`)
+
+	err = SetStoreAttributes(topo, "172.16.5.141:20160", StoreAttributes{
+		Labels: map[string]string{"zone": "z2"},
+	}, time.Second, nil)
+	c.Assert(err, ErrorMatches, "172.16.5.141:20160 is not a TiKV instance of the cluster")
+	c.Assert(pd.calls, HasLen, 0)
+}
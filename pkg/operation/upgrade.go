@@ -14,7 +14,6 @@
 package operator
 
 import (
-	"strconv"
 	"time"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
@@ -30,13 +29,41 @@ func Upgrade(
 	getter ExecutorGetter,
 	spec *meta.Specification,
 	options Options,
+) error {
+	if options.ZoneLabel == "" {
+		return upgrade(getter, spec, options)
+	}
+
+	zones, err := Zones(spec, options.ZoneLabel)
+	if err != nil {
+		return err
+	}
+	for _, zone := range zones {
+		opt, ok := zoneOptions(options, zone)
+		if !ok {
+			continue
+		}
+		log.Infof("Upgrading the instances in zone %s", zone)
+		if err := upgrade(getter, spec, opt); err != nil {
+			return errors.Annotatef(err, "failed to upgrade zone %s", zone)
+		}
+	}
+	return nil
+}
+
+func upgrade(
+	getter ExecutorGetter,
+	spec *meta.Specification,
+	options Options,
 ) error {
 	roleFilter := set.NewStringSet(options.Roles...)
 	nodeFilter := set.NewStringSet(options.Nodes...)
 	components := spec.ComponentsByStartOrder()
 	components = FilterComponent(components, roleFilter)
 
-	leaderAware := set.NewStringSet(meta.ComponentPD, meta.ComponentTiKV)
+	// the instances done in the interrupted upgrade are skipped
+	state := options.UpgradeState
+	leaderAware := set.NewStringSet(meta.ComponentPD, meta.ComponentTiKV, meta.ComponentCDC, meta.ComponentDrainer)
 
 	timeoutOpt := &utils.RetryOption{
 		Timeout: time.Second * time.Duration(options.Timeout),
@@ -49,79 +76,130 @@ func Upgrade(
 			continue
 		}
 
-		// Transfer leader of evict leader if the component is TiKV/PD in non-force mode
+		// Transfer leader of evict leader if the component is TiKV/PD, drain the captures if
+		// it's TiCDC, or wait for the drainers to be synced if it's Drainer in non-force mode
 		if !options.Force && leaderAware.Exist(component.Name()) {
-			pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, nil)
 			switch component.Name() {
-			case meta.ComponentPD:
+			case meta.ComponentPD, meta.ComponentTiKV:
 				log.Infof("Restarting component %s", component.Name())
 
 				for _, instance := range instances {
-					leader, err := pdClient.GetLeader()
+					err := upgradeInstance(state, instance, func() error {
+						return RestartWithLeaderTransferred(spec, instance, timeoutOpt, func() error {
+							if err := stopInstance(getter, instance); err != nil {
+								return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+							}
+							if err := startInstance(getter, instance); err != nil {
+								return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+							}
+							return nil
+						})
+					})
 					if err != nil {
-						return errors.Annotatef(err, "failed to get PD leader %s", instance.GetHost())
+						return err
 					}
+				}
 
-					if len(spec.PDServers) > 1 && leader.Name == instance.(*meta.PDInstance).Name {
-						if err := pdClient.EvictPDLeader(timeoutOpt); err != nil {
-							return errors.Annotatef(err, "failed to evict PD leader %s", instance.GetHost())
-						}
-					}
+			case meta.ComponentDrainer:
+				log.Infof("Restarting component %s", component.Name())
 
-					if err := stopInstance(getter, instance); err != nil {
-						return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
-					}
-					if err := startInstance(getter, instance); err != nil {
-						return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+				for _, instance := range instances {
+					err := upgradeInstance(state, instance, func() error {
+						if err := WaitDrainersSynced([]meta.Instance{instance}, DrainRetryOption(options)); err != nil {
+							return err
+						}
+						if err := stopInstance(getter, instance); err != nil {
+							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+						}
+						if err := startInstance(getter, instance); err != nil {
+							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+						}
+						return nil
+					})
+					if err != nil {
+						return err
 					}
 				}
 
-			case meta.ComponentTiKV:
+			case meta.ComponentCDC:
 				log.Infof("Restarting component %s", component.Name())
-				pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, nil)
-				// Make sure there's leader of PD.
-				// Although we evict pd leader when restart pd,
-				// But when there's only one PD instance the pd might not serve request right away after restart.
-				err := pdClient.WaitLeader(timeoutOpt)
-				if err != nil {
-					return errors.Annotate(err, "failed to wait leader")
-				}
 
 				for _, instance := range instances {
-					if err := pdClient.EvictStoreLeader(addr(instance), timeoutOpt); err != nil {
-						if utils.IsTimeoutOrMaxRetry(err) {
-							log.Warnf("Ignore evicting store leader from %s, %v", instance.ID(), err)
-						} else {
-							return errors.Annotatef(err, "failed to evict store leader %s", instance.GetHost())
+					err := upgradeInstance(state, instance, func() error {
+						if err := DrainCDC(spec, []meta.Instance{instance}, timeoutOpt); err != nil {
+							return errors.Annotatef(err, "failed to drain %s", instance.ID())
 						}
-					}
-
-					if err := stopInstance(getter, instance); err != nil {
-						return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
-					}
-					if err := startInstance(getter, instance); err != nil {
-						return errors.Annotatef(err, "failed to start %s", instance.GetHost())
-					}
-					// remove store leader evict scheduler after restart
-					if err := pdClient.RemoveStoreEvict(addr(instance)); err != nil {
-						return errors.Annotatef(err, "failed to remove evict store scheduler for %s", instance.GetHost())
+						if err := stopInstance(getter, instance); err != nil {
+							return errors.Annotatef(err, "failed to stop %s", instance.GetHost())
+						}
+						if err := startInstance(getter, instance); err != nil {
+							return errors.Annotatef(err, "failed to start %s", instance.GetHost())
+						}
+						return nil
+					})
+					if err != nil {
+						return err
 					}
 				}
 			}
 			continue
 		}
 
-		if err := RestartComponent(getter, instances); err != nil {
-			return errors.Annotatef(err, "failed to restart %s", component.Name())
+		log.Infof("Restarting component %s", component.Name())
+		for _, instance := range instances {
+			err := upgradeInstance(state, instance, func() error {
+				return RestartInstance(getter, instance)
+			})
+			if err != nil {
+				return errors.Annotatef(err, "failed to restart %s", component.Name())
+			}
 		}
 	}
 
 	return nil
 }
 
+// RestartWithLeaderTransferred restarts the instance by restart after its leaders are transferred,
+// the PD leader is transferred from a PD instance, and the store leaders are evicted from a TiKV
+// instance until it's restarted. The other instances are restarted directly.
+func RestartWithLeaderTransferred(spec *meta.Specification, instance meta.Instance, timeoutOpt *utils.RetryOption, restart func() error) error {
+	switch instance.ComponentName() {
+	case meta.ComponentPD:
+		if err := transferPDLeaderBeforeStop(spec, []meta.Instance{instance}, timeoutOpt); err != nil {
+			return err
+		}
+		return restart()
+
+	case meta.ComponentTiKV:
+		pdClient := api.NewPDClient(spec.GetPDList(), 5*time.Second, nil)
+		// Make sure there's leader of PD.
+		// Although we evict pd leader when restart pd,
+		// But when there's only one PD instance the pd might not serve request right away after restart.
+		if err := pdClient.WaitLeader(timeoutOpt); err != nil {
+			return errors.Annotate(err, "failed to wait leader")
+		}
+		if err := pdClient.EvictStoreLeader(addr(instance), timeoutOpt); err != nil {
+			if utils.IsTimeoutOrMaxRetry(err) {
+				log.Warnf("Ignore evicting store leader from %s, %v", instance.ID(), err)
+			} else {
+				return errors.Annotatef(err, "failed to evict store leader %s", instance.GetHost())
+			}
+		}
+		if err := restart(); err != nil {
+			return err
+		}
+		// remove store leader evict scheduler after restart
+		if err := pdClient.RemoveStoreEvict(addr(instance)); err != nil {
+			return errors.Annotatef(err, "failed to remove evict store scheduler for %s", instance.GetHost())
+		}
+		return nil
+	}
+	return restart()
+}
+
 func addr(ins meta.Instance) string {
 	if ins.GetPort() == 0 || ins.GetPort() == 80 {
 		panic(ins)
 	}
-	return ins.GetHost() + ":" + strconv.Itoa(ins.GetPort())
+	return utils.JoinHostPort(ins.GetHost(), ins.GetPort())
 }
@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+// NodeState is the state of an instance in an upgrade
+type NodeState string
+
+// The states of an instance in an upgrade, the binaries of a backed up instance may have been
+// replaced partially, so they must not be backed up again
+const (
+	NodePending   NodeState = "pending"
+	NodeBackedUp  NodeState = "backed-up"
+	NodeUpgrading NodeState = "upgrading"
+	NodeDone      NodeState = "done"
+)
+
+// UpgradeState records the state of each instance in an upgrade persistently, so that an
+// interrupted upgrade is resumed from the first instance not done, and the instance
+// interrupted in upgrading is upgraded again to verify it.
+type UpgradeState interface {
+	// State returns the state of the instance, NodePending if it's not recorded
+	State(id string) NodeState
+	// SetState records the state of the instance
+	SetState(id string, state NodeState) error
+}
+
+// upgradeInstance upgrades the instance by fn with its state recorded before and after, it's
+// skipped if it's done. The instance is always upgraded without a state.
+func upgradeInstance(state UpgradeState, ins meta.Instance, fn func() error) error {
+	if state == nil {
+		return fn()
+	}
+	switch state.State(ins.ID()) {
+	case NodeDone:
+		log.Infof("\tSkip instance %s which has been upgraded", ins.ID())
+		return nil
+	case NodeUpgrading:
+		log.Infof("\tUpgrading instance %s again which was interrupted in upgrading", ins.ID())
+	}
+	if err := state.SetState(ins.ID(), NodeUpgrading); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return state.SetState(ins.ID(), NodeDone)
+}
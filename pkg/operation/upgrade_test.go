@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type upgradeSuite struct{}
+
+var _ = Suite(&upgradeSuite{})
+
+var unitPattern = regexp.MustCompile(`systemctl (start|stop|restart) \S+-(\d+)\.service`)
+
+// upgradeHosts mocks the systemd units of the hosts, the ports of the running units are
+// listed by `ss -ltn`, and the restarts fail once the limit is reached like the upgrade is
+// interrupted
+type upgradeHosts struct {
+	mu       sync.Mutex
+	running  map[string]bool
+	restarts []string
+	starts   []string
+	limit    int
+}
+
+func (h *upgradeHosts) Get(host string) executor.TiOpsExecutor {
+	return &upgradeExecutor{host: host, hosts: h}
+}
+
+type upgradeExecutor struct {
+	host  string
+	hosts *upgradeHosts
+}
+
+func (e *upgradeExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	h := e.hosts
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cmd == "ss -ltn" {
+		var lines []string
+		for addr := range h.running {
+			if strings.HasPrefix(addr, e.host+":") {
+				lines = append(lines, fmt.Sprintf("LISTEN 0 128 %s *:*", addr))
+			}
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil, nil
+	}
+	m := unitPattern.FindStringSubmatch(cmd)
+	if m == nil {
+		return nil, nil, nil
+	}
+	addr := e.host + ":" + m[2]
+	if m[1] == "stop" {
+		delete(h.running, addr)
+		return nil, nil, nil
+	}
+	if m[1] == "restart" {
+		if h.limit >= 0 && len(h.restarts) >= h.limit {
+			return nil, nil, errors.New("connection lost")
+		}
+		h.restarts = append(h.restarts, addr)
+	}
+	if m[1] == "start" {
+		h.starts = append(h.starts, addr)
+	}
+	h.running[addr] = true
+	return nil, nil, nil
+}
+
+func (e *upgradeExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+func upgradeTopology(c *C) *meta.Specification {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`), topo), IsNil)
+	return topo
+}
+
+// memUpgradeState records the states of the instances in memory
+type memUpgradeState map[string]NodeState
+
+func (m memUpgradeState) State(id string) NodeState {
+	if state, ok := m[id]; ok {
+		return state
+	}
+	return NodePending
+}
+
+func (m memUpgradeState) SetState(id string, state NodeState) error {
+	m[id] = state
+	return nil
+}
+
+func (s *upgradeSuite) TestResumeUpgrade(c *C) {
+	topo := upgradeTopology(c)
+	all := []string{"172.16.5.140:20160", "172.16.5.141:20160", "172.16.5.142:20160", "172.16.5.140:4000", "172.16.5.141:4000"}
+
+	// interrupted after upgrading 2 instances
+	hosts := &upgradeHosts{running: map[string]bool{}, limit: 2}
+	state := memUpgradeState{}
+	err := Upgrade(hosts, topo, Options{Force: true, UpgradeState: state})
+	c.Assert(err, ErrorMatches, ".*connection lost.*")
+	c.Assert(hosts.restarts, DeepEquals, all[:2])
+	c.Assert(state, DeepEquals, memUpgradeState{
+		"172.16.5.140:20160": NodeDone,
+		"172.16.5.141:20160": NodeDone,
+		"172.16.5.142:20160": NodeUpgrading,
+	})
+
+	// resumed from the third one, which is upgraded again to verify it
+	hosts.restarts, hosts.limit = nil, -1
+	c.Assert(Upgrade(hosts, topo, Options{Force: true, UpgradeState: state}), IsNil)
+	c.Assert(hosts.restarts, DeepEquals, all[2:])
+	for _, id := range all {
+		c.Assert(state.State(id), Equals, NodeDone)
+	}
+
+	// the backed up instances are upgraded like the pending ones
+	state = memUpgradeState{"172.16.5.140:4000": NodeBackedUp}
+	hosts.restarts = nil
+	c.Assert(Upgrade(hosts, topo, Options{Force: true, Roles: []string{meta.ComponentTiDB}, UpgradeState: state}), IsNil)
+	c.Assert(hosts.restarts, DeepEquals, all[3:])
+}
+
+func (s *upgradeSuite) TestUpgradeWithoutState(c *C) {
+	topo := upgradeTopology(c)
+	hosts := &upgradeHosts{running: map[string]bool{}, limit: -1}
+	c.Assert(Upgrade(hosts, topo, Options{Force: true}), IsNil)
+	c.Assert(hosts.restarts, HasLen, 5)
+
+	// all the instances are upgraded again
+	hosts.restarts = nil
+	c.Assert(Upgrade(hosts, topo, Options{Force: true}), IsNil)
+	c.Assert(hosts.restarts, HasLen, 5)
+}
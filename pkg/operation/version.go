@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+const pdVersionURI = "pd/api/v1/version"
+
+// InstanceVersion is the running version of an instance compared with the expected one
+type InstanceVersion struct {
+	ID       string
+	Role     string
+	Expected string
+	Actual   string
+	Err      error // the version can't be queried
+}
+
+// Drifted returns whether the instance is running another version than the expected one
+func (v InstanceVersion) Drifted() bool {
+	if v.Err != nil || v.Actual == "" || v.Expected == "nightly" {
+		return false
+	}
+	return normalizeVersion(v.Actual) != normalizeVersion(v.Expected)
+}
+
+// CollectVersions queries the running version of each instance and compares it with the
+// version of the cluster. The versions of TiDB and PD are got from their status APIs, the
+// ones of TiKV and TiFlash from the stores registered in PD, and the others are got by
+// running their binaries. The monitoring components are skipped as they are versioned
+// independently.
+func CollectVersions(
+	getter ExecutorGetter,
+	spec *meta.Specification,
+	version string,
+	timeout time.Duration,
+) []InstanceVersion {
+	client := utils.NewHTTPClient(timeout, nil)
+
+	var (
+		stores    map[string]string
+		storesErr error
+	)
+	storeVersions := func() (map[string]string, error) {
+		if stores != nil || storesErr != nil {
+			return stores, storesErr
+		}
+		stores = make(map[string]string)
+		info, err := api.NewPDClient(spec.GetPDList(), timeout, nil).GetStores()
+		if err != nil {
+			storesErr = errors.Annotate(err, "failed to get the stores from PD")
+			return nil, storesErr
+		}
+		for _, store := range info.Stores {
+			if store.Store != nil && store.Store.Store != nil {
+				stores[store.Store.Address] = store.Store.Version
+			}
+		}
+		return stores, nil
+	}
+
+	var versions []InstanceVersion
+	for _, comp := range spec.ComponentsByStartOrder() {
+		for _, inst := range comp.Instances() {
+			v := InstanceVersion{ID: inst.ID(), Role: inst.ComponentName(), Expected: version}
+			switch inst.ComponentName() {
+			case meta.ComponentTiDB:
+				port := inst.(*meta.TiDBInstance).InstanceSpec.(meta.TiDBSpec).StatusPort
+				v.Actual, v.Err = statusVersion(client, fmt.Sprintf("http://%s/status", utils.JoinHostPort(inst.GetHost(), port)))
+				// TiDB reports the version compatible with MySQL like 5.7.25-TiDB-v4.0.0
+				if i := strings.LastIndex(v.Actual, "-TiDB-"); i >= 0 {
+					v.Actual = v.Actual[i+len("-TiDB-"):]
+				}
+			case meta.ComponentPD:
+				v.Actual, v.Err = statusVersion(client, fmt.Sprintf("http://%s/%s", inst.ID(), pdVersionURI))
+			case meta.ComponentTiKV, meta.ComponentTiFlash:
+				addr := inst.ID()
+				if tiflash, ok := inst.(*meta.TiFlashInstance); ok {
+					addr = utils.JoinHostPort(inst.GetHost(), tiflash.InstanceSpec.(meta.TiFlashSpec).FlashServicePort)
+				}
+				stores, err := storeVersions()
+				if err != nil {
+					v.Err = err
+					break
+				}
+				if v.Actual = stores[addr]; v.Actual == "" {
+					v.Err = errors.Errorf("the store %s is not found in PD", addr)
+				}
+			case meta.ComponentCDC:
+				v.Actual, v.Err = statusVersion(client, fmt.Sprintf("http://%s/status", inst.ID()))
+			case meta.ComponentPump, meta.ComponentDrainer, meta.ComponentTiProxy:
+				v.Actual, v.Err = binaryVersion(getter, inst)
+			default:
+				continue
+			}
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// statusVersion gets the version field from the status API
+func statusVersion(client *utils.HTTPClient, url string) (string, error) {
+	body, err := client.Get(url)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to query %s", url)
+	}
+	status := struct {
+		Version string `json:"version"`
+	}{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return "", errors.Annotatef(err, "failed to parse the response of %s", url)
+	}
+	return status.Version, nil
+}
+
+// binaryVersion gets the version from the output of `<binary> -V`, which has a line like
+// `Release Version: v4.0.0`
+func binaryVersion(getter ExecutorGetter, inst meta.Instance) (string, error) {
+	e := getter.Get(inst.GetHost())
+	binary := fmt.Sprintf("%s/bin/%s", strings.TrimSuffix(inst.DeployDir(), "/"), inst.ComponentName())
+	stdout, stderr, err := e.Execute(binary+" -V", false)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to run %s on %s, stderr: %s", binary, inst.GetHost(), stderr)
+	}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) == 2 && kv[0] == "Release Version" {
+			return strings.TrimSpace(kv[1]), nil
+		}
+	}
+	return "", errors.Errorf("no version is reported by %s on %s", binary, inst.GetHost())
+}
+
+// normalizeVersion makes 4.0.0 and v4.0.0 the same
+func normalizeVersion(version string) string {
+	return "v" + strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
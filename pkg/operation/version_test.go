@@ -0,0 +1,141 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type versionSuite struct{}
+
+var _ = Suite(&versionSuite{})
+
+// versionExecutor answers `-V` of the binaries with the outputs
+type versionExecutor map[string]string
+
+func (e versionExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	out, ok := e[cmd]
+	if !ok {
+		return nil, []byte("No such file or directory"), errors.New("exit status 127")
+	}
+	return []byte(out), nil, nil
+}
+
+func (e versionExecutor) Transfer(src string, dst string, download bool) error {
+	return nil
+}
+
+type versionGetter struct {
+	e executor.TiOpsExecutor
+}
+
+func (g versionGetter) Get(host string) executor.TiOpsExecutor {
+	return g.e
+}
+
+func (s *versionSuite) TestCollectVersions(c *C) {
+	tidb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"connections":0,"version":"5.7.25-TiDB-v4.0.0-rc","git_hash":"79db9e30ab8f98ac07c8ae55c66dfecc24b43d56"}`)
+	}))
+	defer tidb.Close()
+	pd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pd/api/v1/version":
+			fmt.Fprint(w, `{"version":"v4.0.0"}`)
+		case "/pd/api/v1/stores":
+			fmt.Fprint(w, `{"count":2,"stores":[
+				{"store":{"id":1,"address":"127.0.0.1:20160","version":"4.0.0","state_name":"Up"}},
+				{"store":{"id":2,"address":"127.0.0.1:3930","version":"v4.0.0","state_name":"Up"}}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer pd.Close()
+	_, pdPort, err := net.SplitHostPort(pd.Listener.Addr().String())
+	c.Assert(err, IsNil)
+	_, tidbPort, err := net.SplitHostPort(tidb.Listener.Addr().String())
+	c.Assert(err, IsNil)
+
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: `+pdPort+`
+    peer_port: 2380
+tidb_servers:
+  - host: 127.0.0.1
+    status_port: `+tidbPort+`
+tikv_servers:
+  - host: 127.0.0.1
+  - host: 127.0.0.1
+    port: 20161
+    status_port: 20181
+tiflash_servers:
+  - host: 127.0.0.1
+pump_servers:
+  - host: 127.0.0.1
+    deploy_dir: /deploy/pump-8250
+  - host: 127.0.0.1
+    port: 8251
+    deploy_dir: /deploy/pump-8251
+monitoring_servers:
+  - host: 127.0.0.1
+`), topo), IsNil)
+
+	getter := versionGetter{versionExecutor{
+		"/deploy/pump-8250/bin/pump -V": "Release Version: v4.0.0\nGit Commit Hash: 0e4e1011\n",
+		"/deploy/pump-8251/bin/pump -V": "Release Version: v3.0.12\nGit Commit Hash: 2ffa3e73\n",
+	}}
+	versions := CollectVersions(getter, topo, "v4.0.0", time.Second)
+
+	byID := make(map[string]InstanceVersion)
+	for _, v := range versions {
+		byID[v.ID] = v
+	}
+	// the monitoring components are skipped
+	c.Assert(versions, HasLen, 7)
+
+	pdID := "127.0.0.1:" + pdPort
+	c.Assert(byID[pdID].Actual, Equals, "v4.0.0")
+	c.Assert(byID[pdID].Drifted(), IsFalse)
+
+	tidbID := "127.0.0.1:4000"
+	c.Assert(byID[tidbID].Role, Equals, meta.ComponentTiDB)
+	c.Assert(byID[tidbID].Actual, Equals, "v4.0.0-rc")
+	c.Assert(byID[tidbID].Drifted(), IsTrue)
+
+	c.Assert(byID["127.0.0.1:20160"].Actual, Equals, "4.0.0")
+	c.Assert(byID["127.0.0.1:20160"].Drifted(), IsFalse)
+	c.Assert(byID["127.0.0.1:20161"].Err, ErrorMatches, "the store 127.0.0.1:20161 is not found in PD")
+	c.Assert(byID["127.0.0.1:20161"].Drifted(), IsFalse)
+	c.Assert(byID["127.0.0.1:9000"].Actual, Equals, "v4.0.0")
+
+	c.Assert(byID["127.0.0.1:8250"].Drifted(), IsFalse)
+	c.Assert(byID["127.0.0.1:8251"].Actual, Equals, "v3.0.12")
+	c.Assert(byID["127.0.0.1:8251"].Drifted(), IsTrue)
+
+	// nothing is expected of nightly
+	c.Assert(InstanceVersion{Expected: "nightly", Actual: "v4.0.0-beta.2-nightly"}.Drifted(), IsFalse)
+}
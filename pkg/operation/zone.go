@@ -0,0 +1,104 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"sort"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+// Zone is a group of instances which are operated together in the zoned rolling operations
+type Zone struct {
+	Name  string // empty if the zone of the instances is unknown
+	Nodes set.StringSet
+}
+
+// Zones groups the instances by the zones of their hosts, the zone of a host is the value of
+// the label in the `server.labels` of the TiKV instances on it. The zones are ordered by name,
+// and the instances on the hosts without the label are in the last zone with an empty name.
+func Zones(spec *meta.Specification, label string) ([]Zone, error) {
+	labels, err := spec.TiKVLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	hostZones := make(map[string]string)
+	for _, inst := range (&meta.TiKVComponent{Specification: spec}).Instances() {
+		zone := labels[inst.ID()][label]
+		if zone == "" {
+			continue
+		}
+		if other, found := hostZones[inst.GetHost()]; found && other != zone {
+			return nil, errors.Errorf("the TiKV instances on host %s are in different zones %s and %s of label %s", inst.GetHost(), other, zone, label)
+		}
+		hostZones[inst.GetHost()] = zone
+	}
+
+	nodes := make(map[string]set.StringSet)
+	var names []string
+	for _, comp := range spec.ComponentsByStartOrder() {
+		for _, inst := range comp.Instances() {
+			zone := hostZones[inst.GetHost()]
+			if _, found := nodes[zone]; !found {
+				nodes[zone] = set.NewStringSet()
+				if zone != "" {
+					names = append(names, zone)
+				}
+			}
+			nodes[zone].Insert(inst.ID())
+		}
+	}
+	sort.Strings(names)
+	if _, found := nodes[""]; found {
+		names = append(names, "")
+	}
+
+	var zones []Zone
+	for _, name := range names {
+		zones = append(zones, Zone{Name: name, Nodes: nodes[name]})
+	}
+	return zones, nil
+}
+
+// String returns the name of the zone for printing
+func (z Zone) String() string {
+	if z.Name == "" {
+		return "unknown"
+	}
+	return z.Name
+}
+
+// zoneOptions returns the options limited to the instances of the zone, ok is false
+// if none of the instances are selected by the options
+func zoneOptions(options Options, zone Zone) (Options, bool) {
+	nodes := zone.Nodes
+	if len(options.Nodes) > 0 {
+		nodes = nodes.Intersection(set.NewStringSet(options.Nodes...))
+	}
+	if len(nodes) == 0 {
+		return options, false
+	}
+
+	var ids []string
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	options.Nodes = ids
+	options.ZoneLabel = ""
+	return options, true
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+type zoneSuite struct{}
+
+var _ = Suite(&zoneSuite{})
+
+func (s *zoneSuite) TestZones(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.142
+  - host: 172.16.5.150
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { zone: z2, host: h1 }
+  - host: 172.16.5.141
+    config:
+      server.labels: { zone: z1, host: h2 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { zone: z1, host: h3 }
+tidb_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.150
+`), topo), IsNil)
+
+	zones, err := Zones(topo, "zone")
+	c.Assert(err, IsNil)
+	c.Assert(zones, HasLen, 3)
+	// ordered by name and the unknown one is the last
+	c.Assert(zones[0].Name, Equals, "z1")
+	c.Assert(zones[0].Nodes.Exist("172.16.5.142:2379"), IsTrue)
+	c.Assert(zones[0].Nodes.Exist("172.16.5.141:4000"), IsTrue)
+	c.Assert(zones[0].Nodes, HasLen, 4)
+	c.Assert(zones[1].Name, Equals, "z2")
+	c.Assert(zones[1].Nodes, HasLen, 2)
+	c.Assert(zones[2].String(), Equals, "unknown")
+	c.Assert(zones[2].Nodes.Exist("172.16.5.150:2379"), IsTrue)
+	c.Assert(zones[2].Nodes.Exist("172.16.5.150:4000"), IsTrue)
+
+	// limited to the specified nodes
+	opt, ok := zoneOptions(Options{Nodes: []string{"172.16.5.141:4000", "172.16.5.150:4000"}, ZoneLabel: "zone"}, zones[0])
+	c.Assert(ok, IsTrue)
+	c.Assert(opt.Nodes, DeepEquals, []string{"172.16.5.141:4000"})
+	c.Assert(opt.ZoneLabel, Equals, "")
+	_, ok = zoneOptions(Options{Nodes: []string{"172.16.5.150:4000"}}, zones[1])
+	c.Assert(ok, IsFalse)
+
+	// the instances of a host must be in the same zone
+	topo.TiKVServers = append(topo.TiKVServers, meta.TiKVSpec{
+		Host:   "172.16.5.140",
+		Port:   20161,
+		Config: map[string]interface{}{"server.labels": map[string]interface{}{"zone": "z1"}},
+	})
+	_, err = Zones(topo, "zone")
+	c.Assert(err, ErrorMatches, "the TiKV instances on host 172.16.5.140 are in different zones z2 and z1 of label zone")
+}
@@ -16,6 +16,7 @@ package task
 import (
 	"fmt"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
 	"github.com/pingcap/errors"
@@ -30,46 +31,50 @@ type ClusterOperate struct {
 
 // Execute implements the Task interface
 func (c *ClusterOperate) Execute(ctx *Context) error {
+	options := c.options
+	if options.Concurrency <= 0 {
+		options.Concurrency = ctx.Concurrency()
+	}
 	switch c.op {
 	case operator.StartOperation:
-		err := operator.Start(ctx, c.spec, c.options)
+		err := operator.Start(ctx, c.spec, options)
 		if err != nil {
-			return errors.Annotate(err, "failed to start")
+			return componentError(err, "failed to start")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.StopOperation:
-		err := operator.Stop(ctx, c.spec, c.options)
+		err := operator.Stop(ctx, c.spec, options)
 		if err != nil {
-			return errors.Annotate(err, "failed to stop")
+			return componentError(err, "failed to stop")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.RestartOperation:
-		err := operator.Restart(ctx, c.spec, c.options)
+		err := operator.Restart(ctx, c.spec, options)
 		if err != nil {
-			return errors.Annotate(err, "failed to restart")
+			return componentError(err, "failed to restart")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.UpgradeOperation:
-		err := operator.Upgrade(ctx, c.spec, c.options)
+		err := operator.Upgrade(ctx, c.spec, options)
 		if err != nil {
-			return errors.Annotate(err, "failed to upgrade")
+			return componentError(err, "failed to upgrade")
 		}
 		operator.PrintClusterStatus(ctx, c.spec)
 	case operator.DestroyOperation:
 		err := operator.Destroy(ctx, c.spec)
 		if err != nil {
-			return errors.Annotate(err, "failed to destroy")
+			return componentError(err, "failed to destroy")
 		}
 	case operator.DestroyTombsomeOperation:
 		_, err := operator.DestroyTombstone(ctx, c.spec, false)
 		if err != nil {
-			return errors.Annotate(err, "failed to destroy")
+			return componentError(err, "failed to destroy")
 		}
 	// print nothing
 	case operator.ScaleInOperation:
-		err := operator.ScaleIn(ctx, c.spec, c.options)
+		err := operator.ScaleIn(ctx, c.spec, options)
 		if err != nil {
-			return errors.Annotate(err, "failed to scale in")
+			return componentError(err, "failed to scale in")
 		}
 	default:
 		return errors.Errorf("nonsupport %s", c.op)
@@ -78,6 +83,12 @@ func (c *ClusterOperate) Execute(ctx *Context) error {
 	return nil
 }
 
+// componentError annotates the error of operating the components, and classifies it as a
+// component failure unless it's caused by something else, e.g. the host is unreachable
+func componentError(err error, action string) error {
+	return errutil.Classify(errors.Annotate(err, action), errutil.CategoryComponent)
+}
+
 // Rollback implements the Task interface
 func (c *ClusterOperate) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
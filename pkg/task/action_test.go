@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *taskSuite) TestClusterOperateCategory(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+grafana_servers:
+  - host: 172.16.5.140
+`), topo), IsNil)
+	stop := NewBuilder().ClusterOperate(topo, operator.StopOperation, operator.Options{Roles: []string{meta.ComponentGrafana}}).Build()
+
+	// the component fails to stop
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.Contains(cmd, "systemctl") {
+			return nil, []byte("Failed to stop grafana-3000.service: Unit is masked."), errors.New("exit status 1")
+		}
+		return nil, nil, nil
+	}}
+	err := stop.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errutil.IsComponentError(err), IsTrue)
+	c.Assert(err, ErrorMatches, "failed to stop: .*exit status 1.*")
+
+	// the failure of the host is kept
+	e = &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, nil, errutil.Classify(errors.New("dial tcp 172.16.5.140:22: i/o timeout"), errutil.CategoryConnectivity)
+	}}
+	err = stop.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errutil.IsConnectivityError(err), IsTrue)
+
+	// the tasks refusing the operation are pre-check failures
+	ctx, _ := timezoneContext(map[string]string{"172.16.5.140": "UTC", "172.16.5.141": "Asia/Shanghai"})
+	err = NewBuilder().CheckTimezone([]string{"172.16.5.140", "172.16.5.141"}, "UTC", false).Build().Execute(ctx)
+	c.Assert(errutil.IsPreCheckError(err), IsTrue)
+}
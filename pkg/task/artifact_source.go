@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+)
+
+var (
+	errNSArtifact = errNS.NewSubNamespace("artifact")
+	// ErrArtifactSourceMismatch means an artifact is resolved to another URL than the pinned one
+	ErrArtifactSourceMismatch = errNSArtifact.NewType("source_mismatch", errutil.ErrTraitPreCheck)
+)
+
+// ArtifactKey returns the key of the artifact of the component version in the recorded
+// and pinned sources, e.g: tikv:v4.0.0
+func ArtifactKey(component, version string) string {
+	return component + ":" + version
+}
+
+// artifactURL returns the URL of the file in the mirror, which is a HTTP server or a local
+// directory
+func artifactURL(mirror, fileName string) string {
+	if strings.HasPrefix(mirror, "http://") || strings.HasPrefix(mirror, "https://") {
+		return strings.TrimSuffix(mirror, "/") + "/" + fileName
+	}
+	return filepath.Join(mirror, fileName)
+}
+
+// PinArtifactSources makes the downloads fail if the artifacts are resolved to other URLs
+// than the pinned ones, the keys are made by ArtifactKey and the artifacts not pinned are
+// not verified
+func (ctx *Context) PinArtifactSources(pinned map[string]string) {
+	ctx.sources.Lock()
+	defer ctx.sources.Unlock()
+	ctx.sources.pinned = pinned
+}
+
+// ArtifactSources returns the resolved URLs of the artifacts downloaded in the run
+func (ctx *Context) ArtifactSources() map[string]string {
+	ctx.sources.Lock()
+	defer ctx.sources.Unlock()
+	sources := make(map[string]string, len(ctx.sources.resolved))
+	for k, v := range ctx.sources.resolved {
+		sources[k] = v
+	}
+	return sources
+}
+
+// recordArtifactSource records the resolved URL of the artifact, and verifies it against
+// the pinned one
+func (ctx *Context) recordArtifactSource(component, version, url string) error {
+	ctx.sources.Lock()
+	defer ctx.sources.Unlock()
+
+	key := ArtifactKey(component, version)
+	if expected, ok := ctx.sources.pinned[key]; ok && expected != url {
+		return ErrArtifactSourceMismatch.
+			New("The artifact %s is resolved to %s rather than the pinned %s", key, url, expected).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please check the mirror (%s) is the expected one, or update the pinned source of %s.", strings.TrimSuffix(url, "/"+filepath.Base(url)), key)))
+	}
+	if ctx.sources.resolved == nil {
+		ctx.sources.resolved = make(map[string]string)
+	}
+	ctx.sources.resolved[key] = url
+	return nil
+}
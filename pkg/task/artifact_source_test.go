@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"os"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestArtifactSource(c *C) {
+	// the package is cached so nothing is downloaded
+	dir := setupCheckBinary(c)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	os.Setenv(repository.EnvMirrors, "https://mirror.example.com/")
+	defer os.Unsetenv(repository.EnvMirrors)
+
+	ctx := NewContext()
+	t := &Downloader{component: "tikv", version: "v4.0.0"}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(ctx.ArtifactSources(), DeepEquals, map[string]string{
+		"tikv:v4.0.0": "https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz",
+	})
+
+	// the pinned one is matched
+	ctx = NewContext()
+	ctx.PinArtifactSources(map[string]string{
+		"tikv:v4.0.0": "https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz",
+		"pd:v4.0.0":   "https://other.example.com/pd-v4.0.0-linux-amd64.tar.gz",
+	})
+	c.Assert(t.Execute(ctx), IsNil)
+
+	// the local mirror
+	os.Setenv(repository.EnvMirrors, "/data/mirror")
+	ctx = NewContext()
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(ctx.ArtifactSources()["tikv:v4.0.0"], Equals, "/data/mirror/tikv-v4.0.0-linux-amd64.tar.gz")
+}
+
+func (s *taskSuite) TestArtifactSourceMismatch(c *C) {
+	dir := setupCheckBinary(c)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+	os.Setenv(repository.EnvMirrors, "https://mirror.example.com")
+	defer os.Unsetenv(repository.EnvMirrors)
+
+	ctx := NewContext()
+	ctx.PinArtifactSources(map[string]string{
+		"tikv:v4.0.0": "https://internal.example.com/tikv-v4.0.0-linux-amd64.tar.gz",
+	})
+	err := (&Downloader{component: "tikv", version: "v4.0.0"}).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrArtifactSourceMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The artifact tikv:v4.0.0 is resolved to https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz rather than the pinned https://internal.example.com/tikv-v4.0.0-linux-amd64.tar.gz.*")
+	c.Assert(ctx.ArtifactSources(), HasLen, 0)
+}
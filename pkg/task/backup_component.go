@@ -18,7 +18,6 @@ import (
 	"path/filepath"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/bindversion"
-	"github.com/pingcap-incubator/tiup/pkg/meta"
 	"github.com/pingcap-incubator/tiup/pkg/repository"
 	"github.com/pingcap/errors"
 )
@@ -37,14 +36,9 @@ type BackupComponent struct {
 
 // Execute implements the Task interface
 func (c *BackupComponent) Execute(ctx *Context) error {
-	m, found := ctx.GetManifest(c.component)
-	if !found {
-		manifest, err := meta.Repository().ComponentVersions(c.component)
-		if err != nil {
-			return err
-		}
-		m = manifest
-		ctx.SetManifest(c.component, m)
+	m, err := ctx.componentManifest(c.component)
+	if err != nil {
+		return err
 	}
 
 	// Copy to remote server
@@ -82,7 +76,7 @@ func (c *BackupComponent) Execute(ctx *Context) error {
 	c.cpTo = dstPathOld
 
 	cmd := fmt.Sprintf(`cp %s %s`, dstPath, dstPathOld)
-	_, _, err := exec.Execute(cmd, false)
+	_, _, err = exec.Execute(cmd, false)
 	if err != nil {
 		return errors.Annotate(err, cmd)
 	}
@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// PDMetaBackup is the snapshot of the metadata of PD exported before a risky operation
+type PDMetaBackup struct {
+	Time      time.Time                  `json:"time"`
+	Operation string                     `json:"operation"`
+	PD        []string                   `json:"pd"`
+	Metadata  map[string]json.RawMessage `json:"metadata"`
+}
+
+// BackupPDMeta is used to export the metadata of PD to a local file before a risky
+// operation, e.g. scale-in or upgrade, so that the state before the change can be referenced
+// for recovery
+type BackupPDMeta struct {
+	spec      *meta.Specification
+	operation string
+	path      string
+}
+
+// Execute implements the Task interface
+func (b *BackupPDMeta) Execute(ctx *Context) error {
+	// nothing is exported if the commands are recorded to the plan
+	if ctx.Planning() {
+		return nil
+	}
+
+	pdList := b.spec.GetPDList()
+	metadata, err := api.NewPDClient(pdList, 10*time.Second, nil).GetMetadata()
+	if err != nil {
+		return errors.Annotate(err, "failed to back up the metadata of PD")
+	}
+	data, err := json.MarshalIndent(&PDMetaBackup{
+		Time:      time.Now(),
+		Operation: b.operation,
+		PD:        pdList,
+		Metadata:  metadata,
+	}, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := utils.CreateDir(filepath.Dir(b.path)); err != nil {
+		return errors.Annotatef(err, "failed to create the directory of %s", b.path)
+	}
+	if err := ioutil.WriteFile(b.path, data, 0600); err != nil {
+		return errors.Annotatef(err, "failed to write the metadata of PD to %s", b.path)
+	}
+	log.Infof("The metadata of PD is backed up to %s", b.path)
+	return nil
+}
+
+// Path returns the file the metadata is backed up to
+func (b *BackupPDMeta) Path() string {
+	return b.path
+}
+
+// Rollback implements the Task interface
+func (b *BackupPDMeta) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (b *BackupPDMeta) String() string {
+	return fmt.Sprintf("BackupPDMeta: pd=%s, path=%s", strings.Join(b.spec.GetPDList(), ","), b.path)
+}
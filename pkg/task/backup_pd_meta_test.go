@@ -0,0 +1,129 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// pdMetaHandler serves the metadata APIs of PD and records the paths requested, the paths
+// in failed respond with an error
+func pdMetaHandler(requested *[]string, mu *sync.Mutex, failed string) http.HandlerFunc {
+	responses := map[string]string{
+		"/pd/api/v1/cluster":    `{"id":6818203946312432197,"max_peer_count":3}`,
+		"/pd/api/v1/config":     `{"replication":{"max-replicas":3}}`,
+		"/pd/api/v1/members":    `{"members":[{"name":"pd-1"}]}`,
+		"/pd/api/v1/stores":     `{"count":1,"stores":[{"store":{"id":1,"address":"172.16.5.140:20160"}}]}`,
+		"/pd/api/v1/schedulers": `["balance-leader-scheduler","evict-leader-scheduler"]`,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*requested = append(*requested, r.URL.Path)
+		mu.Unlock()
+		resp, ok := responses[r.URL.Path]
+		if !ok || r.URL.Path == failed {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}
+}
+
+func pdMetaTopology(c *C, server *httptest.Server) *meta.Specification {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 127.0.0.1
+    client_port: `+server.URL[strings.LastIndex(server.URL, ":")+1:]+`
+`), topo), IsNil)
+	return topo
+}
+
+func (s *taskSuite) TestBackupPDMeta(c *C) {
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	server := httptest.NewServer(pdMetaHandler(&requested, &mu, ""))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "backup-pd-meta")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup", "pd-scale-in.json")
+	t := &BackupPDMeta{spec: pdMetaTopology(c, server), operation: "scale-in", path: path}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	sort.Strings(requested)
+	c.Assert(requested, DeepEquals, []string{
+		"/pd/api/v1/cluster", "/pd/api/v1/config", "/pd/api/v1/members", "/pd/api/v1/schedulers", "/pd/api/v1/stores",
+	})
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	var backup PDMetaBackup
+	c.Assert(json.Unmarshal(data, &backup), IsNil)
+	c.Assert(backup.Operation, Equals, "scale-in")
+	c.Assert(backup.PD, DeepEquals, []string{server.Listener.Addr().String()})
+	c.Assert(backup.Time.IsZero(), IsFalse)
+	c.Assert(backup.Metadata, HasLen, 5)
+	var cluster struct {
+		ID uint64 `json:"id"`
+	}
+	c.Assert(json.Unmarshal(backup.Metadata["cluster"], &cluster), IsNil)
+	c.Assert(cluster.ID, Equals, uint64(6818203946312432197))
+	var schedulers []string
+	c.Assert(json.Unmarshal(backup.Metadata["schedulers"], &schedulers), IsNil)
+	c.Assert(schedulers, DeepEquals, []string{"balance-leader-scheduler", "evict-leader-scheduler"})
+
+	// nothing is exported to the plan
+	requested = nil
+	ctx := NewContext()
+	ctx.SetPlan(NewPlan())
+	c.Assert((&BackupPDMeta{spec: t.spec, operation: "upgrade", path: filepath.Join(dir, "plan.json")}).Execute(ctx), IsNil)
+	c.Assert(requested, HasLen, 0)
+	_, err = os.Stat(filepath.Join(dir, "plan.json"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+func (s *taskSuite) TestBackupPDMetaFailed(c *C) {
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	server := httptest.NewServer(pdMetaHandler(&requested, &mu, "/pd/api/v1/stores"))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "backup-pd-meta")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	// no partial backup is left
+	path := filepath.Join(dir, "pd-upgrade.json")
+	t := &BackupPDMeta{spec: pdMetaTopology(c, server), operation: "upgrade", path: path}
+	err = t.Execute(NewContext())
+	c.Assert(err, ErrorMatches, "failed to back up the metadata of PD: failed to get the stores of PD: .*code 500")
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+)
+
+var (
+	errNSBreakpoint = errNS.NewSubNamespace("breakpoint")
+	// ErrBreakpointAborted means the operation is aborted at a breakpoint
+	ErrBreakpointAborted = errNSBreakpoint.NewType("aborted")
+)
+
+// BreakpointHandler decides whether the operation proceeds at a breakpoint, it's called
+// with the name of the phase after the breakpoint once the summary is output
+type BreakpointHandler func(phase string) bool
+
+// SetBreakpointHandler makes the operation pause at the breakpoints until the handler
+// decides whether to proceed, the breakpoints are passed through if it's nil
+func (ctx *Context) SetBreakpointHandler(h BreakpointHandler) {
+	ctx.breakpoint = h
+}
+
+// Breakpoint pauses the operation between the phases, e.g. after the prechecks and before
+// anything is changed on the hosts. The summary of the operation is output, and an error is
+// returned to stop the operation if the handler of the context decides to abort. It's
+// passed through if there's no handler or the commands are recorded to the plan.
+type Breakpoint struct {
+	phase   string
+	summary []string
+}
+
+// Execute implements the Task interface
+func (b *Breakpoint) Execute(ctx *Context) error {
+	if ctx.breakpoint == nil || ctx.Planning() {
+		return nil
+	}
+
+	ctx.Logger().Infof("Paused before %s:\n  - %s", b.phase, strings.Join(b.summary, "\n  - "))
+	if !ctx.breakpoint(b.phase) {
+		return ErrBreakpointAborted.New("The operation is aborted before %s", b.phase).
+			WithProperty(cliutil.SuggestionFromString("Nothing after the breakpoint is performed, run the operation again to go on."))
+	}
+	ctx.Logger().Infof("Proceeding to %s", b.phase)
+	return nil
+}
+
+// Rollback implements the Task interface
+func (b *Breakpoint) Rollback(ctx *Context) error {
+	return nil
+}
+
+// String implements the fmt.Stringer interface
+func (b *Breakpoint) String() string {
+	return fmt.Sprintf("Breakpoint: phase=%s", b.phase)
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// breakpointTask returns a task pausing between the two phases, the applied phase records
+// whether it's executed
+func breakpointTask(applied *bool) Task {
+	return NewBuilder().
+		Func("precheck", func() error { return nil }).
+		Breakpoint("deploying the files", "Cluster: test", "Instances: pd x1 on 1 hosts").
+		Func("apply", func() error {
+			*applied = true
+			return nil
+		}).
+		Build()
+}
+
+func (s *taskSuite) TestBreakpointConfirm(c *C) {
+	ctx := NewContext()
+	l := &captureLogger{}
+	ctx.SetLogger(l)
+	var phases []string
+	ctx.SetBreakpointHandler(func(phase string) bool {
+		phases = append(phases, phase)
+		return true
+	})
+
+	var applied bool
+	c.Assert(breakpointTask(&applied).Execute(ctx), IsNil)
+	c.Assert(applied, IsTrue)
+	c.Assert(phases, DeepEquals, []string{"deploying the files"})
+	c.Assert(l.lines, DeepEquals, []string{
+		"INFO + [ Serial ] - precheck",
+		"INFO + [ Serial ] - Breakpoint: phase=deploying the files",
+		"INFO Paused before deploying the files:\n  - Cluster: test\n  - Instances: pd x1 on 1 hosts",
+		"INFO Proceeding to deploying the files",
+		"INFO + [ Serial ] - apply",
+	})
+}
+
+func (s *taskSuite) TestBreakpointAbort(c *C) {
+	ctx := NewContext()
+	ctx.SetLogger(&captureLogger{})
+	ctx.SetBreakpointHandler(func(string) bool { return false })
+
+	var applied bool
+	err := breakpointTask(&applied).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrBreakpointAborted), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The operation is aborted before deploying the files.*")
+	c.Assert(applied, IsFalse)
+
+	// passed through without a handler, or when recording the plan
+	applied = false
+	c.Assert(breakpointTask(&applied).Execute(NewContext()), IsNil)
+	c.Assert(applied, IsTrue)
+	applied = false
+	ctx.SetPlan(NewPlan())
+	c.Assert(breakpointTask(&applied).Execute(ctx), IsNil)
+	c.Assert(applied, IsTrue)
+}
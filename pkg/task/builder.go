@@ -14,14 +14,21 @@
 package task
 
 import (
+	"crypto/tls"
+	"time"
+
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
 	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"github.com/pingcap-incubator/tiup/pkg/set"
 )
 
 // Builder is used to build TiOps task
 type Builder struct {
-	tasks []Task
+	tasks       []Task
+	concurrency int
+	incremental bool
 }
 
 // NewBuilder returns a *Builder instance
@@ -29,6 +36,20 @@ func NewBuilder() *Builder {
 	return &Builder{}
 }
 
+// Concurrency limits the inner tasks of the parallel tasks appended since then to be executed
+// at most n at the same time, 0 means the limit of the context
+func (b *Builder) Concurrency(n int) *Builder {
+	b.concurrency = n
+	return b
+}
+
+// Incremental makes the packages of the CopyComponent and InstallPackage tasks appended since
+// then synced to the remote incrementally, only the changed files are transferred
+func (b *Builder) Incremental(enabled bool) *Builder {
+	b.incremental = enabled
+	return b
+}
+
 // RootSSH appends a RootSSH task to the current task collection
 func (b *Builder) RootSSH(
 	host string,
@@ -82,7 +103,7 @@ func (b *Builder) ClusterSSH(spec *meta.Specification, deployUser string, sshTim
 		}
 	}
 
-	b.tasks = append(b.tasks, &Parallel{inner: tasks})
+	b.tasks = append(b.tasks, &Parallel{inner: tasks, concurrency: b.concurrency})
 
 	return b
 }
@@ -97,6 +118,16 @@ func (b *Builder) UpdateMeta(cluster string, metadata *meta.ClusterMeta, deleted
 	return b
 }
 
+// ExportTopology appends a ExportTopology task to the current task collection
+func (b *Builder) ExportTopology(cluster string, topo *meta.Specification, path string) *Builder {
+	b.tasks = append(b.tasks, &ExportTopology{
+		cluster: cluster,
+		topo:    topo,
+		path:    path,
+	})
+	return b
+}
+
 // CopyFile appends a CopyFile task to the current task collection
 func (b *Builder) CopyFile(src, dst, server string, download bool) *Builder {
 	b.tasks = append(b.tasks, &CopyFile{
@@ -120,10 +151,425 @@ func (b *Builder) Download(component string, version repository.Version) *Builde
 // CopyComponent appends a CopyComponent task to the current task collection
 func (b *Builder) CopyComponent(component string, version repository.Version, dstHost, dstDir string) *Builder {
 	b.tasks = append(b.tasks, &CopyComponent{
+		component:   component,
+		version:     version,
+		host:        dstHost,
+		dstDir:      dstDir,
+		incremental: b.incremental,
+	})
+	return b
+}
+
+// CheckBinary appends a CheckBinary task to the current task collection
+func (b *Builder) CheckBinary(component string, version repository.Version, dstHost, deployUser, deployDir string) *Builder {
+	b.tasks = append(b.tasks, &CheckBinary{
 		component: component,
 		version:   version,
 		host:      dstHost,
-		dstDir:    dstDir,
+		user:      deployUser,
+		deployDir: deployDir,
+	})
+	return b
+}
+
+// CheckOS appends a CheckOS task to the current task collection
+func (b *Builder) CheckOS(host string, components []string) *Builder {
+	b.tasks = append(b.tasks, &CheckOS{
+		host:       host,
+		components: components,
+	})
+	return b
+}
+
+// ScanLeftover appends a ScanLeftover task to the current task collection
+func (b *Builder) ScanLeftover(host string, units map[int]string, dirs []string, clean bool) *Builder {
+	b.tasks = append(b.tasks, &ScanLeftover{
+		host:  host,
+		units: units,
+		dirs:  dirs,
+		clean: clean,
+	})
+	return b
+}
+
+// Extensions appends an ExtensionPhase task to the current task collection, the tasks of
+// the registered extensions are executed at the phase
+func (b *Builder) Extensions(phase Phase, instances []meta.Instance) *Builder {
+	b.tasks = append(b.tasks, &ExtensionPhase{
+		phase:     phase,
+		instances: instances,
+	})
+	return b
+}
+
+// CheckDataDir appends a CheckDataDir task to the current task collection
+func (b *Builder) CheckDataDir(host string, dirs []string, allowNonEmpty bool) *Builder {
+	b.tasks = append(b.tasks, &CheckDataDir{
+		host:          host,
+		dirs:          dirs,
+		allowNonEmpty: allowNonEmpty,
+	})
+	return b
+}
+
+// ValidationHook appends a ValidationHook task to the current task collection
+func (b *Builder) ValidationHook(command string, payload *HookPayload) *Builder {
+	b.tasks = append(b.tasks, &ValidationHook{
+		command: command,
+		payload: payload,
+	})
+	return b
+}
+
+// CleanupUnits appends a CleanupUnits task to the current task collection
+func (b *Builder) CleanupUnits(units map[string][]string, pendingFile string) *Builder {
+	b.tasks = append(b.tasks, &CleanupUnits{
+		units:       units,
+		pendingFile: pendingFile,
+	})
+	return b
+}
+
+// ReplaceNode appends a ReplaceNode task to the current task collection
+func (b *Builder) ReplaceNode(oldHost string, deploy, join Task, remove func(reachable bool) Task) *Builder {
+	b.tasks = append(b.tasks, &ReplaceNode{
+		oldHost: oldHost,
+		deploy:  deploy,
+		join:    join,
+		remove:  remove,
+	})
+	return b
+}
+
+// CheckReachability appends a CheckReachability task to the current task collection
+func (b *Builder) CheckReachability(hosts []string, ports map[string][]int, maxPeers int) *Builder {
+	b.tasks = append(b.tasks, &CheckReachability{
+		hosts:       hosts,
+		ports:       ports,
+		maxPeers:    maxPeers,
+		concurrency: b.concurrency,
+	})
+	return b
+}
+
+// CheckInitSystem appends a CheckInitSystem task to the current task collection
+func (b *Builder) CheckInitSystem(host string) *Builder {
+	b.tasks = append(b.tasks, &CheckInitSystem{
+		host: host,
+	})
+	return b
+}
+
+// CheckClocksource appends a CheckClocksource task to the current task collection
+func (b *Builder) CheckClocksource(host string) *Builder {
+	b.tasks = append(b.tasks, &CheckClocksource{
+		host: host,
+	})
+	return b
+}
+
+// CheckTimeSync appends a CheckTimeSync task to the current task collection
+func (b *Builder) CheckTimeSync(host string) *Builder {
+	b.tasks = append(b.tasks, &CheckTimeSync{
+		host: host,
+	})
+	return b
+}
+
+// CheckHostname appends a CheckHostname task to the current task collection
+func (b *Builder) CheckHostname(hosts []string, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckHostname{
+		hosts: hosts,
+		fix:   fix,
+	})
+	return b
+}
+
+// CheckLogDevice appends a CheckLogDevice task to the current task collection
+func (b *Builder) CheckLogDevice(host, id, dataDir, logDir string, strict bool) *Builder {
+	b.tasks = append(b.tasks, &CheckLogDevice{
+		host:    host,
+		id:      id,
+		dataDir: dataDir,
+		logDir:  logDir,
+		strict:  strict,
+	})
+	return b
+}
+
+// CheckNproc appends a CheckNproc task to the current task collection
+func (b *Builder) CheckNproc(hosts []string, user string, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckNproc{
+		hosts:   hosts,
+		user:    user,
+		minimum: MinNproc,
+		fix:     fix,
+	})
+	return b
+}
+
+// CheckTimezone appends a CheckTimezone task to the current task collection
+func (b *Builder) CheckTimezone(hosts []string, expected string, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckTimezone{
+		hosts:    hosts,
+		expected: expected,
+		fix:      fix,
+	})
+	return b
+}
+
+// CheckSSH appends a CheckSSH task to the current task collection
+func (b *Builder) CheckSSH(hosts []string) *Builder {
+	b.tasks = append(b.tasks, &CheckSSH{
+		hosts: hosts,
+	})
+	return b
+}
+
+// CheckOSConsistency appends a CheckOSConsistency task to the current task collection
+func (b *Builder) CheckOSConsistency(groups map[string][]string) *Builder {
+	b.tasks = append(b.tasks, &CheckOSConsistency{
+		groups: groups,
+	})
+	return b
+}
+
+// Breakpoint appends a Breakpoint task to the current task collection, the operation
+// pauses there with the summary before the phase
+func (b *Builder) Breakpoint(phase string, summary ...string) *Builder {
+	b.tasks = append(b.tasks, &Breakpoint{
+		phase:   phase,
+		summary: summary,
+	})
+	return b
+}
+
+// CheckUpgradePath appends a CheckUpgradePath task to the current task collection
+func (b *Builder) CheckUpgradePath(from, to string) *Builder {
+	b.tasks = append(b.tasks, &CheckUpgradePath{
+		from: from,
+		to:   to,
+	})
+	return b
+}
+
+// CheckDeployUser appends a CheckDeployUser task to the current task collection
+func (b *Builder) CheckDeployUser(host, user string) *Builder {
+	b.tasks = append(b.tasks, &CheckDeployUser{
+		host: host,
+		user: user,
+	})
+	return b
+}
+
+// CheckFileLimits appends a CheckFileLimits task to the current task collection
+func (b *Builder) CheckFileLimits(instances []meta.Instance) *Builder {
+	b.tasks = append(b.tasks, &CheckFileLimits{
+		instances: instances,
+	})
+	return b
+}
+
+// CheckSwap appends a CheckSwap task to the current task collection
+func (b *Builder) CheckSwap(hosts []string, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckSwap{
+		hosts: hosts,
+		fix:   fix,
+	})
+	return b
+}
+
+// CheckPortRange appends a CheckPortRange task to the current task collection
+func (b *Builder) CheckPortRange(host string, ports map[int]string) *Builder {
+	b.tasks = append(b.tasks, &CheckPortRange{
+		host:  host,
+		ports: ports,
+	})
+	return b
+}
+
+// CheckBlockDevice appends a CheckBlockDevice task to the current task collection
+func (b *Builder) CheckBlockDevice(host string, dirs []string, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckBlockDevice{
+		host: host,
+		dirs: dirs,
+		fix:  fix,
+	})
+	return b
+}
+
+// CheckDNS appends a CheckDNS task to the current task collection
+func (b *Builder) CheckDNS(hosts []string) *Builder {
+	b.tasks = append(b.tasks, &CheckDNS{hosts: hosts})
+	return b
+}
+
+// CheckConfigReload appends a CheckConfigReload task to the current task collection
+func (b *Builder) CheckConfigReload(applied, current *meta.Specification) *Builder {
+	b.tasks = append(b.tasks, &CheckConfigReload{
+		applied: applied,
+		current: current,
+	})
+	return b
+}
+
+// CheckListenAddress appends a CheckListenAddress task to the current task collection
+func (b *Builder) CheckListenAddress(instances []meta.Instance) *Builder {
+	b.tasks = append(b.tasks, &CheckListenAddress{
+		instances: instances,
+	})
+	return b
+}
+
+// CheckSymlink appends a CheckSymlink task to the current task collection
+func (b *Builder) CheckSymlink(host string, dirs []string, allowed []string) *Builder {
+	b.tasks = append(b.tasks, &CheckSymlink{
+		host:    host,
+		dirs:    dirs,
+		allowed: allowed,
+	})
+	return b
+}
+
+// CheckNUMA appends a CheckNUMA task to the current task collection, nodes are the
+// numa_node of the instances on the host by the ID
+func (b *Builder) CheckNUMA(host string, nodes map[string]string) *Builder {
+	b.tasks = append(b.tasks, &CheckNUMA{
+		host:  host,
+		nodes: nodes,
+	})
+	return b
+}
+
+// CheckUtility appends a CheckUtility task to the current task collection
+func (b *Builder) CheckUtility(host string, utilities []string) *Builder {
+	b.tasks = append(b.tasks, &CheckUtility{
+		host:      host,
+		utilities: utilities,
+	})
+	return b
+}
+
+// CompactTiKV appends a CompactTiKV task to the current task collection
+func (b *Builder) CompactTiKV(spec *meta.Specification, instances []meta.Instance, options operator.CompactOptions, retryOpt *utils.RetryOption) *Builder {
+	b.tasks = append(b.tasks, &CompactTiKV{
+		spec:      spec,
+		instances: instances,
+		options:   options,
+		retryOpt:  retryOpt,
+	})
+	return b
+}
+
+// PauseScheduling appends a PauseScheduling task to the current task collection
+func (b *Builder) PauseScheduling(spec *meta.Specification, path string) *Builder {
+	b.tasks = append(b.tasks, &PauseScheduling{
+		spec: spec,
+		path: path,
+	})
+	return b
+}
+
+// ResumeScheduling appends a ResumeScheduling task to the current task collection
+func (b *Builder) ResumeScheduling(spec *meta.Specification, path string) *Builder {
+	b.tasks = append(b.tasks, &ResumeScheduling{
+		spec: spec,
+		path: path,
+	})
+	return b
+}
+
+// BackupPDMeta appends a BackupPDMeta task to the current task collection
+func (b *Builder) BackupPDMeta(spec *meta.Specification, operation, path string) *Builder {
+	b.tasks = append(b.tasks, &BackupPDMeta{
+		spec:      spec,
+		operation: operation,
+		path:      path,
+	})
+	return b
+}
+
+// CheckTLS appends a CheckTLS task to the current task collection
+func (b *Builder) CheckTLS(endpoints []string, tlsConfig *tls.Config, timeout time.Duration) *Builder {
+	b.tasks = append(b.tasks, &CheckTLS{
+		endpoints: endpoints,
+		tlsConfig: tlsConfig,
+		timeout:   timeout,
+	})
+	return b
+}
+
+// VerifyNUMABinding appends a VerifyNUMABinding task to the current task collection
+func (b *Builder) VerifyNUMABinding(inst meta.Instance, deployDir string) *Builder {
+	b.tasks = append(b.tasks, &VerifyNUMABinding{
+		instance:  inst,
+		deployDir: deployDir,
+	})
+	return b
+}
+
+// CheckHardware appends a CheckHardware task to the current task collection
+func (b *Builder) CheckHardware(groups map[string][]HardwareNode, tolerance float64) *Builder {
+	b.tasks = append(b.tasks, &CheckHardware{
+		groups:    groups,
+		tolerance: tolerance,
+	})
+	return b
+}
+
+// CheckSecurityModule appends a CheckSecurityModule task to the current task collection
+func (b *Builder) CheckSecurityModule(hosts []string, allowEnforcing, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckSecurityModule{
+		hosts:          hosts,
+		allowEnforcing: allowEnforcing,
+		fix:            fix,
+	})
+	return b
+}
+
+// CheckVersion appends a CheckVersion task to the current task collection
+func (b *Builder) CheckVersion(spec *meta.Specification, version string) *Builder {
+	b.tasks = append(b.tasks, &CheckVersion{
+		spec:    spec,
+		version: version,
+	})
+	return b
+}
+
+// VerifyOperation appends a VerifyOperation task running the verifications after the
+// operation to the current task collection
+func (b *Builder) VerifyOperation(operation, cluster string, checks ...Verification) *Builder {
+	b.tasks = append(b.tasks, &VerifyOperation{
+		operation: operation,
+		cluster:   cluster,
+		checks:    checks,
+	})
+	return b
+}
+
+// CheckHealth appends a CheckHealth task to the current task collection
+func (b *Builder) CheckHealth(spec *meta.Specification) *Builder {
+	b.tasks = append(b.tasks, &CheckHealth{
+		spec: spec,
+	})
+	return b
+}
+
+// LogRotate appends a LogRotate task to the current task collection
+func (b *Builder) LogRotate(entries []LogRotateEntry, options LogRotateOptions) *Builder {
+	b.tasks = append(b.tasks, &LogRotate{
+		entries: entries,
+		options: options,
+	})
+	return b
+}
+
+// CheckFirewall appends a CheckFirewall task to the current task collection
+func (b *Builder) CheckFirewall(host string, ports []int, fix bool) *Builder {
+	b.tasks = append(b.tasks, &CheckFirewall{
+		host:  host,
+		ports: ports,
+		fix:   fix,
 	})
 	return b
 }
@@ -131,9 +577,10 @@ func (b *Builder) CopyComponent(component string, version repository.Version, ds
 // InstallPackage appends a InstallPackage task to the current task collection
 func (b *Builder) InstallPackage(srcPath, dstHost, dstDir string) *Builder {
 	b.tasks = append(b.tasks, &InstallPackage{
-		srcPath: srcPath,
-		host:    dstHost,
-		dstDir:  dstDir,
+		srcPath:     srcPath,
+		host:        dstHost,
+		dstDir:      dstDir,
+		incremental: b.incremental,
 	})
 	return b
 }
@@ -161,6 +608,109 @@ func (b *Builder) InitConfig(clusterName, clusterVersion string, inst meta.Insta
 	return b
 }
 
+// CheckedInitConfig appends an InitConfig task whose result is validated by the component
+func (b *Builder) CheckedInitConfig(clusterName, clusterVersion string, inst meta.Instance, deployUser string, paths meta.DirPaths) *Builder {
+	b.tasks = append(b.tasks, &CheckConfig{
+		inner: &InitConfig{
+			clusterName:    clusterName,
+			clusterVersion: clusterVersion,
+			instance:       inst,
+			deployUser:     deployUser,
+			paths:          paths,
+		},
+		instance:  inst,
+		deployDir: paths.Deploy,
+	})
+	return b
+}
+
+// PushConfig appends a PushConfig task to the current task collection
+func (b *Builder) PushConfig(clusterName, clusterVersion, deployUser string, instances []meta.Instance, units bool) *Builder {
+	b.tasks = append(b.tasks, &PushConfig{
+		clusterName:    clusterName,
+		clusterVersion: clusterVersion,
+		deployUser:     deployUser,
+		instances:      instances,
+		units:          units,
+	})
+	return b
+}
+
+// VerifyLayout appends a VerifyLayout task to the current task collection
+func (b *Builder) VerifyLayout(clusterName, clusterVersion string, version repository.Version, inst meta.Instance, deployUser string, paths meta.DirPaths, repair bool) *Builder {
+	b.tasks = append(b.tasks, &VerifyLayout{
+		clusterName:    clusterName,
+		clusterVersion: clusterVersion,
+		version:        version,
+		instance:       inst,
+		deployUser:     deployUser,
+		paths:          paths,
+		repair:         repair,
+	})
+	return b
+}
+
+// CheckMonitorTargets appends a CheckMonitorTargets task to the current task collection
+func (b *Builder) CheckMonitorTargets(clusterName, clusterVersion string, inst *meta.MonitorInstance, deployUser string, paths meta.DirPaths, regenerate bool) *Builder {
+	b.tasks = append(b.tasks, &CheckMonitorTargets{
+		clusterName:    clusterName,
+		clusterVersion: clusterVersion,
+		instance:       inst,
+		deployUser:     deployUser,
+		paths:          paths,
+		regenerate:     regenerate,
+	})
+	return b
+}
+
+// MigrateMonitorData appends a MigrateMonitorData task to the current task collection
+func (b *Builder) MigrateMonitorData(clusterName, clusterVersion string, inst meta.Instance, deployUser, srcDir string, paths meta.DirPaths) *Builder {
+	b.tasks = append(b.tasks, &MigrateMonitorData{
+		clusterName:    clusterName,
+		clusterVersion: clusterVersion,
+		inst:           inst,
+		deployUser:     deployUser,
+		srcDir:         srcDir,
+		paths:          paths,
+	})
+	return b
+}
+
+// TransferMonitor appends a TransferMonitor task to the current task collection, the
+// migrate and repoint tasks are optional
+func (b *Builder) TransferMonitor(deploy, migrate, join, verify, repoint, decommission Task) *Builder {
+	b.tasks = append(b.tasks, &TransferMonitor{
+		deploy:       deploy,
+		migrate:      migrate,
+		join:         join,
+		verify:       verify,
+		repoint:      repoint,
+		decommission: decommission,
+	})
+	return b
+}
+
+// CopyMonitorData appends a CopyMonitorData task to the current task collection
+func (b *Builder) CopyMonitorData(srcHost, srcDir, dstHost, dstDir, cache string) *Builder {
+	b.tasks = append(b.tasks, &CopyMonitorData{
+		srcHost: srcHost,
+		srcDir:  srcDir,
+		dstHost: dstHost,
+		dstDir:  dstDir,
+		cache:   cache,
+	})
+	return b
+}
+
+// CheckMonitorHealth appends a CheckMonitorHealth task to the current task collection
+func (b *Builder) CheckMonitorHealth(instances []meta.Instance, timeout time.Duration) *Builder {
+	b.tasks = append(b.tasks, &CheckMonitorHealth{
+		instances: instances,
+		timeout:   timeout,
+	})
+	return b
+}
+
 // ScaleConfig generate temporary config on scaling
 func (b *Builder) ScaleConfig(clusterName, clusterVersion string, base *meta.TopologySpecification, inst meta.Instance, deployUser string, paths meta.DirPaths) *Builder {
 	b.tasks = append(b.tasks, &ScaleConfig{
@@ -230,6 +780,54 @@ func (b *Builder) ClusterOperate(
 	return b
 }
 
+// RollingRestart appends the tasks to restart the cluster component by component,
+// the instances of a component are restarted in batches sized by the policy. If the
+// zones are given, the instances are restarted zone by zone, and the batches of a zone
+// are all done before the next zone. The concurrency of PD and TiKV is clamped below the
+// majority, and their leaders are transferred before each instance is restarted.
+func (b *Builder) RollingRestart(spec *meta.Specification, options operator.Options, policy operator.ConcurrencyPolicy, zones []operator.Zone) *Builder {
+	policy = policy.Clamp(spec)
+	roleFilter := set.NewStringSet(options.Roles...)
+	nodeFilter := set.NewStringSet(options.Nodes...)
+	if len(zones) == 0 {
+		zones = []operator.Zone{{}}
+	}
+	for _, zone := range zones {
+		for _, com := range operator.FilterComponent(spec.ComponentsByStartOrder(), roleFilter) {
+			insts := operator.FilterInstance(com.Instances(), nodeFilter)
+			if zone.Nodes != nil {
+				insts = operator.FilterInstance(insts, zone.Nodes)
+			}
+			for _, batch := range policy.Batches(insts) {
+				if com.Name() == meta.ComponentCDC && !options.Force {
+					b.tasks = append(b.tasks, &DrainCDC{spec: spec, instances: batch, options: options})
+				}
+				if com.Name() == meta.ComponentDrainer && !options.Force {
+					b.tasks = append(b.tasks, &WaitDrainerSynced{instances: batch, options: options})
+				}
+				var tasks []Task
+				for _, inst := range batch {
+					tasks = append(tasks, &RestartInstance{spec: spec, instance: inst, options: options})
+				}
+				b.tasks = append(b.tasks, &Parallel{inner: tasks, concurrency: b.concurrency})
+			}
+		}
+	}
+	return b
+}
+
+// GracefulStopPD appends a GracefulStopPD task to the current task collection, stopping is
+// the names of all the PD instances stopped in the operation
+func (b *Builder) GracefulStopPD(spec *meta.Specification, inst *meta.PDInstance, stopping []string, retryOpt *utils.RetryOption) *Builder {
+	b.tasks = append(b.tasks, &GracefulStopPD{
+		pdList:   spec.GetPDList(),
+		instance: inst,
+		stopping: stopping,
+		retryOpt: retryOpt,
+	})
+	return b
+}
+
 // Mkdir appends a Mkdir task to the current task collection
 func (b *Builder) Mkdir(user, host string, dirs ...string) *Builder {
 	b.tasks = append(b.tasks, &Mkdir{
@@ -253,6 +851,54 @@ func (b *Builder) Chown(user, host string, dirs ...string) *Builder {
 	return b
 }
 
+// DirPermission appends a DirPermission task to the current task collection
+func (b *Builder) DirPermission(user, host, mode string, dirs ...string) *Builder {
+	b.tasks = append(b.tasks, &DirPermission{
+		user: user,
+		host: host,
+		mode: mode,
+		dirs: dirs,
+	})
+	return b
+}
+
+// ValidateConfig appends a ValidateConfig task to the current task collection
+func (b *Builder) ValidateConfig(topo *meta.Specification, version string) *Builder {
+	b.tasks = append(b.tasks, &ValidateConfig{
+		topo:    topo,
+		version: version,
+	})
+	return b
+}
+
+// ValidateTiFlashConfig appends a ValidateTiFlashConfig task to the current task collection
+func (b *Builder) ValidateTiFlashConfig(topo *meta.Specification, user string) *Builder {
+	b.tasks = append(b.tasks, &ValidateTiFlashConfig{
+		topo: topo,
+		user: user,
+	})
+	return b
+}
+
+// ValidatePolicy appends a ValidatePolicy task to the current task collection
+func (b *Builder) ValidatePolicy(topo *meta.Specification, policy *Policy, removed []string) *Builder {
+	b.tasks = append(b.tasks, &ValidatePolicy{
+		topo:    topo,
+		policy:  policy,
+		removed: set.NewStringSet(removed...),
+	})
+	return b
+}
+
+// ValidateLabels appends a ValidateLabels task to the current task collection
+func (b *Builder) ValidateLabels(topo *meta.Specification, rulesPath string) *Builder {
+	b.tasks = append(b.tasks, &ValidateLabels{
+		topo:      topo,
+		rulesPath: rulesPath,
+	})
+	return b
+}
+
 // Shell command on cluster host
 func (b *Builder) Shell(host, command string, sudo bool) *Builder {
 	b.tasks = append(b.tasks, &Shell{
@@ -263,9 +909,29 @@ func (b *Builder) Shell(host, command string, sudo bool) *Builder {
 	return b
 }
 
+// ShellInDir runs the command on cluster host in the working directory, which must be
+// inside the deploy directory
+func (b *Builder) ShellInDir(host, deployDir, workDir, command string, sudo bool) *Builder {
+	b.tasks = append(b.tasks, &Shell{
+		host:      host,
+		command:   command,
+		sudo:      sudo,
+		deployDir: deployDir,
+		workDir:   workDir,
+	})
+	return b
+}
+
 // Parallel appends a parallel task to the current task collection
 func (b *Builder) Parallel(tasks ...Task) *Builder {
-	b.tasks = append(b.tasks, &Parallel{inner: tasks})
+	b.tasks = append(b.tasks, &Parallel{inner: tasks, concurrency: b.concurrency})
+	return b
+}
+
+// RetryParallel appends a parallel task which retries the failed tasks for at most
+// rounds times, the delay between rounds starts from backoff and is doubled every round
+func (b *Builder) RetryParallel(rounds int, backoff time.Duration, tasks ...Task) *Builder {
+	b.tasks = append(b.tasks, &RetryParallel{inner: tasks, rounds: rounds, backoff: backoff, concurrency: b.concurrency})
 	return b
 }
 
@@ -275,6 +941,15 @@ func (b *Builder) Serial(tasks ...Task) *Builder {
 	return b
 }
 
+// Timeout appends the tasks which should be finished in the specified duration
+func (b *Builder) Timeout(timeout time.Duration, tasks ...Task) *Builder {
+	b.tasks = append(b.tasks, &Timeout{
+		inner:   &Serial{inner: tasks},
+		timeout: timeout,
+	})
+	return b
+}
+
 // Build returns a task that contains all tasks appended by previous operation
 func (b *Builder) Build() Task {
 	// Serial handles event internally. So the following 3 lines are commented out.
@@ -293,7 +968,7 @@ func (b *Builder) Step(prefix string, inner Task) *Builder {
 // ParallelStep appends a new ParallelStepDisplay task, which will print multi line progress in parallel
 // for inner tasks. Inner tasks must be a StepDisplay task.
 func (b *Builder) ParallelStep(prefix string, tasks ...*StepDisplay) *Builder {
-	b.tasks = append(b.tasks, newParallelStepDisplay(prefix, tasks...))
+	b.tasks = append(b.tasks, newParallelStepDisplay(prefix, b.concurrency, tasks...))
 	return b
 }
 
@@ -0,0 +1,31 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/logger"
+)
+
+// SetChangeID sets the id of the change, e.g. the ticket, which the operation is performed
+// for. It's stamped on the audit and debug logs, and the task events of the context.
+func (ctx *Context) SetChangeID(id string) {
+	ctx.changeID = id
+	ctx.ev.changeID = id
+	logger.SetChangeID(id)
+}
+
+// ChangeID returns the id of the change, empty if it's not set
+func (ctx *Context) ChangeID() string {
+	return ctx.changeID
+}
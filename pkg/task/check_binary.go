@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSCheckBinary = errNS.NewSubNamespace("check_binary")
+	// ErrBinaryExecuteFailed means the binary of a component can not be executed on the target host
+	ErrBinaryExecuteFailed = errNSCheckBinary.NewType("execute_failed", errutil.ErrTraitPreCheck)
+)
+
+// componentBinary is the main binary inside the package of a component, and the
+// flag to print its version
+type componentBinary struct {
+	path        string
+	versionFlag string
+}
+
+// componentBinaries is the main binaries of the components, the components not
+// listed are not checked
+var componentBinaries = map[string]componentBinary{
+	meta.ComponentTiDB:             {"tidb-server", "-V"},
+	meta.ComponentTiKV:             {"tikv-server", "--version"},
+	meta.ComponentPD:               {"pd-server", "--version"},
+	meta.ComponentTiFlash:          {"tiflash/tiflash", "--version"},
+	meta.ComponentTiProxy:          {"tiproxy", "--version"},
+	meta.ComponentPump:             {"pump", "-V"},
+	meta.ComponentDrainer:          {"drainer", "-V"},
+	meta.ComponentCDC:              {"cdc", "version"},
+	meta.ComponentNodeExporter:     {"node_exporter/node_exporter", "--version"},
+	meta.ComponentBlackboxExporter: {"blackbox_exporter/blackbox_exporter", "--version"},
+}
+
+// CheckBinary is used to copy only the main binary of a component to the target host
+// and print its version to make sure it can be executed there (e.g. glibc, arch
+// and SELinux problems), before the whole package is deployed. The binary is copied to
+// a temporary directory under the deploy directory, which is on the filesystem the
+// component is executed from.
+type CheckBinary struct {
+	component string
+	version   repository.Version
+	host      string
+	user      string
+	deployDir string
+
+	// the version printed by the binary
+	output string
+}
+
+// Execute implements the Task interface
+func (c *CheckBinary) Execute(ctx *Context) error {
+	bin, ok := componentBinaries[c.component]
+	if !ok {
+		return nil
+	}
+
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	// Extract the binary locally so that only the binary is transferred
+	fileName := fmt.Sprintf("%s-%s-linux-amd64.tar.gz", c.component, c.version)
+	srcPath, err := extractFromPackage(meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName), bin.path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(srcPath)
+
+	// the deploy directory may not be created yet
+	cmd := fmt.Sprintf("mkdir -p %s && chown %s:$(id -gn %s) %s", c.deployDir, c.user, c.user, c.deployDir)
+	if _, _, err := exec.Execute(cmd, true); err != nil {
+		return errors.Trace(err)
+	}
+	tmpDir := path.Join(c.deployDir, fmt.Sprintf(".tiup-check-%s.XXXXXX", c.component))
+	stdout, _, err := exec.Execute(fmt.Sprintf("mktemp -d %s", tmpDir), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to create a temporary directory in %s:%s", c.host, c.deployDir)
+	}
+	// nothing is created when planning, the template is kept in the plan
+	if !ctx.Planning() {
+		tmpDir = strings.TrimSpace(string(stdout))
+	}
+	defer func() {
+		if _, _, err := exec.Execute(fmt.Sprintf("rm -rf %s", tmpDir), false); err != nil {
+			log.Warnf("Failed to clean up %s:%s, %v", c.host, tmpDir, err)
+		}
+	}()
+	dstPath := path.Join(tmpDir, path.Base(bin.path))
+	if err := exec.Transfer(srcPath, dstPath, false); err != nil {
+		return errors.Trace(err)
+	}
+
+	stdout, stderr, err := exec.Execute(fmt.Sprintf("chmod +x %s && %s %s", dstPath, dstPath, bin.versionFlag), false)
+	if err != nil {
+		output := strings.TrimSpace(string(stderr))
+		if output == "" {
+			output = strings.TrimSpace(string(stdout))
+		}
+		return ErrBinaryExecuteFailed.
+			Wrap(err, "Failed to execute %s %s on %s: %s", c.component, c.version, c.host, output).
+			WithProperty(cliutil.SuggestionFromString("Please check the glibc version, CPU architecture and SELinux policy of the host."))
+	}
+
+	// some components print the version to stderr
+	output := strings.TrimSpace(string(stdout))
+	if output == "" {
+		output = strings.TrimSpace(string(stderr))
+	}
+	c.output = output
+	log.Infof("\t%s %s on %s: %s", c.component, c.version, c.host, strings.Split(output, "\n")[0])
+	return nil
+}
+
+// Output returns the version printed by the binary
+func (c *CheckBinary) Output() string {
+	return c.output
+}
+
+// extractFromPackage extracts the file with the name from the tar.gz package to a temporary file
+func extractFromPackage(pkgPath, name string) (string, error) {
+	f, err := os.Open(pkgPath)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to read package %s", pkgPath)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return "", errors.Errorf("%s not found in package %s", name, pkgPath)
+		}
+		if err != nil {
+			return "", errors.Annotatef(err, "failed to read package %s", pkgPath)
+		}
+		if path.Clean(hdr.Name) != name {
+			continue
+		}
+
+		tmp, err := ioutil.TempFile("", path.Base(name))
+		if err != nil {
+			return "", errors.Trace(err)
+		}
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, tr); err != nil {
+			os.Remove(tmp.Name())
+			return "", errors.Trace(err)
+		}
+		return tmp.Name(), nil
+	}
+}
+
+// Rollback implements the Task interface
+func (c *CheckBinary) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckBinary) String() string {
+	return fmt.Sprintf("CheckBinary: component=%s, version=%s, remote=%s:%s", c.component, c.version, c.host, c.deployDir)
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// writePackage writes a component package which contains the files
+func writePackage(c *C, pkgPath string, files map[string]string) {
+	c.Assert(os.MkdirAll(filepath.Dir(pkgPath), 0755), IsNil)
+	f, err := os.Create(pkgPath)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		c.Assert(tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content))}), IsNil)
+		_, err := tw.Write([]byte(content))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(tw.Close(), IsNil)
+	c.Assert(gw.Close(), IsNil)
+}
+
+func setupCheckBinary(c *C) string {
+	dir, err := ioutil.TempDir("", "check-binary")
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentDataDir, dir)
+	c.Assert(meta.Initialize(), IsNil)
+
+	writePackage(c, meta.ProfilePath(meta.TiOpsPackageCacheDir, "tikv-v4.0.0-linux-amd64.tar.gz"), map[string]string{
+		"tikv-server": "tikv binary",
+		"tikv-ctl":    "tikv-ctl binary",
+	})
+	return dir
+}
+
+func (s *taskSuite) TestCheckBinary(c *C) {
+	dir := setupCheckBinary(c)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case strings.HasPrefix(cmd, "mktemp"):
+			return []byte("/home/tidb/deploy/tikv-20160/.tiup-check-tikv.a1B2c3\n"), nil, nil
+		case strings.HasSuffix(cmd, "--version"):
+			return []byte("TiKV \nRelease Version:   4.0.0\nEdition:           Community\n"), nil, nil
+		}
+		return nil, nil, nil
+	}}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckBinary{component: meta.ComponentTiKV, version: "v4.0.0", host: "172.16.5.140", user: "tidb", deployDir: "/home/tidb/deploy/tikv-20160"}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Output(), Equals, "TiKV \nRelease Version:   4.0.0\nEdition:           Community")
+
+	// only the binary is transferred, to the temporary directory under the deploy directory
+	tmp := "/home/tidb/deploy/tikv-20160/.tiup-check-tikv.a1B2c3"
+	c.Assert(e.transfers, HasLen, 1)
+	c.Assert(e.transfers[0], Matches, ".* -> "+tmp+"/tikv-server")
+	c.Assert(e.commands(), DeepEquals, []string{
+		"mkdir -p /home/tidb/deploy/tikv-20160 && chown tidb:$(id -gn tidb) /home/tidb/deploy/tikv-20160",
+		"mktemp -d /home/tidb/deploy/tikv-20160/.tiup-check-tikv.XXXXXX",
+		"chmod +x " + tmp + "/tikv-server && " + tmp + "/tikv-server --version",
+		"rm -rf " + tmp,
+	})
+}
+
+func (s *taskSuite) TestCheckBinaryFailed(c *C) {
+	dir := setupCheckBinary(c)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case strings.HasPrefix(cmd, "mktemp"):
+			return []byte("/data/deploy/.tiup-check-tikv.a1B2c3\n"), nil, nil
+		case strings.HasSuffix(cmd, "--version"):
+			return nil, []byte("bash: /data/deploy/.tiup-check-tikv.a1B2c3/tikv-server: cannot execute binary file"), errors.New("exit status 126")
+		}
+		return nil, nil, nil
+	}}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckBinary{component: meta.ComponentTiKV, version: "v4.0.0", host: "172.16.5.140", user: "tidb", deployDir: "/data/deploy"}
+	err := t.Execute(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*Failed to execute tikv v4.0.0 on 172.16.5.140: .*cannot execute binary file.*")
+	c.Assert(t.Output(), Equals, "")
+
+	// exactly the temporary directory is cleaned up anyway
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "rm -rf /data/deploy/.tiup-check-tikv.a1B2c3")
+}
+
+func (s *taskSuite) TestCheckBinaryUnknownComponent(c *C) {
+	e := &mockExecutor{}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckBinary{component: meta.ComponentGrafana, version: "v4.0.0", host: "172.16.5.140", user: "tidb", deployDir: "/data/deploy"}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(e.commands(), HasLen, 0)
+}
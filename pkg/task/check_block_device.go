@@ -0,0 +1,321 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSBlockDevice = errNS.NewSubNamespace("block_device")
+	// ErrBlockDeviceUnfixed means the I/O scheduler or the read-ahead of some data disks are
+	// still suboptimal after they're set
+	ErrBlockDeviceUnfixed = errNSBlockDevice.NewType("unfixed", errutil.ErrTraitPreCheck)
+)
+
+// maxReadAheadKB is the max read-ahead of the data disks, the larger ones waste the I/O
+// bandwidth on the random reads of TiKV
+const maxReadAheadKB = 128
+
+// The preferred I/O schedulers of the data disks, in the order of preference
+var (
+	ssdSchedulers = []string{"none", "noop"}
+	hddSchedulers = []string{"mq-deadline", "deadline"}
+)
+
+// BlockDevice is the settings of the block device backing some data directories
+type BlockDevice struct {
+	Name       string
+	Dirs       []string
+	Rotational bool
+	// the current I/O scheduler and the available ones
+	Scheduler   string
+	Schedulers  []string
+	ReadAheadKB int
+}
+
+// preferredScheduler returns the preferred I/O scheduler available of the device, empty
+// if none of them is available
+func (d *BlockDevice) preferredScheduler() string {
+	preferred := ssdSchedulers
+	if d.Rotational {
+		preferred = hddSchedulers
+	}
+	for _, s := range preferred {
+		for _, available := range d.Schedulers {
+			if s == available {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// problems returns the suboptimal settings of the device
+func (d *BlockDevice) problems() []string {
+	var problems []string
+	preferred := ssdSchedulers
+	if d.Rotational {
+		preferred = hddSchedulers
+	}
+	optimal := false
+	for _, s := range preferred {
+		optimal = optimal || d.Scheduler == s
+	}
+	// the schedulers are absent on some virtual devices, e.g. the ones of Xen
+	if !optimal && len(d.Schedulers) > 0 {
+		problems = append(problems, fmt.Sprintf("the I/O scheduler is %s rather than %s", d.Scheduler, strings.Join(preferred, " or ")))
+	}
+	if d.ReadAheadKB > maxReadAheadKB {
+		problems = append(problems, fmt.Sprintf("the read-ahead is %dKB, larger than %dKB", d.ReadAheadKB, maxReadAheadKB))
+	}
+	return problems
+}
+
+// CheckBlockDevice is used to check the I/O scheduler and the read-ahead of the block devices
+// backing the data directories on the host. The suboptimal ones are warned, or set if fix is
+// enabled and persisted by a udev rule of each device, then read again to verify them.
+type CheckBlockDevice struct {
+	inspection
+
+	host string
+	dirs []string
+	fix  bool
+
+	devices []*BlockDevice
+}
+
+// Execute implements the Task interface
+func (c *CheckBlockDevice) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	if err := c.readDevices(e); err != nil {
+		return err
+	}
+
+	var suboptimal []*BlockDevice
+	for _, d := range c.devices {
+		if len(d.problems()) > 0 {
+			suboptimal = append(suboptimal, d)
+		}
+	}
+	if len(suboptimal) > 0 && c.fix {
+		for _, d := range suboptimal {
+			log.Infof("Tuning the block device %s of %s", d.Name, c.host)
+			if err := c.tune(e, d, d.preferredScheduler()); err != nil {
+				return err
+			}
+		}
+		if err := c.readDevices(e); err != nil {
+			return err
+		}
+	}
+
+	rows := [][]string{{"Device", "Data Dirs", "Scheduler", "Read Ahead"}}
+	var problems []string
+	for _, d := range c.devices {
+		rows = append(rows, []string{d.Name, strings.Join(d.Dirs, ","), d.Scheduler, fmt.Sprintf("%dKB", d.ReadAheadKB)})
+		for _, p := range d.problems() {
+			problems = append(problems, fmt.Sprintf("%s: %s", d.Name, p))
+		}
+	}
+	cliutil.PrintTable(rows, true)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if !c.fix {
+		log.Warnf("The data disks of %s are not tuned for TiKV, please deploy with --fix-block-device to set them:\n  - %s", c.host, strings.Join(problems, "\n  - "))
+		return nil
+	}
+	return ErrBlockDeviceUnfixed.
+		New("Failed to tune the data disks of %s:\n  - %s", c.host, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please set the scheduler and the read-ahead by /sys/block/<device>/queue/{scheduler,read_ahead_kb} and a udev rule, the read-ahead should not be larger than %dKB.", maxReadAheadKB)))
+}
+
+// readDevices reads the settings of the devices backing the data directories
+func (c *CheckBlockDevice) readDevices(e executor.TiOpsExecutor) error {
+	c.devices = nil
+	byName := make(map[string]*BlockDevice)
+	for _, dir := range c.dirs {
+		stdout, stderr, err := e.Execute(blockDeviceCmd(dir), false)
+		if err != nil {
+			return errors.Annotatef(err, "failed to get the block device of %s on %s, stderr: %s", dir, c.host, stderr)
+		}
+		name := parseBlockDevice(string(stdout))
+		if name == "" {
+			// e.g. tmpfs or overlay in containers
+			log.Warnf("Skip checking the block device of %s on %s as it's not on a block device", dir, c.host)
+			continue
+		}
+		if d, ok := byName[name]; ok {
+			d.Dirs = append(d.Dirs, dir)
+			continue
+		}
+		d := &BlockDevice{Name: name, Dirs: []string{dir}}
+		if err := readBlockDevice(e, d); err != nil {
+			return errors.Annotatef(err, "failed to get the settings of %s on %s", name, c.host)
+		}
+		byName[name] = d
+		c.devices = append(c.devices, d)
+	}
+	return nil
+}
+
+// tune sets the scheduler and the read-ahead of the device, and persists them by a udev rule
+func (c *CheckBlockDevice) tune(e executor.TiOpsExecutor, d *BlockDevice, scheduler string) error {
+	queue := fmt.Sprintf("/sys/block/%s/queue", d.Name)
+	var (
+		cmds  []string
+		attrs []string
+	)
+	if scheduler != "" && scheduler != d.Scheduler {
+		cmds = append(cmds, fmt.Sprintf("echo %s > %s/scheduler", scheduler, queue))
+		attrs = append(attrs, fmt.Sprintf(`ATTR{queue/scheduler}="%s"`, scheduler))
+	}
+	readAhead := d.ReadAheadKB
+	if readAhead > maxReadAheadKB {
+		readAhead = maxReadAheadKB
+		cmds = append(cmds, fmt.Sprintf("echo %d > %s/read_ahead_kb", readAhead, queue))
+	}
+	// the read-ahead is kept in the rule too, as it's reset by some tools when the device changes
+	attrs = append(attrs, fmt.Sprintf(`ATTR{queue/read_ahead_kb}="%d"`, readAhead))
+
+	rule := fmt.Sprintf("ACTION==\"add|change\", KERNEL==\"%s\", %s\n", d.Name, strings.Join(attrs, ", "))
+	content := base64.StdEncoding.EncodeToString([]byte(rule))
+	cmds = append(cmds,
+		fmt.Sprintf("echo %s | base64 -d > %s", content, blockDeviceRulePath(d.Name)),
+		"udevadm control --reload-rules",
+	)
+	for _, cmd := range cmds {
+		if _, stderr, err := e.Execute(cmd, true); err != nil {
+			return errors.Annotatef(err, "failed to tune the block device %s of %s, stderr: %s", d.Name, c.host, stderr)
+		}
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface, the disk of each data directory is supposed
+// to be not tuned at all as it's unknown without reading
+func (c *CheckBlockDevice) planFix(plan *Plan) {
+	if !c.fix {
+		return
+	}
+	for _, dir := range c.dirs {
+		e := plan.conditionalExecutor(c.host, fmt.Sprintf("if the disk of %s is not tuned", dir))
+		d := &BlockDevice{Name: "<disk>", ReadAheadKB: maxReadAheadKB + 1}
+		_ = c.tune(e, d, "<the preferred scheduler>")
+	}
+}
+
+// blockDeviceRulePath returns the path of the udev rule persisting the settings of the device
+func blockDeviceRulePath(name string) string {
+	return fmt.Sprintf("/etc/udev/rules.d/60-tidb-%s.rules", name)
+}
+
+// blockDeviceCmd returns the command printing the block device of the nearest existing
+// directory of dir, the data directories may not be created yet
+func blockDeviceCmd(dir string) string {
+	return fmt.Sprintf(`d=%s; while [ ! -e "$d" ]; do d=$(dirname "$d"); done; lsblk -ndPo KNAME,PKNAME,TYPE $(df -P "$d" | awk 'NR==2 {print $1}') 2>/dev/null || true`, dir)
+}
+
+// parseBlockDevice parses the output of `lsblk -ndPo KNAME,PKNAME,TYPE`, the disk of
+// a partition is returned, e.g. sda of `KNAME="sda1" PKNAME="sda" TYPE="part"`
+func parseBlockDevice(output string) string {
+	fields := make(map[string]string)
+	for _, kv := range strings.Fields(strings.TrimSpace(output)) {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) == 2 {
+			fields[pair[0]] = strings.Trim(pair[1], `"`)
+		}
+	}
+	if fields["TYPE"] == "part" && fields["PKNAME"] != "" {
+		return fields["PKNAME"]
+	}
+	return fields["KNAME"]
+}
+
+// readBlockDevice reads the settings of the device from /sys/block
+func readBlockDevice(e executor.TiOpsExecutor, d *BlockDevice) error {
+	queue := fmt.Sprintf("/sys/block/%s/queue", d.Name)
+	stdout, _, err := e.Execute(fmt.Sprintf("cat %s/scheduler", queue), false)
+	if err != nil {
+		return err
+	}
+	d.Scheduler, d.Schedulers = parseScheduler(string(stdout))
+
+	if stdout, _, err = e.Execute(fmt.Sprintf("cat %s/read_ahead_kb", queue), false); err != nil {
+		return err
+	}
+	if d.ReadAheadKB, err = strconv.Atoi(strings.TrimSpace(string(stdout))); err != nil {
+		return errors.AddStack(err)
+	}
+
+	if stdout, _, err = e.Execute(fmt.Sprintf("cat %s/rotational", queue), false); err != nil {
+		return err
+	}
+	d.Rotational = strings.TrimSpace(string(stdout)) == "1"
+	return nil
+}
+
+// parseScheduler parses the content of the scheduler file, where the current one is in the
+// brackets, e.g. `noop [deadline] cfq`. It's `none` if the device has no scheduler.
+func parseScheduler(output string) (string, []string) {
+	var (
+		current   string
+		available []string
+	)
+	for _, s := range strings.Fields(output) {
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			s = strings.Trim(s, "[]")
+			current = s
+		}
+		available = append(available, s)
+	}
+	if len(available) == 1 && current == "" {
+		current = available[0]
+	}
+	// the device without a scheduler has nothing to choose
+	if len(available) == 1 && available[0] == "none" {
+		available = nil
+	}
+	return current, available
+}
+
+// Devices returns the block devices backing the data directories
+func (c *CheckBlockDevice) Devices() []*BlockDevice {
+	return c.devices
+}
+
+// Rollback implements the Task interface
+func (c *CheckBlockDevice) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckBlockDevice) String() string {
+	return fmt.Sprintf("CheckBlockDevice: host=%s, dirs=%s, fix=%v", c.host, strings.Join(c.dirs, ","), c.fix)
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// blockHost is a mocked host whose /sys/block attributes are changed by echo, or kept if
+// the host is stubborn, e.g. the attributes are read only in a container
+type blockHost struct {
+	mu       sync.Mutex
+	devices  map[string]string // data directory -> the output of lsblk
+	attrs    map[string]string // path -> content
+	rules    map[string]string
+	stubborn bool
+}
+
+func (h *blockHost) executor() *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for dir, out := range h.devices {
+			if cmd == blockDeviceCmd(dir) {
+				return []byte(out), nil, nil
+			}
+		}
+		switch {
+		case strings.HasPrefix(cmd, "cat /sys/block/"):
+			return []byte(h.attrs[strings.TrimPrefix(cmd, "cat ")] + "\n"), nil, nil
+		case h.stubborn:
+		case strings.HasPrefix(cmd, "echo ") && strings.Contains(cmd, " > /sys/block/"):
+			parts := strings.SplitN(strings.TrimPrefix(cmd, "echo "), " > ", 2)
+			value := parts[0]
+			if strings.HasSuffix(parts[1], "/scheduler") {
+				// the current one is in the brackets
+				var schedulers []string
+				for _, s := range strings.Fields(h.attrs[parts[1]]) {
+					if s = strings.Trim(s, "[]"); s == value {
+						s = "[" + s + "]"
+					}
+					schedulers = append(schedulers, s)
+				}
+				value = strings.Join(schedulers, " ")
+			}
+			h.attrs[parts[1]] = value
+		case strings.HasPrefix(cmd, "echo ") && strings.Contains(cmd, " | base64 -d > "):
+			parts := strings.SplitN(strings.TrimPrefix(cmd, "echo "), " | base64 -d > ", 2)
+			content, _ := base64.StdEncoding.DecodeString(parts[0])
+			h.rules[parts[1]] = string(content)
+		}
+		return nil, nil, nil
+	}}
+}
+
+func newBlockHost() *blockHost {
+	return &blockHost{
+		devices: map[string]string{
+			"/data1/tikv-20160": `KNAME="nvme0n1p1" PKNAME="nvme0n1" TYPE="part"`,
+			"/data1/tikv-20161": `KNAME="nvme0n1p1" PKNAME="nvme0n1" TYPE="part"`,
+			"/data2/tikv-20162": `KNAME="sdb" PKNAME="" TYPE="disk"`,
+			"/data3/tikv-20163": `KNAME="xvdc" PKNAME="" TYPE="disk"`,
+		},
+		attrs: map[string]string{
+			"/sys/block/nvme0n1/queue/scheduler":     "[mq-deadline] kyber bfq none",
+			"/sys/block/nvme0n1/queue/read_ahead_kb": "4096",
+			"/sys/block/nvme0n1/queue/rotational":    "0",
+			"/sys/block/sdb/queue/scheduler":         "noop [deadline] cfq",
+			"/sys/block/sdb/queue/read_ahead_kb":     "128",
+			"/sys/block/sdb/queue/rotational":        "1",
+			// no scheduler
+			"/sys/block/xvdc/queue/scheduler":     "none",
+			"/sys/block/xvdc/queue/read_ahead_kb": "64",
+			"/sys/block/xvdc/queue/rotational":    "0",
+		},
+		rules: map[string]string{},
+	}
+}
+
+func (s *taskSuite) TestCheckBlockDevice(c *C) {
+	h := newBlockHost()
+	dirs := []string{"/data1/tikv-20160", "/data1/tikv-20161", "/data2/tikv-20162", "/data3/tikv-20163"}
+
+	// just warned without fix
+	t := &CheckBlockDevice{host: "172.16.5.140", dirs: dirs}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", h.executor())), IsNil)
+	devices := t.Devices()
+	c.Assert(devices, HasLen, 3)
+	c.Assert(devices[0], DeepEquals, &BlockDevice{
+		Name:        "nvme0n1",
+		Dirs:        []string{"/data1/tikv-20160", "/data1/tikv-20161"},
+		Scheduler:   "mq-deadline",
+		Schedulers:  []string{"mq-deadline", "kyber", "bfq", "none"},
+		ReadAheadKB: 4096,
+	})
+	c.Assert(devices[0].problems(), DeepEquals, []string{
+		"the I/O scheduler is mq-deadline rather than none or noop",
+		"the read-ahead is 4096KB, larger than 128KB",
+	})
+	c.Assert(devices[1].Rotational, IsTrue)
+	c.Assert(devices[1].problems(), HasLen, 0)
+	c.Assert(devices[2].Scheduler, Equals, "none")
+	c.Assert(devices[2].problems(), HasLen, 0)
+	c.Assert(h.rules, HasLen, 0)
+
+	// set and persisted
+	t = &CheckBlockDevice{host: "172.16.5.140", dirs: dirs, fix: true}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", h.executor())), IsNil)
+	c.Assert(h.attrs["/sys/block/nvme0n1/queue/scheduler"], Equals, "mq-deadline kyber bfq [none]")
+	c.Assert(h.attrs["/sys/block/nvme0n1/queue/read_ahead_kb"], Equals, "128")
+	c.Assert(t.Devices()[0].problems(), HasLen, 0)
+	c.Assert(h.rules, DeepEquals, map[string]string{
+		"/etc/udev/rules.d/60-tidb-nvme0n1.rules": `ACTION=="add|change", KERNEL=="nvme0n1", ATTR{queue/scheduler}="none", ATTR{queue/read_ahead_kb}="128"` + "\n",
+	})
+}
+
+func (s *taskSuite) TestCheckBlockDeviceUnfixed(c *C) {
+	h := newBlockHost()
+	h.stubborn = true
+	e := h.executor()
+	t := &CheckBlockDevice{host: "172.16.5.140", dirs: []string{"/data1/tikv-20160"}, fix: true}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrBlockDeviceUnfixed), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*Failed to tune the data disks of 172.16.5.140:\n"+
+		"  - nvme0n1: the I/O scheduler is mq-deadline rather than none or noop\n"+
+		"  - nvme0n1: the read-ahead is 4096KB, larger than 128KB.*")
+	c.Assert(e.commands(), HasLen, 12)
+	c.Assert(e.commands()[4:9], DeepEquals, []string{
+		"echo none > /sys/block/nvme0n1/queue/scheduler",
+		"echo 128 > /sys/block/nvme0n1/queue/read_ahead_kb",
+		"echo " + base64.StdEncoding.EncodeToString([]byte(`ACTION=="add|change", KERNEL=="nvme0n1", ATTR{queue/scheduler}="none", ATTR{queue/read_ahead_kb}="128"`+"\n")) + " | base64 -d > /etc/udev/rules.d/60-tidb-nvme0n1.rules",
+		"udevadm control --reload-rules",
+		blockDeviceCmd("/data1/tikv-20160"),
+	})
+
+	// not on a block device
+	c.Assert(parseBlockDevice(""), Equals, "")
+	c.Assert(parseBlockDevice(`KNAME="dm-0" PKNAME="sda2" TYPE="lvm"`), Equals, "dm-0")
+}
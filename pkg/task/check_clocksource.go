@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSClocksource = errNS.NewSubNamespace("clocksource")
+	// ErrClocksourceUnstable means the clock source of the host is a known unstable one
+	ErrClocksourceUnstable = errNSClocksource.NewType("unstable", errutil.ErrTraitPreCheck)
+)
+
+const clocksourceDir = "/sys/devices/system/clocksource/clocksource0"
+
+// unstableClocksources are the clock sources of the low resolution or drifting ones, which
+// cause the time of the host to jump
+var unstableClocksources = map[string]bool{
+	"jiffies":         true,
+	"refined-jiffies": true,
+	"pit":             true,
+}
+
+// slowClocksources are the stable but slow clock sources, the kernel falls back to them
+// if TSC is marked as unstable
+var slowClocksources = map[string]bool{
+	"hpet":    true,
+	"acpi_pm": true,
+}
+
+// stableClocksources are the recommended clock sources in the order of preference
+var stableClocksources = []string{
+	"tsc",
+	"kvm-clock",
+	"hyperv_clocksource_tsc_page",
+	"arch_sys_counter",
+	"xen",
+}
+
+// CheckClocksource is used to check whether the current clock source of the host is a
+// stable one
+type CheckClocksource struct {
+	inspection
+
+	host string
+
+	current   string
+	available []string
+}
+
+// Execute implements the Task interface
+func (c *CheckClocksource) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	stdout, _, err := e.Execute(fmt.Sprintf("cat %s/current_clocksource", clocksourceDir), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the clock source of %s", c.host)
+	}
+	c.current = strings.TrimSpace(string(stdout))
+
+	stdout, _, err = e.Execute(fmt.Sprintf("cat %s/available_clocksource", clocksourceDir), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the available clock sources of %s", c.host)
+	}
+	c.available = strings.Fields(string(stdout))
+
+	recommended := c.recommended()
+	switch {
+	case unstableClocksources[c.current]:
+		suggestion := "None of the stable clock sources is available on the host, please check the settings of the hypervisor or the `clocksource` kernel parameter."
+		if recommended != "" {
+			suggestion = fmt.Sprintf("Please switch to the stable clock source %s with `echo %s > %s/current_clocksource`, and set `clocksource=%s` to the kernel parameters to persist it.",
+				recommended, recommended, clocksourceDir, recommended)
+		}
+		return ErrClocksourceUnstable.
+			New("The clock source of host %s is %s, with which the time of the host may jump", c.host, c.current).
+			WithProperty(cliutil.SuggestionFromString(suggestion))
+	case slowClocksources[c.current] && recommended == "":
+		log.Warnf("The clock source of host %s is %s, the TSC may be marked as unstable by the kernel, which slows down getting the time", c.host, c.current)
+	case slowClocksources[c.current]:
+		log.Warnf("The clock source of host %s is %s, %s is recommended", c.host, c.current, recommended)
+	}
+	return nil
+}
+
+// recommended returns the most preferred stable clock source available on the host
+func (c *CheckClocksource) recommended() string {
+	for _, source := range stableClocksources {
+		for _, available := range c.available {
+			if source == available {
+				return source
+			}
+		}
+	}
+	return ""
+}
+
+// Current returns the current clock source of the host
+func (c *CheckClocksource) Current() string {
+	return c.current
+}
+
+// Rollback implements the Task interface
+func (c *CheckClocksource) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckClocksource) String() string {
+	return fmt.Sprintf("CheckClocksource: host=%s", c.host)
+}
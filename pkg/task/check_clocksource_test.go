@@ -0,0 +1,64 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	. "github.com/pingcap/check"
+)
+
+func clocksourceExecutor(current, available string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch cmd {
+		case "cat " + clocksourceDir + "/current_clocksource":
+			return []byte(current + "\n"), nil, nil
+		case "cat " + clocksourceDir + "/available_clocksource":
+			return []byte(available + " \n"), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckClocksource(c *C) {
+	t := &CheckClocksource{host: "172.16.5.140"}
+	for _, current := range []string{"tsc", "kvm-clock", "hpet"} {
+		e := clocksourceExecutor(current, "tsc kvm-clock hpet acpi_pm")
+		c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+		c.Assert(t.Current(), Equals, current)
+		c.Assert(t.recommended(), Equals, "tsc")
+	}
+
+	// TSC is marked as unstable
+	e := clocksourceExecutor("hpet", "hpet acpi_pm")
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.recommended(), Equals, "")
+}
+
+func (s *taskSuite) TestCheckClocksourceUnstable(c *C) {
+	t := &CheckClocksource{host: "172.16.5.140"}
+
+	e := clocksourceExecutor("jiffies", "kvm-clock jiffies")
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrClocksourceUnstable), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The clock source of host 172.16.5.140 is jiffies, with which the time of the host may jump.*")
+	suggestion, _ := errorx.Cast(err).Property(errutil.ErrPropSuggestion)
+	c.Assert(suggestion, Matches, "Please switch to the stable clock source kvm-clock .*")
+
+	e = clocksourceExecutor("refined-jiffies", "refined-jiffies jiffies")
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrClocksourceUnstable), IsTrue)
+	suggestion, _ = errorx.Cast(err).Property(errutil.ErrPropSuggestion)
+	c.Assert(suggestion, Matches, "None of the stable clock sources is available.*")
+}
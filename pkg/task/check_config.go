@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSConfigCheck = errNS.NewSubNamespace("config_check")
+	// ErrConfigCheckFailed means the generated config is rejected by the component
+	ErrConfigCheckFailed = errNSConfigCheck.NewType("failed", errutil.ErrTraitPreCheck)
+)
+
+// configCheckBinaries are the binaries of the components which support `--config-check`
+var configCheckBinaries = map[string]string{
+	meta.ComponentTiDB: "tidb-server",
+	meta.ComponentTiKV: "tikv-server",
+	meta.ComponentPD:   "pd-server",
+}
+
+// unknownFlagMessages are the messages printed by the binaries which don't know `--config-check`
+var unknownFlagMessages = []string{
+	"flag provided but not defined",
+	"wasn't expected",
+	"unknown flag",
+}
+
+// CheckConfig is used to validate the config of an instance by the component itself before
+// it's taken as the live one. The config generated by the inner task is checked by running
+// the binary with `--config-check`, and the previous config is restored if it's rejected.
+// The check is skipped for the components which don't support it.
+type CheckConfig struct {
+	inner     Task
+	instance  meta.Instance
+	deployDir string
+
+	skipped bool
+}
+
+// Execute implements the Task interface
+func (c *CheckConfig) Execute(ctx *Context) error {
+	binary, supported := configCheckBinaries[c.instance.ComponentName()]
+	if !supported {
+		c.skipped = true
+		return ctx.execute(c.inner)
+	}
+	e, found := ctx.GetExecutor(c.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+
+	config := filepath.Join(c.deployDir, "conf", c.instance.ComponentName()+".toml")
+	backup := config + ".bak"
+	if _, stderr, err := e.Execute(fmt.Sprintf("if [ -f %[1]s ]; then cp -p %[1]s %[2]s; fi", config, backup), false); err != nil {
+		return errors.Annotatef(err, "failed to back up %s on %s, stderr: %s", config, c.instance.GetHost(), stderr)
+	}
+	if err := ctx.execute(c.inner); err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf("%s --config-check --config=%s", filepath.Join(c.deployDir, "bin", binary), config)
+	stdout, stderr, err := e.Execute(cmd, false)
+	if err == nil {
+		c.skipped = false
+		_, _, _ = e.Execute("rm -f "+backup, false)
+		return nil
+	}
+
+	output := strings.TrimSpace(string(stderr) + "\n" + string(stdout))
+	for _, msg := range unknownFlagMessages {
+		if strings.Contains(output, msg) {
+			log.Warnf("Skip checking the config of %s as %s doesn't support --config-check", c.instance.ID(), binary)
+			c.skipped = true
+			_, _, _ = e.Execute("rm -f "+backup, false)
+			return nil
+		}
+	}
+
+	// the rejected config is not kept as the live one
+	if _, stderr, err := e.Execute(fmt.Sprintf("if [ -f %[2]s ]; then mv -f %[2]s %[1]s; else rm -f %[1]s; fi", config, backup), false); err != nil {
+		log.Warnf("Failed to restore %s on %s: %s, stderr: %s", config, c.instance.GetHost(), err, stderr)
+	}
+	return ErrConfigCheckFailed.
+		New("The config of %s is rejected by %s: %s", c.instance.ID(), binary, output).
+		WithProperty(cliutil.SuggestionFromString("Please fix the config of the instance in the topology and try again."))
+}
+
+// Skipped returns whether the config is not checked as the component doesn't support it
+func (c *CheckConfig) Skipped() bool {
+	return c.skipped
+}
+
+// Rollback implements the Task interface
+func (c *CheckConfig) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckConfig) String() string {
+	return fmt.Sprintf("CheckConfig: instance=%s, deploy_dir=%s, inner=%s", c.instance.ID(), c.deployDir, c.inner)
+}
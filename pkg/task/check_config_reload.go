@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+// CheckConfigReload is used to report the configuration items changed since the applied
+// topology. Each of them is classified as online, which can be changed by SQL or pd-ctl
+// without restarting, or restart required, and the instances which have to be restarted for
+// the changes to take effect are reported explicitly.
+type CheckConfigReload struct {
+	applied *meta.Specification
+	current *meta.Specification
+
+	changes []meta.ConfigChange
+	restart []string
+}
+
+// Execute implements the Task interface
+func (c *CheckConfigReload) Execute(ctx *Context) error {
+	changes, err := meta.ConfigChanges(c.applied, c.current)
+	if err != nil {
+		return err
+	}
+	c.changes = changes
+	c.restart = nil
+	if len(changes) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Instance", "Component", "Config", "Effect"}}
+	restart := make(map[string]bool)
+	var online []string
+	for _, change := range changes {
+		key, effect := change.Key, "online"
+		if key == "" {
+			key = "<specification>"
+		}
+		if !change.Online {
+			effect = "restart required"
+			if !restart[change.Instance] {
+				restart[change.Instance] = true
+				c.restart = append(c.restart, change.Instance)
+			}
+		}
+		rows = append(rows, []string{change.Instance, change.Component, key, effect})
+	}
+	for _, change := range changes {
+		if !restart[change.Instance] && (len(online) == 0 || online[len(online)-1] != change.Instance) {
+			online = append(online, change.Instance)
+		}
+	}
+	cliutil.PrintTable(rows, true)
+
+	if len(c.restart) > 0 {
+		log.Warnf("The changes of %d instances take effect only after restarting: %s", len(c.restart), strings.Join(c.restart, ", "))
+	}
+	if len(online) > 0 {
+		log.Infof("The changes of %d instances can be applied online without restarting: %s", len(online), strings.Join(online, ", "))
+	}
+	return nil
+}
+
+// Changes returns the changed configuration items
+func (c *CheckConfigReload) Changes() []meta.ConfigChange {
+	return c.changes
+}
+
+// RestartRequired returns the instances which have to be restarted for the changes
+func (c *CheckConfigReload) RestartRequired() []string {
+	return c.restart
+}
+
+// Rollback implements the Task interface
+func (c *CheckConfigReload) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckConfigReload) String() string {
+	return fmt.Sprintf("CheckConfigReload: changes=%d", len(c.changes))
+}
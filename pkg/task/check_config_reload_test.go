@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *taskSuite) TestCheckConfigReload(c *C) {
+	applied := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+tikv_servers:
+  - host: 172.16.5.140
+`), applied), IsNil)
+	current := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+server_configs:
+  tidb:
+    log.level: warn
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+    config:
+      token-limit: 2000
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      gc.batch-keys: 256
+      readpool.storage.normal-concurrency: 8
+`), current), IsNil)
+
+	t := &CheckConfigReload{applied: applied, current: current}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Changes(), HasLen, 5)
+	c.Assert(t.RestartRequired(), DeepEquals, []string{"172.16.5.140:20160", "172.16.5.141:4000"})
+
+	// the online ones need no restarting
+	online := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+server_configs:
+  tidb:
+    log.level: warn
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+tikv_servers:
+  - host: 172.16.5.140
+`), online), IsNil)
+	t = &CheckConfigReload{applied: applied, current: online}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Changes(), HasLen, 2)
+	c.Assert(t.Changes()[0].Online, IsTrue)
+	c.Assert(t.RestartRequired(), HasLen, 0)
+
+	// nothing is changed
+	t = &CheckConfigReload{applied: applied, current: applied}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Changes(), HasLen, 0)
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// configCheckInstance returns the first instance of the component in a simple topology
+func configCheckInstance(c *C, component string) meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+pump_servers:
+  - host: 172.16.5.140
+`), topo), IsNil)
+	for _, comp := range topo.ComponentsByStartOrder() {
+		if comp.Name() == component {
+			return comp.Instances()[0]
+		}
+	}
+	c.Fatalf("no instance of %s", component)
+	return nil
+}
+
+// configCheckExecutor fails the config check with the stderr, or succeeds if it's empty
+func configCheckExecutor(stderr string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.Contains(cmd, "--config-check") && stderr != "" {
+			return nil, []byte(stderr), errors.New("exit status 1")
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckConfig(c *C) {
+	inst := configCheckInstance(c, meta.ComponentPD)
+	generated := 0
+	inner := &Func{name: "init", fn: func() error { generated++; return nil }}
+
+	e := configCheckExecutor("")
+	t := &CheckConfig{inner: inner, instance: inst, deployDir: "/deploy/pd-2379"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(generated, Equals, 1)
+	c.Assert(t.Skipped(), IsFalse)
+	c.Assert(e.commands(), DeepEquals, []string{
+		"if [ -f /deploy/pd-2379/conf/pd.toml ]; then cp -p /deploy/pd-2379/conf/pd.toml /deploy/pd-2379/conf/pd.toml.bak; fi",
+		"/deploy/pd-2379/bin/pd-server --config-check --config=/deploy/pd-2379/conf/pd.toml",
+		"rm -f /deploy/pd-2379/conf/pd.toml.bak",
+	})
+
+	// the rejected config is replaced by the previous one
+	e = configCheckExecutor("[FATAL] config check failed: invalid schedule.max-merge-region-size")
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrConfigCheckFailed), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The config of 172.16.5.140:2379 is rejected by pd-server: .*invalid schedule.max-merge-region-size.*")
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "if [ -f /deploy/pd-2379/conf/pd.toml.bak ]; then mv -f /deploy/pd-2379/conf/pd.toml.bak /deploy/pd-2379/conf/pd.toml; else rm -f /deploy/pd-2379/conf/pd.toml; fi")
+}
+
+func (s *taskSuite) TestCheckConfigUnsupported(c *C) {
+	inner := &Func{name: "init", fn: func() error { return nil }}
+
+	// the binary doesn't know the flag
+	e := configCheckExecutor("flag provided but not defined: -config-check")
+	t := &CheckConfig{inner: inner, instance: configCheckInstance(c, meta.ComponentPD), deployDir: "/deploy/pd-2379"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Skipped(), IsTrue)
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "rm -f /deploy/pd-2379/conf/pd.toml.bak")
+
+	// the component has no config check
+	e = configCheckExecutor("")
+	t = &CheckConfig{inner: inner, instance: configCheckInstance(c, meta.ComponentPump), deployDir: "/deploy/pump-8250"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Skipped(), IsTrue)
+	c.Assert(e.commands(), HasLen, 0)
+
+	// the error of generating the config is returned
+	inner = &Func{name: "init", fn: func() error { return errors.New("template error") }}
+	t = &CheckConfig{inner: inner, instance: configCheckInstance(c, meta.ComponentPD), deployDir: "/deploy/pd-2379"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", configCheckExecutor(""))), ErrorMatches, "template error")
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSDataDir = errNS.NewSubNamespace("datadir")
+	// ErrDataDirNotEmpty means the data directories to deploy onto contain some data
+	ErrDataDirNotEmpty = errNSDataDir.NewType("not_empty", errutil.ErrTraitPreCheck)
+)
+
+// dataDirSample is the max number of the entries of a non-empty data directory reported
+const dataDirSample = 3
+
+// CheckDataDir is used to check the data directories on the host are empty or absent before
+// the first deploy, as the data of another cluster left in them would be mixed with the new
+// one. The non-empty directories are only warned if allowNonEmpty is enabled, which is the
+// intentional reuse of the data.
+type CheckDataDir struct {
+	host          string
+	dirs          []string
+	allowNonEmpty bool
+
+	nonEmpty map[string][]string // the directory -> the sample of its entries
+}
+
+// Execute implements the Task interface
+func (c *CheckDataDir) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	c.nonEmpty = make(map[string][]string)
+	var problems []string
+	for _, dir := range c.dirs {
+		// the data directories may be owned by the deploy user of another cluster
+		stdout, stderr, err := e.Execute(fmt.Sprintf("ls -A %s", dir), true)
+		if err != nil {
+			if strings.Contains(string(stderr), "No such file or directory") {
+				continue
+			}
+			return errors.Annotatef(err, "failed to list the data directory %s on %s, stderr: %s", dir, c.host, stderr)
+		}
+		var entries []string
+		for _, line := range strings.Split(string(stdout), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				entries = append(entries, line)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		sample := entries
+		if len(sample) > dataDirSample {
+			sample = append(append([]string{}, sample[:dataDirSample]...), "...")
+		}
+		c.nonEmpty[dir] = sample
+		problems = append(problems, fmt.Sprintf("%s contains %s", dir, strings.Join(sample, ", ")))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if c.allowNonEmpty {
+		for _, p := range problems {
+			log.Warnf("Reusing the data directory on %s: %s", c.host, p)
+		}
+		return nil
+	}
+	return ErrDataDirNotEmpty.
+		New("The data directories on %s are not empty, they may belong to another cluster:\n  - %s", c.host, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please specify other data_dir in the topology, or remove the data if it's not needed. Deploy with --allow-non-empty-data-dir if the data is reused intentionally."))
+}
+
+// NonEmpty returns the non-empty data directories and the sample of their entries
+func (c *CheckDataDir) NonEmpty() map[string][]string {
+	return c.nonEmpty
+}
+
+// Rollback implements the Task interface
+func (c *CheckDataDir) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckDataDir) String() string {
+	return fmt.Sprintf("CheckDataDir: host=%s, dirs=%s, allowNonEmpty=%v", c.host, strings.Join(c.dirs, ","), c.allowNonEmpty)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// dataDirExecutor mocks the data directories with their entries, the directories absent
+// from dirs don't exist
+func dataDirExecutor(dirs map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		dir := strings.TrimPrefix(cmd, "ls -A ")
+		entries, ok := dirs[dir]
+		if !ok {
+			return nil, []byte("ls: cannot access " + dir + ": No such file or directory"), errors.New("exit status 2")
+		}
+		return []byte(entries), nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckDataDir(c *C) {
+	dirs := []string{"/data/pd-2379", "/data/tikv-20160", "/data/tiflash-1", "/data/tiflash-2"}
+	e := dataDirExecutor(map[string]string{
+		"/data/pd-2379":    "",
+		"/data/tikv-20160": "db\nimport\nLOCK\nraft\nsnap\n",
+		"/data/tiflash-2":  "flash metadata\n",
+	})
+
+	// absent and empty directories are fine, the others are reported
+	t := &CheckDataDir{host: "172.16.5.140", dirs: dirs}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrDataDirNotEmpty), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*The data directories on 172.16.5.140 are not empty, they may belong to another cluster:
+  - /data/tikv-20160 contains db, import, LOCK, \.\.\.
+  - /data/tiflash-2 contains flash metadata.*`)
+	c.Assert(t.NonEmpty(), DeepEquals, map[string][]string{
+		"/data/tikv-20160": {"db", "import", "LOCK", "..."},
+		"/data/tiflash-2":  {"flash metadata"},
+	})
+	c.Assert(e.commands(), DeepEquals, []string{
+		"ls -A /data/pd-2379", "ls -A /data/tikv-20160", "ls -A /data/tiflash-1", "ls -A /data/tiflash-2",
+	})
+
+	// reused intentionally
+	t = &CheckDataDir{host: "172.16.5.140", dirs: dirs, allowNonEmpty: true}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.NonEmpty(), HasLen, 2)
+}
+
+func (s *taskSuite) TestCheckDataDirEmpty(c *C) {
+	e := dataDirExecutor(map[string]string{"/data/tikv-20160": "\n"})
+	t := &CheckDataDir{host: "172.16.5.140", dirs: []string{"/data/tikv-20160", "/data/tikv-20161"}}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.NonEmpty(), HasLen, 0)
+
+	// can't tell whether it's empty
+	e = &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, []byte("ls: cannot open directory /data/tikv-20160: Input/output error"), errors.New("exit status 2")
+	}}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(err, ErrorMatches, ".*failed to list the data directory /data/tikv-20160 on 172.16.5.140.*Input/output error.*")
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSDeployUser = errNS.NewSubNamespace("deploy_user")
+	// ErrDeployUserUnusable means the existing deploy user can't log in or run the components
+	ErrDeployUserUnusable = errNSDeployUser.NewType("unusable", errutil.ErrTraitPreCheck)
+)
+
+// nologinShells are the shells refusing the logins, the components are started and managed
+// by `su` and SSH as the deploy user, which fail with them
+var nologinShells = map[string]bool{
+	"nologin": true,
+	"false":   true,
+}
+
+// CheckDeployUser is used to check whether the existing deploy user on the host can log in
+// and run the components, i.e. its shell accepts the logins and its home exists, owned and
+// fully accessible by it. A user created by the deploy is always fine, so nothing is checked
+// if the user doesn't exist yet.
+type CheckDeployUser struct {
+	inspection
+
+	host string
+	user string
+
+	exists bool
+	shell  string
+	home   string
+}
+
+// Execute implements the Task interface
+func (c *CheckDeployUser) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	stdout, _, err := e.Execute(fmt.Sprintf("getent passwd %s || true", c.user), true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the user %s on %s", c.user, c.host)
+	}
+	// the fields are name:password:uid:gid:gecos:home:shell
+	fields := strings.Split(strings.TrimSpace(string(stdout)), ":")
+	c.exists = len(fields) == 7
+	if !c.exists {
+		return nil
+	}
+	c.home, c.shell = fields[5], fields[6]
+
+	var problems []string
+	if nologinShells[path.Base(c.shell)] {
+		problems = append(problems, fmt.Sprintf("its shell %s refuses the logins", c.shell))
+	}
+	stdout, _, err = e.Execute(fmt.Sprintf("stat -c '%%U %%a' %s 2>/dev/null || true", c.home), true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to stat the home of %s on %s", c.user, c.host)
+	}
+	if stat := strings.Fields(string(stdout)); len(stat) != 2 {
+		problems = append(problems, fmt.Sprintf("its home %s doesn't exist", c.home))
+	} else if owner, mode := stat[0], stat[1]; owner != c.user {
+		problems = append(problems, fmt.Sprintf("its home %s is owned by %s", c.home, owner))
+	} else if len(mode) < 3 || mode[len(mode)-3] != '7' {
+		problems = append(problems, fmt.Sprintf("its home %s is not fully accessible by it with the mode %s", c.home, mode))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return ErrDeployUserUnusable.
+		New("The deploy user %s on %s can't run the components:\n  - %s", c.user, c.host, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please fix the user on the host, e.g: `usermod -s /bin/bash -d /home/%[1]s -m %[1]s && chown %[1]s: /home/%[1]s && chmod u+rwx /home/%[1]s`, or deploy with another user.", c.user)))
+}
+
+// Exists returns whether the deploy user exists on the host before the deploy
+func (c *CheckDeployUser) Exists() bool {
+	return c.exists
+}
+
+// Rollback implements the Task interface
+func (c *CheckDeployUser) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckDeployUser) String() string {
+	return fmt.Sprintf("CheckDeployUser: host=%s, user=%s", c.host, c.user)
+}
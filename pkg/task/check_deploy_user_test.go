@@ -0,0 +1,72 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// deployUserExecutor returns a mocked executor of a host with the passwd entry of tidb and
+// the stat of its home, the home is missing if stat is empty
+func deployUserExecutor(passwd, stat string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch cmd {
+		case "getent passwd tidb || true":
+			return []byte(passwd), nil, nil
+		case "stat -c '%U %a' /home/tidb 2>/dev/null || true":
+			return []byte(stat), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckDeployUser(c *C) {
+	t := &CheckDeployUser{host: "172.16.5.140", user: "tidb"}
+
+	e := deployUserExecutor("tidb:x:1000:1000::/home/tidb:/bin/bash\n", "tidb 700\n")
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Exists(), IsTrue)
+	c.Assert(e.commands(), DeepEquals, []string{"getent passwd tidb || true", "stat -c '%U %a' /home/tidb 2>/dev/null || true"})
+
+	// the user is created by the deploy
+	e = deployUserExecutor("", "")
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Exists(), IsFalse)
+	c.Assert(e.commands(), HasLen, 1)
+}
+
+func (s *taskSuite) TestCheckDeployUserUnusable(c *C) {
+	t := &CheckDeployUser{host: "172.16.5.140", user: "tidb"}
+
+	// nologin
+	e := deployUserExecutor("tidb:x:1000:1000::/home/tidb:/sbin/nologin\n", "tidb 755\n")
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrDeployUserUnusable), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The deploy user tidb on 172.16.5.140 can't run the components:\n  - its shell /sbin/nologin refuses the logins$")
+
+	// missing home, along with the shell
+	e = deployUserExecutor("tidb:x:1000:1000::/home/tidb:/bin/false\n", "")
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrDeployUserUnusable), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*\n  - its shell /bin/false refuses the logins\n  - its home /home/tidb doesn't exist$")
+
+	// the home is not owned by the user, or not writable
+	e = deployUserExecutor("tidb:x:1000:1000::/home/tidb:/bin/bash\n", "root 755\n")
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(err.Error(), Matches, "(?s).*\n  - its home /home/tidb is owned by root$")
+	e = deployUserExecutor("tidb:x:1000:1000::/home/tidb:/bin/bash\n", "tidb 555\n")
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(err.Error(), Matches, "(?s).*\n  - its home /home/tidb is not fully accessible by it with the mode 555$")
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSDNS = errNS.NewSubNamespace("dns")
+	// ErrDNSResolution means some hosts can't resolve the hostnames of their peers, or the
+	// hostnames are resolved to different addresses on the hosts
+	ErrDNSResolution = errNSDNS.NewType("resolution", errutil.ErrTraitPreCheck)
+)
+
+// CheckDNS is used to check whether the hostnames in the topology are resolved by every host
+// other than the one named. A hostname is expected to be resolved to the same address on all
+// the hosts, and a loopback address is considered unresolved as the peers can't connect to it.
+type CheckDNS struct {
+	inspection
+
+	hosts []string
+
+	// resolved is the address of each hostname resolved on each host indexed by host and
+	// hostname, an empty one means it's not resolved
+	resolved map[string]map[string]string
+}
+
+// Execute implements the Task interface
+func (c *CheckDNS) Execute(ctx *Context) error {
+	c.resolved = make(map[string]map[string]string)
+
+	names := c.hostnames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		var peers []string
+		for _, name := range names {
+			if name != host {
+				peers = append(peers, name)
+			}
+		}
+		if len(peers) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(host string, e executor.TiOpsExecutor, peers []string) {
+			defer wg.Done()
+			stdout, _, err := e.Execute(resolveCmd(peers), false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to resolve the hostnames on %s", host))
+				return
+			}
+			c.resolved[host] = parseResolved(string(stdout), peers)
+		}(host, e, peers)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return c.report(names)
+}
+
+// hostnames returns the hosts which are not IP addresses, in the order of the hosts
+func (c *CheckDNS) hostnames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, host := range c.hosts {
+		if net.ParseIP(host) == nil && !seen[host] {
+			seen[host] = true
+			names = append(names, host)
+		}
+	}
+	return names
+}
+
+// report prints the resolved addresses of the hostnames and returns an error if some of them
+// are not resolved or resolved inconsistently
+func (c *CheckDNS) report(names []string) error {
+	rows := [][]string{{"Hostname", "Address"}}
+	var problems []string
+	for _, name := range names {
+		addrs := make(map[string][]string) // address -> hosts
+		var unresolved []string
+		for _, host := range c.hosts {
+			results, ok := c.resolved[host]
+			if !ok || host == name {
+				continue
+			}
+			addr := results[name]
+			if addr == "" || net.ParseIP(addr).IsLoopback() {
+				unresolved = append(unresolved, host)
+				continue
+			}
+			addrs[addr] = append(addrs[addr], host)
+		}
+
+		var results []string
+		for addr, hosts := range addrs {
+			results = append(results, fmt.Sprintf("%s on %s", addr, strings.Join(hosts, ",")))
+		}
+		sort.Strings(results)
+
+		switch {
+		case len(unresolved) > 0:
+			rows = append(rows, []string{name, "unresolved"})
+			problems = append(problems, fmt.Sprintf("%s is not resolved on %s", name, strings.Join(unresolved, ",")))
+		case len(addrs) > 1:
+			rows = append(rows, []string{name, "inconsistent"})
+			problems = append(problems, fmt.Sprintf("%s is resolved to %s", name, strings.Join(results, " and ")))
+		default:
+			for addr := range addrs {
+				rows = append(rows, []string{name, addr})
+			}
+		}
+	}
+	cliutil.PrintTable(rows, true)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return ErrDNSResolution.
+		New("%d hostnames are not resolved properly:\n  - %s", len(problems), strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please make the hostnames resolved to the same non-loopback addresses on all the hosts by DNS or /etc/hosts, or use the IP addresses in the topology."))
+}
+
+// Resolved returns the address of each hostname resolved on each host
+func (c *CheckDNS) Resolved() map[string]map[string]string {
+	return c.resolved
+}
+
+// resolveCmd returns the command printing a line of each name and its first address resolved
+// by getent, the address is absent if it's not resolved
+func resolveCmd(names []string) string {
+	return fmt.Sprintf(`for name in %s; do echo "$name $(getent hosts $name | awk '{print $1; exit}')"; done`, strings.Join(names, " "))
+}
+
+// parseResolved parses the output of resolveCmd, an empty address is returned for the names
+// not resolved
+func parseResolved(output string, names []string) map[string]string {
+	resolved := make(map[string]string)
+	for _, name := range names {
+		resolved[name] = ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if _, ok := resolved[fields[0]]; ok {
+			resolved[fields[0]] = fields[1]
+		}
+	}
+	return resolved
+}
+
+// Rollback implements the Task interface
+func (c *CheckDNS) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckDNS) String() string {
+	return fmt.Sprintf("CheckDNS: hosts=%s", strings.Join(c.hosts, ","))
+}
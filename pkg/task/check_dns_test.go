@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// dnsContext returns a context of the hosts resolving the names to the addresses of each
+// host, a name absent is not resolved
+func dnsContext(hosts map[string]map[string]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(hosts), func(host string) *mockExecutor {
+		addrs := hosts[host]
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			var lines []string
+			names := strings.TrimPrefix(strings.SplitN(cmd, ";", 2)[0], "for name in ")
+			for _, name := range strings.Fields(names) {
+				lines = append(lines, fmt.Sprintf("%s %s", name, addrs[name]))
+			}
+			return []byte(strings.Join(lines, "\n") + "\n"), nil, nil
+		}}
+	})
+}
+
+func (s *taskSuite) TestCheckDNS(c *C) {
+	hosts := []string{"tikv-1", "tikv-2", "172.16.5.142"}
+	ctx, executors := dnsContext(map[string]map[string]string{
+		"tikv-1":       {"tikv-2": "172.16.5.141"},
+		"tikv-2":       {"tikv-1": "172.16.5.140"},
+		"172.16.5.142": {"tikv-1": "172.16.5.140", "tikv-2": "172.16.5.141"},
+	})
+	t := &CheckDNS{hosts: hosts}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(executors["tikv-1"].commands(), DeepEquals, []string{resolveCmd([]string{"tikv-2"})})
+	c.Assert(executors["172.16.5.142"].commands(), DeepEquals, []string{resolveCmd([]string{"tikv-1", "tikv-2"})})
+	c.Assert(t.Resolved()["172.16.5.142"], DeepEquals, map[string]string{"tikv-1": "172.16.5.140", "tikv-2": "172.16.5.141"})
+
+	// nothing to resolve with the IP addresses
+	ctx, executors = dnsContext(map[string]map[string]string{"172.16.5.140": {}, "172.16.5.141": {}})
+	t = &CheckDNS{hosts: []string{"172.16.5.140", "172.16.5.141"}}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(executors["172.16.5.140"].commands(), HasLen, 0)
+}
+
+func (s *taskSuite) TestCheckDNSInconsistent(c *C) {
+	hosts := []string{"tikv-1", "tikv-2", "tikv-3", "172.16.5.143"}
+	ctx, _ := dnsContext(map[string]map[string]string{
+		"tikv-1":       {"tikv-2": "172.16.5.141", "tikv-3": "172.16.5.142"},
+		"tikv-2":       {"tikv-1": "172.16.5.140", "tikv-3": "10.0.1.142"},
+		"tikv-3":       {"tikv-1": "127.0.1.1", "tikv-2": "172.16.5.141"},
+		"172.16.5.143": {"tikv-1": "172.16.5.140", "tikv-3": "172.16.5.142"},
+	})
+	t := &CheckDNS{hosts: hosts}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDNSResolution), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*3 hostnames are not resolved properly:\n"+
+		"  - tikv-1 is not resolved on tikv-3\n"+
+		"  - tikv-2 is not resolved on 172.16.5.143\n"+
+		"  - tikv-3 is resolved to 10.0.1.142 on tikv-2 and 172.16.5.142 on tikv-1,172.16.5.143.*")
+	c.Assert(t.Resolved()["172.16.5.143"]["tikv-2"], Equals, "")
+
+	c.Assert(parseResolved("a 10.0.0.1\nb\n", []string{"a", "b", "c"}), DeepEquals, map[string]string{"a": "10.0.0.1", "b": "", "c": ""})
+}
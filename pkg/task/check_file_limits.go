@@ -0,0 +1,218 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSLimits = errNS.NewSubNamespace("limits")
+	// ErrLimitsMismatch means the running processes are not applied the limits of their units
+	ErrLimitsMismatch = errNSLimits.NewType("mismatch", errutil.ErrTraitPreCheck)
+)
+
+// unitLimits are the limits set by the systemd units of the instances, by the property of
+// the unit and the name of the limit in /proc/<pid>/limits
+var unitLimits = []struct {
+	property string
+	name     string
+}{
+	{"LimitNOFILE", "Max open files"},
+	{"LimitSTACK", "Max stack size"},
+}
+
+// LimitMismatch is a limit of a running instance which differs from the one of its unit
+type LimitMismatch struct {
+	Instance   string
+	Limit      string
+	Configured string // the soft/hard limit set by the unit
+	Effective  string // the soft/hard limit of the running process
+}
+
+// CheckFileLimits is used to check whether the running processes of the instances are
+// applied the limits set by their systemd units, e.g. LimitNOFILE. The process may still be
+// capped by a lower limit elsewhere, e.g. by ulimit in the scripts or the PAM limits, which
+// breaks it subtly once there are many connections or files. The instances not running are
+// skipped.
+type CheckFileLimits struct {
+	inspection
+
+	instances []meta.Instance
+
+	mismatches []LimitMismatch
+}
+
+// Execute implements the Task interface
+func (c *CheckFileLimits) Execute(ctx *Context) error {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		errs       []error
+		mismatches = make([][]LimitMismatch, len(c.instances))
+	)
+	for i, inst := range c.instances {
+		e, found := ctx.GetExecutor(inst.GetHost())
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(i int, inst meta.Instance) {
+			defer wg.Done()
+			m, err := instanceLimitMismatches(e, inst)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			mismatches[i] = m
+		}(i, inst)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	c.mismatches = nil
+	for _, m := range mismatches {
+		c.mismatches = append(c.mismatches, m...)
+	}
+	if len(c.mismatches) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Instance", "Limit", "Unit", "Process"}}
+	var lines []string
+	for _, m := range c.mismatches {
+		rows = append(rows, []string{m.Instance, m.Limit, m.Configured, m.Effective})
+		lines = append(lines, fmt.Sprintf("%s: %s is %s rather than %s", m.Instance, strings.ToLower(m.Limit), m.Effective, m.Configured))
+	}
+	cliutil.PrintTable(rows, true)
+	return ErrLimitsMismatch.
+		New("The limits of the running instances differ from their systemd units:\n  - %s", strings.Join(lines, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check whether the limits are lowered by ulimit in the run scripts or by the PAM limits, e.g. /etc/security/limits.conf, and restart the instances after fixing them."))
+}
+
+// Mismatches returns the limits of the running instances differing from their units
+func (c *CheckFileLimits) Mismatches() []LimitMismatch {
+	return c.mismatches
+}
+
+// instanceLimitMismatches returns the limits of the running process of the instance which
+// differ from its unit, nothing is returned if the instance is not running
+func instanceLimitMismatches(e executor.TiOpsExecutor, inst meta.Instance) ([]LimitMismatch, error) {
+	args := []string{"-p MainPID"}
+	for _, l := range unitLimits {
+		args = append(args, "-p "+l.property, "-p "+l.property+"Soft")
+	}
+	stdout, _, err := e.Execute(fmt.Sprintf("systemctl show %s %s", strings.Join(args, " "), inst.ServiceName()), false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to get the limits of the unit of %s", inst.ID())
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(string(stdout), "\n") {
+		if kv := strings.SplitN(strings.TrimSpace(line), "=", 2); len(kv) == 2 {
+			props[kv[0]] = kv[1]
+		}
+	}
+	pid := props["MainPID"]
+	if pid == "" || pid == "0" {
+		return nil, nil
+	}
+
+	stdout, _, err = e.Execute(fmt.Sprintf("cat /proc/%s/limits", pid), false)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to get the limits of the process of %s", inst.ID())
+	}
+	limits := parseProcessLimits(string(stdout))
+
+	var mismatches []LimitMismatch
+	for _, l := range unitLimits {
+		hard, ok := props[l.property]
+		if !ok {
+			continue
+		}
+		soft, ok := props[l.property+"Soft"]
+		if !ok {
+			// the soft limit is not shown separately before systemd 233
+			soft = hard
+		}
+		configured := normalizeLimit(soft) + "/" + normalizeLimit(hard)
+		effective, ok := limits[l.name]
+		if !ok {
+			continue
+		}
+		if configured != effective {
+			mismatches = append(mismatches, LimitMismatch{
+				Instance:   inst.ID(),
+				Limit:      l.name,
+				Configured: configured,
+				Effective:  effective,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// parseProcessLimits parses the content of /proc/<pid>/limits to the soft/hard limits by name:
+//
+//	Limit                     Soft Limit           Hard Limit           Units
+//	Max open files            1000000              1000000              files
+func parseProcessLimits(content string) map[string]string {
+	limits := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		for _, l := range unitLimits {
+			if !strings.HasPrefix(line, l.name) {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(line, l.name))
+			if len(fields) >= 2 {
+				limits[l.name] = normalizeLimit(fields[0]) + "/" + normalizeLimit(fields[1])
+			}
+		}
+	}
+	return limits
+}
+
+// normalizeLimit normalizes the unlimited values of systemd and /proc
+func normalizeLimit(v string) string {
+	switch v {
+	case "infinity", "18446744073709551615":
+		return "unlimited"
+	}
+	return v
+}
+
+// Rollback implements the Task interface
+func (c *CheckFileLimits) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckFileLimits) String() string {
+	var ids []string
+	for _, inst := range c.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("CheckFileLimits: instances=%s", strings.Join(ids, ","))
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+// procLimits returns the content of /proc/<pid>/limits with the open files and stack size
+func procLimits(nofile, stack string) string {
+	return fmt.Sprintf(`Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max file size             unlimited            unlimited            bytes
+Max stack size            %s            bytes
+Max core file size        0                    unlimited            bytes
+Max processes             4096                 255252               processes
+Max open files            %s            files
+`, stack, nofile)
+}
+
+// limitsExecutor returns a mocked executor whose services are shown with the properties,
+// and the processes are of the limits by pid
+func limitsExecutor(units map[string]string, procs map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		for unit, props := range units {
+			if strings.HasPrefix(cmd, "systemctl show ") && strings.HasSuffix(cmd, " "+unit) {
+				return []byte(props), nil, nil
+			}
+		}
+		for pid, limits := range procs {
+			if cmd == "cat /proc/"+pid+"/limits" {
+				return []byte(limits), nil, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("unexpected command %s", cmd)
+	}}
+}
+
+func limitsInstances(c *C) []meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.140
+    port: 20161
+    status_port: 20181
+  - host: 172.16.5.140
+    port: 20162
+    status_port: 20182
+`), topo), IsNil)
+	return (&meta.TiKVComponent{Specification: topo}).Instances()
+}
+
+func (s *taskSuite) TestCheckFileLimits(c *C) {
+	e := limitsExecutor(map[string]string{
+		// systemd 219 shows the unlimited as the max uint64
+		"tikv-20160.service": "MainPID=1234\nLimitNOFILE=1000000\nLimitSTACK=10485760\n",
+		"tikv-20161.service": "MainPID=1235\nLimitNOFILE=1000000\nLimitNOFILESoft=1000000\nLimitSTACK=18446744073709551615\nLimitSTACKSoft=infinity\n",
+		// not running
+		"tikv-20162.service": "MainPID=0\nLimitNOFILE=1000000\nLimitSTACK=10485760\n",
+	}, map[string]string{
+		"1234": procLimits("1000000              1000000  ", "10485760             10485760 "),
+		"1235": procLimits("1000000              1000000  ", "unlimited            unlimited"),
+	})
+	t := &CheckFileLimits{instances: limitsInstances(c)}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Mismatches(), HasLen, 0)
+	// the limits of the instance not running are not read
+	c.Assert(e.commands(), HasLen, 5)
+	c.Assert(strings.Join(e.commands(), "\n"), Matches, "(?s).*systemctl show -p MainPID -p LimitNOFILE -p LimitNOFILESoft -p LimitSTACK -p LimitSTACKSoft tikv-20160.service.*")
+}
+
+func (s *taskSuite) TestCheckFileLimitsMismatch(c *C) {
+	e := limitsExecutor(map[string]string{
+		"tikv-20160.service": "MainPID=1234\nLimitNOFILE=1000000\nLimitSTACK=10485760\n",
+		"tikv-20161.service": "MainPID=1235\nLimitNOFILE=1000000\nLimitSTACK=10485760\n",
+		"tikv-20162.service": "MainPID=1236\nLimitNOFILE=1000000\nLimitSTACK=10485760\n",
+	}, map[string]string{
+		// capped by the ulimit in the script
+		"1234": procLimits("65535                1000000  ", "10485760             10485760 "),
+		"1235": procLimits("1000000              1000000  ", "10485760             10485760 "),
+		"1236": procLimits("4096                 4096     ", "8388608              unlimited"),
+	})
+	t := &CheckFileLimits{instances: limitsInstances(c)}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrLimitsMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The limits of the running instances differ from their systemd units:\n"+
+		"  - 172.16.5.140:20160: max open files is 65535/1000000 rather than 1000000/1000000\n"+
+		"  - 172.16.5.140:20162: max open files is 4096/4096 rather than 1000000/1000000\n"+
+		"  - 172.16.5.140:20162: max stack size is 8388608/unlimited rather than 10485760/10485760$")
+	c.Assert(t.Mismatches(), DeepEquals, []LimitMismatch{
+		{Instance: "172.16.5.140:20160", Limit: "Max open files", Configured: "1000000/1000000", Effective: "65535/1000000"},
+		{Instance: "172.16.5.140:20162", Limit: "Max open files", Configured: "1000000/1000000", Effective: "4096/4096"},
+		{Instance: "172.16.5.140:20162", Limit: "Max stack size", Configured: "10485760/10485760", Effective: "8388608/unlimited"},
+	})
+}
@@ -0,0 +1,381 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSFirewall = errNS.NewSubNamespace("firewall")
+	// ErrFirewallBlocked means some ports used by the cluster are blocked by the firewall of the host
+	ErrFirewallBlocked = errNSFirewall.NewType("blocked", errutil.ErrTraitPreCheck)
+)
+
+// The kinds of firewall detected on the host
+const (
+	FirewallNone      = "none"
+	FirewallFirewalld = "firewalld"
+	FirewallIptables  = "iptables"
+)
+
+// CheckFirewall is used to check whether the ports used by the cluster on the host
+// are permitted by the active firewall (firewalld or iptables). The blocked ports
+// are reported, and the rules to permit them are added if fix is enabled. The ports
+// of firewalld are checked in the zone of the interface having the address of the
+// host, which the connections from the other hosts come in by.
+type CheckFirewall struct {
+	inspection
+
+	host  string
+	ports []int
+	fix   bool
+
+	firewall string
+	zone     string
+	blocked  []int
+}
+
+// Execute implements the Task interface
+func (c *CheckFirewall) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	c.firewall = FirewallNone
+	c.zone = ""
+	c.blocked = nil
+
+	stdout, _, err := exec.Execute("systemctl is-active firewalld", true)
+	if err == nil && strings.TrimSpace(string(stdout)) == "active" {
+		c.firewall = FirewallFirewalld
+		if c.zone, err = firewalldZone(exec, c.host); err != nil {
+			return err
+		}
+		if c.blocked, err = firewalldBlocked(exec, c.zone, c.ports); err != nil {
+			return errors.Annotatef(err, "failed to query the ports of firewalld on %s", c.host)
+		}
+	} else if stdout, _, err := exec.Execute("iptables -S INPUT", true); err == nil {
+		// iptables is not installed or not usable if the command fails, there is nothing to block the ports
+		rules := parseIptablesRules(string(stdout))
+		for _, port := range c.ports {
+			if !rules.accept(port) {
+				c.blocked = append(c.blocked, port)
+			}
+		}
+		if len(rules.rules) > 0 || rules.policy != "ACCEPT" {
+			c.firewall = FirewallIptables
+		}
+	}
+
+	if len(c.blocked) == 0 {
+		return nil
+	}
+	sort.Ints(c.blocked)
+	ports := utils.JoinInt(c.blocked, ", ")
+
+	firewall := c.firewall
+	if c.zone != "" {
+		firewall = fmt.Sprintf("%s (zone %s)", c.firewall, c.zone)
+	}
+	if !c.fix {
+		return ErrFirewallBlocked.
+			New("Ports %s on %s are blocked by %s", ports, c.host, firewall).
+			WithProperty(cliutil.SuggestionFromString("Please permit the ports in the firewall, or enable the fixing option to add the rules automatically."))
+	}
+
+	if _, _, err := exec.Execute(permitPortsCmd(c.firewall, c.zone, c.blocked), true); err != nil {
+		return errors.Annotatef(err, "failed to permit ports %s in %s on %s", ports, firewall, c.host)
+	}
+	log.Warnf("Permitted ports %s in %s on %s", ports, firewall, c.host)
+	if c.firewall == FirewallIptables {
+		log.Warnf("The iptables rules on %s are not persisted, please save them to keep the ports permitted after reboot", c.host)
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface, all the ports are supposed to be blocked
+// by the firewall as the rules are unknown without reading
+func (c *CheckFirewall) planFix(plan *Plan) {
+	if !c.fix || len(c.ports) == 0 {
+		return
+	}
+	ports := append([]int{}, c.ports...)
+	sort.Ints(ports)
+	e := plan.conditionalExecutor(c.host, "if firewalld is active and blocks the ports")
+	_, _, _ = e.Execute(permitPortsCmd(FirewallFirewalld, "<the zone of the host address>", ports), true)
+	e = plan.conditionalExecutor(c.host, "if firewalld is not active and iptables blocks the ports")
+	_, _, _ = e.Execute(permitPortsCmd(FirewallIptables, "", ports), true)
+}
+
+// permitPortsCmd returns the command adding the rules of the firewall to permit the ports,
+// the zone is only used by firewalld
+func permitPortsCmd(firewall, zone string, ports []int) string {
+	var cmds []string
+	switch firewall {
+	case FirewallFirewalld:
+		for _, port := range ports {
+			cmds = append(cmds, fmt.Sprintf("firewall-cmd --permanent --zone=%s --add-port=%d/tcp", zone, port))
+		}
+		cmds = append(cmds, "firewall-cmd --reload")
+	case FirewallIptables:
+		for _, port := range ports {
+			cmds = append(cmds, fmt.Sprintf("iptables -I INPUT -p tcp --dport %d -j ACCEPT", port))
+		}
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// firewalldZone returns the zone of the interface having the address of the host, the
+// default zone is used if the interface is not found or not bound to any zone
+func firewalldZone(e executor.TiOpsExecutor, host string) (string, error) {
+	// the interface is not found if the host is a hostname rather than an address
+	stdout, _, err := e.Execute(fmt.Sprintf("ip -o addr show to %s", host), false)
+	if fields := strings.Fields(string(stdout)); err == nil && len(fields) >= 2 {
+		// the VLAN interfaces are printed like `eth0.10@eth0`
+		iface := strings.SplitN(strings.TrimSuffix(fields[1], ":"), "@", 2)[0]
+		stdout, _, err := e.Execute(fmt.Sprintf("firewall-cmd --get-zone-of-interface=%s", iface), true)
+		if zone := strings.TrimSpace(string(stdout)); err == nil && zone != "" {
+			return zone, nil
+		}
+	}
+	stdout, stderr, err := e.Execute("firewall-cmd --get-default-zone", true)
+	if err != nil {
+		return "", errors.Annotatef(err, "failed to get the default zone of firewalld on %s, stderr: %s", host, stderr)
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// firewalldBlocked returns the ports blocked in the zone of firewalld. The zone with the
+// target ACCEPT permits all ports, otherwise the ports are permitted by the ports and the
+// services of the zone.
+func firewalldBlocked(e executor.TiOpsExecutor, zone string, ports []int) ([]int, error) {
+	stdout, _, err := e.Execute(fmt.Sprintf("firewall-cmd --permanent --zone=%s --get-target", zone), true)
+	if err == nil && strings.TrimSpace(string(stdout)) == "ACCEPT" {
+		return nil, nil
+	}
+
+	stdout, _, err = e.Execute(fmt.Sprintf("firewall-cmd --zone=%s --list-ports", zone), true)
+	if err != nil {
+		return nil, err
+	}
+	allowed := []func(int) bool{parseFirewalldPorts(string(stdout))}
+	stdout, _, err = e.Execute(fmt.Sprintf("firewall-cmd --zone=%s --list-services", zone), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range strings.Fields(string(stdout)) {
+		stdout, _, err := e.Execute(fmt.Sprintf("firewall-cmd --permanent --service=%s --get-ports", service), true)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, parseFirewalldPorts(string(stdout)))
+	}
+
+	var blocked []int
+	for _, port := range ports {
+		permitted := false
+		for _, allow := range allowed {
+			permitted = permitted || allow(port)
+		}
+		if !permitted {
+			blocked = append(blocked, port)
+		}
+	}
+	return blocked, nil
+}
+
+// Zone returns the zone of firewalld which the ports are checked in, empty if firewalld is not active
+func (c *CheckFirewall) Zone() string {
+	return c.zone
+}
+
+// Firewall returns the kind of firewall detected on the host
+func (c *CheckFirewall) Firewall() string {
+	return c.firewall
+}
+
+// Blocked returns the ports blocked by the firewall before fixing
+func (c *CheckFirewall) Blocked() []int {
+	return c.blocked
+}
+
+// portRange is a range of ports, both ends are inclusive
+type portRange struct {
+	from, to int
+}
+
+func (r portRange) contains(port int) bool {
+	return port >= r.from && port <= r.to
+}
+
+// parsePortRange parses a port or a range of ports like `9000-9010` or `9000:9010`
+func parsePortRange(s string, sep string) (portRange, bool) {
+	parts := strings.SplitN(s, sep, 2)
+	from, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return portRange{}, false
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.Atoi(parts[1]); err != nil {
+			return portRange{}, false
+		}
+	}
+	return portRange{from, to}, true
+}
+
+// parseFirewalldPorts parses the output of `firewall-cmd --list-ports` or the ports of a
+// service, which is like:
+// 2379/tcp 4000/tcp 9000-9010/tcp 53/udp
+func parseFirewalldPorts(output string) func(port int) bool {
+	var ranges []portRange
+	for _, field := range strings.Fields(output) {
+		fields := strings.SplitN(field, "/", 2)
+		if len(fields) != 2 || fields[1] != "tcp" {
+			continue
+		}
+		if r, ok := parsePortRange(fields[0], "-"); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return func(port int) bool {
+		for _, r := range ranges {
+			if r.contains(port) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// iptablesRule is a simplified rule of the INPUT chain
+type iptablesRule struct {
+	target string
+	ports  []portRange // nil means all ports
+}
+
+type iptablesRules struct {
+	policy string
+	rules  []iptablesRule
+}
+
+// accept reports whether a new TCP connection to the port from other hosts is accepted,
+// the first rule matching the port decides and the policy is used if there is none
+func (r iptablesRules) accept(port int) bool {
+	for _, rule := range r.rules {
+		matched := rule.ports == nil
+		for _, pr := range rule.ports {
+			if pr.contains(port) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			return rule.target == "ACCEPT"
+		}
+	}
+	return r.policy == "ACCEPT"
+}
+
+// parseIptablesRules parses the output of `iptables -S INPUT`, which is like:
+// -P INPUT ACCEPT
+// -A INPUT -m state --state RELATED,ESTABLISHED -j ACCEPT
+// -A INPUT -p tcp -m tcp --dport 2379 -j ACCEPT
+// -A INPUT -p tcp -m multiport --dports 9000:9010,9100 -j ACCEPT
+// -A INPUT -j REJECT --reject-with icmp-host-prohibited
+// The rules which only match part of the traffic (by source, interface, state, etc.)
+// or jump to other chains can not decide whether a port is permitted and are ignored.
+func parseIptablesRules(output string) iptablesRules {
+	rules := iptablesRules{policy: "ACCEPT"}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[1] != "INPUT" {
+			continue
+		}
+		if fields[0] == "-P" && len(fields) >= 3 {
+			rules.policy = fields[2]
+			continue
+		}
+		if fields[0] != "-A" {
+			continue
+		}
+
+		rule := iptablesRule{}
+		partial := false
+		for i := 2; i < len(fields); i++ {
+			arg := fields[i]
+			next := ""
+			if i+1 < len(fields) {
+				next = fields[i+1]
+			}
+			switch arg {
+			case "-j":
+				rule.target = next
+				i++
+			case "-p":
+				if next != "tcp" && next != "all" {
+					partial = true
+				}
+				i++
+			case "-m":
+				// the tcp and multiport matches are decided by the ports
+				if next != "tcp" && next != "multiport" {
+					partial = true
+				}
+				i++
+			case "--dport", "--dports":
+				for _, p := range strings.Split(next, ",") {
+					if r, ok := parsePortRange(p, ":"); ok {
+						rule.ports = append(rule.ports, r)
+					}
+				}
+				i++
+			case "--reject-with":
+				i++
+			default:
+				partial = true
+			}
+		}
+		if partial {
+			continue
+		}
+		switch rule.target {
+		case "ACCEPT", "DROP", "REJECT":
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+	return rules
+}
+
+// Rollback implements the Task interface
+func (c *CheckFirewall) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckFirewall) String() string {
+	return fmt.Sprintf("CheckFirewall: host=%s, ports=%s, fix=%v", c.host, utils.JoinInt(c.ports, ","), c.fix)
+}
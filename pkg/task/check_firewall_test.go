@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// firewallExecutor returns a mocked executor which answers the firewall commands with the outputs
+func firewallExecutor(outputs map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		output, found := outputs[cmd]
+		if !found {
+			return nil, nil, errors.New("command not found")
+		}
+		return []byte(output), nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckFirewallFirewalld(c *C) {
+	e := firewallExecutor(map[string]string{
+		"systemctl is-active firewalld":                                "active\n",
+		"ip -o addr show to 172.16.5.140":                              "2: eth1    inet 172.16.5.140/24 brd 172.16.5.255 scope global eth1\\       valid_lft forever preferred_lft forever\n",
+		"firewall-cmd --get-zone-of-interface=eth1":                    "internal\n",
+		"firewall-cmd --permanent --zone=internal --get-target":        "default\n",
+		"firewall-cmd --zone=internal --list-ports":                    "2379/tcp 4000/tcp 9000-9010/tcp 20160/udp\n",
+		"firewall-cmd --zone=internal --list-services":                 "ssh node-exporter\n",
+		"firewall-cmd --permanent --service=ssh --get-ports":           "22/tcp\n",
+		"firewall-cmd --permanent --service=node-exporter --get-ports": "9100/tcp\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{20160, 4000, 2380, 9005, 9100}}
+	err := t.Execute(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(errorx.IsOfType(err, ErrFirewallBlocked), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Ports 2380, 20160 on 172.16.5.140 are blocked by firewalld \\(zone internal\\).*")
+	c.Assert(t.Firewall(), Equals, FirewallFirewalld)
+	c.Assert(t.Zone(), Equals, "internal")
+	c.Assert(t.Blocked(), DeepEquals, []int{2380, 20160})
+}
+
+func (s *taskSuite) TestCheckFirewallFirewalldZone(c *C) {
+	// the default zone is used if the interface is not bound to a zone
+	e := firewallExecutor(map[string]string{
+		"systemctl is-active firewalld":                       "active\n",
+		"ip -o addr show to 172.16.5.140":                     "3: eth0.10@eth0    inet 172.16.5.140/24 scope global eth0.10\n",
+		"firewall-cmd --get-default-zone":                     "public\n",
+		"firewall-cmd --permanent --zone=public --get-target": "default\n",
+		"firewall-cmd --zone=public --list-ports":             "\n",
+		"firewall-cmd --zone=public --list-services":          "\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{4000}}
+	c.Assert(t.Execute(ctx), NotNil)
+	c.Assert(t.Zone(), Equals, "public")
+	c.Assert(e.commands()[2], Equals, "firewall-cmd --get-zone-of-interface=eth0.10")
+	c.Assert(t.Blocked(), DeepEquals, []int{4000})
+
+	// the zone with the target ACCEPT permits all ports
+	e = firewallExecutor(map[string]string{
+		"systemctl is-active firewalld":                        "active\n",
+		"ip -o addr show to 172.16.5.140":                      "2: eth1    inet 172.16.5.140/24 scope global eth1\n",
+		"firewall-cmd --get-zone-of-interface=eth1":            "trusted\n",
+		"firewall-cmd --permanent --zone=trusted --get-target": "ACCEPT\n",
+	})
+	ctx = newMockContext("172.16.5.140", e)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Zone(), Equals, "trusted")
+	c.Assert(t.Blocked(), HasLen, 0)
+}
+
+func (s *taskSuite) TestCheckFirewallFirewalldFix(c *C) {
+	e := firewallExecutor(map[string]string{
+		"systemctl is-active firewalld":                         "active\n",
+		"ip -o addr show to 172.16.5.140":                       "2: eth1    inet 172.16.5.140/24 scope global eth1\n",
+		"firewall-cmd --get-zone-of-interface=eth1":             "internal\n",
+		"firewall-cmd --permanent --zone=internal --get-target": "default\n",
+		"firewall-cmd --zone=internal --list-ports":             "4000/tcp\n",
+		"firewall-cmd --zone=internal --list-services":          "\n",
+		"firewall-cmd --permanent --zone=internal --add-port=2379/tcp && firewall-cmd --permanent --zone=internal --add-port=2380/tcp && firewall-cmd --reload": "success\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{2379, 2380, 4000}, fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Blocked(), DeepEquals, []int{2379, 2380})
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "firewall-cmd --permanent --zone=internal --add-port=2379/tcp && firewall-cmd --permanent --zone=internal --add-port=2380/tcp && firewall-cmd --reload")
+}
+
+func (s *taskSuite) TestCheckFirewallIptables(c *C) {
+	e := firewallExecutor(map[string]string{
+		"systemctl is-active firewalld": "inactive\n",
+		"iptables -S INPUT": `-P INPUT ACCEPT
+-A INPUT -m state --state RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -i lo -j ACCEPT
+-A INPUT -s 10.0.0.0/8 -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 4000 -j ACCEPT
+-A INPUT -p tcp -m multiport --dports 2379:2380,9100 -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 20180 -j DROP
+-A INPUT -j REJECT --reject-with icmp-host-prohibited
+`,
+		"iptables -I INPUT -p tcp --dport 20160 -j ACCEPT && iptables -I INPUT -p tcp --dport 20180 -j ACCEPT": "",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{4000, 2379, 2380, 9100, 20160, 20180}}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrFirewallBlocked), IsTrue)
+	c.Assert(t.Firewall(), Equals, FirewallIptables)
+	c.Assert(t.Blocked(), DeepEquals, []int{20160, 20180})
+
+	t.fix = true
+	c.Assert(t.Execute(ctx), IsNil)
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "iptables -I INPUT -p tcp --dport 20160 -j ACCEPT && iptables -I INPUT -p tcp --dport 20180 -j ACCEPT")
+}
+
+func (s *taskSuite) TestCheckFirewallIptablesPolicy(c *C) {
+	// all ports are blocked by the policy except the permitted ones
+	e := firewallExecutor(map[string]string{
+		"iptables -S INPUT": "-P INPUT DROP\n-A INPUT -p tcp --dport 4000 -j ACCEPT\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{4000, 10080}}
+	c.Assert(t.Execute(ctx), NotNil)
+	c.Assert(t.Blocked(), DeepEquals, []int{10080})
+}
+
+func (s *taskSuite) TestCheckFirewallNone(c *C) {
+	// neither firewalld nor iptables is available
+	e := firewallExecutor(map[string]string{})
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckFirewall{host: "172.16.5.140", ports: []int{4000, 10080}, fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Firewall(), Equals, FirewallNone)
+	c.Assert(t.Blocked(), HasLen, 0)
+
+	// iptables without any rule blocks nothing
+	e = firewallExecutor(map[string]string{
+		"iptables -S INPUT": "-P INPUT ACCEPT\n",
+	})
+	ctx = newMockContext("172.16.5.140", e)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Firewall(), Equals, FirewallNone)
+	c.Assert(e.commands(), DeepEquals, []string{"systemctl is-active firewalld", "iptables -S INPUT"})
+}
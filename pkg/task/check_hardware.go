@@ -0,0 +1,261 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// HardwareNode is a host of a component with the data directory whose disk is measured,
+// the nearest existing parent is measured if the directory is not created yet
+type HardwareNode struct {
+	Host    string
+	DataDir string
+}
+
+// HostHardware is the CPU count, the memory and the disk size of the data directory of a node
+type HostHardware struct {
+	CPUs     int
+	MemoryKB uint64
+	DiskKB   uint64
+}
+
+// HardwareDeviation is a resource of a node which deviates from the median of its component
+type HardwareDeviation struct {
+	Component string
+	Host      string
+	Resource  string
+	Value     string
+	Median    string
+}
+
+// CheckHardware is used to detect the nodes of a component whose CPU count, memory or disk
+// size deviates from the median of the component by more than the tolerance, e.g. 0.2 is
+// 20%. The mixed nodes are balanced with the same weight by PD, so the smaller ones become
+// the hot spots. The deviations are only warned, the operators may set the labels or the
+// weights of the stores accordingly.
+type CheckHardware struct {
+	inspection
+
+	groups    map[string][]HardwareNode // component -> nodes
+	tolerance float64
+
+	hardware   map[string]map[string]HostHardware // component -> host -> hardware
+	deviations []HardwareDeviation
+}
+
+// Execute implements the Task interface
+func (c *CheckHardware) Execute(ctx *Context) error {
+	c.hardware = make(map[string]map[string]HostHardware)
+	c.deviations = nil
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for comp, nodes := range c.groups {
+		c.hardware[comp] = make(map[string]HostHardware)
+		for _, node := range nodes {
+			e, found := ctx.GetExecutor(node.Host)
+			if !found {
+				return ErrNoExecutor
+			}
+			wg.Add(1)
+			go func(comp string, node HardwareNode) {
+				defer wg.Done()
+				hw, err := collectHardware(e, node)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				c.hardware[comp][node.Host] = hw
+			}(comp, node)
+		}
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	var comps []string
+	for comp := range c.groups {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+	for _, comp := range comps {
+		c.deviations = append(c.deviations, c.deviate(comp)...)
+	}
+	if len(c.deviations) == 0 {
+		return nil
+	}
+
+	log.Warnf("The hardware of the following nodes deviates from the others of the same component by more than %.0f%%:", c.tolerance*100)
+	rows := [][]string{{"Component", "Host", "Resource", "Value", "Median"}}
+	for _, d := range c.deviations {
+		rows = append(rows, []string{d.Component, d.Host, d.Resource, d.Value, d.Median})
+	}
+	cliutil.PrintTable(rows, true)
+	log.Warnf("Please consider setting the labels or the weights of these nodes to keep the load balanced")
+	return nil
+}
+
+// deviate returns the deviations of the nodes of the component in the order of the nodes
+func (c *CheckHardware) deviate(comp string) []HardwareDeviation {
+	nodes := c.groups[comp]
+	if len(nodes) < 2 {
+		return nil
+	}
+	resources := []struct {
+		name   string
+		value  func(HostHardware) float64
+		format func(float64) string
+	}{
+		{"CPU", func(hw HostHardware) float64 { return float64(hw.CPUs) }, func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }},
+		{"Memory", func(hw HostHardware) float64 { return float64(hw.MemoryKB) }, formatKB},
+		{"Disk", func(hw HostHardware) float64 { return float64(hw.DiskKB) }, formatKB},
+	}
+
+	var deviations []HardwareDeviation
+	for _, node := range nodes {
+		for _, r := range resources {
+			var values []float64
+			for _, n := range nodes {
+				values = append(values, r.value(c.hardware[comp][n.Host]))
+			}
+			median := medianOf(values)
+			value := r.value(c.hardware[comp][node.Host])
+			if median == 0 || math.Abs(value-median)/median <= c.tolerance {
+				continue
+			}
+			deviations = append(deviations, HardwareDeviation{
+				Component: comp,
+				Host:      node.Host,
+				Resource:  r.name,
+				Value:     r.format(value),
+				Median:    r.format(median),
+			})
+		}
+	}
+	return deviations
+}
+
+// Hardware returns the hardware of the nodes by the component and the host
+func (c *CheckHardware) Hardware() map[string]map[string]HostHardware {
+	return c.hardware
+}
+
+// Deviations returns the resources of the nodes deviating from their components
+func (c *CheckHardware) Deviations() []HardwareDeviation {
+	return c.deviations
+}
+
+// collectHardware gets the CPU count, the memory and the disk size of the data directory of the node
+func collectHardware(e executor.TiOpsExecutor, node HardwareNode) (HostHardware, error) {
+	var hw HostHardware
+
+	stdout, _, err := e.Execute("nproc", false)
+	if err != nil {
+		return hw, errors.Annotatef(err, "failed to get the CPU count of %s", node.Host)
+	}
+	if hw.CPUs, err = strconv.Atoi(strings.TrimSpace(string(stdout))); err != nil {
+		return hw, errors.Annotatef(err, "failed to parse the CPU count of %s", node.Host)
+	}
+
+	stdout, _, err = e.Execute("cat /proc/meminfo", false)
+	if err != nil {
+		return hw, errors.Annotatef(err, "failed to get the memory of %s", node.Host)
+	}
+	hw.MemoryKB = parseMemTotal(string(stdout))
+
+	if node.DataDir != "" {
+		cmd := fmt.Sprintf(`d=%s; while [ ! -e "$d" ]; do d=$(dirname "$d"); done; df -Pk "$d"`, node.DataDir)
+		stdout, stderr, err := e.Execute(cmd, false)
+		if err != nil {
+			return hw, errors.Annotatef(err, "failed to get the disk size of %s on %s, stderr: %s", node.DataDir, node.Host, stderr)
+		}
+		hw.DiskKB = parseDiskSize(string(stdout))
+	}
+	return hw, nil
+}
+
+// parseMemTotal returns the total memory in KiB from /proc/meminfo, which has a line like
+// `MemTotal:       16314604 kB`
+func parseMemTotal(meminfo string) uint64 {
+	for _, line := range strings.Split(meminfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseUint(fields[1], 10, 64)
+			return kb
+		}
+	}
+	return 0
+}
+
+// parseDiskSize returns the size in KiB of the filesystem from the output of `df -Pk`
+func parseDiskSize(output string) uint64 {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 2 {
+		return 0
+	}
+	kb, _ := strconv.ParseUint(fields[1], 10, 64)
+	return kb
+}
+
+// Rollback implements the Task interface
+func (c *CheckHardware) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckHardware) String() string {
+	var comps []string
+	for comp, nodes := range c.groups {
+		comps = append(comps, fmt.Sprintf("%s(%d)", comp, len(nodes)))
+	}
+	sort.Strings(comps)
+	return fmt.Sprintf("CheckHardware: components=%s, tolerance=%v", strings.Join(comps, ","), c.tolerance)
+}
+
+// medianOf returns the median of the values
+func medianOf(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// formatKB formats the size in KiB to GiB
+func formatKB(kb float64) string {
+	return fmt.Sprintf("%.1fGiB", kb/1024/1024)
+}
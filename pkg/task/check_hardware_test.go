@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+// hardwareContext mocks the hosts with the CPU count, the memory and the disk size in GiB
+func hardwareContext(hosts map[string][3]int) *Context {
+	ctx, _ := mockHostsContext(hostsOf(hosts), func(host string) *mockExecutor {
+		hw := hosts[host]
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			switch {
+			case cmd == "nproc":
+				return []byte(fmt.Sprintf("%d\n", hw[0])), nil, nil
+			case cmd == "cat /proc/meminfo":
+				return []byte(fmt.Sprintf("MemTotal:       %d kB\nMemFree:         1024 kB\n", hw[1]*1024*1024)), nil, nil
+			case strings.Contains(cmd, "df -Pk"):
+				return []byte(fmt.Sprintf("Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/nvme0n1    %d 1024 1024      1%% /data\n", hw[2]*1024*1024)), nil, nil
+			}
+			return nil, nil, nil
+		}}
+	})
+	return ctx
+}
+
+func (s *taskSuite) TestCheckHardwareHomogeneous(c *C) {
+	ctx := hardwareContext(map[string][3]int{
+		"172.16.5.140": {16, 64, 1800},
+		"172.16.5.141": {16, 62, 1800},
+		"172.16.5.142": {16, 64, 1700},
+	})
+	t := &CheckHardware{
+		groups: map[string][]HardwareNode{
+			"tikv": {
+				{Host: "172.16.5.140", DataDir: "/data/tikv-20160"},
+				{Host: "172.16.5.141", DataDir: "/data/tikv-20160"},
+				{Host: "172.16.5.142", DataDir: "/data/tikv-20160"},
+			},
+		},
+		tolerance: 0.2,
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Deviations(), HasLen, 0)
+	c.Assert(t.Hardware()["tikv"]["172.16.5.141"], DeepEquals, HostHardware{CPUs: 16, MemoryKB: 62 * 1024 * 1024, DiskKB: 1800 * 1024 * 1024})
+}
+
+func (s *taskSuite) TestCheckHardwareHeterogeneous(c *C) {
+	ctx := hardwareContext(map[string][3]int{
+		"172.16.5.140": {16, 64, 1800},
+		"172.16.5.141": {16, 64, 1800},
+		"172.16.5.142": {8, 32, 3600},
+		"172.16.5.143": {4, 8, 100},
+	})
+	t := &CheckHardware{
+		groups: map[string][]HardwareNode{
+			"tikv": {
+				{Host: "172.16.5.140", DataDir: "/data/tikv-20160"},
+				{Host: "172.16.5.141", DataDir: "/data/tikv-20160"},
+				{Host: "172.16.5.142", DataDir: "/data/tikv-20160"},
+			},
+			// a single node is never heterogeneous
+			"pd": {{Host: "172.16.5.143", DataDir: "/data/pd-2379"}},
+		},
+		tolerance: 0.2,
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Deviations(), DeepEquals, []HardwareDeviation{
+		{Component: "tikv", Host: "172.16.5.142", Resource: "CPU", Value: "8", Median: "16"},
+		{Component: "tikv", Host: "172.16.5.142", Resource: "Memory", Value: "32.0GiB", Median: "64.0GiB"},
+		{Component: "tikv", Host: "172.16.5.142", Resource: "Disk", Value: "3600.0GiB", Median: "1800.0GiB"},
+	})
+
+	// wide enough
+	t.tolerance = 1
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Deviations(), HasLen, 0)
+}
+
+func (s *taskSuite) TestParseHardware(c *C) {
+	c.Assert(parseMemTotal("MemFree: 1 kB\nMemTotal:       16314604 kB\n"), Equals, uint64(16314604))
+	c.Assert(parseMemTotal(""), Equals, uint64(0))
+	c.Assert(parseDiskSize("Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 102687672 1 1 1% /\n"), Equals, uint64(102687672))
+	c.Assert(parseDiskSize("df: /data: No such file or directory\n"), Equals, uint64(0))
+	c.Assert(medianOf([]float64{4, 1, 3, 2}), Equals, 2.5)
+}
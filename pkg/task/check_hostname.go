@@ -0,0 +1,157 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSHostname = errNS.NewSubNamespace("hostname")
+	// ErrHostnameDuplicated means some hosts of the cluster have the same hostname
+	ErrHostnameDuplicated = errNSHostname.NewType("duplicated", errutil.ErrTraitPreCheck)
+)
+
+// CheckHostname is used to check whether the hostnames of the hosts are unique in the
+// cluster, which are used as the labels of the metrics. The duplicated ones except the
+// first host of each are set to unique ones by hostnamectl if fix is enabled.
+type CheckHostname struct {
+	inspection
+
+	hosts []string
+	fix   bool
+
+	hostnames map[string]string
+}
+
+// Execute implements the Task interface
+func (c *CheckHostname) Execute(ctx *Context) error {
+	c.hostnames = make(map[string]string)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			stdout, _, err := e.Execute("hostname", false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to get the hostname of %s", host))
+				return
+			}
+			c.hostnames[host] = strings.TrimSpace(string(stdout))
+		}(host)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	// the hosts having the same hostname as an earlier one
+	owners := make(map[string]string)
+	var duplicated []string
+	for _, host := range c.hosts {
+		name := c.hostnames[host]
+		if _, ok := owners[name]; ok {
+			duplicated = append(duplicated, host)
+			continue
+		}
+		owners[name] = host
+	}
+	if len(duplicated) == 0 {
+		return nil
+	}
+
+	if !c.fix {
+		var problems []string
+		for _, host := range duplicated {
+			problems = append(problems, fmt.Sprintf("%s is %s, the same as %s", host, c.hostnames[host], owners[c.hostnames[host]]))
+		}
+		return ErrHostnameDuplicated.
+			New("The hostnames of %d hosts are duplicated:\n  - %s", len(duplicated), strings.Join(problems, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString("Please set unique hostnames to the hosts with `hostnamectl set-hostname <name>`, or deploy with --fix-hostname to set them."))
+	}
+
+	for _, host := range duplicated {
+		name := uniqueHostname(c.hostnames[host], host, owners)
+		e, _ := ctx.GetExecutor(host)
+		log.Infof("Setting the hostname of %s from %s to %s", host, c.hostnames[host], name)
+		if _, stderr, err := e.Execute(setHostnameCmd(name), true); err != nil {
+			return errors.Annotatef(err, "failed to set the hostname of %s, stderr: %s", host, stderr)
+		}
+		c.hostnames[host] = name
+		owners[name] = host
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface, the first host keeps its hostname anyway
+func (c *CheckHostname) planFix(plan *Plan) {
+	if !c.fix || len(c.hosts) < 2 {
+		return
+	}
+	for _, host := range c.hosts[1:] {
+		e := plan.conditionalExecutor(host, "if the hostname is the same as an earlier host")
+		_, _, _ = e.Execute(setHostnameCmd(uniqueHostname("<hostname>", host, nil)), true)
+	}
+}
+
+// setHostnameCmd returns the command setting the hostname of the host
+func setHostnameCmd(name string) string {
+	return fmt.Sprintf("hostnamectl set-hostname %s", name)
+}
+
+// uniqueHostname returns a hostname derived from the duplicated one and the address of the
+// host, which is not taken by the other hosts
+func uniqueHostname(name, host string, taken map[string]string) string {
+	suffix := strings.NewReplacer(".", "-", ":", "-").Replace(host)
+	candidate := fmt.Sprintf("%s-%s", strings.SplitN(name, ".", 2)[0], suffix)
+	for i := 2; ; i++ {
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%s-%d", strings.SplitN(name, ".", 2)[0], suffix, i)
+	}
+}
+
+// Hostnames returns the hostname of each host
+func (c *CheckHostname) Hostnames() map[string]string {
+	return c.hostnames
+}
+
+// Rollback implements the Task interface
+func (c *CheckHostname) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckHostname) String() string {
+	return fmt.Sprintf("CheckHostname: hosts=%s, fix=%v", strings.Join(c.hosts, ","), c.fix)
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func hostnameContext(hostnames map[string]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(hostnames), func(host string) *mockExecutor {
+		name := hostnames[host]
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			if cmd == "hostname" {
+				return []byte(name + "\n"), nil, nil
+			}
+			return nil, nil, nil
+		}}
+	})
+}
+
+func (s *taskSuite) TestCheckHostname(c *C) {
+	hosts := []string{"172.16.5.140", "172.16.5.141", "172.16.5.142"}
+	ctx, executors := hostnameContext(map[string]string{
+		"172.16.5.140": "localhost.localdomain",
+		"172.16.5.141": "localhost.localdomain",
+		"172.16.5.142": "tidb-3",
+	})
+
+	t := &CheckHostname{hosts: hosts}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrHostnameDuplicated), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The hostnames of 1 hosts are duplicated:\n  - 172.16.5.141 is localhost.localdomain, the same as 172.16.5.140.*")
+
+	// the later one is set to a unique hostname
+	t = &CheckHostname{hosts: hosts, fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(executors["172.16.5.140"].commands(), DeepEquals, []string{"hostname", "hostname"})
+	cmds := executors["172.16.5.141"].commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "hostnamectl set-hostname localhost-172-16-5-141")
+	c.Assert(t.Hostnames(), DeepEquals, map[string]string{
+		"172.16.5.140": "localhost.localdomain",
+		"172.16.5.141": "localhost-172-16-5-141",
+		"172.16.5.142": "tidb-3",
+	})
+}
+
+func (s *taskSuite) TestCheckHostnameUnique(c *C) {
+	ctx, _ := hostnameContext(map[string]string{
+		"172.16.5.140": "tidb-1",
+		"172.16.5.141": "tidb-2",
+	})
+	t := &CheckHostname{hosts: []string{"172.16.5.140", "172.16.5.141"}}
+	c.Assert(t.Execute(ctx), IsNil)
+
+	// the derived hostname taken by another host is not used
+	taken := map[string]string{"tidb-172-16-5-141": "172.16.5.142"}
+	c.Assert(uniqueHostname("tidb", "172.16.5.141", taken), Equals, "tidb-172-16-5-141-2")
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSInitSystem = errNS.NewSubNamespace("init_system")
+	// ErrInitSystemUnsupported means the host is not managed by a supported systemd
+	ErrInitSystemUnsupported = errNSInitSystem.NewType("unsupported", errutil.ErrTraitPreCheck)
+)
+
+// minSystemdVersion is the systemd shipped with CentOS 7, the oldest supported distribution
+const minSystemdVersion = 219
+
+// detectContainerCmd prints the kind of container the host is in, or nothing if it's not in any
+const detectContainerCmd = `if [ -e /.dockerenv ]; then echo docker; ` +
+	`elif [ -n "$container" ]; then echo "$container"; ` +
+	`elif grep -qaE 'docker|kubepods|containerd|lxc' /proc/1/cgroup 2>/dev/null; then echo container; fi`
+
+// CheckInitSystem is used to check whether the host is managed by systemd, which the
+// services of the cluster are installed to
+type CheckInitSystem struct {
+	inspection
+
+	host string
+
+	systemdVersion int
+	init           string // the command name of PID 1
+	container      string // the kind of container the host is in
+}
+
+// Execute implements the Task interface
+func (c *CheckInitSystem) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	stdout, stderr, err := e.Execute("systemctl --version", false)
+	if err != nil {
+		return ErrInitSystemUnsupported.
+			Wrap(err, "systemctl is not available on %s: %s", c.host, strings.TrimSpace(string(stderr))).
+			WithProperty(cliutil.SuggestionFromString("The services of the cluster are managed by systemd, please deploy to hosts with systemd."))
+	}
+	version := string(stdout)
+
+	stdout, _, err = e.Execute("cat /proc/1/comm", false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the init process of %s", c.host)
+	}
+	c.init = strings.TrimSpace(string(stdout))
+
+	stdout, _, err = e.Execute(detectContainerCmd, false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to detect the container of %s", c.host)
+	}
+	c.container = strings.TrimSpace(string(stdout))
+
+	c.systemdVersion = parseSystemdVersion(version)
+	if c.systemdVersion == 0 {
+		return errors.Errorf("unknown systemd version of %s: %s", c.host, version)
+	}
+
+	if c.init != "systemd" {
+		if c.container != "" {
+			return ErrInitSystemUnsupported.
+				New("Host %s is a %s container whose init process is %s rather than systemd", c.host, c.container, c.init).
+				WithProperty(cliutil.SuggestionFromString("The services of the cluster can't be started without systemd as PID 1, please run the container with systemd as its init, or deploy to hosts or VMs."))
+		}
+		return ErrInitSystemUnsupported.
+			New("The init process of host %s is %s rather than systemd", c.host, c.init).
+			WithProperty(cliutil.SuggestionFromString("The services of the cluster are managed by systemd, please deploy to hosts booted with systemd."))
+	}
+	if c.systemdVersion < minSystemdVersion {
+		return ErrInitSystemUnsupported.
+			New("systemd %d on host %s is older than the minimum supported %d", c.systemdVersion, c.host, minSystemdVersion).
+			WithProperty(cliutil.SuggestionFromString("Please upgrade the OS of the host, or deploy to other hosts."))
+	}
+	return nil
+}
+
+// SystemdVersion returns the version of systemd detected on the host
+func (c *CheckInitSystem) SystemdVersion() int {
+	return c.systemdVersion
+}
+
+// Container returns the kind of container the host is in, empty if it's not in any
+func (c *CheckInitSystem) Container() string {
+	return c.container
+}
+
+// parseSystemdVersion parses the output of `systemctl --version`, the first line of which is
+// like `systemd 219` or `systemd 245 (245.4-4ubuntu3)`
+func parseSystemdVersion(output string) int {
+	fields := strings.Fields(strings.SplitN(output, "\n", 2)[0])
+	if len(fields) < 2 || fields[0] != "systemd" {
+		return 0
+	}
+	version, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// Rollback implements the Task interface
+func (c *CheckInitSystem) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckInitSystem) String() string {
+	return fmt.Sprintf("CheckInitSystem: host=%s", c.host)
+}
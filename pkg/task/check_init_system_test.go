@@ -0,0 +1,95 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// initExecutor returns a mocked executor of a host with the outputs of the commands,
+// a command absent from outputs fails like it's not found
+func initExecutor(outputs map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if cmd == detectContainerCmd {
+			return []byte(outputs["container"]), nil, nil
+		}
+		out, ok := outputs[cmd]
+		if !ok {
+			return nil, []byte("bash: systemctl: command not found"), errors.New("exit status 127")
+		}
+		return []byte(out), nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckInitSystem(c *C) {
+	e := initExecutor(map[string]string{
+		"systemctl --version": "systemd 219\n+PAM +AUDIT +SELINUX +IMA -APPARMOR +SMACK +SYSVINIT +UTMP\n",
+		"cat /proc/1/comm":    "systemd\n",
+	})
+	t := &CheckInitSystem{host: "172.16.5.140"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.SystemdVersion(), Equals, 219)
+	c.Assert(t.Container(), Equals, "")
+
+	// systemd in a container is fine
+	e = initExecutor(map[string]string{
+		"systemctl --version": "systemd 245 (245.4-4ubuntu3)\n",
+		"cat /proc/1/comm":    "systemd\n",
+		"container":           "lxc\n",
+	})
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.SystemdVersion(), Equals, 245)
+	c.Assert(t.Container(), Equals, "lxc")
+}
+
+func (s *taskSuite) TestCheckInitSystemUnsupported(c *C) {
+	t := &CheckInitSystem{host: "172.16.5.140"}
+
+	// systemctl is absent
+	e := initExecutor(map[string]string{"cat /proc/1/comm": "init\n"})
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrInitSystemUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*systemctl is not available on 172.16.5.140: bash: systemctl: command not found.*")
+
+	// systemd is installed in a docker container but not running as PID 1
+	e = initExecutor(map[string]string{
+		"systemctl --version": "systemd 219\n",
+		"cat /proc/1/comm":    "tini\n",
+		"container":           "docker\n",
+	})
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrInitSystemUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Host 172.16.5.140 is a docker container whose init process is tini rather than systemd.*")
+
+	// booted with another init
+	e = initExecutor(map[string]string{
+		"systemctl --version": "systemd 215\n",
+		"cat /proc/1/comm":    "init\n",
+	})
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrInitSystemUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The init process of host 172.16.5.140 is init rather than systemd.*")
+
+	// too old
+	e = initExecutor(map[string]string{
+		"systemctl --version": "systemd 208\n",
+		"cat /proc/1/comm":    "systemd\n",
+	})
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrInitSystemUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*systemd 208 on host 172.16.5.140 is older than the minimum supported 219.*")
+}
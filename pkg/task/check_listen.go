@@ -0,0 +1,204 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSListen = errNS.NewSubNamespace("listen")
+	// ErrListenMismatch means some instances don't listen on the addresses reachable by the others
+	ErrListenMismatch = errNSListen.NewType("mismatch")
+)
+
+// ListenProblem is a port of an instance which is not listened on the host of the instance
+type ListenProblem struct {
+	Instance  string
+	Port      int
+	Addresses []string // the addresses the port is listened on
+}
+
+// CheckListenAddress is used to verify the started instances listen on their hosts, i.e. on
+// the host or a wildcard address. A port bound to 127.0.0.1 or another address by a config
+// bug is not reachable by the other instances and breaks the cluster formation. The main
+// port of an instance must be listened, the others are checked if they are listened.
+type CheckListenAddress struct {
+	inspection
+
+	instances []meta.Instance
+
+	problems []ListenProblem
+}
+
+// Execute implements the Task interface
+func (c *CheckListenAddress) Execute(ctx *Context) error {
+	c.problems = nil
+
+	var hosts []string
+	listeners := make(map[string]map[int][]string) // host -> port -> addresses
+	for _, inst := range c.instances {
+		if _, ok := listeners[inst.GetHost()]; !ok {
+			listeners[inst.GetHost()] = nil
+			hosts = append(hosts, inst.GetHost())
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			stdout, _, err := e.Execute("ss -ltn", false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to list the listening ports of %s", host))
+				return
+			}
+			listeners[host] = parseListenAddresses(string(stdout))
+		}(host)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	for _, inst := range c.instances {
+		for _, port := range inst.UsedPorts() {
+			addrs := listeners[inst.GetHost()][port]
+			if len(addrs) == 0 && port != inst.GetPort() {
+				continue
+			}
+			if !reachableListen(inst.GetHost(), addrs) {
+				c.problems = append(c.problems, ListenProblem{Instance: inst.ID(), Port: port, Addresses: addrs})
+			}
+		}
+	}
+	if len(c.problems) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Instance", "Port", "Listening On"}}
+	var lines []string
+	for _, p := range c.problems {
+		listening := "not listened"
+		if len(p.Addresses) > 0 {
+			listening = strings.Join(p.Addresses, ",")
+		}
+		rows = append(rows, []string{p.Instance, strconv.Itoa(p.Port), listening})
+		lines = append(lines, fmt.Sprintf("%s: port %d is %s", p.Instance, p.Port, describeListen(p.Addresses)))
+	}
+	cliutil.PrintTable(rows, true)
+	return ErrListenMismatch.
+		New("%d ports are not listened on the hosts of the instances:\n  - %s", len(c.problems), strings.Join(lines, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check the listening addresses in the configs of the instances, they must be the host of the instance or 0.0.0.0."))
+}
+
+// Problems returns the ports not listened on the hosts of the instances
+func (c *CheckListenAddress) Problems() []ListenProblem {
+	return c.problems
+}
+
+// parseListenAddresses parses the output of `ss -ltn` to the addresses listened on by port,
+// the local address is like 0.0.0.0:2379, *:2379, [::]:2379, :::2379 or 127.0.0.53%lo:53
+func parseListenAddresses(output string) map[int][]string {
+	result := make(map[int][]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] != "LISTEN" {
+			continue
+		}
+		local := fields[3]
+		i := strings.LastIndex(local, ":")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.Atoi(local[i+1:])
+		if err != nil {
+			continue
+		}
+		addr := strings.Trim(local[:i], "[]")
+		if j := strings.Index(addr, "%"); j >= 0 {
+			addr = addr[:j]
+		}
+		if addr == "" || addr == "*" || addr == "::" {
+			addr = "0.0.0.0"
+		}
+		result[port] = append(result[port], addr)
+	}
+	return result
+}
+
+// reachableListen returns whether any of the addresses is the host or a wildcard one
+func reachableListen(host string, addrs []string) bool {
+	host = utils.UnwrapHost(host)
+	for _, addr := range addrs {
+		if addr == "0.0.0.0" || addr == host {
+			return true
+		}
+		if ip := net.ParseIP(addr); ip != nil && ip.Equal(net.ParseIP(host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeListen describes the addresses of a port which is not reachable
+func describeListen(addrs []string) string {
+	if len(addrs) == 0 {
+		return "not listened"
+	}
+	loopback := true
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip == nil || !ip.IsLoopback() {
+			loopback = false
+		}
+	}
+	if loopback {
+		return fmt.Sprintf("only listened on the loopback address %s", strings.Join(addrs, ","))
+	}
+	return fmt.Sprintf("listened on %s instead", strings.Join(addrs, ","))
+}
+
+// Rollback implements the Task interface
+func (c *CheckListenAddress) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckListenAddress) String() string {
+	var ids []string
+	for _, inst := range c.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("CheckListenAddress: instances=%s", strings.Join(ids, ","))
+}
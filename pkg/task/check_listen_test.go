@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+// ssOutput returns the output of `ss -ltn` with the local addresses
+func ssOutput(locals ...string) string {
+	output := "State      Recv-Q Send-Q Local Address:Port               Peer Address:Port\n"
+	for _, local := range locals {
+		output += "LISTEN     0      128    " + local + "               *:*\n"
+	}
+	return output
+}
+
+func listenContext(outputs map[string]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(outputs), func(host string) *mockExecutor {
+		output := outputs[host]
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			if cmd == "ss -ltn" {
+				return []byte(output), nil, nil
+			}
+			return nil, nil, nil
+		}}
+	})
+}
+
+func listenInstances(c *C) []meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`), topo), IsNil)
+	var insts []meta.Instance
+	insts = append(insts, (&meta.PDComponent{Specification: topo}).Instances()...)
+	insts = append(insts, (&meta.TiKVComponent{Specification: topo}).Instances()...)
+	return insts
+}
+
+func (s *taskSuite) TestCheckListenAddress(c *C) {
+	ctx, executors := listenContext(map[string]string{
+		"172.16.5.140": ssOutput("0.0.0.0:2379", "*:2380", "127.0.0.1:2379", "172.16.5.140:20160", "[::]:20180", "127.0.0.53%lo:53"),
+		"172.16.5.141": ssOutput("172.16.5.141:20160", "[::ffff:172.16.5.141]:20180"),
+	})
+	t := &CheckListenAddress{instances: listenInstances(c)}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Problems(), HasLen, 0)
+	// the listening ports of each host are listed once
+	c.Assert(executors["172.16.5.140"].commands(), DeepEquals, []string{"ss -ltn"})
+	c.Assert(executors["172.16.5.141"].commands(), DeepEquals, []string{"ss -ltn"})
+}
+
+func (s *taskSuite) TestCheckListenAddressMismatch(c *C) {
+	ctx, _ := listenContext(map[string]string{
+		// the client port of PD is only listened on the loopback address
+		"172.16.5.140": ssOutput("127.0.0.1:2379", "172.16.5.140:2380", "0.0.0.0:20160", "0.0.0.0:20180"),
+		// the status port is listened on another address, and the main port is not listened
+		"172.16.5.141": ssOutput("10.0.1.5:20180"),
+	})
+	t := &CheckListenAddress{instances: listenInstances(c)}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrListenMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*3 ports are not listened on the hosts of the instances:\n"+
+		"  - 172.16.5.140:2379: port 2379 is only listened on the loopback address 127.0.0.1\n"+
+		"  - 172.16.5.141:20160: port 20160 is not listened\n"+
+		"  - 172.16.5.141:20160: port 20180 is listened on 10.0.1.5 instead.*")
+	c.Assert(t.Problems(), DeepEquals, []ListenProblem{
+		{Instance: "172.16.5.140:2379", Port: 2379, Addresses: []string{"127.0.0.1"}},
+		{Instance: "172.16.5.141:20160", Port: 20160},
+		{Instance: "172.16.5.141:20160", Port: 20180, Addresses: []string{"10.0.1.5"}},
+	})
+
+	// a loopback host is reachable on the loopback address
+	ctx, _ = listenContext(map[string]string{"127.0.0.1": ssOutput("127.0.0.1:20160")})
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte("tikv_servers:\n  - host: 127.0.0.1\n"), topo), IsNil)
+	t = &CheckListenAddress{instances: (&meta.TiKVComponent{Specification: topo}).Instances()}
+	c.Assert(t.Execute(ctx), IsNil)
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSLogDevice = errNS.NewSubNamespace("log_device")
+	// ErrLogDeviceShared means the data directory and the log directory of an instance are
+	// on the same block device
+	ErrLogDeviceShared = errNSLogDevice.NewType("shared", errutil.ErrTraitPreCheck)
+)
+
+// CheckLogDevice is used to check whether the data directory and the log directory of an
+// instance are on separate block devices, the writes of the logs compete with the ones of
+// the data on the same device otherwise. The shared device is warned, or an error if strict.
+type CheckLogDevice struct {
+	inspection
+
+	host    string
+	id      string
+	dataDir string
+	logDir  string
+	strict  bool
+
+	dataDevice string
+	logDevice  string
+}
+
+// Execute implements the Task interface
+func (c *CheckLogDevice) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	devices := make([]string, 2)
+	for i, dir := range []string{c.dataDir, c.logDir} {
+		stdout, stderr, err := e.Execute(blockDeviceCmd(dir), false)
+		if err != nil {
+			return errors.Annotatef(err, "failed to get the block device of %s on %s, stderr: %s", dir, c.host, stderr)
+		}
+		devices[i] = parseBlockDevice(string(stdout))
+	}
+	c.dataDevice, c.logDevice = devices[0], devices[1]
+
+	// e.g. tmpfs or overlay in containers
+	if c.dataDevice == "" || c.logDevice == "" || c.dataDevice != c.logDevice {
+		return nil
+	}
+	msg := fmt.Sprintf("The data directory %s and the log directory %s of %s are on the same device %s of %s",
+		c.dataDir, c.logDir, c.id, c.dataDevice, c.host)
+	if !c.strict {
+		log.Warnf("%s, which may increase the write latency", msg)
+		return nil
+	}
+	return ErrLogDeviceShared.
+		New("%s", msg).
+		WithProperty(cliutil.SuggestionFromString("Please set the log_dir of the instance to a directory on another disk, or deploy without --deny-shared-log-device to ignore it."))
+}
+
+// Devices returns the block devices backing the data directory and the log directory
+func (c *CheckLogDevice) Devices() (string, string) {
+	return c.dataDevice, c.logDevice
+}
+
+// Rollback implements the Task interface
+func (c *CheckLogDevice) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckLogDevice) String() string {
+	return fmt.Sprintf("CheckLogDevice: host=%s, instance=%s, data_dir=%s, log_dir=%s", c.host, c.id, c.dataDir, c.logDir)
+}
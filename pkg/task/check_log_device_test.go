@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// deviceExecutor reports the block devices backing the directories by lsblk
+func deviceExecutor(devices map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		for dir, device := range devices {
+			if cmd == blockDeviceCmd(dir) {
+				return []byte(device), nil, nil
+			}
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckLogDevice(c *C) {
+	// on separate disks
+	e := deviceExecutor(map[string]string{
+		"/data1/tikv-20160":       `KNAME="nvme0n1" PKNAME="" TYPE="disk"`,
+		"/home/tidb/deploy/log":   `KNAME="sda2" PKNAME="sda" TYPE="part"`,
+		"/data1/tikv-20161":       `KNAME="nvme0n1p1" PKNAME="nvme0n1" TYPE="part"`,
+		"/data1/tikv-20161/log":   `KNAME="nvme0n1p1" PKNAME="nvme0n1" TYPE="part"`,
+		"/dev/shm/tikv-20162":     "",
+		"/dev/shm/tikv-20162/log": "",
+	})
+	t := &CheckLogDevice{host: "172.16.5.140", id: "172.16.5.140:20160", dataDir: "/data1/tikv-20160", logDir: "/home/tidb/deploy/log", strict: true}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	data, log := t.Devices()
+	c.Assert(data, Equals, "nvme0n1")
+	c.Assert(log, Equals, "sda")
+
+	// not on block devices
+	t = &CheckLogDevice{host: "172.16.5.140", id: "172.16.5.140:20162", dataDir: "/dev/shm/tikv-20162", logDir: "/dev/shm/tikv-20162/log", strict: true}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+
+	// on the same disk, it's warned only if not strict
+	t = &CheckLogDevice{host: "172.16.5.140", id: "172.16.5.140:20161", dataDir: "/data1/tikv-20161", logDir: "/data1/tikv-20161/log"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	data, log = t.Devices()
+	c.Assert(data, Equals, log)
+
+	t.strict = true
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrLogDeviceShared), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The data directory /data1/tikv-20161 and the log directory /data1/tikv-20161/log of 172.16.5.140:20161 are on the same device nvme0n1 of 172.16.5.140.*")
+}
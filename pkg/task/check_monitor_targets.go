@@ -0,0 +1,187 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSMonitorTargets = errNS.NewSubNamespace("monitor_targets")
+	// ErrMonitorTargetsStale means the scrape targets deployed to Prometheus don't match the topology
+	ErrMonitorTargetsStale = errNSMonitorTargets.NewType("stale", errutil.ErrTraitPreCheck)
+)
+
+// MonitorTarget is a scrape target of a job of Prometheus
+type MonitorTarget struct {
+	Job    string
+	Target string
+}
+
+// CheckMonitorTargets is used to compare the scrape targets in the prometheus.yml deployed to
+// a Prometheus instance with the instances of the current topology, which may be stale after
+// the topology is changed. The config is generated and Prometheus is reloaded if regenerate
+// is enabled.
+type CheckMonitorTargets struct {
+	inspection
+
+	clusterName    string
+	clusterVersion string
+	instance       *meta.MonitorInstance
+	deployUser     string
+	paths          meta.DirPaths
+	regenerate     bool
+
+	missing []MonitorTarget
+	extra   []MonitorTarget
+}
+
+// Execute implements the Task interface
+func (c *CheckMonitorTargets) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(c.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+
+	if err := os.MkdirAll(c.paths.Cache, 0755); err != nil {
+		return err
+	}
+	src := filepath.Join(c.paths.Deploy, "conf", "prometheus.yml")
+	dst := filepath.Join(c.paths.Cache, fmt.Sprintf("prometheus_%s_%d.live.yml", c.instance.GetHost(), c.instance.GetPort()))
+	if err := exec.Transfer(src, dst, true); err != nil {
+		return errors.Annotatef(err, "failed to fetch %s from %s", src, c.instance.GetHost())
+	}
+	data, err := ioutil.ReadFile(dst)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	live, err := parseScrapeTargets(data)
+	if err != nil {
+		return errors.Annotatef(err, "failed to parse %s of %s", src, c.instance.ID())
+	}
+
+	c.missing, c.extra = diffTargets(c.instance.PrometheusConfig(c.clusterName).Targets(), live)
+	if len(c.missing) == 0 && len(c.extra) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Job", "Target", "Status"}}
+	for _, t := range c.missing {
+		rows = append(rows, []string{t.Job, t.Target, "missing"})
+	}
+	for _, t := range c.extra {
+		rows = append(rows, []string{t.Job, t.Target, "extra"})
+	}
+	cliutil.PrintTable(rows, true)
+
+	if !c.regenerate {
+		return ErrMonitorTargetsStale.
+			New("The scrape targets of %s are stale, %d are missing and %d are extra", c.instance.ID(), len(c.missing), len(c.extra)).
+			WithProperty(cliutil.SuggestionFromString("Please run with --regenerate to generate the config of Prometheus from the topology."))
+	}
+
+	ctx.Logger().Infof("Regenerating the config of Prometheus %s", c.instance.ID())
+	if err := c.instance.InitConfig(exec, c.clusterName, c.clusterVersion, c.deployUser, c.paths); err != nil {
+		return errors.Annotatef(err, "failed to regenerate the config of %s", c.instance.ID())
+	}
+	// Prometheus reloads the config on SIGHUP
+	cmd := fmt.Sprintf("systemctl kill -s HUP %s", c.instance.ServiceName())
+	if _, stderr, err := exec.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to reload %s, stderr: %s", c.instance.ID(), stderr)
+	}
+	return nil
+}
+
+// parseScrapeTargets returns the static targets of each job in the config of Prometheus
+func parseScrapeTargets(data []byte) (map[string][]string, error) {
+	var cfg struct {
+		ScrapeConfigs []struct {
+			JobName       string `yaml:"job_name"`
+			StaticConfigs []struct {
+				Targets []string `yaml:"targets"`
+			} `yaml:"static_configs"`
+		} `yaml:"scrape_configs"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	targets := make(map[string][]string)
+	for _, job := range cfg.ScrapeConfigs {
+		for _, static := range job.StaticConfigs {
+			targets[job.JobName] = append(targets[job.JobName], static.Targets...)
+		}
+	}
+	return targets, nil
+}
+
+// diffTargets returns the expected targets absent from the live ones, and the live targets
+// not expected, only the jobs of the instances are compared
+func diffTargets(expected, live map[string][]string) (missing, extra []MonitorTarget) {
+	var jobs []string
+	for job := range expected {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+	for _, job := range jobs {
+		want := set.NewStringSet(expected[job]...)
+		got := set.NewStringSet(live[job]...)
+		for _, t := range sortedTargets(want.Difference(got)) {
+			missing = append(missing, MonitorTarget{job, t})
+		}
+		for _, t := range sortedTargets(got.Difference(want)) {
+			extra = append(extra, MonitorTarget{job, t})
+		}
+	}
+	return
+}
+
+func sortedTargets(s set.StringSet) []string {
+	targets := make([]string, 0, len(s))
+	for t := range s {
+		targets = append(targets, t)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Missing returns the targets of the topology absent from the deployed config
+func (c *CheckMonitorTargets) Missing() []MonitorTarget {
+	return c.missing
+}
+
+// Extra returns the targets of the deployed config absent from the topology
+func (c *CheckMonitorTargets) Extra() []MonitorTarget {
+	return c.extra
+}
+
+// Rollback implements the Task interface
+func (c *CheckMonitorTargets) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckMonitorTargets) String() string {
+	return fmt.Sprintf("CheckMonitorTargets: instance=%s, regenerate=%v", c.instance.ID(), c.regenerate)
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// liveTargets is the prometheus.yml deployed before 172.16.5.142 is scaled out
+const liveTargets = `
+global:
+  scrape_interval: 15s
+scrape_configs:
+  - job_name: "overwritten-nodes"
+    static_configs:
+    - targets:
+      - '172.16.5.140:9100'
+      - '172.16.5.141:9100'
+  - job_name: "tidb"
+    static_configs:
+    - targets:
+      - '172.16.5.140:10080'
+  - job_name: "tikv"
+    static_configs:
+    - targets:
+      - '172.16.5.141:20180'
+  - job_name: "pd"
+    static_configs:
+    - targets:
+      - '172.16.5.140:2379'
+  - job_name: "port_probe"
+    static_configs:
+    - targets:
+      - '172.16.5.140:2379'
+`
+
+// prometheusExecutor serves the deployed prometheus.yml on download
+type prometheusExecutor struct {
+	mockExecutor
+	live string
+
+	mu      sync.Mutex
+	uploads []string
+}
+
+func (e *prometheusExecutor) Transfer(src string, dst string, download bool) error {
+	if download {
+		return ioutil.WriteFile(dst, []byte(e.live), 0644)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.uploads = append(e.uploads, dst)
+	return nil
+}
+
+func monitorTargetsTask(c *C, cache string, regenerate bool) *CheckMonitorTargets {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tidb_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+monitoring_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	return &CheckMonitorTargets{
+		clusterName:    "test",
+		clusterVersion: "v4.0.0",
+		instance:       (&meta.MonitorComponent{Specification: &topo}).Instances()[0].(*meta.MonitorInstance),
+		deployUser:     "tidb",
+		paths:          meta.DirPaths{Deploy: "/home/tidb/deploy/prometheus-9090", Cache: cache},
+		regenerate:     regenerate,
+	}
+}
+
+func (s *taskSuite) TestCheckMonitorTargets(c *C) {
+	cache, err := ioutil.TempDir("", "monitor-targets")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	// the scaled out instance is missing
+	t := monitorTargetsTask(c, cache, false)
+	e := &prometheusExecutor{live: liveTargets}
+	ctx := NewContext()
+	ctx.SetExecutor("172.16.5.140", e)
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrMonitorTargetsStale), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The scrape targets of 172.16.5.140:9090 are stale, 2 are missing and 0 are extra.*")
+	c.Assert(t.Missing(), DeepEquals, []MonitorTarget{
+		{"overwritten-nodes", "172.16.5.142:9100"},
+		{"tikv", "172.16.5.142:20180"},
+	})
+	c.Assert(t.Extra(), HasLen, 0)
+	c.Assert(e.uploads, HasLen, 0)
+
+	// the scaled in instance is extra, the jobs not for the instances are ignored
+	e = &prometheusExecutor{live: strings.Replace(liveTargets, "'172.16.5.141:20180'", "'172.16.5.141:20180'\n      - '172.16.5.142:20180'\n      - '172.16.5.143:20180'", 1) +
+		"  - job_name: \"overwritten-nodes\"\n    static_configs:\n    - targets: ['172.16.5.142:9100']\n"}
+	ctx.SetExecutor("172.16.5.140", e)
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrMonitorTargetsStale), IsTrue)
+	c.Assert(t.Missing(), HasLen, 0)
+	c.Assert(t.Extra(), DeepEquals, []MonitorTarget{{"tikv", "172.16.5.143:20180"}})
+}
+
+func (s *taskSuite) TestCheckMonitorTargetsRegenerate(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+	cache, err := ioutil.TempDir("", "monitor-targets")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	t := monitorTargetsTask(c, cache, true)
+	e := &prometheusExecutor{live: liveTargets}
+	ctx := NewContext()
+	ctx.SetExecutor("172.16.5.140", e)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Missing(), HasLen, 2)
+
+	// the generated config has all the targets and Prometheus is reloaded
+	c.Assert(e.uploads, DeepEquals, []string{
+		"/tmp/" + filepath.Base(e.uploads[0]),
+		"/home/tidb/deploy/prometheus-9090/scripts/run_prometheus.sh",
+		"/home/tidb/deploy/prometheus-9090/conf/prometheus.yml",
+	})
+	data, err := ioutil.ReadFile(filepath.Join(cache, "tikv_172.16.5.140.yml"))
+	c.Assert(err, IsNil)
+	generated, err := parseScrapeTargets(data)
+	c.Assert(err, IsNil)
+	missing, extra := diffTargets(t.instance.PrometheusConfig("test").Targets(), generated)
+	c.Assert(missing, HasLen, 0)
+	c.Assert(extra, HasLen, 0)
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "systemctl kill -s HUP prometheus-9090.service")
+}
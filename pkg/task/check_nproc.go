@@ -0,0 +1,277 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	// ErrNprocTooLow means the max user processes of the deploy user is too low on some hosts
+	ErrNprocTooLow = errNSLimits.NewType("nproc_too_low", errutil.ErrTraitPreCheck)
+)
+
+// MinNproc is the minimum max user processes of the deploy user, the threads of TiKV
+// and TiDB count towards it and they crash once it's hit under load
+const MinNproc = 65536
+
+// pamLimitsCmd reads the PAM limits, limits.d takes precedence over limits.conf
+const pamLimitsCmd = "cat /etc/security/limits.conf /etc/security/limits.d/*.conf 2>/dev/null || true"
+
+// nprocLimitsFile is the file of the PAM limits written by the fix, which is read after
+// the ones shipped by the distributions, e.g. 20-nproc.conf of CentOS
+const nprocLimitsFile = "/etc/security/limits.d/99-tiup-nproc.conf"
+
+// NprocLimit is the max user processes of the deploy user on a host
+type NprocLimit struct {
+	// the effective soft/hard limits of a login session of the user
+	Soft string
+	Hard string
+	// the soft/hard limits of the user in the PAM limits, empty if they are not set
+	ConfiguredSoft string
+	ConfiguredHard string
+}
+
+// Sufficient returns whether both the effective limits are not below the minimum
+func (l *NprocLimit) Sufficient(minimum int) bool {
+	return nprocAtLeast(l.Soft, minimum) && nprocAtLeast(l.Hard, minimum)
+}
+
+// CheckNproc is used to check whether the max user processes (nproc) of the deploy user is
+// not below the minimum on the hosts. The effective limits of a login session of the user
+// are checked, and the ones set by the PAM limits are reported for reference. If fix is
+// enabled, the limits of the user are raised to the minimum by a file in limits.d, then they
+// are read again to verify.
+type CheckNproc struct {
+	inspection
+
+	hosts   []string
+	user    string
+	minimum int
+	fix     bool
+
+	limits map[string]*NprocLimit
+}
+
+// Execute implements the Task interface
+func (c *CheckNproc) Execute(ctx *Context) error {
+	c.limits = make(map[string]*NprocLimit)
+	if err := c.readLimits(ctx, c.hosts); err != nil {
+		return err
+	}
+
+	low := c.lowHosts(c.hosts)
+	if len(low) > 0 && c.fix {
+		for _, host := range low {
+			e, _ := ctx.GetExecutor(host)
+			log.Infof("Raising the max user processes of %s on %s to %d", c.user, host, c.minimum)
+			if err := c.raise(e, host); err != nil {
+				return err
+			}
+		}
+		// verify the limits are raised by reading them again rather than trusting the commands
+		if err := c.readLimits(ctx, low); err != nil {
+			return err
+		}
+	}
+
+	rows := [][]string{{"Host", "Soft", "Hard", "PAM Limits"}}
+	for _, host := range c.hosts {
+		l := c.limits[host]
+		configured := "-"
+		if l.ConfiguredSoft != "" || l.ConfiguredHard != "" {
+			configured = fmt.Sprintf("%s/%s", orDash(l.ConfiguredSoft), orDash(l.ConfiguredHard))
+		}
+		rows = append(rows, []string{host, l.Soft, l.Hard, configured})
+	}
+	cliutil.PrintTable(rows, true)
+
+	stillLow := c.lowHosts(low)
+	if len(stillLow) == 0 {
+		for _, host := range low {
+			log.Infof("The max user processes of %s on %s is raised to %d", c.user, host, c.minimum)
+		}
+		return nil
+	}
+	var problems []string
+	for _, host := range stillLow {
+		l := c.limits[host]
+		problems = append(problems, fmt.Sprintf("%s has the soft/hard limits %s/%s", host, l.Soft, l.Hard))
+	}
+	msg := "The max user processes of %s are below %d:\n  - %s"
+	suggestion := fmt.Sprintf("Please set `%s soft nproc %d` and `%s hard nproc %d` in /etc/security/limits.conf on the hosts, or deploy with --fix-nproc to set them.", c.user, c.minimum, c.user, c.minimum)
+	if c.fix {
+		msg = "The max user processes of %s are still below %d after raising them:\n  - %s"
+		suggestion = "Please check whether the limits are lowered elsewhere, e.g. by ulimit in the profiles of the user or by the hard limits of the PAM limits."
+	}
+	return ErrNprocTooLow.
+		New(msg, c.user, c.minimum, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(suggestion))
+}
+
+// Limits returns the max user processes of the deploy user by host
+func (c *CheckNproc) Limits() map[string]*NprocLimit {
+	return c.limits
+}
+
+// lowHosts returns the hosts whose limits are below the minimum
+func (c *CheckNproc) lowHosts(hosts []string) []string {
+	var low []string
+	for _, host := range hosts {
+		if !c.limits[host].Sufficient(c.minimum) {
+			low = append(low, host)
+		}
+	}
+	return low
+}
+
+// readLimits reads the limits of the hosts concurrently
+func (c *CheckNproc) readLimits(ctx *Context, hosts []string) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			effective, _, err := e.Execute(fmt.Sprintf("su - %s -s /bin/sh -c 'ulimit -Su; ulimit -Hu'", c.user), true)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, errors.Annotatef(err, "failed to get the max user processes of %s on %s", c.user, host))
+				mu.Unlock()
+				return
+			}
+			pam, _, err := e.Execute(pamLimitsCmd, true)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to read the PAM limits of %s", host))
+				return
+			}
+			l := &NprocLimit{}
+			if fields := strings.Fields(string(effective)); len(fields) >= 2 {
+				l.Soft, l.Hard = fields[0], fields[1]
+			}
+			l.ConfiguredSoft, l.ConfiguredHard = parsePAMNproc(string(pam), c.user)
+			c.limits[host] = l
+		}(host)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// raise raises the limits of the user on the host to the minimum
+func (c *CheckNproc) raise(e executor.TiOpsExecutor, host string) error {
+	cmd := fmt.Sprintf("printf '%s soft nproc %d\\n%s hard nproc %d\\n' > %s", c.user, c.minimum, c.user, c.minimum, nprocLimitsFile)
+	if _, stderr, err := e.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to raise the max user processes on %s, stderr: %s", host, stderr)
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface
+func (c *CheckNproc) planFix(plan *Plan) {
+	if !c.fix {
+		return
+	}
+	for _, host := range c.hosts {
+		e := plan.conditionalExecutor(host, fmt.Sprintf("if the max user processes of %s are less than %d", c.user, c.minimum))
+		_ = c.raise(e, host)
+	}
+}
+
+// parsePAMNproc returns the soft/hard nproc limits of the user in the PAM limits, the
+// entries of the user take precedence over the default ones of `*`, and the later entries
+// take precedence over the earlier ones. The "-" type sets both of them.
+func parsePAMNproc(content, user string) (soft, hard string) {
+	var userSoft, userHard, defSoft, defHard string
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[2] != "nproc" {
+			continue
+		}
+		var s, h *string
+		switch fields[0] {
+		case user:
+			s, h = &userSoft, &userHard
+		case "*":
+			s, h = &defSoft, &defHard
+		default:
+			continue
+		}
+		switch fields[1] {
+		case "soft":
+			*s = fields[3]
+		case "hard":
+			*h = fields[3]
+		case "-":
+			*s, *h = fields[3], fields[3]
+		}
+	}
+	if userSoft == "" {
+		userSoft = defSoft
+	}
+	if userHard == "" {
+		userHard = defHard
+	}
+	return userSoft, userHard
+}
+
+// nprocAtLeast returns whether the limit is unlimited or not below the minimum
+func nprocAtLeast(limit string, minimum int) bool {
+	switch limit {
+	case "unlimited", "infinity", "-1":
+		return true
+	}
+	n, err := strconv.Atoi(limit)
+	return err == nil && n >= minimum
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Rollback implements the Task interface
+func (c *CheckNproc) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckNproc) String() string {
+	return fmt.Sprintf("CheckNproc: hosts=%s, user=%s, minimum=%d, fix=%v", strings.Join(c.hosts, ","), c.user, c.minimum, c.fix)
+}
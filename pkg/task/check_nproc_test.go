@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// centosLimits are the PAM limits of CentOS 7, which caps the max user processes by default
+const centosLimits = `# /etc/security/limits.conf
+tidb     soft   nofile   1000000
+tidb     hard   nofile   1000000
+# Default limit for number of user's processes to prevent
+# accidental fork bombs.
+*          soft    nproc     4096
+root       soft    nproc     unlimited
+`
+
+// nprocExecutor returns a mocked executor of a host with the effective limits and the PAM
+// limits, the effective limits are raised to the written ones by the fix
+func nprocExecutor(effective, pam string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case strings.HasPrefix(cmd, "su - tidb "):
+			return []byte(effective), nil, nil
+		case cmd == pamLimitsCmd:
+			return []byte(pam), nil, nil
+		case strings.HasPrefix(cmd, "printf "):
+			effective = "65536\n65536\n"
+			pam += "tidb soft nproc 65536\ntidb hard nproc 65536\n"
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckNproc(c *C) {
+	ctx := NewContext()
+	ctx.SetExecutor("172.16.5.140", nprocExecutor("4096\n127431\n", centosLimits))
+	ctx.SetExecutor("172.16.5.141", nprocExecutor("unlimited\nunlimited\n", ""))
+
+	t := &CheckNproc{hosts: []string{"172.16.5.140", "172.16.5.141"}, user: "tidb", minimum: MinNproc}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrNprocTooLow), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The max user processes of tidb are below 65536:\n  - 172.16.5.140 has the soft/hard limits 4096/127431.*")
+	c.Assert(t.Limits()["172.16.5.140"], DeepEquals, &NprocLimit{Soft: "4096", Hard: "127431", ConfiguredSoft: "4096"})
+	c.Assert(t.Limits()["172.16.5.141"], DeepEquals, &NprocLimit{Soft: "unlimited", Hard: "unlimited"})
+
+	// raised by the fix
+	t.fix = true
+	c.Assert(t.Execute(ctx), IsNil)
+	e, _ := ctx.GetExecutor("172.16.5.140")
+	c.Assert(e.(*mockExecutor).commands()[4], Equals, `printf 'tidb soft nproc 65536\ntidb hard nproc 65536\n' > /etc/security/limits.d/99-tiup-nproc.conf`)
+	c.Assert(t.Limits()["172.16.5.140"], DeepEquals, &NprocLimit{Soft: "65536", Hard: "65536", ConfiguredSoft: "65536", ConfiguredHard: "65536"})
+	e, _ = ctx.GetExecutor("172.16.5.141")
+	c.Assert(e.(*mockExecutor).commands(), HasLen, 4)
+}
+
+func (s *taskSuite) TestCheckNprocStillLow(c *C) {
+	// the fix doesn't take effect, e.g. ulimit in the profile of the user
+	e := nprocExecutor("16384\n16384\n", "")
+	e.handler = func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "su - tidb ") {
+			return []byte("16384\n16384\n"), nil, nil
+		}
+		return nil, nil, nil
+	}
+	t := &CheckNproc{hosts: []string{"172.16.5.140"}, user: "tidb", minimum: MinNproc, fix: true}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrNprocTooLow), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*are still below 65536 after raising them:\n  - 172.16.5.140 has the soft/hard limits 16384/16384.*")
+}
+
+func (s *taskSuite) TestParsePAMNproc(c *C) {
+	soft, hard := parsePAMNproc(centosLimits, "tidb")
+	c.Assert([]string{soft, hard}, DeepEquals, []string{"4096", ""})
+	soft, hard = parsePAMNproc(centosLimits, "root")
+	c.Assert([]string{soft, hard}, DeepEquals, []string{"unlimited", ""})
+
+	// the entries of the user take precedence, and "-" sets both
+	soft, hard = parsePAMNproc("tidb - nproc 131072 # for TiKV\n* hard nproc 4096\n", "tidb")
+	c.Assert([]string{soft, hard}, DeepEquals, []string{"131072", "131072"})
+
+	c.Assert(nprocAtLeast("65536", MinNproc), IsTrue)
+	c.Assert(nprocAtLeast("-1", MinNproc), IsTrue)
+	c.Assert(nprocAtLeast("4096", MinNproc), IsFalse)
+	c.Assert(nprocAtLeast("", MinNproc), IsFalse)
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSNUMA = errNS.NewSubNamespace("numa")
+	// ErrNUMANodeMissing means the NUMA nodes bound by the instances don't exist on the host
+	ErrNUMANodeMissing = errNSNUMA.NewType("node_missing", errutil.ErrTraitPreCheck)
+	// ErrNUMABindingMismatch means the run script of an instance doesn't bind the NUMA node configured
+	ErrNUMABindingMismatch = errNSNUMA.NewType("binding_mismatch")
+)
+
+// NUMANode returns the numa_node of the TiKV or TiFlash instance, empty if it's not bound
+func NUMANode(inst meta.Instance) string {
+	switch spec := inst.(type) {
+	case *meta.TiKVInstance:
+		return spec.InstanceSpec.(meta.TiKVSpec).NumaNode
+	case *meta.TiFlashInstance:
+		return spec.InstanceSpec.(meta.TiFlashSpec).NumaNode
+	}
+	return ""
+}
+
+// CheckNUMA is used to check the NUMA nodes bound by the instances on the host exist, the
+// instances fail to start by numactl otherwise
+type CheckNUMA struct {
+	inspection
+
+	host  string
+	nodes map[string]string // instance ID -> numa_node
+}
+
+// Execute implements the Task interface
+func (c *CheckNUMA) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	stdout, stderr, err := e.Execute("numactl --hardware", false)
+	if err != nil {
+		return ErrNUMANodeMissing.
+			Wrap(err, "Failed to get the NUMA nodes of %s by numactl: %s", c.host, strings.TrimSpace(string(stderr))).
+			WithProperty(cliutil.SuggestionFromString("Please install numactl on the host, or remove numa_node of the instances from the topology."))
+	}
+	available := parseNUMANodes(string(stdout))
+
+	var ids []string
+	for id := range c.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var problems []string
+	for _, id := range ids {
+		nodes, err := parseNUMASpec(c.nodes[id])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", id, err))
+			continue
+		}
+		for _, n := range nodes {
+			if !available[n] {
+				problems = append(problems, fmt.Sprintf("%s: NUMA node %d doesn't exist", id, n))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	var existing []string
+	for n := range available {
+		existing = append(existing, strconv.Itoa(n))
+	}
+	sort.Strings(existing)
+	return ErrNUMANodeMissing.
+		New("The NUMA nodes of the instances on %s are invalid:\n  - %s", c.host, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please set numa_node to the existing nodes of the host: %s", strings.Join(existing, ","))))
+}
+
+// Rollback implements the Task interface
+func (c *CheckNUMA) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckNUMA) String() string {
+	return fmt.Sprintf("CheckNUMA: host=%s, instances=%d", c.host, len(c.nodes))
+}
+
+// VerifyNUMABinding is used to verify the run script installed for the instance binds it
+// to the NUMA node configured by numactl, or doesn't bind it if numa_node is not set
+type VerifyNUMABinding struct {
+	inspection
+
+	instance  meta.Instance
+	deployDir string
+}
+
+// Execute implements the Task interface
+func (v *VerifyNUMABinding) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(v.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+
+	script := filepath.Join(v.deployDir, "scripts", fmt.Sprintf("run_%s.sh", v.instance.ComponentName()))
+	stdout, stderr, err := e.Execute(fmt.Sprintf("cat %s", script), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to read %s on %s, stderr: %s", script, v.instance.GetHost(), stderr)
+	}
+	return checkNUMABinding(string(stdout), NUMANode(v.instance), v.instance.ID())
+}
+
+// Rollback implements the Task interface
+func (v *VerifyNUMABinding) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *VerifyNUMABinding) String() string {
+	return fmt.Sprintf("VerifyNUMABinding: instance=%s, numa_node=%s", v.instance.ID(), NUMANode(v.instance))
+}
+
+// checkNUMABinding checks the run script binds the NUMA node, or nothing if node is empty
+func checkNUMABinding(script, node, id string) error {
+	bound := strings.Contains(script, "numactl ")
+	if node == "" {
+		if bound {
+			return ErrNUMABindingMismatch.New("The run script of %s is bound by numactl while numa_node is not set", id)
+		}
+		return nil
+	}
+	expected := fmt.Sprintf("exec numactl --cpunodebind=%s --membind=%s ", node, node)
+	if !strings.Contains(script, expected) {
+		return ErrNUMABindingMismatch.
+			New("The run script of %s doesn't bind NUMA node %s, `%s` is expected", id, node, strings.TrimSpace(expected)).
+			WithProperty(cliutil.SuggestionFromString("Please check templates/scripts of the run script, and reload the instance to install it again."))
+	}
+	return nil
+}
+
+// parseNUMANodes returns the nodes in the output of `numactl --hardware`, which has a line
+// like `node 0 cpus: 0 1 2 3` for each node
+func parseNUMANodes(output string) map[int]bool {
+	nodes := make(map[int]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "node" || fields[2] != "cpus:" {
+			continue
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			nodes[n] = true
+		}
+	}
+	return nodes
+}
+
+// parseNUMASpec parses the nodes of numa_node, e.g. `0`, `0,1` or `0-3`
+func parseNUMASpec(spec string) ([]int, error) {
+	var nodes []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		low, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, errors.Errorf("invalid numa_node %s", spec)
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(bounds[1]); err != nil || high < low {
+				return nil, errors.Errorf("invalid numa_node %s", spec)
+			}
+		}
+		for n := low; n <= high; n++ {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
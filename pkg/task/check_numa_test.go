@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/template/scripts"
+	. "github.com/pingcap/check"
+)
+
+const numactlHardware = `available: 2 nodes (0-1)
+node 0 cpus: 0 1 2 3 4 5 6 7 16 17 18 19 20 21 22 23
+node 0 size: 64327 MB
+node 0 free: 1817 MB
+node 1 cpus: 8 9 10 11 12 13 14 15 24 25 26 27 28 29 30 31
+node 1 size: 64507 MB
+node 1 free: 3791 MB
+node distances:
+node   0   1
+  0:  10  21
+  1:  21  10
+`
+
+// goldenTiKVScript is run_tikv.sh rendered for a TiKV instance bound to NUMA node 1
+const goldenTiKVScript = `#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+cd "/home/tidb/deploy/tikv-20160" || exit 1
+
+echo -n 'sync ... '
+stat=$(time sync || sync)
+echo ok
+echo $stat
+exec numactl --cpunodebind=1 --membind=1 bin/tikv-server \
+    --addr "0.0.0.0:20160" \
+    --advertise-addr "172.16.5.140:20160" \
+    --status-addr "172.16.5.140:20180" \
+    --pd "172.16.5.140:2379" \
+    --data-dir "/home/tidb/data/tikv-20160" \
+    --config conf/tikv.toml \
+    --log-file "/home/tidb/deploy/tikv-20160/log/tikv.log" 2>> "/home/tidb/deploy/tikv-20160/log/tikv_stderr.log"
+`
+
+func numaExecutor(script string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch cmd {
+		case "numactl --hardware":
+			return []byte(numactlHardware), nil, nil
+		case "cat /home/tidb/deploy/tikv-20160/scripts/run_tikv.sh":
+			return []byte(script), nil, nil
+		}
+		return nil, []byte("bash: numactl: command not found"), errors.New("exit status 127")
+	}}
+}
+
+func (s *taskSuite) TestCheckNUMA(c *C) {
+	ctx := newMockContext("172.16.5.140", numaExecutor(""))
+	t := &CheckNUMA{host: "172.16.5.140", nodes: map[string]string{
+		"172.16.5.140:20160": "0",
+		"172.16.5.140:20161": "1",
+		"172.16.5.140:3930":  "0,1",
+	}}
+	c.Assert(t.Execute(ctx), IsNil)
+
+	t = &CheckNUMA{host: "172.16.5.140", nodes: map[string]string{
+		"172.16.5.140:20160": "0",
+		"172.16.5.140:20161": "1-3",
+		"172.16.5.140:20162": "first",
+	}}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrNUMANodeMissing), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*The NUMA nodes of the instances on 172.16.5.140 are invalid:
+  - 172.16.5.140:20161: NUMA node 2 doesn't exist
+  - 172.16.5.140:20161: NUMA node 3 doesn't exist
+  - 172.16.5.140:20162: invalid numa_node first.*`)
+
+	// numactl is not installed
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, []byte("bash: numactl: command not found"), errors.New("exit status 127")
+	}}
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrNUMANodeMissing), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Failed to get the NUMA nodes of 172.16.5.140 by numactl: bash: numactl: command not found.*")
+}
+
+func (s *taskSuite) TestNUMABindingRendered(c *C) {
+	tpl, err := ioutil.ReadFile("../../templates/scripts/run_tikv.sh.tpl")
+	c.Assert(err, IsNil)
+	rendered, err := scripts.NewTiKVScript("172.16.5.140", "/home/tidb/deploy/tikv-20160", "/home/tidb/data/tikv-20160", "/home/tidb/deploy/tikv-20160/log").
+		WithNumaNode("1").
+		AppendEndpoints(scripts.NewPDScript("pd-1", "172.16.5.140", "/home/tidb/deploy/pd-2379", "/home/tidb/data/pd-2379", "/home/tidb/deploy/pd-2379/log")).
+		ConfigWithTemplate(string(tpl))
+	c.Assert(err, IsNil)
+	c.Assert(string(rendered), Equals, goldenTiKVScript)
+	c.Assert(checkNUMABinding(string(rendered), "1", "172.16.5.140:20160"), IsNil)
+
+	tpl, err = ioutil.ReadFile("../../templates/scripts/run_tiflash.sh.tpl")
+	c.Assert(err, IsNil)
+	rendered, err = scripts.NewTiFlashScript("172.16.5.140", "/home/tidb/deploy/tiflash-9000", "/home/tidb/data/tiflash-9000", "/home/tidb/deploy/tiflash-9000/log", "", "").
+		WithNumaNode("0").
+		ConfigWithTemplate(string(tpl))
+	c.Assert(err, IsNil)
+	c.Assert(string(rendered), Matches, "(?s).*\nexec numactl --cpunodebind=0 --membind=0  \\\\\n    bin/tiflash/tiflash server --config-file conf/tiflash.toml$")
+	c.Assert(checkNUMABinding(string(rendered), "0", "172.16.5.140:9000"), IsNil)
+	c.Assert(checkNUMABinding(string(rendered), "1", "172.16.5.140:9000"), NotNil)
+}
+
+func (s *taskSuite) TestVerifyNUMABinding(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+    numa_node: "1"
+`), topo), IsNil)
+	inst := (&meta.TiKVComponent{Specification: topo}).Instances()[0]
+	c.Assert(NUMANode(inst), Equals, "1")
+
+	t := &VerifyNUMABinding{instance: inst, deployDir: "/home/tidb/deploy/tikv-20160"}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", numaExecutor(goldenTiKVScript))), IsNil)
+
+	// rendered without the binding
+	unbound := `exec bin/tikv-server \
+    --addr "0.0.0.0:20160"
+`
+	err := t.Execute(newMockContext("172.16.5.140", numaExecutor(unbound)))
+	c.Assert(errorx.IsOfType(err, ErrNUMABindingMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The run script of 172.16.5.140:20160 doesn't bind NUMA node 1, `exec numactl --cpunodebind=1 --membind=1` is expected.*")
+
+	// bound while it's not expected
+	c.Assert(checkNUMABinding(goldenTiKVScript, "", "172.16.5.140:20160"), ErrorMatches, ".*is bound by numactl while numa_node is not set.*")
+	c.Assert(checkNUMABinding(unbound, "", "172.16.5.140:20160"), IsNil)
+}
+
+func (s *taskSuite) TestParseNUMA(c *C) {
+	c.Assert(parseNUMANodes(numactlHardware), DeepEquals, map[int]bool{0: true, 1: true})
+	nodes, err := parseNUMASpec("0-2,5")
+	c.Assert(err, IsNil)
+	c.Assert(nodes, DeepEquals, []int{0, 1, 2, 5})
+	_, err = parseNUMASpec("3-1")
+	c.Assert(err, ErrorMatches, "invalid numa_node 3-1")
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSCheckOS = errNS.NewSubNamespace("check_os")
+	// ErrOSIncompatible means the OS or the glibc of the host is too old for the components
+	ErrOSIncompatible = errNSCheckOS.NewType("incompatible", errutil.ErrTraitPreCheck)
+)
+
+// componentMinGlibc is the minimum glibc version required by the binaries of the
+// components, the components not listed are statically linked
+var componentMinGlibc = map[string]string{
+	meta.ComponentTiDB:    "2.17",
+	meta.ComponentTiKV:    "2.17",
+	meta.ComponentPD:      "2.17",
+	meta.ComponentTiFlash: "2.17",
+	meta.ComponentTiProxy: "2.17",
+	meta.ComponentPump:    "2.17",
+	meta.ComponentDrainer: "2.17",
+}
+
+// minOSVersions is the minimum versions of the known distributions indexed by the
+// `ID` of /etc/os-release, the other distributions are only checked for glibc
+var minOSVersions = map[string]string{
+	"centos": "7",
+	"rhel":   "7",
+	"ol":     "7",
+	"ubuntu": "16.04",
+	"debian": "9",
+}
+
+// CheckOS is used to check the distribution and the glibc version of the host against
+// the minimum requirements of the components to be deployed on it
+type CheckOS struct {
+	inspection
+
+	host       string
+	components []string
+
+	osID      string
+	osVersion string
+	glibc     string
+}
+
+// Execute implements the Task interface
+func (c *CheckOS) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	// There is no /etc/os-release on CentOS 6 and older
+	stdout, _, err := e.Execute("cat /etc/os-release 2>/dev/null || cat /etc/redhat-release", false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to read the OS release of %s", c.host)
+	}
+	c.osID, c.osVersion = parseOSRelease(string(stdout))
+
+	stdout, _, err = e.Execute("ldd --version", false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the glibc version of %s", c.host)
+	}
+
+	c.glibc = parseGlibcVersion(string(stdout))
+	if c.glibc == "" {
+		return errors.Errorf("unknown glibc version of %s: %s", c.host, stdout)
+	}
+
+	var problems []string
+	if min, ok := minOSVersions[c.osID]; ok && compareVersion(c.osVersion, min) < 0 {
+		problems = append(problems, fmt.Sprintf("%s %s is older than the minimum supported %s", c.osID, c.osVersion, min))
+	} else if !ok {
+		log.Warnf("The OS %s %s of %s is not verified, only glibc is checked", c.osID, c.osVersion, c.host)
+	}
+	for _, comp := range c.components {
+		min, ok := componentMinGlibc[comp]
+		if ok && compareVersion(c.glibc, min) < 0 {
+			problems = append(problems, fmt.Sprintf("%s requires glibc %s but the glibc is %s", comp, min, c.glibc))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return ErrOSIncompatible.
+		New("Host %s is incompatible with the components:\n  - %s", c.host, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please upgrade the OS of the host, or deploy the components to other hosts."))
+}
+
+// OS returns the distribution ID and version detected on the host
+func (c *CheckOS) OS() (string, string) {
+	return c.osID, c.osVersion
+}
+
+// Glibc returns the glibc version detected on the host
+func (c *CheckOS) Glibc() string {
+	return c.glibc
+}
+
+var redhatRelease = regexp.MustCompile(`^(CentOS|Red Hat Enterprise Linux).* release ([0-9][0-9.]*)`)
+
+// parseOSRelease parses the ID and VERSION_ID of /etc/os-release, or the content of
+// /etc/redhat-release like `CentOS release 6.10 (Final)`
+func parseOSRelease(output string) (id, version string) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if m := redhatRelease.FindStringSubmatch(line); m != nil {
+			if m[1] == "CentOS" {
+				return "centos", m[2]
+			}
+			return "rhel", m[2]
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Trim(kv[1], `"'`)
+		switch kv[0] {
+		case "ID":
+			id = value
+		case "VERSION_ID":
+			version = value
+		}
+	}
+	return id, version
+}
+
+// parseGlibcVersion parses the output of `ldd --version`, the first line of which is like:
+// ldd (GNU libc) 2.17
+// ldd (Ubuntu GLIBC 2.31-0ubuntu9) 2.31
+func parseGlibcVersion(output string) string {
+	lines := strings.SplitN(output, "\n", 2)
+	fields := strings.Fields(lines[0])
+	if len(fields) == 0 {
+		return ""
+	}
+	version := fields[len(fields)-1]
+	if _, err := strconv.Atoi(strings.Split(version, ".")[0]); err != nil {
+		return ""
+	}
+	return version
+}
+
+// compareVersion compares the numeric versions like `2.17` and `16.04`,
+// the missing or non-numeric parts are treated as 0
+func compareVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Rollback implements the Task interface
+func (c *CheckOS) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckOS) String() string {
+	return fmt.Sprintf("CheckOS: host=%s, components=%s", c.host, strings.Join(c.components, ","))
+}
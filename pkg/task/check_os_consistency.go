@@ -0,0 +1,144 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// CheckOSConsistency is used to detect the components whose hosts run different distributions
+// or major versions of them, e.g. a TiKV pool mixing Ubuntu and CentOS, which is usually not
+// intended and complicates troubleshooting. The mixed groups are only warned.
+type CheckOSConsistency struct {
+	inspection
+
+	groups map[string][]string // component -> hosts
+
+	distributions map[string]string // host -> distribution
+	mixed         []string
+}
+
+// Execute implements the Task interface
+func (c *CheckOSConsistency) Execute(ctx *Context) error {
+	c.distributions = make(map[string]string)
+	c.mixed = nil
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+		seen = make(map[string]struct{})
+	)
+	for _, hosts := range c.groups {
+		for _, host := range hosts {
+			if _, ok := seen[host]; ok {
+				continue
+			}
+			seen[host] = struct{}{}
+			e, found := ctx.GetExecutor(host)
+			if !found {
+				return ErrNoExecutor
+			}
+			wg.Add(1)
+			go func(host string) {
+				defer wg.Done()
+				// There is no /etc/os-release on CentOS 6 and older
+				stdout, _, err := e.Execute("cat /etc/os-release 2>/dev/null || cat /etc/redhat-release", false)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, errors.Annotatef(err, "failed to read the OS release of %s", host))
+					return
+				}
+				c.distributions[host] = distribution(parseOSRelease(string(stdout)))
+			}(host)
+		}
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	var comps []string
+	for comp := range c.groups {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+
+	rows := [][]string{{"Component", "Host", "Distribution"}}
+	for _, comp := range comps {
+		kinds := make(map[string]struct{})
+		for _, host := range c.groups[comp] {
+			kinds[c.distributions[host]] = struct{}{}
+		}
+		if len(kinds) < 2 {
+			continue
+		}
+		c.mixed = append(c.mixed, comp)
+		for _, host := range c.groups[comp] {
+			rows = append(rows, []string{comp, host, c.distributions[host]})
+		}
+	}
+	if len(c.mixed) == 0 {
+		return nil
+	}
+
+	log.Warnf("The hosts of the following components run different OS distributions:")
+	cliutil.PrintTable(rows, true)
+	log.Warnf("Please make sure it's intended, the hosts of a component are expected to be identical for troubleshooting")
+	return nil
+}
+
+// distribution returns the ID and the major version of a distribution like `centos 7`
+func distribution(id, version string) string {
+	if id == "" {
+		return "unknown"
+	}
+	if major := strings.Split(version, ".")[0]; major != "" {
+		return id + " " + major
+	}
+	return id
+}
+
+// Distributions returns the distribution and the major version of each host
+func (c *CheckOSConsistency) Distributions() map[string]string {
+	return c.distributions
+}
+
+// Mixed returns the components whose hosts run different distributions in order
+func (c *CheckOSConsistency) Mixed() []string {
+	return c.mixed
+}
+
+// Rollback implements the Task interface
+func (c *CheckOSConsistency) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckOSConsistency) String() string {
+	var comps []string
+	for comp := range c.groups {
+		comps = append(comps, comp)
+	}
+	sort.Strings(comps)
+	return fmt.Sprintf("CheckOSConsistency: components=%s", strings.Join(comps, ","))
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+const (
+	centos7Release = "ID=\"centos\"\nVERSION_ID=\"7\"\n"
+	centos8Release = "ID=\"centos\"\nVERSION_ID=\"8.2\"\n"
+	ubuntuRelease  = "ID=ubuntu\nVERSION_ID=\"20.04\"\n"
+)
+
+// releaseContext returns a context with the mocked executors of the hosts with the OS releases
+func releaseContext(releases map[string]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(releases), func(host string) *mockExecutor {
+		return osExecutor(releases[host], "")
+	})
+}
+
+func (s *taskSuite) TestCheckOSConsistency(c *C) {
+	ctx, executors := releaseContext(map[string]string{
+		"172.16.5.140": centos7Release,
+		"172.16.5.141": centos7Release,
+		"172.16.5.142": centos7Release,
+	})
+	t := &CheckOSConsistency{groups: map[string][]string{
+		meta.ComponentTiKV: {"172.16.5.140", "172.16.5.141"},
+		meta.ComponentPD:   {"172.16.5.140", "172.16.5.142"},
+	}}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Mixed(), HasLen, 0)
+	c.Assert(t.Distributions(), DeepEquals, map[string]string{
+		"172.16.5.140": "centos 7",
+		"172.16.5.141": "centos 7",
+		"172.16.5.142": "centos 7",
+	})
+	// the host shared by the components is read once
+	c.Assert(executors["172.16.5.140"].commands(), HasLen, 1)
+}
+
+func (s *taskSuite) TestCheckOSConsistencyMixed(c *C) {
+	ctx, _ := releaseContext(map[string]string{
+		"172.16.5.140": centos7Release,
+		"172.16.5.141": ubuntuRelease,
+		"172.16.5.142": centos8Release,
+		"172.16.5.143": centos7Release,
+		"172.16.5.144": "",
+	})
+	t := &CheckOSConsistency{groups: map[string][]string{
+		meta.ComponentTiKV: {"172.16.5.140", "172.16.5.141"},
+		meta.ComponentPD:   {"172.16.5.140", "172.16.5.142"},
+		meta.ComponentTiDB: {"172.16.5.140", "172.16.5.143"},
+		meta.ComponentPump: {"172.16.5.144"},
+	}}
+
+	// only warned
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Mixed(), DeepEquals, []string{meta.ComponentPD, meta.ComponentTiKV})
+	c.Assert(t.Distributions()["172.16.5.141"], Equals, "ubuntu 20")
+	c.Assert(t.Distributions()["172.16.5.142"], Equals, "centos 8")
+	c.Assert(t.Distributions()["172.16.5.144"], Equals, "unknown")
+}
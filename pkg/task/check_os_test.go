@@ -0,0 +1,89 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// osExecutor returns a mocked executor of a host with the OS release and the output of ldd
+func osExecutor(release, ldd string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "cat /etc/os-release") {
+			return []byte(release), nil, nil
+		}
+		if cmd == "ldd --version" {
+			return []byte(ldd), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckOSCompatible(c *C) {
+	e := osExecutor(`NAME="CentOS Linux"
+VERSION="7 (Core)"
+ID="centos"
+ID_LIKE="rhel fedora"
+VERSION_ID="7"
+`, "ldd (GNU libc) 2.17\nCopyright (C) 2012 Free Software Foundation, Inc.\n")
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckOS{host: "172.16.5.140", components: []string{meta.ComponentTiKV, meta.ComponentPrometheus}}
+	c.Assert(t.Execute(ctx), IsNil)
+	id, version := t.OS()
+	c.Assert(id, Equals, "centos")
+	c.Assert(version, Equals, "7")
+	c.Assert(t.Glibc(), Equals, "2.17")
+
+	e = osExecutor(`NAME="Ubuntu"
+VERSION="20.04 LTS (Focal Fossa)"
+ID=ubuntu
+VERSION_ID="20.04"
+`, "ldd (Ubuntu GLIBC 2.31-0ubuntu9) 2.31\n")
+	ctx = newMockContext("172.16.5.140", e)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Glibc(), Equals, "2.31")
+}
+
+func (s *taskSuite) TestCheckOSIncompatible(c *C) {
+	// CentOS 6 has no /etc/os-release
+	e := osExecutor("CentOS release 6.10 (Final)\n", "ldd (GNU libc) 2.12\n")
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &CheckOS{host: "172.16.5.140", components: []string{meta.ComponentTiKV, meta.ComponentGrafana}}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrOSIncompatible), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*centos 6.10 is older than the minimum supported 7.*tikv requires glibc 2.17 but the glibc is 2.12.*")
+	c.Assert(strings.Contains(err.Error(), "grafana"), IsFalse)
+
+	// an unknown distribution is checked only for glibc
+	e = osExecutor("ID=alpine\nVERSION_ID=3.12.0\n", "ldd (GNU libc) 2.16\n")
+	ctx = newMockContext("172.16.5.140", e)
+	t = &CheckOS{host: "172.16.5.140", components: []string{meta.ComponentPD}}
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrOSIncompatible), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*pd requires glibc 2.17 but the glibc is 2.16.*")
+}
+
+func (s *taskSuite) TestCompareVersion(c *C) {
+	c.Assert(compareVersion("2.17", "2.17"), Equals, 0)
+	c.Assert(compareVersion("2.9", "2.17"), Equals, -1)
+	c.Assert(compareVersion("18.04", "16.04"), Equals, 1)
+	c.Assert(compareVersion("7", "7.0"), Equals, 0)
+	c.Assert(compareVersion("6.10", "7"), Equals, -1)
+}
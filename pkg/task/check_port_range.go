@@ -0,0 +1,159 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSPortRange = errNS.NewSubNamespace("port_range")
+	// ErrPortInEphemeralRange means some ports of the cluster are in the ephemeral port range
+	// of the host, which may be taken by the outbound connections before the components bind
+	ErrPortInEphemeralRange = errNSPortRange.NewType("ephemeral", errutil.ErrTraitPreCheck)
+)
+
+// The files of the ephemeral port range and the ports excluded from it
+const (
+	localPortRangeFile    = "/proc/sys/net/ipv4/ip_local_port_range"
+	localReservedPortFile = "/proc/sys/net/ipv4/ip_local_reserved_ports"
+)
+
+// CheckPortRange is used to check whether the ports of the cluster on the host are out
+// of the ephemeral port range, which the kernel picks the local ports of the outbound
+// connections from. The ports reserved by ip_local_reserved_ports are never picked.
+type CheckPortRange struct {
+	inspection
+
+	host  string
+	ports map[int]string // port -> the instance using it
+
+	low, high int
+}
+
+// Execute implements the Task interface
+func (c *CheckPortRange) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	stdout, stderr, err := e.Execute(fmt.Sprintf("cat %s", localPortRangeFile), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the ephemeral port range of %s, stderr: %s", c.host, stderr)
+	}
+	// the file is absent on the old kernels
+	reserved, _, err := e.Execute(fmt.Sprintf("cat %s 2>/dev/null || true", localReservedPortFile), false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the reserved ports of %s", c.host)
+	}
+
+	c.low, c.high, err = parseLocalPortRange(string(stdout))
+	if err != nil {
+		return errors.Annotatef(err, "invalid ephemeral port range of %s", c.host)
+	}
+	reservedPorts, err := parseReservedPorts(string(reserved))
+	if err != nil {
+		return errors.Annotatef(err, "invalid reserved ports of %s", c.host)
+	}
+
+	var overlapped []int
+	for port := range c.ports {
+		if port >= c.low && port <= c.high && !reservedPorts[port] {
+			overlapped = append(overlapped, port)
+		}
+	}
+	if len(overlapped) == 0 {
+		return nil
+	}
+	sort.Ints(overlapped)
+
+	var (
+		problems []string
+		ports    []string
+	)
+	for _, port := range overlapped {
+		problems = append(problems, fmt.Sprintf("%d of %s", port, c.ports[port]))
+		ports = append(ports, strconv.Itoa(port))
+	}
+	return ErrPortInEphemeralRange.
+		New("The ports on %s are in the ephemeral port range %d-%d, they may be taken by the outbound connections:\n  - %s", c.host, c.low, c.high, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please use the ports out of the range, or reserve them by `sysctl -w net.ipv4.ip_local_reserved_ports=%s` and persist it to /etc/sysctl.conf.", strings.Join(ports, ","))))
+}
+
+// Range returns the ephemeral port range of the host
+func (c *CheckPortRange) Range() (int, int) {
+	return c.low, c.high
+}
+
+// parseLocalPortRange parses the content of ip_local_port_range, e.g. `32768	60999`
+func parseLocalPortRange(output string) (int, int, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, errors.Errorf("unexpected %q", strings.TrimSpace(output))
+	}
+	low, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, errors.AddStack(err)
+	}
+	high, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, errors.AddStack(err)
+	}
+	return low, high, nil
+}
+
+// parseReservedPorts parses the content of ip_local_reserved_ports, which is a comma
+// separated list of the ports and the port ranges, e.g. `2379-2380,20160`
+func parseReservedPorts(output string) (map[int]bool, error) {
+	ports := make(map[int]bool)
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ports, nil
+	}
+	for _, item := range strings.Split(output, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(item), "-", 2)
+		low, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, errors.AddStack(err)
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, errors.AddStack(err)
+			}
+		}
+		for port := low; port <= high; port++ {
+			ports[port] = true
+		}
+	}
+	return ports, nil
+}
+
+// Rollback implements the Task interface
+func (c *CheckPortRange) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckPortRange) String() string {
+	return fmt.Sprintf("CheckPortRange: host=%s, ports=%d", c.host, len(c.ports))
+}
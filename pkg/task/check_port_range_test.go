@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// portRangeExecutor returns a mocked executor with the ephemeral port range and the reserved ports
+func portRangeExecutor(portRange, reserved string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch cmd {
+		case "cat " + localPortRangeFile:
+			return []byte(portRange), nil, nil
+		case "cat " + localReservedPortFile + " 2>/dev/null || true":
+			return []byte(reserved), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckPortRange(c *C) {
+	ports := map[int]string{
+		9100:  "node_exporter",
+		20160: "172.16.5.140:20160",
+		20180: "172.16.5.140:20160",
+		32768: "172.16.5.140:32768",
+		40160: "172.16.5.140:40160",
+		60999: "172.16.5.140:60999",
+	}
+	t := &CheckPortRange{host: "172.16.5.140", ports: ports}
+
+	// the ports in the range, including the bounds
+	err := t.Execute(newMockContext("172.16.5.140", portRangeExecutor("32768\t60999\n", "\n")))
+	c.Assert(errorx.IsOfType(err, ErrPortInEphemeralRange), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The ports on 172.16.5.140 are in the ephemeral port range 32768-60999.*:\n"+
+		"  - 32768 of 172.16.5.140:32768\n  - 40160 of 172.16.5.140:40160\n  - 60999 of 172.16.5.140:60999.*")
+	low, high := t.Range()
+	c.Assert(low, Equals, 32768)
+	c.Assert(high, Equals, 60999)
+
+	// the reserved ports are never picked
+	err = t.Execute(newMockContext("172.16.5.140", portRangeExecutor("32768\t60999\n", "32768,40000-40200,60999\n")))
+	c.Assert(err, IsNil)
+
+	// a wide range covers the ports of TiKV too
+	err = t.Execute(newMockContext("172.16.5.140", portRangeExecutor("10000\t65535\n", "")))
+	c.Assert(err, ErrorMatches, "(?s).*  - 20160 of 172.16.5.140:20160\n  - 20180 of 172.16.5.140:20160\n  - 32768 of .*")
+
+	// all the ports are out of the range
+	err = t.Execute(newMockContext("172.16.5.140", portRangeExecutor("61000\t65535\n", "")))
+	c.Assert(err, IsNil)
+
+	err = t.Execute(newMockContext("172.16.5.140", portRangeExecutor("32768\n", "")))
+	c.Assert(err, ErrorMatches, `invalid ephemeral port range of 172.16.5.140: unexpected "32768"`)
+	_, err = parseReservedPorts("20160-x")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSReachability = errNS.NewSubNamespace("reachability")
+	// ErrHostUnreachable means some hosts of the cluster can't connect to the ports of the others
+	ErrHostUnreachable = errNSReachability.NewType("unreachable", errutil.ErrTraitPreCheck)
+)
+
+const (
+	// reachabilityConcurrency is the max number of hosts probing their peers at the same time
+	reachabilityConcurrency = 32
+	// reachabilityBatch is the max number of connections a host attempts at the same time
+	reachabilityBatch = 16
+	// reachabilityProbeTimeout is the timeout in seconds of connecting a port
+	reachabilityProbeTimeout = 2
+)
+
+// ReachabilityMatrix is the ports unreachable from the source host to the target host indexed
+// by source and target, a pair not probed is absent and an empty slice means all reachable
+type ReachabilityMatrix map[string]map[string][]int
+
+// CheckReachability is used to check whether every host can connect to the ports of its peers.
+// The connections are attempted from each host via its executor. Only the hosts within
+// maxPeers/2 positions in both directions of the ring of hosts are probed if there are more
+// than maxPeers peers, so every pair probed is checked in both directions.
+//
+// The services are not started yet before deploying, so a refused connection is considered
+// reachable as the host responds, only the dropped ones (timed out) are reported.
+//
+// At most concurrency hosts probe their peers at the same time, or the limit of the context,
+// or reachabilityConcurrency if neither is positive.
+type CheckReachability struct {
+	inspection
+
+	hosts       []string
+	ports       map[string][]int
+	maxPeers    int
+	concurrency int
+
+	matrix ReachabilityMatrix
+}
+
+// Execute implements the Task interface
+func (c *CheckReachability) Execute(ctx *Context) error {
+	c.matrix = make(ReachabilityMatrix)
+
+	concurrency := c.concurrency
+	if concurrency <= 0 {
+		concurrency = ctx.Concurrency()
+	}
+	if concurrency <= 0 {
+		concurrency = reachabilityConcurrency
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, concurrency)
+		errs []error
+	)
+	for i, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		peers := c.peers(i)
+		if len(peers) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string, e executor.TiOpsExecutor, peers []string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			script, batches := c.probeScript(peers)
+			timeout := time.Duration(batches*(reachabilityProbeTimeout+1))*time.Second + 10*time.Second
+			stdout, _, err := e.Execute(script, false, timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to probe the peers from %s", host))
+				return
+			}
+			c.matrix[host] = c.unreachable(peers, parseProbeResults(string(stdout)))
+		}(host, e, peers)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return c.report()
+}
+
+// peers returns the hosts to be probed by the i-th host
+func (c *CheckReachability) peers(i int) []string {
+	n := len(c.hosts)
+	if c.maxPeers <= 0 || n-1 <= c.maxPeers {
+		peers := make([]string, 0, n-1)
+		for j, host := range c.hosts {
+			if j != i {
+				peers = append(peers, host)
+			}
+		}
+		return peers
+	}
+
+	half := c.maxPeers / 2
+	if half == 0 {
+		half = 1
+	}
+	var peers []string
+	for d := 1; d <= half; d++ {
+		peers = append(peers, c.hosts[(i+d)%n], c.hosts[(i-d+n)%n])
+	}
+	return peers
+}
+
+// probeScript returns the shell script to connect the ports of peers, which prints a line
+// like `<host> <port> open|refused|timeout` for every port, and the number of batches
+func (c *CheckReachability) probeScript(peers []string) (string, int) {
+	var targets []string
+	for _, peer := range peers {
+		for _, port := range c.ports[peer] {
+			targets = append(targets, fmt.Sprintf("probe %s %d &", utils.UnwrapHost(peer), port))
+		}
+	}
+
+	lines := []string{fmt.Sprintf(
+		`probe() { timeout %d bash -c "</dev/tcp/$1/$2" 2>/dev/null; case $? in 0) echo "$1 $2 open";; 124) echo "$1 $2 timeout";; *) echo "$1 $2 refused";; esac; }`,
+		reachabilityProbeTimeout)}
+	batches := 0
+	for start := 0; start < len(targets); start += reachabilityBatch {
+		end := start + reachabilityBatch
+		if end > len(targets) {
+			end = len(targets)
+		}
+		lines = append(lines, strings.Join(targets[start:end], " ")+" wait")
+		batches++
+	}
+	return strings.Join(lines, "\n"), batches
+}
+
+// parseProbeResults parses the output of the probe script to a map from `<host> <port>` to the status
+func parseProbeResults(output string) map[string]string {
+	results := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		results[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return results
+}
+
+// unreachable returns the unreachable ports of each peer, a port without result is unreachable
+func (c *CheckReachability) unreachable(peers []string, results map[string]string) map[string][]int {
+	ports := make(map[string][]int)
+	for _, peer := range peers {
+		ports[peer] = []int{}
+		for _, port := range c.ports[peer] {
+			switch results[utils.UnwrapHost(peer)+" "+strconv.Itoa(port)] {
+			case "open", "refused":
+			default:
+				ports[peer] = append(ports[peer], port)
+			}
+		}
+	}
+	return ports
+}
+
+// report prints the unreachable pairs and returns an error if there is any of them
+func (c *CheckReachability) report() error {
+	rows := [][]string{{"Source", "Target", "Status", "Unreachable Ports"}}
+	var problems []string
+	for _, source := range c.hosts {
+		for _, target := range c.hosts {
+			ports := c.matrix[source][target]
+			if len(ports) == 0 {
+				continue
+			}
+			status := "unreachable"
+			if back, probed := c.matrix[target][source]; probed && len(back) == 0 {
+				status = "unreachable (asymmetric)"
+			}
+			rows = append(rows, []string{source, target, status, utils.JoinInt(ports, ",")})
+			problems = append(problems, fmt.Sprintf("%s -> %s: %s %s", source, target, status, utils.JoinInt(ports, ",")))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	log.Warnf("Connectivity issues between the hosts:")
+	cliutil.PrintTable(rows, true)
+	return ErrHostUnreachable.
+		New("%d pairs of hosts are not reachable:\n  - %s", len(problems), strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check the firewall and the network between the hosts, an asymmetric one is usually caused by a firewall rule or a route in one direction."))
+}
+
+// Matrix returns the result of the probes
+func (c *CheckReachability) Matrix() ReachabilityMatrix {
+	return c.matrix
+}
+
+// Rollback implements the Task interface
+func (c *CheckReachability) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckReachability) String() string {
+	hosts := append([]string(nil), c.hosts...)
+	sort.Strings(hosts)
+	return fmt.Sprintf("CheckReachability: hosts=%s, max_peers=%d", strings.Join(hosts, ","), c.maxPeers)
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+var probeRegexp = regexp.MustCompile(`probe (\S+) (\d+) &`)
+
+// meshExecutor returns a mocked executor of the source host, the connections to the ports
+// of the target are timed out if dropped returns true, and refused if listening returns false
+func meshExecutor(source string, dropped func(source, target string) bool, listening func(port string) bool) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		var out []string
+		for _, m := range probeRegexp.FindAllStringSubmatch(cmd, -1) {
+			status := "open"
+			if dropped(source, m[1]) {
+				status = "timeout"
+			} else if !listening(m[2]) {
+				status = "refused"
+			}
+			out = append(out, fmt.Sprintf("%s %s %s", m[1], m[2], status))
+		}
+		return []byte(strings.Join(out, "\n")), nil, nil
+	}}
+}
+
+func meshContext(hosts []string, dropped func(source, target string) bool) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hosts, func(host string) *mockExecutor {
+		return meshExecutor(host, dropped, func(port string) bool { return port != "20160" })
+	})
+}
+
+func (s *taskSuite) TestCheckReachabilityPartition(c *C) {
+	hosts := []string{"172.16.5.1", "172.16.5.2", "172.16.5.3", "172.16.5.4"}
+	ports := map[string][]int{}
+	for _, host := range hosts {
+		ports[host] = []int{2379, 20160}
+	}
+	// 172.16.5.1 can't reach 172.16.5.3 but the reverse is fine,
+	// 172.16.5.2 and 172.16.5.4 are partitioned from each other
+	dropped := func(source, target string) bool {
+		switch source + " " + target {
+		case "172.16.5.1 172.16.5.3", "172.16.5.2 172.16.5.4", "172.16.5.4 172.16.5.2":
+			return true
+		}
+		return false
+	}
+	ctx, _ := meshContext(hosts, dropped)
+
+	t := &CheckReachability{hosts: hosts, ports: ports}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrHostUnreachable), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*3 pairs of hosts are not reachable:
+  - 172.16.5.1 -> 172.16.5.3: unreachable \(asymmetric\) 2379,20160
+  - 172.16.5.2 -> 172.16.5.4: unreachable 2379,20160
+  - 172.16.5.4 -> 172.16.5.2: unreachable 2379,20160.*`)
+
+	matrix := t.Matrix()
+	c.Assert(matrix, HasLen, 4)
+	// the refused connections are reachable
+	c.Assert(matrix["172.16.5.1"]["172.16.5.2"], HasLen, 0)
+	c.Assert(matrix["172.16.5.3"]["172.16.5.1"], HasLen, 0)
+	c.Assert(matrix["172.16.5.1"]["172.16.5.3"], DeepEquals, []int{2379, 20160})
+	_, probed := matrix["172.16.5.1"]["172.16.5.1"]
+	c.Assert(probed, IsFalse)
+
+	// all reachable
+	ctx, _ = meshContext(hosts, func(source, target string) bool { return false })
+	c.Assert(t.Execute(ctx), IsNil)
+}
+
+func (s *taskSuite) TestCheckReachabilityMaxPeers(c *C) {
+	var hosts []string
+	ports := map[string][]int{}
+	for i := 0; i < 10; i++ {
+		host := fmt.Sprintf("172.16.5.%d", i)
+		hosts = append(hosts, host)
+		ports[host] = []int{2379}
+	}
+	ctx, executors := meshContext(hosts, func(source, target string) bool { return false })
+
+	t := &CheckReachability{hosts: hosts, ports: ports, maxPeers: 4}
+	c.Assert(t.Execute(ctx), IsNil)
+	for _, host := range hosts {
+		cmds := executors[host].commands()
+		c.Assert(cmds, HasLen, 1)
+		c.Assert(probeRegexp.FindAllString(cmds[0], -1), HasLen, 4)
+	}
+	// every pair probed is probed in both directions
+	matrix := t.Matrix()
+	c.Assert(matrix["172.16.5.0"], HasLen, 4)
+	for source, targets := range matrix {
+		for target := range targets {
+			_, probed := matrix[target][source]
+			c.Assert(probed, IsTrue, Commentf("%s -> %s", source, target))
+		}
+	}
+	_, probed := matrix["172.16.5.0"]["172.16.5.9"]
+	c.Assert(probed, IsTrue)
+	_, probed = matrix["172.16.5.0"]["172.16.5.5"]
+	c.Assert(probed, IsFalse)
+}
+
+func (s *taskSuite) TestProbeScriptBatches(c *C) {
+	t := &CheckReachability{ports: map[string][]int{"a": {1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, "b": {11, 12, 13, 14, 15, 16, 17, 18, 19, 20}}}
+	script, batches := t.probeScript([]string{"a", "b"})
+	c.Assert(batches, Equals, 2)
+	lines := strings.Split(script, "\n")
+	c.Assert(lines, HasLen, 3)
+	c.Assert(probeRegexp.FindAllString(lines[1], -1), HasLen, reachabilityBatch)
+	c.Assert(strings.HasSuffix(lines[2], "probe b 20 & wait"), IsTrue)
+}
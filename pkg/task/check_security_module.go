@@ -0,0 +1,169 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSSecurityModule = errNS.NewSubNamespace("security_module")
+	// ErrSELinuxEnforcing means SELinux is enforcing on some hosts, which may block the
+	// components from binding the ports and accessing the files
+	ErrSELinuxEnforcing = errNSSecurityModule.NewType("selinux_enforcing", errutil.ErrTraitPreCheck)
+)
+
+// The modes of the security modules
+const (
+	SecurityModuleNone       = "none"
+	SELinuxEnforcing         = "Enforcing"
+	SELinuxPermissive        = "Permissive"
+	AppArmorEnforcingProfile = "enforce"
+	AppArmorLoaded           = "loaded"
+)
+
+// selinuxPermissiveCmd sets SELinux to permissive for now and after reboot
+const selinuxPermissiveCmd = "setenforce 0 && sed -i 's/^SELINUX=enforcing/SELINUX=permissive/' /etc/selinux/config"
+
+var aaEnforceProfilesRegexp = regexp.MustCompile(`(?m)^\s*(\d+) profiles are in enforce mode`)
+
+// SecurityModes are the modes of the security modules of a host
+type SecurityModes struct {
+	SELinux  string
+	AppArmor string
+	// the number of the AppArmor profiles in enforce mode
+	AppArmorEnforced int
+}
+
+// CheckSecurityModule is used to check whether SELinux or AppArmor may block the components.
+// SELinux in enforcing mode is rejected unless it's allowed, or it's set to permissive if fix
+// is enabled. AppArmor only confines the programs with profiles, so it's just reported.
+type CheckSecurityModule struct {
+	inspection
+
+	hosts          []string
+	allowEnforcing bool
+	fix            bool
+
+	modes map[string]*SecurityModes
+}
+
+// Execute implements the Task interface
+func (c *CheckSecurityModule) Execute(ctx *Context) error {
+	c.modes = make(map[string]*SecurityModes)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			modes := &SecurityModes{SELinux: SecurityModuleNone, AppArmor: SecurityModuleNone}
+			// getenforce is absent if SELinux is not installed
+			if stdout, _, err := e.Execute("getenforce", false); err == nil && strings.TrimSpace(string(stdout)) != "" {
+				modes.SELinux = strings.TrimSpace(string(stdout))
+			}
+			// aa-status fails if AppArmor is not installed or not enabled
+			if stdout, _, err := e.Execute("aa-status", true); err == nil {
+				modes.AppArmor = AppArmorLoaded
+				if m := aaEnforceProfilesRegexp.FindStringSubmatch(string(stdout)); m != nil {
+					if n, _ := strconv.Atoi(m[1]); n > 0 {
+						modes.AppArmor = AppArmorEnforcingProfile
+						modes.AppArmorEnforced = n
+					}
+				}
+			}
+			mu.Lock()
+			c.modes[host] = modes
+			mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	rows := [][]string{{"Host", "SELinux", "AppArmor"}}
+	var enforcing []string
+	for _, host := range c.hosts {
+		modes := c.modes[host]
+		apparmor := modes.AppArmor
+		if modes.AppArmorEnforced > 0 {
+			apparmor = fmt.Sprintf("%s (%d profiles)", apparmor, modes.AppArmorEnforced)
+			log.Warnf("AppArmor on %s enforces %d profiles, please make sure none of them confines the components", host, modes.AppArmorEnforced)
+		}
+		rows = append(rows, []string{host, modes.SELinux, apparmor})
+		if modes.SELinux == SELinuxEnforcing {
+			enforcing = append(enforcing, host)
+		}
+	}
+	cliutil.PrintTable(rows, true)
+	if len(enforcing) == 0 || c.allowEnforcing {
+		return nil
+	}
+
+	if !c.fix {
+		return ErrSELinuxEnforcing.
+			New("SELinux is enforcing on %d hosts: %s, it may block the components from binding the ports or accessing the files", len(enforcing), strings.Join(enforcing, ", ")).
+			WithProperty(cliutil.SuggestionFromString("Please set SELinux to permissive by `setenforce 0` and SELINUX=permissive in /etc/selinux/config, or deploy with --fix-selinux to set it, or --allow-selinux-enforcing if the policies permit the components."))
+	}
+
+	for _, host := range enforcing {
+		e, _ := ctx.GetExecutor(host)
+		log.Infof("Setting SELinux of %s to permissive", host)
+		if _, stderr, err := e.Execute(selinuxPermissiveCmd, true); err != nil {
+			return errors.Annotatef(err, "failed to set SELinux of %s to permissive, stderr: %s", host, stderr)
+		}
+		c.modes[host].SELinux = SELinuxPermissive
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface
+func (c *CheckSecurityModule) planFix(plan *Plan) {
+	if !c.fix || c.allowEnforcing {
+		return
+	}
+	for _, host := range c.hosts {
+		e := plan.conditionalExecutor(host, "if SELinux is enforcing")
+		_, _, _ = e.Execute(selinuxPermissiveCmd, true)
+	}
+}
+
+// Modes returns the modes of the security modules of each host
+func (c *CheckSecurityModule) Modes() map[string]*SecurityModes {
+	return c.modes
+}
+
+// Rollback implements the Task interface
+func (c *CheckSecurityModule) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckSecurityModule) String() string {
+	return fmt.Sprintf("CheckSecurityModule: hosts=%s, allow_enforcing=%v, fix=%v", strings.Join(c.hosts, ","), c.allowEnforcing, c.fix)
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+const aaStatusOutput = `apparmor module is loaded.
+31 profiles are loaded.
+29 profiles are in enforce mode.
+   /usr/bin/man
+   /usr/sbin/tcpdump
+2 profiles are in complain mode.
+   /usr/sbin/sssd
+0 processes are in complain mode.
+`
+
+// securityContext returns a context of the hosts with the outputs of getenforce and
+// aa-status, an empty output means the command is absent
+func securityContext(outputs map[string][2]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(outputs), func(host string) *mockExecutor {
+		out := outputs[host]
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			var stdout string
+			switch cmd {
+			case "getenforce":
+				stdout = out[0]
+			case "aa-status":
+				stdout = out[1]
+			default:
+				return nil, nil, nil
+			}
+			if stdout == "" {
+				return nil, []byte("command not found"), errors.New("exit status 127")
+			}
+			return []byte(stdout), nil, nil
+		}}
+	})
+}
+
+func (s *taskSuite) TestCheckSecurityModule(c *C) {
+	hosts := []string{"172.16.5.140", "172.16.5.141", "172.16.5.142"}
+	ctx, executors := securityContext(map[string][2]string{
+		"172.16.5.140": {"Enforcing\n", ""},
+		"172.16.5.141": {"Permissive\n", ""},
+		"172.16.5.142": {"", aaStatusOutput},
+	})
+
+	t := &CheckSecurityModule{hosts: hosts}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrSELinuxEnforcing), IsTrue)
+	c.Assert(err.Error(), Matches, ".*SELinux is enforcing on 1 hosts: 172.16.5.140.*")
+	c.Assert(t.Modes()["172.16.5.140"], DeepEquals, &SecurityModes{SELinux: SELinuxEnforcing, AppArmor: SecurityModuleNone})
+	c.Assert(t.Modes()["172.16.5.141"], DeepEquals, &SecurityModes{SELinux: SELinuxPermissive, AppArmor: SecurityModuleNone})
+	c.Assert(t.Modes()["172.16.5.142"], DeepEquals, &SecurityModes{SELinux: SecurityModuleNone, AppArmor: AppArmorEnforcingProfile, AppArmorEnforced: 29})
+
+	// allowed by the deploy
+	t = &CheckSecurityModule{hosts: hosts, allowEnforcing: true}
+	c.Assert(t.Execute(ctx), IsNil)
+
+	// fix it
+	t = &CheckSecurityModule{hosts: hosts, fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	cmds := executors["172.16.5.140"].commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "setenforce 0 && sed -i 's/^SELINUX=enforcing/SELINUX=permissive/' /etc/selinux/config")
+	c.Assert(t.Modes()["172.16.5.140"].SELinux, Equals, SELinuxPermissive)
+	c.Assert(executors["172.16.5.141"].commands(), DeepEquals, []string{
+		"getenforce", "aa-status", "getenforce", "aa-status", "getenforce", "aa-status",
+	})
+}
+
+func (s *taskSuite) TestCheckSecurityModuleLoaded(c *C) {
+	ctx, _ := securityContext(map[string][2]string{
+		"172.16.5.140": {"Disabled\n", "apparmor module is loaded.\n0 profiles are loaded.\n0 profiles are in enforce mode.\n"},
+	})
+	t := &CheckSecurityModule{hosts: []string{"172.16.5.140"}}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Modes()["172.16.5.140"], DeepEquals, &SecurityModes{SELinux: "Disabled", AppArmor: AppArmorLoaded})
+}
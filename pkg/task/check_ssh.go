@@ -0,0 +1,111 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSSSH = errNS.NewSubNamespace("ssh")
+	// ErrSSHUnavailable means some hosts can't be logged in via SSH
+	ErrSSHUnavailable = errNSSSH.NewType("unavailable", errutil.ErrTraitPreCheck)
+)
+
+// sshProbeCmd is the trivial command run to check a session can be established
+const sshProbeCmd = "echo ok"
+
+// CheckSSH is used to check whether every host can be logged in via SSH, by running a
+// trivial command with the executors of the hosts. The executors are kept in the context,
+// so the subsequent tasks reuse the same connection settings.
+type CheckSSH struct {
+	inspection
+
+	hosts []string
+
+	results map[string]error
+}
+
+// Execute implements the Task interface
+func (c *CheckSSH) Execute(ctx *Context) error {
+	c.results = make(map[string]error)
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string, e executor.TiOpsExecutor) {
+			defer wg.Done()
+			stdout, _, err := e.Execute(sshProbeCmd, false)
+			if err == nil && strings.TrimSpace(string(stdout)) != "ok" {
+				err = errors.Errorf("unexpected output %q of `%s`", strings.TrimSpace(string(stdout)), sshProbeCmd)
+			}
+			mu.Lock()
+			c.results[host] = err
+			mu.Unlock()
+		}(host, e)
+	}
+	wg.Wait()
+
+	rows := [][]string{{"Host", "Status", "Message"}}
+	var failed []string
+	for _, host := range c.hosts {
+		err := c.results[host]
+		if err == nil {
+			rows = append(rows, []string{host, "OK", ""})
+			continue
+		}
+		msg := strings.SplitN(errors.Cause(err).Error(), "\n", 2)[0]
+		rows = append(rows, []string{host, "Fail", msg})
+		failed = append(failed, fmt.Sprintf("%s: %s", host, msg))
+	}
+	cliutil.PrintTable(rows, true)
+	log.Infof("%d of %d hosts are available via SSH", len(c.hosts)-len(failed), len(c.hosts))
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return ErrSSHUnavailable.
+		New("%d hosts can't be logged in via SSH:\n  - %s", len(failed), strings.Join(failed, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check the SSH port, the user and the key or password of the hosts, and increase --ssh-timeout if the network is slow."))
+}
+
+// Results returns the error of each host, which is nil if the host is available
+func (c *CheckSSH) Results() map[string]error {
+	return c.results
+}
+
+// Rollback implements the Task interface
+func (c *CheckSSH) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckSSH) String() string {
+	return fmt.Sprintf("CheckSSH: hosts=%s", strings.Join(c.hosts, ","))
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	. "github.com/pingcap/check"
+)
+
+// sshContext returns a context with the mocked executors of the hosts, the login to the hosts
+// in denied fails like the authentication is rejected by the SSH server
+func sshContext(hosts []string, denied map[string]bool) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hosts, func(host string) *mockExecutor {
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			if denied[host] {
+				return nil, nil, executor.ErrSSHExecuteFailed.
+					Wrap(errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"),
+						"Failed to execute command over SSH for 'tidb@%s:22'", host)
+			}
+			return []byte("ok\n"), nil, nil
+		}}
+	})
+}
+
+func (s *taskSuite) TestCheckSSH(c *C) {
+	hosts := []string{"172.16.5.140", "172.16.5.141", "172.16.5.142"}
+	ctx, executors := sshContext(hosts, nil)
+
+	t := &CheckSSH{hosts: hosts}
+	c.Assert(t.Execute(ctx), IsNil)
+	for _, host := range hosts {
+		c.Assert(t.Results()[host], IsNil)
+		c.Assert(executors[host].commands(), DeepEquals, []string{"echo ok"})
+	}
+
+	// the executors are required
+	t = &CheckSSH{hosts: []string{"172.16.5.143"}}
+	c.Assert(t.Execute(ctx), Equals, ErrNoExecutor)
+
+	// the check is skipped in the plan
+	plan := NewPlan()
+	ctx.SetPlan(plan)
+	t = &CheckSSH{hosts: hosts}
+	for _, host := range hosts {
+		ctx.SetExecutor(host, plan.Executor(host))
+	}
+	c.Assert(NewBuilder().Parallel(t).Build().Execute(ctx), IsNil)
+	c.Assert(plan.Steps(), HasLen, 0)
+	c.Assert(t.Results(), HasLen, 0)
+}
+
+func (s *taskSuite) TestCheckSSHDenied(c *C) {
+	hosts := []string{"172.16.5.140", "172.16.5.141", "172.16.5.142"}
+	ctx, _ := sshContext(hosts, map[string]bool{"172.16.5.141": true, "172.16.5.142": true})
+
+	t := &CheckSSH{hosts: hosts}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrSSHUnavailable), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*2 hosts can't be logged in via SSH:\n"+
+		"  - 172.16.5.141: ssh: handshake failed: ssh: unable to authenticate.*\n"+
+		"  - 172.16.5.142: ssh: handshake failed: ssh: unable to authenticate.*")
+	c.Assert(t.Results()["172.16.5.140"], IsNil)
+	c.Assert(t.Results()["172.16.5.141"], NotNil)
+
+	// an unexpected output is a failure as well
+	ctx = newMockContext("172.16.5.140", &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return []byte("Welcome!\n"), nil, nil
+	}})
+	t = &CheckSSH{hosts: hosts[:1]}
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrSSHUnavailable), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*172.16.5.140: unexpected output "Welcome!" of .echo ok.*`)
+}
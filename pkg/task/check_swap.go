@@ -0,0 +1,309 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSSwap = errNS.NewSubNamespace("swap")
+	// ErrSwapNotPersistent means the swap is still enabled after reboot on some hosts
+	// though it's disabled at runtime
+	ErrSwapNotPersistent = errNSSwap.NewType("not_persistent", errutil.ErrTraitPreCheck)
+)
+
+// The commands to read the swap state of a host
+const (
+	procSwapsCmd     = "cat /proc/swaps"
+	fstabCmd         = "cat /etc/fstab"
+	swapUnitFilesCmd = "systemctl list-unit-files --type=swap --no-legend 2>/dev/null || true"
+)
+
+// commentSwapAwk comments out the lines whose type field is swap
+const commentSwapAwk = `$0 !~ /^[[:space:]]*#/ && $3 == "swap" { $0 = "# " $0 } { print }`
+
+// commentFstabSwapCmd comments out the swap entries of /etc/fstab, the original one is
+// backed up to /etc/fstab.tiup.bak
+var commentFstabSwapCmd = commentSwapCmd("/etc/fstab")
+
+// commentSwapCmd returns the command to comment out the swap entries of the fstab file. The
+// awk script is passed in base64, as it's quoted again by sudo and can't have `$` or `"`.
+func commentSwapCmd(fstab string) string {
+	script := base64.StdEncoding.EncodeToString([]byte(commentSwapAwk))
+	return fmt.Sprintf("cp -p %[1]s %[1]s.tiup.bak && echo %[2]s | base64 -d | awk -f /dev/stdin %[1]s.tiup.bak > %[1]s", fstab, script)
+}
+
+// SwapState is the swap state of a host
+type SwapState struct {
+	// the active swap devices
+	Active []string
+	// the swap entries of /etc/fstab, which are activated on boot
+	FstabEntries []string
+	// the swap units not masked, which may be activated on boot too, e.g. the ones
+	// generated by systemd-gpt-auto-generator
+	Units []string
+}
+
+// Persistent returns whether the swap is kept off after reboot
+func (s *SwapState) Persistent() bool {
+	return len(s.FstabEntries) == 0 && len(s.Units) == 0
+}
+
+// CheckSwap is used to check whether the swap is disabled on the hosts, as swapping slows
+// down the components a lot. If fix is enabled, the swap is disabled at runtime, the swap
+// entries of /etc/fstab are commented out and the swap units are masked, then the state is
+// read again to verify the swap is kept off after reboot.
+type CheckSwap struct {
+	inspection
+
+	hosts []string
+	fix   bool
+
+	states map[string]*SwapState
+}
+
+// Execute implements the Task interface
+func (c *CheckSwap) Execute(ctx *Context) error {
+	c.states = make(map[string]*SwapState)
+	if err := c.readStates(ctx, c.hosts); err != nil {
+		return err
+	}
+
+	var enabled []string
+	for _, host := range c.hosts {
+		state := c.states[host]
+		if len(state.Active) > 0 || !state.Persistent() {
+			enabled = append(enabled, host)
+		}
+	}
+	if len(enabled) > 0 && c.fix {
+		for _, host := range enabled {
+			e, _ := ctx.GetExecutor(host)
+			log.Infof("Disabling the swap of %s", host)
+			if err := c.disable(e, host, c.states[host]); err != nil {
+				return err
+			}
+		}
+		// verify the swap is off by reading the state again rather than trusting the commands
+		if err := c.readStates(ctx, enabled); err != nil {
+			return err
+		}
+	}
+
+	rows := [][]string{{"Host", "Active Swap", "Persistent Off"}}
+	var (
+		active        []string
+		notPersistent []string
+	)
+	for _, host := range c.hosts {
+		state := c.states[host]
+		swap := "none"
+		if len(state.Active) > 0 {
+			swap = strings.Join(state.Active, ",")
+			active = append(active, host)
+		}
+		persistent := "yes"
+		if !state.Persistent() {
+			persistent = "no"
+			notPersistent = append(notPersistent, host)
+		}
+		rows = append(rows, []string{host, swap, persistent})
+	}
+	cliutil.PrintTable(rows, true)
+
+	if !c.fix {
+		if len(enabled) > 0 {
+			log.Warnf("The swap is enabled on %d hosts: %s, it's recommended to disable it or deploy with --fix-swap to disable it", len(enabled), strings.Join(enabled, ", "))
+		}
+		return nil
+	}
+	if len(active) > 0 || len(notPersistent) > 0 {
+		var problems []string
+		for _, host := range enabled {
+			state := c.states[host]
+			if len(state.Active) > 0 {
+				problems = append(problems, fmt.Sprintf("%s still has the active swap %s", host, strings.Join(state.Active, ", ")))
+			}
+			for _, entry := range state.FstabEntries {
+				problems = append(problems, fmt.Sprintf("%s still has the swap entry `%s` in /etc/fstab", host, entry))
+			}
+			for _, unit := range state.Units {
+				problems = append(problems, fmt.Sprintf("%s still has the swap unit %s not masked", host, unit))
+			}
+		}
+		return ErrSwapNotPersistent.
+			New("Failed to disable the swap on the hosts persistently:\n  - %s", strings.Join(problems, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString("Please remove the swap entries from /etc/fstab, mask the swap units by `systemctl mask` and run `swapoff -a` on the hosts."))
+	}
+	for _, host := range enabled {
+		log.Infof("The swap of %s is disabled persistently", host)
+	}
+	return nil
+}
+
+// readStates reads the swap states of the hosts concurrently
+func (c *CheckSwap) readStates(ctx *Context, hosts []string) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			state, err := readSwapState(e)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to get the swap state of %s", host))
+				return
+			}
+			c.states[host] = state
+		}(host)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// disable disables the swap of the host at runtime and after reboot
+func (c *CheckSwap) disable(e executor.TiOpsExecutor, host string, state *SwapState) error {
+	var cmds []string
+	if len(state.FstabEntries) > 0 {
+		cmds = append(cmds, commentFstabSwapCmd)
+	}
+	if len(state.Units) > 0 {
+		cmds = append(cmds, "systemctl mask "+strings.Join(state.Units, " "))
+	}
+	// the units generated from /etc/fstab are gone after reloading
+	cmds = append(cmds, "systemctl daemon-reload", "swapoff -a")
+	for _, cmd := range cmds {
+		if _, stderr, err := e.Execute(cmd, true); err != nil {
+			return errors.Annotatef(err, "failed to disable the swap of %s, stderr: %s", host, stderr)
+		}
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface, the swap entries and units are supposed to
+// be present as they are unknown without reading
+func (c *CheckSwap) planFix(plan *Plan) {
+	if !c.fix {
+		return
+	}
+	state := &SwapState{
+		FstabEntries: []string{"<the swap entries>"},
+		Units:        []string{"<the swap units not masked>"},
+	}
+	for _, host := range c.hosts {
+		_ = c.disable(plan.conditionalExecutor(host, "if the swap is enabled"), host, state)
+	}
+}
+
+// readSwapState reads the swap state by the executor
+func readSwapState(e executor.TiOpsExecutor) (*SwapState, error) {
+	swaps, _, err := e.Execute(procSwapsCmd, false)
+	if err != nil {
+		return nil, err
+	}
+	fstab, _, err := e.Execute(fstabCmd, false)
+	if err != nil {
+		return nil, err
+	}
+	units, _, err := e.Execute(swapUnitFilesCmd, false)
+	if err != nil {
+		return nil, err
+	}
+	return &SwapState{
+		Active:       parseProcSwaps(string(swaps)),
+		FstabEntries: parseFstabSwap(string(fstab)),
+		Units:        parseSwapUnits(string(units)),
+	}, nil
+}
+
+// parseProcSwaps parses the devices of /proc/swaps, whose first line is the header
+func parseProcSwaps(output string) []string {
+	var devices []string
+	for i, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) == 0 {
+			continue
+		}
+		devices = append(devices, fields[0])
+	}
+	return devices
+}
+
+// parseFstabSwap parses the swap entries of /etc/fstab, whose type field is swap
+func parseFstabSwap(output string) []string {
+	var entries []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[2] == "swap" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+// parseSwapUnits parses the swap units not masked from `systemctl list-unit-files`,
+// e.g. `dev-sda2.swap generated`
+func parseSwapUnits(output string) []string {
+	var units []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasSuffix(fields[0], ".swap") {
+			continue
+		}
+		if fields[1] != "masked" {
+			units = append(units, fields[0])
+		}
+	}
+	return units
+}
+
+// States returns the swap state of each host
+func (c *CheckSwap) States() map[string]*SwapState {
+	return c.states
+}
+
+// Rollback implements the Task interface
+func (c *CheckSwap) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckSwap) String() string {
+	return fmt.Sprintf("CheckSwap: hosts=%s, fix=%v", strings.Join(c.hosts, ","), c.fix)
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+const fstabWithSwap = `# /etc/fstab
+UUID=0a3407de-014b-458b-b5c1-848e92a327a3 /     xfs  defaults 0 0
+# /dev/sdb1 none swap sw 0 0
+/dev/mapper/centos-swap swap swap defaults 0 0
+`
+
+// swapHost is a mocked host whose swap state is changed by the commands disabling it,
+// or kept if the host is stubborn, e.g. /etc/fstab is read only
+type swapHost struct {
+	mu       sync.Mutex
+	swaps    []string
+	fstab    string
+	units    map[string]string
+	stubborn bool
+}
+
+func (h *swapHost) executor() *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		switch {
+		case cmd == procSwapsCmd:
+			out := "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n"
+			for _, s := range h.swaps {
+				out += s + "\tpartition\t8388604\t0\t-2\n"
+			}
+			return []byte(out), nil, nil
+		case cmd == fstabCmd:
+			return []byte(h.fstab), nil, nil
+		case cmd == swapUnitFilesCmd:
+			var lines []string
+			for unit, state := range h.units {
+				lines = append(lines, unit+" "+state+"\n")
+			}
+			sort.Strings(lines)
+			return []byte(strings.Join(lines, "")), nil, nil
+		case h.stubborn:
+		case cmd == commentFstabSwapCmd:
+			h.fstab = strings.Replace(h.fstab, "/dev/mapper/centos-swap", "# /dev/mapper/centos-swap", 1)
+			delete(h.units, "dev-mapper-centos\\x2dswap.swap")
+		case strings.HasPrefix(cmd, "systemctl mask "):
+			for _, unit := range strings.Fields(strings.TrimPrefix(cmd, "systemctl mask ")) {
+				h.units[unit] = "masked"
+			}
+		case cmd == "swapoff -a":
+			h.swaps = nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckSwap(c *C) {
+	enabled := &swapHost{
+		swaps: []string{"/dev/dm-1"},
+		fstab: fstabWithSwap,
+		units: map[string]string{
+			"dev-mapper-centos\\x2dswap.swap": "generated",
+			"dev-sda3.swap":                   "generated",
+			"dev-sda4.swap":                   "masked",
+		},
+	}
+	disabled := &swapHost{fstab: "UUID=0a3407de /data ext4 defaults 0 0\n", units: map[string]string{}}
+	ctx := NewContext()
+	ctx.SetExecutor("172.16.5.140", enabled.executor())
+	ctx.SetExecutor("172.16.5.141", disabled.executor())
+	hosts := []string{"172.16.5.140", "172.16.5.141"}
+
+	// just warned without fix
+	t := &CheckSwap{hosts: hosts}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.States()["172.16.5.140"], DeepEquals, &SwapState{
+		Active:       []string{"/dev/dm-1"},
+		FstabEntries: []string{"/dev/mapper/centos-swap swap swap defaults 0 0"},
+		Units:        []string{"dev-mapper-centos\\x2dswap.swap", "dev-sda3.swap"},
+	})
+	c.Assert(t.States()["172.16.5.141"].Persistent(), IsTrue)
+	c.Assert(enabled.fstab, Equals, fstabWithSwap)
+
+	// disabled and verified by reading again
+	t = &CheckSwap{hosts: hosts, fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	state := t.States()["172.16.5.140"]
+	c.Assert(state.Active, HasLen, 0)
+	c.Assert(state.Persistent(), IsTrue)
+	c.Assert(enabled.fstab, Equals, `# /etc/fstab
+UUID=0a3407de-014b-458b-b5c1-848e92a327a3 /     xfs  defaults 0 0
+# /dev/sdb1 none swap sw 0 0
+# /dev/mapper/centos-swap swap swap defaults 0 0
+`)
+	c.Assert(enabled.units["dev-sda3.swap"], Equals, "masked")
+}
+
+func (s *taskSuite) TestCheckSwapNotPersistent(c *C) {
+	host := &swapHost{
+		swaps:    []string{"/dev/sdb1"},
+		fstab:    "/dev/sdb1 none swap sw 0 0\n",
+		units:    map[string]string{},
+		stubborn: true,
+	}
+	e := host.executor()
+	t := &CheckSwap{hosts: []string{"172.16.5.140"}, fix: true}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrSwapNotPersistent), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*172.16.5.140 still has the active swap /dev/sdb1\n  - 172.16.5.140 still has the swap entry `/dev/sdb1 none swap sw 0 0` in /etc/fstab.*")
+	c.Assert(e.commands()[3:], DeepEquals, []string{
+		commentFstabSwapCmd, "systemctl daemon-reload", "swapoff -a", procSwapsCmd, fstabCmd, swapUnitFilesCmd,
+	})
+
+	c.Assert(parseProcSwaps("Filename Type Size Used Priority\n"), HasLen, 0)
+	c.Assert(parseSwapUnits("dev-sdb1.swap masked\nswap.target static\n"), HasLen, 0)
+}
+
+func (s *taskSuite) TestCommentSwapCmd(c *C) {
+	fstab := filepath.Join(c.MkDir(), "fstab")
+	c.Assert(ioutil.WriteFile(fstab, []byte(fstabWithSwap), 0644), IsNil)
+
+	out, err := runSudo(c, commentSwapCmd(fstab))
+	c.Assert(err, IsNil, Commentf("output: %s", out))
+	data, err := ioutil.ReadFile(fstab)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `# /etc/fstab
+UUID=0a3407de-014b-458b-b5c1-848e92a327a3 /     xfs  defaults 0 0
+# /dev/sdb1 none swap sw 0 0
+# /dev/mapper/centos-swap swap swap defaults 0 0
+`)
+	// backed up
+	data, err = ioutil.ReadFile(fstab + ".tiup.bak")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, fstabWithSwap)
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSSymlink = errNS.NewSubNamespace("symlink")
+	// ErrSymlinkTargetUnexpected means some directories to deploy onto are redirected by
+	// symlinks to the targets not allowed
+	ErrSymlinkTargetUnexpected = errNSSymlink.NewType("unexpected_target", errutil.ErrTraitPreCheck)
+)
+
+// systemDirs are the directories of the root filesystem never expected to hold the files
+// of the instances, a symlink pointing to them or inside them is always flagged
+var systemDirs = []string{"/", "/bin", "/boot", "/dev", "/etc", "/lib", "/lib64", "/proc", "/run", "/sbin", "/sys", "/usr"}
+
+// SymlinkedDir is a directory resolved to another path by a symlink
+type SymlinkedDir struct {
+	Dir     string
+	Target  string
+	Self    bool // the directory itself is the symlink rather than one of its parents
+	Allowed bool
+}
+
+// CheckSymlink is used to check whether the deploy, data and log directories on the host are
+// redirected by symlinks, either the directory itself or one of its parents. The symlinked
+// directories are reported with their targets, and the targets must be under one of the
+// allowed directories if any is given. The targets in the system directories are never allowed.
+type CheckSymlink struct {
+	inspection
+
+	host    string
+	dirs    []string
+	allowed []string
+
+	symlinks []SymlinkedDir
+}
+
+// Execute implements the Task interface
+func (c *CheckSymlink) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	c.symlinks = nil
+	for _, dir := range c.dirs {
+		// the parents are resolved even if the directory doesn't exist yet
+		stdout, stderr, err := e.Execute(fmt.Sprintf("readlink -m %s", dir), true)
+		if err != nil {
+			return errors.Annotatef(err, "failed to resolve the directory %s on %s, stderr: %s", dir, c.host, stderr)
+		}
+		target := strings.TrimSpace(string(stdout))
+		if target == "" || target == filepath.Clean(dir) {
+			continue
+		}
+		stdout, _, err = e.Execute(fmt.Sprintf("stat -c %%F %s", dir), true)
+		self := err == nil && strings.TrimSpace(string(stdout)) == "symbolic link"
+		c.symlinks = append(c.symlinks, SymlinkedDir{
+			Dir:     dir,
+			Target:  target,
+			Self:    self,
+			Allowed: symlinkTargetAllowed(target, c.allowed),
+		})
+	}
+	if len(c.symlinks) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Directory", "Target", "Symlink", "Allowed"}}
+	var problems []string
+	for _, s := range c.symlinks {
+		by := "parent"
+		if s.Self {
+			by = "itself"
+		}
+		rows = append(rows, []string{s.Dir, s.Target, by, fmt.Sprintf("%v", s.Allowed)})
+		if !s.Allowed {
+			problems = append(problems, fmt.Sprintf("%s -> %s", s.Dir, s.Target))
+		}
+	}
+	cliutil.PrintTable(rows, true)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	expected := "outside the system directories"
+	if len(c.allowed) > 0 {
+		expected = "under " + strings.Join(c.allowed, ", ")
+	}
+	return ErrSymlinkTargetUnexpected.
+		New("The directories on %s are symlinks to the targets not %s:\n  - %s", c.host, expected, strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check the symlinks point to the intended disks, or deploy with --allowed-symlink-target to allow the targets."))
+}
+
+// symlinkTargetAllowed returns if the resolved target is allowed, it must be out of the
+// system directories and under one of the allowed ones if any is given
+func symlinkTargetAllowed(target string, allowed []string) bool {
+	for _, dir := range systemDirs {
+		if target == dir || (dir != "/" && strings.HasPrefix(target, dir+"/")) {
+			return false
+		}
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, dir := range allowed {
+		dir = filepath.Clean(dir)
+		if target == dir || strings.HasPrefix(target, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Symlinks returns the symlinked directories and their targets
+func (c *CheckSymlink) Symlinks() []SymlinkedDir {
+	return c.symlinks
+}
+
+// Rollback implements the Task interface
+func (c *CheckSymlink) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckSymlink) String() string {
+	return fmt.Sprintf("CheckSymlink: host=%s, dirs=%s, allowed=%s", c.host, strings.Join(c.dirs, ","), strings.Join(c.allowed, ","))
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// symlinkExecutor mocks a host where the symlinks are resolved to the targets, the
+// directories not in targets are real or absent
+func symlinkExecutor(targets map[string]string, links map[string]bool) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case strings.HasPrefix(cmd, "readlink -m "):
+			dir := strings.TrimPrefix(cmd, "readlink -m ")
+			if target, ok := targets[dir]; ok {
+				return []byte(target + "\n"), nil, nil
+			}
+			return []byte(dir + "\n"), nil, nil
+		case strings.HasPrefix(cmd, "stat -c %F "):
+			dir := strings.TrimPrefix(cmd, "stat -c %F ")
+			if links[dir] {
+				return []byte("symbolic link\n"), nil, nil
+			}
+			if _, ok := targets[dir]; ok {
+				return []byte("directory\n"), nil, nil
+			}
+			return nil, []byte("stat: cannot stat '" + dir + "': No such file or directory"), errors.New("exit status 1")
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckSymlink(c *C) {
+	dirs := []string{"/home/tidb/deploy/tikv-20160", "/data1/tikv-20160", "/data2/tikv-20160", "/data3/tikv-20160"}
+	e := symlinkExecutor(map[string]string{
+		// the directory itself is a symlink
+		"/data1/tikv-20160": "/mnt/ssd1/tikv-20160",
+		// the parent /data2 is a symlink, the directory is not created yet
+		"/data2/tikv-20160": "/mnt/ssd2/tikv-20160",
+		// pointing to the root filesystem by mistake
+		"/data3/tikv-20160": "/usr/local/tikv-20160",
+	}, map[string]bool{"/data1/tikv-20160": true})
+
+	t := &CheckSymlink{host: "172.16.5.140", dirs: dirs}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrSymlinkTargetUnexpected), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The directories on 172.16.5.140 are symlinks to the targets not outside the system directories:\n  - /data3/tikv-20160 -> /usr/local/tikv-20160.*")
+	c.Assert(t.Symlinks(), DeepEquals, []SymlinkedDir{
+		{Dir: "/data1/tikv-20160", Target: "/mnt/ssd1/tikv-20160", Self: true, Allowed: true},
+		{Dir: "/data2/tikv-20160", Target: "/mnt/ssd2/tikv-20160", Allowed: true},
+		{Dir: "/data3/tikv-20160", Target: "/usr/local/tikv-20160"},
+	})
+
+	// only the allowed targets
+	t = &CheckSymlink{host: "172.16.5.140", dirs: dirs[:3], allowed: []string{"/mnt/ssd1/"}}
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrSymlinkTargetUnexpected), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*are symlinks to the targets not under /mnt/ssd1/:\n  - /data2/tikv-20160 -> /mnt/ssd2/tikv-20160.*")
+
+	t = &CheckSymlink{host: "172.16.5.140", dirs: dirs[:3], allowed: []string{"/mnt/ssd1", "/mnt/ssd2"}}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+
+	// no symlink
+	t = &CheckSymlink{host: "172.16.5.140", dirs: dirs[:1]}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Symlinks(), HasLen, 0)
+	c.Assert(e.commands()[len(e.commands())-1], Equals, "readlink -m /home/tidb/deploy/tikv-20160")
+}
+
+func (s *taskSuite) TestSymlinkTargetAllowed(c *C) {
+	c.Assert(symlinkTargetAllowed("/", nil), IsFalse)
+	c.Assert(symlinkTargetAllowed("/etc/tikv", nil), IsFalse)
+	c.Assert(symlinkTargetAllowed("/usrdata/tikv", nil), IsTrue)
+	c.Assert(symlinkTargetAllowed("/data1", []string{"/data1"}), IsTrue)
+	c.Assert(symlinkTargetAllowed("/data10/tikv", []string{"/data1"}), IsFalse)
+	c.Assert(symlinkTargetAllowed("/data10/tikv", []string{"/"}), IsTrue)
+	c.Assert(symlinkTargetAllowed("/usr/tikv", []string{"/"}), IsFalse)
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSTimeSync = errNS.NewSubNamespace("time_sync")
+	// ErrTimeSyncInactive means none of the time sync services is running on the host
+	ErrTimeSyncInactive = errNSTimeSync.NewType("inactive", errutil.ErrTraitPreCheck)
+)
+
+// timeSyncServices are the units of chrony and ntp on the supported distributions
+var timeSyncServices = []string{"chronyd", "chrony", "ntpd", "ntp"}
+
+// CheckTimeSync is used to check whether a time sync service, i.e. chrony or ntp, is active
+// on the host rather than just installed
+type CheckTimeSync struct {
+	inspection
+
+	host string
+
+	states map[string]string
+}
+
+// Execute implements the Task interface
+func (c *CheckTimeSync) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	// it exits non-zero if any of the units is not active
+	cmd := fmt.Sprintf("systemctl is-active %s || true", strings.Join(timeSyncServices, " "))
+	stdout, _, err := e.Execute(cmd, false)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the state of the time sync services of %s", c.host)
+	}
+
+	// a line is printed for each unit in order
+	c.states = make(map[string]string)
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	for i, service := range timeSyncServices {
+		state := "unknown"
+		if i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			state = strings.TrimSpace(lines[i])
+		}
+		c.states[service] = state
+		if state == "active" {
+			return nil
+		}
+	}
+
+	var failed []string
+	for _, service := range timeSyncServices {
+		if c.states[service] == "failed" {
+			failed = append(failed, service)
+		}
+	}
+	if len(failed) > 0 {
+		return ErrTimeSyncInactive.
+			New("The time sync service %s failed on host %s", strings.Join(failed, ", "), c.host).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please check the service with `journalctl -u %s` and start it with `systemctl restart %s`.", failed[0], failed[0])))
+	}
+	return ErrTimeSyncInactive.
+		New("None of the time sync services %s is active on host %s", strings.Join(timeSyncServices, ", "), c.host).
+		WithProperty(cliutil.SuggestionFromString("The clocks of the hosts must be synchronized for the TSO of PD, please install chrony or ntp and start it, e.g: systemctl enable --now chronyd"))
+}
+
+// States returns the state of each time sync service on the host
+func (c *CheckTimeSync) States() map[string]string {
+	return c.states
+}
+
+// Rollback implements the Task interface
+func (c *CheckTimeSync) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckTimeSync) String() string {
+	return fmt.Sprintf("CheckTimeSync: host=%s", c.host)
+}
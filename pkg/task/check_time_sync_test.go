@@ -0,0 +1,59 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func timeSyncExecutor(output string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if cmd == "systemctl is-active chronyd chrony ntpd ntp || true" {
+			return []byte(output), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func (s *taskSuite) TestCheckTimeSync(c *C) {
+	t := &CheckTimeSync{host: "172.16.5.140"}
+
+	// chronyd is active
+	c.Assert(t.Execute(newMockContext("172.16.5.140", timeSyncExecutor("active\ninactive\ninactive\ninactive\n"))), IsNil)
+	c.Assert(t.States(), DeepEquals, map[string]string{"chronyd": "active"})
+
+	// ntpd is active
+	c.Assert(t.Execute(newMockContext("172.16.5.140", timeSyncExecutor("inactive\ninactive\nactive\ninactive\n"))), IsNil)
+	c.Assert(t.States()["ntpd"], Equals, "active")
+}
+
+func (s *taskSuite) TestCheckTimeSyncInactive(c *C) {
+	t := &CheckTimeSync{host: "172.16.5.140"}
+
+	err := t.Execute(newMockContext("172.16.5.140", timeSyncExecutor("inactive\ninactive\ninactive\ninactive\n")))
+	c.Assert(errorx.IsOfType(err, ErrTimeSyncInactive), IsTrue)
+	c.Assert(err.Error(), Matches, ".*None of the time sync services chronyd, chrony, ntpd, ntp is active on host 172.16.5.140.*")
+
+	// the failed one is reported
+	err = t.Execute(newMockContext("172.16.5.140", timeSyncExecutor("failed\ninactive\ninactive\ninactive\n")))
+	c.Assert(errorx.IsOfType(err, ErrTimeSyncInactive), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The time sync service chronyd failed on host 172.16.5.140.*")
+	c.Assert(t.States(), DeepEquals, map[string]string{"chronyd": "failed", "chrony": "inactive", "ntpd": "inactive", "ntp": "inactive"})
+
+	// the old systemd prints unknown for the absent units
+	err = t.Execute(newMockContext("172.16.5.140", timeSyncExecutor("unknown\nunknown\n")))
+	c.Assert(errorx.IsOfType(err, ErrTimeSyncInactive), IsTrue)
+	c.Assert(t.States()["ntp"], Equals, "unknown")
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSTimezone = errNS.NewSubNamespace("timezone")
+	// ErrTimezoneMismatch means the timezones of some hosts are not the expected one
+	ErrTimezoneMismatch = errNSTimezone.NewType("mismatch", errutil.ErrTraitPreCheck)
+)
+
+// CheckTimezone is used to check whether all the hosts are in the same timezone. The
+// expected timezone is the most common one of the hosts if it's not specified, and the
+// timezones of the deviated hosts are set by timedatectl if fix is enabled.
+type CheckTimezone struct {
+	inspection
+
+	hosts    []string
+	expected string
+	fix      bool
+
+	timezones map[string]string
+}
+
+// Execute implements the Task interface
+func (c *CheckTimezone) Execute(ctx *Context) error {
+	c.timezones = make(map[string]string)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, host := range c.hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			stdout, _, err := e.Execute("timedatectl status", false)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, errors.Annotatef(err, "failed to get the timezone of %s", host))
+				return
+			}
+			c.timezones[host] = parseTimezone(string(stdout))
+		}(host)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	expected := c.expected
+	if expected == "" {
+		expected = c.mostCommon()
+	}
+
+	rows := [][]string{{"Host", "Timezone"}}
+	var deviated []string
+	for _, host := range c.hosts {
+		tz := c.timezones[host]
+		if tz == "" {
+			tz = "unknown"
+		}
+		rows = append(rows, []string{host, tz})
+		if c.timezones[host] != expected {
+			deviated = append(deviated, host)
+		}
+	}
+	cliutil.PrintTable(rows, true)
+	if len(deviated) == 0 {
+		return nil
+	}
+
+	if !c.fix {
+		var problems []string
+		for _, host := range deviated {
+			problems = append(problems, fmt.Sprintf("%s is in %s", host, c.timezones[host]))
+		}
+		return ErrTimezoneMismatch.
+			New("The timezones of %d hosts are not %s:\n  - %s", len(deviated), expected, strings.Join(problems, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please set the timezones of the hosts with `timedatectl set-timezone %s`, or deploy with --fix-timezone to set them.", expected)))
+	}
+
+	for _, host := range deviated {
+		e, _ := ctx.GetExecutor(host)
+		log.Infof("Setting the timezone of %s from %s to %s", host, c.timezones[host], expected)
+		if _, stderr, err := e.Execute(setTimezoneCmd(expected), true); err != nil {
+			return errors.Annotatef(err, "failed to set the timezone of %s, stderr: %s", host, stderr)
+		}
+		c.timezones[host] = expected
+	}
+	return nil
+}
+
+// planFix implements the fixPlanner interface
+func (c *CheckTimezone) planFix(plan *Plan) {
+	if !c.fix {
+		return
+	}
+	expected := c.expected
+	if expected == "" {
+		expected = "<the most common timezone>"
+	}
+	for _, host := range c.hosts {
+		e := plan.conditionalExecutor(host, fmt.Sprintf("if the timezone is not %s", expected))
+		_, _, _ = e.Execute(setTimezoneCmd(expected), true)
+	}
+}
+
+// setTimezoneCmd returns the command setting the timezone of the host
+func setTimezoneCmd(timezone string) string {
+	return fmt.Sprintf("timedatectl set-timezone %s", timezone)
+}
+
+// mostCommon returns the timezone of the most hosts, the one of the earlier host wins a tie
+func (c *CheckTimezone) mostCommon() string {
+	counts := make(map[string]int)
+	var result string
+	for _, host := range c.hosts {
+		tz := c.timezones[host]
+		counts[tz]++
+		if counts[tz] > counts[result] {
+			result = tz
+		}
+	}
+	return result
+}
+
+// Timezones returns the timezone of each host
+func (c *CheckTimezone) Timezones() map[string]string {
+	return c.timezones
+}
+
+// parseTimezone parses the output of `timedatectl status`, which has a line like
+// `Time zone: Asia/Shanghai (CST, +0800)`, or `Timezone: Asia/Shanghai` with the old
+// versions of systemd
+func parseTimezone(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) != 2 || (kv[0] != "Time zone" && kv[0] != "Timezone") {
+			continue
+		}
+		fields := strings.Fields(kv[1])
+		if len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// Rollback implements the Task interface
+func (c *CheckTimezone) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckTimezone) String() string {
+	return fmt.Sprintf("CheckTimezone: hosts=%s, expected=%s, fix=%v", strings.Join(c.hosts, ","), c.expected, c.fix)
+}
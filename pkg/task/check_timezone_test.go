@@ -0,0 +1,92 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// timedatectlOutput returns the output of `timedatectl status` in the timezone
+func timedatectlOutput(tz string) string {
+	return fmt.Sprintf(`      Local time: Wed 2020-04-15 10:35:21 CST
+  Universal time: Wed 2020-04-15 02:35:21 UTC
+        RTC time: Wed 2020-04-15 02:35:20
+       Time zone: %s (CST, +0800)
+     NTP enabled: yes
+NTP synchronized: yes
+`, tz)
+}
+
+func timezoneContext(timezones map[string]string) (*Context, map[string]*mockExecutor) {
+	return mockHostsContext(hostsOf(timezones), func(host string) *mockExecutor {
+		output := timedatectlOutput(timezones[host])
+		return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			if cmd == "timedatectl status" {
+				return []byte(output), nil, nil
+			}
+			return nil, nil, nil
+		}}
+	})
+}
+
+func (s *taskSuite) TestCheckTimezone(c *C) {
+	hosts := []string{"172.16.5.140", "172.16.5.141", "172.16.5.142"}
+	ctx, executors := timezoneContext(map[string]string{
+		"172.16.5.140": "UTC",
+		"172.16.5.141": "Asia/Shanghai",
+		"172.16.5.142": "Asia/Shanghai",
+	})
+
+	// the most common one is expected
+	t := &CheckTimezone{hosts: hosts}
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrTimezoneMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The timezones of 1 hosts are not Asia/Shanghai:\n  - 172.16.5.140 is in UTC.*")
+	c.Assert(t.Timezones(), DeepEquals, map[string]string{
+		"172.16.5.140": "UTC",
+		"172.16.5.141": "Asia/Shanghai",
+		"172.16.5.142": "Asia/Shanghai",
+	})
+
+	// the configured one is expected
+	t = &CheckTimezone{hosts: hosts, expected: "UTC"}
+	err = t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrTimezoneMismatch), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The timezones of 2 hosts are not UTC:\n  - 172.16.5.141 is in Asia/Shanghai\n  - 172.16.5.142 is in Asia/Shanghai.*")
+
+	// fix them
+	t = &CheckTimezone{hosts: hosts, expected: "UTC", fix: true}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(executors["172.16.5.140"].commands(), DeepEquals, []string{"timedatectl status", "timedatectl status", "timedatectl status"})
+	for _, host := range []string{"172.16.5.141", "172.16.5.142"} {
+		cmds := executors[host].commands()
+		c.Assert(cmds[len(cmds)-1], Equals, "timedatectl set-timezone UTC")
+		c.Assert(t.Timezones()[host], Equals, "UTC")
+	}
+}
+
+func (s *taskSuite) TestCheckTimezoneConsistent(c *C) {
+	ctx, _ := timezoneContext(map[string]string{
+		"172.16.5.140": "Asia/Shanghai",
+		"172.16.5.141": "Asia/Shanghai",
+	})
+	t := &CheckTimezone{hosts: []string{"172.16.5.140", "172.16.5.141"}}
+	c.Assert(t.Execute(ctx), IsNil)
+
+	c.Assert(parseTimezone("Timezone: America/New_York\n"), Equals, "America/New_York")
+	c.Assert(parseTimezone("unknown"), Equals, "")
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSTLS = errNS.NewSubNamespace("tls")
+	// ErrTLSClientInvalid means the API of some PD servers can't be called by the TLS client
+	ErrTLSClientInvalid = errNSTLS.NewType("client_invalid", errutil.ErrTraitPreCheck)
+)
+
+// CheckTLS is used to check the TLS client can call the API of each PD server, so that the
+// operations calling the API later don't fail by the confusing handshake errors
+type CheckTLS struct {
+	inspection
+
+	endpoints []string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+}
+
+// Execute implements the Task interface
+func (c *CheckTLS) Execute(ctx *Context) error {
+	rows := [][]string{{"PD", "Result"}}
+	var problems []string
+	for _, endpoint := range c.endpoints {
+		_, err := api.NewPDClient([]string{endpoint}, c.timeout, c.tlsConfig).GetMembers()
+		if err == nil {
+			rows = append(rows, []string{endpoint, "ok"})
+			continue
+		}
+		problem := tlsProblem(err)
+		rows = append(rows, []string{endpoint, problem})
+		problems = append(problems, fmt.Sprintf("%s: %s", endpoint, problem))
+	}
+	cliutil.PrintTable(rows, true)
+	if len(problems) == 0 {
+		return nil
+	}
+	return ErrTLSClientInvalid.
+		New("The API of %d PD servers can't be called by the TLS client:\n  - %s", len(problems), strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please check the CA, the client certificate and key are the ones the cluster is deployed with."))
+}
+
+// tlsProblem explains the error of calling the API by the TLS client
+func tlsProblem(err error) string {
+	cause := errors.Cause(err)
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+	)
+	switch {
+	case stderrors.As(cause, &unknownAuthority):
+		return "the certificate of the server is not signed by the CA"
+	case stderrors.As(cause, &hostname):
+		return fmt.Sprintf("the certificate of the server is not valid for the host: %s", hostname.Error())
+	case stderrors.As(cause, &invalid):
+		return fmt.Sprintf("the certificate of the server is invalid: %s", invalid.Error())
+	case stderrors.As(cause, &recordHeader), strings.Contains(cause.Error(), "server gave HTTP response to HTTPS client"):
+		return "the server doesn't serve TLS"
+	case strings.Contains(cause.Error(), "remote error: tls:"):
+		return fmt.Sprintf("the client certificate is rejected by the server: %s", cause.Error()[strings.Index(cause.Error(), "remote error: tls:"):])
+	}
+	return cause.Error()
+}
+
+// Rollback implements the Task interface
+func (c *CheckTLS) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckTLS) String() string {
+	return fmt.Sprintf("CheckTLS: endpoints=%s", strings.Join(c.endpoints, ","))
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+// testCert is a certificate with its key, signed by the parent or self-signed
+type testCert struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(c *C, name string, parent *testCert, isCA bool) *testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and the key in PEM to the directory
+func (t *testCert) write(c *C, dir, name string) (certPath, keyPath string) {
+	certPath = filepath.Join(dir, name+".crt")
+	keyPath = filepath.Join(dir, name+".key")
+	c.Assert(ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: t.der}), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(t.key)}), 0600), IsNil)
+	return
+}
+
+func pdMembersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/pd/api/v1/members" {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write([]byte(`{"members":[]}`))
+}
+
+func (s *taskSuite) TestCheckTLS(c *C) {
+	dir, err := ioutil.TempDir("", "check-tls")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(c, "ca", nil, true)
+	otherCA := newTestCert(c, "other-ca", nil, true)
+	server := newTestCert(c, "pd", ca, false)
+	caPath, _ := ca.write(c, dir, "ca")
+	otherCAPath, _ := otherCA.write(c, dir, "other-ca")
+	clientCert, clientKey := newTestCert(c, "client", ca, false).write(c, dir, "client")
+	strangerCert, strangerKey := newTestCert(c, "stranger", otherCA, false).write(c, dir, "stranger")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(pdMembersHandler))
+	tlsServer.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.der}, PrivateKey: server.key}},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	tlsServer.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(pdMembersHandler))
+	defer plainServer.Close()
+	tlsAddr := strings.TrimPrefix(tlsServer.URL, "https://")
+	plainAddr := strings.TrimPrefix(plainServer.URL, "http://")
+
+	// the valid client
+	config, err := utils.LoadTLSConfig(caPath, clientCert, clientKey)
+	c.Assert(err, IsNil)
+	t := &CheckTLS{endpoints: []string{tlsAddr}, tlsConfig: config, timeout: time.Second}
+	c.Assert(t.Execute(NewContext()), IsNil)
+
+	// the client certificate signed by another CA is rejected, and the server not serving
+	// TLS is reported too
+	config, err = utils.LoadTLSConfig(caPath, strangerCert, strangerKey)
+	c.Assert(err, IsNil)
+	t = &CheckTLS{endpoints: []string{tlsAddr, plainAddr}, tlsConfig: config, timeout: time.Second}
+	err = t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrTLSClientInvalid), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The API of 2 PD servers can't be called by the TLS client:\n"+
+		"  - "+tlsAddr+": the client certificate is rejected by the server: remote error: tls: .*\n"+
+		"  - "+plainAddr+": the server doesn't serve TLS.*")
+
+	// the server certificate is not signed by the CA
+	config, err = utils.LoadTLSConfig(otherCAPath, clientCert, clientKey)
+	c.Assert(err, IsNil)
+	t = &CheckTLS{endpoints: []string{tlsAddr}, tlsConfig: config, timeout: time.Second}
+	err = t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrTLSClientInvalid), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*"+tlsAddr+": the certificate of the server is not signed by the CA.*")
+
+	_, err = utils.LoadTLSConfig(filepath.Join(dir, "absent.crt"), "", "")
+	c.Assert(err, ErrorMatches, "failed to read the CA .*")
+	_, err = utils.LoadTLSConfig(caPath, clientCert, strangerKey)
+	c.Assert(err, ErrorMatches, "failed to load the client certificate .*")
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	errNSUpgradePath = errNS.NewSubNamespace("upgrade_path")
+	// ErrUpgradePathUnsupported means the cluster can't be upgraded to the target version directly
+	ErrUpgradePathUnsupported = errNSUpgradePath.NewType("unsupported", errutil.ErrTraitPreCheck)
+)
+
+// supportedUpgradePaths are the release series which a series can be upgraded to directly,
+// the upgrades between the versions of the same series are always supported
+var supportedUpgradePaths = map[string][]string{
+	"v2.1": {"v3.0"},
+	"v3.0": {"v3.1", "v4.0"},
+	"v3.1": {"v4.0"},
+	"v4.0": {},
+}
+
+// CheckUpgradePath is used to check whether the cluster can be upgraded from the current
+// version to the target one directly. Skipping a series which must be upgraded through
+// risks corrupting the data, so the intermediate series are suggested in that case. The
+// nightly version can be upgraded to from any version.
+type CheckUpgradePath struct {
+	from string
+	to   string
+}
+
+// Execute implements the Task interface
+func (c *CheckUpgradePath) Execute(ctx *Context) error {
+	if repository.Version(c.to).IsNightly() {
+		return nil
+	}
+	for _, v := range []string{c.from, c.to} {
+		if !semver.IsValid(v) {
+			return ErrUpgradePathUnsupported.New("%s is not a valid version", v)
+		}
+	}
+
+	switch semver.Compare(c.from, c.to) {
+	case 0:
+		return ErrUpgradePathUnsupported.New("The cluster is already %s", c.from).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please specify a higher version than %s.", c.from)))
+	case 1:
+		return ErrUpgradePathUnsupported.New("Downgrading the cluster from %s to %s is not supported", c.from, c.to).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please specify a higher version than %s.", c.from)))
+	}
+
+	from, to := semver.MajorMinor(c.from), semver.MajorMinor(c.to)
+	if from == to {
+		return nil
+	}
+	_, knownFrom := supportedUpgradePaths[from]
+	_, knownTo := supportedUpgradePaths[to]
+	if !knownFrom || !knownTo {
+		ctx.Logger().Warnf("The upgrade path from %s to %s is unknown, please make sure it's supported", c.from, c.to)
+		return nil
+	}
+
+	path := upgradePath(from, to)
+	switch {
+	case len(path) == 2:
+		return nil
+	case len(path) == 0:
+		return ErrUpgradePathUnsupported.New("Upgrading the cluster from %s to %s is not supported", c.from, c.to)
+	}
+	return ErrUpgradePathUnsupported.
+		New("Upgrading the cluster from %s to %s directly is not supported, the supported path is %s", c.from, c.to, strings.Join(path, " -> ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please upgrade the cluster to the latest %s release first, e.g: `%s upgrade <cluster-name> %s.x`.", path[1], cliutil.OsArgs0(), path[1])))
+}
+
+// upgradePath returns the shortest path of the series from one to another, nil if there's no path
+func upgradePath(from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		series := queue[0]
+		queue = queue[1:]
+		if series == to {
+			var path []string
+			for ; series != ""; series = prev[series] {
+				path = append([]string{series}, path...)
+			}
+			return path
+		}
+		for _, next := range supportedUpgradePaths[series] {
+			if _, visited := prev[next]; !visited {
+				prev[next] = series
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CheckUpgradePath) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckUpgradePath) String() string {
+	return fmt.Sprintf("CheckUpgradePath: from=%s, to=%s", c.from, c.to)
+}
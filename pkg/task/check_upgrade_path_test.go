@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestCheckUpgradePath(c *C) {
+	ctx := NewContext()
+	l := &captureLogger{}
+	ctx.SetLogger(l)
+
+	// supported single steps
+	for _, versions := range [][2]string{
+		{"v3.0.12", "v4.0.0"},
+		{"v3.0.12", "v3.1.0"},
+		{"v4.0.0-rc", "v4.0.0"},
+		{"v4.0.0", "nightly"},
+	} {
+		t := &CheckUpgradePath{from: versions[0], to: versions[1]}
+		c.Assert(t.Execute(ctx), IsNil, Commentf("%s -> %s", versions[0], versions[1]))
+	}
+
+	// a series unknown to the table is let through with a warning
+	c.Assert((&CheckUpgradePath{from: "v4.0.0", to: "v5.0.0"}).Execute(ctx), IsNil)
+	c.Assert(l.lines, DeepEquals, []string{"WARN The upgrade path from v4.0.0 to v5.0.0 is unknown, please make sure it's supported"})
+}
+
+func (s *taskSuite) TestCheckUpgradePathUnsupported(c *C) {
+	// skipping a series
+	err := (&CheckUpgradePath{from: "v2.1.19", to: "v4.0.0"}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrUpgradePathUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Upgrading the cluster from v2.1.19 to v4.0.0 directly is not supported, the supported path is v2.1 -> v3.0 -> v4.0.*")
+
+	// downgrading
+	err = (&CheckUpgradePath{from: "v4.0.0", to: "v3.0.12"}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrUpgradePathUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Downgrading the cluster from v4.0.0 to v3.0.12 is not supported.*")
+
+	// the same version
+	err = (&CheckUpgradePath{from: "v4.0.0", to: "v4.0.0"}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrUpgradePathUnsupported), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The cluster is already v4.0.0.*")
+
+	err = (&CheckUpgradePath{from: "v4.0.0", to: "4.0.1"}).Execute(NewContext())
+	c.Assert(err.Error(), Matches, ".*4.0.1 is not a valid version.*")
+
+	c.Assert(upgradePath("v3.1", "v3.0"), IsNil)
+	c.Assert(upgradePath("v3.0", "v4.0"), DeepEquals, []string{"v3.0", "v4.0"})
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+)
+
+var (
+	errNSUtility = errNS.NewSubNamespace("utility")
+	// ErrUtilityMissing means some utilities required by the operation are not installed on the host
+	ErrUtilityMissing = errNSUtility.NewType("missing", errutil.ErrTraitPreCheck)
+)
+
+// utilityPackages are the packages providing the utilities, used to suggest how to install them
+var utilityPackages = map[string]string{
+	"tar":         "tar",
+	"ss":          "iproute",
+	"df":          "coreutils",
+	"numactl":     "numactl",
+	"timedatectl": "systemd",
+	"logrotate":   "logrotate",
+	"chronyc":     "chrony",
+}
+
+// CheckUtility is used to check the utilities the later tasks shell out to are installed on
+// the host, so that a missing one is reported up front rather than by a cryptic failure of
+// the task using it
+type CheckUtility struct {
+	inspection
+
+	host      string
+	utilities []string
+
+	missing []string
+}
+
+// Execute implements the Task interface
+func (c *CheckUtility) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(c.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	c.missing = nil
+	for _, utility := range c.utilities {
+		if _, _, err := e.Execute(fmt.Sprintf("command -v %s", utility), false); err != nil {
+			c.missing = append(c.missing, utility)
+		}
+	}
+	if len(c.missing) == 0 {
+		return nil
+	}
+
+	var packages []string
+	for _, utility := range c.missing {
+		if pkg, ok := utilityPackages[utility]; ok {
+			packages = append(packages, pkg)
+		} else {
+			packages = append(packages, utility)
+		}
+	}
+	return ErrUtilityMissing.
+		New("The utilities required are not installed on %s: %s", c.host, strings.Join(c.missing, ", ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please install the packages on the host, e.g: yum install -y %s", strings.Join(packages, " "))))
+}
+
+// Missing returns the utilities not installed on the host
+func (c *CheckUtility) Missing() []string {
+	return c.missing
+}
+
+// Rollback implements the Task interface
+func (c *CheckUtility) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckUtility) String() string {
+	return fmt.Sprintf("CheckUtility: host=%s, utilities=%s", c.host, strings.Join(c.utilities, ","))
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	. "github.com/pingcap/check"
+)
+
+// utilityExecutor returns a mocked executor of a host where only the utilities installed are found
+func utilityExecutor(installed ...string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		for _, utility := range installed {
+			if cmd == "command -v "+utility {
+				return []byte("/usr/bin/" + utility + "\n"), nil, nil
+			}
+		}
+		return nil, nil, errors.New("exit status 1")
+	}}
+}
+
+func (s *taskSuite) TestCheckUtility(c *C) {
+	utilities := []string{"tar", "ss", "numactl", "timedatectl"}
+
+	e := utilityExecutor("tar", "ss", "numactl", "timedatectl", "logrotate")
+	t := &CheckUtility{host: "172.16.5.140", utilities: utilities}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Missing(), HasLen, 0)
+	c.Assert(e.commands(), DeepEquals, []string{"command -v tar", "command -v ss", "command -v numactl", "command -v timedatectl"})
+
+	// all the missing ones are reported at once
+	e = utilityExecutor("tar", "timedatectl")
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrUtilityMissing), IsTrue)
+	c.Assert(t.Missing(), DeepEquals, []string{"ss", "numactl"})
+	c.Assert(err.Error(), Matches, ".*The utilities required are not installed on 172.16.5.140: ss, numactl.*")
+	suggestion, _ := errorx.Cast(err).Property(errutil.ErrPropSuggestion)
+	c.Assert(suggestion, Equals, "Please install the packages on the host, e.g: yum install -y iproute numactl")
+}
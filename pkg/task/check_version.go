@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+)
+
+var (
+	errNSVersion = errNS.NewSubNamespace("version")
+	// ErrVersionDrift means some instances are not running the version of the cluster
+	ErrVersionDrift = errNSVersion.NewType("drift", errutil.ErrTraitPreCheck)
+)
+
+// versionQueryTimeout is the timeout of querying the version of an instance
+const versionQueryTimeout = 5 * time.Second
+
+// CheckVersion is used to check whether all the instances are running the version of the
+// cluster, the instances left on another version by an interrupted upgrade are reported
+type CheckVersion struct {
+	inspection
+
+	spec    *meta.Specification
+	version string
+
+	versions []operator.InstanceVersion
+}
+
+// Execute implements the Task interface
+func (c *CheckVersion) Execute(ctx *Context) error {
+	c.versions = operator.CollectVersions(ctx, c.spec, c.version, versionQueryTimeout)
+
+	rows := [][]string{{"ID", "Role", "Expected", "Actual", "Status"}}
+	var drifted []string
+	for _, v := range c.versions {
+		status := "OK"
+		switch {
+		case v.Err != nil:
+			status = "Unknown: " + v.Err.Error()
+		case v.Drifted():
+			status = "Drifted"
+			drifted = append(drifted, v.ID)
+		}
+		rows = append(rows, []string{v.ID, v.Role, v.Expected, v.Actual, status})
+	}
+	cliutil.PrintTable(rows, true)
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	return ErrVersionDrift.
+		New("%d instances are not running %s: %s", len(drifted), c.version, strings.Join(drifted, ", ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please upgrade them with `%s upgrade <cluster-name> %s -N %s`.", cliutil.OsArgs0(), c.version, strings.Join(drifted, ","))))
+}
+
+// Versions returns the running version of each instance
+func (c *CheckVersion) Versions() []operator.InstanceVersion {
+	return c.versions
+}
+
+// Rollback implements the Task interface
+func (c *CheckVersion) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckVersion) String() string {
+	return fmt.Sprintf("CheckVersion: version=%s", c.version)
+}
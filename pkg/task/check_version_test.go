@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestCheckVersion(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pump_servers:
+  - host: 172.16.5.140
+    deploy_dir: /deploy/pump-8250
+  - host: 172.16.5.140
+    port: 8251
+    deploy_dir: /deploy/pump-8251
+`), topo), IsNil)
+
+	outputs := map[string]string{
+		"/deploy/pump-8250/bin/pump -V": "Release Version: v4.0.0\n",
+		"/deploy/pump-8251/bin/pump -V": "Release Version: v3.0.12\n",
+	}
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return []byte(outputs[cmd]), nil, nil
+	}}
+
+	t := &CheckVersion{spec: topo, version: "v4.0.0"}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrVersionDrift), IsTrue)
+	c.Assert(err.Error(), Matches, ".*1 instances are not running v4.0.0: 172.16.5.140:8251.*")
+	c.Assert(t.Versions(), HasLen, 2)
+	c.Assert(t.Versions()[1].Actual, Equals, "v3.0.12")
+
+	outputs["/deploy/pump-8251/bin/pump -V"] = "Release Version: v4.0.0\n"
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+}
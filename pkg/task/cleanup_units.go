@@ -0,0 +1,185 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSUnit = errNS.NewSubNamespace("unit")
+	// ErrStaleUnit means the systemd unit of a removed instance is still there after cleanup
+	ErrStaleUnit = errNSUnit.NewType("stale", errutil.ErrTraitPreCheck)
+)
+
+// CleanupUnits is used to disable and remove the systemd units of the removed instances,
+// so they won't be started again after the hosts reboot. The units on the unreachable hosts
+// are recorded in the pending file, and are cleaned up by the next run.
+type CleanupUnits struct {
+	units       map[string][]string // host -> units
+	pendingFile string
+
+	pending map[string][]string
+}
+
+// Execute implements the Task interface
+func (c *CleanupUnits) Execute(ctx *Context) error {
+	units, err := c.loadPending()
+	if err != nil {
+		return err
+	}
+	for host, us := range c.units {
+		units[host] = appendUnique(units[host], us...)
+	}
+
+	hosts := make([]string, 0, len(units))
+	for host := range units {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	c.pending = make(map[string][]string)
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			c.pending[host] = units[host]
+			continue
+		}
+		if _, _, err := e.Execute("true", false, reachableTimeout); err != nil {
+			log.Warnf("The host %s is unreachable, the cleanup of %s is pending: %s", host, strings.Join(units[host], ","), err)
+			c.pending[host] = units[host]
+			continue
+		}
+
+		for _, unit := range units[host] {
+			if err := c.cleanup(ctx, host, unit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.savePending()
+}
+
+// cleanup disables and removes the unit on the host, and verifies it's gone after reload
+func (c *CleanupUnits) cleanup(ctx *Context, host, unit string) error {
+	e, _ := ctx.GetExecutor(host)
+	// the unit file may be removed already, so the wanted-by links are removed explicitly
+	if _, stderr, err := e.Execute(fmt.Sprintf("systemctl disable %s", unit), true); err != nil {
+		log.Debugf("Failed to disable %s on %s: %s, stderr: %s", unit, host, err, stderr)
+	}
+	cmd := fmt.Sprintf("rm -f /etc/systemd/system/%[1]s /etc/systemd/system/*.wants/%[1]s", unit)
+	if _, stderr, err := e.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to remove %s on %s, stderr: %s", unit, host, stderr)
+	}
+	if _, stderr, err := e.Execute("systemctl daemon-reload", true); err != nil {
+		return errors.Annotatef(err, "failed to reload systemd on %s, stderr: %s", host, stderr)
+	}
+
+	// the removal is not verified when planning
+	if ctx.Planning() {
+		return nil
+	}
+	stdout, _, err := e.Execute(fmt.Sprintf("systemctl list-unit-files --no-legend %s", unit), true)
+	if err == nil && strings.Contains(string(stdout), unit) {
+		return ErrStaleUnit.
+			New("The unit %s is still on %s after cleanup", unit, host).
+			WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("Please check where the unit is by `systemctl status %s` on the host and remove it manually.", unit)))
+	}
+	log.Infof("Cleaned up %s on %s", unit, host)
+	return nil
+}
+
+func (c *CleanupUnits) loadPending() (map[string][]string, error) {
+	units := make(map[string][]string)
+	if c.pendingFile == "" {
+		return units, nil
+	}
+	data, err := ioutil.ReadFile(c.pendingFile)
+	if os.IsNotExist(err) {
+		return units, nil
+	}
+	if err != nil {
+		return nil, errors.AddStack(err)
+	}
+	if err := yaml.Unmarshal(data, &units); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse %s", c.pendingFile)
+	}
+	return units, nil
+}
+
+func (c *CleanupUnits) savePending() error {
+	if c.pendingFile == "" {
+		return nil
+	}
+	if len(c.pending) == 0 {
+		if err := os.Remove(c.pendingFile); err != nil && !os.IsNotExist(err) {
+			return errors.AddStack(err)
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(c.pending)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	return ioutil.WriteFile(c.pendingFile, data, 0644)
+}
+
+// Pending returns the units which are not cleaned up because the hosts are unreachable
+func (c *CleanupUnits) Pending() map[string][]string {
+	return c.pending
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, e := range list {
+			if e == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Rollback implements the Task interface
+func (c *CleanupUnits) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CleanupUnits) String() string {
+	var units []string
+	for host, us := range c.units {
+		for _, u := range us {
+			units = append(units, host+":"+u)
+		}
+	}
+	sort.Strings(units)
+	return fmt.Sprintf("CleanupUnits: units=%s", strings.Join(units, ","))
+}
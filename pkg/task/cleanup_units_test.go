@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestCleanupUnits(c *C) {
+	e := &mockExecutor{}
+	t := &CleanupUnits{units: map[string][]string{"172.16.5.140": {"tidb-4000.service"}}}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(e.commands(), DeepEquals, []string{
+		"true",
+		"systemctl disable tidb-4000.service",
+		"rm -f /etc/systemd/system/tidb-4000.service /etc/systemd/system/*.wants/tidb-4000.service",
+		"systemctl daemon-reload",
+		"systemctl list-unit-files --no-legend tidb-4000.service",
+	})
+	c.Assert(t.Pending(), HasLen, 0)
+
+	// the unit is still loaded from another place
+	e = &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if cmd == "systemctl list-unit-files --no-legend tidb-4000.service" {
+			return []byte("tidb-4000.service enabled\n"), nil, nil
+		}
+		return nil, nil, nil
+	}}
+	err := t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrStaleUnit), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The unit tidb-4000.service is still on 172.16.5.140 after cleanup.*")
+}
+
+func (s *taskSuite) TestCleanupUnitsPending(c *C) {
+	dir, err := ioutil.TempDir("", "cleanup-units")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	pendingFile := filepath.Join(dir, "pending_cleanup.yaml")
+
+	ctx := NewContext()
+	down := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, nil, errors.New("dial tcp 172.16.5.141:22: i/o timeout")
+	}}
+	up := &mockExecutor{}
+	ctx.SetExecutor("172.16.5.140", up)
+	ctx.SetExecutor("172.16.5.141", down)
+
+	// the cleanup on the unreachable host is recorded
+	t := &CleanupUnits{
+		units: map[string][]string{
+			"172.16.5.140": {"tidb-4000.service"},
+			"172.16.5.141": {"pd-2379.service"},
+		},
+		pendingFile: pendingFile,
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Pending(), DeepEquals, map[string][]string{"172.16.5.141": {"pd-2379.service"}})
+	c.Assert(down.commands(), DeepEquals, []string{"true"})
+	_, err = os.Stat(pendingFile)
+	c.Assert(err, IsNil)
+
+	// and retried by the next run after the host is back
+	ctx.SetExecutor("172.16.5.141", up)
+	t = &CleanupUnits{
+		units:       map[string][]string{"172.16.5.141": {"pump-8250.service"}},
+		pendingFile: pendingFile,
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Pending(), HasLen, 0)
+	c.Assert(up.commands()[5:], DeepEquals, []string{
+		"true",
+		"systemctl disable pd-2379.service",
+		"rm -f /etc/systemd/system/pd-2379.service /etc/systemd/system/*.wants/pd-2379.service",
+		"systemctl daemon-reload",
+		"systemctl list-unit-files --no-legend pd-2379.service",
+		"systemctl disable pump-8250.service",
+		"rm -f /etc/systemd/system/pump-8250.service /etc/systemd/system/*.wants/pump-8250.service",
+		"systemctl daemon-reload",
+		"systemctl list-unit-files --no-legend pump-8250.service",
+	})
+	_, err = os.Stat(pendingFile)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
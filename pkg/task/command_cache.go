@@ -0,0 +1,168 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+)
+
+// defaultCacheableCommands are the informational commands whose results don't change during
+// a run, a single word matches the command of any arguments
+var defaultCacheableCommands = []string{
+	"uname",
+	"nproc",
+	"ldd --version",
+	"systemctl --version",
+	"cat /etc/os-release",
+	"cat /etc/redhat-release",
+	"cat /etc/os-release 2>/dev/null || cat /etc/redhat-release",
+	"cat /proc/1/comm",
+	"cat /proc/cpuinfo",
+}
+
+// CommandCache caches the results of the side-effect-free commands by host and command,
+// a command is cached if it matches a cacheable pattern and not a non-cacheable one. A
+// pattern matches the command equal to it, or the simple command whose first word is
+// the single-word pattern.
+type CommandCache struct {
+	mu           sync.Mutex
+	cacheable    []string
+	nonCacheable []string
+	entries      map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done   chan struct{}
+	stdout []byte
+	stderr []byte
+	err    error
+}
+
+// EnableCommandCache makes the results of the cacheable commands served from the cache for
+// the rest of the run, it must be called before the executors are set
+func (ctx *Context) EnableCommandCache() *CommandCache {
+	if ctx.cache == nil {
+		ctx.cache = &CommandCache{
+			cacheable: append([]string{}, defaultCacheableCommands...),
+			entries:   make(map[string]*cacheEntry),
+		}
+	}
+	return ctx.cache
+}
+
+// Cacheable marks the commands matching the patterns as cacheable
+func (c *CommandCache) Cacheable(patterns ...string) {
+	c.mu.Lock()
+	c.cacheable = append(c.cacheable, patterns...)
+	c.mu.Unlock()
+}
+
+// NonCacheable marks the commands matching the patterns as non-cacheable, even if they
+// are cacheable by default
+func (c *CommandCache) NonCacheable(patterns ...string) {
+	c.mu.Lock()
+	c.nonCacheable = append(c.nonCacheable, patterns...)
+	c.mu.Unlock()
+}
+
+func (c *CommandCache) shouldCache(cmd string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, pattern := range c.nonCacheable {
+		if matchCommand(cmd, pattern) {
+			return false
+		}
+	}
+	for _, pattern := range c.cacheable {
+		if matchCommand(cmd, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCommand reports whether the command is the pattern, or a simple command without the
+// shell operators whose first word is the single-word pattern
+func matchCommand(cmd, pattern string) bool {
+	if cmd == pattern {
+		return true
+	}
+	if strings.ContainsAny(pattern, " \t") || strings.ContainsAny(cmd, ";&|<>()$`\n") {
+		return false
+	}
+	fields := strings.Fields(cmd)
+	return len(fields) > 0 && fields[0] == pattern
+}
+
+// execute returns the cached result of the command, or executes it by fn. The concurrent
+// callers of the same command wait for the first one, and the failed results are not kept.
+func (c *CommandCache) execute(key string, fn func() ([]byte, []byte, error)) ([]byte, []byte, error) {
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	if !found {
+		entry = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	if found {
+		<-entry.done
+		return entry.stdout, entry.stderr, entry.err
+	}
+
+	entry.stdout, entry.stderr, entry.err = fn()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.stdout, entry.stderr, entry.err
+}
+
+// cachingExecutor serves the cacheable commands of the host from the cache
+type cachingExecutor struct {
+	host  string
+	inner executor.TiOpsExecutor
+	cache *CommandCache
+}
+
+// Execute implements the TiOpsExecutor interface
+func (e *cachingExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	if !e.cache.shouldCache(cmd) {
+		return e.inner.Execute(cmd, sudo, timeout...)
+	}
+	key := e.host + "\x00" + cmd
+	if sudo {
+		key = e.host + "\x00sudo\x00" + cmd
+	}
+	return e.cache.execute(key, func() ([]byte, []byte, error) {
+		return e.inner.Execute(cmd, sudo, timeout...)
+	})
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *cachingExecutor) Transfer(src string, dst string, download bool) error {
+	return e.inner.Transfer(src, dst, download)
+}
+
+// Stream implements the StreamExecutor interface, the streamed outputs are never cached
+func (e *cachingExecutor) Stream(ctx context.Context, cmd string, sudo bool, fn func(line string)) error {
+	return executor.Stream(ctx, e.inner, cmd, sudo, fn)
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"sync"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestCommandCache(c *C) {
+	ctx := NewContext()
+	cache := ctx.EnableCommandCache()
+	cache.NonCacheable("df -h /tmp")
+
+	mocks := map[string]*mockExecutor{}
+	for _, host := range []string{"172.16.5.140", "172.16.5.141"} {
+		mocks[host] = &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			return []byte("Linux\n"), nil, nil
+		}}
+		ctx.SetExecutor(host, mocks[host])
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, _ := ctx.GetExecutor("172.16.5.140")
+			stdout, _, err := e.Execute("uname -s", false)
+			c.Check(err, IsNil)
+			c.Check(string(stdout), Equals, "Linux\n")
+		}()
+	}
+	wg.Wait()
+
+	e140, _ := ctx.GetExecutor("172.16.5.140")
+	e141, _ := ctx.GetExecutor("172.16.5.141")
+	// sudo is cached separately
+	_, _, _ = e140.Execute("uname -s", true)
+	_, _, _ = e140.Execute("uname -s", true)
+	// not cacheable
+	_, _, _ = e140.Execute("df -h /tmp", false)
+	_, _, _ = e140.Execute("df -h /tmp", false)
+	_, _, _ = e140.Execute("mkdir -p /tmp/a", false)
+	_, _, _ = e140.Execute("mkdir -p /tmp/a", false)
+	// cached by host
+	_, _, _ = e141.Execute("uname -s", false)
+
+	c.Assert(mocks["172.16.5.140"].commands(), DeepEquals, []string{
+		"uname -s", "uname -s", "df -h /tmp", "df -h /tmp", "mkdir -p /tmp/a", "mkdir -p /tmp/a",
+	})
+	c.Assert(mocks["172.16.5.141"].commands(), DeepEquals, []string{"uname -s"})
+
+	// set again from GetExecutor is not wrapped twice
+	ctx.SetExecutor("172.16.5.141", e141)
+	e141, _ = ctx.GetExecutor("172.16.5.141")
+	_, ok := e141.(*cachingExecutor).inner.(*mockExecutor)
+	c.Assert(ok, IsTrue)
+}
+
+func (s *taskSuite) TestCommandCacheFailure(c *C) {
+	ctx := NewContext()
+	cache := ctx.EnableCommandCache()
+	cache.Cacheable("free -m")
+
+	failures := 1
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if failures > 0 {
+			failures--
+			return nil, nil, errors.New("connection reset")
+		}
+		return []byte("ok"), nil, nil
+	}}
+	ctx.SetExecutor("172.16.5.140", e)
+	cached, _ := ctx.GetExecutor("172.16.5.140")
+
+	// the failed result is not kept
+	_, _, err := cached.Execute("free -m", false)
+	c.Assert(err, NotNil)
+	for i := 0; i < 3; i++ {
+		stdout, _, err := cached.Execute("free -m", false)
+		c.Assert(err, IsNil)
+		c.Assert(string(stdout), Equals, "ok")
+	}
+	c.Assert(e.commands(), DeepEquals, []string{"free -m", "free -m"})
+
+	// the commands are executed every time without the cache
+	ctx = newMockContext("172.16.5.140", e)
+	plain, _ := ctx.GetExecutor("172.16.5.140")
+	_, _, _ = plain.Execute("uname -s", false)
+	_, _, _ = plain.Execute("uname -s", false)
+	c.Assert(e.commands()[2:], DeepEquals, []string{"uname -s", "uname -s"})
+}
+
+func (s *taskSuite) TestCommandCacheMatch(c *C) {
+	cache := NewContext().EnableCommandCache()
+	cache.NonCacheable("uname -n")
+
+	for cmd, cached := range map[string]bool{
+		"uname":                 true,
+		"uname -m":              true,
+		"uname -n":              false,
+		"unamex -m":             false,
+		"uname -m; reboot":      false,
+		"uname -m && rm -rf /a": false,
+		"ldd --version":         true,
+		"ldd --version /bin/sh": false,
+		"cat /proc/cpuinfo":     true,
+		"cat /proc/cpuinfo.bak": false,
+		"df -h /data":           false,
+	} {
+		c.Assert(cache.shouldCache(cmd), Equals, cached, Commentf("%s", cmd))
+	}
+}
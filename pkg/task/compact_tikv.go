@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+)
+
+// CompactTiKV is used to compact the kv DB of the TiKV instances one by one with their
+// leaders evicted
+type CompactTiKV struct {
+	spec      *meta.Specification
+	instances []meta.Instance
+	options   operator.CompactOptions
+	retryOpt  *utils.RetryOption
+}
+
+// Execute implements the Task interface
+func (c *CompactTiKV) Execute(ctx *Context) error {
+	return operator.CompactTiKV(ctx, c.spec, c.instances, c.options, c.retryOpt)
+}
+
+// Rollback implements the Task interface
+func (c *CompactTiKV) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CompactTiKV) String() string {
+	var ids []string
+	for _, inst := range c.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("CompactTiKV: instances=%s, cfs=%s", strings.Join(ids, ","), strings.Join(c.options.CFs, ","))
+}
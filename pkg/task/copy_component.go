@@ -23,10 +23,11 @@ import (
 // CopyComponent is used to copy all files related the specific version a component
 // to the target directory of path
 type CopyComponent struct {
-	component string
-	version   repository.Version
-	host      string
-	dstDir    string
+	component   string
+	version     repository.Version
+	host        string
+	dstDir      string
+	incremental bool
 }
 
 // Execute implements the Task interface
@@ -37,9 +38,10 @@ func (c *CopyComponent) Execute(ctx *Context) error {
 	srcPath := meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName)
 
 	install := &InstallPackage{
-		srcPath: srcPath,
-		host:    c.host,
-		dstDir:  c.dstDir,
+		srcPath:     srcPath,
+		host:        c.host,
+		dstDir:      c.dstDir,
+		incremental: c.incremental,
 	}
 
 	return install.Execute(ctx)
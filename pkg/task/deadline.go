@@ -0,0 +1,173 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+)
+
+var (
+	// ErrDeadlineExceeded means the operation or the task is not finished before its deadline,
+	// it's different from the errors returned by the tasks themselves.
+	ErrDeadlineExceeded = errNS.NewType("deadline_exceeded")
+)
+
+// SetDeadline sets the deadline of the whole operation, the Serial and Parallel
+// tasks executed with this context don't start the rest of their tasks, stop waiting
+// for the outstanding tasks and return ErrDeadlineExceeded once the deadline is
+// reached, and the executors got from the context refuse to run new commands. Zero
+// means no deadline.
+//
+// A command already running at the deadline is not interrupted unless its own timeout
+// is reached, which is capped by the deadline, only the result of it is dropped.
+func (ctx *Context) SetDeadline(deadline time.Time) {
+	ctx.deadline = deadline
+}
+
+// Deadline returns the deadline of the whole operation and if it's set.
+func (ctx *Context) Deadline() (time.Time, bool) {
+	return ctx.deadline, !ctx.deadline.IsZero()
+}
+
+// checkDeadline returns ErrDeadlineExceeded if the deadline of the operation is reached,
+// the Serial and Parallel tasks check it before starting each of their tasks
+func (ctx *Context) checkDeadline() error {
+	if ctx.deadline.IsZero() || time.Now().Before(ctx.deadline) {
+		return nil
+	}
+	return deadlineExceeded(ctx.deadline, "operation")
+}
+
+// execute executes the task, the inspections are skipped when planning except that
+// their fixing commands are recorded
+func (ctx *Context) execute(t Task) error {
+	if ctx.skippedInPlan(t) {
+		if f, ok := t.(fixPlanner); ok {
+			f.planFix(ctx.plan)
+		}
+		return nil
+	}
+	ctx.markExecuted(t)
+	return t.Execute(ctx)
+}
+
+// runBeforeDeadline runs fn of a composite task and returns ErrDeadlineExceeded if it's not
+// finished before the deadline of the operation. Only the outermost composite task waits for
+// the deadline by a timer, the inner ones are run directly and just check the deadline before
+// starting each of their tasks.
+func (ctx *Context) runBeforeDeadline(fn func() error) error {
+	if ctx.deadline.IsZero() || !atomic.CompareAndSwapInt32(&ctx.deadlineWaiting, 0, 1) {
+		return fn()
+	}
+	defer atomic.StoreInt32(&ctx.deadlineWaiting, 0)
+	return runBefore(fn, ctx.deadline, "operation")
+}
+
+// executeBefore executes the task and returns ErrDeadlineExceeded if it is not
+// finished before the deadline.
+func executeBefore(ctx *Context, t Task, deadline time.Time, what string) error {
+	return runBefore(func() error {
+		ctx.markExecuted(t)
+		return t.Execute(ctx)
+	}, deadline, what)
+}
+
+// runBefore runs fn and returns ErrDeadlineExceeded if it is not finished before the
+// deadline. fn can't be interrupted, so it is left running in background and the result
+// of it is dropped.
+func runBefore(fn func() error, deadline time.Time, what string) error {
+	remain := time.Until(deadline)
+	if remain <= 0 {
+		return deadlineExceeded(deadline, what)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	timer := time.NewTimer(remain)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return deadlineExceeded(deadline, what)
+	}
+}
+
+// deadlineExecutor refuses to run new commands after the deadline of the operation, and
+// caps the timeout of the commands by it
+type deadlineExecutor struct {
+	inner    executor.TiOpsExecutor
+	deadline time.Time
+}
+
+// Execute implements the TiOpsExecutor interface
+func (e *deadlineExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	remain := time.Until(e.deadline)
+	if remain <= 0 {
+		return nil, nil, deadlineExceeded(e.deadline, "operation")
+	}
+	if len(timeout) > 0 && timeout[0] > remain {
+		timeout = []time.Duration{remain}
+	}
+	return e.inner.Execute(cmd, sudo, timeout...)
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *deadlineExecutor) Transfer(src string, dst string, download bool) error {
+	if !time.Now().Before(e.deadline) {
+		return deadlineExceeded(e.deadline, "operation")
+	}
+	return e.inner.Transfer(src, dst, download)
+}
+
+// withDeadline wraps the executor by the deadline of the operation if it's set
+func (ctx *Context) withDeadline(e executor.TiOpsExecutor) executor.TiOpsExecutor {
+	if ctx.deadline.IsZero() || e == nil {
+		return e
+	}
+	return &deadlineExecutor{inner: e, deadline: ctx.deadline}
+}
+
+func deadlineExceeded(deadline time.Time, what string) error {
+	return ErrDeadlineExceeded.New("%s deadline %s exceeded", what, deadline.Format(time.RFC3339))
+}
+
+// Timeout wraps a task which should be finished in the specified duration
+type Timeout struct {
+	inner   Task
+	timeout time.Duration
+}
+
+// Execute implements the Task interface
+func (t *Timeout) Execute(ctx *Context) error {
+	return executeBefore(ctx, t.inner, time.Now().Add(t.timeout), "task")
+}
+
+// Rollback implements the Task interface
+func (t *Timeout) Rollback(ctx *Context) error {
+	return t.inner.Rollback(ctx)
+}
+
+// String implements the fmt.Stringer interface
+func (t *Timeout) String() string {
+	return fmt.Sprintf("Timeout: timeout=%s, task=%s", t.timeout, t.inner.String())
+}
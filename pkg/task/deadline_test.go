@@ -0,0 +1,162 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+func sleepTask(d time.Duration, executed *int32) Task {
+	return &Func{
+		name: "sleep",
+		fn: func() error {
+			time.Sleep(d)
+			atomic.AddInt32(executed, 1)
+			return nil
+		},
+	}
+}
+
+func (s *taskSuite) TestSerialDeadline(c *C) {
+	var executed int32
+	t := NewBuilder().
+		Serial(sleepTask(10*time.Millisecond, &executed)).
+		Serial(sleepTask(time.Second, &executed)).
+		Serial(sleepTask(10*time.Millisecond, &executed)).
+		Build()
+
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	start := time.Now()
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+	// the last task should never be started
+	time.Sleep(time.Second)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(2))
+}
+
+func (s *taskSuite) TestParallelDeadline(c *C) {
+	var executed int32
+	t := NewBuilder().
+		Parallel(sleepTask(10*time.Millisecond, &executed), sleepTask(time.Second, &executed)).
+		Build()
+
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	start := time.Now()
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+}
+
+func (s *taskSuite) TestDeadlineNotExceeded(c *C) {
+	var executed int32
+	failed := errors.New("task failed")
+	t := NewBuilder().
+		Parallel(sleepTask(10*time.Millisecond, &executed)).
+		Func("fail", func() error { return failed }).
+		Build()
+
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(time.Second))
+	err := t.Execute(ctx)
+	// the errors of tasks are returned as is
+	c.Assert(err, Equals, failed)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsFalse)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+
+	// already exceeded
+	ctx.SetDeadline(time.Now().Add(-time.Second))
+	err = NewBuilder().Serial(sleepTask(0, &executed)).Build().Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+}
+
+func (s *taskSuite) TestTaskTimeout(c *C) {
+	var executed int32
+	t := NewBuilder().
+		Timeout(50*time.Millisecond, sleepTask(time.Second, &executed)).
+		Build()
+
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(err.Error(), Matches, ".*task deadline.*exceeded.*")
+}
+
+func (s *taskSuite) TestDeadlineNotStartingRest(c *C) {
+	var executed int32
+	t := NewBuilder().
+		Concurrency(1).
+		Parallel(
+			sleepTask(200*time.Millisecond, &executed),
+			sleepTask(0, &executed),
+			sleepTask(0, &executed),
+		).
+		Build()
+
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(50 * time.Millisecond))
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	// the running task is not interrupted, but the queued ones are never started
+	time.Sleep(300 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(1))
+}
+
+func (s *taskSuite) TestDeadlineExecutor(c *C) {
+	e := &mockExecutor{}
+	ctx := newMockContext("172.16.5.140", e)
+	ctx.SetDeadline(time.Now().Add(time.Second))
+
+	exec, found := ctx.GetExecutor("172.16.5.140")
+	c.Assert(found, IsTrue)
+	_, _, err := exec.Execute("uptime", false, time.Minute)
+	c.Assert(err, IsNil)
+
+	// no more commands are run after the deadline
+	ctx.SetDeadline(time.Now().Add(-time.Second))
+	exec, _ = ctx.GetExecutor("172.16.5.140")
+	_, _, err = exec.Execute("uptime", false)
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(errorx.IsOfType(ctx.Get("172.16.5.140").Transfer("/tmp/a", "/tmp/b", false), ErrDeadlineExceeded), IsTrue)
+	c.Assert(e.commands(), DeepEquals, []string{"uptime"})
+}
+
+func (s *taskSuite) TestDeadlineNestedSerial(c *C) {
+	var executed int32
+	t := NewBuilder().
+		Serial(NewBuilder().
+			Parallel(sleepTask(10*time.Millisecond, &executed), sleepTask(time.Second, &executed)).
+			Build()).
+		Serial(sleepTask(0, &executed)).
+		Build()
+
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	start := time.Now()
+	err := t.Execute(ctx)
+	// the outermost task returns at the deadline and the inner ones stop starting tasks
+	c.Assert(errorx.IsOfType(err, ErrDeadlineExceeded), IsTrue)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+	time.Sleep(time.Second)
+	c.Assert(atomic.LoadInt32(&executed), Equals, int32(2))
+	c.Assert(atomic.LoadInt32(&ctx.deadlineWaiting), Equals, int32(0))
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync"
+)
+
+// SetDeterministic makes the inner tasks of Parallel and RetryParallel executed one by one
+// in the order of them, so that the logs and the events of a run are reproducible. The first
+// error is still returned after all the inner tasks are executed.
+func (ctx *Context) SetDeterministic(deterministic bool) {
+	ctx.deterministic = deterministic
+}
+
+// SetConcurrency limits the inner tasks of each parallel task and the instances of each
+// component started or stopped to be executed at most n at the same time, 0 means no limit.
+// The limit of a parallel task set by the builder takes precedence.
+func (ctx *Context) SetConcurrency(n int) {
+	ctx.concurrency = n
+}
+
+// Concurrency returns the limit set by SetConcurrency
+func (ctx *Context) Concurrency() int {
+	return ctx.concurrency
+}
+
+// runAll calls fn with the index of each of the n tasks concurrently, at most limit of them at
+// the same time if limit is positive or the limit of the context otherwise, or one by one in
+// order if the context is deterministic, and waits for all of them
+func (ctx *Context) runAll(n, limit int, fn func(i int)) {
+	if limit <= 0 {
+		limit = ctx.concurrency
+	}
+	if ctx.deterministic {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		go func(i int) {
+			defer func() {
+				if sem != nil {
+					<-sem
+				}
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
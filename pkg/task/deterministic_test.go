@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// orderedTasks returns the tasks recording the order they are executed in, the earlier ones
+// take longer so that they finish later if executed concurrently. The tasks in failures fail.
+func orderedTasks(n int, failures map[int]bool) ([]Task, func() []string) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	var tasks []Task
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("t%d", i)
+		delay := time.Duration(n-i) * 20 * time.Millisecond
+		fail := failures[i]
+		tasks = append(tasks, &Func{name: name, fn: func() error {
+			time.Sleep(delay)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			if fail {
+				return fmt.Errorf("%s failed", name)
+			}
+			return nil
+		}})
+	}
+	return tasks, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, order...)
+	}
+}
+
+func (s *taskSuite) TestParallelDeterministic(c *C) {
+	tasks, order := orderedTasks(4, map[int]bool{1: true, 3: true})
+	ctx := NewContext()
+	ctx.SetDeterministic(true)
+	l := &captureLogger{}
+	ctx.SetLogger(l)
+
+	// all the tasks are executed in order and the first error is returned
+	err := NewBuilder().Parallel(tasks...).Build().Execute(ctx)
+	c.Assert(err, ErrorMatches, "t1 failed")
+	c.Assert(order(), DeepEquals, []string{"t0", "t1", "t2", "t3"})
+	c.Assert(l.lines, DeepEquals, []string{
+		"INFO + [Parallel] - t0", "INFO + [Parallel] - t1", "INFO + [Parallel] - t2", "INFO + [Parallel] - t3",
+	})
+
+	// the retried ones too
+	var executed [3]int32
+	l.lines = nil
+	t := NewBuilder().
+		RetryParallel(1, time.Millisecond,
+			flakyTask("r0", 0, &executed[0]),
+			flakyTask("r1", 1, &executed[1]),
+			flakyTask("r2", 1, &executed[2]),
+		).
+		Build()
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(l.lines, DeepEquals, []string{
+		"INFO + [Parallel] - r0", "INFO + [Parallel] - r1", "INFO + [Parallel] - r2",
+		"WARN 2 of 3 tasks failed, retrying them in 1ms (1/1)",
+		"INFO + [Parallel] - r1", "INFO + [Parallel] - r2",
+	})
+}
+
+func (s *taskSuite) TestParallelConcurrent(c *C) {
+	// the tasks finish in the reversed order when executed concurrently
+	tasks, order := orderedTasks(4, map[int]bool{1: true, 3: true})
+	err := NewBuilder().Parallel(tasks...).Build().Execute(NewContext())
+	c.Assert(err, ErrorMatches, "t3 failed")
+	c.Assert(order(), DeepEquals, []string{"t3", "t2", "t1", "t0"})
+}
+
+// inflightTasks returns the tasks recording the max number of them executed at the same time
+func inflightTasks(n int) ([]Task, func() int32) {
+	var running, max int32
+	var tasks []Task
+	for i := 0; i < n; i++ {
+		tasks = append(tasks, &Func{name: fmt.Sprintf("t%d", i), fn: func() error {
+			cur := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&max)
+				if cur <= old || atomic.CompareAndSwapInt32(&max, old, cur) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		}})
+	}
+	return tasks, func() int32 { return atomic.LoadInt32(&max) }
+}
+
+func (s *taskSuite) TestParallelConcurrency(c *C) {
+	tasks, max := inflightTasks(6)
+	c.Assert(NewBuilder().Concurrency(2).Parallel(tasks...).Build().Execute(NewContext()), IsNil)
+	c.Assert(max(), Equals, int32(2))
+
+	tasks, max = inflightTasks(6)
+	c.Assert(NewBuilder().Parallel(tasks...).Build().Execute(NewContext()), IsNil)
+	c.Assert(max(), Equals, int32(6))
+
+	tasks, max = inflightTasks(6)
+	c.Assert(NewBuilder().Concurrency(3).RetryParallel(1, time.Millisecond, tasks...).Build().Execute(NewContext()), IsNil)
+	c.Assert(max(), Equals, int32(3))
+}
+
+func (s *taskSuite) TestContextConcurrency(c *C) {
+	ctx := NewContext()
+	ctx.SetConcurrency(2)
+
+	tasks, max := inflightTasks(6)
+	c.Assert(NewBuilder().Parallel(tasks...).Build().Execute(ctx), IsNil)
+	c.Assert(max(), Equals, int32(2))
+
+	// the limit of the builder takes precedence
+	tasks, max = inflightTasks(6)
+	c.Assert(NewBuilder().Concurrency(3).Parallel(tasks...).Build().Execute(ctx), IsNil)
+	c.Assert(max(), Equals, int32(3))
+
+	step := NewBuilder().Func("step", func() error { return nil }).BuildAsStep("step")
+	t := NewBuilder().
+		Parallel().
+		ParallelStep("+ steps", step).
+		CheckReachability([]string{"172.16.5.140"}, nil, 0).
+		Build().(*Serial)
+	c.Assert(t.inner[0].(*Parallel).concurrency, Equals, 0)
+	c.Assert(t.inner[1].(*ParallelStepDisplay).inner.concurrency, Equals, 0)
+	c.Assert(t.inner[2].(*CheckReachability).concurrency, Equals, 0)
+	t = NewBuilder().Concurrency(2).Parallel().Build().(*Serial)
+	c.Assert(t.inner[0].(*Parallel).concurrency, Equals, 2)
+}
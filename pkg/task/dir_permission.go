@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+// DirPermission is used to enforce the owner and mode of directories on the target host,
+// the directories which are not as expected are reported and then fixed
+type DirPermission struct {
+	user string
+	host string
+	mode string
+	dirs []string
+
+	// directories found with wrong owner or mode before fixing
+	mismatched []string
+}
+
+// dirStat is the owner and mode of a directory read from the remote host
+type dirStat struct {
+	owner string
+	mode  string
+}
+
+// Execute implements the Task interface
+func (m *DirPermission) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(m.host)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	dirs := m.managedDirs()
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	stats, err := m.stat(ctx, dirs)
+	if err != nil {
+		return err
+	}
+	m.mismatched = m.mismatched[:0]
+	for _, dir := range dirs {
+		if !m.expected(stats[dir]) {
+			m.mismatched = append(m.mismatched, dir)
+		}
+	}
+
+	// Always apply the expected owner and mode, it's idempotent. The group is set to the primary
+	// group of the user, which is not always named after the user
+	cmd := fmt.Sprintf("chown -R %s:$(id -gn %s) %s && chmod %s %s",
+		m.user, m.user, strings.Join(dirs, " "), m.mode, strings.Join(dirs, " "))
+	if _, _, err := exec.Execute(cmd, true); err != nil {
+		return errors.Trace(err)
+	}
+
+	// Nothing is really changed when planning
+	if ctx.Planning() {
+		return nil
+	}
+
+	for _, dir := range m.mismatched {
+		st := stats[dir]
+		log.Warnf("Fixed permission of %s:%s, owner=%s, mode=%s", m.host, dir, st.owner, st.mode)
+	}
+
+	// Read back to verify the result
+	stats, err = m.stat(ctx, dirs)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if st := stats[dir]; !m.expected(st) {
+			return errors.Errorf("permission of %s:%s is still not as expected after fixing, owner=%s, mode=%s",
+				m.host, dir, st.owner, st.mode)
+		}
+	}
+
+	return nil
+}
+
+// Mismatched returns the directories which had wrong owner or mode before being fixed
+func (m *DirPermission) Mismatched() []string {
+	return m.mismatched
+}
+
+func (m *DirPermission) managedDirs() []string {
+	var dirs []string
+	for _, dir := range m.dirs {
+		if dir == "" {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// expected returns whether the directory is owned by the user with the mode, the group is not
+// compared as it's not always named after the user
+func (m *DirPermission) expected(st dirStat) bool {
+	return strings.SplitN(st.owner, ":", 2)[0] == m.user &&
+		strings.TrimLeft(st.mode, "0") == strings.TrimLeft(m.mode, "0")
+}
+
+// stat reads the owner and mode of dirs, the output of stat is as following:
+// tidb:tidb 755 /home/tidb/deploy
+func (m *DirPermission) stat(ctx *Context, dirs []string) (map[string]dirStat, error) {
+	exec, found := ctx.GetExecutor(m.host)
+	if !found {
+		return nil, ErrNoExecutor
+	}
+
+	cmd := fmt.Sprintf("stat -c '%%U:%%G %%a %%n' %s", strings.Join(dirs, " "))
+	stdout, _, err := exec.Execute(cmd, true)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to stat directories on %s", m.host)
+	}
+
+	stats := make(map[string]dirStat)
+	for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stats[fields[2]] = dirStat{owner: fields[0], mode: fields[1]}
+	}
+	return stats, nil
+}
+
+// Rollback implements the Task interface
+func (m *DirPermission) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (m *DirPermission) String() string {
+	return fmt.Sprintf("DirPermission: host=%s, user=%s, mode=%s, directories='%s'",
+		m.host, m.user, m.mode, strings.Join(m.dirs, "','"))
+}
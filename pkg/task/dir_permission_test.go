@@ -0,0 +1,77 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestDirPermission(c *C) {
+	fixed := false
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case strings.HasPrefix(cmd, "stat"):
+			if fixed {
+				return []byte("tidb:tidb 755 /data/deploy\ntidb:users 755 /data/log\n"), nil, nil
+			}
+			// the login group of the user is not always named after it
+			return []byte("root:root 700 /data/deploy\ntidb:users 755 /data/log\n"), nil, nil
+		case strings.HasPrefix(cmd, "chown"):
+			fixed = true
+		}
+		return nil, nil, nil
+	}}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &DirPermission{
+		user: "tidb",
+		host: "172.16.5.140",
+		mode: "755",
+		dirs: []string{"/data/deploy", "", "/data/log"},
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Mismatched(), DeepEquals, []string{"/data/deploy"})
+
+	cmds := e.commands()
+	c.Assert(cmds, HasLen, 3)
+	c.Assert(cmds[0], Equals, "stat -c '%U:%G %a %n' /data/deploy /data/log")
+	c.Assert(cmds[1], Equals, "chown -R tidb:$(id -gn tidb) /data/deploy /data/log && chmod 755 /data/deploy /data/log")
+	c.Assert(cmds[2], Equals, cmds[0])
+
+	// run again and nothing should be reported
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Mismatched(), HasLen, 0)
+}
+
+func (s *taskSuite) TestDirPermissionVerifyFailed(c *C) {
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "stat") {
+			return []byte("root:root 700 /data/deploy\n"), nil, nil
+		}
+		return nil, nil, nil
+	}}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := &DirPermission{
+		user: "tidb",
+		host: "172.16.5.140",
+		mode: "755",
+		dirs: []string{"/data/deploy"},
+	}
+	err := t.Execute(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Matches, ".*still not as expected.*")
+}
@@ -16,12 +16,16 @@ package task
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
 	tiupmeta "github.com/pingcap-incubator/tiup/pkg/meta"
 	"github.com/pingcap-incubator/tiup/pkg/repository"
-	"github.com/pingcap-incubator/tiup/pkg/utils"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 )
 
@@ -33,7 +37,7 @@ type Downloader struct {
 }
 
 // Execute implements the Task interface
-func (d *Downloader) Execute(_ *Context) error {
+func (d *Downloader) Execute(ctx *Context) error {
 	if d.component == "" {
 		return errors.New("component name not specified")
 	}
@@ -44,10 +48,27 @@ func (d *Downloader) Execute(_ *Context) error {
 	resName := fmt.Sprintf("%s-%s", d.component, d.version)
 	fileName := fmt.Sprintf("%s-linux-amd64.tar.gz", resName)
 	sha1File := fmt.Sprintf("%s-linux-amd64.sha1", resName)
+	sigFile := fmt.Sprintf("%s-linux-amd64.sig", resName)
 	srcPath := meta.ProfilePath(meta.TiOpsPackageCacheDir, fileName)
 
+	// The source is resolved and verified even if the package is cached
+	if err := ctx.recordArtifactSource(d.component, d.version.String(), artifactURL(tiupmeta.Mirror(), fileName)); err != nil {
+		return err
+	}
+
+	if ctx.Offline() {
+		if tiuputils.IsNotExist(srcPath) {
+			return ErrOfflineCacheMiss.
+				New("The package %s is not cached in %s and can't be downloaded in the offline mode", fileName, meta.ProfilePath(meta.TiOpsPackageCacheDir)).
+				WithProperty(cliutil.SuggestionFromString(offlineSuggestion))
+		}
+		// the cached nightly package is used as it can't be refreshed
+		return d.verifySignature(ctx, fileName, sigFile, false)
+	}
+
 	// Download from repository if not exists
-	if d.version.IsNightly() || utils.IsNotExist(srcPath) {
+	refresh := d.version.IsNightly() || tiuputils.IsNotExist(srcPath)
+	if refresh {
 		options := repository.MirrorOptions{
 			Progress: repository.DisableProgress{},
 		}
@@ -74,6 +95,19 @@ func (d *Downloader) Execute(_ *Context) error {
 			return errors.Errorf("component '%s' doesn't contains version '%s'", d.component, d.version)
 		}
 
+		// the packages are downloaded resumably from the HTTP mirrors, which are slow for
+		// the large packages usually
+		if mirrorURL := tiupmeta.Mirror(); strings.HasPrefix(mirrorURL, "http://") || strings.HasPrefix(mirrorURL, "https://") {
+			// the nightly package may be changed since the interrupted download
+			if d.version.IsNightly() {
+				_ = os.Remove(srcPath + utils.PartialSuffix)
+			}
+			if err := downloadResumable(mirrorURL, fileName, sha1File, srcPath); err != nil {
+				return err
+			}
+			return d.verifySignature(ctx, fileName, sigFile, refresh)
+		}
+
 		err = repo.Mirror().Download(fileName, meta.ProfilePath(meta.TiOpsPackageCacheDir))
 		if err != nil {
 			return errors.AddStack(err)
@@ -95,7 +129,7 @@ func (d *Downloader) Execute(_ *Context) error {
 			return errors.Trace(err)
 		}
 
-		err = utils.CheckSHA(file, string(sha))
+		err = tiuputils.CheckSHA(file, string(sha))
 		_ = file.Close()
 
 		if err != nil {
@@ -105,7 +139,44 @@ func (d *Downloader) Execute(_ *Context) error {
 		}
 	}
 
-	return nil
+	return d.verifySignature(ctx, fileName, sigFile, refresh)
+}
+
+// verifySignature verifies the cached package against its signature if the signature check
+// is enabled, the signature is fetched again if the package is just downloaded
+func (d *Downloader) verifySignature(ctx *Context, fileName, sigFile string, refresh bool) error {
+	if !ctx.SignatureCheck() {
+		return nil
+	}
+	return ctx.verifyArtifact(tiupmeta.Mirror(), meta.ProfilePath(meta.TiOpsPackageCacheDir), fileName, sigFile, refresh)
+}
+
+// downloadResumable downloads the package from the HTTP mirror to dst. The package is
+// kept as a partial file until its checksum is verified against the sha1 file, so an
+// interrupted download is continued by the next one rather than started over.
+func downloadResumable(mirror, fileName, sha1File, dst string) error {
+	client := &http.Client{}
+	res, err := client.Get(artifactURL(mirror, sha1File))
+	if err != nil {
+		return errors.Annotatef(err, "failed to download %s", sha1File)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to download %s: %s", sha1File, res.Status)
+	}
+	sha, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Annotatef(err, "failed to download %s", sha1File)
+	}
+
+	return utils.DownloadResumable(client, artifactURL(mirror, fileName), dst, func(path string) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer file.Close()
+		return tiuputils.CheckSHA(file, string(sha))
+	})
 }
 
 // Rollback implements the Task interface
@@ -14,6 +14,8 @@
 package task
 
 import (
+	"errors"
+
 	ev "github.com/asaskevich/EventBus"
 	"go.uber.org/zap"
 )
@@ -21,6 +23,7 @@ import (
 // EventBus is an event bus for task events.
 type EventBus struct {
 	eventBus ev.Bus
+	changeID string
 }
 
 // EventKind is the task event kind.
@@ -33,6 +36,10 @@ const (
 	EventTaskFinish EventKind = "task_finish"
 	// EventTaskProgress is emitted when a task has made some progress.
 	EventTaskProgress EventKind = "task_progress"
+	// EventOperationBegin is emitted when an operation on a cluster begins.
+	EventOperationBegin EventKind = "operation_begin"
+	// EventOperationFinish is emitted when an operation on a cluster finishes.
+	EventOperationFinish EventKind = "operation_finish"
 )
 
 // NewEventBus creates a new EventBus.
@@ -48,9 +55,17 @@ func (ev *EventBus) PublishTaskBegin(task Task) {
 	ev.eventBus.Publish(string(EventTaskBegin), task)
 }
 
+// errTaskSucceeded is published instead of a nil error in the TaskFinish events, as the
+// underlying bus can't pass a nil interface to the handlers
+var errTaskSucceeded = errors.New("task succeeded")
+
 // PublishTaskFinish publishes a TaskFinish event. This should be called only by Parallel or Serial.
+// The handlers receive errTaskSucceeded if the task succeeded.
 func (ev *EventBus) PublishTaskFinish(task Task, err error) {
 	zap.L().Debug("TaskFinish", zap.String("task", task.String()), zap.Error(err))
+	if err == nil {
+		err = errTaskSucceeded
+	}
 	ev.eventBus.Publish(string(EventTaskFinish), task, err)
 }
 
@@ -60,6 +75,32 @@ func (ev *EventBus) PublishTaskProgress(task Task, progress string) {
 	ev.eventBus.Publish(string(EventTaskProgress), task, progress)
 }
 
+// PublishOperationBegin publishes an OperationBegin event.
+func (ev *EventBus) PublishOperationBegin(operation, cluster string) {
+	zap.L().Debug("OperationBegin", zap.String("operation", operation), zap.String("cluster", cluster))
+	ev.eventBus.Publish(string(EventOperationBegin), operation, cluster)
+}
+
+// PublishOperationFinish publishes an OperationFinish event.
+// The handlers receive errTaskSucceeded if the operation succeeded.
+func (ev *EventBus) PublishOperationFinish(operation, cluster string, err error) {
+	zap.L().Debug("OperationFinish", zap.String("operation", operation), zap.String("cluster", cluster), zap.Error(err))
+	if err == nil {
+		err = errTaskSucceeded
+	}
+	ev.eventBus.Publish(string(EventOperationFinish), operation, cluster, err)
+}
+
+// SetChangeID sets the id of the change which the events are published for.
+func (ev *EventBus) SetChangeID(id string) {
+	ev.changeID = id
+}
+
+// ChangeID returns the id of the change which the events are published for.
+func (ev *EventBus) ChangeID() string {
+	return ev.changeID
+}
+
 // Subscribe subscribes events.
 func (ev *EventBus) Subscribe(eventName EventKind, handler interface{}) {
 	err := ev.eventBus.Subscribe(string(eventName), handler)
@@ -0,0 +1,235 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// eventSocketBuffer is the number of frames buffered for each client, the client
+	// is disconnected if it's too slow to keep the buffer from being filled
+	eventSocketBuffer = 1024
+	// eventSocketWriteTimeout is the timeout of writing a frame to a client
+	eventSocketWriteTimeout = 5 * time.Second
+)
+
+// EventFrame is a task event sent to the clients of the event socket as a line of JSON
+type EventFrame struct {
+	Kind     EventKind `json:"kind"`
+	Task     string    `json:"task"`
+	Error    string    `json:"error,omitempty"`
+	Category string    `json:"category,omitempty"`
+	ChangeID string    `json:"change_id,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// EventSocket serves the task begin and finish events of the contexts attached to it
+// over a Unix domain socket, each event is written as a newline-delimited JSON frame
+// to all the clients connected. Executing tasks is never blocked by the clients, the
+// slow clients are disconnected.
+type EventSocket struct {
+	path     string
+	listener net.Listener
+
+	mu       sync.Mutex
+	clients  map[*eventClient]struct{}
+	attached map[*Context]*socketHandlers
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// socketHandlers are the handlers subscribed to the events of a context, they're
+// kept to be unsubscribed on detaching
+type socketHandlers struct {
+	begin  func(task Task)
+	finish func(task Task, err error)
+}
+
+type eventClient struct {
+	conn   net.Conn
+	frames chan []byte
+	once   sync.Once
+}
+
+func (c *eventClient) close() {
+	c.once.Do(func() {
+		close(c.frames)
+	})
+}
+
+// NewEventSocket listens on the Unix domain socket at path, a stale socket file is removed
+func NewEventSocket(path string) (*EventSocket, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Annotatef(err, "failed to remove stale socket %s", path)
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to listen on %s", path)
+	}
+
+	s := &EventSocket{
+		path:     path,
+		listener: listener,
+		clients:  make(map[*eventClient]struct{}),
+		attached: make(map[*Context]*socketHandlers),
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Path returns the path of the socket
+func (s *EventSocket) Path() string {
+	return s.path
+}
+
+func (s *EventSocket) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			// the listener is closed
+			return
+		}
+
+		c := &eventClient{conn: conn, frames: make(chan []byte, eventSocketBuffer)}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+// serve writes the frames to the client until the client is closed or fails to be written
+func (s *EventSocket) serve(c *eventClient) {
+	defer s.wg.Done()
+	defer c.conn.Close()
+	for frame := range c.frames {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(eventSocketWriteTimeout))
+		if _, err := c.conn.Write(frame); err != nil {
+			zap.L().Debug("Disconnect event socket client", zap.Error(err))
+			s.remove(c)
+			// drain the frames left so the broadcasting never blocks
+			for range c.frames {
+			}
+			return
+		}
+	}
+}
+
+func (s *EventSocket) remove(c *eventClient) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	c.close()
+}
+
+// broadcast sends the event to all clients without blocking
+func (s *EventSocket) broadcast(frame EventFrame) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		zap.L().Debug("Marshal event frame", zap.Error(err))
+		return
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.frames <- data:
+		default:
+			// the client is too slow
+			zap.L().Debug("Disconnect slow event socket client")
+			delete(s.clients, c)
+			c.close()
+		}
+	}
+}
+
+func (s *EventSocket) handleTaskBegin(ev *EventBus, task Task) {
+	s.broadcast(EventFrame{Kind: EventTaskBegin, Task: task.String(), ChangeID: ev.ChangeID(), Time: time.Now()})
+}
+
+func (s *EventSocket) handleTaskFinish(ev *EventBus, task Task, err error) {
+	frame := EventFrame{Kind: EventTaskFinish, Task: task.String(), ChangeID: ev.ChangeID(), Time: time.Now()}
+	if err != errTaskSucceeded && err != nil {
+		frame.Error = err.Error()
+		frame.Category = string(errutil.CategoryOf(err))
+	}
+	s.broadcast(frame)
+}
+
+// Attach subscribes the task events of the context
+func (s *EventSocket) Attach(ctx *Context) {
+	h := &socketHandlers{
+		begin:  func(task Task) { s.handleTaskBegin(&ctx.ev, task) },
+		finish: func(task Task, err error) { s.handleTaskFinish(&ctx.ev, task, err) },
+	}
+	s.mu.Lock()
+	s.attached[ctx] = h
+	s.mu.Unlock()
+	ctx.ev.Subscribe(EventTaskBegin, h.begin)
+	ctx.ev.Subscribe(EventTaskFinish, h.finish)
+}
+
+// Detach unsubscribes the task events of the context
+func (s *EventSocket) Detach(ctx *Context) {
+	s.mu.Lock()
+	h, found := s.attached[ctx]
+	delete(s.attached, ctx)
+	s.mu.Unlock()
+	if !found {
+		return
+	}
+	ctx.ev.Unsubscribe(EventTaskFinish, h.finish)
+	ctx.ev.Unsubscribe(EventTaskBegin, h.begin)
+}
+
+// Close stops accepting new clients, disconnects all clients after the frames
+// buffered are written, and removes the socket file
+func (s *EventSocket) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		delete(s.clients, c)
+		c.close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	if err != nil {
+		return errors.Annotatef(err, "failed to close %s", s.path)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	. "github.com/pingcap/check"
+)
+
+// waitClients waits until the number of clients connected to the socket is n
+func waitClients(c *C, s *EventSocket, n int) {
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		count := len(s.clients)
+		s.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("timed out waiting for %d clients", n)
+}
+
+// readFrames reads all frames from the connection until it's closed by the socket
+func readFrames(c *C, conn net.Conn) <-chan []EventFrame {
+	ch := make(chan []EventFrame, 1)
+	go func() {
+		var frames []EventFrame
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var frame EventFrame
+			c.Check(json.Unmarshal(scanner.Bytes(), &frame), IsNil)
+			frames = append(frames, frame)
+		}
+		ch <- frames
+	}()
+	return ch
+}
+
+func (s *taskSuite) TestEventSocket(c *C) {
+	dir, err := ioutil.TempDir("", "event-socket")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.sock")
+	// a stale socket file left by a crashed process is replaced
+	stale, err := net.Listen("unix", path)
+	c.Assert(err, IsNil)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	socket, err := NewEventSocket(path)
+	c.Assert(err, IsNil)
+
+	var results []<-chan []EventFrame
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", path)
+		c.Assert(err, IsNil)
+		defer conn.Close()
+		results = append(results, readFrames(c, conn))
+	}
+	waitClients(c, socket, 2)
+
+	ctx := NewContext()
+	socket.Attach(ctx)
+	t := NewBuilder().
+		Func("first", func() error { return nil }).
+		Func("second", func() error { return errutil.Classify(errors.New("second failed"), errutil.CategoryComponent) }).
+		Build()
+	c.Assert(t.Execute(ctx), NotNil)
+	socket.Detach(ctx)
+	c.Assert(socket.Close(), IsNil)
+
+	for _, result := range results {
+		frames := <-result
+		c.Assert(frames, HasLen, 4)
+		var kinds, tasks, errs, categories []string
+		for _, frame := range frames {
+			kinds = append(kinds, string(frame.Kind))
+			tasks = append(tasks, frame.Task)
+			errs = append(errs, frame.Error)
+			categories = append(categories, frame.Category)
+			c.Assert(frame.Time.IsZero(), IsFalse)
+		}
+		c.Assert(kinds, DeepEquals, []string{"task_begin", "task_finish", "task_begin", "task_finish"})
+		c.Assert(tasks, DeepEquals, []string{"first", "first", "second", "second"})
+		c.Assert(errs, DeepEquals, []string{"", "", "", "second failed"})
+		c.Assert(categories, DeepEquals, []string{"", "", "", "component"})
+	}
+
+	// the socket file is removed
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+func (s *taskSuite) TestEventSocketChangeID(c *C) {
+	dir, err := ioutil.TempDir("", "event-socket")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	socket, err := NewEventSocket(filepath.Join(dir, "events.sock"))
+	c.Assert(err, IsNil)
+	conn, err := net.Dial("unix", socket.Path())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	result := readFrames(c, conn)
+	waitClients(c, socket, 1)
+
+	// the contexts attached are told apart
+	stamped := NewContext()
+	stamped.SetChangeID("CHG-1024")
+	defer stamped.SetChangeID("")
+	c.Assert(stamped.ChangeID(), Equals, "CHG-1024")
+	plain := NewContext()
+	socket.Attach(stamped)
+	socket.Attach(plain)
+	c.Assert(NewBuilder().Func("stamped", func() error { return nil }).Build().Execute(stamped), IsNil)
+	c.Assert(NewBuilder().Func("plain", func() error { return nil }).Build().Execute(plain), IsNil)
+	socket.Detach(stamped)
+	c.Assert(NewBuilder().Func("detached", func() error { return nil }).Build().Execute(stamped), IsNil)
+	socket.Detach(plain)
+	c.Assert(socket.Close(), IsNil)
+
+	frames := <-result
+	c.Assert(frames, HasLen, 4)
+	for _, frame := range frames[:2] {
+		c.Assert(frame.Task, Equals, "stamped")
+		c.Assert(frame.ChangeID, Equals, "CHG-1024")
+	}
+	for _, frame := range frames[2:] {
+		c.Assert(frame.Task, Equals, "plain")
+		c.Assert(frame.ChangeID, Equals, "")
+	}
+}
+
+func (s *taskSuite) TestEventSocketSlowClient(c *C) {
+	dir, err := ioutil.TempDir("", "event-socket")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	socket, err := NewEventSocket(filepath.Join(dir, "events.sock"))
+	c.Assert(err, IsNil)
+	defer socket.Close()
+
+	// the client never reads
+	conn, err := net.Dial("unix", socket.Path())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	waitClients(c, socket, 1)
+
+	ctx := NewContext()
+	socket.Attach(ctx)
+	defer socket.Detach(ctx)
+
+	builder := NewBuilder()
+	for i := 0; i < 4*eventSocketBuffer; i++ {
+		builder.Func("a task with a long name to fill the buffer of the socket quickly", func() error { return nil })
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- builder.Build().Execute(ctx)
+	}()
+	select {
+	case err := <-done:
+		c.Assert(err, IsNil)
+	case <-time.After(10 * time.Second):
+		c.Fatal("executing tasks is blocked by the slow client")
+	}
+
+	// the slow client is disconnected
+	waitClients(c, socket, 0)
+}
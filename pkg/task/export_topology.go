@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+// ExportTopology is used to write the current topology of the cluster to a YAML file
+type ExportTopology struct {
+	cluster string
+	topo    *meta.Specification
+	path    string
+}
+
+// Execute implements the Task interface
+func (e *ExportTopology) Execute(ctx *Context) error {
+	data, err := meta.ExportTopology(e.cluster, e.topo)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(e.path, data, 0644); err != nil {
+		return errors.Annotatef(err, "failed to write topology to %s", e.path)
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (e *ExportTopology) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (e *ExportTopology) String() string {
+	return fmt.Sprintf("ExportTopology: cluster=%s, path=%s", e.cluster, e.path)
+}
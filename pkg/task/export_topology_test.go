@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestExportTopology(c *C) {
+	dir, err := ioutil.TempDir("", "export")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	topo := &meta.Specification{}
+	err = yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.138
+tikv_servers:
+  - host: 172.16.5.139
+pd_servers:
+  - host: 172.16.5.140
+`), topo)
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(dir, "topology.yaml")
+	t := NewBuilder().ExportTopology("test", topo, path).Build()
+	c.Assert(t.Execute(NewContext()), IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	exported := &meta.Specification{}
+	c.Assert(yaml.Unmarshal(data, exported), IsNil)
+	c.Assert(exported, DeepEquals, topo)
+}
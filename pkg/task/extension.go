@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+// Phase is a point of the built-in operations where the tasks of the extensions are executed
+type Phase string
+
+const (
+	// PhasePreDeploy is after the hosts are prepared and before the components are copied
+	PhasePreDeploy Phase = "pre-deploy"
+	// PhasePostStart is after the instances are started
+	PhasePostStart Phase = "post-start"
+	// PhasePreStop is before the instances are stopped
+	PhasePreStop Phase = "pre-stop"
+)
+
+// Extension contributes the custom tasks to the built-in operations, e.g. registering the
+// nodes to an external inventory. Tasks is called at each phase with the context and the
+// instances operated on, and the tasks returned are executed serially in place, an error
+// either returned or got from the tasks aborts the operation.
+type Extension interface {
+	// Name identifies the extension, it must be unique among the registered ones
+	Name() string
+	// Tasks returns the tasks executed at the phase, nil if there's nothing to do
+	Tasks(phase Phase, ctx *Context, instances []meta.Instance) ([]Task, error)
+}
+
+var extensions struct {
+	sync.Mutex
+	registered []Extension
+}
+
+// RegisterExtension registers the extension, the tasks of the extensions are executed at
+// a phase in the order they are registered
+func RegisterExtension(ext Extension) error {
+	extensions.Lock()
+	defer extensions.Unlock()
+	for _, e := range extensions.registered {
+		if e.Name() == ext.Name() {
+			return errors.Errorf("extension %s is already registered", ext.Name())
+		}
+	}
+	extensions.registered = append(extensions.registered, ext)
+	return nil
+}
+
+// UnregisterExtension removes the extension by the name, it's a no-op if it's not registered
+func UnregisterExtension(name string) {
+	extensions.Lock()
+	defer extensions.Unlock()
+	for i, e := range extensions.registered {
+		if e.Name() == name {
+			extensions.registered = append(extensions.registered[:i], extensions.registered[i+1:]...)
+			return
+		}
+	}
+}
+
+// registeredExtensions returns a snapshot of the registered extensions
+func registeredExtensions() []Extension {
+	extensions.Lock()
+	defer extensions.Unlock()
+	return append([]Extension{}, extensions.registered...)
+}
+
+// ExtensionPhase is used to execute the tasks of the registered extensions at the phase
+type ExtensionPhase struct {
+	phase     Phase
+	instances []meta.Instance
+}
+
+// Execute implements the Task interface
+func (e *ExtensionPhase) Execute(ctx *Context) error {
+	for _, ext := range registeredExtensions() {
+		tasks, err := ext.Tasks(e.phase, ctx, e.instances)
+		if err != nil {
+			return errors.Annotatef(err, "extension %s failed at %s", ext.Name(), e.phase)
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+		if err := (&Serial{inner: tasks}).Execute(ctx); err != nil {
+			return errors.Annotatef(err, "extension %s failed at %s", ext.Name(), e.phase)
+		}
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (e *ExtensionPhase) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (e *ExtensionPhase) String() string {
+	ids := make([]string, 0, len(e.instances))
+	for _, inst := range e.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("ExtensionPhase: phase=%s, instances=%s", e.phase, strings.Join(ids, ","))
+}
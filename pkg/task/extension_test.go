@@ -0,0 +1,130 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// inventoryTask is a custom task registering the instances to an external inventory
+type inventoryTask struct {
+	action string
+	ids    []string
+	events *[]string
+}
+
+func (t *inventoryTask) Execute(ctx *Context) error {
+	for _, id := range t.ids {
+		*t.events = append(*t.events, fmt.Sprintf("%s %s", t.action, id))
+	}
+	return nil
+}
+
+func (t *inventoryTask) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+func (t *inventoryTask) String() string {
+	return "Inventory: " + t.action
+}
+
+type inventoryExtension struct {
+	name   string
+	events *[]string
+	err    error
+}
+
+func (e *inventoryExtension) Name() string {
+	return e.name
+}
+
+func (e *inventoryExtension) Tasks(phase Phase, ctx *Context, instances []meta.Instance) ([]Task, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	var ids []string
+	for _, inst := range instances {
+		ids = append(ids, inst.ID())
+	}
+	switch phase {
+	case PhasePreStop:
+		return []Task{&inventoryTask{action: "drain", ids: ids, events: e.events}}, nil
+	case PhasePostStart:
+		return []Task{&inventoryTask{action: "register", ids: ids, events: e.events}}, nil
+	}
+	return nil, nil
+}
+
+func extensionInstances(c *C) []meta.Instance {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+`), topo), IsNil)
+	var insts []meta.Instance
+	topo.IterInstance(func(inst meta.Instance) {
+		insts = append(insts, inst)
+	})
+	return insts
+}
+
+func (s *taskSuite) TestExtension(c *C) {
+	var events []string
+	c.Assert(RegisterExtension(&inventoryExtension{name: "inventory", events: &events}), IsNil)
+	defer UnregisterExtension("inventory")
+	c.Assert(RegisterExtension(&inventoryExtension{name: "inventory"}), ErrorMatches, "extension inventory is already registered")
+
+	insts := extensionInstances(c)
+	t := NewBuilder().
+		Extensions(PhasePreDeploy, insts).
+		Extensions(PhasePreStop, insts[:1]).
+		Func("stop", func() error { events = append(events, "stop"); return nil }).
+		Func("start", func() error { events = append(events, "start"); return nil }).
+		Extensions(PhasePostStart, insts).
+		Build()
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(events, DeepEquals, []string{
+		"drain 172.16.5.140:20160",
+		"stop",
+		"start",
+		"register 172.16.5.140:20160",
+		"register 172.16.5.141:20160",
+	})
+
+	// nothing is executed after unregistering
+	events = nil
+	UnregisterExtension("inventory")
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(events, DeepEquals, []string{"stop", "start"})
+}
+
+func (s *taskSuite) TestExtensionFailed(c *C) {
+	c.Assert(RegisterExtension(&inventoryExtension{name: "broken", err: errors.New("inventory unavailable")}), IsNil)
+	defer UnregisterExtension("broken")
+
+	started := false
+	t := NewBuilder().
+		Extensions(PhasePreStop, extensionInstances(c)).
+		Func("stop", func() error { started = true; return nil }).
+		Build()
+	err := t.Execute(NewContext())
+	c.Assert(err, ErrorMatches, "extension broken failed at pre-stop: inventory unavailable")
+	c.Assert(started, IsFalse)
+}
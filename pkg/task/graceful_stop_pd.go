@@ -0,0 +1,62 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+)
+
+// GracefulStopPD is used to stop a PD instance, the leadership is transferred to a healthy
+// member not in stopping before stopping if the instance is the leader of the PD cluster,
+// see operator.TransferPDLeader for the details.
+type GracefulStopPD struct {
+	pdList   []string
+	instance *meta.PDInstance
+	stopping []string
+	retryOpt *utils.RetryOption
+
+	// the member which the leadership is transferred to, empty if no transfer happened
+	transferredTo string
+}
+
+// Execute implements the Task interface
+func (s *GracefulStopPD) Execute(ctx *Context) error {
+	target, err := operator.TransferPDLeader(s.pdList, s.instance, s.stopping, s.retryOpt)
+	if err != nil {
+		return err
+	}
+	s.transferredTo = target
+
+	return operator.StopComponent(ctx, []meta.Instance{s.instance})
+}
+
+// TransferredTo returns the member which the leadership was transferred to
+func (s *GracefulStopPD) TransferredTo() string {
+	return s.transferredTo
+}
+
+// Rollback implements the Task interface
+func (s *GracefulStopPD) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (s *GracefulStopPD) String() string {
+	return fmt.Sprintf("GracefulStopPD: name=%s, host=%s, stopping=%s", s.instance.Name, s.instance.GetHost(), strings.Join(s.stopping, ","))
+}
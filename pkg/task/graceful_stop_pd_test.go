@@ -0,0 +1,170 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// mockPD serves the members, health, leader and leader transfer API of PD
+type mockPD struct {
+	sync.Mutex
+	members   []string
+	down      []string
+	leader    string
+	transfers []string
+}
+
+func (m *mockPD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+
+	switch {
+	case r.URL.Path == "/pd/api/v1/members":
+		resp := pdpb.GetMembersResponse{}
+		for _, name := range m.members {
+			member := &pdpb.Member{Name: name}
+			resp.Members = append(resp.Members, member)
+			if name == m.leader {
+				resp.Leader = member
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case r.URL.Path == "/pd/health":
+		var healths []map[string]interface{}
+		for _, name := range m.members {
+			healthy := true
+			for _, down := range m.down {
+				healthy = healthy && name != down
+			}
+			healths = append(healths, map[string]interface{}{"name": name, "health": healthy})
+		}
+		_ = json.NewEncoder(w).Encode(healths)
+	case r.URL.Path == "/pd/api/v1/leader":
+		_ = json.NewEncoder(w).Encode(pdpb.Member{Name: m.leader})
+	case strings.HasPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/") && r.Method == http.MethodPost:
+		m.leader = strings.TrimPrefix(r.URL.Path, "/pd/api/v1/leader/transfer/")
+		m.transfers = append(m.transfers, m.leader)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newGracefulStopPD(c *C, srv *httptest.Server, topology string) (*GracefulStopPD, *mockExecutor, *Context) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(topology), &topo), IsNil)
+
+	e := &mockExecutor{}
+	ctx := newMockContext("172.16.5.140", e)
+	inst := (&meta.PDComponent{Specification: &topo}).Instances()[0].(*meta.PDInstance)
+	t := &GracefulStopPD{
+		pdList:   []string{strings.TrimPrefix(srv.URL, "http://")},
+		instance: inst,
+		retryOpt: &utils.RetryOption{Delay: 10 * time.Millisecond, Timeout: time.Second},
+	}
+	return t, e, ctx
+}
+
+func (s *taskSuite) TestGracefulStopPDLeader(c *C) {
+	pd := &mockPD{members: []string{"pd-1", "pd-2", "pd-3"}, leader: "pd-1"}
+	srv := httptest.NewServer(pd)
+	defer srv.Close()
+	t, e, ctx := newGracefulStopPD(c, srv, `
+pd_servers:
+  - host: 172.16.5.140
+    name: pd-1
+  - host: 172.16.5.141
+    name: pd-2
+  - host: 172.16.5.142
+    name: pd-3
+`)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.TransferredTo(), Equals, "pd-2")
+	c.Assert(pd.transfers, DeepEquals, []string{"pd-2"})
+	c.Assert(pd.leader, Equals, "pd-2")
+	c.Assert(strings.Join(e.commands(), "\n"), Matches, "(?s).*systemctl stop pd-2379.service.*")
+}
+
+func (s *taskSuite) TestGracefulStopPDFollower(c *C) {
+	pd := &mockPD{members: []string{"pd-1", "pd-2"}, leader: "pd-2"}
+	srv := httptest.NewServer(pd)
+	defer srv.Close()
+	t, e, ctx := newGracefulStopPD(c, srv, `
+pd_servers:
+  - host: 172.16.5.140
+    name: pd-1
+  - host: 172.16.5.141
+    name: pd-2
+`)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.TransferredTo(), Equals, "")
+	c.Assert(pd.transfers, HasLen, 0)
+	c.Assert(strings.Join(e.commands(), "\n"), Matches, "(?s).*systemctl stop pd-2379.service.*")
+}
+
+func (s *taskSuite) TestGracefulStopPDSingleMember(c *C) {
+	pd := &mockPD{members: []string{"pd-1"}, leader: "pd-1"}
+	srv := httptest.NewServer(pd)
+	defer srv.Close()
+	t, e, ctx := newGracefulStopPD(c, srv, `
+pd_servers:
+  - host: 172.16.5.140
+    name: pd-1
+`)
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.TransferredTo(), Equals, "")
+	c.Assert(pd.transfers, HasLen, 0)
+	c.Assert(strings.Join(e.commands(), "\n"), Matches, "(?s).*systemctl stop pd-2379.service.*")
+}
+
+func (s *taskSuite) TestGracefulStopPDSkipStoppingAndDown(c *C) {
+	pd := &mockPD{members: []string{"pd-1", "pd-2", "pd-3", "pd-4"}, down: []string{"pd-3"}, leader: "pd-1"}
+	srv := httptest.NewServer(pd)
+	defer srv.Close()
+	t, _, ctx := newGracefulStopPD(c, srv, `
+pd_servers:
+  - host: 172.16.5.140
+    name: pd-1
+  - host: 172.16.5.141
+    name: pd-2
+  - host: 172.16.5.142
+    name: pd-3
+  - host: 172.16.5.143
+    name: pd-4
+`)
+	// pd-2 is stopped next and pd-3 is down
+	t.stopping = []string{"pd-1", "pd-2"}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.TransferredTo(), Equals, "pd-4")
+	c.Assert(pd.transfers, DeepEquals, []string{"pd-4"})
+
+	// no healthy member is kept running
+	pd.leader = "pd-1"
+	pd.transfers = nil
+	t.stopping = []string{"pd-1", "pd-2", "pd-4"}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.TransferredTo(), Equals, "")
+	c.Assert(pd.transfers, HasLen, 0)
+}
@@ -15,18 +15,25 @@ package task
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	tiuputils "github.com/pingcap-incubator/tiup/pkg/utils"
 	"github.com/pingcap/errors"
 )
 
 // InstallPackage is used to copy all files related the specific version a component
-// to the target directory of path
+// to the target directory of path. If incremental is enabled, the package is extracted
+// locally and only the files changed on the remote are transferred.
 type InstallPackage struct {
-	srcPath string
-	host    string
-	dstDir  string
+	srcPath     string
+	host        string
+	dstDir      string
+	incremental bool
 }
 
 // Execute implements the Task interface
@@ -38,6 +45,9 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 	}
 
 	dstDir := filepath.Join(c.dstDir, "bin")
+	if c.incremental {
+		return c.sync(exec, dstDir)
+	}
 	dstPath := filepath.Join(dstDir, path.Base(c.srcPath))
 
 	err := exec.Transfer(c.srcPath, dstPath, false)
@@ -54,6 +64,32 @@ func (c *InstallPackage) Execute(ctx *Context) error {
 	return nil
 }
 
+// sync extracts the package locally and syncs the files to the remote directory
+func (c *InstallPackage) sync(e executor.TiOpsExecutor, dstDir string) error {
+	local, err := ioutil.TempDir("", "tiup-package-")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	defer os.RemoveAll(local)
+
+	f, err := os.Open(c.srcPath)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	err = tiuputils.Untar(f, local)
+	f.Close()
+	if err != nil {
+		return errors.Annotatef(err, "failed to extract %s", c.srcPath)
+	}
+
+	stats, err := executor.SyncDir(e, local, dstDir)
+	if err != nil {
+		return err
+	}
+	log.Infof("\tSynced %s to %s:%s, %s", path.Base(c.srcPath), c.host, dstDir, stats)
+	return nil
+}
+
 // Rollback implements the Task interface
 func (c *InstallPackage) Rollback(ctx *Context) error {
 	return ErrUnsupportedRollback
@@ -61,5 +97,5 @@ func (c *InstallPackage) Rollback(ctx *Context) error {
 
 // String implements the fmt.Stringer interface
 func (c *InstallPackage) String() string {
-	return fmt.Sprintf("InstallPackage: srcPath=%s, remote=%s:%s", c.srcPath, c.host, c.dstDir)
+	return fmt.Sprintf("InstallPackage: srcPath=%s, remote=%s:%s, incremental=%v", c.srcPath, c.host, c.dstDir, c.incremental)
 }
@@ -0,0 +1,174 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+// logRotateDir is the directory of the logrotate configs
+const logRotateDir = "/etc/logrotate.d"
+
+// builtinRotation are the components which rotate their own logs, only the stderr logs
+// redirected by the run scripts are rotated by logrotate for them
+var builtinRotation = map[string]bool{
+	meta.ComponentTiDB:    true,
+	meta.ComponentTiKV:    true,
+	meta.ComponentPD:      true,
+	meta.ComponentTiFlash: true,
+	meta.ComponentTiProxy: true,
+	meta.ComponentPump:    true,
+	meta.ComponentDrainer: true,
+	meta.ComponentCDC:     true,
+}
+
+// LogRotateOptions is the retention of the rotated logs
+type LogRotateOptions struct {
+	MaxSize string // the size a log is rotated at, e.g: 100M
+	MaxAge  int    // the days the rotated logs are kept
+	Keep    int    // the max number of the rotated logs kept
+}
+
+// LogRotateEntry is the log directory of an instance to rotate
+type LogRotateEntry struct {
+	Host      string
+	Component string
+	Name      string // the name of the instance, e.g: tikv-20160
+	LogDir    string
+}
+
+// LogRotate is used to install the logrotate configs of the instances, which are validated
+// by logrotate after installed. The instances whose logs are not rotated, because logrotate
+// is absent or rejects the config, are reported.
+type LogRotate struct {
+	entries []LogRotateEntry
+	options LogRotateOptions
+
+	missing map[string]string // the reasons of the instances missing rotation by name@host
+}
+
+// Execute implements the Task interface
+func (l *LogRotate) Execute(ctx *Context) error {
+	l.missing = make(map[string]string)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for _, entry := range l.entries {
+		e, found := ctx.GetExecutor(entry.Host)
+		if !found {
+			return ErrNoExecutor
+		}
+		wg.Add(1)
+		go func(entry LogRotateEntry) {
+			defer wg.Done()
+			reason, err := l.install(e, entry)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			} else if reason != "" {
+				l.missing[entry.Name+"@"+entry.Host] = reason
+			}
+		}(entry)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if ctx.Planning() || len(l.missing) == 0 {
+		return nil
+	}
+
+	rows := [][]string{{"Instance", "Host", "Log Dir", "Reason"}}
+	for _, entry := range l.entries {
+		if reason, ok := l.missing[entry.Name+"@"+entry.Host]; ok {
+			rows = append(rows, []string{entry.Name, entry.Host, entry.LogDir, reason})
+		}
+	}
+	log.Warnf("The logs of %d instances are not rotated:", len(l.missing))
+	cliutil.PrintTable(rows, true)
+	return nil
+}
+
+// install writes and validates the logrotate config of the instance, the reason is returned
+// if the logs of the instance can't be rotated
+func (l *LogRotate) install(e executor.TiOpsExecutor, entry LogRotateEntry) (string, error) {
+	if _, _, err := e.Execute("command -v logrotate", false); err != nil {
+		return "logrotate is not installed", nil
+	}
+
+	path := logRotatePath(entry)
+	content := base64.StdEncoding.EncodeToString([]byte(renderLogRotate(entry, l.options)))
+	if _, stderr, err := e.Execute(fmt.Sprintf("echo %s | base64 -d > %s", content, path), true); err != nil {
+		return "", errors.Annotatef(err, "failed to write %s on %s, stderr: %s", path, entry.Host, stderr)
+	}
+	if _, stderr, err := e.Execute("logrotate -d "+path, true); err != nil {
+		return fmt.Sprintf("the config is rejected by logrotate: %s", strings.TrimSpace(string(stderr))), nil
+	}
+	return "", nil
+}
+
+// Missing returns the reasons of the instances whose logs are not rotated, by name@host
+func (l *LogRotate) Missing() map[string]string {
+	return l.missing
+}
+
+// logRotatePath returns the path of the logrotate config of the instance
+func logRotatePath(entry LogRotateEntry) string {
+	return filepath.Join(logRotateDir, "tiup-"+entry.Name)
+}
+
+// renderLogRotate renders the logrotate config of the instance, the logs are truncated after
+// copied as the processes keep writing to the same files
+func renderLogRotate(entry LogRotateEntry, opt LogRotateOptions) string {
+	pattern := filepath.Join(entry.LogDir, "*.log")
+	if builtinRotation[entry.Component] {
+		pattern = filepath.Join(entry.LogDir, "*_stderr.log")
+	}
+	return fmt.Sprintf(`# generated by tiup-cluster for %s
+%s {
+    size %s
+    maxage %d
+    rotate %d
+    missingok
+    notifempty
+    copytruncate
+    compress
+    delaycompress
+}
+`, entry.Name, pattern, opt.MaxSize, opt.MaxAge, opt.Keep)
+}
+
+// Rollback implements the Task interface
+func (l *LogRotate) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (l *LogRotate) String() string {
+	return fmt.Sprintf("LogRotate: instances=%d, size=%s, age=%d, keep=%d", len(l.entries), l.options.MaxSize, l.options.MaxAge, l.options.Keep)
+}
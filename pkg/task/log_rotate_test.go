@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+var logRotateOptions = LogRotateOptions{MaxSize: "100M", MaxAge: 7, Keep: 10}
+
+func (s *taskSuite) TestRenderLogRotate(c *C) {
+	config := renderLogRotate(LogRotateEntry{
+		Host:      "172.16.5.140",
+		Component: meta.ComponentTiKV,
+		Name:      "tikv-20160",
+		LogDir:    "/tidb-deploy/tikv-20160/log",
+	}, logRotateOptions)
+	c.Assert(config, Equals, `# generated by tiup-cluster for tikv-20160
+/tidb-deploy/tikv-20160/log/*_stderr.log {
+    size 100M
+    maxage 7
+    rotate 10
+    missingok
+    notifempty
+    copytruncate
+    compress
+    delaycompress
+}
+`)
+
+	// all the logs are rotated for the components without built-in rotation
+	config = renderLogRotate(LogRotateEntry{
+		Component: meta.ComponentPrometheus,
+		Name:      "prometheus-9090",
+		LogDir:    "/tidb-deploy/prometheus-9090/log",
+	}, LogRotateOptions{MaxSize: "1G", MaxAge: 30, Keep: 5})
+	c.Assert(config, Matches, "(?s).*\n/tidb-deploy/prometheus-9090/log/\\*.log \\{\n    size 1G\n    maxage 30\n    rotate 5\n.*")
+}
+
+func (s *taskSuite) TestLogRotate(c *C) {
+	entries := []LogRotateEntry{
+		{Host: "172.16.5.140", Component: meta.ComponentTiKV, Name: "tikv-20160", LogDir: "/tidb-deploy/tikv-20160/log"},
+		{Host: "172.16.5.141", Component: meta.ComponentPD, Name: "pd-2379", LogDir: "/tidb-deploy/pd-2379/log"},
+		{Host: "172.16.5.142", Component: meta.ComponentGrafana, Name: "grafana-3000", LogDir: "/tidb-deploy/grafana-3000/log"},
+	}
+
+	handlers := map[string]*mockExecutor{
+		// rotated
+		"172.16.5.140": {handler: func(cmd string) ([]byte, []byte, error) { return nil, nil, nil }},
+		// logrotate is absent
+		"172.16.5.141": {handler: func(cmd string) ([]byte, []byte, error) {
+			if cmd == "command -v logrotate" {
+				return nil, nil, errors.New("exit status 1")
+			}
+			return nil, nil, nil
+		}},
+		// the config is rejected
+		"172.16.5.142": {handler: func(cmd string) ([]byte, []byte, error) {
+			if strings.HasPrefix(cmd, "logrotate -d") {
+				return nil, []byte("error: /etc/logrotate.d/tiup-grafana-3000:3 unknown option 'size'\n"), errors.New("exit status 1")
+			}
+			return nil, nil, nil
+		}},
+	}
+	ctx, executors := mockHostsContext(hostsOf(handlers), func(host string) *mockExecutor {
+		return handlers[host]
+	})
+
+	t := &LogRotate{entries: entries, options: logRotateOptions}
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(t.Missing(), DeepEquals, map[string]string{
+		"pd-2379@172.16.5.141":      "logrotate is not installed",
+		"grafana-3000@172.16.5.142": "the config is rejected by logrotate: error: /etc/logrotate.d/tiup-grafana-3000:3 unknown option 'size'",
+	})
+
+	content := base64.StdEncoding.EncodeToString([]byte(renderLogRotate(entries[0], logRotateOptions)))
+	c.Assert(executors["172.16.5.140"].commands(), DeepEquals, []string{
+		"command -v logrotate",
+		"echo " + content + " | base64 -d > /etc/logrotate.d/tiup-tikv-20160",
+		"logrotate -d /etc/logrotate.d/tiup-tikv-20160",
+	})
+	c.Assert(executors["172.16.5.141"].commands(), DeepEquals, []string{"command -v logrotate"})
+}
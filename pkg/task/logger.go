@@ -0,0 +1,46 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+)
+
+// Logger is used to output the progress lines of the tasks, e.g. the tasks executed by
+// Serial and Parallel. An application embedding the tasks can set its own one to capture
+// or redirect the lines.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// stdLogger outputs the lines by the package-level log
+type stdLogger struct{}
+
+func (stdLogger) Infof(format string, args ...interface{}) { log.Infof(format, args...) }
+func (stdLogger) Warnf(format string, args ...interface{}) { log.Warnf(format, args...) }
+
+// SetLogger sets the logger the progress lines are output to, the package-level log is
+// used if it's not set
+func (ctx *Context) SetLogger(l Logger) {
+	ctx.logger = l
+}
+
+// Logger returns the logger of the context
+func (ctx *Context) Logger() Logger {
+	if ctx.logger == nil {
+		return stdLogger{}
+	}
+	return ctx.logger
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// captureLogger records the lines output to it
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, "WARN "+fmt.Sprintf(format, args...))
+}
+
+func (s *taskSuite) TestLogger(c *C) {
+	ctx := NewContext()
+	_, ok := ctx.Logger().(stdLogger)
+	c.Assert(ok, IsTrue)
+
+	l := &captureLogger{}
+	ctx.SetLogger(l)
+	var executed int32
+	t := NewBuilder().
+		Func("t0", func() error { return nil }).
+		Parallel(
+			&Func{name: "t1", fn: func() error { return nil }},
+			&Func{name: "t2", fn: func() error { return nil }},
+		).
+		Build()
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(l.lines[0], Equals, "INFO + [ Serial ] - t0")
+	parallel := append([]string{}, l.lines[1:]...)
+	sort.Strings(parallel)
+	c.Assert(parallel, DeepEquals, []string{"INFO + [Parallel] - t1", "INFO + [Parallel] - t2"})
+
+	// the retries are warned to the logger too
+	l.lines = nil
+	t = NewBuilder().RetryParallel(1, time.Millisecond, flakyTask("t3", 1, &executed)).Build()
+	c.Assert(t.Execute(ctx), IsNil)
+	c.Assert(l.lines, DeepEquals, []string{
+		"INFO + [Parallel] - t3",
+		"WARN 1 of 1 tasks failed, retrying them in 1ms (1/1)",
+		"INFO + [Parallel] - t3",
+	})
+}
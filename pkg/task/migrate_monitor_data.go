@@ -0,0 +1,176 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSMigrate = errNS.NewSubNamespace("migrate")
+	// ErrMigrateNoSpace means there is not enough space in the new data directory
+	ErrMigrateNoSpace = errNSMigrate.NewType("no_space", errutil.ErrTraitPreCheck)
+	// ErrMigrateDataMismatch means the data copied to the new data directory is different from the original
+	ErrMigrateDataMismatch = errNSMigrate.NewType("data_mismatch")
+)
+
+// MigrateMonitorData is used to move the TSDB of a Prometheus instance to a new data
+// directory, the instance is stopped during copying and started with the new directory.
+// The original data is kept and should be removed manually after confirming.
+type MigrateMonitorData struct {
+	clusterName    string
+	clusterVersion string
+	deployUser     string
+	inst           meta.Instance
+	srcDir         string
+	paths          meta.DirPaths // paths.Data is the new data directory
+}
+
+// Execute implements the Task interface
+func (m *MigrateMonitorData) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(m.inst.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+	dstDir := m.paths.Data
+
+	if _, _, err := e.Execute(fmt.Sprintf("mkdir -p %s && chown %s:%s %s", dstDir, m.deployUser, m.deployUser, dstDir), true); err != nil {
+		return errors.Annotatef(err, "failed to create %s on %s", dstDir, m.inst.GetHost())
+	}
+	if err := m.checkSpace(e, dstDir); err != nil {
+		return err
+	}
+
+	if err := operator.StopComponent(ctx, []meta.Instance{m.inst}); err != nil {
+		return err
+	}
+
+	if err := m.copy(e, dstDir); err != nil {
+		// the config is not changed yet, so bring it back with the original data
+		log.Errorf("Failed to migrate the data of %s, starting it with %s", m.inst.ID(), m.srcDir)
+		if err := operator.StartComponent(ctx, []meta.Instance{m.inst}); err != nil {
+			log.Errorf("Failed to start %s: %s", m.inst.ID(), err)
+		}
+		return err
+	}
+
+	if err := m.inst.InitConfig(e, m.clusterName, m.clusterVersion, m.deployUser, m.paths); err != nil {
+		return err
+	}
+	if err := operator.StartComponent(ctx, []meta.Instance{m.inst}); err != nil {
+		return err
+	}
+	log.Infof("Migrated the data of %s from %s to %s, please remove %s after confirming", m.inst.ID(), m.srcDir, dstDir, m.srcDir)
+	return nil
+}
+
+// checkSpace makes sure the available space of the new data directory is enough to hold the data
+func (m *MigrateMonitorData) checkSpace(e executor.TiOpsExecutor, dstDir string) error {
+	stdout, _, err := e.Execute(fmt.Sprintf("du -sk %s", m.srcDir), true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the size of %s on %s", m.srcDir, m.inst.GetHost())
+	}
+	fields := strings.Fields(string(stdout))
+	if len(fields) < 1 {
+		return errors.Errorf("unexpected output of du: %s", stdout)
+	}
+	required, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return errors.Annotatef(err, "unexpected output of du: %s", stdout)
+	}
+
+	stdout, _, err = e.Execute(fmt.Sprintf("df -Pk %s", dstDir), true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to get the available space of %s on %s", dstDir, m.inst.GetHost())
+	}
+	// Filesystem 1024-blocks Used Available Capacity Mounted on
+	// /dev/vdb   103080888   61464 97759304 1%       /data2
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	fields = strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 4 {
+		return errors.Errorf("unexpected output of df: %s", stdout)
+	}
+	available, err := strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return errors.Annotatef(err, "unexpected output of df: %s", stdout)
+	}
+
+	if available < required {
+		return ErrMigrateNoSpace.
+			New("%s requires %d KiB but only %d KiB is available on %s", dstDir, required, available, m.inst.GetHost()).
+			WithProperty(cliutil.SuggestionFromString("Please choose a data directory on a larger disk, or free some space of it."))
+	}
+	return nil
+}
+
+// copy copies the data to the new data directory and verifies it
+func (m *MigrateMonitorData) copy(e executor.TiOpsExecutor, dstDir string) error {
+	cmd := fmt.Sprintf("if command -v rsync >/dev/null 2>&1; then rsync -a %s/ %s/; else cp -a %s/. %s/; fi",
+		m.srcDir, dstDir, m.srcDir, dstDir)
+	if _, stderr, err := e.Execute(cmd, true); err != nil {
+		return errors.Annotatef(err, "failed to copy %s to %s on %s: %s", m.srcDir, dstDir, m.inst.GetHost(), stderr)
+	}
+	return m.verify(e, dstDir)
+}
+
+// dirSummaryCmd prints the number and the total size of files in the directory, the `$` of
+// awk is escaped as the command is double quoted again by sudo
+func dirSummaryCmd(dir string) string {
+	return fmt.Sprintf("find %s -type f -printf '%%s\\n' | awk '{n++; s+=\\$1} END {print n+0, s+0}'", dir)
+}
+
+// verify compares the number and the total size of files in the original and the new data directory
+func (m *MigrateMonitorData) verify(e executor.TiOpsExecutor, dstDir string) error {
+	summary := func(dir string) (string, error) {
+		stdout, _, err := e.Execute(dirSummaryCmd(dir), true)
+		if err != nil {
+			return "", errors.Annotatef(err, "failed to summarize %s on %s", dir, m.inst.GetHost())
+		}
+		return strings.TrimSpace(string(stdout)), nil
+	}
+
+	src, err := summary(m.srcDir)
+	if err != nil {
+		return err
+	}
+	dst, err := summary(dstDir)
+	if err != nil {
+		return err
+	}
+	if src != dst {
+		return ErrMigrateDataMismatch.New("Data copied to %s (files, bytes: %s) is different from %s (files, bytes: %s) on %s",
+			dstDir, dst, m.srcDir, src, m.inst.GetHost())
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (m *MigrateMonitorData) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (m *MigrateMonitorData) String() string {
+	return fmt.Sprintf("MigrateMonitorData: instance=%s, src=%s, dst=%s", m.inst.ID(), m.srcDir, m.paths.Data)
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// monitorExecutor mocks a host running Prometheus on port 9090 and answers the
+// commands of the migration with the outputs
+func monitorExecutor(outputs map[string]string) *mockExecutor {
+	var (
+		mu      sync.Mutex
+		running = true
+	)
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case cmd == "ss -ltn":
+			if running {
+				return []byte("LISTEN 0 128 *:9090 *:*\n"), nil, nil
+			}
+			return nil, nil, nil
+		case strings.Contains(cmd, "systemctl stop"):
+			running = false
+		case strings.Contains(cmd, "systemctl start"):
+			running = true
+		}
+		for prefix, output := range outputs {
+			if strings.HasPrefix(cmd, prefix) {
+				return []byte(output), nil, nil
+			}
+		}
+		return nil, nil, nil
+	}}
+}
+
+func setupMigrateMonitorData(c *C) (*MigrateMonitorData, string) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+
+	cache, err := ioutil.TempDir("", "migrate-monitor")
+	c.Assert(err, IsNil)
+
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+monitoring_servers:
+  - host: 172.16.5.140
+    deploy_dir: /home/tidb/deploy/prometheus-9090
+    data_dir: /home/tidb/deploy/prometheus-9090/data
+`), &topo), IsNil)
+	inst := (&meta.MonitorComponent{Specification: &topo}).Instances()[0]
+
+	return &MigrateMonitorData{
+		clusterName:    "test-cluster",
+		clusterVersion: "v4.0.0",
+		deployUser:     "tidb",
+		inst:           inst,
+		srcDir:         "/home/tidb/deploy/prometheus-9090/data",
+		paths: meta.DirPaths{
+			Deploy: "/home/tidb/deploy/prometheus-9090",
+			Data:   "/data2/prometheus",
+			Log:    "/home/tidb/deploy/prometheus-9090/log",
+			Cache:  cache,
+		},
+	}, cache
+}
+
+func (s *taskSuite) TestMigrateMonitorData(c *C) {
+	t, cache := setupMigrateMonitorData(c)
+	defer os.RemoveAll(cache)
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	e := monitorExecutor(map[string]string{
+		"du -sk":  "1024\t/home/tidb/deploy/prometheus-9090/data\n",
+		"df -Pk":  "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vdb 103080888 61464 97759304 1% /data2\n",
+		"find /h": "128 1048576\n",
+		"find /d": "128 1048576\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+	c.Assert(t.Execute(ctx), IsNil)
+
+	// the data is copied after stopped and the new data dir is used by the run script
+	var steps []string
+	for _, cmd := range e.commands() {
+		switch {
+		case strings.HasPrefix(cmd, "mkdir"), strings.HasPrefix(cmd, "du"), strings.HasPrefix(cmd, "df"):
+			steps = append(steps, strings.Fields(cmd)[0])
+		case strings.Contains(cmd, "systemctl stop"):
+			steps = append(steps, "stop")
+		case strings.Contains(cmd, "rsync"):
+			steps = append(steps, "copy")
+		case strings.HasPrefix(cmd, "find"):
+			steps = append(steps, "verify")
+		case strings.Contains(cmd, "systemctl start"):
+			steps = append(steps, "start")
+		}
+	}
+	c.Assert(steps, DeepEquals, []string{"mkdir", "du", "df", "stop", "copy", "verify", "verify", "start"})
+
+	script, err := ioutil.ReadFile(filepath.Join(cache, "run_prometheus_172.16.5.140_9090.sh"))
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Matches, `(?s).*--storage\.tsdb\.path="/data2/prometheus".*`)
+}
+
+func (s *taskSuite) TestMigrateMonitorDataNoSpace(c *C) {
+	t, cache := setupMigrateMonitorData(c)
+	defer os.RemoveAll(cache)
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	e := monitorExecutor(map[string]string{
+		"du -sk": "2048\t/home/tidb/deploy/prometheus-9090/data\n",
+		"df -Pk": "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vdb 4096 3072 1024 75% /data2\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrMigrateNoSpace), IsTrue)
+	c.Assert(err.Error(), Matches, ".*/data2/prometheus requires 2048 KiB but only 1024 KiB is available on 172.16.5.140.*")
+
+	// Prometheus is not stopped
+	for _, cmd := range e.commands() {
+		c.Assert(strings.Contains(cmd, "systemctl stop"), IsFalse)
+	}
+}
+
+func (s *taskSuite) TestMigrateMonitorDataMismatch(c *C) {
+	t, cache := setupMigrateMonitorData(c)
+	defer os.RemoveAll(cache)
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+
+	e := monitorExecutor(map[string]string{
+		"du -sk":  "1024\t/home/tidb/deploy/prometheus-9090/data\n",
+		"df -Pk":  "Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vdb 103080888 61464 97759304 1% /data2\n",
+		"find /h": "128 1048576\n",
+		"find /d": "127 1040000\n",
+	})
+	ctx := newMockContext("172.16.5.140", e)
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrMigrateDataMismatch), IsTrue)
+
+	// Prometheus is started again with the original data
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-2], Matches, ".*systemctl start prometheus-9090.service.*")
+	c.Assert(t.inst.DataDir(), Equals, "/home/tidb/deploy/prometheus-9090/data")
+	for _, tf := range e.transfers {
+		c.Assert(strings.Contains(tf, "run_prometheus.sh"), IsFalse)
+	}
+}
+
+func (s *taskSuite) TestDirSummaryCmd(c *C) {
+	dir := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "wal"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "wal", "00000001"), make([]byte, 1000), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "lock"), make([]byte, 24), 0644), IsNil)
+
+	out, err := runSudo(c, dirSummaryCmd(dir))
+	c.Assert(err, IsNil, Commentf("output: %s", out))
+	c.Assert(out, Equals, "2 1024\n")
+
+	out, err = runSudo(c, dirSummaryCmd(c.MkDir()))
+	c.Assert(err, IsNil, Commentf("output: %s", out))
+	c.Assert(out, Equals, "0 0\n")
+}
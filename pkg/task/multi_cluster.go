@@ -0,0 +1,103 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+)
+
+// ClusterResult is the result of an operation against a cluster
+type ClusterResult struct {
+	Cluster  string
+	Err      error
+	Duration time.Duration
+}
+
+// ClusterRunner performs the operation against the cluster with the context of it
+type ClusterRunner func(cluster string, ctx *Context) error
+
+// RunClusters performs the operation against the clusters concurrently, at most concurrency
+// clusters at the same time, unlimited if it's not positive. Each cluster runs with its own
+// context created by newContext, so the executors, outputs, caches and checkpoints of one
+// cluster are never seen by the others, and a failed or panicked cluster doesn't affect the
+// others. The results are in the order of the clusters.
+func RunClusters(clusters []string, concurrency int, newContext func(cluster string) *Context, run ClusterRunner) []ClusterResult {
+	results := make([]ClusterResult, len(clusters))
+	if concurrency <= 0 || concurrency > len(clusters) {
+		concurrency = len(clusters)
+	}
+
+	var (
+		mu   sync.Mutex
+		used = make(map[*Context]string)
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[i] = ClusterResult{Cluster: cluster}
+			defer func() {
+				if r := recover(); r != nil {
+					results[i].Err = errors.Errorf("panic: %v\n%s", r, debug.Stack())
+				}
+				results[i].Duration = time.Since(start)
+			}()
+
+			ctx := newContext(cluster)
+			mu.Lock()
+			owner, shared := used[ctx]
+			if !shared {
+				used[ctx] = cluster
+			}
+			mu.Unlock()
+			if shared {
+				results[i].Err = errors.Errorf("the context of cluster %s is shared with cluster %s", cluster, owner)
+				return
+			}
+			results[i].Err = run(cluster, ctx)
+		}(i, cluster)
+	}
+	wg.Wait()
+	return results
+}
+
+// FailedClusters returns the results of the clusters failed
+func FailedClusters(results []ClusterResult) []ClusterResult {
+	var failed []ClusterResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// String implements the fmt.Stringer interface
+func (r ClusterResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s: failed in %s: %s", r.Cluster, r.Duration.Round(time.Millisecond), r.Err)
+	}
+	return fmt.Sprintf("%s: succeeded in %s", r.Cluster, r.Duration.Round(time.Millisecond))
+}
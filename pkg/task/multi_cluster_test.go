@@ -0,0 +1,117 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestRunClusters(c *C) {
+	// the clusters have the hosts of the same address
+	var (
+		mu     sync.Mutex
+		mocks  = map[string]*mockExecutor{}
+		unames = map[string]string{}
+	)
+	newContext := func(cluster string) *Context {
+		ctx := NewContext()
+		ctx.EnableCommandCache()
+		e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			// both clusters are running at the same time
+			time.Sleep(20 * time.Millisecond)
+			if cluster == "broken" {
+				return nil, nil, errors.New("connection refused")
+			}
+			return []byte("Linux " + cluster), nil, nil
+		}}
+		ctx.SetExecutor("172.16.5.140", e)
+		mu.Lock()
+		mocks[cluster] = e
+		mu.Unlock()
+		return ctx
+	}
+	run := func(cluster string, ctx *Context) error {
+		t := NewBuilder().
+			Func("uname", func() error {
+				e, _ := ctx.GetExecutor("172.16.5.140")
+				for i := 0; i < 2; i++ {
+					stdout, _, err := e.Execute("uname -a", false)
+					if err != nil {
+						return err
+					}
+					mu.Lock()
+					unames[cluster] = string(stdout)
+					mu.Unlock()
+				}
+				return nil
+			}).
+			Build()
+		if cluster == "panicked" {
+			var spec map[string]string
+			spec["boom"] = "nil map"
+		}
+		return t.Execute(ctx)
+	}
+
+	start := time.Now()
+	results := RunClusters([]string{"c1", "broken", "c2", "panicked"}, 0, newContext, run)
+	c.Assert(time.Since(start) < 150*time.Millisecond, IsTrue)
+	c.Assert(results, HasLen, 4)
+	for i, name := range []string{"c1", "broken", "c2", "panicked"} {
+		c.Assert(results[i].Cluster, Equals, name)
+	}
+	c.Assert(results[0].Err, IsNil)
+	c.Assert(results[1].Err, ErrorMatches, "connection refused")
+	c.Assert(results[2].Err, IsNil)
+	c.Assert(results[3].Err, ErrorMatches, "(?s)panic: assignment to entry in nil map.*")
+	c.Assert(FailedClusters(results), HasLen, 2)
+	c.Assert(results[0].String(), Matches, "c1: succeeded in .*")
+
+	// the outputs and the caches are kept by each cluster
+	c.Assert(unames, DeepEquals, map[string]string{"c1": "Linux c1", "c2": "Linux c2"})
+	c.Assert(mocks["c1"].commands(), DeepEquals, []string{"uname -a"})
+	c.Assert(mocks["c2"].commands(), DeepEquals, []string{"uname -a"})
+}
+
+func (s *taskSuite) TestRunClustersIsolation(c *C) {
+	var running, peak int32
+	run := func(cluster string, ctx *Context) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+	clusters := []string{"c1", "c2", "c3", "c4", "c5"}
+	results := RunClusters(clusters, 2, func(string) *Context { return NewContext() }, run)
+	c.Assert(FailedClusters(results), HasLen, 0)
+	c.Assert(atomic.LoadInt32(&peak), Equals, int32(2))
+
+	// a context is never shared by the clusters
+	shared := NewContext()
+	results = RunClusters([]string{"c1", "c2"}, 1, func(string) *Context { return shared }, run)
+	failed := FailedClusters(results)
+	c.Assert(failed, HasLen, 1)
+	c.Assert(failed[0].Err, ErrorMatches, "the context of cluster c[12] is shared with cluster c[12]")
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	tiupmeta "github.com/pingcap-incubator/tiup/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+)
+
+var (
+	errNSOffline = errNS.NewSubNamespace("offline")
+	// ErrOfflineCacheMiss means something not cached locally is required in the offline mode
+	ErrOfflineCacheMiss = errNSOffline.NewType("cache_miss", errutil.ErrTraitPreCheck)
+)
+
+// offlineSuggestion is the suggestion of the errors of the missed cache in the offline mode
+const offlineSuggestion = "Please cache the components on a host with the access to the mirror, e.g. by deploying with the same version there and copying the cache, or set TIUP_MIRRORS to a local mirror."
+
+// SetOffline makes the tasks use the local cache strictly and never fetch anything from
+// the mirror, the manifests and packages not cached are reported as errors
+func (ctx *Context) SetOffline(offline bool) {
+	ctx.offline = offline
+}
+
+// Offline returns whether the context is in the offline mode
+func (ctx *Context) Offline() bool {
+	return ctx.offline
+}
+
+// componentManifest returns the manifest of the component from the cache of the context,
+// or fetches it from the repository if it's not cached and the context is not offline
+func (ctx *Context) componentManifest(comp string) (*repository.VersionManifest, error) {
+	if m, found := ctx.GetManifest(comp); found {
+		return m, nil
+	}
+	if ctx.offline {
+		return nil, ErrOfflineCacheMiss.
+			New("The manifest of %s is not cached and can't be fetched from the mirror in the offline mode", comp).
+			WithProperty(cliutil.SuggestionFromString(offlineSuggestion))
+	}
+	m, err := tiupmeta.Repository().ComponentVersions(comp)
+	if err != nil {
+		return nil, err
+	}
+	ctx.SetManifest(comp, m)
+	return m, nil
+}
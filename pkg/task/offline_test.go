@@ -0,0 +1,74 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestOffline(c *C) {
+	dir := setupCheckBinary(c)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(localdata.EnvNameComponentDataDir)
+
+	// the mirror counts the requests to it
+	var requests int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer mirror.Close()
+	os.Setenv(repository.EnvMirrors, mirror.URL)
+	defer os.Unsetenv(repository.EnvMirrors)
+
+	ctx := NewContext()
+	ctx.SetOffline(true)
+	c.Assert(ctx.Offline(), IsTrue)
+
+	// the cached packages are used
+	c.Assert((&Downloader{component: "tikv", version: "v4.0.0"}).Execute(ctx), IsNil)
+
+	// the missed ones are reported without fetching
+	err := (&Downloader{component: "pd", version: "v4.0.0"}).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrOfflineCacheMiss), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The package pd-v4.0.0-linux-amd64.tar.gz is not cached in .* and can't be downloaded in the offline mode.*")
+	err = (&Downloader{component: "pd", version: "nightly"}).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrOfflineCacheMiss), IsTrue)
+
+	_, err = ctx.componentManifest("pd")
+	c.Assert(errorx.IsOfType(err, ErrOfflineCacheMiss), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The manifest of pd is not cached and can't be fetched from the mirror in the offline mode.*")
+	err = (&BackupComponent{component: "pd", fromVer: "v3.0.12", host: "172.16.5.140"}).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrOfflineCacheMiss), IsTrue)
+
+	// the cached manifest is used
+	ctx.SetManifest("pd", &repository.VersionManifest{Description: "PD"})
+	m, err := ctx.componentManifest("pd")
+	c.Assert(err, IsNil)
+	c.Assert(m.Description, Equals, "PD")
+	c.Assert(atomic.LoadInt32(&requests), Equals, int32(0))
+
+	// the mirror is fetched from if it's not offline
+	ctx = NewContext()
+	c.Assert((&Downloader{component: "pd", version: "v4.0.0"}).Execute(ctx), NotNil)
+	c.Assert(atomic.LoadInt32(&requests) > 0, IsTrue)
+}
@@ -0,0 +1,217 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/api"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSScheduling = errNS.NewSubNamespace("scheduling")
+	// ErrSchedulingPaused means the scheduling of PD is already paused
+	ErrSchedulingPaused = errNSScheduling.NewType("paused", errutil.ErrTraitPreCheck)
+	// ErrSchedulingNotPaused means the scheduling of PD is not paused by pause-scheduling
+	ErrSchedulingNotPaused = errNSScheduling.NewType("not_paused", errutil.ErrTraitPreCheck)
+)
+
+// SchedulingState is the schedule limits of PD before the scheduling is paused, which are
+// restored when it's resumed
+type SchedulingState struct {
+	Time   time.Time         `json:"time"`
+	PD     []string          `json:"pd"`
+	Limits map[string]uint64 `json:"limits"`
+}
+
+// ReadSchedulingState reads the state saved by PauseScheduling, it returns nil if the
+// scheduling is not paused
+func ReadSchedulingState(path string) (*SchedulingState, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	state := &SchedulingState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Annotatef(err, "invalid scheduling state %s", path)
+	}
+	return state, nil
+}
+
+// PauseScheduling is used to stop PD from moving regions during a maintenance by setting
+// the schedule limits to 0. The prior limits are saved to a local file before they are
+// changed, so that ResumeScheduling restores exactly them. It's refused if the scheduling
+// is already paused, otherwise the saved limits would be overwritten by the zeros.
+type PauseScheduling struct {
+	spec *meta.Specification
+	path string
+}
+
+// Execute implements the Task interface
+func (p *PauseScheduling) Execute(ctx *Context) error {
+	// nothing is changed if the commands are recorded to the plan
+	if ctx.Planning() {
+		return nil
+	}
+
+	state, err := ReadSchedulingState(p.path)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		return ErrSchedulingPaused.
+			New("The scheduling of PD is already paused since %s", state.Time.Format(time.RFC3339)).
+			WithProperty(cliutil.SuggestionFromString("Please resume it by resume-scheduling before pausing it again."))
+	}
+
+	pdList := p.spec.GetPDList()
+	client := api.NewPDClient(pdList, 10*time.Second, nil)
+	limits, err := client.GetScheduleLimits()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(&SchedulingState{Time: time.Now(), PD: pdList, Limits: limits}, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := utils.CreateDir(filepath.Dir(p.path)); err != nil {
+		return errors.Annotatef(err, "failed to create the directory of %s", p.path)
+	}
+	if err := ioutil.WriteFile(p.path, data, 0600); err != nil {
+		return errors.Annotatef(err, "failed to save the schedule limits of PD to %s", p.path)
+	}
+
+	paused := make(map[string]uint64, len(limits))
+	for key := range limits {
+		paused[key] = 0
+	}
+	if err := client.SetScheduleLimits(paused); err != nil {
+		// the limits are restored in case some of them are set
+		if rerr := client.SetScheduleLimits(limits); rerr != nil {
+			log.Errorf("Failed to restore the schedule limits of PD, they are saved in %s: %s", p.path, rerr)
+			return err
+		}
+		_ = os.Remove(p.path)
+		return err
+	}
+	log.Infof("The scheduling of PD is paused, the prior schedule limits are saved to %s: %s", p.path, formatScheduleLimits(limits))
+	return nil
+}
+
+// Rollback implements the Task interface
+func (p *PauseScheduling) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (p *PauseScheduling) String() string {
+	return fmt.Sprintf("PauseScheduling: pd=%s, path=%s", strings.Join(p.spec.GetPDList(), ","), p.path)
+}
+
+// ResumeScheduling is used to restore the schedule limits of PD saved by PauseScheduling,
+// the saved state is removed only after the restored limits are verified
+type ResumeScheduling struct {
+	spec *meta.Specification
+	path string
+}
+
+// Execute implements the Task interface
+func (r *ResumeScheduling) Execute(ctx *Context) error {
+	// nothing is changed if the commands are recorded to the plan
+	if ctx.Planning() {
+		return nil
+	}
+
+	state, err := ReadSchedulingState(r.path)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return ErrSchedulingNotPaused.
+			New("The scheduling of PD is not paused by pause-scheduling, there are no schedule limits to restore").
+			WithProperty(cliutil.SuggestionFromString("Please check the schedule limits of PD by `pd-ctl config show` if the scheduling is still stopped."))
+	}
+
+	client := api.NewPDClient(r.spec.GetPDList(), 10*time.Second, nil)
+	current, err := client.GetScheduleLimits()
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedLimitKeys(state.Limits) {
+		if v := current[key]; v != 0 && v != state.Limits[key] {
+			log.Warnf("The %s of PD is changed to %d during the pause, it's restored to %d", key, v, state.Limits[key])
+		}
+	}
+	if err := client.SetScheduleLimits(state.Limits); err != nil {
+		return err
+	}
+
+	restored, err := client.GetScheduleLimits()
+	if err != nil {
+		return err
+	}
+	for key, v := range state.Limits {
+		if restored[key] != v {
+			return errors.Errorf("the %s of PD is %d rather than %d after resuming the scheduling, the prior limits are kept in %s", key, restored[key], v, r.path)
+		}
+	}
+	if err := os.Remove(r.path); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("The scheduling of PD paused since %s is resumed: %s", state.Time.Format(time.RFC3339), formatScheduleLimits(state.Limits))
+	return nil
+}
+
+// Rollback implements the Task interface
+func (r *ResumeScheduling) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (r *ResumeScheduling) String() string {
+	return fmt.Sprintf("ResumeScheduling: pd=%s, path=%s", strings.Join(r.spec.GetPDList(), ","), r.path)
+}
+
+func sortedLimitKeys(limits map[string]uint64) []string {
+	keys := make([]string, 0, len(limits))
+	for key := range limits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatScheduleLimits(limits map[string]uint64) string {
+	var parts []string
+	for _, key := range sortedLimitKeys(limits) {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, limits[key]))
+	}
+	return strings.Join(parts, ", ")
+}
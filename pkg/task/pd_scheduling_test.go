@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// schedulePD mocks the schedule config APIs of PD, the POSTs fail while failures > 0
+type schedulePD struct {
+	mu       sync.Mutex
+	config   map[string]uint64
+	posts    []map[string]uint64
+	failures int
+}
+
+func (pd *schedulePD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/pd/api/v1/config/schedule":
+		config := map[string]interface{}{"max-merge-region-size": 20, "enable-one-way-merge": "false"}
+		for k, v := range pd.config {
+			config[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(config)
+	case r.Method == http.MethodPost && r.URL.Path == "/pd/api/v1/config":
+		if pd.failures > 0 {
+			pd.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		update := map[string]uint64{}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pd.posts = append(pd.posts, update)
+		for k, v := range update {
+			pd.config[k] = v
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newSchedulePD() *schedulePD {
+	return &schedulePD{config: map[string]uint64{
+		"leader-schedule-limit":     4,
+		"region-schedule-limit":     2048,
+		"replica-schedule-limit":    64,
+		"merge-schedule-limit":      8,
+		"hot-region-schedule-limit": 4,
+	}}
+}
+
+func (s *taskSuite) TestPauseResumeScheduling(c *C) {
+	pd := newSchedulePD()
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	topo := pdMetaTopology(c, server)
+	path := filepath.Join(c.MkDir(), "scheduling-paused.json")
+	prior := map[string]uint64{}
+	for k, v := range pd.config {
+		prior[k] = v
+	}
+
+	// the prior limits are saved before they are set to 0
+	c.Assert((&PauseScheduling{spec: topo, path: path}).Execute(NewContext()), IsNil)
+	state, err := ReadSchedulingState(path)
+	c.Assert(err, IsNil)
+	c.Assert(state.Limits, DeepEquals, prior)
+	c.Assert(state.PD, DeepEquals, topo.GetPDList())
+	for k, v := range pd.config {
+		c.Assert(v, Equals, uint64(0), Commentf("%s", k))
+	}
+
+	// pausing again would lose the prior limits
+	err = (&PauseScheduling{spec: topo, path: path}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrSchedulingPaused), IsTrue)
+	state, err = ReadSchedulingState(path)
+	c.Assert(err, IsNil)
+	c.Assert(state.Limits, DeepEquals, prior)
+
+	// restored exactly, even if some of them are changed during the pause
+	pd.config["region-schedule-limit"] = 16
+	c.Assert((&ResumeScheduling{spec: topo, path: path}).Execute(NewContext()), IsNil)
+	c.Assert(pd.config, DeepEquals, prior)
+	_, err = os.Stat(path)
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// nothing to resume
+	err = (&ResumeScheduling{spec: topo, path: path}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrSchedulingNotPaused), IsTrue)
+	c.Assert(pd.posts, HasLen, 2)
+}
+
+func (s *taskSuite) TestPauseSchedulingFailure(c *C) {
+	pd := newSchedulePD()
+	pd.failures = 1
+	server := httptest.NewServer(pd)
+	defer server.Close()
+	path := filepath.Join(c.MkDir(), "scheduling-paused.json")
+
+	// the limits are restored and the scheduling is not left paused
+	err := (&PauseScheduling{spec: pdMetaTopology(c, server), path: path}).Execute(NewContext())
+	c.Assert(err, ErrorMatches, "failed to set the schedule config of PD.*")
+	c.Assert(pd.posts, HasLen, 1)
+	c.Assert(pd.config["leader-schedule-limit"], Equals, uint64(4))
+	state, err := ReadSchedulingState(path)
+	c.Assert(err, IsNil)
+	c.Assert(state, IsNil)
+}
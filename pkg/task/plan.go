@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap/errors"
+)
+
+// Type of the steps in a plan
+const (
+	PlanStepCommand  = "command"
+	PlanStepTransfer = "transfer"
+)
+
+// PlanStep is a remote command or a file transfer which an operation would perform
+type PlanStep struct {
+	Host        string `json:"host" yaml:"host"`
+	Type        string `json:"type" yaml:"type"`
+	Command     string `json:"command,omitempty" yaml:"command,omitempty"`
+	Sudo        bool   `json:"sudo,omitempty" yaml:"sudo,omitempty"`
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination string `json:"destination,omitempty" yaml:"destination,omitempty"`
+	Size        int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Download    bool   `json:"download,omitempty" yaml:"download,omitempty"`
+	// the step is only performed if the condition holds on the host, e.g. the fixing
+	// commands of the checks which are only run if the checks fail
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+}
+
+// Plan collects the remote commands and file transfers of an operation instead of
+// performing them, the steps are in the order of being issued by the tasks
+type Plan struct {
+	mu    sync.Mutex
+	steps []PlanStep
+}
+
+// NewPlan returns an empty plan
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Steps returns all the steps collected
+func (p *Plan) Steps() []PlanStep {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlanStep{}, p.steps...)
+}
+
+func (p *Plan) add(step PlanStep) {
+	p.mu.Lock()
+	p.steps = append(p.steps, step)
+	p.mu.Unlock()
+}
+
+// Executor returns an executor of the host which records the commands and transfers to the plan
+func (p *Plan) Executor(host string) executor.TiOpsExecutor {
+	return &planExecutor{host: host, plan: p}
+}
+
+// conditionalExecutor returns an executor of the host which records the commands and
+// transfers to the plan as the steps only performed if the condition holds
+func (p *Plan) conditionalExecutor(host, condition string) executor.TiOpsExecutor {
+	return &planExecutor{host: host, plan: p, condition: condition}
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (p *Plan) MarshalJSON() ([]byte, error) {
+	return json.MarshalIndent(map[string][]PlanStep{"steps": p.Steps()}, "", "  ")
+}
+
+// MarshalYAML implements the yaml.BytesMarshaler interface
+func (p *Plan) MarshalYAML() ([]byte, error) {
+	return yaml.Marshal(map[string][]PlanStep{"steps": p.Steps()})
+}
+
+// WriteFile writes the plan to the file, the JSON format is used if the file name
+// ends with `.json`, otherwise the YAML format is used
+func (p *Plan) WriteFile(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		data, err = p.MarshalJSON()
+	} else {
+		data, err = p.MarshalYAML()
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Annotatef(err, "failed to write plan to %s", path)
+	}
+	return nil
+}
+
+// planExecutor implements the TiOpsExecutor interface, nothing is run on the
+// remote host and all commands succeed with empty output
+type planExecutor struct {
+	host      string
+	plan      *Plan
+	condition string
+}
+
+// Execute implements the TiOpsExecutor interface
+func (e *planExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.plan.add(PlanStep{
+		Host:      e.host,
+		Type:      PlanStepCommand,
+		Command:   cmd,
+		Sudo:      sudo,
+		Condition: e.condition,
+	})
+	return nil, nil, nil
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *planExecutor) Transfer(src string, dst string, download bool) error {
+	step := PlanStep{
+		Host:        e.host,
+		Type:        PlanStepTransfer,
+		Source:      src,
+		Destination: dst,
+		Download:    download,
+		Condition:   e.condition,
+	}
+	if !download {
+		fi, err := os.Stat(src)
+		if err != nil {
+			return errors.Annotatef(err, "failed to stat %s", src)
+		}
+		step.Size = fi.Size()
+	}
+	e.plan.add(step)
+	return nil
+}
+
+// SetPlan makes the tasks record their remote commands and transfers to the plan
+// instead of performing them
+func (ctx *Context) SetPlan(p *Plan) {
+	ctx.plan = p
+}
+
+// Plan returns the plan which the tasks are recording to, nil if the tasks are really executed
+func (ctx *Context) Plan() *Plan {
+	return ctx.plan
+}
+
+// Planning returns whether the tasks are recording to the plan rather than really executed
+func (ctx *Context) Planning() bool {
+	return ctx.plan != nil
+}
+
+// inspection is embedded by the tasks which only inspect the hosts and decide by the outputs,
+// they are skipped by the composite tasks when planning since nothing is got from the hosts
+type inspection struct{}
+
+func (inspection) inspectOnly() {}
+
+// fixPlanner is implemented by the inspections which fix the hosts if the checks fail, the
+// reading commands are skipped when planning but the fixing ones are recorded to the plan
+// as the conditional steps, with the values only known by reading as placeholders
+type fixPlanner interface {
+	planFix(plan *Plan)
+}
+
+// skippedInPlan returns whether the task is an inspection skipped when planning
+func (ctx *Context) skippedInPlan(t Task) bool {
+	_, ok := t.(interface{ inspectOnly() })
+	return ok && ctx.Planning()
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestPlan(c *C) {
+	dir, err := ioutil.TempDir("", "plan")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "run_tidb.sh")
+	c.Assert(ioutil.WriteFile(src, []byte("#!/bin/bash\n"), 0755), IsNil)
+
+	t := NewBuilder().
+		UserSSH("172.16.5.140", 22, "tidb", 5).
+		Mkdir("tidb", "172.16.5.140", "/home/tidb/deploy").
+		CopyFile(src, "/home/tidb/deploy/scripts/run_tidb.sh", "172.16.5.140", false).
+		DirPermission("tidb", "172.16.5.140", "755", "/home/tidb/deploy").
+		Build()
+
+	ctx := NewContext()
+	plan := NewPlan()
+	ctx.SetPlan(plan)
+	c.Assert(t.Execute(ctx), IsNil)
+
+	steps := plan.Steps()
+	c.Assert(steps, DeepEquals, []PlanStep{
+		{Host: "172.16.5.140", Type: PlanStepCommand, Command: "mkdir -p {/home/tidb/deploy}", Sudo: true},
+		{Host: "172.16.5.140", Type: PlanStepCommand, Command: "chown -R tidb:tidb {/home/tidb/deploy}", Sudo: true},
+		{Host: "172.16.5.140", Type: PlanStepTransfer, Source: src, Destination: "/home/tidb/deploy/scripts/run_tidb.sh", Size: 12},
+		{Host: "172.16.5.140", Type: PlanStepCommand, Command: "stat -c '%U:%G %a %n' /home/tidb/deploy", Sudo: true},
+		{Host: "172.16.5.140", Type: PlanStepCommand, Command: "chown -R tidb:$(id -gn tidb) /home/tidb/deploy && chmod 755 /home/tidb/deploy", Sudo: true},
+	})
+
+	// export the plan in both formats
+	var exported struct {
+		Steps []PlanStep `json:"steps" yaml:"steps"`
+	}
+	jsonPath := filepath.Join(dir, "plan.json")
+	c.Assert(plan.WriteFile(jsonPath), IsNil)
+	data, err := ioutil.ReadFile(jsonPath)
+	c.Assert(err, IsNil)
+	c.Assert(json.Unmarshal(data, &exported), IsNil)
+	c.Assert(exported.Steps, DeepEquals, steps)
+
+	exported.Steps = nil
+	yamlPath := filepath.Join(dir, "plan.yaml")
+	c.Assert(plan.WriteFile(yamlPath), IsNil)
+	data, err = ioutil.ReadFile(yamlPath)
+	c.Assert(err, IsNil)
+	c.Assert(yaml.Unmarshal(data, &exported), IsNil)
+	c.Assert(exported.Steps, DeepEquals, steps)
+}
+
+func (s *taskSuite) TestPlanTransferMissingFile(c *C) {
+	ctx := NewContext()
+	ctx.SetPlan(NewPlan())
+
+	t := NewBuilder().
+		UserSSH("172.16.5.140", 22, "tidb", 5).
+		CopyFile("/path/not/exist", "/home/tidb/deploy/conf/tidb.toml", "172.16.5.140", false).
+		Build()
+	c.Assert(t.Execute(ctx), NotNil)
+}
+
+func (s *taskSuite) TestPlanFixingCommands(c *C) {
+	dir, err := ioutil.TempDir("", "tiup-plan-fix")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	hosts := []string{"172.16.5.140", "172.16.5.141"}
+	t := NewBuilder().
+		CheckTimezone(hosts, "Asia/Shanghai", true).
+		CheckSwap(hosts[:1], false).
+		CheckFirewall(hosts[1], []int{4000, 2379}, true).
+		Build()
+
+	ctx := NewContext()
+	plan := NewPlan()
+	ctx.SetPlan(plan)
+	c.Assert(t.Execute(ctx), IsNil)
+
+	// nothing is read from the hosts, and the checks without fixing record nothing
+	steps := plan.Steps()
+	c.Assert(steps, DeepEquals, []PlanStep{
+		{Host: "172.16.5.140", Type: PlanStepCommand, Command: "timedatectl set-timezone Asia/Shanghai", Sudo: true, Condition: "if the timezone is not Asia/Shanghai"},
+		{Host: "172.16.5.141", Type: PlanStepCommand, Command: "timedatectl set-timezone Asia/Shanghai", Sudo: true, Condition: "if the timezone is not Asia/Shanghai"},
+		{Host: "172.16.5.141", Type: PlanStepCommand, Command: "firewall-cmd --permanent --zone=<the zone of the host address> --add-port=2379/tcp && firewall-cmd --permanent --zone=<the zone of the host address> --add-port=4000/tcp && firewall-cmd --reload", Sudo: true, Condition: "if firewalld is active and blocks the ports"},
+		{Host: "172.16.5.141", Type: PlanStepCommand, Command: "iptables -I INPUT -p tcp --dport 2379 -j ACCEPT && iptables -I INPUT -p tcp --dport 4000 -j ACCEPT", Sudo: true, Condition: "if firewalld is not active and iptables blocks the ports"},
+	})
+
+	var exported struct {
+		Steps []PlanStep `json:"steps" yaml:"steps"`
+	}
+	path := filepath.Join(dir, "plan.yaml")
+	c.Assert(plan.WriteFile(path), IsNil)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(yaml.Unmarshal(data, &exported), IsNil)
+	c.Assert(exported.Steps, DeepEquals, steps)
+}
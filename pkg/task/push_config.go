@@ -0,0 +1,239 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+// PushConfig is used to render the config files, the run scripts and optionally the systemd
+// units of the instances and push them to the hosts without restarting anything, then
+// systemd is reloaded on the hosts. The rendered files are compared with the remote ones by
+// checksum, the unchanged ones are not transferred and the changed ones are reported.
+type PushConfig struct {
+	clusterName    string
+	clusterVersion string
+	deployUser     string
+	instances      []meta.Instance
+	units          bool
+
+	changed map[string][]string
+}
+
+// Execute implements the Task interface
+func (p *PushConfig) Execute(ctx *Context) error {
+	p.changed = make(map[string][]string)
+
+	var (
+		mu    sync.Mutex
+		hosts []string
+		tasks []Task
+	)
+	seen := make(map[string]bool)
+	for _, inst := range p.instances {
+		inst := inst
+		if !seen[inst.GetHost()] {
+			seen[inst.GetHost()] = true
+			hosts = append(hosts, inst.GetHost())
+		}
+		tasks = append(tasks, &Func{
+			name: "PushConfig: " + inst.ID(),
+			fn: func() error {
+				changed, err := p.push(ctx, inst)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				p.changed[inst.ID()] = changed
+				mu.Unlock()
+				return nil
+			},
+		})
+	}
+	if err := (&Parallel{inner: tasks, hideDetailDisplay: true}).Execute(ctx); err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		e, found := ctx.GetExecutor(host)
+		if !found {
+			return ErrNoExecutor
+		}
+		if _, stderr, err := e.Execute("systemctl daemon-reload", true); err != nil {
+			return errors.Annotatef(err, "failed to reload systemd on %s, stderr: %s", host, stderr)
+		}
+	}
+	// nothing is compared if the commands are recorded to the plan
+	if ctx.Planning() {
+		return nil
+	}
+
+	rows := [][]string{{"ID", "Changed"}}
+	total := 0
+	for _, inst := range p.instances {
+		changed := p.changed[inst.ID()]
+		total += len(changed)
+		files := "-"
+		if len(changed) > 0 {
+			files = strings.Join(changed, ", ")
+		}
+		rows = append(rows, []string{inst.ID(), files})
+	}
+	cliutil.PrintTable(rows, true)
+	log.Infof("%d files of %d instances of cluster `%s` are changed, nothing is restarted", total, len(p.instances), p.clusterName)
+	return nil
+}
+
+// push pushes the config of the instance and returns the remote paths of the changed files
+func (p *PushConfig) push(ctx *Context, inst meta.Instance) ([]string, error) {
+	exec, found := ctx.GetExecutor(inst.GetHost())
+	if !found {
+		return nil, ErrNoExecutor
+	}
+	dataDir := inst.DataDir()
+	if dataDir != "" {
+		dataDir = clusterutil.Abs(p.deployUser, dataDir)
+	}
+	paths := meta.DirPaths{
+		Deploy: clusterutil.Abs(p.deployUser, inst.DeployDir()),
+		Data:   dataDir,
+		Log:    clusterutil.Abs(p.deployUser, inst.LogDir()),
+		Cache:  meta.ClusterPath(p.clusterName, "config"),
+	}
+	if err := os.MkdirAll(paths.Cache, 0755); err != nil {
+		return nil, err
+	}
+
+	e := &pushExecutor{inner: exec, units: p.units, staged: make(map[string]string)}
+	if err := inst.InitConfig(e, p.clusterName, p.clusterVersion, p.deployUser, paths); err != nil {
+		return nil, errors.Annotatef(err, "failed to push the config of %s", inst.ID())
+	}
+	return e.changed, nil
+}
+
+// Changed returns the remote paths of the changed files by the ids of the instances
+func (p *PushConfig) Changed() map[string][]string {
+	return p.changed
+}
+
+// Rollback implements the Task interface
+func (p *PushConfig) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (p *PushConfig) String() string {
+	var ids []string
+	for _, inst := range p.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("PushConfig: cluster=%s, instances=%s, units=%v", p.clusterName, strings.Join(ids, ","), p.units)
+}
+
+// pushExecutor transfers the files only if they differ from the remote ones. The systemd
+// unit is staged to /tmp and moved to /etc/systemd/system by InitConfig, so its transfer
+// is deferred to the move, and both are skipped if the units are not pushed.
+type pushExecutor struct {
+	inner executor.TiOpsExecutor
+	units bool
+
+	mu      sync.Mutex
+	staged  map[string]string // the staged unit to its local source
+	changed []string
+}
+
+// Execute implements the TiOpsExecutor interface
+func (e *pushExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 3 && fields[0] == "mv" && strings.HasPrefix(fields[2], "/etc/systemd/system/") {
+		e.mu.Lock()
+		src, ok := e.staged[fields[1]]
+		e.mu.Unlock()
+		if ok {
+			return nil, nil, e.pushUnit(src, fields[1], fields[2], cmd, sudo)
+		}
+	}
+	return e.inner.Execute(cmd, sudo, timeout...)
+}
+
+func (e *pushExecutor) pushUnit(src, staged, dst, cmd string, sudo bool) error {
+	if !e.units {
+		return nil
+	}
+	same, err := e.same(src, dst)
+	if err != nil || same {
+		return err
+	}
+	if err := e.inner.Transfer(src, staged, false); err != nil {
+		return err
+	}
+	if _, _, err := e.inner.Execute(cmd, sudo); err != nil {
+		return err
+	}
+	e.record(dst)
+	return nil
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *pushExecutor) Transfer(src string, dst string, download bool) error {
+	if download {
+		return e.inner.Transfer(src, dst, download)
+	}
+	if strings.HasPrefix(dst, "/tmp/") && strings.HasSuffix(dst, ".service") {
+		e.mu.Lock()
+		e.staged[dst] = src
+		e.mu.Unlock()
+		return nil
+	}
+	same, err := e.same(src, dst)
+	if err != nil || same {
+		return err
+	}
+	if err := e.inner.Transfer(src, dst, false); err != nil {
+		return err
+	}
+	e.record(dst)
+	return nil
+}
+
+// same returns whether the remote file has the same content as the local one
+func (e *pushExecutor) same(src, dst string) (bool, error) {
+	local, err := executor.FileChecksum(src)
+	if err != nil {
+		return false, errors.Annotatef(err, "failed to compute the checksum of %s", src)
+	}
+	stdout, _, err := e.inner.Execute(fmt.Sprintf("sha256sum %s 2>/dev/null || true", dst), true)
+	if err != nil {
+		return false, errors.Annotatef(err, "failed to compute the checksum of %s", dst)
+	}
+	fields := strings.Fields(string(stdout))
+	return len(fields) > 0 && fields[0] == local, nil
+}
+
+func (e *pushExecutor) record(dst string) {
+	e.mu.Lock()
+	e.changed = append(e.changed, dst)
+	e.mu.Unlock()
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// pushTask returns a PushConfig task of the TiDB instance
+func pushTask(c *C, units bool) *PushConfig {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	return &PushConfig{
+		clusterName:    "test",
+		clusterVersion: "v4.0.0",
+		deployUser:     "tidb",
+		instances:      (&meta.TiDBComponent{Specification: &topo}).Instances(),
+		units:          units,
+	}
+}
+
+// pushExecutorMock answers the checksums of the remote files by sums, and records the
+// commands and transfers
+func pushExecutorMock(sums map[string]string) *mockExecutor {
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "sha256sum ") {
+			path := strings.Fields(cmd)[1]
+			if sum, ok := sums[path]; ok {
+				return []byte(fmt.Sprintf("%s  %s\n", sum, path)), nil, nil
+			}
+		}
+		return nil, nil, nil
+	}}
+}
+
+func setupPushConfig(c *C) func() {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	dir, err := ioutil.TempDir("", "push-config")
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	os.Setenv(localdata.EnvNameComponentDataDir, dir)
+	c.Assert(meta.Initialize(), IsNil)
+	return func() {
+		os.Unsetenv(localdata.EnvNameComponentInstallDir)
+		os.Unsetenv(localdata.EnvNameComponentDataDir)
+		os.RemoveAll(dir)
+	}
+}
+
+func (s *taskSuite) TestPushConfig(c *C) {
+	defer setupPushConfig(c)()
+
+	// everything is pushed to a fresh host, except the unit
+	t := pushTask(c, false)
+	e := pushExecutorMock(nil)
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Changed(), DeepEquals, map[string][]string{"172.16.5.140:4000": {
+		"/home/tidb/deploy/tidb-4000/scripts/run_tidb.sh",
+		"/home/tidb/deploy/tidb-4000/conf/tidb.toml",
+	}})
+	sums := make(map[string]string)
+	for _, transfer := range e.transfers {
+		paths := strings.Split(transfer, " -> ")
+		c.Assert(paths[1], Not(Matches), ".*\\.service")
+		sum, err := executor.FileChecksum(paths[0])
+		c.Assert(err, IsNil)
+		sums[paths[1]] = sum
+	}
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "systemctl daemon-reload")
+	for _, cmd := range cmds {
+		c.Assert(cmd, Not(Matches), ".*systemctl (start|stop|restart).*")
+		c.Assert(cmd, Not(Matches), "mv .*")
+	}
+
+	// nothing is transferred if the config is not changed
+	e = pushExecutorMock(sums)
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Changed()["172.16.5.140:4000"], HasLen, 0)
+	c.Assert(e.transfers, HasLen, 0)
+
+	// the unit is pushed as well
+	t = pushTask(c, true)
+	e = pushExecutorMock(sums)
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Changed(), DeepEquals, map[string][]string{"172.16.5.140:4000": {"/etc/systemd/system/tidb-4000.service"}})
+	c.Assert(e.transfers, HasLen, 1)
+	c.Assert(e.transfers[0], Matches, ".*tidb-172.16.5.140-4000.service -> /tmp/tidb_.*\\.service")
+	cmds = e.commands()
+	c.Assert(cmds[0], Equals, "sha256sum /etc/systemd/system/tidb-4000.service 2>/dev/null || true")
+	c.Assert(cmds[1], Matches, "mv /tmp/tidb_.*\\.service /etc/systemd/system/tidb-4000.service")
+	c.Assert(cmds[len(cmds)-1], Equals, "systemctl daemon-reload")
+}
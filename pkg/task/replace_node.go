@@ -0,0 +1,72 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+)
+
+// reachableTimeout is the timeout of probing whether the host of the replaced node is alive
+const reachableTimeout = time.Second * 10
+
+// ReplaceNode is used to replace a node by its successor on another host. The successor is
+// deployed and joins the cluster before the replaced node is removed, so the replicas on the
+// replaced node are kept until the new one is serving. The replaced node is removed without
+// touching its host if the host is unreachable.
+type ReplaceNode struct {
+	oldHost string
+	deploy  Task
+	join    Task
+	remove  func(reachable bool) Task
+
+	reachable bool
+}
+
+// Execute implements the Task interface
+func (r *ReplaceNode) Execute(ctx *Context) error {
+	if err := ctx.execute(r.deploy); err != nil {
+		return err
+	}
+	if err := ctx.execute(r.join); err != nil {
+		return err
+	}
+
+	r.reachable = true
+	if e, found := ctx.GetExecutor(r.oldHost); !found {
+		r.reachable = false
+	} else if _, _, err := e.Execute("true", false, reachableTimeout); err != nil {
+		log.Warnf("The host %s of the replaced node is unreachable: %s", r.oldHost, err)
+		r.reachable = false
+	}
+
+	return ctx.execute(r.remove(r.reachable))
+}
+
+// Reachable returns whether the host of the replaced node was reachable when removing it
+func (r *ReplaceNode) Reachable() bool {
+	return r.reachable
+}
+
+// Rollback implements the Task interface
+func (r *ReplaceNode) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (r *ReplaceNode) String() string {
+	return fmt.Sprintf("ReplaceNode: host=%s", r.oldHost)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/pingcap/check"
+)
+
+// replaceSteps returns the tasks of replacing a node which record the steps run
+func replaceSteps(steps *[]string, deployErr error) (Task, Task, func(bool) Task) {
+	deploy := NewBuilder().Func("deploy", func() error {
+		*steps = append(*steps, "deploy 172.16.5.150")
+		return deployErr
+	}).Build()
+	join := NewBuilder().Func("join", func() error {
+		*steps = append(*steps, "join 172.16.5.150")
+		return nil
+	}).Build()
+	remove := func(reachable bool) Task {
+		return NewBuilder().Func("remove", func() error {
+			*steps = append(*steps, fmt.Sprintf("remove 172.16.5.140 reachable=%v", reachable))
+			return nil
+		}).Build()
+	}
+	return deploy, join, remove
+}
+
+func (s *taskSuite) TestReplaceNode(c *C) {
+	var steps []string
+	e := &mockExecutor{}
+	deploy, join, remove := replaceSteps(&steps, nil)
+	t := &ReplaceNode{oldHost: "172.16.5.140", deploy: deploy, join: join, remove: remove}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Reachable(), IsTrue)
+	c.Assert(steps, DeepEquals, []string{
+		"deploy 172.16.5.150",
+		"join 172.16.5.150",
+		"remove 172.16.5.140 reachable=true",
+	})
+	c.Assert(e.commands(), DeepEquals, []string{"true"})
+}
+
+func (s *taskSuite) TestReplaceNodeUnreachable(c *C) {
+	var steps []string
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, nil, errors.New("dial tcp 172.16.5.140:22: i/o timeout")
+	}}
+	deploy, join, remove := replaceSteps(&steps, nil)
+	t := &ReplaceNode{oldHost: "172.16.5.140", deploy: deploy, join: join, remove: remove}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Reachable(), IsFalse)
+	c.Assert(steps, DeepEquals, []string{
+		"deploy 172.16.5.150",
+		"join 172.16.5.150",
+		"remove 172.16.5.140 reachable=false",
+	})
+
+	// the replaced node is kept if the successor fails to deploy
+	steps = nil
+	deploy, join, remove = replaceSteps(&steps, errors.New("disk full"))
+	t = &ReplaceNode{oldHost: "172.16.5.140", deploy: deploy, join: join, remove: remove}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), ErrorMatches, "disk full")
+	c.Assert(steps, DeepEquals, []string{"deploy 172.16.5.150"})
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+)
+
+var (
+	// ErrRetryExhausted means some tasks still fail after all the retry rounds
+	ErrRetryExhausted = errNS.NewType("retry_exhausted")
+)
+
+// RetryParallel executes the tasks in parallel like Parallel, then retries only the
+// failed ones in parallel for at most rounds times. The delay before the first retry
+// is backoff and it's doubled every round. The tasks succeeded are never executed again.
+// At most concurrency tasks are executed at the same time if it's positive.
+type RetryParallel struct {
+	hideDetailDisplay bool
+	inner             []Task
+	rounds            int
+	backoff           time.Duration
+	concurrency       int
+}
+
+// Execute implements the Task interface
+func (pt *RetryParallel) Execute(ctx *Context) error {
+	ctx.markExecuted(pt)
+	return ctx.runBeforeDeadline(func() error {
+		return pt.retry(ctx)
+	})
+}
+
+// retry executes the tasks and retries the failed ones in rounds
+func (pt *RetryParallel) retry(ctx *Context) error {
+	pending := pt.inner
+	delay := pt.backoff
+	for round := 0; ; round++ {
+		failed, errs := pt.execute(ctx, pending)
+		if len(failed) == 0 {
+			return nil
+		}
+
+		if round >= pt.rounds {
+			return errorx.WrapMany(ErrRetryExhausted,
+				fmt.Sprintf("%d of %d tasks failed after %d retries", len(failed), len(pt.inner), round),
+				errs...)
+		}
+		if !ctx.deadline.IsZero() && time.Now().Add(delay).After(ctx.deadline) {
+			return errorx.WrapMany(ErrRetryExhausted,
+				fmt.Sprintf("%d of %d tasks failed and there is no time to retry before the deadline", len(failed), len(pt.inner)),
+				errs...)
+		}
+
+		ctx.Logger().Warnf("%d of %d tasks failed, retrying them in %s (%d/%d)", len(failed), len(pt.inner), delay, round+1, pt.rounds)
+		time.Sleep(delay)
+		delay *= 2
+		pending = failed
+	}
+}
+
+// execute executes the tasks in parallel and returns the failed ones with their errors
+// in the original order
+func (pt *RetryParallel) execute(ctx *Context, tasks []Task) ([]Task, []error) {
+	errs := make([]error, len(tasks))
+	ctx.runAll(len(tasks), pt.concurrency, func(i int) {
+		t := tasks[i]
+		if errs[i] = ctx.checkDeadline(); errs[i] != nil {
+			return
+		}
+		if !isDisplayTask(t) {
+			if !pt.hideDetailDisplay {
+				ctx.Logger().Infof("+ [Parallel] - %s", t.String())
+			}
+		}
+		ctx.ev.PublishTaskBegin(t)
+		errs[i] = ctx.execute(t)
+		ctx.ev.PublishTaskFinish(t, errs[i])
+	})
+
+	var (
+		failed     []Task
+		failedErrs []error
+	)
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, tasks[i])
+			failedErrs = append(failedErrs, err)
+		}
+	}
+	return failed, failedErrs
+}
+
+// Rollback implements the Task interface
+func (pt *RetryParallel) Rollback(ctx *Context) error {
+	return (&Parallel{inner: pt.inner, concurrency: pt.concurrency}).Rollback(ctx)
+}
+
+// String implements the fmt.Stringer interface
+func (pt *RetryParallel) String() string {
+	var ss []string
+	for _, t := range pt.inner {
+		ss = append(ss, t.String())
+	}
+	return strings.Join(ss, "\n")
+}
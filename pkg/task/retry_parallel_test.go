@@ -0,0 +1,98 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// flakyTask returns a task which fails for the first failures executions
+func flakyTask(name string, failures int32, executed *int32) Task {
+	return &Func{name: name, fn: func() error {
+		if n := atomic.AddInt32(executed, 1); n <= failures {
+			return fmt.Errorf("%s failed at #%d", name, n)
+		}
+		return nil
+	}}
+}
+
+func (s *taskSuite) TestRetryParallel(c *C) {
+	var executed [4]int32
+	t := NewBuilder().
+		RetryParallel(3, 10*time.Millisecond,
+			flakyTask("t0", 0, &executed[0]),
+			flakyTask("t1", 1, &executed[1]),
+			flakyTask("t2", 0, &executed[2]),
+			flakyTask("t3", 2, &executed[3]),
+		).
+		Build()
+
+	start := time.Now()
+	c.Assert(t.Execute(NewContext()), IsNil)
+	// the succeeded tasks are never executed again
+	c.Assert(executed, DeepEquals, [4]int32{1, 2, 1, 3})
+	// backoff of 10ms and 20ms
+	c.Assert(time.Since(start) >= 30*time.Millisecond, IsTrue)
+}
+
+func (s *taskSuite) TestRetryParallelExhausted(c *C) {
+	var executed [3]int32
+	t := NewBuilder().
+		RetryParallel(2, time.Millisecond,
+			flakyTask("t0", 1, &executed[0]),
+			flakyTask("t1", 10, &executed[1]),
+			flakyTask("t2", 10, &executed[2]),
+		).
+		Build()
+
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrRetryExhausted), IsTrue)
+	c.Assert(err.Error(), Matches, ".*2 of 3 tasks failed after 2 retries.*t1 failed at #3.*t2 failed at #3.*")
+	c.Assert(executed, DeepEquals, [3]int32{2, 3, 3})
+}
+
+func (s *taskSuite) TestRetryParallelNoRetry(c *C) {
+	var executed int32
+	t := NewBuilder().
+		RetryParallel(0, time.Millisecond, flakyTask("t0", 1, &executed)).
+		Build()
+
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrRetryExhausted), IsTrue)
+	c.Assert(executed, Equals, int32(1))
+}
+
+func (s *taskSuite) TestRetryParallelDeadline(c *C) {
+	var executed int32
+	t := NewBuilder().
+		RetryParallel(3, time.Hour, &Func{name: "t0", fn: func() error {
+			atomic.AddInt32(&executed, 1)
+			return errors.New("failed")
+		}}).
+		Build()
+
+	// there is no time to wait for the backoff before the deadline
+	ctx := NewContext()
+	ctx.SetDeadline(time.Now().Add(time.Minute))
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrRetryExhausted), IsTrue)
+	c.Assert(err.Error(), Matches, ".*no time to retry before the deadline.*")
+	c.Assert(executed, Equals, int32(1))
+}
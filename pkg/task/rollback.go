@@ -0,0 +1,180 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+)
+
+var (
+	errNSRollback = errNS.NewSubNamespace("rollback")
+	// ErrRollbackTaskNotFound means there is no task with the specified ID in the task tree
+	ErrRollbackTaskNotFound = errNSRollback.NewType("task_not_found")
+	// ErrRollbackTaskNotExecuted means the task to be rolled back has never been executed
+	ErrRollbackTaskNotExecuted = errNSRollback.NewType("task_not_executed")
+	// ErrRollbackRecordMismatch means the task tree differs from the one the record is made of
+	ErrRollbackRecordMismatch = errNSRollback.NewType("record_mismatch")
+)
+
+// markExecuted records the task has been executed
+func (ctx *Context) markExecuted(t Task) {
+	ctx.executed.Lock()
+	ctx.executed.tasks[t] = struct{}{}
+	ctx.executed.Unlock()
+}
+
+// Executed returns if the task has been executed with this context, no matter
+// it's succeeded or not
+func (ctx *Context) Executed(t Task) bool {
+	ctx.executed.Lock()
+	defer ctx.executed.Unlock()
+	_, ok := ctx.executed.tasks[t]
+	return ok
+}
+
+// children returns the inner tasks of the composite tasks, and nil for the others
+func children(t Task) []Task {
+	switch t := t.(type) {
+	case *Serial:
+		return t.inner
+	case *Parallel:
+		return t.inner
+	case *RetryParallel:
+		return t.inner
+	case *StepDisplay:
+		return []Task{t.inner}
+	case *ParallelStepDisplay:
+		return []Task{t.inner}
+	case *Timeout:
+		return []Task{t.inner}
+	}
+	return nil
+}
+
+// Walk visits all tasks in the tree in pre-order. The ID of a task is the path of
+// indexes from the root to it, e.g: "2/0" is the first inner task of the third inner
+// task of root, and the ID of root itself is "".
+func Walk(root Task, fn func(id string, t Task)) {
+	walk("", root, fn)
+}
+
+func walk(id string, t Task, fn func(id string, t Task)) {
+	fn(id, t)
+	for i, child := range children(t) {
+		walk(joinID(id, i), child, fn)
+	}
+}
+
+func joinID(parent string, index int) string {
+	if parent == "" {
+		return strconv.Itoa(index)
+	}
+	return parent + "/" + strconv.Itoa(index)
+}
+
+// Find returns the task with the ID in the tree of root, see Walk for the format of ID
+func Find(root Task, id string) (Task, bool) {
+	t := root
+	if id == "" {
+		return t, true
+	}
+	for _, part := range strings.Split(id, "/") {
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		inner := children(t)
+		if index < 0 || index >= len(inner) {
+			return nil, false
+		}
+		t = inner[index]
+	}
+	return t, true
+}
+
+// RollbackSubtree rolls back the task with the ID and all its inner tasks which have
+// been executed with ctx, the inner tasks are rolled back in reverse order and the
+// ones which have never been executed or don't support rollback are skipped.
+// It's used to recover from a failed operation without rolling back the whole operation.
+//
+// The executed tasks are tracked in the memory of ctx, to roll back the subtree of an
+// operation of a previous run, the executed tasks are persisted by an ExecutionRecord and
+// applied to the same task tree rebuilt by the later run.
+func RollbackSubtree(ctx *Context, root Task, id string) error {
+	t, found := Find(root, id)
+	if !found {
+		return ErrRollbackTaskNotFound.New("task '%s' not found", id)
+	}
+	if !ctx.Executed(t) {
+		return ErrRollbackTaskNotExecuted.New("task '%s' has never been executed", id)
+	}
+	return rollbackExecuted(ctx, id, t)
+}
+
+func rollbackExecuted(ctx *Context, id string, t Task) error {
+	if !ctx.Executed(t) {
+		return nil
+	}
+
+	inner := children(t)
+	if inner == nil {
+		err := t.Rollback(ctx)
+		if err == ErrUnsupportedRollback {
+			log.Warnf("Skip rolling back task '%s' which doesn't support rollback: %s", id, t.String())
+			return nil
+		}
+		return err
+	}
+
+	for i := len(inner) - 1; i >= 0; i-- {
+		if err := rollbackExecuted(ctx, joinID(id, i), inner[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecutionRecord is the persistable record of the tasks executed in a tree, by the ID of
+// each task, see Walk, to its description which is used to check the tree is rebuilt the same
+type ExecutionRecord map[string]string
+
+// NewExecutionRecord returns the record of the tasks in the tree executed with ctx
+func NewExecutionRecord(ctx *Context, root Task) ExecutionRecord {
+	r := make(ExecutionRecord)
+	Walk(root, func(id string, t Task) {
+		if ctx.Executed(t) {
+			r[id] = t.String()
+		}
+	})
+	return r
+}
+
+// Apply marks the tasks in the record as executed with ctx, the tree must be rebuilt the same
+// as the one of the record
+func (r ExecutionRecord) Apply(ctx *Context, root Task) error {
+	for id, desc := range r {
+		t, found := Find(root, id)
+		if !found || t.String() != desc {
+			return ErrRollbackRecordMismatch.
+				New("task '%s' is not '%s' in the rebuilt task tree", id, desc).
+				WithProperty(cliutil.SuggestionFromString("The cluster or the arguments may have changed since the operation failed, the tasks can't be rolled back by the record."))
+		}
+		ctx.markExecuted(t)
+	}
+	return nil
+}
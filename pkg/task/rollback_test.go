@@ -0,0 +1,141 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/errors"
+)
+
+// recordTask records the names of rolled back tasks
+type recordTask struct {
+	name   string
+	fail   bool
+	mu     *sync.Mutex
+	rolled *[]string
+}
+
+func (t *recordTask) Execute(ctx *Context) error {
+	if t.fail {
+		return errors.Errorf("%s failed", t.name)
+	}
+	return nil
+}
+
+func (t *recordTask) Rollback(ctx *Context) error {
+	t.mu.Lock()
+	*t.rolled = append(*t.rolled, t.name)
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *recordTask) String() string {
+	return t.name
+}
+
+func (s *taskSuite) TestRollbackSubtree(c *C) {
+	var mu sync.Mutex
+	var rolled []string
+	newTask := func(name string, fail bool) Task {
+		return &recordTask{name: name, fail: fail, mu: &mu, rolled: &rolled}
+	}
+
+	root := NewBuilder().
+		Serial(newTask("t0", false)).
+		Parallel(newTask("t1a", false), newTask("t1b", false)).
+		Serial(NewBuilder().
+			Serial(newTask("t2a", false), newTask("t2b", true), newTask("t2c", false)).
+			Func("fn", func() error { return nil }).
+			Build()).
+		Serial(newTask("t3", false)).
+		Build()
+
+	ctx := NewContext()
+	c.Assert(root.Execute(ctx), NotNil)
+
+	var ids []string
+	Walk(root, func(id string, t Task) {
+		ids = append(ids, id)
+	})
+	c.Assert(ids, DeepEquals, []string{"", "0", "1", "1/0", "1/1", "2", "2/0", "2/1", "2/2", "2/3", "3"})
+
+	t, found := Find(root, "2/1")
+	c.Assert(found, IsTrue)
+	c.Assert(t.String(), Equals, "t2b")
+	_, found = Find(root, "2/9")
+	c.Assert(found, IsFalse)
+
+	// the Parallel subtree
+	c.Assert(RollbackSubtree(ctx, root, "1"), IsNil)
+	c.Assert(rolled, HasLen, 2)
+	c.Assert(rolled[0], Equals, "t1b")
+	c.Assert(rolled[1], Equals, "t1a")
+
+	// the failed subtree, t2c and fn are never executed
+	rolled = nil
+	c.Assert(RollbackSubtree(ctx, root, "2"), IsNil)
+	c.Assert(rolled, DeepEquals, []string{"t2b", "t2a"})
+
+	rolled = nil
+	err := RollbackSubtree(ctx, root, "3")
+	c.Assert(errorx.IsOfType(err, ErrRollbackTaskNotExecuted), IsTrue)
+	err = RollbackSubtree(ctx, root, "2/2")
+	c.Assert(errorx.IsOfType(err, ErrRollbackTaskNotExecuted), IsTrue)
+	err = RollbackSubtree(ctx, root, "4")
+	c.Assert(errorx.IsOfType(err, ErrRollbackTaskNotFound), IsTrue)
+	c.Assert(rolled, HasLen, 0)
+}
+
+func (s *taskSuite) TestRollbackByExecutionRecord(c *C) {
+	var mu sync.Mutex
+	var rolled []string
+	build := func(name string) Task {
+		newTask := func(name string, fail bool) Task {
+			return &recordTask{name: name, fail: fail, mu: &mu, rolled: &rolled}
+		}
+		return NewBuilder().
+			Serial(newTask("t0", false)).
+			RetryParallel(1, 0, newTask("t1a", false), newTask("t1b", false)).
+			Serial(newTask(name, true), newTask("t3", false)).
+			Build()
+	}
+
+	root := build("t2")
+	ctx := NewContext()
+	c.Assert(root.Execute(ctx), NotNil)
+	record := NewExecutionRecord(ctx, root)
+	c.Assert(record["1/1"], Equals, "t1b")
+	_, ok := record["3"]
+	c.Assert(ok, IsFalse)
+
+	// the persisted record is applied to the tree rebuilt by another run
+	data, err := json.Marshal(record)
+	c.Assert(err, IsNil)
+	var loaded ExecutionRecord
+	c.Assert(json.Unmarshal(data, &loaded), IsNil)
+	root = build("t2")
+	ctx = NewContext()
+	c.Assert(loaded.Apply(ctx, root), IsNil)
+	c.Assert(RollbackSubtree(ctx, root, "1"), IsNil)
+	c.Assert(rolled, DeepEquals, []string{"t1b", "t1a"})
+	err = RollbackSubtree(ctx, root, "3")
+	c.Assert(errorx.IsOfType(err, ErrRollbackTaskNotExecuted), IsTrue)
+
+	err = loaded.Apply(NewContext(), build("t2'"))
+	c.Assert(errorx.IsOfType(err, ErrRollbackRecordMismatch), IsTrue)
+}
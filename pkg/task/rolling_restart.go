@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+)
+
+// RestartInstance is used to restart a single instance and wait for it to be ready, the
+// instance is stopped by the stop policy before started again if it's set. The leaders of PD
+// and TiKV instances are transferred before they are restarted unless it's forced.
+type RestartInstance struct {
+	spec     *meta.Specification
+	instance meta.Instance
+	options  operator.Options
+}
+
+// Execute implements the Task interface
+func (r *RestartInstance) Execute(ctx *Context) error {
+	if r.options.Force {
+		return r.restart(ctx)
+	}
+	return operator.RestartWithLeaderTransferred(r.spec, r.instance, operator.DrainRetryOption(r.options), func() error {
+		return r.restart(ctx)
+	})
+}
+
+func (r *RestartInstance) restart(ctx *Context) error {
+	policy, escalate := r.options.StopPolicy()
+	if !escalate {
+		return operator.RestartInstance(ctx, r.instance)
+	}
+	if err := operator.StopComponentWithPolicy(ctx, []meta.Instance{r.instance}, policy); err != nil {
+		return err
+	}
+	return operator.StartComponent(ctx, []meta.Instance{r.instance})
+}
+
+// Rollback implements the Task interface
+func (r *RestartInstance) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (r *RestartInstance) String() string {
+	return fmt.Sprintf("RestartInstance: component=%s, instance=%s", r.instance.ComponentName(), r.instance.ID())
+}
+
+// DrainCDC is used to move the changefeeds of the TiCDC instances to the other captures
+// before they are restarted
+type DrainCDC struct {
+	spec      *meta.Specification
+	instances []meta.Instance
+	options   operator.Options
+}
+
+// Execute implements the Task interface
+func (d *DrainCDC) Execute(ctx *Context) error {
+	return operator.DrainCDC(d.spec, d.instances, operator.DrainRetryOption(d.options))
+}
+
+// Rollback implements the Task interface
+func (d *DrainCDC) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (d *DrainCDC) String() string {
+	var ids []string
+	for _, inst := range d.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("DrainCDC: instances=%s", strings.Join(ids, ","))
+}
+
+// WaitDrainerSynced is used to wait for the Drainer instances to replicate the pending
+// binlogs before they are restarted
+type WaitDrainerSynced struct {
+	instances []meta.Instance
+	options   operator.Options
+}
+
+// Execute implements the Task interface
+func (w *WaitDrainerSynced) Execute(ctx *Context) error {
+	return operator.WaitDrainersSynced(w.instances, operator.DrainRetryOption(w.options))
+}
+
+// Rollback implements the Task interface
+func (w *WaitDrainerSynced) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (w *WaitDrainerSynced) String() string {
+	var ids []string
+	for _, inst := range w.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("WaitDrainerSynced: instances=%s", strings.Join(ids, ","))
+}
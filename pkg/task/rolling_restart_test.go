@@ -0,0 +1,164 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	. "github.com/pingcap/check"
+)
+
+var rollingTopology = `
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+  - host: 172.16.5.143
+  - host: 172.16.5.144
+`
+
+// rollingBatches returns the instances restarted by each batch of the rolling restart
+func rollingBatches(t Task) [][]string {
+	var batches [][]string
+	for _, batch := range t.(*Serial).inner {
+		var ids []string
+		for _, inner := range batch.(*Parallel).inner {
+			r := inner.(*RestartInstance)
+			ids = append(ids, r.instance.ComponentName()+"@"+r.instance.GetHost())
+		}
+		batches = append(batches, ids)
+	}
+	return batches
+}
+
+func (s *taskSuite) TestRollingRestartPolicy(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(rollingTopology), &topo), IsNil)
+
+	policy := operator.ConcurrencyPolicy{meta.ComponentTiDB: 4}
+	t := NewBuilder().RollingRestart(&topo, operator.Options{}, policy, nil).Build()
+	c.Assert(rollingBatches(t), DeepEquals, [][]string{
+		{"pd@172.16.5.140"},
+		{"tikv@172.16.5.140"},
+		{"tikv@172.16.5.141"},
+		{"tikv@172.16.5.142"},
+		{"tidb@172.16.5.140", "tidb@172.16.5.141", "tidb@172.16.5.142", "tidb@172.16.5.143"},
+		{"tidb@172.16.5.144"},
+	})
+}
+
+func (s *taskSuite) TestRollingRestartDefaultPolicy(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(rollingTopology), &topo), IsNil)
+
+	// zero or negative values fall back to the defaults
+	policy := operator.ConcurrencyPolicy{meta.ComponentTiKV: 0}
+	c.Assert(policy.Concurrency(meta.ComponentTiKV), Equals, 1)
+	c.Assert(policy.Concurrency(meta.ComponentPD), Equals, 1)
+	c.Assert(policy.Concurrency(meta.ComponentTiDB), Equals, 2)
+
+	t := NewBuilder().RollingRestart(&topo, operator.Options{Roles: []string{meta.ComponentTiDB}}, policy, nil).Build()
+	c.Assert(rollingBatches(t), DeepEquals, [][]string{
+		{"tidb@172.16.5.140", "tidb@172.16.5.141"},
+		{"tidb@172.16.5.142", "tidb@172.16.5.143"},
+		{"tidb@172.16.5.144"},
+	})
+}
+
+func (s *taskSuite) TestRollingRestartZones(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { zone: z2 }
+  - host: 172.16.5.141
+    config:
+      server.labels: { zone: z1 }
+  - host: 172.16.5.142
+    config:
+      server.labels: { zone: z1 }
+tidb_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+  - host: 172.16.5.143
+`), &topo), IsNil)
+	zones, err := operator.Zones(&topo, "zone")
+	c.Assert(err, IsNil)
+
+	t := NewBuilder().RollingRestart(&topo, operator.Options{}, operator.ConcurrencyPolicy{}, zones).Build()
+	batches := rollingBatches(t)
+	c.Assert(batches, DeepEquals, [][]string{
+		{"pd@172.16.5.141"},
+		{"tikv@172.16.5.141"},
+		{"tikv@172.16.5.142"},
+		{"tidb@172.16.5.141", "tidb@172.16.5.142"},
+		{"pd@172.16.5.140"},
+		{"tikv@172.16.5.140"},
+		{"tidb@172.16.5.140"},
+		{"tidb@172.16.5.143"},
+	})
+
+	// a batch never spans zones, and a zone is never revisited after it's done
+	zoneOf := map[string]string{"172.16.5.140": "z2", "172.16.5.141": "z1", "172.16.5.142": "z1", "172.16.5.143": ""}
+	var done []string
+	visited := map[string]bool{}
+	for _, batch := range batches {
+		zone := zoneOf[strings.SplitN(batch[0], "@", 2)[1]]
+		for _, id := range batch[1:] {
+			c.Assert(zoneOf[strings.SplitN(id, "@", 2)[1]], Equals, zone)
+		}
+		if len(done) == 0 || done[len(done)-1] != zone {
+			c.Assert(visited[zone], IsFalse)
+			visited[zone] = true
+			done = append(done, zone)
+		}
+	}
+	c.Assert(done, DeepEquals, []string{"z1", "z2", ""})
+}
+
+func (s *taskSuite) TestRollingRestartClamp(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(rollingTopology), &topo), IsNil)
+
+	// a region of 3 replicas loses its quorum if 2 stores are down
+	policy := operator.ConcurrencyPolicy{meta.ComponentTiKV: 3, meta.ComponentPD: 2}
+	t := NewBuilder().RollingRestart(&topo, operator.Options{Roles: []string{meta.ComponentTiKV, meta.ComponentPD}}, policy, nil).Build()
+	c.Assert(rollingBatches(t), DeepEquals, [][]string{
+		{"pd@172.16.5.140"},
+		{"tikv@172.16.5.140"},
+		{"tikv@172.16.5.141"},
+		{"tikv@172.16.5.142"},
+	})
+	c.Assert(policy[meta.ComponentTiKV], Equals, 3)
+
+	topo.ServerConfigs.PD = map[string]interface{}{"replication.max-replicas": 5}
+	clamped := policy.Clamp(&topo)
+	c.Assert(clamped.Concurrency(meta.ComponentTiKV), Equals, 2)
+	c.Assert(clamped.Concurrency(meta.ComponentPD), Equals, 1)
+	c.Assert(clamped.Concurrency(meta.ComponentTiDB), Equals, 2)
+}
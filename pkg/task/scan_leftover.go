@@ -0,0 +1,289 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSLeftover = errNS.NewSubNamespace("leftover")
+	// ErrLeftoverFound means there are stray processes or partial files left by a previous deploy
+	ErrLeftoverFound = errNSLeftover.NewType("found", errutil.ErrTraitPreCheck)
+)
+
+// StrayProcess is a process listening on a port of the cluster which is not managed by
+// the systemd unit expected for the port
+type StrayProcess struct {
+	Port    int
+	PID     int
+	Command string
+	Unit    string // the systemd unit the process belongs to, empty if it's not in any
+	Exe     string // the executable of the process
+	// Leftover means the executable or the working directory of the process is in a deploy
+	// directory, so it's left by a previous deploy rather than another service of the host
+	Leftover bool
+}
+
+// ScanLeftover is used to detect the leftovers of a previous deploy on the host: the
+// processes listening on the ports of the cluster but not managed by the expected
+// systemd units, and the partial files in the deploy directories, which are the packages
+// not extracted and the empty files in the bin directories. They are killed and removed
+// if clean is enabled, except the processes running outside the deploy directories, which
+// are other services of the host occupying the ports, e.g. nginx, and are only reported.
+type ScanLeftover struct {
+	host  string
+	units map[int]string // port -> the systemd unit expected to listen on it
+	dirs  []string       // deploy directories
+	clean bool
+
+	strays   []StrayProcess
+	partials []string
+}
+
+// Execute implements the Task interface
+func (s *ScanLeftover) Execute(ctx *Context) error {
+	e, found := ctx.GetExecutor(s.host)
+	if !found {
+		return ErrNoExecutor
+	}
+	s.strays = nil
+	s.partials = nil
+
+	// The process info of other users is only shown to root
+	stdout, _, err := e.Execute("ss -ltnp", true)
+	if err != nil {
+		return errors.Annotatef(err, "failed to list the listening ports of %s", s.host)
+	}
+	pidUnits := make(map[int]string)
+	for _, l := range parseListeners(string(stdout)) {
+		unit, ok := s.units[l.Port]
+		if !ok {
+			continue
+		}
+		if _, ok := pidUnits[l.PID]; !ok {
+			cgroup, _, err := e.Execute(fmt.Sprintf("cat /proc/%d/cgroup", l.PID), true)
+			if err != nil {
+				return errors.Annotatef(err, "failed to get the cgroup of process %d on %s", l.PID, s.host)
+			}
+			pidUnits[l.PID] = parseSystemdUnit(string(cgroup))
+		}
+		l.Unit = pidUnits[l.PID]
+		if l.Unit != unit {
+			s.strays = append(s.strays, l)
+		}
+	}
+	owners := make(map[int][2]string)
+	for i := range s.strays {
+		p := &s.strays[i]
+		owner, ok := owners[p.PID]
+		if !ok {
+			// the process may exit in the meantime, it's not a leftover then
+			exe, _, _ := e.Execute(fmt.Sprintf("readlink /proc/%d/exe", p.PID), true)
+			cwd, _, _ := e.Execute(fmt.Sprintf("readlink /proc/%d/cwd", p.PID), true)
+			owner = [2]string{strings.TrimSpace(string(exe)), strings.TrimSpace(string(cwd))}
+			owners[p.PID] = owner
+		}
+		p.Exe = owner[0]
+		p.Leftover = s.inDeployDir(owner[0]) || s.inDeployDir(owner[1])
+	}
+
+	if len(s.dirs) > 0 {
+		var bins []string
+		for _, dir := range s.dirs {
+			bins = append(bins, filepath.Join(dir, "bin"))
+		}
+		cmd := fmt.Sprintf(`find %s -maxdepth 1 -type f \( -name '*.tar.gz' -o -size 0 \) 2>/dev/null; true`, strings.Join(bins, " "))
+		stdout, _, err := e.Execute(cmd, false)
+		if err != nil {
+			return errors.Annotatef(err, "failed to find the partial files on %s", s.host)
+		}
+		for _, line := range strings.Split(string(stdout), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				s.partials = append(s.partials, line)
+			}
+		}
+	}
+
+	if len(s.strays) == 0 && len(s.partials) == 0 {
+		return nil
+	}
+	if s.clean {
+		if err := s.cleanup(e); err != nil {
+			return err
+		}
+		var foreign []string
+		for _, p := range s.strays {
+			if !p.Leftover {
+				foreign = append(foreign, s.describeProcess(p))
+			}
+		}
+		if len(foreign) == 0 {
+			return nil
+		}
+		return ErrLeftoverFound.
+			New("Found the processes not in the deploy directories listening on the ports of the cluster on %s, they are not killed:\n  - %s", s.host, strings.Join(foreign, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString("Please stop the services occupying the ports, or change the ports in the topology."))
+	}
+
+	return ErrLeftoverFound.
+		New("Found the leftovers of a previous deploy on %s:\n  - %s", s.host, strings.Join(s.describe(), "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please stop the processes and remove the files manually, or deploy with --cleanup-leftovers to clean them, the processes not in the deploy directories are never killed."))
+}
+
+// inDeployDir returns whether the path is in one of the deploy directories
+func (s *ScanLeftover) inDeployDir(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, dir := range s.dirs {
+		dir = filepath.Clean(dir)
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanup kills the stray processes left by a previous deploy and removes the partial files
+func (s *ScanLeftover) cleanup(e executor.TiOpsExecutor) error {
+	for _, p := range s.strays {
+		if p.Leftover {
+			log.Warnf("Cleaning the leftover on %s: %s", s.host, s.describeProcess(p))
+		}
+	}
+	for _, f := range s.partials {
+		log.Warnf("Cleaning the leftover on %s: partial file %s", s.host, f)
+	}
+	// a process may listen on several ports
+	var pids []string
+	killed := make(map[int]bool)
+	for _, p := range s.strays {
+		if p.Leftover && !killed[p.PID] {
+			killed[p.PID] = true
+			pids = append(pids, strconv.Itoa(p.PID))
+		}
+	}
+	if len(pids) > 0 {
+		if _, stderr, err := e.Execute(fmt.Sprintf("kill -9 %s", strings.Join(pids, " ")), true); err != nil {
+			return errors.Annotatef(err, "failed to kill the stray processes on %s, stderr: %s", s.host, stderr)
+		}
+	}
+	if len(s.partials) > 0 {
+		if _, stderr, err := e.Execute(fmt.Sprintf("rm -f %s", strings.Join(s.partials, " ")), true); err != nil {
+			return errors.Annotatef(err, "failed to remove the partial files on %s, stderr: %s", s.host, stderr)
+		}
+	}
+	return nil
+}
+
+// describe returns a line for every leftover
+func (s *ScanLeftover) describe() []string {
+	var lines []string
+	for _, p := range s.strays {
+		lines = append(lines, s.describeProcess(p))
+	}
+	for _, f := range s.partials {
+		lines = append(lines, fmt.Sprintf("partial file %s", f))
+	}
+	return lines
+}
+
+// describeProcess returns the line of a stray process
+func (s *ScanLeftover) describeProcess(p StrayProcess) string {
+	unit := p.Unit
+	if unit == "" {
+		unit = "no unit"
+	}
+	exe := p.Exe
+	if exe == "" {
+		exe = "unknown executable"
+	}
+	line := fmt.Sprintf("process %s (pid %d, %s, %s) is listening on port %d of %s",
+		p.Command, p.PID, unit, exe, p.Port, s.units[p.Port])
+	if !p.Leftover {
+		line += ", not in the deploy directories"
+	}
+	return line
+}
+
+// Leftovers returns the stray processes and the partial files found
+func (s *ScanLeftover) Leftovers() ([]StrayProcess, []string) {
+	return s.strays, s.partials
+}
+
+// listenerRegexp matches the local address and the first process of a line of `ss -ltnp`, the
+// process is like `users:(("tikv-server",pid=1234,fd=10))`, or `users:(("tikv-server",1234,10))`
+// with the old versions of ss
+var listenerRegexp = regexp.MustCompile(`\S+:(\d+)\s+\S+\s+users:\(\("([^"]+)",(?:pid=)?(\d+),`)
+
+// parseListeners parses the output of `ss -ltnp` to the processes listening on the ports,
+// a process listening on both IPv4 and IPv6 addresses of a port is returned once
+func parseListeners(output string) []StrayProcess {
+	var listeners []StrayProcess
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		m := listenerRegexp.FindStringSubmatch(line)
+		if m == nil || seen[m[1]+"/"+m[3]] {
+			continue
+		}
+		seen[m[1]+"/"+m[3]] = true
+		port, _ := strconv.Atoi(m[1])
+		pid, _ := strconv.Atoi(m[3])
+		listeners = append(listeners, StrayProcess{Port: port, PID: pid, Command: m[2]})
+	}
+	sort.Slice(listeners, func(i, j int) bool { return listeners[i].Port < listeners[j].Port })
+	return listeners
+}
+
+// parseSystemdUnit returns the systemd service of a process from its /proc/<pid>/cgroup,
+// the line is like `1:name=systemd:/system.slice/tikv-20160.service`
+func parseSystemdUnit(cgroup string) string {
+	for _, line := range strings.Split(cgroup, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		// name=systemd for cgroup v1, and the unified hierarchy (0::) for cgroup v2
+		if parts[1] != "name=systemd" && !(parts[0] == "0" && parts[1] == "") {
+			continue
+		}
+		base := filepath.Base(parts[2])
+		if strings.HasSuffix(base, ".service") {
+			return base
+		}
+	}
+	return ""
+}
+
+// Rollback implements the Task interface
+func (s *ScanLeftover) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (s *ScanLeftover) String() string {
+	return fmt.Sprintf("ScanLeftover: host=%s, dirs=%s, clean=%v", s.host, strings.Join(s.dirs, ","), s.clean)
+}
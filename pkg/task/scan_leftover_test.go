@@ -0,0 +1,160 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+const leftoverListeners = `State      Recv-Q Send-Q Local Address:Port               Peer Address:Port
+LISTEN     0      128          *:22                       *:*                   users:(("sshd",pid=812,fd=3))
+LISTEN     0      128          *:20160                    *:*                   users:(("tikv-server",pid=2301,fd=77))
+LISTEN     0      128          *:20180                    *:*                   users:(("tikv-server",pid=2301,fd=78))
+LISTEN     0      128          *:9100                     *:*                   users:(("node_exporter",pid=1888,fd=3))
+LISTEN     0      128       [::]:9100                  [::]:*                   users:(("node_exporter",pid=1888,fd=4))
+LISTEN     0      128          *:2379                     *:*                   users:(("pd-server",2155,9))
+`
+
+// leftoverExecutor returns a mocked executor with the listening ports above, pd-server
+// is managed by its unit, tikv-server is started manually from the deploy directory and
+// node_exporter is another service of the host
+func leftoverExecutor(files string) *mockExecutor {
+	cgroups := map[string]string{
+		"2301": "1:name=systemd:/user.slice/user-1000.slice/session-3.scope\n",
+		"1888": "0::/system.slice/node_exporter-9101.service\n",
+		"2155": "11:cpuset:/\n1:name=systemd:/system.slice/pd-2379.service\n",
+	}
+	links := map[string]string{
+		"readlink /proc/2301/exe": "/home/tidb/deploy/tikv-20160/bin/tikv-server (deleted)\n",
+		"readlink /proc/2301/cwd": "/home/tidb\n",
+		"readlink /proc/1888/exe": "/usr/local/bin/node_exporter\n",
+		"readlink /proc/1888/cwd": "/\n",
+	}
+	return &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		switch {
+		case cmd == "ss -ltnp":
+			return []byte(leftoverListeners), nil, nil
+		case strings.HasPrefix(cmd, "readlink "):
+			return []byte(links[cmd]), nil, nil
+		case strings.HasPrefix(cmd, "cat /proc/"):
+			return []byte(cgroups[strings.Split(cmd, "/")[2]]), nil, nil
+		case strings.HasPrefix(cmd, "find "):
+			return []byte(files), nil, nil
+		}
+		return nil, nil, nil
+	}}
+}
+
+func newScanLeftover(clean bool) *ScanLeftover {
+	return &ScanLeftover{
+		host: "172.16.5.140",
+		units: map[int]string{
+			2379:  "pd-2379.service",
+			2380:  "pd-2379.service",
+			20160: "tikv-20160.service",
+			20180: "tikv-20160.service",
+			9100:  "node_exporter-9100.service",
+		},
+		dirs:  []string{"/home/tidb/deploy/pd-2379", "/home/tidb/deploy/tikv-20160"},
+		clean: clean,
+	}
+}
+
+func (s *taskSuite) TestScanLeftover(c *C) {
+	e := leftoverExecutor("/home/tidb/deploy/tikv-20160/bin/tikv-v4.0.0-linux-amd64.tar.gz\n/home/tidb/deploy/pd-2379/bin/pd-server\n")
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := newScanLeftover(false)
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrLeftoverFound), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*
+  - process node_exporter \(pid 1888, node_exporter-9101.service, /usr/local/bin/node_exporter\) is listening on port 9100 of node_exporter-9100.service, not in the deploy directories
+  - process tikv-server \(pid 2301, no unit, /home/tidb/deploy/tikv-20160/bin/tikv-server \(deleted\)\) is listening on port 20160 of tikv-20160.service
+  - process tikv-server \(pid 2301, no unit, /home/tidb/deploy/tikv-20160/bin/tikv-server \(deleted\)\) is listening on port 20180 of tikv-20160.service
+  - partial file /home/tidb/deploy/tikv-20160/bin/tikv-v4.0.0-linux-amd64.tar.gz
+  - partial file /home/tidb/deploy/pd-2379/bin/pd-server.*`)
+
+	strays, partials := t.Leftovers()
+	c.Assert(strays, HasLen, 3)
+	c.Assert(strays[0].Leftover, IsFalse)
+	c.Assert(strays[1].Leftover, IsTrue)
+	c.Assert(partials, HasLen, 2)
+	// nothing is cleaned
+	c.Assert(e.commands(), DeepEquals, []string{
+		"ss -ltnp",
+		"cat /proc/2155/cgroup",
+		"cat /proc/1888/cgroup",
+		"cat /proc/2301/cgroup",
+		"readlink /proc/1888/exe",
+		"readlink /proc/1888/cwd",
+		"readlink /proc/2301/exe",
+		"readlink /proc/2301/cwd",
+		`find /home/tidb/deploy/pd-2379/bin /home/tidb/deploy/tikv-20160/bin -maxdepth 1 -type f \( -name '*.tar.gz' -o -size 0 \) 2>/dev/null; true`,
+	})
+}
+
+func (s *taskSuite) TestScanLeftoverClean(c *C) {
+	e := leftoverExecutor("/home/tidb/deploy/tikv-20160/bin/tikv-v4.0.0-linux-amd64.tar.gz\n")
+	ctx := newMockContext("172.16.5.140", e)
+
+	// only the process in the deploy directory is killed, the other one is reported
+	err := newScanLeftover(true).Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrLeftoverFound), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*they are not killed:
+  - process node_exporter \(pid 1888, .*\) is listening on port 9100 of node_exporter-9100.service, not in the deploy directories.*`)
+	cmds := e.commands()
+	c.Assert(cmds[len(cmds)-2:], DeepEquals, []string{
+		"kill -9 2301",
+		"rm -f /home/tidb/deploy/tikv-20160/bin/tikv-v4.0.0-linux-amd64.tar.gz",
+	})
+
+	// a process whose executable is gone but works in the deploy directory is a leftover too
+	t := newScanLeftover(true)
+	t.units = map[int]string{20160: "tikv-20160.service"}
+	e = leftoverExecutor("")
+	inner := e.handler
+	e.handler = func(cmd string) ([]byte, []byte, error) {
+		switch cmd {
+		case "readlink /proc/2301/exe":
+			return nil, []byte("readlink: /proc/2301/exe: No such file or directory"), errors.New("exit status 1")
+		case "readlink /proc/2301/cwd":
+			return []byte("/home/tidb/deploy/tikv-20160\n"), nil, nil
+		}
+		return inner(cmd)
+	}
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	cmds = e.commands()
+	c.Assert(cmds[len(cmds)-1], Equals, "kill -9 2301")
+}
+
+func (s *taskSuite) TestScanLeftoverNothing(c *C) {
+	e := &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if cmd == "ss -ltnp" {
+			// only sshd is listening
+			return []byte(strings.Join(strings.Split(leftoverListeners, "\n")[:2], "\n")), nil, nil
+		}
+		return nil, nil, nil
+	}}
+	ctx := newMockContext("172.16.5.140", e)
+
+	t := newScanLeftover(false)
+	c.Assert(t.Execute(ctx), IsNil)
+	strays, partials := t.Leftovers()
+	c.Assert(strays, HasLen, 0)
+	c.Assert(partials, HasLen, 0)
+}
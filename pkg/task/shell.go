@@ -16,6 +16,7 @@ package task
 import (
 	"fmt"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
 	"github.com/pingcap/errors"
 )
@@ -25,6 +26,10 @@ type Shell struct {
 	host    string
 	command string
 	sudo    bool
+
+	// the command is run in workDir if it's not empty, which must be inside deployDir
+	deployDir string
+	workDir   string
 }
 
 // Execute implements the Task interface
@@ -34,6 +39,14 @@ func (m *Shell) Execute(ctx *Context) error {
 		return ErrNoExecutor
 	}
 
+	if m.workDir != "" {
+		e, err := executor.NewWorkDirExecutor(exec, m.deployDir, m.workDir)
+		if err != nil {
+			return err
+		}
+		exec = e
+	}
+
 	log.Infof("Run command on %s(sudo:%v): %s", m.host, m.sudo, m.command)
 
 	stdout, stderr, err := exec.Execute(m.command, m.sudo)
@@ -52,5 +65,8 @@ func (m *Shell) Rollback(ctx *Context) error {
 
 // String implements the fmt.Stringer interface
 func (m *Shell) String() string {
+	if m.workDir != "" {
+		return fmt.Sprintf("Shell: host=%s, sudo=%v, dir=%s, command=`%s`", m.host, m.sudo, m.workDir, m.command)
+	}
 	return fmt.Sprintf("Shell: host=%s, sudo=%v, command=`%s`", m.host, m.sudo, m.command)
 }
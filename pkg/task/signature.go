@@ -0,0 +1,236 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSSignature = errNS.NewSubNamespace("signature")
+	// ErrSignatureMissing means the signature of an artifact is not published by the mirror
+	ErrSignatureMissing = errNSSignature.NewType("missing")
+	// ErrSignatureInvalid means an artifact doesn't match its signature by the signing key
+	ErrSignatureInvalid = errNSSignature.NewType("invalid")
+)
+
+// SigningKeyFile is the file of the public key published by the mirror, which signs the
+// artifacts by ed25519
+const SigningKeyFile = "signing.pub"
+
+// signatureSuggestion is the suggestion of the errors of the signature verification
+const signatureSuggestion = "Please check the mirror is the trusted one and the artifacts are not tampered with, or use --skip-signature-check if the mirror doesn't sign the artifacts."
+
+// SetSignatureCheck makes the downloaded artifacts verified against the signing key, which
+// is the one published by the mirror unless it's set by SetSigningKey
+func (ctx *Context) SetSignatureCheck(enabled bool) {
+	ctx.signing.Lock()
+	defer ctx.signing.Unlock()
+	ctx.signing.enabled = enabled
+}
+
+// SetSigningKey sets the trusted public key to verify the artifacts, instead of the
+// one published by the mirror
+func (ctx *Context) SetSigningKey(key ed25519.PublicKey) {
+	ctx.signing.Lock()
+	defer ctx.signing.Unlock()
+	ctx.signing.key = key
+}
+
+// SignatureCheck returns whether the downloaded artifacts are verified
+func (ctx *Context) SignatureCheck() bool {
+	ctx.signing.Lock()
+	defer ctx.signing.Unlock()
+	return ctx.signing.enabled
+}
+
+// ParseSigningKey parses the base64 encoded ed25519 public key
+func ParseSigningKey(data []byte) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Annotate(err, "the signing key is not base64 encoded")
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("the signing key is %d bytes rather than %d bytes of an ed25519 public key", len(key), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// SignArtifact returns the base64 encoded signature of the file, which is the ed25519
+// signature of its sha256 digest
+func SignArtifact(key ed25519.PrivateKey, path string) ([]byte, error) {
+	digest, err := artifactDigest(path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest))), nil
+}
+
+// VerifySignature verifies the file against the base64 encoded signature by the key
+func VerifySignature(key ed25519.PublicKey, path string, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrSignatureInvalid.
+			New("The signature of %s is malformed", filepath.Base(path)).
+			WithProperty(cliutil.SuggestionFromString(signatureSuggestion))
+	}
+	digest, err := artifactDigest(path)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, sig) {
+		return ErrSignatureInvalid.
+			New("The artifact %s doesn't match its signature by the signing key", filepath.Base(path)).
+			WithProperty(cliutil.SuggestionFromString(signatureSuggestion))
+	}
+	return nil
+}
+
+func artifactDigest(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, errors.Annotatef(err, "failed to read %s", path)
+	}
+	return h.Sum(nil), nil
+}
+
+// verifyArtifact verifies the artifact cached in cacheDir against its signature file, the
+// signature and the signing key are fetched from the mirror unless they are cached or the
+// context is offline. The invalid artifact is removed from the cache to be downloaded again.
+func (ctx *Context) verifyArtifact(mirror, cacheDir, fileName, sigFile string, refresh bool) error {
+	key, err := ctx.signingKey(mirror, cacheDir)
+	if err != nil {
+		return err
+	}
+	sigPath := filepath.Join(cacheDir, sigFile)
+	sig, err := ctx.cachedMirrorFile(mirror, sigPath, sigFile, refresh)
+	if err != nil {
+		return err
+	}
+	if sig == nil {
+		return ErrSignatureMissing.
+			New("The signature %s of %s is not published by the mirror %s", sigFile, fileName, mirror).
+			WithProperty(cliutil.SuggestionFromString(signatureSuggestion))
+	}
+
+	path := filepath.Join(cacheDir, fileName)
+	if err := VerifySignature(key, path, sig); err != nil {
+		_ = os.Remove(path)
+		_ = os.Remove(sigPath)
+		return err
+	}
+	return nil
+}
+
+// signingKey returns the trusted signing key, or the one published by the mirror, which
+// is cached on the first use and trusted since then
+func (ctx *Context) signingKey(mirror, cacheDir string) (ed25519.PublicKey, error) {
+	ctx.signing.Lock()
+	defer ctx.signing.Unlock()
+	if ctx.signing.key != nil {
+		return ctx.signing.key, nil
+	}
+
+	data, err := ctx.cachedMirrorFile(mirror, filepath.Join(cacheDir, SigningKeyFile), SigningKeyFile, false)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrSignatureMissing.
+			New("The signing key %s is not published by the mirror %s", SigningKeyFile, mirror).
+			WithProperty(cliutil.SuggestionFromString(signatureSuggestion))
+	}
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid signing key of the mirror %s", mirror)
+	}
+	ctx.signing.key = key
+	return key, nil
+}
+
+// cachedMirrorFile returns the content of the cached file, the file is fetched from the
+// mirror to the cache if it's not cached or refresh is true. It returns nil if the mirror
+// doesn't have the file.
+func (ctx *Context) cachedMirrorFile(mirror, path, fileName string, refresh bool) ([]byte, error) {
+	if ctx.offline || !refresh {
+		data, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			return data, nil
+		case !os.IsNotExist(err):
+			return nil, errors.Trace(err)
+		case ctx.offline:
+			return nil, nil
+		}
+	}
+
+	data, err := fetchMirrorFile(mirror, fileName)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return data, nil
+}
+
+// fetchMirrorFile returns the content of the file in the mirror, or nil if the mirror
+// doesn't have it
+func fetchMirrorFile(mirror, fileName string) ([]byte, error) {
+	url := artifactURL(mirror, fileName)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		data, err := ioutil.ReadFile(url)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, errors.Trace(err)
+	}
+
+	res, err := http.Get(url)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to download %s", fileName)
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("failed to download %s: %s", fileName, res.Status)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to download %s", fileName)
+	}
+	return data, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	. "github.com/pingcap/check"
+)
+
+// signedMirror returns a local mirror with a package signed by the key, and publishes the
+// public key if publish is true
+func signedMirror(c *C, key ed25519.PrivateKey, publish bool) string {
+	mirror := c.MkDir()
+	pkg := filepath.Join(mirror, "tikv-v4.0.0-linux-amd64.tar.gz")
+	c.Assert(ioutil.WriteFile(pkg, []byte("tikv-server"), 0644), IsNil)
+	sig, err := SignArtifact(key, pkg)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(mirror, "tikv-v4.0.0-linux-amd64.sig"), sig, 0644), IsNil)
+	if publish {
+		pub := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		c.Assert(ioutil.WriteFile(filepath.Join(mirror, SigningKeyFile), []byte(pub+"\n"), 0644), IsNil)
+	}
+	return mirror
+}
+
+// cachePackage copies the package from the mirror to the cache, with the content
+// replaced if it's not nil
+func cachePackage(c *C, mirror string, content []byte) string {
+	cache := c.MkDir()
+	if content == nil {
+		var err error
+		content, err = ioutil.ReadFile(filepath.Join(mirror, "tikv-v4.0.0-linux-amd64.tar.gz"))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(cache, "tikv-v4.0.0-linux-amd64.tar.gz"), content, 0644), IsNil)
+	return cache
+}
+
+func (s *taskSuite) TestVerifyArtifact(c *C) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	c.Assert(err, IsNil)
+	mirror := signedMirror(c, key, true)
+
+	// the signature and key of the mirror are cached
+	cache := cachePackage(c, mirror, nil)
+	ctx := NewContext()
+	c.Assert(ctx.verifyArtifact(mirror, cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", true), IsNil)
+	c.Assert(ctx.signing.key, DeepEquals, pub)
+	for _, name := range []string{"tikv-v4.0.0-linux-amd64.sig", SigningKeyFile} {
+		_, err := os.Stat(filepath.Join(cache, name))
+		c.Assert(err, IsNil)
+	}
+
+	// verified by the cache in the offline mode
+	ctx = NewContext()
+	ctx.SetOffline(true)
+	c.Assert(ctx.verifyArtifact(c.MkDir(), cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", false), IsNil)
+
+	// the tampered package is removed
+	cache = cachePackage(c, mirror, []byte("tampered"))
+	ctx = NewContext()
+	err = ctx.verifyArtifact(mirror, cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", true)
+	c.Assert(errorx.IsOfType(err, ErrSignatureInvalid), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The artifact tikv-v4.0.0-linux-amd64.tar.gz doesn't match its signature by the signing key.*")
+	_, err = os.Stat(filepath.Join(cache, "tikv-v4.0.0-linux-amd64.tar.gz"))
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// signed by another key than the trusted one
+	other, _, err := ed25519.GenerateKey(nil)
+	c.Assert(err, IsNil)
+	cache = cachePackage(c, mirror, nil)
+	ctx = NewContext()
+	ctx.SetSigningKey(other)
+	err = ctx.verifyArtifact(mirror, cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", true)
+	c.Assert(errorx.IsOfType(err, ErrSignatureInvalid), IsTrue)
+}
+
+func (s *taskSuite) TestVerifyArtifactMissing(c *C) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	c.Assert(err, IsNil)
+
+	// the mirror doesn't publish the key
+	mirror := signedMirror(c, key, false)
+	cache := cachePackage(c, mirror, nil)
+	ctx := NewContext()
+	err = ctx.verifyArtifact(mirror, cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", true)
+	c.Assert(errorx.IsOfType(err, ErrSignatureMissing), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The signing key signing.pub is not published by the mirror.*")
+
+	// nor the signature
+	c.Assert(os.Remove(filepath.Join(mirror, "tikv-v4.0.0-linux-amd64.sig")), IsNil)
+	ctx.SetSigningKey(pub)
+	err = ctx.verifyArtifact(mirror, cache, "tikv-v4.0.0-linux-amd64.tar.gz", "tikv-v4.0.0-linux-amd64.sig", true)
+	c.Assert(errorx.IsOfType(err, ErrSignatureMissing), IsTrue)
+	c.Assert(err.Error(), Matches, ".*The signature tikv-v4.0.0-linux-amd64.sig of tikv-v4.0.0-linux-amd64.tar.gz is not published.*")
+
+	// malformed
+	err = VerifySignature(pub, filepath.Join(cache, "tikv-v4.0.0-linux-amd64.tar.gz"), []byte("not a signature"))
+	c.Assert(errorx.IsOfType(err, ErrSignatureInvalid), IsTrue)
+
+	_, err = ParseSigningKey([]byte("c2hvcnQ="))
+	c.Assert(err, ErrorMatches, "the signing key is 5 bytes rather than 32 bytes of an ed25519 public key")
+}
@@ -0,0 +1,293 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"github.com/pingcap/errors"
+)
+
+// ContextSnapshot is the state of a context persisted to resume an operation or to replay
+// it in the tests. The connections can't be persisted, so only the SSH configs authenticated
+// by key files are kept to rebuild the executors, the passwords are never written to disk.
+type ContextSnapshot struct {
+	ChangeID        string                                 `json:"change_id,omitempty"`
+	PrivateKeyPath  string                                 `json:"private_key_path,omitempty"`
+	PublicKeyPath   string                                 `json:"public_key_path,omitempty"`
+	Manifests       map[string]*repository.VersionManifest `json:"manifests,omitempty"`
+	Outputs         map[string]SnapshotOutput              `json:"outputs,omitempty"`
+	ArtifactSources map[string]string                      `json:"artifact_sources,omitempty"`
+	PinnedSources   map[string]string                      `json:"pinned_sources,omitempty"`
+	Executors       map[string]SnapshotSSH                 `json:"executors,omitempty"`
+	Checkpoints     []string                               `json:"checkpoints,omitempty"`
+}
+
+// SnapshotOutput is the last outputs of a host
+type SnapshotOutput struct {
+	Stdout []byte `json:"stdout,omitempty"`
+	Stderr []byte `json:"stderr,omitempty"`
+}
+
+// SnapshotSSH is the config to rebuild the SSH executor of a host
+type SnapshotSSH struct {
+	Host    string        `json:"host"`
+	Port    int           `json:"port"`
+	User    string        `json:"user"`
+	KeyFile string        `json:"key_file"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+// MarkCheckpoint marks the checkpoint as reached, it's persisted by the snapshot so that
+// the resumed operation can skip the finished part
+func (ctx *Context) MarkCheckpoint(name string) error {
+	ctx.markCheckpoint(name)
+	return ctx.saveSnapshot()
+}
+
+// UnmarkCheckpoints removes the checkpoints, e.g. the ones of the finished part which is not
+// skipped by the next operation
+func (ctx *Context) UnmarkCheckpoints(names ...string) error {
+	ctx.checkpoints.Lock()
+	for _, name := range names {
+		delete(ctx.checkpoints.names, name)
+	}
+	ctx.checkpoints.Unlock()
+	return ctx.saveSnapshot()
+}
+
+func (ctx *Context) markCheckpoint(name string) {
+	ctx.checkpoints.Lock()
+	defer ctx.checkpoints.Unlock()
+	if ctx.checkpoints.names == nil {
+		ctx.checkpoints.names = make(map[string]struct{})
+	}
+	ctx.checkpoints.names[name] = struct{}{}
+}
+
+// checkpointsWithPrefix returns the sorted checkpoints starting with the prefix
+func (ctx *Context) checkpointsWithPrefix(prefix string) []string {
+	ctx.checkpoints.Lock()
+	defer ctx.checkpoints.Unlock()
+	var names []string
+	for name := range ctx.checkpoints.names {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SnapshotTo makes the snapshot of the context saved to the file every time the checkpoints
+// change, so that an interrupted operation is resumed by restoring the context from it
+func (ctx *Context) SnapshotTo(path string) {
+	ctx.checkpoints.Lock()
+	ctx.checkpoints.path = path
+	ctx.checkpoints.Unlock()
+}
+
+// saveSnapshot saves the snapshot to the file set by SnapshotTo, the snapshots of the
+// concurrent changes are saved one by one
+func (ctx *Context) saveSnapshot() error {
+	ctx.checkpoints.save.Lock()
+	defer ctx.checkpoints.save.Unlock()
+	ctx.checkpoints.Lock()
+	path := ctx.checkpoints.path
+	ctx.checkpoints.Unlock()
+	if path == "" {
+		return nil
+	}
+	return ctx.Snapshot().WriteFile(path)
+}
+
+// removeSnapshot removes the file set by SnapshotTo, it's called after the operation is finished
+func (ctx *Context) removeSnapshot() error {
+	ctx.checkpoints.save.Lock()
+	defer ctx.checkpoints.save.Unlock()
+	ctx.checkpoints.Lock()
+	path := ctx.checkpoints.path
+	ctx.checkpoints.Unlock()
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Annotatef(err, "failed to remove the snapshot %s", path)
+	}
+	return nil
+}
+
+// Checkpointed returns whether the checkpoint has been reached
+func (ctx *Context) Checkpointed(name string) bool {
+	ctx.checkpoints.Lock()
+	defer ctx.checkpoints.Unlock()
+	_, ok := ctx.checkpoints.names[name]
+	return ok
+}
+
+// Snapshot returns the persistable state of the context
+func (ctx *Context) Snapshot() *ContextSnapshot {
+	s := &ContextSnapshot{
+		ChangeID:        ctx.changeID,
+		PrivateKeyPath:  ctx.PrivateKeyPath,
+		PublicKeyPath:   ctx.PublicKeyPath,
+		Manifests:       make(map[string]*repository.VersionManifest),
+		Outputs:         make(map[string]SnapshotOutput),
+		ArtifactSources: ctx.ArtifactSources(),
+		Executors:       make(map[string]SnapshotSSH),
+	}
+
+	ctx.manifestCache.RLock()
+	for comp, m := range ctx.manifestCache.manifests {
+		s.Manifests[comp] = m
+	}
+	ctx.manifestCache.RUnlock()
+
+	var hosts []string
+	ctx.exec.RLock()
+	for host := range ctx.exec.stdouts {
+		hosts = append(hosts, host)
+	}
+	for host, e := range ctx.exec.executors {
+		if cf, ok := snapshotSSH(e); ok {
+			s.Executors[host] = cf
+		}
+	}
+	ctx.exec.RUnlock()
+	for _, host := range hosts {
+		stdout, stderr, _ := ctx.GetOutputs(host)
+		s.Outputs[host] = SnapshotOutput{Stdout: stdout, Stderr: stderr}
+	}
+
+	ctx.sources.Lock()
+	if len(ctx.sources.pinned) > 0 {
+		s.PinnedSources = make(map[string]string, len(ctx.sources.pinned))
+		for k, v := range ctx.sources.pinned {
+			s.PinnedSources[k] = v
+		}
+	}
+	ctx.sources.Unlock()
+
+	ctx.checkpoints.Lock()
+	for name := range ctx.checkpoints.names {
+		s.Checkpoints = append(s.Checkpoints, name)
+	}
+	ctx.checkpoints.Unlock()
+	sort.Strings(s.Checkpoints)
+
+	return s
+}
+
+// snapshotSSH returns the config of the SSH executor, which may be wrapped by the context,
+// false if it's not an SSH executor authenticated by a key file
+func snapshotSSH(e executor.TiOpsExecutor) (SnapshotSSH, bool) {
+	for {
+		switch inner := e.(type) {
+		case *verboseExecutor:
+			e = inner.inner
+		case *cachingExecutor:
+			e = inner.inner
+		case *executor.SSHExecutor:
+			if inner.Config == nil || inner.Config.KeyPath == "" || inner.Config.Passphrase != "" {
+				return SnapshotSSH{}, false
+			}
+			port, _ := strconv.Atoi(inner.Config.Port)
+			return SnapshotSSH{
+				Host:    inner.Config.Server,
+				Port:    port,
+				User:    inner.Config.User,
+				KeyFile: inner.Config.KeyPath,
+				Timeout: inner.Config.Timeout,
+			}, true
+		default:
+			return SnapshotSSH{}, false
+		}
+	}
+}
+
+// Restore restores the state of the context from the snapshot, the SSH executors are rebuilt
+// and the ones authenticated by passwords must be set again
+func (ctx *Context) Restore(s *ContextSnapshot) {
+	if s.ChangeID != "" {
+		ctx.SetChangeID(s.ChangeID)
+	}
+	ctx.PrivateKeyPath = s.PrivateKeyPath
+	ctx.PublicKeyPath = s.PublicKeyPath
+	for comp, m := range s.Manifests {
+		ctx.SetManifest(comp, m)
+	}
+	for host, out := range s.Outputs {
+		ctx.SetOutputs(host, out.Stdout, out.Stderr)
+	}
+	for host, cf := range s.Executors {
+		ctx.SetExecutor(host, executor.NewSSHExecutor(executor.SSHConfig{
+			Host:    cf.Host,
+			Port:    cf.Port,
+			User:    cf.User,
+			KeyFile: cf.KeyFile,
+			Timeout: cf.Timeout,
+		}))
+	}
+
+	ctx.sources.Lock()
+	if ctx.sources.resolved == nil {
+		ctx.sources.resolved = make(map[string]string)
+	}
+	for k, v := range s.ArtifactSources {
+		ctx.sources.resolved[k] = v
+	}
+	if len(s.PinnedSources) > 0 {
+		ctx.sources.pinned = s.PinnedSources
+	}
+	ctx.sources.Unlock()
+
+	for _, name := range s.Checkpoints {
+		ctx.markCheckpoint(name)
+	}
+}
+
+// WriteFile writes the snapshot to the file in JSON, it's written to a temporary file and
+// renamed so that an interruption never leaves a partial file
+func (s *ContextSnapshot) WriteFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Annotatef(err, "failed to write the snapshot to %s", path)
+	}
+	return errors.Annotatef(os.Rename(tmp, path), "failed to write the snapshot to %s", path)
+}
+
+// ReadContextSnapshot reads the snapshot written by WriteFile
+func ReadContextSnapshot(path string) (*ContextSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read the snapshot from %s", path)
+	}
+	s := &ContextSnapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the snapshot %s", path)
+	}
+	return s, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestContextSnapshot(c *C) {
+	ctx := NewContext()
+	ctx.SetChangeID("CHG-1024")
+	c.Assert(ctx.SetSSHKeySet("/home/tidb/.tiup/id_rsa", "/home/tidb/.tiup/id_rsa.pub"), IsNil)
+	ctx.SetManifest("tikv", &repository.VersionManifest{
+		Description: "TiKV",
+		Versions:    []repository.VersionInfo{{Version: "v4.0.0", Date: "2020-04-15", Entry: "tikv-server"}},
+	})
+	ctx.SetOutputs("172.16.5.140", []byte("stdout"), []byte("stderr"))
+	ctx.PinArtifactSources(map[string]string{"tikv:v4.0.0": "https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz"})
+	c.Assert(ctx.recordArtifactSource("tikv", "v4.0.0", "https://mirror.example.com/tikv-v4.0.0-linux-amd64.tar.gz"), IsNil)
+	ctx.EnableCommandCache()
+	ctx.SetExecutor("172.16.5.140", executor.NewSSHExecutor(executor.SSHConfig{
+		Host:    "172.16.5.140",
+		Port:    22,
+		User:    "tidb",
+		KeyFile: "/home/tidb/.tiup/id_rsa",
+		Timeout: 10 * time.Second,
+	}))
+	// the passwords are not persisted
+	ctx.SetExecutor("172.16.5.141", executor.NewSSHExecutor(executor.SSHConfig{Host: "172.16.5.141", User: "root", Password: "secret"}))
+	ctx.SetExecutor("172.16.5.142", &mockExecutor{})
+	ctx.MarkCheckpoint("copy-files")
+	ctx.MarkCheckpoint("download")
+
+	path := filepath.Join(c.MkDir(), "snapshot.json")
+	c.Assert(ctx.Snapshot().WriteFile(path), IsNil)
+	snapshot, err := ReadContextSnapshot(path)
+	c.Assert(err, IsNil)
+	c.Assert(snapshot, DeepEquals, ctx.Snapshot())
+	c.Assert(snapshot.Checkpoints, DeepEquals, []string{"copy-files", "download"})
+	c.Assert(snapshot.Executors, DeepEquals, map[string]SnapshotSSH{
+		"172.16.5.140": {Host: "172.16.5.140", Port: 22, User: "tidb", KeyFile: "/home/tidb/.tiup/id_rsa", Timeout: 10 * time.Second},
+	})
+
+	restored := NewContext()
+	restored.Restore(snapshot)
+	c.Assert(restored.changeID, Equals, "CHG-1024")
+	c.Assert(restored.PrivateKeyPath, Equals, "/home/tidb/.tiup/id_rsa")
+	c.Assert(restored.PublicKeyPath, Equals, "/home/tidb/.tiup/id_rsa.pub")
+	m, ok := restored.GetManifest("tikv")
+	c.Assert(ok, IsTrue)
+	c.Assert(m.Versions[0].Entry, Equals, "tikv-server")
+	stdout, stderr, ok := restored.GetOutputs("172.16.5.140")
+	c.Assert(ok, IsTrue)
+	c.Assert(string(stdout), Equals, "stdout")
+	c.Assert(string(stderr), Equals, "stderr")
+	c.Assert(restored.ArtifactSources(), DeepEquals, ctx.ArtifactSources())
+	c.Assert(restored.Checkpointed("download"), IsTrue)
+	c.Assert(restored.Checkpointed("start"), IsFalse)
+	e, ok := restored.GetExecutor("172.16.5.140")
+	c.Assert(ok, IsTrue)
+	c.Assert(e.(*executor.SSHExecutor).Config.KeyPath, Equals, "/home/tidb/.tiup/id_rsa")
+	_, ok = restored.GetExecutor("172.16.5.141")
+	c.Assert(ok, IsFalse)
+	c.Assert(restored.Snapshot(), DeepEquals, snapshot)
+}
@@ -38,6 +38,11 @@ type RootSSH struct {
 
 // Execute implements the Task interface
 func (s *RootSSH) Execute(ctx *Context) error {
+	if p := ctx.Plan(); p != nil {
+		ctx.SetExecutor(s.host, p.Executor(s.host))
+		return nil
+	}
+
 	e := executor.NewSSHExecutor(executor.SSHConfig{
 		Host:       s.host,
 		Port:       s.port,
@@ -78,6 +83,11 @@ type UserSSH struct {
 
 // Execute implements the Task interface
 func (s *UserSSH) Execute(ctx *Context) error {
+	if p := ctx.Plan(); p != nil {
+		ctx.SetExecutor(s.host, p.Executor(s.host))
+		return nil
+	}
+
 	e := executor.NewSSHExecutor(executor.SSHConfig{
 		Host:    s.host,
 		Port:    s.port,
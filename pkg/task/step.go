@@ -46,6 +46,13 @@ func addChildren(m map[Task]struct{}, task Task) {
 				addChildren(m, tx)
 			}
 		}
+	} else if t, ok := task.(*RetryParallel); ok {
+		t.hideDetailDisplay = true
+		for _, tx := range t.inner {
+			if _, exists := m[tx]; !exists {
+				addChildren(m, tx)
+			}
+		}
 	}
 }
 
@@ -71,7 +78,7 @@ func (s *StepDisplay) Execute(ctx *Context) error {
 	}
 	ctx.ev.Subscribe(EventTaskBegin, s.handleTaskBegin)
 	ctx.ev.Subscribe(EventTaskProgress, s.handleTaskProgress)
-	err := s.inner.Execute(ctx)
+	err := ctx.execute(s.inner)
 	ctx.ev.Unsubscribe(EventTaskProgress, s.handleTaskProgress)
 	ctx.ev.Unsubscribe(EventTaskBegin, s.handleTaskBegin)
 	if err != nil {
@@ -129,7 +136,7 @@ type ParallelStepDisplay struct {
 	progressBar *progress.MultiBar
 }
 
-func newParallelStepDisplay(prefix string, sdTasks ...*StepDisplay) *ParallelStepDisplay {
+func newParallelStepDisplay(prefix string, concurrency int, sdTasks ...*StepDisplay) *ParallelStepDisplay {
 	bar := progress.NewMultiBar(prefix)
 	tasks := make([]Task, 0, len(sdTasks))
 	for _, t := range sdTasks {
@@ -137,7 +144,7 @@ func newParallelStepDisplay(prefix string, sdTasks ...*StepDisplay) *ParallelSte
 		tasks = append(tasks, t)
 	}
 	return &ParallelStepDisplay{
-		inner:       &Parallel{inner: tasks},
+		inner:       &Parallel{inner: tasks, concurrency: concurrency},
 		prefix:      prefix,
 		progressBar: bar,
 	}
@@ -146,7 +153,7 @@ func newParallelStepDisplay(prefix string, sdTasks ...*StepDisplay) *ParallelSte
 // Execute implements the Task interface
 func (ps *ParallelStepDisplay) Execute(ctx *Context) error {
 	ps.progressBar.StartRenderLoop()
-	err := ps.inner.Execute(ctx)
+	err := ctx.execute(ps.inner)
 	ps.progressBar.StopRenderLoop()
 	return err
 }
@@ -14,10 +14,13 @@
 package task
 
 import (
+	"crypto/ed25519"
 	stderrors "errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
 	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
@@ -62,6 +65,71 @@ type (
 		PublicKeyPath  string
 
 		manifestCache manifestCache
+
+		// The whole operation should be finished before the deadline if it's not zero
+		deadline time.Time
+		// 1 if the outermost composite task is waiting for the deadline
+		deadlineWaiting int32
+
+		// The tasks have been executed, only these tasks can be rolled back manually
+		executed struct {
+			sync.Mutex
+			tasks map[Task]struct{}
+		}
+
+		// The remote commands and transfers are recorded to the plan instead of
+		// being performed if it's not nil
+		plan *Plan
+
+		// The remote commands and outputs in the scope are logged to out
+		verbose struct {
+			scope *log.Scope
+			out   io.Writer
+		}
+
+		// The results of the side-effect-free commands are cached if it's not nil
+		cache *CommandCache
+
+		// The id of the change which the operation is performed for, it's stamped on
+		// the logs and events for correlation
+		changeID string
+
+		// The resolved URLs of the downloaded artifacts, and the pinned ones to verify them
+		sources struct {
+			sync.Mutex
+			resolved map[string]string
+			pinned   map[string]string
+		}
+
+		// The progress lines are output to the logger, or the package-level log if it's nil
+		logger Logger
+
+		// The inner tasks of Parallel are executed one by one in order if it's true
+		deterministic bool
+		concurrency   int
+
+		// Decides whether the operation proceeds at the breakpoints if it's not nil
+		breakpoint BreakpointHandler
+
+		// Nothing is fetched from the mirror and only the local cache is used if it's true
+		offline bool
+
+		// The downloaded artifacts are verified against the signing key if it's enabled,
+		// the key is the one published by the mirror if it's nil
+		signing struct {
+			sync.Mutex
+			enabled bool
+			key     ed25519.PublicKey
+		}
+
+		// The checkpoints reached by the operation, persisted by the snapshot to resume it
+		checkpoints struct {
+			sync.Mutex
+			names map[string]struct{}
+			// the snapshot is saved to the file after every change of the checkpoints if set
+			path string
+			save sync.Mutex
+		}
 	}
 
 	// Serial will execute a bundle of task in serialized way
@@ -70,16 +138,18 @@ type (
 		inner             []Task
 	}
 
-	// Parallel will execute a bundle of task in parallelism way
+	// Parallel will execute a bundle of task in parallelism way, at most concurrency
+	// of them at the same time if it's positive
 	Parallel struct {
 		hideDetailDisplay bool
 		inner             []Task
+		concurrency       int
 	}
 )
 
 // NewContext create a context instance.
 func NewContext() *Context {
-	return &Context{
+	ctx := &Context{
 		ev: NewEventBus(),
 		exec: struct {
 			sync.RWMutex
@@ -95,6 +165,8 @@ func NewContext() *Context {
 			manifests: map[string]*repository.VersionManifest{},
 		},
 	}
+	ctx.executed.tasks = make(map[Task]struct{})
+	return ctx
 }
 
 // Get implements operation ExecutorGetter interface.
@@ -106,7 +178,7 @@ func (ctx *Context) Get(host string) (e executor.TiOpsExecutor) {
 	if !ok {
 		panic("no init executor for " + host)
 	}
-	return
+	return ctx.withDeadline(e)
 }
 
 // GetExecutor get the executor.
@@ -114,11 +186,22 @@ func (ctx *Context) GetExecutor(host string) (e executor.TiOpsExecutor, ok bool)
 	ctx.exec.RLock()
 	e, ok = ctx.exec.executors[host]
 	ctx.exec.RUnlock()
-	return
+	return ctx.withDeadline(e), ok
 }
 
 // SetExecutor set the executor.
 func (ctx *Context) SetExecutor(host string, e executor.TiOpsExecutor) {
+	switch e.(type) {
+	case *verboseExecutor, *cachingExecutor:
+		// set again from GetExecutor
+	default:
+		if !ctx.verbose.scope.Empty() {
+			e = &verboseExecutor{host: host, inner: e, scope: ctx.verbose.scope, out: ctx.verbose.out}
+		}
+		if ctx.cache != nil {
+			e = &cachingExecutor{host: host, inner: e, cache: ctx.cache}
+		}
+	}
 	ctx.exec.Lock()
 	ctx.exec.executors[host] = e
 	ctx.exec.Unlock()
@@ -163,6 +246,9 @@ func isDisplayTask(t Task) bool {
 	if _, ok := t.(*Parallel); ok {
 		return true
 	}
+	if _, ok := t.(*RetryParallel); ok {
+		return true
+	}
 	if _, ok := t.(*StepDisplay); ok {
 		return true
 	}
@@ -174,14 +260,24 @@ func isDisplayTask(t Task) bool {
 
 // Execute implements the Task interface
 func (s *Serial) Execute(ctx *Context) error {
+	ctx.markExecuted(s)
+	return ctx.runBeforeDeadline(func() error {
+		return s.execute(ctx)
+	})
+}
+
+func (s *Serial) execute(ctx *Context) error {
 	for _, t := range s.inner {
+		if err := ctx.checkDeadline(); err != nil {
+			return err
+		}
 		if !isDisplayTask(t) {
 			if !s.hideDetailDisplay {
-				log.Infof("+ [ Serial ] - %s", t.String())
+				ctx.Logger().Infof("+ [ Serial ] - %s", t.String())
 			}
 		}
 		ctx.ev.PublishTaskBegin(t)
-		err := t.Execute(ctx)
+		err := ctx.execute(t)
 		ctx.ev.PublishTaskFinish(t, err)
 		if err != nil {
 			return err
@@ -213,31 +309,36 @@ func (s *Serial) String() string {
 
 // Execute implements the Task interface
 func (pt *Parallel) Execute(ctx *Context) error {
+	ctx.markExecuted(pt)
+	return ctx.runBeforeDeadline(func() error {
+		return pt.execute(ctx)
+	})
+}
+
+func (pt *Parallel) execute(ctx *Context) error {
 	var firstError error
 	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	for _, t := range pt.inner {
-		wg.Add(1)
-		go func(t Task) {
-			defer wg.Done()
+	ctx.runAll(len(pt.inner), pt.concurrency, func(i int) {
+		t := pt.inner[i]
+		err := ctx.checkDeadline()
+		if err == nil {
 			if !isDisplayTask(t) {
 				if !pt.hideDetailDisplay {
-					log.Infof("+ [Parallel] - %s", t.String())
+					ctx.Logger().Infof("+ [Parallel] - %s", t.String())
 				}
 			}
 			ctx.ev.PublishTaskBegin(t)
-			err := t.Execute(ctx)
+			err = ctx.execute(t)
 			ctx.ev.PublishTaskFinish(t, err)
-			if err != nil {
-				mu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				mu.Unlock()
+		}
+		if err != nil {
+			mu.Lock()
+			if firstError == nil {
+				firstError = err
 			}
-		}(t)
-	}
-	wg.Wait()
+			mu.Unlock()
+		}
+	})
 	return firstError
 }
 
@@ -245,22 +346,16 @@ func (pt *Parallel) Execute(ctx *Context) error {
 func (pt *Parallel) Rollback(ctx *Context) error {
 	var firstError error
 	var mu sync.Mutex
-	wg := sync.WaitGroup{}
-	for _, t := range pt.inner {
-		wg.Add(1)
-		go func(t Task) {
-			defer wg.Done()
-			err := t.Rollback(ctx)
-			if err != nil {
-				mu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				mu.Unlock()
+	ctx.runAll(len(pt.inner), pt.concurrency, func(i int) {
+		err := pt.inner[i].Rollback(ctx)
+		if err != nil {
+			mu.Lock()
+			if firstError == nil {
+				firstError = err
 			}
-		}(t)
-	}
-	wg.Wait()
+			mu.Unlock()
+		}
+	})
 	return firstError
 }
 
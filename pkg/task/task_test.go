@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	. "github.com/pingcap/check"
+)
+
+type taskSuite struct {
+}
+
+var _ = Suite(&taskSuite{})
+
+func TestTask(t *testing.T) {
+	TestingT(t)
+}
+
+// mockExecutor records all commands and transfers, the result of a command
+// is decided by the handler
+type mockExecutor struct {
+	sync.Mutex
+	cmds      []string
+	transfers []string
+	handler   func(cmd string) ([]byte, []byte, error)
+}
+
+func (e *mockExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	e.Lock()
+	e.cmds = append(e.cmds, cmd)
+	e.Unlock()
+
+	if e.handler == nil {
+		return nil, nil, nil
+	}
+	return e.handler(cmd)
+}
+
+func (e *mockExecutor) Transfer(src string, dst string, download bool) error {
+	e.Lock()
+	e.transfers = append(e.transfers, src+" -> "+dst)
+	e.Unlock()
+	return nil
+}
+
+func (e *mockExecutor) commands() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string{}, e.cmds...)
+}
+
+func newMockContext(host string, e *mockExecutor) *Context {
+	ctx := NewContext()
+	ctx.SetExecutor(host, e)
+	return ctx
+}
+
+// mockHostsContext returns a context with the executor of each host created by newExecutor,
+// the executors are returned by host to check the commands
+func mockHostsContext(hosts []string, newExecutor func(host string) *mockExecutor) (*Context, map[string]*mockExecutor) {
+	ctx := NewContext()
+	executors := make(map[string]*mockExecutor)
+	for _, host := range hosts {
+		e := newExecutor(host)
+		ctx.SetExecutor(host, e)
+		executors[host] = e
+	}
+	return ctx, executors
+}
+
+// hostsOf returns the sorted keys of the map keyed by host, for the fixtures of the hosts
+func hostsOf(m interface{}) []string {
+	var hosts []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		hosts = append(hosts, k.String())
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// runSudo runs the command wrapped by sudo like the SSH executor does, through a real shell
+// with a stub sudo running it as the current user, so that the quoting is verified
+func runSudo(c *C, command string) (string, error) {
+	bin := c.MkDir()
+	stub := "#!/bin/sh\n# drop -H -u root\nshift 3\nexec \"$@\"\n"
+	c.Assert(ioutil.WriteFile(filepath.Join(bin, "sudo"), []byte(stub), 0755), IsNil)
+
+	cmd := exec.Command("bash", "-c", executor.SudoCommand(command))
+	cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
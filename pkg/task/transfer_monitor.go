@@ -0,0 +1,184 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+	"github.com/pingcap/errors"
+)
+
+// TransferMonitor is used to move the Prometheus and Grafana instances to a new host. The
+// successors are deployed, the TSDB of Prometheus is copied to them, and they are started
+// and verified healthy while the old instances are still running. Then Grafana is re-pointed
+// to the new Prometheus and the old instances are decommissioned, so the gap of monitoring
+// is only the data scraped by the old Prometheus after copying. The old instances are kept
+// if any step before the decommission fails.
+type TransferMonitor struct {
+	deploy       Task
+	migrate      Task
+	join         Task
+	verify       Task
+	repoint      Task
+	decommission Task
+}
+
+// Execute implements the Task interface
+func (t *TransferMonitor) Execute(ctx *Context) error {
+	steps := []struct {
+		name string
+		task Task
+	}{
+		{"deploy the new instances", t.deploy},
+		{"migrate the monitoring data", t.migrate},
+		{"start the new instances", t.join},
+		{"verify the new instances", t.verify},
+		{"re-point Grafana to the new Prometheus", t.repoint},
+	}
+	for _, step := range steps {
+		if step.task == nil {
+			continue
+		}
+		if err := ctx.execute(step.task); err != nil {
+			log.Warnf("Failed to %s, the old monitoring instances are kept running", step.name)
+			return err
+		}
+	}
+	return ctx.execute(t.decommission)
+}
+
+// Rollback implements the Task interface
+func (t *TransferMonitor) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (t *TransferMonitor) String() string {
+	return "TransferMonitor"
+}
+
+// CopyMonitorData is used to copy the TSDB of a running Prometheus instance to the data
+// directory of its successor on another host via the control machine. The lock file of the
+// running instance is excluded.
+type CopyMonitorData struct {
+	srcHost string
+	srcDir  string
+	dstHost string
+	dstDir  string
+	cache   string // local directory to stage the archive
+}
+
+// Execute implements the Task interface
+func (c *CopyMonitorData) Execute(ctx *Context) error {
+	src, found := ctx.GetExecutor(c.srcHost)
+	if !found {
+		return ErrNoExecutor
+	}
+	dst, found := ctx.GetExecutor(c.dstHost)
+	if !found {
+		return ErrNoExecutor
+	}
+
+	name := fmt.Sprintf("tiup-prometheus-data-%s.tar.gz", strings.ReplaceAll(utils.UnwrapHost(c.srcHost), ":", "-"))
+	remote := filepath.Join("/tmp", name)
+	local := filepath.Join(c.cache, name)
+	defer os.Remove(local)
+
+	cmd := fmt.Sprintf("tar --exclude=./lock -czf %s -C %s .", remote, c.srcDir)
+	if _, stderr, err := src.Execute(cmd, false, time.Hour); err != nil {
+		return errors.Annotatef(err, "failed to archive %s on %s: %s", c.srcDir, c.srcHost, stderr)
+	}
+	defer func() { _, _, _ = src.Execute("rm -f "+remote, false) }()
+	if err := src.Transfer(remote, local, true); err != nil {
+		return errors.Annotatef(err, "failed to download the data of Prometheus from %s", c.srcHost)
+	}
+	if err := dst.Transfer(local, remote, false); err != nil {
+		return errors.Annotatef(err, "failed to upload the data of Prometheus to %s", c.dstHost)
+	}
+
+	cmd = fmt.Sprintf("mkdir -p %s && tar -xzf %s -C %s && rm -f %s", c.dstDir, remote, c.dstDir, remote)
+	if _, stderr, err := dst.Execute(cmd, false, time.Hour); err != nil {
+		return errors.Annotatef(err, "failed to extract the data of Prometheus to %s on %s: %s", c.dstDir, c.dstHost, stderr)
+	}
+	log.Infof("Copied the data of Prometheus from %s:%s to %s:%s", c.srcHost, c.srcDir, c.dstHost, c.dstDir)
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CopyMonitorData) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CopyMonitorData) String() string {
+	return fmt.Sprintf("CopyMonitorData: src=%s:%s, dst=%s:%s", c.srcHost, c.srcDir, c.dstHost, c.dstDir)
+}
+
+// monitorHealthPaths is the path of the health api of the monitoring components
+var monitorHealthPaths = map[string]string{
+	meta.ComponentPrometheus: "/-/ready",
+	meta.ComponentGrafana:    "/api/health",
+}
+
+// CheckMonitorHealth is used to wait for the Prometheus and Grafana instances to be healthy
+// by their health apis
+type CheckMonitorHealth struct {
+	inspection
+
+	instances []meta.Instance
+	timeout   time.Duration
+}
+
+// Execute implements the Task interface
+func (c *CheckMonitorHealth) Execute(ctx *Context) error {
+	client := utils.NewHTTPClient(5*time.Second, nil)
+	for _, inst := range c.instances {
+		path, ok := monitorHealthPaths[inst.ComponentName()]
+		if !ok {
+			continue
+		}
+		url := fmt.Sprintf("http://%s%s", utils.JoinHostPort(inst.GetHost(), inst.GetPort()), path)
+		var lastErr error
+		err := utils.Retry(func() error {
+			_, lastErr = client.Get(url)
+			return lastErr
+		}, utils.RetryOption{Delay: time.Second, Timeout: c.timeout})
+		if err != nil {
+			return errors.Errorf("%s %s is not healthy in %s: %v", inst.ComponentName(), inst.ID(), c.timeout, lastErr)
+		}
+		log.Infof("%s %s is healthy", inst.ComponentName(), inst.ID())
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (c *CheckMonitorHealth) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckMonitorHealth) String() string {
+	var ids []string
+	for _, inst := range c.instances {
+		ids = append(ids, inst.ID())
+	}
+	return fmt.Sprintf("CheckMonitorHealth: instances=%s", strings.Join(ids, ","))
+}
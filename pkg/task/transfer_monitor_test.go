@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// sequenceTasks returns the named tasks recording the order they are executed in, the one
+// named failed fails
+func sequenceTasks(failed string, names ...string) ([]Task, func() []string) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	var tasks []Task
+	for _, name := range names {
+		name := name
+		tasks = append(tasks, &Func{name: name, fn: func() error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			if name == failed {
+				return errors.New(name + " failed")
+			}
+			return nil
+		}})
+	}
+	return tasks, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, order...)
+	}
+}
+
+func (s *taskSuite) TestTransferMonitor(c *C) {
+	names := []string{"deploy", "migrate", "join", "verify", "repoint", "decommission"}
+	tasks, order := sequenceTasks("", names...)
+	t := NewBuilder().TransferMonitor(tasks[0], tasks[1], tasks[2], tasks[3], tasks[4], tasks[5]).Build()
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(order(), DeepEquals, names)
+
+	// the data is not migrated, and there is no Grafana to re-point
+	tasks, order = sequenceTasks("", names...)
+	t = NewBuilder().TransferMonitor(tasks[0], nil, tasks[2], tasks[3], nil, tasks[5]).Build()
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(order(), DeepEquals, []string{"deploy", "join", "verify", "decommission"})
+
+	// the old instances are kept if the new ones are not healthy
+	tasks, order = sequenceTasks("verify", names...)
+	t = NewBuilder().TransferMonitor(tasks[0], tasks[1], tasks[2], tasks[3], tasks[4], tasks[5]).Build()
+	c.Assert(t.Execute(NewContext()), ErrorMatches, "verify failed")
+	c.Assert(order(), DeepEquals, []string{"deploy", "migrate", "join", "verify"})
+
+	// or the data is not migrated
+	tasks, order = sequenceTasks("migrate", names...)
+	t = NewBuilder().TransferMonitor(tasks[0], tasks[1], tasks[2], tasks[3], tasks[4], tasks[5]).Build()
+	c.Assert(t.Execute(NewContext()), ErrorMatches, "migrate failed")
+	c.Assert(order(), DeepEquals, []string{"deploy", "migrate"})
+}
+
+func (s *taskSuite) TestCopyMonitorData(c *C) {
+	ctx := NewContext()
+	src, dst := &mockExecutor{}, &mockExecutor{}
+	ctx.SetExecutor("172.16.5.140", src)
+	ctx.SetExecutor("172.16.5.150", dst)
+
+	t := &CopyMonitorData{
+		srcHost: "172.16.5.140",
+		srcDir:  "/home/tidb/deploy/prometheus-9090/data.metrics",
+		dstHost: "172.16.5.150",
+		dstDir:  "/home/tidb/deploy/prometheus-9090/data.metrics",
+		cache:   c.MkDir(),
+	}
+	c.Assert(t.Execute(ctx), IsNil)
+
+	archive := "/tmp/tiup-prometheus-data-172.16.5.140.tar.gz"
+	c.Assert(src.commands(), DeepEquals, []string{
+		"tar --exclude=./lock -czf " + archive + " -C /home/tidb/deploy/prometheus-9090/data.metrics .",
+		"rm -f " + archive,
+	})
+	c.Assert(src.transfers, DeepEquals, []string{archive + " -> " + t.cache + "/tiup-prometheus-data-172.16.5.140.tar.gz"})
+	c.Assert(dst.transfers, DeepEquals, []string{t.cache + "/tiup-prometheus-data-172.16.5.140.tar.gz -> " + archive})
+	c.Assert(dst.commands(), DeepEquals, []string{
+		"mkdir -p /home/tidb/deploy/prometheus-9090/data.metrics && tar -xzf " + archive +
+			" -C /home/tidb/deploy/prometheus-9090/data.metrics && rm -f " + archive,
+	})
+
+	// the archive is removed from the old host even if it's not extracted
+	src, dst = &mockExecutor{}, &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		return nil, []byte("No space left on device"), errors.New("exit status 2")
+	}}
+	ctx.SetExecutor("172.16.5.140", src)
+	ctx.SetExecutor("172.16.5.150", dst)
+	c.Assert(t.Execute(ctx), ErrorMatches, ".*failed to extract the data of Prometheus to .* on 172.16.5.150: No space left on device.*")
+	c.Assert(src.commands()[1], Equals, "rm -f "+archive)
+}
+
+func (s *taskSuite) TestCheckMonitorHealth(c *C) {
+	var (
+		pending   int32 = 2
+		requested []string
+		mu        sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/-/ready" && atomic.AddInt32(&pending, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	port, err := strconv.Atoi(server.URL[strings.LastIndex(server.URL, ":")+1:])
+	c.Assert(err, IsNil)
+	// both are served by the same server
+	topo := &meta.Specification{
+		Monitors: []meta.PrometheusSpec{{Host: "127.0.0.1", Port: port}},
+		Grafana:  []meta.GrafanaSpec{{Host: "127.0.0.1", Port: port}},
+	}
+	insts := append((&meta.MonitorComponent{Specification: topo}).Instances(), (&meta.GrafanaComponent{Specification: topo}).Instances()...)
+
+	// Prometheus is ready after replaying the WAL
+	t := &CheckMonitorHealth{instances: insts, timeout: 10 * time.Second}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(requested, DeepEquals, []string{"/-/ready", "/-/ready", "/-/ready", "/api/health"})
+
+	// never ready
+	atomic.StoreInt32(&pending, 1000)
+	t = &CheckMonitorHealth{instances: insts[:1], timeout: 1500 * time.Millisecond}
+	c.Assert(t.Execute(NewContext()), ErrorMatches, "prometheus 127.0.0.1:"+strconv.Itoa(port)+" is not healthy in 1.5s: .*code 503.*")
+}
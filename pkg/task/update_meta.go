@@ -59,6 +59,12 @@ func (u *UpdateMeta) Execute(ctx *Context) error {
 		}
 		newMeta.Topology.PDServers = append(newMeta.Topology.PDServers, topo.PDServers[i])
 	}
+	for i, instance := range (&meta.TiProxyComponent{Specification: topo}).Instances() {
+		if deleted.Exist(instance.ID()) {
+			continue
+		}
+		newMeta.Topology.TiProxyServers = append(newMeta.Topology.TiProxyServers, topo.TiProxyServers[i])
+	}
 	for i, instance := range (&meta.TiFlashComponent{Specification: topo}).Instances() {
 		if deleted.Exist(instance.ID()) {
 			continue
@@ -77,6 +83,12 @@ func (u *UpdateMeta) Execute(ctx *Context) error {
 		}
 		newMeta.Topology.Drainers = append(newMeta.Topology.Drainers, topo.Drainers[i])
 	}
+	for i, instance := range (&meta.CDCComponent{Specification: topo}).Instances() {
+		if deleted.Exist(instance.ID()) {
+			continue
+		}
+		newMeta.Topology.CDCServers = append(newMeta.Topology.CDCServers, topo.CDCServers[i])
+	}
 	for i, instance := range (&meta.MonitorComponent{Specification: topo}).Instances() {
 		if deleted.Exist(instance.ID()) {
 			continue
@@ -0,0 +1,133 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+)
+
+// UpgradeSnapshotFileName is the file name of the snapshot of an upgrade in the cluster directory
+const UpgradeSnapshotFileName = "upgrade-snapshot.json"
+
+// upgradeStates are the states recorded by the checkpoints, the later ones take precedence
+var upgradeStates = []operator.NodeState{operator.NodeBackedUp, operator.NodeUpgrading, operator.NodeDone}
+
+// UpgradeState records the state of each instance in an upgrade by the checkpoints of the
+// context, which are persisted by the snapshot of the context to resume an interrupted upgrade.
+type UpgradeState struct {
+	ctx     *Context
+	version string
+}
+
+// ResumeUpgrade restores the context from the snapshot of the interrupted upgrade to the same
+// version if there is one, and makes the context snapshotted to the file while upgrading.
+// The snapshot of an upgrade to another version is discarded.
+func ResumeUpgrade(ctx *Context, path, version string) (*UpgradeState, error) {
+	state := &UpgradeState{ctx: ctx, version: version}
+	if utils.IsExist(path) {
+		s, err := ReadContextSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		prefix := upgradeCheckpointPrefix("")
+		discarded := false
+		for _, name := range s.Checkpoints {
+			if strings.HasPrefix(name, prefix) && !strings.HasPrefix(name, state.prefix()) {
+				discarded = true
+				break
+			}
+		}
+		if discarded {
+			log.Warnf("Discard the interrupted upgrade in %s which is not to %s", path, version)
+		} else {
+			ctx.Restore(s)
+		}
+	}
+	ctx.SnapshotTo(path)
+	return state, nil
+}
+
+func upgradeCheckpointPrefix(version string) string {
+	if version == "" {
+		return "upgrade/"
+	}
+	return fmt.Sprintf("upgrade/%s/", version)
+}
+
+func (s *UpgradeState) prefix() string {
+	return upgradeCheckpointPrefix(s.version)
+}
+
+func (s *UpgradeState) checkpoint(id string, state operator.NodeState) string {
+	return fmt.Sprintf("%s%s/%s", s.prefix(), state, id)
+}
+
+// State implements the operator.UpgradeState interface
+func (s *UpgradeState) State(id string) operator.NodeState {
+	for i := len(upgradeStates) - 1; i >= 0; i-- {
+		if s.ctx.Checkpointed(s.checkpoint(id, upgradeStates[i])) {
+			return upgradeStates[i]
+		}
+	}
+	return operator.NodePending
+}
+
+// SetState implements the operator.UpgradeState interface
+func (s *UpgradeState) SetState(id string, state operator.NodeState) error {
+	return s.ctx.MarkCheckpoint(s.checkpoint(id, state))
+}
+
+// BackedUp records the binaries of the instance have been backed up, the ones of an instance
+// not pending have been replaced and must not be backed up again
+func (s *UpgradeState) BackedUp(id string) error {
+	if s.State(id) != operator.NodePending {
+		return nil
+	}
+	return s.SetState(id, operator.NodeBackedUp)
+}
+
+// Started returns whether the upgrade is resumed from an interrupted one
+func (s *UpgradeState) Started() bool {
+	return len(s.ctx.checkpointsWithPrefix(s.prefix())) > 0
+}
+
+// Done returns the number of the instances which have been upgraded
+func (s *UpgradeState) Done() int {
+	prefix := fmt.Sprintf("%s%s/", s.prefix(), operator.NodeDone)
+	return len(s.ctx.checkpointsWithPrefix(prefix))
+}
+
+// Finish clears the states of the upgraded instances, the snapshot is removed if no other
+// instance is in the upgrade, otherwise the remaining instances are still resumed by the
+// next upgrade to the same version
+func (s *UpgradeState) Finish(ids []string) error {
+	var names []string
+	for _, id := range ids {
+		for _, state := range upgradeStates {
+			names = append(names, s.checkpoint(id, state))
+		}
+	}
+	if err := s.ctx.UnmarkCheckpoints(names...); err != nil {
+		return err
+	}
+	if len(s.ctx.checkpointsWithPrefix(upgradeCheckpointPrefix(""))) > 0 {
+		return nil
+	}
+	return s.ctx.removeSnapshot()
+}
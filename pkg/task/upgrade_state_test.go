@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"path/filepath"
+
+	operator "github.com/pingcap-incubator/tiup-cluster/pkg/operation"
+	"github.com/pingcap-incubator/tiup/pkg/utils"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestResumeUpgrade(c *C) {
+	path := filepath.Join(c.MkDir(), UpgradeSnapshotFileName)
+
+	ctx := NewContext()
+	ctx.SetChangeID("CHG-2048")
+	state, err := ResumeUpgrade(ctx, path, "v4.0.0")
+	c.Assert(err, IsNil)
+	c.Assert(state.Started(), IsFalse)
+	c.Assert(state.BackedUp("172.16.5.140:4000"), IsNil)
+	c.Assert(state.SetState("172.16.5.140:20160", operator.NodeUpgrading), IsNil)
+	c.Assert(state.SetState("172.16.5.140:20160", operator.NodeDone), IsNil)
+	c.Assert(state.SetState("172.16.5.141:20160", operator.NodeUpgrading), IsNil)
+	c.Assert(utils.IsExist(path), IsTrue)
+
+	// the interrupted upgrade is resumed by a new context from the snapshot
+	ctx = NewContext()
+	state, err = ResumeUpgrade(ctx, path, "v4.0.0")
+	c.Assert(err, IsNil)
+	c.Assert(ctx.ChangeID(), Equals, "CHG-2048")
+	c.Assert(state.Started(), IsTrue)
+	c.Assert(state.Done(), Equals, 1)
+	c.Assert(state.State("172.16.5.140:4000"), Equals, operator.NodeBackedUp)
+	c.Assert(state.State("172.16.5.140:20160"), Equals, operator.NodeDone)
+	c.Assert(state.State("172.16.5.141:20160"), Equals, operator.NodeUpgrading)
+	c.Assert(state.State("172.16.5.142:20160"), Equals, operator.NodePending)
+	// the binaries of an instance not pending are never backed up again
+	c.Assert(state.BackedUp("172.16.5.141:20160"), IsNil)
+	c.Assert(state.State("172.16.5.141:20160"), Equals, operator.NodeUpgrading)
+
+	// the instances not upgraded are kept for the next upgrade
+	c.Assert(state.Finish([]string{"172.16.5.140:20160", "172.16.5.141:20160"}), IsNil)
+	c.Assert(utils.IsExist(path), IsTrue)
+	state, err = ResumeUpgrade(NewContext(), path, "v4.0.0")
+	c.Assert(err, IsNil)
+	c.Assert(state.Done(), Equals, 0)
+	c.Assert(state.State("172.16.5.140:4000"), Equals, operator.NodeBackedUp)
+	c.Assert(state.State("172.16.5.141:20160"), Equals, operator.NodePending)
+
+	c.Assert(state.Finish([]string{"172.16.5.140:4000"}), IsNil)
+	c.Assert(utils.IsExist(path), IsFalse)
+}
+
+func (s *taskSuite) TestResumeUpgradeToAnotherVersion(c *C) {
+	path := filepath.Join(c.MkDir(), UpgradeSnapshotFileName)
+
+	state, err := ResumeUpgrade(NewContext(), path, "v4.0.0-rc")
+	c.Assert(err, IsNil)
+	c.Assert(state.SetState("172.16.5.140:20160", operator.NodeDone), IsNil)
+
+	state, err = ResumeUpgrade(NewContext(), path, "v4.0.0")
+	c.Assert(err, IsNil)
+	c.Assert(state.Started(), IsFalse)
+	c.Assert(state.State("172.16.5.140:20160"), Equals, operator.NodePending)
+
+	// the discarded snapshot is overwritten by the upgrade to the new version
+	c.Assert(state.SetState("172.16.5.140:20160", operator.NodeUpgrading), IsNil)
+	snapshot, err := ReadContextSnapshot(path)
+	c.Assert(err, IsNil)
+	c.Assert(snapshot.Checkpoints, DeepEquals, []string{"upgrade/v4.0.0/upgrading/172.16.5.140:20160"})
+}
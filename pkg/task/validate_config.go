@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+var (
+	errNSValidateConfig = errNS.NewSubNamespace("validate_config")
+	// ErrConfigKeyRemoved means some configuration items have been removed in the target version
+	ErrConfigKeyRemoved = errNSValidateConfig.NewType("key_removed", errutil.ErrTraitPreCheck)
+)
+
+// ValidateConfig is used to check the configuration items of all instances against
+// the known items of the target version. The unknown and deprecated items are
+// reported as warnings, and the removed items fail the task.
+type ValidateConfig struct {
+	topo    *meta.Specification
+	version string
+	issues  []meta.ConfigKeyIssue
+}
+
+// Execute implements the Task interface
+func (v *ValidateConfig) Execute(ctx *Context) error {
+	issues, err := v.topo.CheckConfigKeys(v.version)
+	if err != nil {
+		return err
+	}
+	v.issues = issues
+
+	var removed []string
+	for _, issue := range issues {
+		if issue.Removed {
+			removed = append(removed, issue.String())
+			continue
+		}
+		log.Warnf("Config check for %s: %s", v.version, issue.String())
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	return ErrConfigKeyRemoved.
+		New("Some configs are not supported by %s:\n  - %s", v.version, strings.Join(removed, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please remove or replace the configs listed above and try again."))
+}
+
+// Issues returns all the problems found in the configuration
+func (v *ValidateConfig) Issues() []meta.ConfigKeyIssue {
+	return v.issues
+}
+
+// Rollback implements the Task interface
+func (v *ValidateConfig) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *ValidateConfig) String() string {
+	return fmt.Sprintf("ValidateConfig: version=%s", v.version)
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestValidateConfig(c *C) {
+	topo := &meta.Specification{}
+	err := yaml.Unmarshal([]byte(`
+server_configs:
+  tidb:
+    enable-streaming: true
+tidb_servers:
+  - host: 172.16.5.138
+    config:
+      log.file.log-rotate: true
+tikv_servers:
+  - host: 172.16.5.139
+pd_servers:
+  - host: 172.16.5.140
+`), topo)
+	c.Assert(err, IsNil)
+
+	// removed configs fail the task
+	t := &ValidateConfig{topo: topo, version: "v4.0.0"}
+	err = t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrConfigKeyRemoved), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*`log.file.log-rotate` has been removed since v4.0.0.*")
+	c.Assert(t.Issues(), HasLen, 2)
+
+	// deprecated configs are only warned
+	topo.TiDBServers[0].Config = nil
+	t = &ValidateConfig{topo: topo, version: "v4.0.0"}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Issues(), HasLen, 1)
+
+	t = &ValidateConfig{topo: topo, version: "v3.0.0"}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Issues(), HasLen, 0)
+}
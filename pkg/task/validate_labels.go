@@ -0,0 +1,97 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSValidateLabels = errNS.NewSubNamespace("validate_labels")
+	// ErrLabelInvalid means the labels of TiKV instances can not be used by PD to distribute the replicas
+	ErrLabelInvalid = errNSValidateLabels.NewType("invalid", errutil.ErrTraitPreCheck)
+)
+
+// ValidateLabels is used to check the `server.labels` of all TiKV instances against the
+// `replication.location-labels` of PD, the instances sharing a host without location
+// labels are only reported as warnings. The placement rules generated from the topology
+// are written to rulesPath as JSON if it's not empty and the labels are valid.
+type ValidateLabels struct {
+	topo      *meta.Specification
+	rulesPath string
+	issues    []meta.LabelIssue
+}
+
+// Execute implements the Task interface
+func (v *ValidateLabels) Execute(ctx *Context) error {
+	issues, err := v.topo.CheckLabels()
+	if err != nil {
+		return err
+	}
+	v.issues = issues
+
+	var msgs []string
+	for _, issue := range issues {
+		if issue.Kind == meta.LabelIssueUnlabeled {
+			log.Warnf("Label check: %s", issue.String())
+			continue
+		}
+		msgs = append(msgs, issue.String())
+	}
+	if len(msgs) > 0 {
+		return ErrLabelInvalid.
+			New("Some TiKV labels are invalid for the replication config of PD:\n  - %s", strings.Join(msgs, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString("Please fix the server.labels of TiKV or the replication.location-labels of PD and try again."))
+	}
+
+	if v.rulesPath == "" {
+		return nil
+	}
+	rules, err := v.topo.PlacementRules()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if err := ioutil.WriteFile(v.rulesPath, data, 0644); err != nil {
+		return errors.Annotatef(err, "failed to write placement rules to %s", v.rulesPath)
+	}
+	return nil
+}
+
+// Issues returns all the problems found in the labels
+func (v *ValidateLabels) Issues() []meta.LabelIssue {
+	return v.issues
+}
+
+// Rollback implements the Task interface
+func (v *ValidateLabels) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *ValidateLabels) String() string {
+	return fmt.Sprintf("ValidateLabels: rules=%s", v.rulesPath)
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+var labeledTopology = `
+server_configs:
+  pd:
+    replication.location-labels: ["zone", "host"]
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+    config:
+      server.labels: { zone: "z1", host: "h1" }
+  - host: 172.16.5.141
+    config:
+      server.labels: { zone: "z2", host: "h2" }
+  - host: 172.16.5.142
+    config:
+      server.labels: { zone: "z3", host: "h3" }
+`
+
+func (s *taskSuite) TestValidateLabels(c *C) {
+	dir, err := ioutil.TempDir("", "validate-labels")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(labeledTopology), &topo), IsNil)
+
+	path := filepath.Join(dir, "rules.json")
+	t := &ValidateLabels{topo: &topo, rulesPath: path}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Issues(), HasLen, 0)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	var rules []meta.PlacementRule
+	c.Assert(json.Unmarshal(data, &rules), IsNil)
+	c.Assert(rules, HasLen, 3)
+	for _, rule := range rules {
+		c.Assert(rule.Count, Equals, 1)
+		c.Assert(rule.LocationLabels, DeepEquals, []string{"host"})
+	}
+}
+
+func (s *taskSuite) TestValidateLabelsInvalid(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(labeledTopology), &topo), IsNil)
+	topo.TiKVServers[2].Config = map[string]interface{}{"server.labels": map[string]interface{}{"zone": "z1", "host": "h1"}}
+
+	t := &ValidateLabels{topo: &topo}
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrLabelInvalid), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*172.16.5.142:20160: location zone=z1/host=h1 is the same as 172.16.5.140:20160 on a different host.*")
+	c.Assert(t.Issues(), HasLen, 1)
+}
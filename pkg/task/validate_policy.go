@@ -0,0 +1,314 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSPolicy = errNS.NewSubNamespace("policy")
+	// ErrPolicyViolated means the topology violates some rules of the policy
+	ErrPolicyViolated = errNSPolicy.NewType("violated", errutil.ErrTraitPreCheck)
+)
+
+// The kinds of the policy rules
+const (
+	// PolicyMinInstances requires at least Count instances of Component
+	PolicyMinInstances = "min_instances"
+	// PolicyMaxInstances requires at most Count instances of Component
+	PolicyMaxInstances = "max_instances"
+	// PolicyMinReplicas requires the `replication.max-replicas` of PD is at least Count
+	PolicyMinReplicas = "min_replicas"
+	// PolicyExclusiveComponents requires no host has the instances of more than one of Components
+	PolicyExclusiveComponents = "exclusive_components"
+	// PolicyDistinctHosts requires the instances of Component are on different hosts
+	PolicyDistinctHosts = "distinct_hosts"
+	// PolicyFieldMatch requires the Field of the instances of Component matches Pattern, the
+	// fields are host, port, ssh_port, deploy_dir, data_dir and log_dir
+	PolicyFieldMatch = "field_match"
+)
+
+// BuiltinPolicyRules are the rules referenced by name from the policy files
+var BuiltinPolicyRules = map[string]PolicyRule{
+	"min-3-pd":            {Kind: PolicyMinInstances, Component: meta.ComponentPD, Count: 3},
+	"min-3-tikv":          {Kind: PolicyMinInstances, Component: meta.ComponentTiKV, Count: 3},
+	"replicas-at-least-3": {Kind: PolicyMinReplicas, Count: 3},
+	"pd-tikv-separated":   {Kind: PolicyExclusiveComponents, Components: []string{meta.ComponentPD, meta.ComponentTiKV}},
+	"tikv-distinct-hosts": {Kind: PolicyDistinctHosts, Component: meta.ComponentTiKV},
+}
+
+// PolicyRule is a declarative rule the topology must comply with. It's either a builtin
+// rule referenced by Builtin, or a custom one of Kind with the parameters of the kind.
+type PolicyRule struct {
+	Name       string   `yaml:"name,omitempty"`
+	Builtin    string   `yaml:"builtin,omitempty"`
+	Kind       string   `yaml:"kind,omitempty"`
+	Component  string   `yaml:"component,omitempty"`
+	Components []string `yaml:"components,omitempty"`
+	Count      int      `yaml:"count,omitempty"`
+	Field      string   `yaml:"field,omitempty"`
+	Pattern    string   `yaml:"pattern,omitempty"`
+	// the message reported instead of the generated one if the rule is violated
+	Message string `yaml:"message,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Policy is the rules loaded from a policy file, e.g:
+//
+//	rules:
+//	  - builtin: min-3-pd
+//	  - builtin: pd-tikv-separated
+//	  - name: tikv-on-data-disks
+//	    kind: field_match
+//	    component: tikv
+//	    field: data_dir
+//	    pattern: ^/data\d+/
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyViolation is a rule violated by the topology
+type PolicyViolation struct {
+	Rule    string
+	Message string
+}
+
+// LoadPolicy loads the policy from the file, the builtin rules are resolved and the
+// custom ones are validated
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "failed to read the policy %s", path)
+	}
+	policy := &Policy{}
+	if err := yaml.NewDecoder(bytes.NewBuffer(data), yaml.DisallowUnknownField()).Decode(policy); err != nil {
+		return nil, errors.Annotatef(err, "failed to parse the policy %s", path)
+	}
+	if err := policy.resolve(); err != nil {
+		return nil, errors.Annotatef(err, "invalid policy %s", path)
+	}
+	return policy, nil
+}
+
+// resolve replaces the builtin rules by their definitions and validates the rules
+func (p *Policy) resolve() error {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Builtin != "" {
+			builtin, ok := BuiltinPolicyRules[rule.Builtin]
+			if !ok {
+				return errors.Errorf("unknown builtin rule %s", rule.Builtin)
+			}
+			builtin.Name = rule.Builtin
+			if rule.Name != "" {
+				builtin.Name = rule.Name
+			}
+			builtin.Message = rule.Message
+			*rule = builtin
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if err := rule.validate(); err != nil {
+			return errors.Annotatef(err, "rule %s", rule.Name)
+		}
+	}
+	return nil
+}
+
+func (r *PolicyRule) validate() error {
+	switch r.Kind {
+	case PolicyMinInstances, PolicyMaxInstances, PolicyDistinctHosts, PolicyFieldMatch:
+		if r.Component == "" {
+			return errors.Errorf("component is required by %s", r.Kind)
+		}
+	case PolicyExclusiveComponents:
+		if len(r.Components) < 2 {
+			return errors.Errorf("at least 2 components are required by %s", r.Kind)
+		}
+	case PolicyMinReplicas:
+	case "":
+		return errors.New("either builtin or kind is required")
+	default:
+		return errors.Errorf("unknown kind %s", r.Kind)
+	}
+	switch r.Kind {
+	case PolicyMinInstances, PolicyMinReplicas:
+		if r.Count <= 0 {
+			return errors.Errorf("a positive count is required by %s", r.Kind)
+		}
+	case PolicyFieldMatch:
+		if _, ok := instanceFields[r.Field]; !ok {
+			return errors.Errorf("unknown field %s", r.Field)
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return errors.Annotatef(err, "invalid pattern %s", r.Pattern)
+		}
+		r.pattern = pattern
+	}
+	return nil
+}
+
+// instanceFields are the fields of the instances matched by the field_match rules
+var instanceFields = map[string]func(inst meta.Instance) string{
+	"host":       meta.Instance.GetHost,
+	"port":       func(inst meta.Instance) string { return strconv.Itoa(inst.GetPort()) },
+	"ssh_port":   func(inst meta.Instance) string { return strconv.Itoa(inst.GetSSHPort()) },
+	"deploy_dir": meta.Instance.DeployDir,
+	"data_dir":   meta.Instance.DataDir,
+	"log_dir":    meta.Instance.LogDir,
+}
+
+// Evaluate returns the rules violated by the topology, the instances removed by the
+// operation are excluded
+func (p *Policy) Evaluate(topo *meta.Specification, removed set.StringSet) ([]PolicyViolation, error) {
+	instances := make(map[string][]meta.Instance)
+	topo.IterInstance(func(inst meta.Instance) {
+		if !removed.Exist(inst.ID()) {
+			instances[inst.ComponentName()] = append(instances[inst.ComponentName()], inst)
+		}
+	})
+
+	var violations []PolicyViolation
+	for _, rule := range p.Rules {
+		msgs, err := rule.evaluate(topo, instances)
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to evaluate rule %s", rule.Name)
+		}
+		if len(msgs) > 0 && rule.Message != "" {
+			msgs = []string{rule.Message}
+		}
+		for _, msg := range msgs {
+			violations = append(violations, PolicyViolation{Rule: rule.Name, Message: msg})
+		}
+	}
+	return violations, nil
+}
+
+func (r *PolicyRule) evaluate(topo *meta.Specification, instances map[string][]meta.Instance) ([]string, error) {
+	switch r.Kind {
+	case PolicyMinInstances:
+		if n := len(instances[r.Component]); n < r.Count {
+			return []string{fmt.Sprintf("%d %s instances are deployed, at least %d are required", n, r.Component, r.Count)}, nil
+		}
+	case PolicyMaxInstances:
+		if n := len(instances[r.Component]); n > r.Count {
+			return []string{fmt.Sprintf("%d %s instances are deployed, at most %d are allowed", n, r.Component, r.Count)}, nil
+		}
+	case PolicyMinReplicas:
+		_, replicas, err := topo.ReplicationConfig()
+		if err != nil {
+			return nil, err
+		}
+		if replicas < r.Count {
+			return []string{fmt.Sprintf("replication.max-replicas of PD is %d, at least %d is required", replicas, r.Count)}, nil
+		}
+	case PolicyExclusiveComponents:
+		hosts := make(map[string][]string)
+		var order []string
+		for _, comp := range r.Components {
+			for _, inst := range instances[comp] {
+				comps, ok := hosts[inst.GetHost()]
+				if !ok {
+					order = append(order, inst.GetHost())
+				}
+				if len(comps) == 0 || comps[len(comps)-1] != comp {
+					hosts[inst.GetHost()] = append(comps, comp)
+				}
+			}
+		}
+		var msgs []string
+		for _, host := range order {
+			if comps := hosts[host]; len(comps) > 1 {
+				msgs = append(msgs, fmt.Sprintf("host %s has %s together", host, strings.Join(comps, " and ")))
+			}
+		}
+		return msgs, nil
+	case PolicyDistinctHosts:
+		seen := make(map[string]string)
+		var msgs []string
+		for _, inst := range instances[r.Component] {
+			if other, ok := seen[inst.GetHost()]; ok {
+				msgs = append(msgs, fmt.Sprintf("%s %s is on the same host as %s", r.Component, inst.ID(), other))
+				continue
+			}
+			seen[inst.GetHost()] = inst.ID()
+		}
+		return msgs, nil
+	case PolicyFieldMatch:
+		var msgs []string
+		for _, inst := range instances[r.Component] {
+			if v := instanceFields[r.Field](inst); !r.pattern.MatchString(v) {
+				msgs = append(msgs, fmt.Sprintf("%s of %s %s is %s, which doesn't match %s", r.Field, r.Component, inst.ID(), v, r.Pattern))
+			}
+		}
+		return msgs, nil
+	}
+	return nil, nil
+}
+
+// ValidatePolicy is used to check the topology against the rules of the policy before an
+// operation, all the violated rules are reported together
+type ValidatePolicy struct {
+	topo    *meta.Specification
+	policy  *Policy
+	removed set.StringSet
+}
+
+// Execute implements the Task interface
+func (v *ValidatePolicy) Execute(ctx *Context) error {
+	violations, err := v.policy.Evaluate(v.topo, v.removed)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, violation := range violations {
+		lines = append(lines, fmt.Sprintf("%s: %s", violation.Rule, violation.Message))
+	}
+	return ErrPolicyViolated.
+		New("The topology violates %d rules of the policy:\n  - %s", len(violations), strings.Join(lines, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please change the topology to comply with the policy, or ask the owner of the policy for an exception."))
+}
+
+// Rollback implements the Task interface
+func (v *ValidatePolicy) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *ValidatePolicy) String() string {
+	var names []string
+	for _, rule := range v.policy.Rules {
+		names = append(names, rule.Name)
+	}
+	return fmt.Sprintf("ValidatePolicy: rules=%s", strings.Join(names, ","))
+}
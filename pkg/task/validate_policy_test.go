@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/set"
+	. "github.com/pingcap/check"
+)
+
+const testPolicy = `
+rules:
+  - builtin: min-3-pd
+  - builtin: replicas-at-least-3
+  - builtin: pd-tikv-separated
+  - builtin: tikv-distinct-hosts
+    message: the replicas of TiKV must be on different hosts
+  - name: tikv-on-data-disks
+    kind: field_match
+    component: tikv
+    field: data_dir
+    pattern: ^/data\d+/
+  - kind: max_instances
+    component: tidb
+    count: 2
+`
+
+func loadTestPolicy(c *C, content string) (*Policy, error) {
+	path := filepath.Join(c.MkDir(), "policy.yaml")
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+	return LoadPolicy(path)
+}
+
+func policyTopology(c *C, doc string) *meta.Specification {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(doc), topo), IsNil)
+	return topo
+}
+
+func (s *taskSuite) TestValidatePolicy(c *C) {
+	policy, err := loadTestPolicy(c, testPolicy)
+	c.Assert(err, IsNil)
+	var names []string
+	for _, rule := range policy.Rules {
+		names = append(names, rule.Name)
+	}
+	c.Assert(names, DeepEquals, []string{"min-3-pd", "replicas-at-least-3", "pd-tikv-separated", "tikv-distinct-hosts", "tikv-on-data-disks", "rule-6"})
+
+	compliant := policyTopology(c, `
+pd_servers:
+  - host: 172.16.5.140
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+tikv_servers:
+  - host: 172.16.5.143
+    data_dir: /data1/tikv
+  - host: 172.16.5.144
+    data_dir: /data1/tikv
+  - host: 172.16.5.145
+    data_dir: /data2/tikv
+tidb_servers:
+  - host: 172.16.5.140
+`)
+	c.Assert((&ValidatePolicy{topo: compliant, policy: policy}).Execute(NewContext()), IsNil)
+
+	// the removed nodes are excluded
+	violations, err := policy.Evaluate(compliant, set.NewStringSet("172.16.5.142:2379"))
+	c.Assert(err, IsNil)
+	c.Assert(violations, DeepEquals, []PolicyViolation{
+		{Rule: "min-3-pd", Message: "2 pd instances are deployed, at least 3 are required"},
+	})
+
+	violating := policyTopology(c, `
+server_configs:
+  pd:
+    replication.max-replicas: 1
+pd_servers:
+  - host: 172.16.5.140
+tikv_servers:
+  - host: 172.16.5.140
+    data_dir: /data1/tikv
+  - host: 172.16.5.141
+    port: 20160
+    data_dir: /home/tidb/tikv
+  - host: 172.16.5.141
+    port: 20161
+    status_port: 20181
+    data_dir: /data1/tikv-20161
+tidb_servers:
+  - host: 172.16.5.141
+  - host: 172.16.5.142
+  - host: 172.16.5.143
+`)
+	err = (&ValidatePolicy{topo: violating, policy: policy}).Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrPolicyViolated), IsTrue)
+	c.Assert(err.Error(), Matches, `(?s).*The topology violates 6 rules of the policy:
+  - min-3-pd: 1 pd instances are deployed, at least 3 are required
+  - replicas-at-least-3: replication.max-replicas of PD is 1, at least 3 is required
+  - pd-tikv-separated: host 172.16.5.140 has pd and tikv together
+  - tikv-distinct-hosts: the replicas of TiKV must be on different hosts
+  - tikv-on-data-disks: data_dir of tikv 172.16.5.141:20160 is /home/tidb/tikv, which doesn't match \^/data\\d\+/
+  - rule-6: 3 tidb instances are deployed, at most 2 are allowed.*`)
+}
+
+func (s *taskSuite) TestLoadPolicyInvalid(c *C) {
+	for content, msg := range map[string]string{
+		"rules:\n  - builtin: min-5-pd\n":                                       ".*unknown builtin rule min-5-pd",
+		"rules:\n  - kind: min_instances\n    component: pd\n":                  ".*rule rule-1: a positive count is required by min_instances",
+		"rules:\n  - kind: exclusive_components\n    components: [pd]\n":        ".*at least 2 components are required by exclusive_components",
+		"rules:\n  - kind: field_match\n    component: tikv\n    field: arch\n": ".*unknown field arch",
+		"rules:\n  - name: empty\n":                                             ".*rule empty: either builtin or kind is required",
+		"rules:\n  - kind: min_cpu\n":                                           ".*unknown kind min_cpu",
+		"rule:\n  - builtin: min-3-pd\n":                                        ".*failed to parse the policy.*",
+	} {
+		_, err := loadTestPolicy(c, content)
+		c.Assert(err, ErrorMatches, "(?s)"+msg, Commentf("%s", content))
+	}
+}
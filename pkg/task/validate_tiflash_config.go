@@ -0,0 +1,91 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/clusterutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+var (
+	errNSValidateTiFlash = errNS.NewSubNamespace("validate_tiflash_config")
+	// ErrTiFlashConfigInconsistent means the configs of TiFlash and its proxy don't match
+	ErrTiFlashConfigInconsistent = errNSValidateTiFlash.NewType("inconsistent", errutil.ErrTraitPreCheck)
+)
+
+// ValidateTiFlashConfig is used to cross-check the configs of TiFlash and its proxy rendered
+// for each TiFlash instance, the ports, paths and labels coupled by them must be consistent
+// or TiFlash fails to start. The imported instances are not checked.
+type ValidateTiFlashConfig struct {
+	topo *meta.Specification
+	user string
+
+	problems map[string][]string
+}
+
+// Execute implements the Task interface
+func (v *ValidateTiFlashConfig) Execute(ctx *Context) error {
+	v.problems = make(map[string][]string)
+
+	var problems []string
+	for _, inst := range (&meta.TiFlashComponent{Specification: v.topo}).Instances() {
+		if inst.IsImported() {
+			continue
+		}
+		deployDir := clusterutil.Abs(v.user, inst.DeployDir())
+		flash, learner, err := inst.(*meta.TiFlashInstance).RenderConfigs(meta.DirPaths{
+			Deploy: deployDir,
+			Data:   inst.DataDir(),
+			Log:    clusterutil.Abs(v.user, inst.LogDir()),
+		})
+		if err != nil {
+			return err
+		}
+		found := meta.CheckTiFlashConfigs(flash, learner, deployDir)
+		if len(found) == 0 {
+			continue
+		}
+		v.problems[inst.ID()] = found
+		for _, p := range found {
+			problems = append(problems, fmt.Sprintf("%s: %s", inst.ID(), p))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return ErrTiFlashConfigInconsistent.
+		New("The configs of TiFlash and its proxy are inconsistent:\n  - %s", strings.Join(problems, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString("Please make the config and learner_config of the TiFlash instances consistent, or remove the overrides to use the generated values."))
+}
+
+// Problems returns the mismatches of each TiFlash instance
+func (v *ValidateTiFlashConfig) Problems() map[string][]string {
+	return v.problems
+}
+
+// Rollback implements the Task interface
+func (v *ValidateTiFlashConfig) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *ValidateTiFlashConfig) String() string {
+	return fmt.Sprintf("ValidateTiFlashConfig: user=%s", v.user)
+}
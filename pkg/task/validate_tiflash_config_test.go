@@ -0,0 +1,75 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+	"gopkg.in/yaml.v2"
+)
+
+func (s *taskSuite) TestValidateTiFlashConfig(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tiflash_servers:
+  - host: 172.16.5.141
+    data_dir: /data1/tiflash,/data2/tiflash
+    learner_config:
+      server.labels: { engine: tiflash, zone: z1 }
+  - host: 172.16.5.142
+    tcp_port: 9010
+    config:
+      logger.level: info
+`), topo), IsNil)
+	v := &ValidateTiFlashConfig{topo: topo, user: "tidb"}
+	c.Assert(v.Execute(NewContext()), IsNil)
+	c.Assert(v.Problems(), HasLen, 0)
+}
+
+func (s *taskSuite) TestValidateTiFlashConfigInconsistent(c *C) {
+	topo := &meta.Specification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+pd_servers:
+  - host: 172.16.5.140
+tiflash_servers:
+  - host: 172.16.5.141
+    deploy_dir: /deploy/tiflash
+    data_dir: /data1/tiflash
+    config:
+      flash.proxy.config: /etc/tiflash-learner.toml
+    learner_config:
+      server.engine-addr: 172.16.5.141:3931
+      server.addr: 0.0.0.0:9000
+      storage.data-dir: /data2/flash
+      server.labels: { engine: tikv }
+  - host: 172.16.5.142
+`), topo), IsNil)
+	v := &ValidateTiFlashConfig{topo: topo, user: "tidb"}
+	err := v.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrTiFlashConfigInconsistent), IsTrue)
+	c.Assert(v.Problems(), DeepEquals, map[string][]string{
+		"172.16.5.141:9000": {
+			"flash.service_addr `172.16.5.141:3930` is not the server.engine-addr `172.16.5.141:3931` of the proxy",
+			"flash.proxy.config `/etc/tiflash-learner.toml` is not the config of the proxy `/deploy/tiflash/conf/tiflash-learner.toml`",
+			"tcp_port and server.addr of the proxy use the same port 9000",
+			"server.advertise-addr port 20170 of the proxy is not its server.addr port 9000",
+			"storage.data-dir `/data2/flash` of the proxy is not under the path `/data1/tiflash`",
+			"server.labels.engine of the proxy is `tikv` rather than `tiflash`",
+		},
+	})
+	c.Assert(err.Error(), Matches, "(?s).*The configs of TiFlash and its proxy are inconsistent:\n  - 172.16.5.141:9000: flash.service_addr.*")
+}
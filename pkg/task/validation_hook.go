@@ -0,0 +1,116 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSHook = errNS.NewSubNamespace("hook")
+	// ErrHookRejected means the validation hook exits non-zero and the operation is blocked
+	ErrHookRejected = errNSHook.NewType("rejected", errutil.ErrTraitPreCheck)
+)
+
+// HookPayload is the JSON document passed to the validation hook via stdin
+type HookPayload struct {
+	Operation string          `json:"operation"`
+	Cluster   string          `json:"cluster"`
+	Version   string          `json:"version,omitempty"`
+	Nodes     []string        `json:"nodes,omitempty"`
+	Topology  json.RawMessage `json:"topology,omitempty"`
+	Tasks     []string        `json:"tasks,omitempty"`
+	ChangeID  string          `json:"change_id,omitempty"`
+}
+
+// NewHookPayload returns the payload of the operation on the cluster, nodes are the
+// instances operated on if it's not the whole cluster, and t is the task to be executed
+func NewHookPayload(operation, cluster, version string, nodes []string, topo *meta.Specification, t Task) (*HookPayload, error) {
+	p := &HookPayload{
+		Operation: operation,
+		Cluster:   cluster,
+		Version:   version,
+		Nodes:     nodes,
+	}
+	if topo != nil {
+		data, err := meta.TopologyJSON(topo)
+		if err != nil {
+			return nil, err
+		}
+		p.Topology = data
+	}
+	if t != nil {
+		for _, line := range strings.Split(t.String(), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				p.Tasks = append(p.Tasks, line)
+			}
+		}
+	}
+	return p, nil
+}
+
+// ValidationHook is used to run a user-provided command as a gate before an operation.
+// The command is run by `sh -c` on the local host with the payload as JSON on stdin,
+// and the operation is blocked unless it exits zero.
+type ValidationHook struct {
+	command string
+	payload *HookPayload
+}
+
+// Execute implements the Task interface
+func (v *ValidationHook) Execute(ctx *Context) error {
+	data, err := json.Marshal(v.payload)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+
+	cmd := exec.Command("sh", "-c", v.command)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return errors.Annotatef(err, "failed to run the validation hook `%s`", v.command)
+		}
+		return ErrHookRejected.
+			New("Validation hook `%s` rejected the %s of cluster %s: %s\n%s",
+				v.command, v.payload.Operation, v.payload.Cluster, err, strings.TrimSpace(string(output))).
+			WithProperty(cliutil.SuggestionFromString("Please fix the problems reported by the validation hook and try again."))
+	}
+
+	log.Infof("Validation hook `%s` passed", v.command)
+	if len(output) > 0 {
+		log.Infof("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Rollback implements the Task interface
+func (v *ValidationHook) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *ValidationHook) String() string {
+	return fmt.Sprintf("ValidationHook: command=%s, operation=%s", v.command, v.payload.Operation)
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+// hookedOperation returns an operation guarded by the validation hook, the operation
+// sets *executed to true
+func hookedOperation(c *C, command string, executed *bool) Task {
+	topo := &meta.Specification{
+		GlobalOptions: meta.GlobalOptions{User: "tidb"},
+		TiDBServers:   []meta.TiDBSpec{{Host: "172.16.5.138", Port: 4000}},
+	}
+	op := NewBuilder().Func("stop cluster", func() error {
+		*executed = true
+		return nil
+	}).Build()
+	payload, err := NewHookPayload("stop", "test-cluster", "v4.0.0", []string{"172.16.5.138:4000"}, topo, op)
+	c.Assert(err, IsNil)
+	return NewBuilder().ValidationHook(command, payload).Serial(op).Build()
+}
+
+func (s *taskSuite) TestValidationHookPass(c *C) {
+	dir, err := ioutil.TempDir("", "validation-hook")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	stdin := filepath.Join(dir, "stdin.json")
+	var executed bool
+	t := hookedOperation(c, fmt.Sprintf("cat > %s", stdin), &executed)
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(executed, IsTrue)
+
+	data, err := ioutil.ReadFile(stdin)
+	c.Assert(err, IsNil)
+	var payload struct {
+		HookPayload
+		Topology struct {
+			Global struct {
+				User string `json:"user"`
+			} `json:"global"`
+			TiDBServers []struct {
+				Host string `json:"host"`
+			} `json:"tidb_servers"`
+		} `json:"topology"`
+	}
+	c.Assert(json.Unmarshal(data, &payload), IsNil)
+	c.Assert(payload.Operation, Equals, "stop")
+	c.Assert(payload.Cluster, Equals, "test-cluster")
+	c.Assert(payload.Version, Equals, "v4.0.0")
+	c.Assert(payload.Nodes, DeepEquals, []string{"172.16.5.138:4000"})
+	c.Assert(payload.Tasks, DeepEquals, []string{"stop cluster"})
+	c.Assert(payload.Topology.Global.User, Equals, "tidb")
+	c.Assert(payload.Topology.TiDBServers, HasLen, 1)
+	c.Assert(payload.Topology.TiDBServers[0].Host, Equals, "172.16.5.138")
+}
+
+func (s *taskSuite) TestValidationHookReject(c *C) {
+	var executed bool
+	t := hookedOperation(c, `grep -q '"version":"v3' || { echo "v4.0.0 is not approved" >&2; exit 3; }`, &executed)
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrHookRejected), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*rejected the stop of cluster test-cluster: exit status 3.*v4.0.0 is not approved.*")
+	// the operation is blocked
+	c.Assert(executed, IsFalse)
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+)
+
+// SetVerboseScope makes the remote commands in the scope and their outputs logged to out,
+// it must be called before the executors are set
+func (ctx *Context) SetVerboseScope(scope *log.Scope, out io.Writer) {
+	ctx.verbose.scope = scope
+	ctx.verbose.out = out
+}
+
+// verboseMu serializes the lines of the commands issued in parallel
+var verboseMu sync.Mutex
+
+// verboseExecutor logs the commands and the transfers matching the scope with their outputs
+type verboseExecutor struct {
+	host  string
+	inner executor.TiOpsExecutor
+	scope *log.Scope
+	out   io.Writer
+}
+
+// Execute implements the TiOpsExecutor interface
+func (e *verboseExecutor) Execute(cmd string, sudo bool, timeout ...time.Duration) ([]byte, []byte, error) {
+	stdout, stderr, err := e.inner.Execute(cmd, sudo, timeout...)
+	if !e.scope.Match(e.host, cmd) {
+		return stdout, stderr, err
+	}
+
+	buf := new(bytes.Buffer)
+	prompt := "$"
+	if sudo {
+		prompt = "#"
+	}
+	fmt.Fprintf(buf, "[%s] %s %s\n", e.host, prompt, cmd)
+	writeIndented(buf, e.host, "stdout", stdout)
+	writeIndented(buf, e.host, "stderr", stderr)
+	if err != nil {
+		fmt.Fprintf(buf, "[%s] error: %s\n", e.host, err)
+	}
+	e.write(buf.Bytes())
+	return stdout, stderr, err
+}
+
+// Transfer implements the TiOpsExecutor interface
+func (e *verboseExecutor) Transfer(src string, dst string, download bool) error {
+	err := e.inner.Transfer(src, dst, download)
+	if !e.scope.Match(e.host, src+" "+dst) {
+		return err
+	}
+
+	direction := "upload"
+	if download {
+		direction = "download"
+	}
+	line := fmt.Sprintf("[%s] %s %s -> %s\n", e.host, direction, src, dst)
+	if err != nil {
+		line += fmt.Sprintf("[%s] error: %s\n", e.host, err)
+	}
+	e.write([]byte(line))
+	return err
+}
+
+// Stream implements the StreamExecutor interface, only the command is logged as the
+// lines are already shown
+func (e *verboseExecutor) Stream(ctx context.Context, cmd string, sudo bool, fn func(line string)) error {
+	if e.scope.Match(e.host, cmd) {
+		prompt := "$"
+		if sudo {
+			prompt = "#"
+		}
+		e.write([]byte(fmt.Sprintf("[%s] %s %s\n", e.host, prompt, cmd)))
+	}
+	return executor.Stream(ctx, e.inner, cmd, sudo, fn)
+}
+
+func (e *verboseExecutor) write(data []byte) {
+	verboseMu.Lock()
+	_, _ = e.out.Write(data)
+	verboseMu.Unlock()
+}
+
+func writeIndented(buf *bytes.Buffer, host, name string, output []byte) {
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(buf, "[%s] %s: %s\n", host, name, line)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	. "github.com/pingcap/check"
+)
+
+func (s *taskSuite) TestVerboseScope(c *C) {
+	scope, err := log.ParseScope("host=172.16.5.140, component=tikv")
+	c.Assert(err, IsNil)
+
+	out := new(bytes.Buffer)
+	ctx := NewContext()
+	ctx.SetVerboseScope(scope, out)
+	for _, host := range []string{"172.16.5.140", "172.16.5.141"} {
+		ctx.SetExecutor(host, &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+			if strings.HasPrefix(cmd, "systemctl start") {
+				return nil, []byte("Job failed\nSee journalctl"), errors.New("exit status 1")
+			}
+			return []byte("ok\n"), nil, nil
+		}})
+	}
+
+	e140, _ := ctx.GetExecutor("172.16.5.140")
+	e141, _ := ctx.GetExecutor("172.16.5.141")
+	_, _, _ = e140.Execute("ls /tidb-deploy/pd-2379", false)
+	_, _, _ = e141.Execute("ls /tidb-deploy/pd-2379", false)
+	_, _, _ = e141.Execute("ls /tidb-deploy/tikv-importer-8287", false)
+	_, _, _ = e141.Execute("systemctl start tikv-20160.service", true)
+	c.Assert(e141.Transfer("/tmp/run_tikv.sh", "/tidb-deploy/tikv-20160/scripts/run_tikv.sh", false), IsNil)
+	c.Assert(e141.Transfer("/tmp/run_pd.sh", "/tidb-deploy/pd-2379/scripts/run_pd.sh", false), IsNil)
+
+	// only the commands on 172.16.5.140 and the ones on the tikv instances are logged
+	c.Assert(out.String(), Equals, `[172.16.5.140] $ ls /tidb-deploy/pd-2379
+[172.16.5.140] stdout: ok
+[172.16.5.141] # systemctl start tikv-20160.service
+[172.16.5.141] stderr: Job failed
+[172.16.5.141] stderr: See journalctl
+[172.16.5.141] error: exit status 1
+[172.16.5.141] upload /tmp/run_tikv.sh -> /tidb-deploy/tikv-20160/scripts/run_tikv.sh
+`)
+}
+
+func (s *taskSuite) TestVerboseScopeDisabled(c *C) {
+	ctx := NewContext()
+	ctx.SetVerboseScope(nil, nil)
+	e := &mockExecutor{}
+	ctx.SetExecutor("172.16.5.140", e)
+	got, _ := ctx.GetExecutor("172.16.5.140")
+	c.Assert(got, Equals, e)
+
+	for _, spec := range []string{"tikv", "host=", "node=172.16.5.140"} {
+		_, err := log.ParseScope(spec)
+		c.Assert(err, NotNil, Commentf("spec %s", spec))
+	}
+	scope, err := log.ParseScope("")
+	c.Assert(err, IsNil)
+	c.Assert(scope.Empty(), IsTrue)
+}
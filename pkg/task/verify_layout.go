@@ -0,0 +1,203 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/executor"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/repository"
+	"github.com/pingcap/errors"
+)
+
+var (
+	errNSLayout = errNS.NewSubNamespace("layout")
+	// ErrLayoutDiverged means some pieces of the deploy directory of an instance are missing
+	ErrLayoutDiverged = errNSLayout.NewType("diverged", errutil.ErrTraitPreCheck)
+)
+
+// layoutDirs are the subdirectories of the deploy directory of every instance
+var layoutDirs = []string{"bin", "conf", "scripts"}
+
+// componentConfigFiles are the files generated to conf/ of the components which are not
+// configured by conf/<component>.toml
+var componentConfigFiles = map[string][]string{
+	meta.ComponentTiFlash:      {"tiflash.toml", "tiflash-learner.toml"},
+	meta.ComponentPrometheus:   {"prometheus.yml"},
+	meta.ComponentGrafana:      {"grafana.ini"},
+	meta.ComponentAlertManager: {"alertmanager.yml"},
+}
+
+// VerifyLayout is used to check that the deploy directory of an instance has the expected
+// subdirectories and key files, i.e. the main binary, the config files and the run script,
+// in case they are moved around manually. The missing pieces are staged again if repair is
+// enabled: the directories are created, the binary is copied from the package and the
+// config files and the script are generated again.
+type VerifyLayout struct {
+	inspection
+
+	clusterName    string
+	clusterVersion string
+	version        repository.Version
+	instance       meta.Instance
+	deployUser     string
+	paths          meta.DirPaths
+	repair         bool
+
+	missing []string
+}
+
+// Execute implements the Task interface
+func (v *VerifyLayout) Execute(ctx *Context) error {
+	exec, found := ctx.GetExecutor(v.instance.GetHost())
+	if !found {
+		return ErrNoExecutor
+	}
+
+	missing, err := v.check(exec)
+	if err != nil {
+		return err
+	}
+	v.missing = missing
+	if len(missing) == 0 {
+		return nil
+	}
+
+	ctx.Logger().Warnf("The deploy directory %s of %s is missing: %s", v.paths.Deploy, v.instance.ID(), strings.Join(missing, ", "))
+	if !v.repair {
+		return ErrLayoutDiverged.
+			New("The deploy directory %s of %s is missing:\n  - %s", v.paths.Deploy, v.instance.ID(), strings.Join(missing, "\n  - ")).
+			WithProperty(cliutil.SuggestionFromString("Please restore the files, or run with --repair to stage them again."))
+	}
+
+	ctx.Logger().Infof("Repairing the deploy directory %s of %s", v.paths.Deploy, v.instance.ID())
+	for _, t := range v.repairTasks(missing) {
+		if err := t.Execute(ctx); err != nil {
+			return errors.Annotatef(err, "failed to repair the deploy directory of %s", v.instance.ID())
+		}
+	}
+
+	// the pieces may be still missing, e.g. the binary is absent from the package
+	if missing, err = v.check(exec); err != nil {
+		return err
+	}
+	v.missing = missing
+	if len(missing) > 0 {
+		return ErrLayoutDiverged.
+			New("The deploy directory %s of %s is still missing after the repair:\n  - %s", v.paths.Deploy, v.instance.ID(), strings.Join(missing, "\n  - "))
+	}
+	return nil
+}
+
+// expected returns the pieces of the layout relative to the deploy directory
+func (v *VerifyLayout) expected() []string {
+	comp := v.instance.ComponentName()
+	pieces := append([]string{}, layoutDirs...)
+	if bin, ok := componentBinaries[comp]; ok {
+		pieces = append(pieces, filepath.Join("bin", bin.path))
+	}
+	confs, ok := componentConfigFiles[comp]
+	if !ok {
+		confs = []string{comp + ".toml"}
+	}
+	for _, conf := range confs {
+		pieces = append(pieces, filepath.Join("conf", conf))
+	}
+	return append(pieces, filepath.Join("scripts", fmt.Sprintf("run_%s.sh", comp)))
+}
+
+// check returns the pieces of the layout absent from the deploy directory
+func (v *VerifyLayout) check(exec executor.TiOpsExecutor) ([]string, error) {
+	pieces := v.expected()
+	cmd := fmt.Sprintf("cd %s && for p in %s; do test -e $p || echo $p; done", v.paths.Deploy, strings.Join(pieces, " "))
+	stdout, stderr, err := exec.Execute(cmd, false)
+	if err != nil {
+		// the deploy directory itself is gone
+		return pieces, errors.Annotatef(err, "failed to check the deploy directory of %s, stderr: %s", v.instance.ID(), stderr)
+	}
+
+	absent := map[string]bool{}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			absent[line] = true
+		}
+	}
+	var missing []string
+	for _, p := range pieces {
+		if absent[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// repairTasks returns the tasks staging the missing pieces again
+func (v *VerifyLayout) repairTasks(missing []string) []Task {
+	var (
+		dirs          []string
+		binary, confs bool
+	)
+	for _, p := range missing {
+		switch {
+		case !strings.Contains(p, "/"):
+			dirs = append(dirs, filepath.Join(v.paths.Deploy, p))
+		case strings.HasPrefix(p, "bin/"):
+			binary = true
+		default:
+			confs = true
+		}
+	}
+
+	var tasks []Task
+	if len(dirs) > 0 {
+		tasks = append(tasks, &Mkdir{user: v.deployUser, host: v.instance.GetHost(), dirs: dirs})
+	}
+	if binary {
+		comp := v.instance.ComponentName()
+		tasks = append(tasks,
+			&Downloader{component: comp, version: v.version},
+			&CopyComponent{component: comp, version: v.version, host: v.instance.GetHost(), dstDir: v.paths.Deploy},
+		)
+	}
+	if confs {
+		tasks = append(tasks, &InitConfig{
+			clusterName:    v.clusterName,
+			clusterVersion: v.clusterVersion,
+			instance:       v.instance,
+			deployUser:     v.deployUser,
+			paths:          v.paths,
+		})
+	}
+	return tasks
+}
+
+// Missing returns the pieces absent from the deploy directory, relative to it
+func (v *VerifyLayout) Missing() []string {
+	return v.missing
+}
+
+// Rollback implements the Task interface
+func (v *VerifyLayout) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *VerifyLayout) String() string {
+	return fmt.Sprintf("VerifyLayout: instance=%s, deploy=%s, repair=%v", v.instance.ID(), v.paths.Deploy, v.repair)
+}
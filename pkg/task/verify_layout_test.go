@@ -0,0 +1,120 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+	. "github.com/pingcap/check"
+)
+
+// layoutExecutor reports the pieces of the layout are missing until a mkdir is executed
+func layoutExecutor(missing string) *mockExecutor {
+	e := &mockExecutor{}
+	e.handler = func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "mkdir -p") {
+			missing = ""
+		}
+		if strings.HasPrefix(cmd, "cd /home/tidb/deploy/tidb-4000 && for p in") {
+			return []byte(missing), nil, nil
+		}
+		return nil, nil, nil
+	}
+	return e
+}
+
+func layoutTask(c *C, cache string, repair bool) *VerifyLayout {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(`
+tidb_servers:
+  - host: 172.16.5.140
+`), &topo), IsNil)
+	return &VerifyLayout{
+		clusterName:    "test",
+		clusterVersion: "v4.0.0",
+		version:        "v4.0.0",
+		instance:       (&meta.TiDBComponent{Specification: &topo}).Instances()[0],
+		deployUser:     "tidb",
+		paths:          meta.DirPaths{Deploy: "/home/tidb/deploy/tidb-4000", Log: "/home/tidb/deploy/tidb-4000/log", Cache: cache},
+		repair:         repair,
+	}
+}
+
+func (s *taskSuite) TestVerifyLayout(c *C) {
+	t := layoutTask(c, "", false)
+	c.Assert(t.expected(), DeepEquals, []string{"bin", "conf", "scripts", "bin/tidb-server", "conf/tidb.toml", "scripts/run_tidb.sh"})
+
+	e := layoutExecutor("")
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Missing(), HasLen, 0)
+	c.Assert(e.commands(), DeepEquals, []string{
+		"cd /home/tidb/deploy/tidb-4000 && for p in bin conf scripts bin/tidb-server conf/tidb.toml scripts/run_tidb.sh; do test -e $p || echo $p; done",
+	})
+
+	// the conf directory is moved away
+	ctx := newMockContext("172.16.5.140", layoutExecutor("conf\nconf/tidb.toml\n"))
+	logger := &captureLogger{}
+	ctx.SetLogger(logger)
+	err := t.Execute(ctx)
+	c.Assert(errorx.IsOfType(err, ErrLayoutDiverged), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*The deploy directory /home/tidb/deploy/tidb-4000 of 172.16.5.140:4000 is missing:\n  - conf\n  - conf/tidb.toml.*")
+	c.Assert(t.Missing(), DeepEquals, []string{"conf", "conf/tidb.toml"})
+	c.Assert(logger.lines, DeepEquals, []string{
+		"WARN The deploy directory /home/tidb/deploy/tidb-4000 of 172.16.5.140:4000 is missing: conf, conf/tidb.toml",
+	})
+}
+
+func (s *taskSuite) TestVerifyLayoutRepair(c *C) {
+	wd, err := os.Getwd()
+	c.Assert(err, IsNil)
+	os.Setenv(localdata.EnvNameComponentInstallDir, filepath.Join(wd, "..", ".."))
+	defer os.Unsetenv(localdata.EnvNameComponentInstallDir)
+	cache, err := ioutil.TempDir("", "verify-layout")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(cache)
+
+	// the directory is created and the config is generated again
+	t := layoutTask(c, cache, true)
+	e := layoutExecutor("conf\nconf/tidb.toml\n")
+	c.Assert(t.Execute(newMockContext("172.16.5.140", e)), IsNil)
+	c.Assert(t.Missing(), HasLen, 0)
+	cmds := e.commands()
+	c.Assert(cmds[1], Equals, "mkdir -p {/home/tidb/deploy/tidb-4000/conf}")
+	c.Assert(cmds[len(cmds)-1], Matches, "cd /home/tidb/deploy/tidb-4000 && for p in .*")
+	var transferred bool
+	for _, transfer := range e.transfers {
+		if strings.HasSuffix(transfer, " -> /home/tidb/deploy/tidb-4000/conf/tidb.toml") {
+			transferred = true
+		}
+	}
+	c.Assert(transferred, IsTrue)
+
+	// the pieces still missing are reported
+	e = &mockExecutor{handler: func(cmd string) ([]byte, []byte, error) {
+		if strings.HasPrefix(cmd, "cd ") {
+			return []byte("scripts/run_tidb.sh\n"), nil, nil
+		}
+		return nil, nil, nil
+	}}
+	err = t.Execute(newMockContext("172.16.5.140", e))
+	c.Assert(errorx.IsOfType(err, ErrLayoutDiverged), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*is still missing after the repair:\n  - scripts/run_tidb.sh.*")
+}
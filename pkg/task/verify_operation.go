@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+)
+
+var (
+	errNSVerify = errNS.NewSubNamespace("verify")
+	// ErrVerificationFailed means the end state of the cluster deviates from the intended one
+	// after an operation
+	ErrVerificationFailed = errNSVerify.NewType("failed")
+	// ErrInstanceUnhealthy means some instances are not healthy
+	ErrInstanceUnhealthy = errNSVerify.NewType("unhealthy")
+)
+
+// Verification is a named check of the end state of the cluster, it fails if the state
+// deviates from the intended one
+type Verification struct {
+	Name string
+	Task Task
+}
+
+// VerifyOperation is used to run the verifications after an operation in parallel, and
+// summarize the results. All of them are run even if some fail, and the deviations are
+// reported together.
+type VerifyOperation struct {
+	inspection
+
+	operation string
+	cluster   string
+	checks    []Verification
+
+	results []error
+}
+
+// Execute implements the Task interface
+func (v *VerifyOperation) Execute(ctx *Context) error {
+	v.results = make([]error, len(v.checks))
+
+	var mu sync.Mutex
+	var tasks []Task
+	for i, check := range v.checks {
+		i, check := i, check
+		tasks = append(tasks, &Func{
+			name: check.Name,
+			fn: func() error {
+				err := check.Task.Execute(ctx)
+				mu.Lock()
+				v.results[i] = err
+				mu.Unlock()
+				return nil
+			},
+		})
+	}
+	if err := (&Parallel{inner: tasks, hideDetailDisplay: true}).Execute(ctx); err != nil {
+		return err
+	}
+
+	rows := [][]string{{"Verification", "Result"}}
+	var deviations []string
+	for i, check := range v.checks {
+		result := "OK"
+		if err := v.results[i]; err != nil {
+			result = "Deviated"
+			deviations = append(deviations, fmt.Sprintf("%s: %s", check.Name, err))
+		}
+		rows = append(rows, []string{check.Name, result})
+	}
+	cliutil.PrintTable(rows, true)
+
+	if len(deviations) == 0 {
+		log.Infof("All the %d verifications after the %s of cluster `%s` passed", len(v.checks), v.operation, v.cluster)
+		return nil
+	}
+	return ErrVerificationFailed.
+		New("%d of %d verifications after the %s of cluster %s failed:\n  - %s",
+			len(deviations), len(v.checks), v.operation, v.cluster, strings.Join(deviations, "\n  - ")).
+		WithProperty(cliutil.SuggestionFromString(fmt.Sprintf("The %s is performed, but the cluster is not in the intended state, please check the deviations above.", v.operation)))
+}
+
+// Results returns the error of each verification, nil for the passed ones
+func (v *VerifyOperation) Results() map[string]error {
+	results := make(map[string]error)
+	for i, check := range v.checks {
+		results[check.Name] = v.results[i]
+	}
+	return results
+}
+
+// Rollback implements the Task interface
+func (v *VerifyOperation) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (v *VerifyOperation) String() string {
+	var names []string
+	for _, check := range v.checks {
+		names = append(names, check.Name)
+	}
+	return fmt.Sprintf("VerifyOperation: operation=%s, cluster=%s, verifications=%s", v.operation, v.cluster, strings.Join(names, ","))
+}
+
+// CheckHealth is used to check whether all the instances of the cluster are up by their
+// status apis
+type CheckHealth struct {
+	inspection
+
+	spec   *meta.Specification
+	status func(inst meta.Instance, pdList []string) string
+
+	unhealthy []string
+}
+
+// Execute implements the Task interface
+func (c *CheckHealth) Execute(ctx *Context) error {
+	status := c.status
+	if status == nil {
+		status = func(inst meta.Instance, pdList []string) string {
+			return inst.Status(pdList...)
+		}
+	}
+
+	pdList := c.spec.GetPDList()
+	var instances []meta.Instance
+	c.spec.IterInstance(func(inst meta.Instance) {
+		instances = append(instances, inst)
+	})
+	statuses := make([]string, len(instances))
+	var wg sync.WaitGroup
+	for i, inst := range instances {
+		wg.Add(1)
+		go func(i int, inst meta.Instance) {
+			defer wg.Done()
+			statuses[i] = status(inst, pdList)
+		}(i, inst)
+	}
+	wg.Wait()
+
+	c.unhealthy = nil
+	var problems []string
+	for i, inst := range instances {
+		if !healthyStatus(statuses[i]) {
+			c.unhealthy = append(c.unhealthy, inst.ID())
+			problems = append(problems, fmt.Sprintf("%s %s is %s", inst.ComponentName(), inst.ID(), statuses[i]))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return ErrInstanceUnhealthy.New("%d instances are not healthy: %s", len(problems), strings.Join(problems, ", "))
+}
+
+// Unhealthy returns the IDs of the unhealthy instances
+func (c *CheckHealth) Unhealthy() []string {
+	return c.unhealthy
+}
+
+// healthyStatus reports whether the status of an instance is a healthy one, the status of
+// PD is like `Healthy|L` and the others are like `Up` or `Up|UI`
+func healthyStatus(status string) bool {
+	return strings.HasPrefix(status, "Up") || strings.HasPrefix(status, "Healthy")
+}
+
+// Rollback implements the Task interface
+func (c *CheckHealth) Rollback(ctx *Context) error {
+	return ErrUnsupportedRollback
+}
+
+// String implements the fmt.Stringer interface
+func (c *CheckHealth) String() string {
+	return "CheckHealth"
+}
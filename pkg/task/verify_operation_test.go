@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"errors"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joomcode/errorx"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/meta"
+	. "github.com/pingcap/check"
+)
+
+func verification(name string, err error) Verification {
+	return Verification{Name: name, Task: &Func{name: name, fn: func() error { return err }}}
+}
+
+func (s *taskSuite) TestVerifyOperation(c *C) {
+	t := &VerifyOperation{
+		operation: "upgrade",
+		cluster:   "test",
+		checks:    []Verification{verification("version", nil), verification("health", nil)},
+	}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Results(), DeepEquals, map[string]error{"version": nil, "health": nil})
+}
+
+func (s *taskSuite) TestVerifyOperationDeviated(c *C) {
+	drift := errors.New("1 instances are not running v4.0.0: 172.16.5.140:20160")
+	t := &VerifyOperation{
+		operation: "upgrade",
+		cluster:   "test",
+		checks: []Verification{
+			verification("version", drift),
+			verification("health", nil),
+			verification("layout", errors.New("bin/tikv-server is missing")),
+		},
+	}
+
+	// all the verifications are run even if some fail
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrVerificationFailed), IsTrue)
+	c.Assert(err.Error(), Matches, "(?s).*2 of 3 verifications after the upgrade of cluster test failed:\n"+
+		"  - version: 1 instances are not running v4.0.0: 172.16.5.140:20160\n"+
+		"  - layout: bin/tikv-server is missing.*")
+	c.Assert(t.Results()["version"], Equals, drift)
+	c.Assert(t.Results()["health"], IsNil)
+}
+
+func (s *taskSuite) TestCheckHealth(c *C) {
+	topo := meta.TopologySpecification{}
+	c.Assert(yaml.Unmarshal([]byte(labeledTopology), &topo), IsNil)
+
+	statuses := map[string]string{
+		"172.16.5.140:2379":  "Healthy|L",
+		"172.16.5.140:20160": "Up",
+		"172.16.5.141:20160": "Up",
+		"172.16.5.142:20160": "Up",
+	}
+	t := &CheckHealth{spec: &topo, status: func(inst meta.Instance, pdList []string) string {
+		c.Assert(pdList, DeepEquals, []string{"172.16.5.140:2379"})
+		return statuses[inst.ID()]
+	}}
+	c.Assert(t.Execute(NewContext()), IsNil)
+	c.Assert(t.Unhealthy(), HasLen, 0)
+
+	statuses["172.16.5.141:20160"] = "Down"
+	statuses["172.16.5.142:20160"] = "Offline"
+	err := t.Execute(NewContext())
+	c.Assert(errorx.IsOfType(err, ErrInstanceUnhealthy), IsTrue)
+	c.Assert(t.Unhealthy(), DeepEquals, []string{"172.16.5.141:20160", "172.16.5.142:20160"})
+	c.Assert(err.Error(), Matches, ".*2 instances are not healthy: tikv 172.16.5.141:20160 is Down, tikv 172.16.5.142:20160 is Offline.*")
+}
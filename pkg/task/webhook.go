@@ -0,0 +1,164 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/log"
+	"go.uber.org/zap"
+)
+
+const (
+	// webhookBuffer is the number of events buffered to be posted, the later ones are
+	// dropped if the webhook is too slow to keep the buffer from being filled
+	webhookBuffer = 64
+	// webhookRetries is the number of times a failed post is retried
+	webhookRetries = 3
+)
+
+// The lifecycle events of the operations posted to the webhook
+const (
+	WebhookEventStart  = "start"
+	WebhookEventFinish = "finish"
+	WebhookEventFail   = "fail"
+)
+
+// WebhookPayload is the JSON document posted to the webhook for each lifecycle event of
+// an operation
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	Operation string    `json:"operation"`
+	Cluster   string    `json:"cluster"`
+	ChangeID  string    `json:"change_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Webhook posts the operation begin and finish events of the event buses attached to it to
+// a URL as JSON, e.g. for the ChatOps notifications. The events are posted in order in the
+// background so the operations are never blocked, and the posts failed by the network or
+// the 5xx responses are retried with backoff.
+type Webhook struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+
+	events chan WebhookPayload
+	done   chan struct{}
+}
+
+// NewWebhook returns a webhook posting to the url, each post times out after timeout
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	w := &Webhook{
+		url:     url,
+		client:  &http.Client{Timeout: timeout},
+		retries: webhookRetries,
+		backoff: time.Second,
+		events:  make(chan WebhookPayload, webhookBuffer),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Attach subscribes the operation events of the event bus
+func (w *Webhook) Attach(ev *EventBus) {
+	ev.Subscribe(EventOperationBegin, func(operation, cluster string) {
+		w.enqueue(WebhookPayload{Event: WebhookEventStart, Operation: operation, Cluster: cluster, ChangeID: ev.ChangeID(), Time: time.Now()})
+	})
+	ev.Subscribe(EventOperationFinish, func(operation, cluster string, err error) {
+		p := WebhookPayload{Event: WebhookEventFinish, Operation: operation, Cluster: cluster, ChangeID: ev.ChangeID(), Time: time.Now()}
+		if err != errTaskSucceeded && err != nil {
+			p.Event = WebhookEventFail
+			p.Error = err.Error()
+			p.Category = string(errutil.CategoryOf(err))
+		}
+		w.enqueue(p)
+	})
+}
+
+// enqueue queues the event to be posted without blocking
+func (w *Webhook) enqueue(p WebhookPayload) {
+	select {
+	case w.events <- p:
+	default:
+		zap.L().Debug("Drop the webhook event as the webhook is too slow", zap.String("event", p.Event))
+	}
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	for p := range w.events {
+		if err := w.post(p); err != nil {
+			log.Warnf("Failed to post the %s event of the %s of cluster `%s` to the webhook: %s", p.Event, p.Operation, p.Cluster, err)
+		}
+	}
+}
+
+// post posts the event, and retries if it fails by the network or the server
+func (w *Webhook) post(p WebhookPayload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.backoff
+	for i := 0; ; i++ {
+		err = w.postOnce(data)
+		if err == nil {
+			return nil
+		}
+		if _, retryable := err.(retryableError); !retryable || i >= w.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// retryableError is the error of a post which may succeed if it's retried
+type retryableError struct{ error }
+
+func (w *Webhook) postOnce(data []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return retryableError{err}
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return retryableError{fmt.Errorf("the webhook responded %s", resp.Status)}
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("the webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Close stops accepting the events and waits at most timeout for the queued ones to be posted
+func (w *Webhook) Close(timeout time.Duration) {
+	close(w.events)
+	select {
+	case <-w.done:
+	case <-time.After(timeout):
+		log.Warnf("Some events are not posted to the webhook in %s", timeout)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// webhookReceiver records the payloads posted, and responds the statuses in order before
+// responding 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	attempts int
+	payloads []WebhookPayload
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	var p WebhookPayload
+	if err := json.NewDecoder(req.Body).Decode(&p); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.payloads = append(r.payloads, p)
+}
+
+func newTestWebhook(url string) *Webhook {
+	w := NewWebhook(url, time.Second)
+	w.backoff = time.Millisecond
+	return w
+}
+
+func (s *taskSuite) TestWebhook(c *C) {
+	r := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ev := NewEventBus()
+	ev.SetChangeID("CHG-1024")
+	w := newTestWebhook(server.URL)
+	w.Attach(&ev)
+
+	ev.PublishOperationBegin("upgrade", "test")
+	ev.PublishOperationFinish("upgrade", "test", nil)
+	ev.PublishOperationBegin("restart", "test")
+	ev.PublishOperationFinish("restart", "test", errors.New("failed to restart tikv"))
+	w.Close(5 * time.Second)
+
+	// the first one is retried on 5xx
+	c.Assert(r.attempts, Equals, 6)
+	c.Assert(r.payloads, HasLen, 4)
+	for _, p := range r.payloads {
+		c.Assert(p.Cluster, Equals, "test")
+		c.Assert(p.ChangeID, Equals, "CHG-1024")
+	}
+	c.Assert(r.payloads[0].Event, Equals, WebhookEventStart)
+	c.Assert(r.payloads[0].Operation, Equals, "upgrade")
+	c.Assert(r.payloads[1].Event, Equals, WebhookEventFinish)
+	c.Assert(r.payloads[1].Error, Equals, "")
+	c.Assert(r.payloads[3].Event, Equals, WebhookEventFail)
+	c.Assert(r.payloads[3].Operation, Equals, "restart")
+	c.Assert(r.payloads[3].Error, Equals, "failed to restart tikv")
+}
+
+func (s *taskSuite) TestWebhookGiveUp(c *C) {
+	// not retried on 4xx
+	r := &webhookReceiver{statuses: []int{http.StatusNotFound}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	w := newTestWebhook(server.URL)
+	c.Assert(w.post(WebhookPayload{Event: WebhookEventStart}), ErrorMatches, "the webhook responded 404 Not Found")
+	c.Assert(r.attempts, Equals, 1)
+
+	// given up after the retries
+	r.statuses = []int{500, 500, 500, 500, 500}
+	err := w.post(WebhookPayload{Event: WebhookEventStart})
+	c.Assert(err, ErrorMatches, "the webhook responded 500 Internal Server Error")
+	c.Assert(r.attempts, Equals, 1+webhookRetries+1)
+	w.Close(time.Second)
+
+	// the operation is not blocked by a slow webhook
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer slow.Close()
+	ev := NewEventBus()
+	w = newTestWebhook(slow.URL)
+	w.Attach(&ev)
+	start := time.Now()
+	for i := 0; i < webhookBuffer*2; i++ {
+		ev.PublishOperationBegin("upgrade", "test")
+	}
+	w.Close(10 * time.Millisecond)
+	c.Assert(time.Since(start) < time.Second, IsTrue)
+}
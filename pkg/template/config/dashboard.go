@@ -50,7 +50,7 @@ func (c *DashboardConfig) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Dashboard config content by tpl
 func (c *DashboardConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("dashboard").Parse(tpl)
+	tmpl, err := template.New("dashboard").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +58,7 @@ func (c *DatasourceConfig) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Datasource config content by tpl
 func (c *DatasourceConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Datasource").Parse(tpl)
+	tmpl, err := template.New("Datasource").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
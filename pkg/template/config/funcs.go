@@ -0,0 +1,30 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"text/template"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+)
+
+// funcMap is the helper functions available in the config templates,
+// use {{joinHostPort .IP .Port}} to render an address so that IPv6 hosts are bracketed
+var funcMap = template.FuncMap{
+	"joinHostPort": func(host string, port interface{}) string {
+		return net.JoinHostPort(utils.UnwrapHost(host), fmt.Sprint(port))
+	},
+}
@@ -58,7 +58,7 @@ func (c *GrafanaConfig) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Grafana config content by tpl
 func (c *GrafanaConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Grafana").Parse(tpl)
+	tmpl, err := template.New("Grafana").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -15,12 +15,13 @@ package config
 
 import (
 	"bytes"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"text/template"
 
+	"github.com/pingcap-incubator/tiup-cluster/pkg/utils"
+
 	"github.com/pingcap-incubator/tiup/pkg/localdata"
 )
 
@@ -33,9 +34,11 @@ type PrometheusConfig struct {
 	TiKVStatusAddrs           []string
 	PDAddrs                   []string
 	TiFlashStatusAddrs        []string
+	TiProxyStatusAddrs        []string
 	TiFlashLearnerStatusAddrs []string
 	PumpAddrs                 []string
 	DrainerAddrs              []string
+	CDCAddrs                  []string
 	ZookeeperAddrs            []string
 	BlackboxExporterAddrs     []string
 	LightningAddrs            []string
@@ -56,73 +59,85 @@ func NewPrometheusConfig(cluster string) *PrometheusConfig {
 
 // AddKafka add a kafka address
 func (c *PrometheusConfig) AddKafka(ip string, port uint64) *PrometheusConfig {
-	c.KafkaAddrs = append(c.KafkaAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.KafkaAddrs = append(c.KafkaAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddNodeExpoertor add a node expoter address
 func (c *PrometheusConfig) AddNodeExpoertor(ip string, port uint64) *PrometheusConfig {
-	c.NodeExporterAddrs = append(c.NodeExporterAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.NodeExporterAddrs = append(c.NodeExporterAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddTiDB add a TiDB address
 func (c *PrometheusConfig) AddTiDB(ip string, port uint64) *PrometheusConfig {
-	c.TiDBStatusAddrs = append(c.TiDBStatusAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.TiDBStatusAddrs = append(c.TiDBStatusAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddTiKV add a TiKV address
 func (c *PrometheusConfig) AddTiKV(ip string, port uint64) *PrometheusConfig {
-	c.TiKVStatusAddrs = append(c.TiKVStatusAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.TiKVStatusAddrs = append(c.TiKVStatusAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddPD add a PD address
 func (c *PrometheusConfig) AddPD(ip string, port uint64) *PrometheusConfig {
-	c.PDAddrs = append(c.PDAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.PDAddrs = append(c.PDAddrs, utils.JoinHostPort(ip, int(port)))
+	return c
+}
+
+// AddTiProxy add a TiProxy status address
+func (c *PrometheusConfig) AddTiProxy(ip string, port uint64) *PrometheusConfig {
+	c.TiProxyStatusAddrs = append(c.TiProxyStatusAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddTiFlashLearner add a TiFlash learner address
 func (c *PrometheusConfig) AddTiFlashLearner(ip string, port uint64) *PrometheusConfig {
-	c.TiFlashLearnerStatusAddrs = append(c.TiFlashLearnerStatusAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.TiFlashLearnerStatusAddrs = append(c.TiFlashLearnerStatusAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddTiFlash add a TiFlash address
 func (c *PrometheusConfig) AddTiFlash(ip string, port uint64) *PrometheusConfig {
-	c.TiFlashStatusAddrs = append(c.TiFlashStatusAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.TiFlashStatusAddrs = append(c.TiFlashStatusAddrs, utils.JoinHostPort(ip, int(port)))
+	return c
+}
+
+// AddCDC add a TiCDC address
+func (c *PrometheusConfig) AddCDC(ip string, port uint64) *PrometheusConfig {
+	c.CDCAddrs = append(c.CDCAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddPump add a pump address
 func (c *PrometheusConfig) AddPump(ip string, port uint64) *PrometheusConfig {
-	c.PumpAddrs = append(c.PumpAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.PumpAddrs = append(c.PumpAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddDrainer add a drainer address
 func (c *PrometheusConfig) AddDrainer(ip string, port uint64) *PrometheusConfig {
-	c.DrainerAddrs = append(c.DrainerAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.DrainerAddrs = append(c.DrainerAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddZooKeeper add a zookeeper address
 func (c *PrometheusConfig) AddZooKeeper(ip string, port uint64) *PrometheusConfig {
-	c.ZookeeperAddrs = append(c.ZookeeperAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.ZookeeperAddrs = append(c.ZookeeperAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddBlackboxExporter add a BlackboxExporter address
 func (c *PrometheusConfig) AddBlackboxExporter(ip string, port uint64) *PrometheusConfig {
-	c.BlackboxExporterAddrs = append(c.BlackboxExporterAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.BlackboxExporterAddrs = append(c.BlackboxExporterAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
 // AddLightning add a lightning address
 func (c *PrometheusConfig) AddLightning(ip string, port uint64) *PrometheusConfig {
-	c.LightningAddrs = append(c.LightningAddrs, fmt.Sprintf("%s:%d", ip, port))
+	c.LightningAddrs = append(c.LightningAddrs, utils.JoinHostPort(ip, int(port)))
 	return c
 }
 
@@ -134,34 +149,59 @@ func (c *PrometheusConfig) AddMonitoredServer(ip string) *PrometheusConfig {
 
 // AddAlertmanager add an alertmanager address
 func (c *PrometheusConfig) AddAlertmanager(ip string, port uint64) *PrometheusConfig {
-	c.AlertmanagerAddr = fmt.Sprintf("%s:%d", ip, port)
+	c.AlertmanagerAddr = utils.JoinHostPort(ip, int(port))
 	return c
 }
 
 // AddPushgateway add an pushgateway address
 func (c *PrometheusConfig) AddPushgateway(ip string, port uint64) *PrometheusConfig {
-	c.PushgatewayAddr = fmt.Sprintf("%s:%d", ip, port)
+	c.PushgatewayAddr = utils.JoinHostPort(ip, int(port))
 	return c
 }
 
 // AddBlackbox add an blackbox address
 func (c *PrometheusConfig) AddBlackbox(ip string, port uint64) *PrometheusConfig {
-	c.BlackboxAddr = fmt.Sprintf("%s:%d", ip, port)
+	c.BlackboxAddr = utils.JoinHostPort(ip, int(port))
 	return c
 }
 
 // AddKafkaExporter add an kafka exporter address
 func (c *PrometheusConfig) AddKafkaExporter(ip string, port uint64) *PrometheusConfig {
-	c.KafkaExporterAddr = fmt.Sprintf("%s:%d", ip, port)
+	c.KafkaExporterAddr = utils.JoinHostPort(ip, int(port))
 	return c
 }
 
 // AddGrafana add an kafka exporter address
 func (c *PrometheusConfig) AddGrafana(ip string, port uint64) *PrometheusConfig {
-	c.GrafanaAddr = fmt.Sprintf("%s:%d", ip, port)
+	c.GrafanaAddr = utils.JoinHostPort(ip, int(port))
 	return c
 }
 
+// Targets returns the scrape targets of the instances by the job names in the template, the
+// probes of the blackbox exporter are not included
+func (c *PrometheusConfig) Targets() map[string][]string {
+	targets := map[string][]string{
+		"overwritten-nodes": c.NodeExporterAddrs,
+		"tidb":              c.TiDBStatusAddrs,
+		"tikv":              c.TiKVStatusAddrs,
+		"pd":                c.PDAddrs,
+	}
+	if len(c.TiProxyStatusAddrs) > 0 {
+		targets["tiproxy"] = c.TiProxyStatusAddrs
+	}
+	if len(c.CDCAddrs) > 0 {
+		targets["ticdc"] = c.CDCAddrs
+	}
+	if len(c.TiFlashStatusAddrs) > 0 {
+		targets["tiflash"] = append(append([]string{}, c.TiFlashStatusAddrs...), c.TiFlashLearnerStatusAddrs...)
+	}
+	if len(c.PumpAddrs) > 0 {
+		targets["pump"] = c.PumpAddrs
+		targets["drainer"] = c.DrainerAddrs
+	}
+	return targets
+}
+
 // Config read ${localdata.EnvNameComponentInstallDir}/templates/config/prometheus.yml.tpl
 // and generate the config by ConfigWithTemplate
 func (c *PrometheusConfig) Config() ([]byte, error) {
@@ -175,7 +215,7 @@ func (c *PrometheusConfig) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Prometheus config content by tpl
 func (c *PrometheusConfig) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Prometheus").Parse(tpl)
+	tmpl, err := template.New("Prometheus").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +50,7 @@ func (c *ActionScript) Config() (string, error) {
 
 // ConfigWithTemplate generate the Action config content by tpl
 func (c *ActionScript) ConfigWithTemplate(tpl string) (string, error) {
-	tmpl, err := template.New("action").Parse(tpl)
+	tmpl, err := template.New("action").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return "", err
 	}
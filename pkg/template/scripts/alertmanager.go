@@ -84,7 +84,7 @@ func (c *AlertManagerScript) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the AlertManager config content by tpl
 func (c *AlertManagerScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("AlertManager").Parse(tpl)
+	tmpl, err := template.New("AlertManager").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
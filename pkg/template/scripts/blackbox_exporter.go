@@ -74,7 +74,7 @@ func (c *BlackboxExporterScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the BlackboxExporter config content by tpl
 func (c *BlackboxExporterScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("BlackboxExporter").Parse(tpl)
+	tmpl, err := template.New("BlackboxExporter").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
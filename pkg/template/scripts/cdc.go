@@ -0,0 +1,111 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scripts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+)
+
+// CDCScript represent the data to generate TiCDC config
+type CDCScript struct {
+	IP        string
+	Port      int
+	DeployDir string
+	LogDir    string
+	NumaNode  string
+	GCTTL     int64
+	TZ        string
+	Endpoints []*PDScript
+}
+
+// NewCDCScript returns a CDCScript with given arguments
+func NewCDCScript(ip, deployDir, logDir string) *CDCScript {
+	return &CDCScript{
+		IP:        ip,
+		Port:      8300,
+		DeployDir: deployDir,
+		LogDir:    logDir,
+	}
+}
+
+// WithPort set Port field of CDCScript
+func (c *CDCScript) WithPort(port int) *CDCScript {
+	c.Port = port
+	return c
+}
+
+// WithNumaNode set NumaNode field of CDCScript
+func (c *CDCScript) WithNumaNode(numa string) *CDCScript {
+	c.NumaNode = numa
+	return c
+}
+
+// WithGCTTL set GCTTL field of CDCScript
+func (c *CDCScript) WithGCTTL(ttl int64) *CDCScript {
+	c.GCTTL = ttl
+	return c
+}
+
+// WithTZ set TZ field of CDCScript
+func (c *CDCScript) WithTZ(tz string) *CDCScript {
+	c.TZ = tz
+	return c
+}
+
+// AppendEndpoints add new PDScript to Endpoints field
+func (c *CDCScript) AppendEndpoints(ends ...*PDScript) *CDCScript {
+	c.Endpoints = append(c.Endpoints, ends...)
+	return c
+}
+
+// Config read ${localdata.EnvNameComponentInstallDir}/templates/scripts/run_cdc.sh.tpl as template
+// and generate the config by ConfigWithTemplate
+func (c *CDCScript) Config() ([]byte, error) {
+	fp := path.Join(os.Getenv(localdata.EnvNameComponentInstallDir), "templates", "scripts", "run_cdc.sh.tpl")
+	tpl, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigWithTemplate(string(tpl))
+}
+
+// ConfigToFile write config content to specific path
+func (c *CDCScript) ConfigToFile(file string) error {
+	config, err := c.Config()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, config, 0755)
+}
+
+// ConfigWithTemplate generate the TiCDC config content by tpl
+func (c *CDCScript) ConfigWithTemplate(tpl string) ([]byte, error) {
+	tmpl, err := template.New("CDC").Funcs(funcMap).Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBufferString("")
+	if err := tmpl.Execute(content, c); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
@@ -95,7 +95,7 @@ func (c *DrainerScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the Drainer config content by tpl
 func (c *DrainerScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Drainer").Parse(tpl)
+	tmpl, err := template.New("Drainer").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +57,7 @@ func (c *GrafanaScript) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Grafana config content by tpl
 func (c *GrafanaScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Grafana").Parse(tpl)
+	tmpl, err := template.New("Grafana").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +74,7 @@ func (c *NodeExporterScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the NodeExporter config content by tpl
 func (c *NodeExporterScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("NodeExporter").Parse(tpl)
+	tmpl, err := template.New("NodeExporter").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
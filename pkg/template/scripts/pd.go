@@ -105,7 +105,7 @@ func (c *PDScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the PD config content by tpl
 func (c *PDScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("PD").Parse(tpl)
+	tmpl, err := template.New("PD").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +69,7 @@ func (c *PrometheusScript) Config() ([]byte, error) {
 
 // ConfigWithTemplate generate the Prometheus config content by tpl
 func (c *PrometheusScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Prometheus").Parse(tpl)
+	tmpl, err := template.New("Prometheus").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +88,7 @@ func (c *PumpScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the Pump config content by tpl
 func (c *PumpScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("Pump").Parse(tpl)
+	tmpl, err := template.New("Pump").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +181,7 @@ func (c *TiFlashScript) ConfigTiFlashToFile(file string) error {
 
 // ConfigWithTemplate generate the TiFlash config content by tpl
 func (c *TiFlashScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("TiFlash").Parse(tpl)
+	tmpl, err := template.New("TiFlash").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +93,7 @@ func (c *TiKVScript) ConfigToFile(file string) error {
 
 // ConfigWithTemplate generate the TiKV config content by tpl
 func (c *TiKVScript) ConfigWithTemplate(tpl string) ([]byte, error) {
-	tmpl, err := template.New("TiKV").Parse(tpl)
+	tmpl, err := template.New("TiKV").Funcs(funcMap).Parse(tpl)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scripts
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"text/template"
+
+	"github.com/pingcap-incubator/tiup/pkg/localdata"
+)
+
+// TiProxyScript represent the data to generate TiProxy config
+type TiProxyScript struct {
+	IP        string
+	DeployDir string
+	LogDir    string
+	NumaNode  string
+}
+
+// NewTiProxyScript returns a TiProxyScript with given arguments
+func NewTiProxyScript(ip, deployDir, logDir string) *TiProxyScript {
+	return &TiProxyScript{
+		IP:        ip,
+		DeployDir: deployDir,
+		LogDir:    logDir,
+	}
+}
+
+// WithNumaNode set NumaNode field of TiProxyScript
+func (c *TiProxyScript) WithNumaNode(numa string) *TiProxyScript {
+	c.NumaNode = numa
+	return c
+}
+
+// Config read ${localdata.EnvNameComponentInstallDir}/templates/scripts/run_tiproxy.sh.tpl as template
+// and generate the config by ConfigWithTemplate
+func (c *TiProxyScript) Config() ([]byte, error) {
+	fp := path.Join(os.Getenv(localdata.EnvNameComponentInstallDir), "templates", "scripts", "run_tiproxy.sh.tpl")
+	tpl, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigWithTemplate(string(tpl))
+}
+
+// ConfigToFile write config content to specific path
+func (c *TiProxyScript) ConfigToFile(file string) error {
+	config, err := c.Config()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, config, 0755)
+}
+
+// ConfigWithTemplate generate the TiProxy config content by tpl
+func (c *TiProxyScript) ConfigWithTemplate(tpl string) ([]byte, error) {
+	tmpl, err := template.New("TiProxy").Funcs(funcMap).Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBufferString("")
+	if err := tmpl.Execute(content, c); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
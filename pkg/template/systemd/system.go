@@ -33,6 +33,7 @@ type Config struct {
 	IOWriteBandwidthMax string
 	DeployDir           string
 	DisableSendSigkill  bool
+	TimeoutStopSec      int // the seconds to wait for the service to exit before it's killed
 	// Takes one of no, on-success, on-failure, on-abnormal, on-watchdog, on-abort, or always.
 	// The Template set as always if this is not setted.
 	Restart string
@@ -71,6 +72,12 @@ func (c *Config) WithIOWriteBandwidthMax(io string) *Config {
 	return c
 }
 
+// WithTimeoutStopSec set the TimeoutStopSec field of Config
+func (c *Config) WithTimeoutStopSec(sec int) *Config {
+	c.TimeoutStopSec = sec
+	return c
+}
+
 // ConfigToFile write config content to specific path
 func (c *Config) ConfigToFile(file string) error {
 	config, err := c.Config()
@@ -80,6 +87,25 @@ func (c *Config) ConfigToFile(file string) error {
 	return ioutil.WriteFile(file, config, 0755)
 }
 
+// ConfigToFileWithTemplate write the config content generated by the template file tplFile to
+// specific path, it's used to override the built-in template
+func (c *Config) ConfigToFileWithTemplate(tplFile, file string) error {
+	config, err := c.ConfigWithTemplateFile(tplFile)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, config, 0755)
+}
+
+// ConfigWithTemplateFile read tplFile as template and generate the config by ConfigWithTemplate
+func (c *Config) ConfigWithTemplateFile(tplFile string) ([]byte, error) {
+	tpl, err := ioutil.ReadFile(tplFile)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfigWithTemplate(string(tpl))
+}
+
 // Config read ${localdata.EnvNameComponentInstallDir}/templates/systemd/system.service.tpl as template
 // and generate the config by ConfigWithTemplate
 func (c *Config) Config() ([]byte, error) {
@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/pingcap/errors"
+)
+
+// PartialSuffix is the suffix of the files being downloaded, a file is renamed to drop
+// the suffix only after it's verified
+const PartialSuffix = ".partial"
+
+// DownloadResumable downloads the url to dst. The content is written to the partial file
+// dst.partial first, which is continued from its end by a range request if it's left by an
+// interrupted download. The partial file is renamed to dst after it passes the verification,
+// or removed if it fails as the corrupted content can't be continued.
+func DownloadResumable(client *http.Client, url, dst string, verify func(path string) error) error {
+	partial := dst + PartialSuffix
+
+	var offset int64
+	if fi, err := os.Stat(partial); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return errors.AddStack(err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Annotatef(err, "failed to download %s", url)
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// the server doesn't support range requests, download from the beginning
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is complete already
+		flags = 0
+	default:
+		return errors.Errorf("failed to download %s: %s", url, res.Status)
+	}
+
+	if flags != 0 {
+		f, err := os.OpenFile(partial, flags, 0644)
+		if err != nil {
+			return errors.AddStack(err)
+		}
+		_, err = io.Copy(f, res.Body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return errors.Annotatef(err, "download of %s is interrupted, it will be continued by the next download", url)
+		}
+	}
+
+	if err := verify(partial); err != nil {
+		_ = os.Remove(partial)
+		return err
+	}
+	return errors.AddStack(os.Rename(partial, dst))
+}
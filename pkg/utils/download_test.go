@@ -0,0 +1,136 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+// flakyServer serves the content with range requests supported, the first response is
+// cut off after the number of bytes to simulate an interrupted download
+type flakyServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	cutOff int
+	ranges []string
+}
+
+func newFlakyServer(content []byte, cutOff int) *flakyServer {
+	s := &flakyServer{cutOff: cutOff}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		cutOff := s.cutOff
+		s.cutOff = 0
+		s.mu.Unlock()
+
+		if cutOff > 0 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:cutOff])
+			return
+		}
+		http.ServeContent(w, r, "package.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	return s
+}
+
+// downloadClient doesn't use the default transport, which caches the proxy environments used by
+// the tests of proxies
+var downloadClient = &http.Client{Transport: &http.Transport{}}
+
+func (s *utilsSuite) TestDownloadResumable(c *C) {
+	content := bytes.Repeat([]byte("tikv-v4.0.0-linux-amd64"), 1024)
+	server := newFlakyServer(content, 1000)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "tiops-download")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "tikv-v4.0.0-linux-amd64.tar.gz")
+
+	verified := 0
+	verify := func(path string) error {
+		verified++
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, content) {
+			return errors.New("checksum mismatch")
+		}
+		return nil
+	}
+
+	// the interrupted download is kept as the partial file
+	err = DownloadResumable(downloadClient, server.URL, dst, verify)
+	c.Assert(err, ErrorMatches, ".*download of .* is interrupted, it will be continued by the next download.*")
+	c.Assert(verified, Equals, 0)
+	_, err = os.Stat(dst)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	partial, err := ioutil.ReadFile(dst + PartialSuffix)
+	c.Assert(err, IsNil)
+	c.Assert(partial, DeepEquals, content[:1000])
+
+	// continued from the end of the partial file and verified
+	c.Assert(DownloadResumable(downloadClient, server.URL, dst, verify), IsNil)
+	c.Assert(server.ranges, DeepEquals, []string{"", "bytes=1000-"})
+	c.Assert(verified, Equals, 1)
+	data, err := ioutil.ReadFile(dst)
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, content)
+	_, err = os.Stat(dst + PartialSuffix)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
+
+func (s *utilsSuite) TestDownloadResumableCorrupted(c *C) {
+	content := bytes.Repeat([]byte("pd-v4.0.0-linux-amd64"), 1024)
+	server := newFlakyServer(content, 0)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "tiops-download")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "pd-v4.0.0-linux-amd64.tar.gz")
+
+	// the partial file left by another version can't be continued, it's removed after
+	// failing the verification
+	c.Assert(ioutil.WriteFile(dst+PartialSuffix, []byte("garbage"), 0644), IsNil)
+	err = DownloadResumable(downloadClient, server.URL, dst, func(path string) error {
+		return errors.New("checksum mismatch")
+	})
+	c.Assert(err, ErrorMatches, "checksum mismatch")
+	_, err = os.Stat(dst + PartialSuffix)
+	c.Assert(os.IsNotExist(err), IsTrue)
+	_, err = os.Stat(dst)
+	c.Assert(os.IsNotExist(err), IsTrue)
+
+	// the complete partial file is verified without downloading again
+	c.Assert(ioutil.WriteFile(dst+PartialSuffix, content, 0644), IsNil)
+	c.Assert(DownloadResumable(downloadClient, server.URL, dst, func(path string) error { return nil }), IsNil)
+	c.Assert(server.ranges[len(server.ranges)-1], Equals, "bytes="+strconv.Itoa(len(content))+"-")
+	data, err := ioutil.ReadFile(dst)
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, content)
+}
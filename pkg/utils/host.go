@@ -0,0 +1,51 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// UnwrapHost strips the brackets around an IPv6 literal, e.g. "[::1]" => "::1",
+// other hosts are returned as is
+func UnwrapHost(host string) string {
+	if len(host) > 1 && strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// JoinHostPort combines host and port into a network address of the form "host:port",
+// IPv6 literals are enclosed in square brackets, e.g. "[::1]:2379"
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(UnwrapHost(host), strconv.Itoa(port))
+}
+
+// ParseHostPort splits a network address of the form "host:port" or "[host]:port"
+// into host and port, the brackets of IPv6 literals are removed from the host
+func ParseHostPort(addr string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, errors.Annotatef(err, "invalid address '%s'", addr)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, errors.Annotatef(err, "invalid port of address '%s'", addr)
+	}
+	return host, port, nil
+}
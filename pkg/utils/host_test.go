@@ -0,0 +1,52 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+type utilsSuite struct {
+}
+
+var _ = Suite(&utilsSuite{})
+
+func TestUtils(t *testing.T) {
+	TestingT(t)
+}
+
+func (s *utilsSuite) TestJoinHostPort(c *C) {
+	c.Assert(JoinHostPort("172.16.5.140", 2379), Equals, "172.16.5.140:2379")
+	c.Assert(JoinHostPort("tidb.example.com", 4000), Equals, "tidb.example.com:4000")
+	c.Assert(JoinHostPort("fd00::1", 2379), Equals, "[fd00::1]:2379")
+	c.Assert(JoinHostPort("[fd00::1]", 2379), Equals, "[fd00::1]:2379")
+	c.Assert(UnwrapHost("[::1]"), Equals, "::1")
+	c.Assert(UnwrapHost("::1"), Equals, "::1")
+}
+
+func (s *utilsSuite) TestParseHostPort(c *C) {
+	for _, host := range []string{"172.16.5.140", "tidb.example.com", "fd00::1", "::1"} {
+		h, p, err := ParseHostPort(JoinHostPort(host, 20160))
+		c.Assert(err, IsNil)
+		c.Assert(h, Equals, host)
+		c.Assert(p, Equals, 20160)
+	}
+
+	_, _, err := ParseHostPort("fd00::1:2379")
+	c.Assert(err, NotNil)
+	_, _, err = ParseHostPort("172.16.5.140:port")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pingcap-incubator/tiup-cluster/pkg/cliutil"
+	"github.com/pingcap-incubator/tiup-cluster/pkg/errutil"
+	"golang.org/x/net/http/httpproxy"
+)
+
+var (
+	// ErrInvalidProxy is an error for the malformed proxy URL
+	ErrInvalidProxy = errNS.NewType("invalid_proxy", errutil.ErrTraitPreCheck)
+)
+
+// ProxyConfig is the proxy settings used to fetch the manifests and artifacts from the mirror
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// ProxyConfigFromEnv reads the proxy settings from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// or the lowercase versions of them
+func ProxyConfigFromEnv() ProxyConfig {
+	env := httpproxy.FromEnvironment()
+	return ProxyConfig{
+		HTTPProxy:  env.HTTPProxy,
+		HTTPSProxy: env.HTTPSProxy,
+		NoProxy:    env.NoProxy,
+	}
+}
+
+// WithOverride returns the config which uses proxy for both HTTP and HTTPS requests,
+// it's the config itself if proxy is empty
+func (p ProxyConfig) WithOverride(proxy string) ProxyConfig {
+	if proxy != "" {
+		p.HTTPProxy = proxy
+		p.HTTPSProxy = proxy
+	}
+	return p
+}
+
+// Validate checks the proxy URLs of the config
+func (p ProxyConfig) Validate() error {
+	for _, proxy := range []string{p.HTTPProxy, p.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if _, err := ParseProxyURL(proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProxyFunc returns a function to be used as the Proxy of http.Transport
+func (p ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	fn := (&httpproxy.Config{
+		HTTPProxy:  p.HTTPProxy,
+		HTTPSProxy: p.HTTPSProxy,
+		NoProxy:    p.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}
+}
+
+// Apply sets the environment variables of the proxy settings. The HTTP clients used to download
+// from the mirror read them by http.ProxyFromEnvironment, which caches the settings at the first
+// request, so it must be called before anything is fetched.
+func (p ProxyConfig) Apply() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	envs := map[string]string{
+		"HTTP_PROXY":  p.HTTPProxy,
+		"HTTPS_PROXY": p.HTTPSProxy,
+		"NO_PROXY":    p.NoProxy,
+	}
+	for key, value := range envs {
+		// the lowercase ones are merged into the config already, and they are read if
+		// the uppercase ones are empty
+		if err := os.Unsetenv(strings.ToLower(key)); err != nil {
+			return err
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseProxyURL parses and validates a proxy URL, the scheme defaults to http if omitted
+func ParseProxyURL(proxy string) (*url.URL, error) {
+	raw := proxy
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, ErrInvalidProxy.New("Proxy '%s' is invalid: %s", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, ErrInvalidProxy.
+			New("Proxy '%s' is invalid: unsupported scheme '%s'", proxy, u.Scheme).
+			WithProperty(cliutil.SuggestionFromString("The scheme of the proxy should be one of http, https and socks5."))
+	}
+	if u.Hostname() == "" {
+		return nil, ErrInvalidProxy.New("Proxy '%s' is invalid: missing host", proxy)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, ErrInvalidProxy.New("Proxy '%s' is invalid: unexpected path '%s'", proxy, u.Path)
+	}
+	return u, nil
+}
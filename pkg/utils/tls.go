@@ -0,0 +1,47 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pingcap/errors"
+)
+
+// LoadTLSConfig returns the TLS config of the clients connecting the components, which
+// verifies the servers by the CA and presents the client certificate if it's specified
+func LoadTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if caPath != "" {
+		ca, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to read the CA %s", caPath)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificate is found in the CA %s", caPath)
+		}
+		config.RootCAs = pool
+	}
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, errors.Annotatef(err, "failed to load the client certificate %s and key %s", certPath, keyPath)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
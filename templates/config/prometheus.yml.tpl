@@ -94,6 +94,25 @@ scrape_configs:
 {{- range .PDAddrs}}
       - '{{.}}'
 {{- end}}
+{{- if .TiProxyStatusAddrs}}
+  - job_name: "tiproxy"
+    honor_labels: true # don't overwrite job & instance labels
+    metrics_path: /api/metrics
+    static_configs:
+    - targets:
+    {{- range .TiProxyStatusAddrs}}
+      - '{{.}}'
+    {{- end}}
+{{- end}}
+{{- if .CDCAddrs}}
+  - job_name: "ticdc"
+    honor_labels: true # don't overwrite job & instance labels
+    static_configs:
+    - targets:
+    {{- range .CDCAddrs}}
+      - '{{.}}'
+    {{- end}}
+{{- end}}
 {{- if .TiFlashStatusAddrs}}
   - job_name: "tiflash"
     honor_labels: true # don't overwrite job & instance labels
@@ -208,6 +227,22 @@ scrape_configs:
       labels:
         group: 'tiflash'
 {{- end}}
+{{- if .TiProxyStatusAddrs}}
+    - targets:
+    {{- range .TiProxyStatusAddrs}}
+      - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'tiproxy'
+{{- end}}
+{{- if .CDCAddrs}}
+    - targets:
+    {{- range .CDCAddrs}}
+      - '{{.}}'
+    {{- end}}
+      labels:
+        group: 'ticdc'
+{{- end}}
 {{- if .PushgatewayAddr}}
     - targets:
       - '{{.PushgatewayAddr}}'
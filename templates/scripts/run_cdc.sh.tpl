@@ -0,0 +1,35 @@
+#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+DEPLOY_DIR={{.DeployDir}}
+
+cd "${DEPLOY_DIR}" || exit 1
+
+{{- define "PDList"}}
+  {{- range $idx, $pd := .}}
+    {{- if eq $idx 0}}
+      {{- $pd.Scheme}}://{{joinHostPort $pd.IP $pd.ClientPort}}
+    {{- else -}}
+      ,{{- $pd.Scheme}}://{{joinHostPort $pd.IP $pd.ClientPort}}
+    {{- end}}
+  {{- end}}
+{{- end}}
+
+{{- if .NumaNode}}
+exec numactl --cpunodebind={{.NumaNode}} --membind={{.NumaNode}} bin/cdc server \
+{{- else}}
+exec bin/cdc server \
+{{- end}}
+    --addr "0.0.0.0:{{.Port}}" \
+    --advertise-addr "{{joinHostPort .IP .Port}}" \
+    --pd "{{template "PDList" .Endpoints}}" \
+{{- if .GCTTL}}
+    --gc-ttl {{.GCTTL}} \
+{{- end}}
+{{- if .TZ}}
+    --tz "{{.TZ}}" \
+{{- end}}
+    --config conf/cdc.toml \
+    --log-file "{{.LogDir}}/cdc.log" 2>> "{{.LogDir}}/cdc_stderr.log"
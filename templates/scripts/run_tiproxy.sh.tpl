@@ -0,0 +1,15 @@
+#!/bin/bash
+set -e
+
+# WARNING: This file was auto-generated. Do not edit!
+#          All your edit might be overwritten!
+DEPLOY_DIR={{.DeployDir}}
+
+cd "${DEPLOY_DIR}" || exit 1
+
+{{- if .NumaNode}}
+exec numactl --cpunodebind={{.NumaNode}} --membind={{.NumaNode}} bin/tiproxy \
+{{- else}}
+exec bin/tiproxy \
+{{- end}}
+    --config=conf/tiproxy.toml 2>> "{{.LogDir}}/tiproxy_stderr.log"
@@ -28,6 +28,9 @@ Restart={{.Restart}}
 Restart=always
 {{end}}
 RestartSec=15s
+{{- if .TimeoutStopSec}}
+TimeoutStopSec={{.TimeoutStopSec}}s
+{{- end}}
 {{- if .DisableSendSigkill}}
 SendSIGKILL=no
 {{- end}}